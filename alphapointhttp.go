@@ -7,6 +7,8 @@ import (
 	"github.com/gorilla/websocket"
 	"log"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 )
 
@@ -31,16 +33,34 @@ const (
 	ALPHAPOINT_CANCEALLORDERS    = "CancelAllOrders"
 	ALPHAPOINT_OPEN_ORDERS       = "GetAccountOpenOrders"
 	ALPHAPOINT_ORDER_FEE         = "GetOrderFee"
+
+	BRIGHTONPEAK_API_URL       = "https://api.brightonpeak.com:8400"
+	BRIGHTONPEAK_WEBSOCKET_URL = "wss://api.brightonpeak.com:8401"
 )
 
+// AlphapointPlatforms are the endpoints of exchanges known to run on the
+// Alphapoint platform. Any other exchange configured with "Platform":
+// "Alphapoint" needs its APIURL and WebsocketURL set in config.
+var AlphapointPlatforms = map[string]ExchangeURLs{
+	"Brighton Peak": {BRIGHTONPEAK_API_URL, BRIGHTONPEAK_WEBSOCKET_URL},
+}
+
 type Alphapoint struct {
+	Name                              string
+	Enabled                           bool
+	Verbose                           bool
+	Websocket                         bool
 	WebsocketConn                     *websocket.Conn
 	WebsocketURL                      string
-	ExchangeName                      string
-	ExchangeEnabled                   bool
-	WebsocketEnabled                  bool
-	Verbose                           bool
+	RESTPollingDelay                  time.Duration
+	AuthenticatedAPISupport           bool
 	APIUrl, APIKey, UserID, APISecret string
+	TakerFee, MakerFee                float64
+	BaseCurrencies                    []string
+	AvailablePairs                    []string
+	EnabledPairs                      []string
+	Ticker                            map[string]AlphapointTicker
+	tickerMtx                         sync.Mutex
 }
 
 type AlphapointTrade struct {
@@ -142,28 +162,28 @@ type AlphapointUserInfo struct {
 
 type AlphapointAccountInfo struct {
 	Currencies []struct {
-		Name    string `json:"name"`
-		Balance int    `json:"balance"`
-		Hold    int    `json:"hold"`
+		Name    string  `json:"name"`
+		Balance float64 `json:"balance"`
+		Hold    float64 `json:"hold"`
 	} `json:"currencies"`
 	ProductPairs []struct {
-		ProductPairName string `json:"productPairName"`
-		ProductPairCode int    `json:"productPairCode"`
-		TradeCount      int    `json:"tradeCount"`
-		TradeVolume     int    `json:"tradeVolume"`
+		ProductPairName string  `json:"productPairName"`
+		ProductPairCode int     `json:"productPairCode"`
+		TradeCount      int     `json:"tradeCount"`
+		TradeVolume     float64 `json:"tradeVolume"`
 	} `json:"productPairs"`
 	IsAccepted   bool   `json:"isAccepted"`
 	RejectReason string `json:"rejectReason"`
 }
 
 type AlphapointOrder struct {
-	Serverorderid int   `json:"ServerOrderId"`
-	AccountID     int   `json:"AccountId"`
-	Price         int   `json:"Price"`
-	QtyTotal      int   `json:"QtyTotal"`
-	QtyRemaining  int   `json:"QtyRemaining"`
-	ReceiveTime   int64 `json:"ReceiveTime"`
-	Side          int   `json:"Side"`
+	Serverorderid int     `json:"ServerOrderId"`
+	AccountID     int     `json:"AccountId"`
+	Price         float64 `json:"Price"`
+	QtyTotal      float64 `json:"QtyTotal"`
+	QtyRemaining  float64 `json:"QtyRemaining"`
+	ReceiveTime   int64   `json:"ReceiveTime"`
+	Side          int     `json:"Side"`
 }
 
 type AlphapointOpenOrders struct {
@@ -183,9 +203,163 @@ type AlphapointDepositAddresses struct {
 	DepositAddress string `json:"depositAddress"`
 }
 
+// IsAlphapointExchange reports whether an exchange in config runs on the
+// Alphapoint platform and so is served by an Alphapoint client.
+func IsAlphapointExchange(exch Exchanges) bool {
+	if _, ok := AlphapointPlatforms[exch.Name]; ok {
+		return true
+	}
+	return strings.EqualFold(exch.Platform, "Alphapoint")
+}
+
+// GetAlphapointExchange returns the Alphapoint client set up for the named
+// exchange, or nil.
+func GetAlphapointExchange(name string) *Alphapoint {
+	for _, x := range bot.exchange.alphapoint {
+		if x.GetName() == name {
+			return x
+		}
+	}
+	return nil
+}
+
 func (a *Alphapoint) SetDefaults() {
-	a.APIUrl = ALPHAPOINT_DEFAULT_API_URL
-	a.WebsocketURL = ALPHAPOINT_DEFAULT_WEBSOCKET_URL
+	if a.Name == "" {
+		a.Name = "Alphapoint"
+	}
+	a.Enabled = false
+	a.TakerFee = 0.5
+	a.MakerFee = 0.5
+	a.Verbose = false
+	a.Websocket = false
+	a.RESTPollingDelay = 10
+	a.Ticker = make(map[string]AlphapointTicker)
+
+	urls, ok := AlphapointPlatforms[a.Name]
+	if !ok {
+		urls = ExchangeURLs{ALPHAPOINT_DEFAULT_API_URL, ALPHAPOINT_DEFAULT_WEBSOCKET_URL}
+	}
+	a.APIUrl, a.WebsocketURL = urls.API, urls.Websocket
+}
+
+func (a *Alphapoint) GetName() string {
+	return a.Name
+}
+
+func (a *Alphapoint) SetEnabled(enabled bool) {
+	a.Enabled = enabled
+}
+
+func (a *Alphapoint) IsEnabled() bool {
+	return a.Enabled
+}
+
+func (a *Alphapoint) Setup(exch Exchanges) {
+	if !exch.Enabled {
+		a.SetEnabled(false)
+	} else {
+		a.Enabled = true
+		a.AuthenticatedAPISupport = exch.AuthenticatedAPISupport
+		a.SetAPIKeys(exch.APIKey, exch.APISecret, exch.ClientID)
+		a.RESTPollingDelay = exch.RESTPollingDelay
+		a.Verbose = exch.Verbose
+		a.Websocket = exch.Websocket
+		a.BaseCurrencies = SplitStrings(exch.BaseCurrencies, ",")
+		a.AvailablePairs = SplitStrings(exch.AvailablePairs, ",")
+		a.EnabledPairs = SplitStrings(exch.EnabledPairs, ",")
+		urls := GetExchangeURLs(exch, ExchangeURLs{a.APIUrl, a.WebsocketURL})
+		a.APIUrl, a.WebsocketURL = urls.API, urls.Websocket
+	}
+}
+
+func (a *Alphapoint) Start() {
+	go a.Run()
+}
+
+func (a *Alphapoint) SetAPIKeys(apiKey, apiSecret, clientID string) {
+	a.UserID = clientID
+	a.APIKey = apiKey
+	a.APISecret = apiSecret
+}
+
+func (a *Alphapoint) GetFee(maker bool) float64 {
+	if maker {
+		return a.MakerFee
+	} else {
+		return a.TakerFee
+	}
+}
+
+// Run polls the tickers of the enabled pairs, unless the websocket is enabled
+// and streams them instead.
+func (a *Alphapoint) Run() {
+	if a.Verbose {
+		log.Printf("%s Websocket: %s. (url: %s).\n", a.GetName(), IsEnabled(a.Websocket), a.WebsocketURL)
+		log.Printf("%s polling delay: %ds.\n", a.GetName(), a.RESTPollingDelay)
+		log.Printf("%s %d currencies enabled: %s.\n", a.GetName(), len(a.EnabledPairs), a.EnabledPairs)
+	}
+
+	if a.Websocket {
+		go a.WebsocketClient()
+	}
+
+	exchangeProducts, err := a.GetProductPairs()
+	if err != nil {
+		log.Printf("%s Failed to get available products.\n", a.GetName())
+	} else {
+		currencies := []string{}
+		for _, x := range exchangeProducts.ProductPairs {
+			currencies = append(currencies, x.Name)
+		}
+		diff := StringSliceDifference(a.AvailablePairs, currencies)
+		if len(diff) > 0 {
+			exch, err := GetExchangeConfig(a.Name)
+			if err != nil {
+				log.Println(err)
+			} else {
+				log.Printf("%s Updating available pairs. Difference: %s.\n", a.Name, diff)
+				exch.AvailablePairs = JoinStrings(currencies, ",")
+				UpdateExchangeConfig(exch)
+			}
+		}
+	}
+
+	for a.Enabled {
+		if !a.Websocket {
+			for _, x := range a.EnabledPairs {
+				ticker, err := a.GetTicker(x)
+				if err != nil {
+					log.Println(err)
+					continue
+				}
+				a.UpdateTicker(x, ticker)
+			}
+		}
+		time.Sleep(time.Second * a.RESTPollingDelay)
+	}
+}
+
+// UpdateTicker stores the latest ticker for a pair, from REST or the
+// websocket, and passes it on to the bot's market data.
+func (a *Alphapoint) UpdateTicker(pair string, ticker AlphapointTicker) {
+	a.tickerMtx.Lock()
+	a.Ticker[pair] = ticker
+	a.tickerMtx.Unlock()
+
+	if a.Verbose {
+		log.Printf("%s %s Last %f High %f Low %f Volume %f\n", a.GetName(), pair, ticker.Last, ticker.High, ticker.Low, ticker.Volume)
+	}
+	if len(pair) == 6 {
+		AddExchangeInfo(a.GetName(), pair[0:3], pair[3:], ticker.Last, ticker.Volume)
+	}
+}
+
+// GetLastTicker returns the most recently stored ticker for a pair.
+func (a *Alphapoint) GetLastTicker(pair string) (AlphapointTicker, bool) {
+	a.tickerMtx.Lock()
+	defer a.tickerMtx.Unlock()
+	ticker, ok := a.Ticker[pair]
+	return ticker, ok
 }
 
 func (a *Alphapoint) GetTicker(symbol string) (AlphapointTicker, error) {
@@ -295,7 +469,7 @@ func (a *Alphapoint) CreateAccount(firstName, lastName, email, phone, password s
 	err := a.SendAuthenticatedHTTPRequest("POST", ALPHAPOINT_CREATE_ACCOUNT, request, &response)
 
 	if err != nil {
-		log.Println(err)
+		return err
 	}
 
 	if !response.IsAccepted {
@@ -311,6 +485,9 @@ func (a *Alphapoint) GetUserInfo() (AlphapointUserInfo, error) {
 	if err != nil {
 		return AlphapointUserInfo{}, err
 	}
+	if !response.IsAccepted {
+		return response, errors.New(response.RejectReason)
+	}
 	return response, nil
 }
 
@@ -345,9 +522,9 @@ func (a *Alphapoint) GetAccountTrades(symbol string, startIndex, count int) (Alp
 
 func (a *Alphapoint) GetDepositAddresses() ([]AlphapointDepositAddresses, error) {
 	type Response struct {
-		Addresses    []AlphapointDepositAddresses
-		IsAccepted   bool   `json:"isAccepted"`
-		RejectReason string `json:"rejectReason"`
+		Addresses    []AlphapointDepositAddresses `json:"addresses"`
+		IsAccepted   bool                         `json:"isAccepted"`
+		RejectReason string                       `json:"rejectReason"`
 	}
 
 	response := Response{}
@@ -533,10 +710,10 @@ func (a *Alphapoint) SendRequest(method, path string, data map[string]interface{
 		return errors.New("SendAuthenticatedHTTPRequest: Unable to JSON request")
 	}
 
-	resp, err := SendHTTPRequest(method, path, headers, bytes.NewBuffer(PayloadJson))
+	resp, err := GetHTTPClient(a.Name).SendHTTPRequest(method, path, headers, bytes.NewBuffer(PayloadJson))
 
 	if err != nil {
-		return err
+		return ClassifyExchangeError(a.Name, err)
 	}
 
 	err = JSONDecode([]byte(resp), &result)
@@ -551,7 +728,7 @@ func (a *Alphapoint) SendAuthenticatedHTTPRequest(method, path string, data map[
 	headers := make(map[string]string)
 	headers["Content-Type"] = "application/json"
 	data["apiKey"] = a.APIKey
-	nonce := time.Now().UnixNano()
+	nonce := Nonces.Next(NonceKey(a.Name, a.APIKey), time.Nanosecond)
 	nonceStr := strconv.FormatInt(nonce, 10)
 	data["apiNonce"] = nonce
 	hmac := GetHMAC(HASH_SHA256, []byte(nonceStr+a.UserID+a.APIKey), []byte(a.APISecret))
@@ -563,10 +740,10 @@ func (a *Alphapoint) SendAuthenticatedHTTPRequest(method, path string, data map[
 		return errors.New("SendAuthenticatedHTTPRequest: Unable to JSON request")
 	}
 
-	resp, err := SendHTTPRequest(method, path, headers, bytes.NewBuffer(PayloadJson))
+	resp, err := GetHTTPClient(a.Name).SendAuthenticatedHTTPRequest(method, path, headers, bytes.NewBuffer(PayloadJson))
 
 	if err != nil {
-		return err
+		return ClassifyExchangeError(a.Name, err)
 	}
 
 	err = JSONDecode([]byte(resp), &result)
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func alphapointFixtures(t *testing.T) (*Alphapoint, *fixtureServer) {
+	a := &Alphapoint{}
+	a.SetDefaults()
+	a.SetAPIKeys("alphapoint-key", "alphapoint-secret", "4")
+	return a, newFixtureServer(t, a.Name, "alphapoint.json")
+}
+
+func TestAlphapointPublicFixtures(t *testing.T) {
+	a, f := alphapointFixtures(t)
+	defer f.Close()
+
+	runFixtureCases(t, f, []fixtureCase{
+		{Name: "GetTicker", Call: func() (interface{}, error) { return a.GetTicker("BTCUSD") },
+			Params: map[string]string{"productPair": "BTCUSD"},
+			Want:   []string{"{High:445.5 Last:440.05 Bid:439.9 Volume:12.5 Low:430 Ask:440.3 Total24HrQtyTraded:12.5 Total24HrNumTrades:41"}},
+		{Name: "GetTrades", Call: func() (interface{}, error) { return a.GetTrades("BTCUSD", 0, 1) },
+			Params: map[string]string{"ins": "BTCUSD", "startIndex": "0", "Count": "1"},
+			Want:   []string{"Trades:[{TID:2 Price:440.05 Quantity:0.5 Unixtime:1459238810 UTCTicks:635947388100000000 IncomingOrderSide:0 IncomingServerOrderID:57 BookServerOrderID:55}]"}},
+		{Name: "GetTradesByDate", Call: func() (interface{}, error) { return a.GetTradesByDate("BTCUSD", 1459238000, 1459239000) },
+			Params: map[string]string{"ins": "BTCUSD", "startDate": "1459238000", "endDate": "1459239000"},
+			Want:   []string{"StartDate:1459238000 EndDate:1459239000 Trades:[{TID:2 Price:440.05"}},
+		{Name: "GetOrderbook", Call: func() (interface{}, error) { return a.GetOrderbook("BTCUSD") },
+			Want: []string{"{Bids:[{Quantity:1.5 Price:439.9}] Asks:[{Quantity:0.25 Price:440.3}]"}},
+		{Name: "GetProductPairs", Call: func() (interface{}, error) { return a.GetProductPairs() },
+			Want: []string{"{Name:BTCUSD Productpaircode:1 Product1Label:BTC Product1Decimalplaces:8 Product2Label:USD Product2Decimalplaces:2}"}},
+		{Name: "GetProducts", Call: func() (interface{}, error) { return a.GetProducts() },
+			Want: []string{"{Name:BTC IsDigital:true ProductCode:1 DecimalPlaces:8 FullName:Bitcoin}"}},
+	})
+
+	if _, err := a.GetTicker("XYZUSD"); err == nil || err.Error() != "Invalid product pair" {
+		t.Error(fmt.Sprintf("Test failed - GetTicker. Expected the reject reason. Actual %v", err))
+	}
+}
+
+func TestAlphapointAuthenticatedFixtures(t *testing.T) {
+	a, f := alphapointFixtures(t)
+	defer f.Close()
+
+	runFixtureCases(t, f, []fixtureCase{
+		{Name: "CreateAccount", Call: func() (interface{}, error) {
+			return nil, a.CreateAccount("Satoshi", "Nakamoto", "satoshi@example.com", "555-0100", "hunter2")
+		},
+			Params: map[string]string{"firstname": "Satoshi", "lastname": "Nakamoto", "email": "satoshi@example.com", "phone": "555-0100", "password": "hunter2"}},
+		{Name: "GetUserInfo", Call: func() (interface{}, error) { return a.GetUserInfo() },
+			Want: []string{"UserInfoKVP:[{Key:UserLastName Value:Nakamoto}]"}},
+		{Name: "GetAccountInfo", Call: func() (interface{}, error) { return a.GetAccountInfo() },
+			Want: []string{"Currencies:[{Name:BTC Balance:1.5 Hold:0.25} {Name:USD Balance:1000.5 Hold:0}]", "TradeVolume:1.75"}},
+		{Name: "GetAccountTrades", Call: func() (interface{}, error) { return a.GetAccountTrades("BTCUSD", 0, 1) },
+			Params: map[string]string{"ins": "BTCUSD", "startIndex": "0", "count": "1"},
+			Want:   []string{"Trades:[{TID:9 Price:438 Quantity:0.1"}},
+		{Name: "GetDepositAddresses", Call: func() (interface{}, error) { return a.GetDepositAddresses() },
+			Want: []string{"[{Name:BTC DepositAddress:1AlphapointDepositAddress}]"}},
+		{Name: "WithdrawCoins", Call: func() (interface{}, error) {
+			return nil, a.WithdrawCoins("BTCUSD", "BTC", 0.5, "1AlphapointWithdrawAddress")
+		},
+			Params: map[string]string{"ins": "BTCUSD", "product": "BTC", "amount": "0.5", "sendToAddress": "1AlphapointWithdrawAddress"}},
+		{Name: "CreateOrder", Call: func() (interface{}, error) { return a.CreateOrder("BTCUSD", "buy", 1, 0.5, 439.5) },
+			Params: map[string]string{"ins": "BTCUSD", "side": "buy", "orderType": "1", "qty": "0.5", "px": "439.5"},
+			Want:   []string{"1400"}},
+		{Name: "ModifyOrder", Call: func() (interface{}, error) { return a.ModifyOrder("BTCUSD", 1400, 1) },
+			Params: map[string]string{"ins": "BTCUSD", "serverOrderId": "1400", "modifyAction": "1"},
+			Want:   []string{"1401"}},
+		{Name: "CancelOrder", Call: func() (interface{}, error) { return a.CancelOrder("BTCUSD", 1400) },
+			Params: map[string]string{"ins": "BTCUSD", "serverOrderId": "1400"},
+			Want:   []string{"1402"}},
+		{Name: "CancelAllOrders", Call: func() (interface{}, error) { return nil, a.CancelAllOrders("BTCUSD") },
+			Params: map[string]string{"ins": "BTCUSD"}},
+		{Name: "GetOrders", Call: func() (interface{}, error) { return a.GetOrders() },
+			Want: []string{"[{Instrument:BTCUSD Openorders:[{Serverorderid:1400 AccountID:4 Price:439.5 QtyTotal:0.5 QtyRemaining:0.25 ReceiveTime:635504540880633671 Side:0}]}]"}},
+		{Name: "GetOrderFee", Call: func() (interface{}, error) { return a.GetOrderFee("BTCUSD", "buy", 1, 439.5) },
+			Params: map[string]string{"ins": "BTCUSD", "side": "buy", "qty": "1", "px": "439.5"},
+			Want:   []string{"0.0025"}},
+	})
+
+	if err := a.CreateAccount("Satoshi", "Nakamoto", "taken@example.com", "", ""); err == nil || err.Error() != "Email already registered" {
+		t.Error(fmt.Sprintf("Test failed - CreateAccount. Expected the reject reason. Actual %v", err))
+	}
+
+	a.APIKey = "revoked-key"
+	if _, err := a.GetAccountInfo(); !IsAuthFailed(err) {
+		t.Error(fmt.Sprintf("Test failed - GetAccountInfo. Expected %v error. Actual %v", ERROR_AUTH_FAILED, err))
+	}
+}
+
+func TestAlphapointSigning(t *testing.T) {
+	a, f := alphapointFixtures(t)
+	defer f.Close()
+
+	if _, err := a.GetAccountInfo(); err != nil {
+		t.Fatal(err)
+	}
+
+	req := f.Last()
+	params := req.Params()
+	hmac := GetHMAC(HASH_SHA256, []byte(params.Get("apiNonce")+"4"+"alphapoint-key"), []byte("alphapoint-secret"))
+	if sign := strings.ToUpper(HexEncodeToString(hmac)); params.Get("apiSig") != sign {
+		t.Error(fmt.Sprintf("Test failed. Expected signature %s. Actual %s", sign, params.Get("apiSig")))
+	}
+	if params.Get("apiKey") != "alphapoint-key" || req.Header.Get("Content-Type") != "application/json" {
+		t.Error(fmt.Sprintf("Test failed. Unexpected request %v %s", req.Header, req.Body))
+	}
+}
+
+func TestAlphapointPlatformExchanges(t *testing.T) {
+	for _, x := range []struct {
+		exch       Exchanges
+		alphapoint bool
+		urls       ExchangeURLs
+	}{
+		{Exchanges{Name: "Brighton Peak"}, true, ExchangeURLs{BRIGHTONPEAK_API_URL, BRIGHTONPEAK_WEBSOCKET_URL}},
+		{Exchanges{Name: "Acme", Platform: "alphapoint", APIURL: "https://api.acme.example:8400", WebsocketURL: "wss://api.acme.example:8401/v1/GetTicker/"},
+			true, ExchangeURLs{"https://api.acme.example:8400", "wss://api.acme.example:8401/v1/GetTicker/"}},
+		{Exchanges{Name: "Kraken"}, false, ExchangeURLs{}},
+	} {
+		if IsAlphapointExchange(x.exch) != x.alphapoint {
+			t.Error(fmt.Sprintf("Test failed - %s. Expected Alphapoint %v", x.exch.Name, x.alphapoint))
+		}
+		if !x.alphapoint {
+			continue
+		}
+
+		x.exch.Enabled = true
+		a := &Alphapoint{Name: x.exch.Name}
+		a.SetDefaults()
+		a.Setup(x.exch)
+		if a.GetName() != x.exch.Name || a.APIUrl != x.urls.API || a.WebsocketURL != x.urls.Websocket {
+			t.Error(fmt.Sprintf("Test failed - %s. Expected %+v. Actual %s %s %s", x.exch.Name, x.urls, a.GetName(), a.APIUrl, a.WebsocketURL))
+		}
+	}
+}
+
+func TestAlphapointWebsocketTicker(t *testing.T) {
+	a := &Alphapoint{Name: "Brighton Peak"}
+	a.SetDefaults()
+
+	message := AlphapointWebsocketTicker{}
+	err := JSONDecode([]byte(`{"messageType": "Ticker", "prodPair": "BTCUSD", "high": 445.5, "low": 430, "last": 440.05, "volume": 12.5, "bid": 439.9, "ask": 440.3, "buyOrderCount": 3, "sellOrderCount": 2}`), &message)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.UpdateTicker(message.ProductPair, message.AlphapointTicker())
+
+	ticker, ok := a.GetLastTicker("BTCUSD")
+	if !ok || ticker.Last != 440.05 || ticker.Bid != 439.9 || ticker.Ask != 440.3 || ticker.BuyOrderCount != 3 {
+		t.Error(fmt.Sprintf("Test failed. Unexpected ticker %+v", ticker))
+	}
+}
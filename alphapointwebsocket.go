@@ -28,34 +28,50 @@ type AlphapointWebsocketTicker struct {
 	SellOrderCount          int     `json:"sellOrderCount"`
 }
 
+// AlphapointTicker converts a streamed ticker to the REST API's form.
+func (t AlphapointWebsocketTicker) AlphapointTicker() AlphapointTicker {
+	return AlphapointTicker{
+		High:               t.High,
+		Last:               t.Last,
+		Bid:                t.Bid,
+		Volume:             t.Volume,
+		Low:                t.Low,
+		Ask:                t.Ask,
+		Total24HrQtyTraded: t.Total24HrQtyTraded,
+		Total24HrNumTrades: t.Total24HrNumTrades,
+		SellOrderCount:     float64(t.SellOrderCount),
+		BuyOrderCount:      float64(t.BuyOrderCount),
+		IsAccepted:         true,
+	}
+}
+
 func (a *Alphapoint) WebsocketClient() {
-	for a.ExchangeEnabled && a.WebsocketEnabled {
+	s := GetWebsocketSupervisor(a.Name)
+	s.Verbose = a.Verbose
+	s.Running = func() bool { return a.Enabled && a.Websocket }
+	s.Dial = func() error {
 		var Dialer websocket.Dialer
 		var err error
 		a.WebsocketConn, _, err = Dialer.Dial(a.WebsocketURL, http.Header{})
-
 		if err != nil {
-			log.Printf("%s Unable to connect to Websocket. Error: %s\n", a.ExchangeName, err)
-			continue
-		}
-
-		if a.Verbose {
-			log.Printf("%s Connected to Websocket.\n", a.ExchangeName)
+			return err
 		}
 
 		err = a.WebsocketConn.WriteMessage(websocket.TextMessage, []byte(`{"messageType": "logon"}`))
-
 		if err != nil {
-			log.Println(err)
-			return
+			a.WebsocketConn.Close()
+			return err
 		}
-
-		for a.ExchangeEnabled && a.WebsocketEnabled {
+		return nil
+	}
+	s.Close = func() error { return a.WebsocketConn.Close() }
+	s.Serve = func() error {
+		for a.Enabled && a.Websocket {
 			msgType, resp, err := a.WebsocketConn.ReadMessage()
 			if err != nil {
-				log.Println(err)
-				break
+				return err
 			}
+			s.Received()
 
 			switch msgType {
 			case websocket.TextMessage:
@@ -78,10 +94,11 @@ func (a *Alphapoint) WebsocketClient() {
 						log.Println(err)
 						continue
 					}
+					a.UpdateTicker(ticker.ProductPair, ticker.AlphapointTicker())
 				}
 			}
 		}
-		a.WebsocketConn.Close()
-		log.Printf("%s Websocket client disconnected.", a.ExchangeName)
+		return nil
 	}
+	s.Run()
 }
@@ -30,6 +30,7 @@ type ANX struct {
 	Websocket               bool
 	RESTPollingDelay        time.Duration
 	AuthenticatedAPISupport bool
+	APIUrl                  string
 	APIKey, APISecret       string
 	TakerFee, MakerFee      float64
 	BaseCurrencies          []string
@@ -85,8 +86,8 @@ type ANXTicker struct {
 		Last       ANXTickerComponent `json:"last"`
 		Buy        ANXTickerComponent `json:"buy"`
 		Sell       ANXTickerComponent `json:"sell"`
-		Now        float64            `json:"now"`
-		UpdateTime float64            `json:"dataUpdateTime"`
+		Now        int64              `json:"now,string"`
+		UpdateTime int64              `json:"dataUpdateTime,string"`
 	} `json:"data"`
 }
 
@@ -98,6 +99,7 @@ func (a *ANX) SetDefaults() {
 	a.Verbose = false
 	a.Websocket = false
 	a.RESTPollingDelay = 10
+	a.APIUrl = ANX_API_URL
 }
 
 //Setup is run on startup to setup exchange with config values
@@ -114,6 +116,7 @@ func (a *ANX) Setup(exch Exchanges) {
 		a.BaseCurrencies = SplitStrings(exch.BaseCurrencies, ",")
 		a.AvailablePairs = SplitStrings(exch.AvailablePairs, ",")
 		a.EnabledPairs = SplitStrings(exch.EnabledPairs, ",")
+		a.APIUrl = GetExchangeURLs(exch, ExchangeURLs{API: a.APIUrl}).API
 	}
 }
 
@@ -144,7 +147,7 @@ func (a *ANX) SetAPIKeys(apiKey, apiSecret string) {
 
 	if err != nil {
 		log.Printf("%s unable to decode secret key. Authenticated API support disabled.", a.GetName())
-		a.AuthenticatedAPISupport = true
+		a.AuthenticatedAPISupport = false
 		return
 	}
 
@@ -180,7 +183,7 @@ func (a *ANX) Run() {
 
 func (a *ANX) GetTicker(currency string) ANXTicker {
 	var ticker ANXTicker
-	err := SendHTTPGetRequest(fmt.Sprintf("%sapi/2/%s/%s", ANX_API_URL, currency, ANX_TICKER), true, &ticker)
+	err := GetHTTPClient(a.Name).SendHTTPGetRequest(fmt.Sprintf("%sapi/2/%s/%s", a.APIUrl, currency, ANX_TICKER), true, &ticker)
 	if err != nil {
 		log.Println(err)
 		return ANXTicker{}
@@ -190,7 +193,7 @@ func (a *ANX) GetTicker(currency string) ANXTicker {
 
 func (a *ANX) GetAPIKey(username, password, otp, deviceID string) (string, string) {
 	request := make(map[string]interface{})
-	request["nonce"] = strconv.FormatInt(time.Now().UnixNano(), 10)[0:13]
+	request["nonce"] = strconv.FormatInt(Nonces.Next(NonceKey(a.Name, username), time.Millisecond), 10)
 	request["username"] = username
 	request["password"] = password
 
@@ -250,7 +253,7 @@ func (a *ANX) GetDataToken() string {
 }
 
 func (a *ANX) NewOrder(orderType string, buy bool, tradedCurrency, tradedCurrencyAmount, settlementCurrency, settlementCurrencyAmount, limitPriceSettlement string,
-	replace bool, replaceUUID string, replaceIfActive bool) {
+	replace bool, replaceUUID string, replaceIfActive bool) (string, error) {
 	request := make(map[string]interface{})
 
 	var order ANXOrder
@@ -284,14 +287,14 @@ func (a *ANX) NewOrder(orderType string, buy bool, tradedCurrency, tradedCurrenc
 	err := a.SendAuthenticatedHTTPRequest(ANX_ORDER_NEW, request, &response)
 
 	if err != nil {
-		log.Println(err)
-		return
+		return "", err
 	}
 
 	if response.ResultCode != "OK" {
 		log.Printf("Response code is not OK: %s\n", response.ResultCode)
-		return
+		return "", errors.New(response.ResultCode)
 	}
+	return response.OrderID, nil
 }
 
 func (a *ANX) OrderInfo(orderID string) (ANXOrderResponse, error) {
@@ -411,7 +414,7 @@ func (a *ANX) GetDepositAddress(currency, name string, new bool) (string, error)
 
 func (a *ANX) SendAuthenticatedHTTPRequest(path string, params map[string]interface{}, result interface{}) (err error) {
 	request := make(map[string]interface{})
-	request["nonce"] = strconv.FormatInt(time.Now().UnixNano(), 10)[0:13]
+	request["nonce"] = strconv.FormatInt(Nonces.Next(NonceKey(a.Name, a.APIKey), time.Millisecond), 10)
 	path = fmt.Sprintf("api/%s/%s", ANX_API_VERSION, path)
 
 	if params != nil {
@@ -436,12 +439,16 @@ func (a *ANX) SendAuthenticatedHTTPRequest(path string, params map[string]interf
 	headers["Rest-Sign"] = Base64Encode([]byte(hmac))
 	headers["Content-Type"] = "application/json"
 
-	resp, err := SendHTTPRequest("POST", ANX_API_URL+path, headers, bytes.NewBuffer(PayloadJson))
+	resp, err := GetHTTPClient(a.Name).SendAuthenticatedHTTPRequest("POST", a.APIUrl+path, headers, bytes.NewBuffer(PayloadJson))
 
 	if a.Verbose {
 		log.Printf("Recieved raw: \n%s\n", resp)
 	}
 
+	if err != nil {
+		return ClassifyExchangeError(a.Name, err)
+	}
+
 	err = JSONDecode([]byte(resp), &result)
 
 	if err != nil {
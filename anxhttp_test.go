@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+func anxFixtures(t *testing.T) (*ANX, *fixtureServer) {
+	a := &ANX{}
+	a.SetDefaults()
+	a.AuthenticatedAPISupport = true
+	a.SetAPIKeys("anx-key", Base64Encode([]byte("anx-secret")))
+	return a, newFixtureServer(t, a.Name, "anx.json")
+}
+
+func TestANXPublicFixtures(t *testing.T) {
+	a, f := anxFixtures(t)
+	defer f.Close()
+
+	runFixtureCases(t, f, []fixtureCase{
+		{Name: "GetTicker", Call: func() (interface{}, error) { return a.GetTicker("BTCUSD"), nil },
+			Want: []string{"Result:success", "Last:{Currency:USD Display:$640.00000 DisplayShort:$640.00 Value:640 ValueInt:64000000}", "Now:1403010542007059 UpdateTime:1403010540843975"}},
+	})
+}
+
+func TestANXAuthenticatedFixtures(t *testing.T) {
+	a, f := anxFixtures(t)
+	defer f.Close()
+
+	order := "ba5acfa6-2a8a-4b7f-8e3a-c4bd0c1f1c6a"
+	runFixtureCases(t, f, []fixtureCase{
+		{Name: "GetAPIKey", Call: func() (interface{}, error) {
+			key, secret := a.GetAPIKey("user", "password", "", "device")
+			return key + " " + secret, nil
+		},
+			Params: map[string]string{"username": "user", "password": "password", "otp": "", "deviceId": "device"},
+			Want:   []string{"new-anx-key bmV3LWFueC1zZWNyZXQ="}},
+		{Name: "GetDataToken", Call: func() (interface{}, error) { return a.GetDataToken(), nil },
+			Want: []string{"b1d8d6e1-7a1c-4ec3-9d1f-d4a1d8e7e5a1"}},
+		{Name: "NewOrder", Call: func() (interface{}, error) {
+			return a.NewOrder("LIMIT", true, "BTC", "1", "USD", "", "640", false, "", false)
+		},
+			Params: map[string]string{"order": "map[buyTradedCurrency:true limitPriceInSettlementCurrency:640 orderType:LIMIT replaceExistingOrderUuid: replaceOnlyIfActive:false settlementCurrency:USD settlementCurrencyAmount: tradedCurrency:BTC tradedCurrencyAmount:1]"},
+			Want:   []string{order}},
+		{Name: "OrderInfo", Call: func() (interface{}, error) { return a.OrderInfo(order) },
+			Params: map[string]string{"orderId": order},
+			Want:   []string{"OrderStatus:FULL_FILL OrderType:LIMIT", "TradedCurrencyAmount:1.00000000 TradedCurrencyOutstanding:0.00000000"}},
+		{Name: "Send", Call: func() (interface{}, error) { return a.Send("BTC", "1MqKo86hY1yWMHKUEBGe3a8ELF5KJjZGrF", "", "0.5") },
+			Params: map[string]string{"ccy": "BTC", "address": "1MqKo86hY1yWMHKUEBGe3a8ELF5KJjZGrF", "amount": "0.5", "otp": ""},
+			Want:   []string{"e4d8f7b2-4c36-4a68-a1f6-16a0c7f2d5e4"}},
+		{Name: "CreateNewSubAccount", Call: func() (interface{}, error) { return a.CreateNewSubAccount("BTC", "MY_SAVINGS") },
+			Params: map[string]string{"ccy": "BTC", "customRef": "MY_SAVINGS"},
+			Want:   []string{"MY_SAVINGS"}},
+		{Name: "GetDepositAddress", Call: func() (interface{}, error) { return a.GetDepositAddress("BTC", "MY_SAVINGS", false) },
+			Params: map[string]string{"subAccount": "MY_SAVINGS"},
+			Want:   []string{"1MqKo86hY1yWMHKUEBGe3a8ELF5KJjZGrF"}},
+		{Name: "GetDepositAddress new", Call: func() (interface{}, error) { return a.GetDepositAddress("BTC", "", true) },
+			Params: map[string]string{"subAccount": ""},
+			Want:   []string{"1Fxa2Lj3xFQNnMK8nLzqWZCSpqJ2V3FfTd"}},
+	})
+}
+
+func TestANXSigning(t *testing.T) {
+	a, f := anxFixtures(t)
+	defer f.Close()
+
+	if _, err := a.OrderInfo("ba5acfa6-2a8a-4b7f-8e3a-c4bd0c1f1c6a"); err != nil {
+		t.Fatal(err)
+	}
+
+	req := f.Last()
+	hmac := GetHMAC(HASH_SHA512, []byte("api/3/order/info\x00"+req.Body), []byte("anx-secret"))
+	if sign := Base64Encode(hmac); req.Header.Get("Rest-Sign") != sign {
+		t.Error(fmt.Sprintf("Test failed. Expected signature %s. Actual %s", sign, req.Header.Get("Rest-Sign")))
+	}
+	if req.Header.Get("Rest-Key") != "anx-key" || req.Header.Get("Content-Type") != "application/json" || req.Params().Get("nonce") == "" {
+		t.Error(fmt.Sprintf("Test failed. Unexpected request %v %s", req.Header, req.Body))
+	}
+}
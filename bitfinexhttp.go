@@ -76,10 +76,10 @@ type MarginLimits struct {
 type BitfinexMarginInfo struct {
 	MarginBalance     float64        `json:"margin_balance,string"`
 	TradableBalance   float64        `json:"tradable_balance,string"`
-	UnrealizedPL      int64          `json:"unrealized_pl"`
-	UnrealizedSwap    int64          `json:"unrealized_swap"`
+	UnrealizedPL      float64        `json:"unrealized_pl,string"`
+	UnrealizedSwap    float64        `json:"unrealized_swap,string"`
 	NetValue          float64        `json:"net_value,string"`
-	RequiredMargin    int64          `json:"required_margin"`
+	RequiredMargin    float64        `json:"required_margin,string"`
 	Leverage          float64        `json:"leverage,string"`
 	MarginRequirement float64        `json:"margin_requirement,string"`
 	MarginLimits      []MarginLimits `json:"margin_limits"`
@@ -133,7 +133,7 @@ type BitfinexOffer struct {
 	IsCancelled     bool    `json:"is_cancelled"`
 	OriginalAmount  float64 `json:"original_amount,string"`
 	RemainingAmount float64 `json:"remaining_amount,string"`
-	ExecutedAmount  float64 `json:"remaining_amount,string"`
+	ExecutedAmount  float64 `json:"executed_amount,string"`
 }
 
 type BookStructure struct {
@@ -171,8 +171,10 @@ type Bitfinex struct {
 	Enabled                 bool
 	Verbose                 bool
 	Websocket               bool
+	WebsocketURL            string
 	RESTPollingDelay        time.Duration
 	AuthenticatedAPISupport bool
+	APIUrl                  string
 	APIKey, APISecret       string
 	ActiveOrders            []BitfinexOrder
 	BaseCurrencies          []string
@@ -189,6 +191,8 @@ func (b *Bitfinex) SetDefaults() {
 	b.Websocket = false
 	b.RESTPollingDelay = 10
 	b.WebsocketSubdChannels = make(map[int]BitfinexWebsocketChanInfo)
+	b.APIUrl = BITFINEX_API_URL
+	b.WebsocketURL = BITFINEX_WEBSOCKET
 }
 
 func (b *Bitfinex) GetName() string {
@@ -208,6 +212,8 @@ func (b *Bitfinex) Setup(exch Exchanges) {
 		b.BaseCurrencies = SplitStrings(exch.BaseCurrencies, ",")
 		b.AvailablePairs = SplitStrings(exch.AvailablePairs, ",")
 		b.EnabledPairs = SplitStrings(exch.EnabledPairs, ",")
+		urls := GetExchangeURLs(exch, ExchangeURLs{b.APIUrl, b.WebsocketURL})
+		b.APIUrl, b.WebsocketURL = urls.API, urls.Websocket
 	}
 }
 
@@ -275,9 +281,9 @@ func (b *Bitfinex) Run() {
 }
 
 func (b *Bitfinex) GetTicker(symbol string, values url.Values) (BitfinexTicker, error) {
-	path := EncodeURLValues(BITFINEX_API_URL+BITFINEX_TICKER+symbol, values)
+	path := EncodeURLValues(b.APIUrl+BITFINEX_TICKER+symbol, values)
 	response := BitfinexTicker{}
-	err := SendHTTPGetRequest(path, true, &response)
+	err := GetHTTPClient(b.Name).SendHTTPGetRequest(path, true, &response)
 	if err != nil {
 		return response, err
 	}
@@ -297,19 +303,20 @@ type BitfinexLendbook struct {
 	Asks []BitfinexLendbookBidAsk `json:"asks"`
 }
 
-func (b *Bitfinex) GetStats(symbol string) (BitfinexStats, error) {
-	response := BitfinexStats{}
-	err := SendHTTPGetRequest(BITFINEX_API_URL+BITFINEX_STATS+symbol, true, &response)
+// GetStats returns the volume over the last 1, 7 and 30 days.
+func (b *Bitfinex) GetStats(symbol string) ([]BitfinexStats, error) {
+	response := []BitfinexStats{}
+	err := GetHTTPClient(b.Name).SendHTTPGetRequest(b.APIUrl+BITFINEX_STATS+symbol, true, &response)
 	if err != nil {
-		return response, err
+		return nil, err
 	}
 	return response, nil
 }
 
 func (b *Bitfinex) GetLendbook(symbol string, values url.Values) (BitfinexLendbook, error) {
-	path := EncodeURLValues(BITFINEX_API_URL+BITFINEX_LENDBOOK+symbol, values)
+	path := EncodeURLValues(b.APIUrl+BITFINEX_LENDBOOK+symbol, values)
 	response := BitfinexLendbook{}
-	err := SendHTTPGetRequest(path, true, &response)
+	err := GetHTTPClient(b.Name).SendHTTPGetRequest(path, true, &response)
 	if err != nil {
 		return response, err
 	}
@@ -317,9 +324,9 @@ func (b *Bitfinex) GetLendbook(symbol string, values url.Values) (BitfinexLendbo
 }
 
 func (b *Bitfinex) GetOrderbook(symbol string, values url.Values) (BitfinexOrderbook, error) {
-	path := EncodeURLValues(BITFINEX_API_URL+BITFINEX_ORDERBOOK+symbol, values)
+	path := EncodeURLValues(b.APIUrl+BITFINEX_ORDERBOOK+symbol, values)
 	response := BitfinexOrderbook{}
-	err := SendHTTPGetRequest(path, true, &response)
+	err := GetHTTPClient(b.Name).SendHTTPGetRequest(path, true, &response)
 	if err != nil {
 		return response, err
 	}
@@ -327,15 +334,47 @@ func (b *Bitfinex) GetOrderbook(symbol string, values url.Values) (BitfinexOrder
 }
 
 func (b *Bitfinex) GetTrades(symbol string, values url.Values) ([]BitfinexTradeStructure, error) {
-	path := EncodeURLValues(BITFINEX_API_URL+BITFINEX_TRADES+symbol, values)
+	path := EncodeURLValues(b.APIUrl+BITFINEX_TRADES+symbol, values)
 	response := []BitfinexTradeStructure{}
-	err := SendHTTPGetRequest(path, true, &response)
+	err := GetHTTPClient(b.Name).SendHTTPGetRequest(path, true, &response)
 	if err != nil {
 		return nil, err
 	}
 	return response, nil
 }
 
+// GetCandleHistory is a CandleHistoryFunc which aggregates public trades, as
+// Bitfinex v1 has no candle endpoint. Only the most recent 1000 trades since
+// start are available per call.
+func (b *Bitfinex) GetCandleHistory(symbol string, start, end time.Time, period time.Duration) ([]Candle, error) {
+	values := url.Values{}
+	values.Set("timestamp", strconv.FormatInt(start.Unix(), 10))
+	values.Set("limit_trades", "1000")
+
+	trades, err := b.GetTrades(symbol, values)
+	if err != nil {
+		return nil, err
+	}
+
+	candleTrades := []CandleTrade{}
+	for _, x := range trades {
+		t := time.Unix(x.Timestamp, 0)
+		if !t.Before(end) {
+			continue
+		}
+		price, err := strconv.ParseFloat(x.Price, 64)
+		if err != nil {
+			return nil, err
+		}
+		amount, err := strconv.ParseFloat(x.Amount, 64)
+		if err != nil {
+			return nil, err
+		}
+		candleTrades = append(candleTrades, CandleTrade{Price: price, Amount: amount, Time: t})
+	}
+	return CandlesFromTrades(candleTrades, period), nil
+}
+
 type BitfinexLends struct {
 	Rate       float64 `json:"rate,string"`
 	AmountLent float64 `json:"amount_lent,string"`
@@ -344,9 +383,9 @@ type BitfinexLends struct {
 }
 
 func (b *Bitfinex) GetLends(symbol string, values url.Values) ([]BitfinexLends, error) {
-	path := EncodeURLValues(BITFINEX_API_URL+BITFINEX_LENDS+symbol, values)
+	path := EncodeURLValues(b.APIUrl+BITFINEX_LENDS+symbol, values)
 	response := []BitfinexLends{}
-	err := SendHTTPGetRequest(path, true, &response)
+	err := GetHTTPClient(b.Name).SendHTTPGetRequest(path, true, &response)
 	if err != nil {
 		return nil, err
 	}
@@ -355,7 +394,7 @@ func (b *Bitfinex) GetLends(symbol string, values url.Values) ([]BitfinexLends,
 
 func (b *Bitfinex) GetSymbols() ([]string, error) {
 	products := []string{}
-	err := SendHTTPGetRequest(BITFINEX_API_URL+BITFINEX_SYMBOLS, true, &products)
+	err := GetHTTPClient(b.Name).SendHTTPGetRequest(b.APIUrl+BITFINEX_SYMBOLS, true, &products)
 	if err != nil {
 		return nil, err
 	}
@@ -364,7 +403,7 @@ func (b *Bitfinex) GetSymbols() ([]string, error) {
 
 func (b *Bitfinex) GetSymbolsDetails() ([]BitfinexSymbolDetails, error) {
 	response := []BitfinexSymbolDetails{}
-	err := SendHTTPGetRequest(BITFINEX_API_URL+BITFINEX_SYMBOLS_DETAILS, true, &response)
+	err := GetHTTPClient(b.Name).SendHTTPGetRequest(b.APIUrl+BITFINEX_SYMBOLS_DETAILS, true, &response)
 	if err != nil {
 		return nil, err
 	}
@@ -386,15 +425,14 @@ func (b *Bitfinex) GetAccountInfo() ([]BitfinexAccountInfo, error) {
 	err := b.SendAuthenticatedHTTPRequest("POST", BITFINEX_ACCOUNT_INFO, nil, &response)
 
 	if err != nil {
-		log.Println(err)
+		return nil, err
 	}
 
-	log.Println(response)
 	return response, nil
 }
 
 type BitfinexDepositResponse struct {
-	Result   string `json:"string"`
+	Result   string `json:"result"`
 	Method   string `json:"method"`
 	Currency string `json:"currency"`
 	Address  string `json:"address"`
@@ -485,7 +523,7 @@ func (b *Bitfinex) CancelMultiplateOrders(OrderIDs []int64) (string, error) {
 	request["order_ids"] = OrderIDs
 	response := BitfinexGenericResponse{}
 
-	err := b.SendAuthenticatedHTTPRequest("POST", BITFINEX_ORDER_CANCEL_MULTI, request, nil)
+	err := b.SendAuthenticatedHTTPRequest("POST", BITFINEX_ORDER_CANCEL_MULTI, request, &response)
 
 	if err != nil {
 		return "", err
@@ -496,7 +534,7 @@ func (b *Bitfinex) CancelMultiplateOrders(OrderIDs []int64) (string, error) {
 
 func (b *Bitfinex) CancelAllOrders() (string, error) {
 	response := BitfinexGenericResponse{}
-	err := b.SendAuthenticatedHTTPRequest("GET", BITFINEX_ORDER_CANCEL_ALL, nil, nil)
+	err := b.SendAuthenticatedHTTPRequest("POST", BITFINEX_ORDER_CANCEL_ALL, nil, &response)
 
 	if err != nil {
 		return "", err
@@ -560,8 +598,8 @@ func (b *Bitfinex) GetActiveOrders() ([]BitfinexOrder, error) {
 
 type BitfinexPosition struct {
 	ID        int64   `json:"id"`
-	Symbol    string  `json:"string"`
-	Status    string  `json:"active"`
+	Symbol    string  `json:"symbol"`
+	Status    string  `json:"status"`
 	Base      float64 `json:"base,string"`
 	Amount    float64 `json:"amount,string"`
 	Timestamp string  `json:"timestamp"`
@@ -585,7 +623,7 @@ func (b *Bitfinex) ClaimPosition(PositionID int) (BitfinexPosition, error) {
 	request["position_id"] = PositionID
 	response := BitfinexPosition{}
 
-	err := b.SendAuthenticatedHTTPRequest("POST", BITFINEX_CLAIM_POSITION, nil, nil)
+	err := b.SendAuthenticatedHTTPRequest("POST", BITFINEX_CLAIM_POSITION, request, &response)
 
 	if err != nil {
 		return BitfinexPosition{}, err
@@ -607,11 +645,11 @@ func (b *Bitfinex) GetBalanceHistory(symbol string, timeSince time.Time, timeUnt
 	request["currency"] = symbol
 
 	if !timeSince.IsZero() {
-		request["since"] = timeSince
+		request["since"] = strconv.FormatInt(timeSince.Unix(), 10)
 	}
 
 	if !timeUntil.IsZero() {
-		request["until"] = timeUntil
+		request["until"] = strconv.FormatInt(timeUntil.Unix(), 10)
 	}
 
 	if limit > 0 {
@@ -636,7 +674,7 @@ type BitfinexMovementHistory struct {
 	ID          int64   `json:"id"`
 	Currency    string  `json:"currency"`
 	Method      string  `json:"method"`
-	Type        string  `json:"withdrawal"`
+	Type        string  `json:"type"`
 	Amount      float64 `json:"amount,string"`
 	Description string  `json:"description"`
 	Status      string  `json:"status"`
@@ -652,11 +690,11 @@ func (b *Bitfinex) GetMovementHistory(symbol, method string, timeSince, timeUnti
 	}
 
 	if !timeSince.IsZero() {
-		request["since"] = timeSince
+		request["since"] = strconv.FormatInt(timeSince.Unix(), 10)
 	}
 
 	if !timeUntil.IsZero() {
-		request["until"] = timeUntil
+		request["until"] = strconv.FormatInt(timeUntil.Unix(), 10)
 	}
 
 	if limit > 0 {
@@ -688,10 +726,10 @@ type BitfinexTradeHistory struct {
 func (b *Bitfinex) GetTradeHistory(symbol string, timestamp, until time.Time, limit, reverse int) ([]BitfinexTradeHistory, error) {
 	request := make(map[string]interface{})
 	request["currency"] = symbol
-	request["timestamp"] = timestamp
+	request["timestamp"] = strconv.FormatInt(timestamp.Unix(), 10)
 
 	if !until.IsZero() {
-		request["until"] = until
+		request["until"] = strconv.FormatInt(until.Unix(), 10)
 	}
 
 	if limit > 0 {
@@ -715,8 +753,8 @@ func (b *Bitfinex) GetTradeHistory(symbol string, timestamp, until time.Time, li
 func (b *Bitfinex) NewOffer(symbol string, amount, rate float64, period int64, direction string) int64 {
 	request := make(map[string]interface{})
 	request["currency"] = symbol
-	request["amount"] = amount
-	request["rate"] = rate
+	request["amount"] = strconv.FormatFloat(amount, 'f', -1, 64)
+	request["rate"] = strconv.FormatFloat(rate, 'f', -1, 64)
 	request["period"] = period
 	request["direction"] = direction
 
@@ -754,7 +792,7 @@ func (b *Bitfinex) GetOfferStatus(OfferID int64) (BitfinexOffer, error) {
 	request["offer_id"] = OfferID
 	response := BitfinexOffer{}
 
-	err := b.SendAuthenticatedHTTPRequest("POST", BITFINEX_ORDER_STATUS, request, &response)
+	err := b.SendAuthenticatedHTTPRequest("POST", BITFINEX_OFFER_STATUS, request, &response)
 
 	if err != nil {
 		return response, err
@@ -853,10 +891,10 @@ type BitfinexWalletTransfer struct {
 
 func (b *Bitfinex) WalletTransfer(amount float64, currency, walletFrom, walletTo string) ([]BitfinexWalletTransfer, error) {
 	request := make(map[string]interface{})
-	request["amount"] = amount
+	request["amount"] = strconv.FormatFloat(amount, 'f', -1, 64)
 	request["currency"] = currency
 	request["walletfrom"] = walletFrom
-	request["walletTo"] = walletTo
+	request["walletto"] = walletTo
 
 	response := []BitfinexWalletTransfer{}
 	err := b.SendAuthenticatedHTTPRequest("POST", BITFINEX_TRANSFER, request, &response)
@@ -894,7 +932,7 @@ func (b *Bitfinex) Withdrawal(withdrawType, wallet, address string, amount float
 func (b *Bitfinex) SendAuthenticatedHTTPRequest(method, path string, params map[string]interface{}, result interface{}) (err error) {
 	request := make(map[string]interface{})
 	request["request"] = fmt.Sprintf("/v%s/%s", BITFINEX_API_VERSION, path)
-	request["nonce"] = strconv.FormatInt(time.Now().UnixNano(), 10)
+	request["nonce"] = strconv.FormatInt(Nonces.Next(NonceKey(b.Name, b.APIKey), time.Nanosecond), 10)
 
 	if params != nil {
 		for key, value := range params {
@@ -919,12 +957,16 @@ func (b *Bitfinex) SendAuthenticatedHTTPRequest(method, path string, params map[
 	headers["X-BFX-PAYLOAD"] = PayloadBase64
 	headers["X-BFX-SIGNATURE"] = HexEncodeToString(hmac)
 
-	resp, err := SendHTTPRequest(method, BITFINEX_API_URL+path, headers, strings.NewReader(""))
+	resp, err := GetHTTPClient(b.Name).SendAuthenticatedHTTPRequest(method, b.APIUrl+path, headers, strings.NewReader(""))
 
 	if b.Verbose {
 		log.Printf("Recieved raw: \n%s\n", resp)
 	}
 
+	if err != nil {
+		return ClassifyExchangeError(b.Name, err)
+	}
+
 	err = JSONDecode([]byte(resp), &result)
 
 	if err != nil {
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func bitfinexFixtures(t *testing.T) (*Bitfinex, *fixtureServer) {
+	b := &Bitfinex{}
+	b.SetDefaults()
+	b.SetAPIKeys("bitfinex-key", "bitfinex-secret")
+	return b, newFixturePayloadServer(t, b.Name, "bitfinex.json", func(h http.Header) string {
+		payload, _ := Base64Decode(h.Get("X-BFX-PAYLOAD"))
+		return string(payload)
+	})
+}
+
+func TestBitfinexPublicFixtures(t *testing.T) {
+	b, f := bitfinexFixtures(t)
+	defer f.Close()
+
+	runFixtureCases(t, f, []fixtureCase{
+		{Name: "GetTicker", Call: func() (interface{}, error) { return b.GetTicker("btcusd", nil) },
+			Want: []string{"{Mid:244.755 Bid:244.75 Ask:244.76 Last:244.82 Low:244.2 High:248.19 Volume:7842.11542563 Timestamp:1444253422.348340958}"}},
+		{Name: "GetStats", Call: func() (interface{}, error) { return b.GetStats("btcusd") },
+			Want: []string{"[{Period:1 Volume:7967.96766158} {Period:7 Volume:55938.67260266} {Period:30 Volume:275148.09653645}]"}},
+		{Name: "GetLendbook", Call: func() (interface{}, error) { return b.GetLendbook("usd", url.Values{"limit_bids": {"1"}}) },
+			Params: map[string]string{"limit_bids": "1"},
+			Want:   []string{"Bids:[{Rate:9.1287 Amount:5000 Period:30 Timestamp:1444257541.0 FlashReturnRate:No}]"}},
+		{Name: "GetOrderbook", Call: func() (interface{}, error) { return b.GetOrderbook("btcusd", url.Values{"group": {"0"}}) },
+			Params: map[string]string{"group": "0"},
+			Want:   []string{"Bids:[{Price:574.61 Amount:0.1439327 Timestamp:1472506127.0}]", "Asks:[{Price:574.62"}},
+		{Name: "GetTrades", Call: func() (interface{}, error) { return b.GetTrades("btcusd", nil) },
+			Want: []string{"{Timestamp:1444266681 Tid:11988919 Price:244.8 Amount:0.03297384 Exchange:bitfinex Type:sell}"}},
+		{Name: "GetCandleHistory", Call: func() (interface{}, error) {
+			return b.GetCandleHistory("btcusd", time.Unix(1444266600, 0), time.Unix(1444266700, 0), time.Minute)
+		},
+			Params: map[string]string{"timestamp": "1444266600", "limit_trades": "1000"},
+			Want:   []string{"Open:244.5", "Close:244.8"}},
+		{Name: "GetLends", Call: func() (interface{}, error) { return b.GetLends("usd", nil) },
+			Want: []string{"{Rate:9.8998 AmountLent:2.252893377950878e+07 AmountUsed:0 Timestamp:1444264307}"}},
+		{Name: "GetSymbols", Call: func() (interface{}, error) { return b.GetSymbols() },
+			Want: []string{"[btcusd ltcusd ltcbtc ethusd ethbtc]"}},
+		{Name: "GetSymbolsDetails", Call: func() (interface{}, error) { return b.GetSymbolsDetails() },
+			Want: []string{"{Pair:btcusd PricePrecision:5 InitialMargin:30 MinimumMargin:15 MaximumOrderSize:2000 MinimumOrderSize:0.01 Expiration:NA}"}},
+	})
+}
+
+func TestBitfinexAuthenticatedFixtures(t *testing.T) {
+	b, f := bitfinexFixtures(t)
+	defer f.Close()
+
+	since, until := time.Unix(1444000000, 0), time.Unix(1445000000, 0)
+	runFixtureCases(t, f, []fixtureCase{
+		{Name: "GetAccountInfo", Call: func() (interface{}, error) { return b.GetAccountInfo() },
+			Params: map[string]string{"request": "/v1/account_infos"},
+			Want:   []string{"MakerFees:0.1 TakerFees:0.2 Fees:[{Pairs:BTC"}},
+		{Name: "NewDeposit", Call: func() (interface{}, error) { return b.NewDeposit("bitcoin", "exchange", 0) },
+			Params: map[string]string{"request": "/v1/deposit/new", "method": "bitcoin", "wallet_name": "exchange", "renew": "0"},
+			Want:   []string{"{Result:success Method:bitcoin Currency:BTC Address:1A2wyHKJ4KWEoahDHVxwQy3kdd6g1qiSYV}"}},
+		{Name: "NewOrder", Call: func() (interface{}, error) { return b.NewOrder("btcusd", 0.01, 0.01, true, "exchange limit", false) },
+			Params: map[string]string{"symbol": "btcusd", "amount": "0.01", "price": "0.01", "exchange": "bitfinex", "side": "buy", "type": "exchange limit"},
+			Want:   []string{"ID:448364249 Symbol:btcusd", "IsLive:true", "OriginalAmount:0.01 RemainingAmount:0.01 ExecutedAmount:0 OrderID:448364249"}},
+		{Name: "NewOrder insufficient", Call: func() (interface{}, error) { return b.NewOrder("ltcbtc", 1, 0.01, false, "exchange limit", false) },
+			Params: map[string]string{"side": "sell"},
+			Err:    ERROR_INSUFFICIENT_FUNDS},
+		{Name: "NewOrderMulti", Call: func() (interface{}, error) {
+			return b.NewOrderMulti([]BitfinexPlaceOrder{{Symbol: "btcusd", Amount: 0.01, Price: 0.01, Exchange: "bitfinex", Side: "buy", Type: "exchange limit"}})
+		},
+			Params: map[string]string{"orders": "[map[amount:0.01 exchange:bitfinex price:0.01 side:buy symbol:btcusd type:exchange limit]]"},
+			Want:   []string{"Orders:[{ID:448383727", "Status:success"}},
+		{Name: "CancelOrder", Call: func() (interface{}, error) { return b.CancelOrder(446915287) },
+			Params: map[string]string{"request": "/v1/order/cancel", "order_id": "446915287"},
+			Want:   []string{"ID:446915287", "Type:trailing stop"}},
+		{Name: "CancelMultiplateOrders", Call: func() (interface{}, error) { return b.CancelMultiplateOrders([]int64{1, 2}) },
+			Params: map[string]string{"order_ids": "[1 2]"},
+			Want:   []string{"Orders cancelled"}},
+		{Name: "CancelAllOrders", Call: func() (interface{}, error) { return b.CancelAllOrders() },
+			Params: map[string]string{"request": "/v1/order/cancel/all"},
+			Want:   []string{"All orders cancelled"}},
+		{Name: "ReplaceOrder", Call: func() (interface{}, error) {
+			return b.ReplaceOrder(448411153, "btcusd", 0.02, 0.02, true, "exchange limit", false)
+		},
+			Params: map[string]string{"request": "/v1/order/cancel/replace", "order_id": "448411153", "amount": "0.02", "side": "buy"},
+			Want:   []string{"ID:448411365", "Price:0.02"}},
+		{Name: "GetOrderStatus", Call: func() (interface{}, error) { return b.GetOrderStatus(448411153) },
+			Params: map[string]string{"request": "/v1/order/status", "order_id": "448411153"},
+			Want:   []string{"IsLive:false IsCancelled:true"}},
+		{Name: "GetOrderStatus unknown", Call: func() (interface{}, error) { return b.GetOrderStatus(1) },
+			Err: ERROR_ORDER_NOT_FOUND},
+		{Name: "GetActiveOrders", Call: func() (interface{}, error) { return b.GetActiveOrders() },
+			Want: []string{"[{ID:448411365"}},
+		{Name: "GetActivePositions", Call: func() (interface{}, error) { return b.GetActivePositions() },
+			Want: []string{"[{ID:943715 Symbol:btcusd Status:ACTIVE Base:246.94 Amount:1 Timestamp:1444141857.0 Swap:0 PL:-2.22042}]"}},
+		{Name: "ClaimPosition", Call: func() (interface{}, error) { return b.ClaimPosition(943715) },
+			Params: map[string]string{"request": "/v1/position/claim", "position_id": "943715"},
+			Want:   []string{"{ID:943715 Symbol:btcusd"}},
+		{Name: "GetBalanceHistory", Call: func() (interface{}, error) { return b.GetBalanceHistory("USD", since, until, 10, "trading") },
+			Params: map[string]string{"currency": "USD", "since": "1444000000", "until": "1445000000", "limit": "10", "wallet": "trading"},
+			Want:   []string{"{Currency:USD Amount:-246.94 Balance:515.4476526 Description:Position claimed @ 245.2 on wallet trading Timestamp:1444277602.0}"}},
+		{Name: "GetMovementHistory", Call: func() (interface{}, error) { return b.GetMovementHistory("BTC", "bitcoin", since, time.Time{}, 0) },
+			Params: map[string]string{"currency": "BTC", "method": "bitcoin", "since": "1444000000", "until": "", "limit": ""},
+			Want:   []string{"{ID:581183 Currency:BTC Method:BITCOIN Type:WITHDRAWAL Amount:0.01", "Status:COMPLETED"}},
+		{Name: "GetTradeHistory", Call: func() (interface{}, error) { return b.GetTradeHistory("btcusd", since, until, 50, 1) },
+			Params: map[string]string{"currency": "btcusd", "timestamp": "1444000000", "until": "1445000000", "limit": "50", "reverse": "1"},
+			Want:   []string{"{Price:246.94 Amount:1 Timestamp:1444141857.0 Exchange: Type:Buy FeeCurrency:USD FeeAmount:-0.49388 TID:11970839 OrderID:446913929}"}},
+		{Name: "NewOffer", Call: func() (interface{}, error) { return b.NewOffer("USD", 50, 20, 2, "lend"), nil },
+			Params: map[string]string{"currency": "USD", "amount": "50", "rate": "20", "period": "2", "direction": "lend"},
+			Want:   []string{"13800585"}},
+		{Name: "CancelOffer", Call: func() (interface{}, error) { return b.CancelOffer(13800585) },
+			Params: map[string]string{"request": "/v1/offer/cancel", "offer_id": "13800585"},
+			Want:   []string{"ID:13800585 Currency:USD Rate:20 Period:2 Direction:lend"}},
+		{Name: "GetOfferStatus", Call: func() (interface{}, error) { return b.GetOfferStatus(13800585) },
+			Params: map[string]string{"request": "/v1/offer/status", "offer_id": "13800585"},
+			Want:   []string{"IsLive:false IsCancelled:true"}},
+		{Name: "GetActiveOffers", Call: func() (interface{}, error) { return b.GetActiveOffers() },
+			Want: []string{"[{ID:13800719 Currency:USD Rate:31.39"}},
+		{Name: "GetActiveMarginFunding", Call: func() (interface{}, error) { return b.GetActiveMarginFunding() },
+			Want: []string{"[{ID:11576737 PositionID:944309 Currency:USD Rate:9.8874 Period:2 Amount:34.24603414 Timestamp:1444280948.0}]"}},
+		{Name: "GetMarginTotalTakenFunds", Call: func() (interface{}, error) { return b.GetMarginTotalTakenFunds() },
+			Want: []string{"[{PositionPair:BTCUSD TotalSwaps:34.24603414}]"}},
+		{Name: "CloseMarginFunding", Call: func() (interface{}, error) { return b.CloseMarginFunding(11576737) },
+			Params: map[string]string{"request": "/v1/funding/close", "swap_id": "11576737"},
+			Want:   []string{"RemainingAmount:0 ExecutedAmount:34.24603414"}},
+		{Name: "GetAccountBalance", Call: func() (interface{}, error) { return b.GetAccountBalance() },
+			Want: []string{"{Type:trading Currency:usd Amount:246.94 Available:0.02}"}},
+		{Name: "GetMarginInfo", Call: func() (interface{}, error) { return b.GetMarginInfo() },
+			Want: []string{"UnrealizedPL:-0.18392 UnrealizedSwap:-0.00038653", "RequiredMargin:7.3569", "MarginLimits:[{On_Pair:BTCUSD InitialMargin:30 MarginRequirement:15"}},
+		{Name: "WalletTransfer", Call: func() (interface{}, error) { return b.WalletTransfer(1, "USD", "exchange", "deposit") },
+			Params: map[string]string{"amount": "1", "currency": "USD", "walletfrom": "exchange", "walletto": "deposit"},
+			Want:   []string{"[{Status:success Message:1.0 USD transfered from Exchange to Deposit}]"}},
+		{Name: "Withdrawal", Call: func() (interface{}, error) {
+			return b.Withdrawal("bitcoin", "exchange", "1A2wyHKJ4KWEoahDHVxwQy3kdd6g1qiSYV", 0.5)
+		},
+			Params: map[string]string{"withdrawal_type": "bitcoin", "walletselected": "exchange", "amount": "0.5", "address": "1A2wyHKJ4KWEoahDHVxwQy3kdd6g1qiSYV"},
+			Want:   []string{"WithdrawalID:586829"}},
+	})
+}
+
+func TestBitfinexSigning(t *testing.T) {
+	b, f := bitfinexFixtures(t)
+	defer f.Close()
+
+	if _, err := b.GetAccountBalance(); err != nil {
+		t.Fatal(err)
+	}
+
+	req := f.Last()
+	if req.Method != "POST" || req.Path != "/v1/balances" || req.Header.Get("X-BFX-APIKEY") != "bitfinex-key" {
+		t.Error(fmt.Sprintf("Test failed. Unexpected request %s %s key %q", req.Method, req.Path, req.Header.Get("X-BFX-APIKEY")))
+	}
+	payload := req.Header.Get("X-BFX-PAYLOAD")
+	if sign := HexEncodeToString(GetHMAC(HASH_SHA512_384, []byte(payload), []byte("bitfinex-secret"))); req.Header.Get("X-BFX-SIGNATURE") != sign {
+		t.Error(fmt.Sprintf("Test failed. Expected signature %s. Actual %s", sign, req.Header.Get("X-BFX-SIGNATURE")))
+	}
+	if params := req.JSON(); params["request"] != "/v1/balances" || params["nonce"] == nil {
+		t.Error(fmt.Sprintf("Test failed. Expected request and nonce in the payload. Actual %v", params))
+	}
+}
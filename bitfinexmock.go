@@ -0,0 +1,525 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	BITFINEX_MOCK_DEPTH  = 50
+	BITFINEX_MOCK_TRADES = 50
+)
+
+// bitfinexMock serves Bitfinex's v1 REST API and websocket for a MockVenue,
+// whose pairs are Bitfinex's lower case symbols. Bitstamp uses the same
+// symbols.
+type bitfinexMock struct {
+	venue *MockVenue
+}
+
+func bitfinexMockPair(pair string) (string, string) {
+	if len(pair) != 6 {
+		return StringToUpper(pair), ""
+	}
+	return StringToUpper(pair[3:]), StringToUpper(pair[:3])
+}
+
+func bitfinexMockError(w http.ResponseWriter, message string) {
+	mockWriteJSON(w, http.StatusBadRequest, map[string]string{"message": message})
+}
+
+func bitfinexMockTimestamp(t time.Time) string {
+	return strconv.FormatFloat(float64(t.UnixNano())/1e9, 'f', 1, 64)
+}
+
+func bitfinexMockSide(buy bool) string {
+	if buy {
+		return "buy"
+	}
+	return "sell"
+}
+
+func (b *bitfinexMock) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/")
+	if r.Method == "POST" {
+		b.authenticated(w, r, path)
+		return
+	}
+
+	values := r.URL.Query()
+	switch {
+	case strings.HasPrefix(path, BITFINEX_TICKER):
+		pair := strings.TrimPrefix(path, BITFINEX_TICKER)
+		ticker, err := b.venue.Ticker(pair, 24*time.Hour)
+		if err != nil {
+			bitfinexMockError(w, "Unknown symbol")
+			return
+		}
+		mockWriteJSON(w, http.StatusOK, map[string]string{
+			"mid":        mockFormatFloat((ticker.Bid + ticker.Ask) / 2),
+			"bid":        mockFormatFloat(ticker.Bid),
+			"ask":        mockFormatFloat(ticker.Ask),
+			"last_price": mockFormatFloat(ticker.Last),
+			"low":        mockFormatFloat(ticker.Low),
+			"high":       mockFormatFloat(ticker.High),
+			"volume":     mockFormatFloat(ticker.Volume),
+			"timestamp":  bitfinexMockTimestamp(time.Now()),
+		})
+	case strings.HasPrefix(path, BITFINEX_ORDERBOOK):
+		depth, err := strconv.Atoi(values.Get("limit_bids"))
+		if err != nil {
+			depth = BITFINEX_MOCK_DEPTH
+		}
+		bids, asks, _, err := b.venue.Book(strings.TrimPrefix(path, BITFINEX_ORDERBOOK), depth)
+		if err != nil {
+			bitfinexMockError(w, "Unknown symbol")
+			return
+		}
+		levels := func(x []PaperBookLevel) []map[string]string {
+			result := []map[string]string{}
+			for _, y := range x {
+				result = append(result, map[string]string{"price": mockFormatFloat(y.Price), "amount": mockFormatFloat(y.Amount), "timestamp": bitfinexMockTimestamp(time.Now())})
+			}
+			return result
+		}
+		mockWriteJSON(w, http.StatusOK, map[string]interface{}{"bids": levels(bids), "asks": levels(asks)})
+	case strings.HasPrefix(path, BITFINEX_TRADES):
+		limit, err := strconv.Atoi(values.Get("limit_trades"))
+		if err != nil {
+			limit = BITFINEX_MOCK_TRADES
+		}
+		since, _ := strconv.ParseInt(values.Get("timestamp"), 10, 64)
+		start := time.Time{}
+		if since > 0 {
+			start = time.Unix(since, 0)
+		}
+		trades, err := b.venue.Trades(strings.TrimPrefix(path, BITFINEX_TRADES), start, time.Time{}, limit)
+		if err != nil {
+			bitfinexMockError(w, "Unknown symbol")
+			return
+		}
+		result := []BitfinexTradeStructure{}
+		for _, x := range trades {
+			result = append(result, BitfinexTradeStructure{Timestamp: x.Time.Unix(), Tid: x.TradeID, Price: mockFormatFloat(x.Price), Amount: mockFormatFloat(x.Amount), Exchange: "bitfinex", Type: bitfinexMockSide(x.Buy)})
+		}
+		mockWriteJSON(w, http.StatusOK, result)
+	case strings.TrimSuffix(path, "/") == strings.TrimSuffix(BITFINEX_SYMBOLS, "/"):
+		mockWriteJSON(w, http.StatusOK, b.venue.Pairs())
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (b *bitfinexMock) authenticated(w http.ResponseWriter, r *http.Request, path string) {
+	payload := r.Header.Get("X-BFX-PAYLOAD")
+	if r.Header.Get("X-BFX-APIKEY") != MOCK_EXCHANGE_API_KEY {
+		bitfinexMockError(w, "Could not find a key matching the given X-BFX-APIKEY.")
+		return
+	}
+	if r.Header.Get("X-BFX-SIGNATURE") != HexEncodeToString(GetHMAC(HASH_SHA512_384, []byte(payload), []byte(MOCK_EXCHANGE_API_SECRET))) {
+		bitfinexMockError(w, "Invalid X-BFX-SIGNATURE.")
+		return
+	}
+
+	data, err := Base64Decode(payload)
+	request := struct {
+		Request  string      `json:"request"`
+		Nonce    string      `json:"nonce"`
+		Symbol   string      `json:"symbol"`
+		Amount   float64     `json:"amount,string"`
+		Price    float64     `json:"price,string"`
+		Side     string      `json:"side"`
+		Type     string      `json:"type"`
+		PostOnly bool        `json:"is_postonly"`
+		OrderID  json.Number `json:"order_id"`
+	}{}
+	if err != nil || json.Unmarshal(data, &request) != nil || request.Request != "/v"+BITFINEX_API_VERSION+"/"+path {
+		bitfinexMockError(w, "Invalid X-BFX-PAYLOAD.")
+		return
+	}
+	nonce, _ := strconv.ParseInt(request.Nonce, 10, 64)
+	if last, ok := b.venue.CheckNonce(nonce); !ok {
+		bitfinexMockError(w, "Nonce is too small. Last nonce was "+strconv.FormatInt(last, 10)+".")
+		return
+	}
+	orderID, _ := request.OrderID.Int64()
+
+	switch path {
+	case BITFINEX_ORDER_NEW:
+		buy := request.Side == "buy"
+		margin := !strings.HasPrefix(request.Type, "exchange")
+		immediate := strings.HasSuffix(request.Type, "market") || strings.HasSuffix(request.Type, "fill-or-kill")
+		price := request.Price
+		if strings.HasSuffix(request.Type, "market") {
+			price = math.MaxFloat64
+			if !buy {
+				price = math.SmallestNonzeroFloat64
+			}
+		}
+
+		order, err := b.venue.PlaceOrder(request.Symbol, price, request.Amount, buy, margin, request.PostOnly, immediate)
+		switch err {
+		case nil:
+			mockWriteJSON(w, http.StatusOK, b.order(order, request.Type))
+		case ErrPaperInsufficientFunds:
+			bitfinexMockError(w, "Invalid order: not enough exchange balance for "+mockFormatFloat(request.Amount)+" "+StringToUpper(request.Symbol)+" at "+mockFormatFloat(request.Price))
+		case ErrPaperPostOnly:
+			bitfinexMockError(w, "Invalid order: post only order would have taken liquidity.")
+		case ErrMockExchangeUnknownPair:
+			bitfinexMockError(w, "Invalid order: unknown symbol")
+		default:
+			bitfinexMockError(w, "Invalid order: "+err.Error())
+		}
+	case BITFINEX_ORDER_CANCEL:
+		if err := b.venue.CancelOrder(orderID); err != nil {
+			bitfinexMockError(w, "Order could not be cancelled.")
+			return
+		}
+		order, _ := b.venue.Order(orderID)
+		mockWriteJSON(w, http.StatusOK, b.order(order, ""))
+	case BITFINEX_ORDER_STATUS:
+		order, err := b.venue.Order(orderID)
+		if err != nil {
+			bitfinexMockError(w, "No such order found.")
+			return
+		}
+		mockWriteJSON(w, http.StatusOK, b.order(order, ""))
+	case BITFINEX_ORDERS:
+		result := []map[string]interface{}{}
+		for _, x := range b.venue.OpenOrders("") {
+			result = append(result, b.order(x, ""))
+		}
+		mockWriteJSON(w, http.StatusOK, result)
+	case BITFINEX_BALANCES:
+		held := b.venue.Held()
+		result := []map[string]string{}
+		for _, account := range []string{PAPER_ACCOUNT_EXCHANGE, PAPER_ACCOUNT_MARGIN} {
+			for currency, x := range b.venue.Balances()[account] {
+				wallet, total := "exchange", x
+				if account == PAPER_ACCOUNT_MARGIN {
+					wallet = "trading"
+				} else {
+					total += held[currency]
+				}
+				result = append(result, map[string]string{"type": wallet, "currency": strings.ToLower(currency), "amount": mockFormatFloat(total), "available": mockFormatFloat(x)})
+			}
+		}
+		mockWriteJSON(w, http.StatusOK, result)
+	case BITFINEX_POSITIONS:
+		result := []map[string]interface{}{}
+		for _, x := range b.venue.Pairs() {
+			position, err := b.venue.Position(x)
+			if err != nil || position.Amount == 0 {
+				continue
+			}
+			result = append(result, map[string]interface{}{
+				"id":        len(result) + 1,
+				"symbol":    x,
+				"status":    "ACTIVE",
+				"base":      mockFormatFloat(position.BasePrice),
+				"amount":    mockFormatFloat(position.Amount),
+				"timestamp": bitfinexMockTimestamp(time.Now()),
+				"swap":      mockFormatFloat(position.LendingFees),
+				"pl":        mockFormatFloat(position.ProfitLoss),
+			})
+		}
+		mockWriteJSON(w, http.StatusOK, result)
+	case BITFINEX_TRADE_HISTORY:
+		b.tradeHistory(w, data)
+	default:
+		bitfinexMockError(w, "Unknown request.")
+	}
+}
+
+// tradeHistory returns the account's trades on the requested currency pair,
+// newest first.
+func (b *bitfinexMock) tradeHistory(w http.ResponseWriter, data []byte) {
+	request := struct {
+		Currency string `json:"currency"`
+	}{}
+	json.Unmarshal(data, &request)
+
+	result := []BitfinexTradeHistory{}
+	orders := b.venue.Orders(request.Currency)
+	for i := len(orders) - 1; i >= 0; i-- {
+		for j := len(orders[i].Fills) - 1; j >= 0; j-- {
+			x := orders[i].Fills[j]
+			base, quote := b.venue.Split(x.Pair)
+			feeCurrency := base
+			if x.Buy && !orders[i].Margin {
+				feeCurrency = quote
+			}
+			result = append(result, BitfinexTradeHistory{
+				Price:       x.Price,
+				Amount:      x.Amount,
+				Timestamp:   bitfinexMockTimestamp(x.Time),
+				Exchange:    "bitfinex",
+				Type:        strings.Title(bitfinexMockSide(x.Buy)),
+				FeeCurrency: feeCurrency,
+				FeeAmount:   -x.Fee,
+				TID:         x.TradeID,
+				OrderID:     x.OrderID,
+			})
+		}
+	}
+	mockWriteJSON(w, http.StatusOK, result)
+}
+
+// order renders order as Bitfinex does. The mock doesn't keep order types,
+// so one is made up unless given.
+func (b *bitfinexMock) order(order PaperOrder, orderType string) map[string]interface{} {
+	if orderType == "" {
+		orderType = "limit"
+		if !order.Margin {
+			orderType = "exchange limit"
+		}
+	}
+	var average float64
+	if order.Filled > 0 {
+		for _, x := range order.Fills {
+			average += x.Total
+		}
+		average /= order.Filled
+	}
+	return map[string]interface{}{
+		"id":                  order.OrderID,
+		"order_id":            order.OrderID,
+		"symbol":              order.Pair,
+		"exchange":            "bitfinex",
+		"price":               mockFormatFloat(order.Price),
+		"avg_execution_price": mockFormatFloat(average),
+		"side":                bitfinexMockSide(order.Buy),
+		"type":                orderType,
+		"timestamp":           bitfinexMockTimestamp(order.Time),
+		"is_live":             order.Open,
+		"is_cancelled":        !order.Open && order.Filled < order.Amount,
+		"is_hidden":           false,
+		"was_forced":          false,
+		"original_amount":     mockFormatFloat(order.Amount),
+		"remaining_amount":    mockFormatFloat(order.Amount - order.Filled),
+		"executed_amount":     mockFormatFloat(order.Filled),
+	}
+}
+
+var mockUpgrader = websocket.Upgrader{CheckOrigin: func(r *http.Request) bool { return true }}
+
+type bitfinexMockChannel struct {
+	Channel string
+	Pair    string
+	book    map[float64]float64 // the levels last sent, asks negative
+}
+
+// websocket serves the v1 websocket's book, trades and ticker channels, and
+// the account snapshots once authenticated.
+func (b *bitfinexMock) websocket(w http.ResponseWriter, r *http.Request) {
+	conn, err := mockUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	events := b.venue.Subscribe()
+	defer b.venue.Unsubscribe(events)
+
+	requests := make(chan map[string]interface{})
+	go func() {
+		defer close(requests)
+		for {
+			request := make(map[string]interface{})
+			if err := conn.ReadJSON(&request); err != nil {
+				return
+			}
+			requests <- request
+		}
+	}()
+
+	if conn.WriteJSON(map[string]interface{}{"event": "info", "version": 1}) != nil {
+		return
+	}
+
+	channels := make(map[int]*bitfinexMockChannel)
+	heartbeat := time.NewTicker(MOCK_EXCHANGE_HEARTBEAT)
+	defer heartbeat.Stop()
+
+	for {
+		messages := []interface{}{}
+		select {
+		case request, ok := <-requests:
+			if !ok {
+				return
+			}
+			messages = b.websocketRequest(request, channels)
+		case event := <-events:
+			for id, x := range channels {
+				if StringToUpper(event.Pair) == x.Pair {
+					messages = append(messages, b.websocketUpdate(id, x, event)...)
+				}
+			}
+		case <-heartbeat.C:
+			for id := range channels {
+				messages = append(messages, []interface{}{id, BITFINEX_WEBSOCKET_HEARTBEAT})
+			}
+		}
+
+		for _, x := range messages {
+			if conn.WriteJSON(x) != nil {
+				return
+			}
+		}
+	}
+}
+
+func (b *bitfinexMock) websocketRequest(request map[string]interface{}, channels map[int]*bitfinexMockChannel) []interface{} {
+	switch request["event"] {
+	case "ping":
+		return []interface{}{map[string]string{"event": "pong"}}
+	case "subscribe":
+		channel, _ := request["channel"].(string)
+		pair, _ := request["pair"].(string)
+		pair = StringToUpper(pair)
+		if _, err := b.venue.Ticker(strings.ToLower(pair), 0); err != nil || (channel != "book" && channel != "trades" && channel != "ticker") {
+			return []interface{}{map[string]interface{}{"event": "error", "msg": "Could not subscribe", "code": 10300, "channel": channel, "pair": pair}}
+		}
+
+		id := len(channels) + 1
+		channels[id] = &bitfinexMockChannel{Channel: channel, Pair: pair, book: make(map[float64]float64)}
+		messages := []interface{}{map[string]interface{}{"event": "subscribed", "channel": channel, "chanId": id, "pair": pair}}
+		return append(messages, b.websocketSnapshot(id, channels[id])...)
+	case "auth":
+		payload, _ := request["authPayload"].(string)
+		if request["apiKey"] != MOCK_EXCHANGE_API_KEY || request["authSig"] != HexEncodeToString(GetHMAC(HASH_SHA512_384, []byte(payload), []byte(MOCK_EXCHANGE_API_SECRET))) {
+			return []interface{}{map[string]interface{}{"event": "auth", "status": "FAILED", "chanId": 0, "code": 10100}}
+		}
+		return append([]interface{}{map[string]interface{}{"event": "auth", "status": "OK", "chanId": 0, "userId": 1}}, b.accountSnapshots()...)
+	case "unauth":
+		return []interface{}{map[string]interface{}{"event": "unauth", "status": "OK", "chanId": 0}}
+	}
+	return []interface{}{map[string]interface{}{"event": "error", "msg": "Unknown event", "code": 10000}}
+}
+
+func (b *bitfinexMock) websocketSnapshot(id int, channel *bitfinexMockChannel) []interface{} {
+	pair := strings.ToLower(channel.Pair)
+	switch channel.Channel {
+	case "book":
+		return []interface{}{[]interface{}{id, b.bookChanges(channel, pair)}}
+	case "trades":
+		trades, _ := b.venue.Trades(pair, time.Time{}, time.Time{}, BITFINEX_MOCK_TRADES)
+		snapshot := [][]interface{}{}
+		for _, x := range trades {
+			snapshot = append(snapshot, bitfinexMockTrade(x))
+		}
+		return []interface{}{[]interface{}{id, snapshot}}
+	}
+	return []interface{}{b.ticker(id, pair)}
+}
+
+func (b *bitfinexMock) websocketUpdate(id int, channel *bitfinexMockChannel, event MockEvent) []interface{} {
+	switch channel.Channel {
+	case "book":
+		messages := []interface{}{}
+		for _, x := range b.bookChanges(channel, event.Pair) {
+			messages = append(messages, append([]interface{}{id}, x...))
+		}
+		return messages
+	case "trades":
+		messages := []interface{}{}
+		for _, x := range event.Trades {
+			messages = append(messages, append([]interface{}{id}, bitfinexMockTrade(x)...))
+		}
+		return messages
+	}
+	if len(event.Trades) == 0 {
+		return nil
+	}
+	return []interface{}{b.ticker(id, event.Pair)}
+}
+
+func bitfinexMockTrade(trade PaperFill) []interface{} {
+	amount := trade.Amount
+	if !trade.Buy {
+		amount = -amount
+	}
+	return []interface{}{trade.TradeID, trade.Time.Unix(), trade.Price, amount}
+}
+
+func (b *bitfinexMock) ticker(id int, pair string) []interface{} {
+	ticker, _ := b.venue.Ticker(pair, 24*time.Hour)
+	change := ticker.Last - ticker.Open
+	return []interface{}{id, ticker.Bid, ticker.BidSize, ticker.Ask, ticker.AskSize, change, change / ticker.Open, ticker.Last, ticker.Volume, ticker.High, ticker.Low}
+}
+
+// bookChanges returns the [price, count, amount] levels which changed since
+// those last sent on channel, with a count of 0 for those removed. Counts
+// aren't kept, so a level is always one order.
+func (b *bitfinexMock) bookChanges(channel *bitfinexMockChannel, pair string) [][]interface{} {
+	bids, asks, _, _ := b.venue.Book(pair, BITFINEX_MOCK_DEPTH)
+	book := make(map[float64]float64)
+	for _, x := range bids {
+		book[x.Price] = x.Amount
+	}
+	for _, x := range asks {
+		book[x.Price] = -x.Amount
+	}
+
+	changes := [][]interface{}{}
+	for _, x := range append(bids, asks...) {
+		if channel.book[x.Price] != book[x.Price] {
+			changes = append(changes, []interface{}{x.Price, 1, book[x.Price]})
+		}
+	}
+	for price, amount := range channel.book {
+		if _, ok := book[price]; !ok {
+			if amount < 0 {
+				changes = append(changes, []interface{}{price, 0, -1})
+			} else {
+				changes = append(changes, []interface{}{price, 0, 1})
+			}
+		}
+	}
+	channel.book = book
+	return changes
+}
+
+// accountSnapshots are the position, wallet and order snapshots sent on
+// channel 0 after authenticating.
+func (b *bitfinexMock) accountSnapshots() []interface{} {
+	positions := [][]interface{}{}
+	for _, x := range b.venue.Pairs() {
+		position, err := b.venue.Position(x)
+		if err == nil && position.Amount != 0 {
+			positions = append(positions, []interface{}{StringToUpper(x), "ACTIVE", position.Amount, position.BasePrice, 0, 0})
+		}
+	}
+
+	wallets := [][]interface{}{}
+	for _, account := range []string{PAPER_ACCOUNT_EXCHANGE, PAPER_ACCOUNT_MARGIN} {
+		for currency, x := range b.venue.Balances()[account] {
+			wallet := "exchange"
+			if account == PAPER_ACCOUNT_MARGIN {
+				wallet = "trading"
+			}
+			wallets = append(wallets, []interface{}{wallet, currency, x, 0})
+		}
+	}
+
+	orders := [][]interface{}{}
+	for _, x := range b.venue.OpenOrders("") {
+		amount, original := x.Amount-x.Filled, x.Amount
+		if !x.Buy {
+			amount, original = -amount, -original
+		}
+		orders = append(orders, []interface{}{x.OrderID, StringToUpper(x.Pair), amount, original, StringToUpper(b.order(x, "")["type"].(string)), "ACTIVE", x.Price, 0, x.Time.UTC().Format(time.RFC3339)})
+	}
+
+	return []interface{}{
+		[]interface{}{0, BITFINEX_WEBSOCKET_POSITION_SNAPSHOT, positions},
+		[]interface{}{0, BITFINEX_WEBSOCKET_WALLET_SNAPSHOT, wallets},
+		[]interface{}{0, BITFINEX_WEBSOCKET_ORDER_SNAPSHOT, orders},
+	}
+}
@@ -1,11 +1,14 @@
 package main
 
 import (
+	"fmt"
 	"github.com/gorilla/websocket"
 	"log"
+	"math"
 	"net/http"
 	"reflect"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -23,6 +26,7 @@ const (
 	BITFINEX_WEBSOCKET_ORDER_UPDATE      = "ou"
 	BITFINEX_WEBSOCKET_ORDER_CANCEL      = "oc"
 	BITFINEX_WEBSOCKET_TRADE_EXECUTED    = "te"
+	BITFINEX_WEBSOCKET_TRADE_UPDATE      = "tu"
 	BITFINEX_WEBSOCKET_HEARTBEAT         = "hb"
 )
 
@@ -85,12 +89,24 @@ type BitfinexWebsocketOrder struct {
 }
 
 type BitfinexWebsocketTradeExecuted struct {
+	Sequence       string
+	Pair           string
+	Timestamp      int64
+	OrderID        int64
+	AmountExecuted float64
+	PriceExecuted  float64
+}
+
+type BitfinexWebsocketTradeUpdate struct {
+	Sequence       string
 	TradeID        int64
 	Pair           string
 	Timestamp      int64
 	OrderID        int64
 	AmountExecuted float64
 	PriceExecuted  float64
+	Fee            float64
+	FeeCurrency    string
 }
 
 func (b *Bitfinex) WebsocketPingHandler() error {
@@ -105,26 +121,32 @@ func (b *Bitfinex) WebsocketSend(data interface{}) error {
 		return err
 	}
 
-	err = b.WebsocketConn.WriteMessage(websocket.TextMessage, json)
+	return GetWebsocketSupervisor(b.GetName()).Write(func() error {
+		return b.WebsocketConn.WriteMessage(websocket.TextMessage, json)
+	})
+}
 
+// WebsocketSubscribe records the subscription so it is replayed on reconnect,
+// sending it now if connected.
+func (b *Bitfinex) WebsocketSubscribe(channel string, params map[string]string) {
+	err := GetWebsocketSupervisor(b.GetName()).Subscribe(WebsocketSubscription{Channel: channel, Params: params})
 	if err != nil {
-		return err
+		log.Println(err)
 	}
-	return nil
 }
 
-func (b *Bitfinex) WebsocketSubscribe(channel string, params map[string]string) {
+func (b *Bitfinex) WebsocketSendSubscribe(sub WebsocketSubscription) error {
 	request := make(map[string]string)
 	request["event"] = "subscribe"
-	request["channel"] = channel
+	request["channel"] = sub.Channel
 
-	if len(params) > 0 {
-		for k, v := range params {
+	if len(sub.Params) > 0 {
+		for k, v := range sub.Params {
 			request[k] = v
 		}
 	}
 
-	b.WebsocketSend(request)
+	return b.WebsocketSend(request)
 }
 
 func (b *Bitfinex) WebsocketSendAuth() error {
@@ -154,196 +176,329 @@ func (b *Bitfinex) WebsocketAddSubscriptionChannel(chanID int, channel, pair str
 
 func (b *Bitfinex) WebsocketClient() {
 	channels := []string{"book", "trades", "ticker"}
-	for b.Enabled && b.Websocket {
-		var Dialer websocket.Dialer
-		var err error
-		b.WebsocketConn, _, err = Dialer.Dial(BITFINEX_WEBSOCKET, http.Header{})
-
-		if err != nil {
-			log.Printf("%s Unable to connect to Websocket. Error: %s\n", b.GetName(), err)
-			continue
+	for _, x := range channels {
+		for _, y := range b.EnabledPairs {
+			params := make(map[string]string)
+			if x == "book" {
+				params["prec"] = "P0"
+			}
+			params["pair"] = y
+			b.WebsocketSubscribe(x, params)
 		}
+	}
 
-		msgType, resp, err := b.WebsocketConn.ReadMessage()
-		if msgType != websocket.TextMessage {
-			continue
-		}
+	s := GetWebsocketSupervisor(b.GetName())
+	s.Verbose = b.Verbose
+	s.Running = func() bool { return b.Enabled && b.Websocket }
+	s.Dial = b.WebsocketConnect
+	s.Serve = b.WebsocketServe
+	s.Send = b.WebsocketSendSubscribe
+	s.Close = func() error { return b.WebsocketConn.Close() }
+	s.Run()
+}
 
-		type WebsocketHandshake struct {
-			Event   string `json:"event"`
-			Code    int64  `json:"code"`
-			Version int    `json:"version"`
-		}
+// WebsocketConnect dials and waits for the info handshake. Channel IDs are
+// reassigned on every connection so the subscribed channels are reset.
+func (b *Bitfinex) WebsocketConnect() error {
+	var Dialer websocket.Dialer
+	var err error
+	b.WebsocketConn, _, err = Dialer.Dial(b.WebsocketURL, http.Header{})
+	if err != nil {
+		return err
+	}
+
+	type WebsocketHandshake struct {
+		Event   string `json:"event"`
+		Code    int64  `json:"code"`
+		Version int    `json:"version"`
+	}
 
-		hs := WebsocketHandshake{}
+	_, resp, err := b.WebsocketConn.ReadMessage()
+	hs := WebsocketHandshake{}
+	if err == nil {
 		err = JSONDecode(resp, &hs)
+	}
+	if err != nil {
+		b.WebsocketConn.Close()
+		return err
+	}
+
+	if hs.Event == "info" && b.Verbose {
+		log.Printf("%s Websocket API version %d.\n", b.GetName(), hs.Version)
+	}
+
+	b.WebsocketSubdChannels = make(map[int]BitfinexWebsocketChanInfo)
+	if b.AuthenticatedAPISupport {
+		err = b.WebsocketSendAuth()
 		if err != nil {
 			log.Println(err)
-			continue
-		}
-
-		if hs.Event == "info" {
-			if b.Verbose {
-				log.Printf("%s Connected to Websocket.\n", b.GetName())
-			}
 		}
+	}
+	return nil
+}
 
-		for _, x := range channels {
-			for _, y := range b.EnabledPairs {
-				params := make(map[string]string)
-				if x == "book" {
-					params["prec"] = "P0"
-				}
-				params["pair"] = y
-				b.WebsocketSubscribe(x, params)
-			}
-		}
+func (b *Bitfinex) WebsocketServe() error {
+	s := GetWebsocketSupervisor(b.GetName())
 
-		if b.AuthenticatedAPISupport {
-			err = b.WebsocketSendAuth()
-			if err != nil {
-				log.Println(err)
-			}
+	for b.Enabled && b.Websocket {
+		msgType, resp, err := b.WebsocketConn.ReadMessage()
+		if err != nil {
+			return err
 		}
+		s.Received()
 
-		for b.Enabled && b.Websocket {
-			msgType, resp, err := b.WebsocketConn.ReadMessage()
+		switch msgType {
+		case websocket.TextMessage:
+			var result interface{}
+			err := JSONDecode(resp, &result)
 			if err != nil {
 				log.Println(err)
-				break
+				continue
 			}
 
-			switch msgType {
-			case websocket.TextMessage:
-				var result interface{}
-				err := JSONDecode(resp, &result)
-				if err != nil {
-					log.Println(err)
-					continue
+			switch reflect.TypeOf(result).String() {
+			case "map[string]interface {}":
+				eventData := result.(map[string]interface{})
+				event := eventData["event"]
+
+				switch event {
+				case "subscribed":
+					b.WebsocketAddSubscriptionChannel(int(eventData["chanId"].(float64)), eventData["channel"].(string), eventData["pair"].(string))
+				case "auth":
+					status := eventData["status"].(string)
+
+					if status == "OK" {
+						b.WebsocketAddSubscriptionChannel(0, "account", "N/A")
+					} else if status == "fail" {
+						log.Printf("%s Websocket unable to AUTH. Error code: %s\n", b.GetName(), eventData["code"].(string))
+						b.AuthenticatedAPISupport = false
+					}
 				}
-
-				switch reflect.TypeOf(result).String() {
-				case "map[string]interface {}":
-					eventData := result.(map[string]interface{})
-					event := eventData["event"]
-
-					switch event {
-					case "subscribed":
-						b.WebsocketAddSubscriptionChannel(int(eventData["chanId"].(float64)), eventData["channel"].(string), eventData["pair"].(string))
-					case "auth":
-						status := eventData["status"].(string)
-
-						if status == "OK" {
-							b.WebsocketAddSubscriptionChannel(0, "account", "N/A")
-						} else if status == "fail" {
-							log.Printf("%s Websocket unable to AUTH. Error code: %s\n", b.GetName(), eventData["code"].(string))
-							b.AuthenticatedAPISupport = false
+			case "[]interface {}":
+				chanData := result.([]interface{})
+				chanID := int(chanData[0].(float64))
+				chanInfo, ok := b.WebsocketSubdChannels[chanID]
+
+				if !ok {
+					log.Println("Unable to locate chanID: %d", chanID)
+				} else {
+					if len(chanData) == 2 {
+						if reflect.TypeOf(chanData[1]).String() == "string" {
+							if chanData[1].(string) == BITFINEX_WEBSOCKET_HEARTBEAT {
+								continue
+							}
 						}
 					}
-				case "[]interface {}":
-					chanData := result.([]interface{})
-					chanID := int(chanData[0].(float64))
-					chanInfo, ok := b.WebsocketSubdChannels[chanID]
-
-					if !ok {
-						log.Println("Unable to locate chanID: %d", chanID)
-					} else {
-						if len(chanData) == 2 {
-							if reflect.TypeOf(chanData[1]).String() == "string" {
-								if chanData[1].(string) == BITFINEX_WEBSOCKET_HEARTBEAT {
-									continue
-								}
+					switch chanInfo.Channel {
+					case "book":
+						orderbook := []BitfinexWebsocketBook{}
+						switch len(chanData) {
+						case 2:
+							data := chanData[1].([]interface{})
+							for _, x := range data {
+								y := x.([]interface{})
+								orderbook = append(orderbook, BitfinexWebsocketBook{Price: y[0].(float64), Count: int(y[1].(float64)), Amount: y[2].(float64)})
 							}
+						case 4:
+							orderbook = append(orderbook, BitfinexWebsocketBook{Price: chanData[1].(float64), Count: int(chanData[2].(float64)), Amount: chanData[3].(float64)})
 						}
-						switch chanInfo.Channel {
-						case "book":
-							orderbook := []BitfinexWebsocketBook{}
-							switch len(chanData) {
-							case 2:
-								data := chanData[1].([]interface{})
-								for _, x := range data {
-									y := x.([]interface{})
-									orderbook = append(orderbook, BitfinexWebsocketBook{Price: y[0].(float64), Count: int(y[1].(float64)), Amount: y[2].(float64)})
-								}
-							case 4:
-								orderbook = append(orderbook, BitfinexWebsocketBook{Price: chanData[1].(float64), Count: int(chanData[2].(float64)), Amount: chanData[3].(float64)})
-							}
-						case "ticker":
-							ticker := BitfinexWebsocketTicker{Bid: chanData[1].(float64), BidSize: chanData[2].(float64), Ask: chanData[3].(float64), AskSize: chanData[4].(float64),
-								DailyChange: chanData[5].(float64), DialyChangePerc: chanData[6].(float64), LastPrice: chanData[7].(float64), Volume: chanData[8].(float64)}
-
-							log.Printf("Bitfinex %s Websocket Last %f Volume %f\n", chanInfo.Pair, ticker.LastPrice, ticker.Volume)
-						case "account":
-							switch chanData[1].(string) {
-							case BITFINEX_WEBSOCKET_POSITION_SNAPSHOT:
-								positionSnapshot := []BitfinexWebsocketPosition{}
-								data := chanData[2].([]interface{})
-								for _, x := range data {
-									y := x.([]interface{})
-									positionSnapshot = append(positionSnapshot, BitfinexWebsocketPosition{Pair: y[0].(string), Status: y[1].(string), Amount: y[2].(float64), Price: y[3].(float64),
-										MarginFunding: y[4].(float64), MarginFundingType: int(y[5].(float64))})
-								}
-								log.Println(positionSnapshot)
-							case BITFINEX_WEBSOCKET_POSITION_NEW, BITFINEX_WEBSOCKET_POSITION_UPDATE, BITFINEX_WEBSOCKET_POSITION_CLOSE:
-								data := chanData[2].([]interface{})
-								position := BitfinexWebsocketPosition{Pair: data[0].(string), Status: data[1].(string), Amount: data[2].(float64), Price: data[3].(float64),
-									MarginFunding: data[4].(float64), MarginFundingType: int(data[5].(float64))}
-								log.Println(position)
-							case BITFINEX_WEBSOCKET_WALLET_SNAPSHOT:
-								data := chanData[2].([]interface{})
-								walletSnapshot := []BitfinexWebsocketWallet{}
-								for _, x := range data {
-									y := x.([]interface{})
-									walletSnapshot = append(walletSnapshot, BitfinexWebsocketWallet{Name: y[0].(string), Currency: y[1].(string), Balance: y[2].(float64), UnsettledInterest: y[3].(float64)})
-								}
-								log.Println(walletSnapshot)
-							case BITFINEX_WEBSOCKET_WALLET_UPDATE:
-								data := chanData[2].([]interface{})
-								wallet := BitfinexWebsocketWallet{Name: data[0].(string), Currency: data[1].(string), Balance: data[2].(float64), UnsettledInterest: data[3].(float64)}
-								log.Println(wallet)
-							case BITFINEX_WEBSOCKET_ORDER_SNAPSHOT:
-								orderSnapshot := []BitfinexWebsocketOrder{}
-								data := chanData[2].([]interface{})
-								for _, x := range data {
-									y := x.([]interface{})
-									orderSnapshot = append(orderSnapshot, BitfinexWebsocketOrder{OrderID: int64(y[0].(float64)), Pair: y[1].(string), Amount: y[2].(float64), OrigAmount: y[3].(float64),
-										OrderType: y[4].(string), Status: y[5].(string), Price: y[6].(float64), PriceAvg: y[7].(float64), Timestamp: y[8].(string)})
-								}
-								log.Println(orderSnapshot)
-							case BITFINEX_WEBSOCKET_ORDER_NEW, BITFINEX_WEBSOCKET_ORDER_UPDATE, BITFINEX_WEBSOCKET_ORDER_CANCEL:
-								data := chanData[2].([]interface{})
-								order := BitfinexWebsocketOrder{OrderID: int64(data[0].(float64)), Pair: data[1].(string), Amount: data[2].(float64), OrigAmount: data[3].(float64),
-									OrderType: data[4].(string), Status: data[5].(string), Price: data[6].(float64), PriceAvg: data[7].(float64), Timestamp: data[8].(string), Notify: int(data[9].(float64))}
-								log.Println(order)
-							case BITFINEX_WEBSOCKET_TRADE_EXECUTED:
-								data := chanData[2].([]interface{})
-								trade := BitfinexWebsocketTradeExecuted{TradeID: int64(data[0].(float64)), Pair: data[1].(string), Timestamp: int64(data[2].(float64)), OrderID: int64(data[3].(float64)),
-									AmountExecuted: data[4].(float64), PriceExecuted: data[5].(float64)}
-								log.Println(trade)
+					case "ticker":
+						ticker := BitfinexWebsocketTicker{Bid: chanData[1].(float64), BidSize: chanData[2].(float64), Ask: chanData[3].(float64), AskSize: chanData[4].(float64),
+							DailyChange: chanData[5].(float64), DialyChangePerc: chanData[6].(float64), LastPrice: chanData[7].(float64), Volume: chanData[8].(float64)}
+
+						log.Printf("Bitfinex %s Websocket Last %f Volume %f\n", chanInfo.Pair, ticker.LastPrice, ticker.Volume)
+					case "account":
+						b.WebsocketHandleAccount(chanData)
+					case "trades":
+						trades := []BitfinexWebsocketTrade{}
+						switch len(chanData) {
+						case 2:
+							data := chanData[1].([]interface{})
+							for _, x := range data {
+								y := x.([]interface{})
+								trades = append(trades, BitfinexWebsocketTrade{ID: int64(y[0].(float64)), Timestamp: int64(y[1].(float64)), Price: y[2].(float64), Amount: y[3].(float64)})
 							}
-						case "trades":
-							trades := []BitfinexWebsocketTrade{}
-							switch len(chanData) {
-							case 2:
-								data := chanData[1].([]interface{})
-								for _, x := range data {
-									y := x.([]interface{})
-									trades = append(trades, BitfinexWebsocketTrade{ID: int64(y[0].(float64)), Timestamp: int64(y[1].(float64)), Price: y[2].(float64), Amount: y[3].(float64)})
-								}
-							case 5:
-								trade := BitfinexWebsocketTrade{ID: int64(chanData[1].(float64)), Timestamp: int64(chanData[2].(float64)), Price: chanData[3].(float64), Amount: chanData[4].(float64)}
-								trades = append(trades, trade)
-
-								if b.Verbose {
-									log.Printf("Bitfinex %s Websocket Trade ID %d Timestamp %d Price %f Amount %f\n", chanInfo.Pair, trade.ID, trade.Timestamp, trade.Price, trade.Amount)
-								}
+						case 5:
+							trade := BitfinexWebsocketTrade{ID: int64(chanData[1].(float64)), Timestamp: int64(chanData[2].(float64)), Price: chanData[3].(float64), Amount: chanData[4].(float64)}
+							trades = append(trades, trade)
+							CandleAddTrade(b.GetName(), chanInfo.Pair, trade.Price, math.Abs(trade.Amount), time.Unix(trade.Timestamp, 0))
+
+							if b.Verbose {
+								log.Printf("Bitfinex %s Websocket Trade ID %d Timestamp %d Price %f Amount %f\n", chanInfo.Pair, trade.ID, trade.Timestamp, trade.Price, trade.Amount)
 							}
 						}
 					}
 				}
 			}
 		}
-		b.WebsocketConn.Close()
-		log.Printf("%s Websocket client disconnected.\n", b.GetName())
+	}
+	return nil
+}
+
+// bitfinexWebsocketFields checks the fields of an account message are the
+// expected types, f for numbers, s for strings and ? for either.
+func bitfinexWebsocketFields(data []interface{}, types string) bool {
+	if len(data) < len(types) {
+		return false
+	}
+	for i, x := range types {
+		switch x {
+		case 'f':
+			if _, ok := data[i].(float64); !ok {
+				return false
+			}
+		case 's':
+			if _, ok := data[i].(string); !ok {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func bitfinexWebsocketOrder(data []interface{}) (BitfinexWebsocketOrder, bool) {
+	if !bitfinexWebsocketFields(data, "fsffssffs") {
+		return BitfinexWebsocketOrder{}, false
+	}
+	order := BitfinexWebsocketOrder{OrderID: int64(data[0].(float64)), Pair: data[1].(string), Amount: data[2].(float64), OrigAmount: data[3].(float64),
+		OrderType: data[4].(string), Status: data[5].(string), Price: data[6].(float64), PriceAvg: data[7].(float64), Timestamp: data[8].(string)}
+	if len(data) > 9 {
+		if notify, ok := data[9].(float64); ok {
+			order.Notify = int(notify)
+		}
+	}
+	return order, true
+}
+
+// BitfinexOrderStatus maps an order's status, such as "EXECUTED @ 412.0(1.0):
+// was PARTIALLY FILLED @ 411.9(0.5)", to the OMS status it ended up in.
+func BitfinexOrderStatus(status string) (string, bool) {
+	switch {
+	case strings.HasPrefix(status, "ACTIVE"):
+		return ORDER_STATUS_ACKNOWLEDGED, true
+	case strings.HasPrefix(status, "PARTIALLY FILLED"):
+		return ORDER_STATUS_PARTIALLY_FILLED, true
+	case strings.HasPrefix(status, "EXECUTED"):
+		return ORDER_STATUS_FILLED, true
+	case strings.HasPrefix(status, "CANCELED"), strings.HasPrefix(status, "INSUFFICIENT"):
+		return ORDER_STATUS_CANCELLED, true
+	}
+	return "", false
+}
+
+func (b *Bitfinex) publishWebsocketOrder(order BitfinexWebsocketOrder) {
+	status, ok := BitfinexOrderStatus(order.Status)
+	if !ok {
+		log.Printf("%s Websocket unknown status %q for order %d\n", b.GetName(), order.Status, order.OrderID)
+		return
+	}
+	PublishOrderEvent(OrderEvent{
+		Exchange:        b.GetName(),
+		ExchangeOrderID: strconv.FormatInt(order.OrderID, 10),
+		Pair:            order.Pair,
+		Buy:             order.OrigAmount > 0,
+		Status:          status,
+		Amount:          math.Abs(order.OrigAmount),
+		Remaining:       math.Abs(order.Amount),
+		Price:           order.Price,
+		AveragePrice:    order.PriceAvg,
+		Time:            time.Now(),
+	})
+}
+
+// WebsocketHandleAccount turns the authenticated channel's order, trade and
+// wallet messages into OMS events. Fills are taken from tu rather than te as
+// only the update carries the trade ID and fee, and booking both would count
+// every trade twice.
+func (b *Bitfinex) WebsocketHandleAccount(chanData []interface{}) {
+	if len(chanData) < 3 {
+		return
+	}
+	event, _ := chanData[1].(string)
+	data, ok := chanData[2].([]interface{})
+	if !ok {
+		log.Printf("%s Websocket unexpected %s message: %v\n", b.GetName(), event, chanData)
+		return
+	}
+
+	switch event {
+	case BITFINEX_WEBSOCKET_POSITION_SNAPSHOT:
+		positionSnapshot := []BitfinexWebsocketPosition{}
+		for _, x := range data {
+			y, ok := x.([]interface{})
+			if !ok || !bitfinexWebsocketFields(y, "ssffff") {
+				continue
+			}
+			positionSnapshot = append(positionSnapshot, BitfinexWebsocketPosition{Pair: y[0].(string), Status: y[1].(string), Amount: y[2].(float64), Price: y[3].(float64),
+				MarginFunding: y[4].(float64), MarginFundingType: int(y[5].(float64))})
+		}
+		if b.Verbose {
+			log.Println(positionSnapshot)
+		}
+	case BITFINEX_WEBSOCKET_POSITION_NEW, BITFINEX_WEBSOCKET_POSITION_UPDATE, BITFINEX_WEBSOCKET_POSITION_CLOSE:
+		if !bitfinexWebsocketFields(data, "ssffff") {
+			return
+		}
+		position := BitfinexWebsocketPosition{Pair: data[0].(string), Status: data[1].(string), Amount: data[2].(float64), Price: data[3].(float64),
+			MarginFunding: data[4].(float64), MarginFundingType: int(data[5].(float64))}
+		if b.Verbose {
+			log.Println(position)
+		}
+	case BITFINEX_WEBSOCKET_WALLET_SNAPSHOT, BITFINEX_WEBSOCKET_WALLET_UPDATE:
+		wallets := []interface{}{data}
+		if event == BITFINEX_WEBSOCKET_WALLET_SNAPSHOT {
+			wallets = data
+		}
+		for _, x := range wallets {
+			y, ok := x.([]interface{})
+			if !ok || !bitfinexWebsocketFields(y, "ssff") {
+				continue
+			}
+			wallet := BitfinexWebsocketWallet{Name: y[0].(string), Currency: y[1].(string), Balance: y[2].(float64), UnsettledInterest: y[3].(float64)}
+			PublishBalanceEvent(BalanceEvent{Exchange: b.GetName(), Wallet: wallet.Name, Currency: StringToUpper(wallet.Currency), Total: wallet.Balance, Available: -1, Time: time.Now()})
+		}
+	case BITFINEX_WEBSOCKET_ORDER_SNAPSHOT:
+		for _, x := range data {
+			y, _ := x.([]interface{})
+			if order, ok := bitfinexWebsocketOrder(y); ok {
+				b.publishWebsocketOrder(order)
+			}
+		}
+	case BITFINEX_WEBSOCKET_ORDER_NEW, BITFINEX_WEBSOCKET_ORDER_UPDATE, BITFINEX_WEBSOCKET_ORDER_CANCEL:
+		if order, ok := bitfinexWebsocketOrder(data); ok {
+			b.publishWebsocketOrder(order)
+		}
+	case BITFINEX_WEBSOCKET_TRADE_EXECUTED:
+		if !bitfinexWebsocketFields(data, "?sffff") {
+			return
+		}
+		trade := BitfinexWebsocketTradeExecuted{Sequence: fmt.Sprint(data[0]), Pair: data[1].(string), Timestamp: int64(data[2].(float64)), OrderID: int64(data[3].(float64)),
+			AmountExecuted: data[4].(float64), PriceExecuted: data[5].(float64)}
+		if b.Verbose {
+			log.Printf("%s Websocket trade executed for order %d: %f@%f\n", b.GetName(), trade.OrderID, trade.AmountExecuted, trade.PriceExecuted)
+		}
+	case BITFINEX_WEBSOCKET_TRADE_UPDATE:
+		if !bitfinexWebsocketFields(data, "?fsffff") {
+			return
+		}
+		trade := BitfinexWebsocketTradeUpdate{Sequence: fmt.Sprint(data[0]), TradeID: int64(data[1].(float64)), Pair: data[2].(string), Timestamp: int64(data[3].(float64)),
+			OrderID: int64(data[4].(float64)), AmountExecuted: data[5].(float64), PriceExecuted: data[6].(float64)}
+		if len(data) > 10 {
+			trade.Fee, _ = data[9].(float64)
+			trade.FeeCurrency, _ = data[10].(string)
+		}
+		PublishFillEvent(FillEvent{
+			Exchange:        b.GetName(),
+			ExchangeOrderID: strconv.FormatInt(trade.OrderID, 10),
+			Pair:            trade.Pair,
+			Buy:             trade.AmountExecuted > 0,
+			Fill: OrderFill{
+				TradeID: strconv.FormatInt(trade.TradeID, 10),
+				Price:   trade.PriceExecuted,
+				Amount:  math.Abs(trade.AmountExecuted),
+				Fee:     math.Abs(trade.Fee),
+				Time:    time.Unix(trade.Timestamp, 0),
+			},
+		})
 	}
 }
@@ -20,7 +20,7 @@ const (
 	BITSTAMP_API_BALANCE             = "balance/"
 	BITSTAMP_API_USER_TRANSACTIONS   = "user_transactions/"
 	BITSTAMP_API_OPEN_ORDERS         = "open_orders/"
-	BITSTAMP_API_ORDER_STATUS        = "order_status"
+	BITSTAMP_API_ORDER_STATUS        = "order_status/"
 	BITSTAMP_API_CANCEL_ORDER        = "cancel_order/"
 	BITSTAMP_API_CANCEL_ALL_ORDERS   = "cancel_all_orders/"
 	BITSTAMP_API_BUY                 = "buy/"
@@ -40,6 +40,7 @@ type Bitstamp struct {
 	Websocket                   bool
 	RESTPollingDelay            time.Duration
 	AuthenticatedAPISupport     bool
+	APIUrl, WebsocketURL        string
 	ClientID, APIKey, APISecret string
 	Balance                     BitstampAccountBalance
 	TakerFee, MakerFee          float64
@@ -59,9 +60,9 @@ type BitstampTicker struct {
 }
 
 type BitstampAccountBalance struct {
-	BTCReserved  float64 `json:"usd_balance,string"`
+	BTCReserved  float64 `json:"btc_reserved,string"`
 	Fee          float64 `json:",string"`
-	BTCAvailable float64 `json:"btc_balance,string"`
+	BTCAvailable float64 `json:"btc_available,string"`
 	USDReserved  float64 `json:"usd_reserved,string"`
 	BTCBalance   float64 `json:"btc_balance,string"`
 	USDBalance   float64 `json:"usd_balance,string"`
@@ -105,10 +106,10 @@ type BitstampUserTransactions struct {
 
 type BitstampOrder struct {
 	ID     int64   `json:"id"`
-	Date   string  `json:"date"`
+	Date   string  `json:"datetime"`
 	Type   int     `json:"type"`
-	Price  float64 `json:"price"`
-	Amount float64 `json:"amount"`
+	Price  float64 `json:"price,string"`
+	Amount float64 `json:"amount,string"`
 }
 
 type BitstampOrderStatus struct {
@@ -143,6 +144,8 @@ func (b *Bitstamp) SetDefaults() {
 	b.Verbose = false
 	b.Websocket = false
 	b.RESTPollingDelay = 10
+	b.APIUrl = BITSTAMP_API_URL
+	b.WebsocketURL = BITSTAMP_PUSHER_URL
 }
 
 func (b *Bitstamp) Start() {
@@ -166,6 +169,8 @@ func (b *Bitstamp) Setup(exch Exchanges) {
 		b.BaseCurrencies = SplitStrings(exch.BaseCurrencies, ",")
 		b.AvailablePairs = SplitStrings(exch.AvailablePairs, ",")
 		b.EnabledPairs = SplitStrings(exch.EnabledPairs, ",")
+		urls := GetExchangeURLs(exch, ExchangeURLs{b.APIUrl, b.WebsocketURL})
+		b.APIUrl, b.WebsocketURL = urls.API, urls.Websocket
 	}
 }
 
@@ -216,7 +221,7 @@ func (b *Bitstamp) Run() {
 }
 
 func (b *Bitstamp) GetTicker(hourly bool) (BitstampTicker, error) {
-	path := BITSTAMP_API_URL
+	path := b.APIUrl
 	ticker := BitstampTicker{}
 
 	if hourly {
@@ -225,7 +230,7 @@ func (b *Bitstamp) GetTicker(hourly bool) (BitstampTicker, error) {
 		path += BITSTAMP_API_TICKER
 	}
 
-	err := SendHTTPGetRequest(path, true, &ticker)
+	err := GetHTTPClient(b.Name).SendHTTPGetRequest(path, true, &ticker)
 
 	if err != nil {
 		return ticker, err
@@ -242,7 +247,7 @@ func (b *Bitstamp) GetOrderbook() (BitstampOrderbook, error) {
 	}
 
 	resp := response{}
-	err := SendHTTPGetRequest(BITSTAMP_API_URL+BITSTAMP_API_ORDERBOOK, true, &resp)
+	err := GetHTTPClient(b.Name).SendHTTPGetRequest(b.APIUrl+BITSTAMP_API_ORDERBOOK, true, &resp)
 	if err != nil {
 		return BitstampOrderbook{}, err
 	}
@@ -282,9 +287,9 @@ func (b *Bitstamp) GetOrderbook() (BitstampOrderbook, error) {
 }
 
 func (b *Bitstamp) GetTransactions(values url.Values) ([]BitstampTransactions, error) {
-	path := EncodeURLValues(BITSTAMP_API_URL+BITSTAMP_API_TRANSACTIONS, values)
+	path := EncodeURLValues(b.APIUrl+BITSTAMP_API_TRANSACTIONS, values)
 	transactions := []BitstampTransactions{}
-	err := SendHTTPGetRequest(path, true, &transactions)
+	err := GetHTTPClient(b.Name).SendHTTPGetRequest(path, true, &transactions)
 	if err != nil {
 		return nil, err
 	}
@@ -293,7 +298,7 @@ func (b *Bitstamp) GetTransactions(values url.Values) ([]BitstampTransactions, e
 
 func (b *Bitstamp) GetEURUSDConversionRate() (BitstampEURUSDConversionRate, error) {
 	rate := BitstampEURUSDConversionRate{}
-	err := SendHTTPGetRequest(BITSTAMP_API_URL+BITSTAMP_API_EURUSD, true, &rate)
+	err := GetHTTPClient(b.Name).SendHTTPGetRequest(b.APIUrl+BITSTAMP_API_EURUSD, true, &rate)
 
 	if err != nil {
 		return rate, err
@@ -338,7 +343,7 @@ func (b *Bitstamp) GetOrderStatus(OrderID int64) (BitstampOrderStatus, error) {
 	req.Add("id", strconv.FormatInt(OrderID, 10))
 	resp := BitstampOrderStatus{}
 
-	err := b.SendAuthenticatedHTTPRequest(BITSTAMP_API_CANCEL_ORDER, req, &resp)
+	err := b.SendAuthenticatedHTTPRequest(BITSTAMP_API_ORDER_STATUS, req, &resp)
 
 	if err != nil {
 		return resp, err
@@ -474,7 +479,7 @@ func (b *Bitstamp) GetRippleDepositAddress() (string, error) {
 }
 
 func (b *Bitstamp) SendAuthenticatedHTTPRequest(path string, values url.Values, result interface{}) (err error) {
-	nonce := strconv.FormatInt(time.Now().UnixNano(), 10)
+	nonce := strconv.FormatInt(Nonces.Next(NonceKey(b.Name, b.APIKey), time.Nanosecond), 10)
 
 	if values == nil {
 		values = url.Values{}
@@ -484,7 +489,7 @@ func (b *Bitstamp) SendAuthenticatedHTTPRequest(path string, values url.Values,
 	values.Set("nonce", nonce)
 	hmac := GetHMAC(HASH_SHA256, []byte(nonce+b.ClientID+b.APIKey), []byte(b.APISecret))
 	values.Set("signature", strings.ToUpper(HexEncodeToString(hmac)))
-	path = BITSTAMP_API_URL + path
+	path = b.APIUrl + path
 
 	if b.Verbose {
 		log.Println("Sending POST request to " + path)
@@ -493,15 +498,22 @@ func (b *Bitstamp) SendAuthenticatedHTTPRequest(path string, values url.Values,
 	headers := make(map[string]string)
 	headers["Content-Type"] = "application/x-www-form-urlencoded"
 
-	resp, err := SendHTTPRequest("POST", path, headers, strings.NewReader(values.Encode()))
+	resp, err := GetHTTPClient(b.Name).SendAuthenticatedHTTPRequest("POST", path, headers, strings.NewReader(values.Encode()))
 	if err != nil {
-		return err
+		return ClassifyExchangeError(b.Name, err)
 	}
 
 	if b.Verbose {
 		log.Printf("Recieved raw: %s\n", resp)
 	}
 
+	// errors come back as {"error": ...} or {"status": "error", ...} with a
+	// 200
+	err = CheckExchangeErrorBody(b.Name, resp)
+	if err != nil {
+		return err
+	}
+
 	err = JSONDecode([]byte(resp), &result)
 
 	if err != nil {
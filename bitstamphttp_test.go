@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func bitstampFixtures(t *testing.T) (*Bitstamp, *fixtureServer) {
+	b := &Bitstamp{}
+	b.SetDefaults()
+	b.SetAPIKeys("123456", "bitstamp-key", "bitstamp-secret")
+	return b, newFixtureServer(t, b.Name, "bitstamp.json")
+}
+
+func TestBitstampPublicFixtures(t *testing.T) {
+	b, f := bitstampFixtures(t)
+	defer f.Close()
+
+	runFixtureCases(t, f, []fixtureCase{
+		{Name: "GetTicker", Call: func() (interface{}, error) { return b.GetTicker(false) },
+			Want: []string{"{Last:444.2 High:448.7 Low:438.27 Vwap:441.42 Volume:6389.67913416 Bid:444.08 Ask:444.2}"}},
+		{Name: "GetTicker hourly", Call: func() (interface{}, error) { return b.GetTicker(true) },
+			Want: []string{"Volume:189.96117304"}},
+		{Name: "GetOrderbook", Call: func() (interface{}, error) { return b.GetOrderbook() },
+			Want: []string{"{Timestamp:1459238818 Bids:[{Price:444.08 Amount:2.3402} {Price:444.01 Amount:0.0572}] Asks:[{Price:444.2 Amount:0.6}]}"}},
+		{Name: "GetTransactions", Call: func() (interface{}, error) { return b.GetTransactions(url.Values{"time": {"hour"}}) },
+			Params: map[string]string{"time": "hour"},
+			Want:   []string{"{Date:1459238810 TradeID:10796405 Price:444.2 Type:0 Amount:0.0533}"}},
+		{Name: "GetEURUSDConversionRate", Call: func() (interface{}, error) { return b.GetEURUSDConversionRate() },
+			Want: []string{"{Buy:1.1245 Sell:1.1107}"}},
+	})
+}
+
+func TestBitstampAuthenticatedFixtures(t *testing.T) {
+	b, f := bitstampFixtures(t)
+	defer f.Close()
+
+	runFixtureCases(t, f, []fixtureCase{
+		{Name: "GetBalance", Call: func() (interface{}, error) { return b.GetBalance() },
+			Want: []string{"{BTCReserved:0.5 Fee:0.25 BTCAvailable:1 USDReserved:10 BTCBalance:1.5 USDBalance:110 USDAvailable:100}"}},
+		{Name: "GetUserTransactions", Call: func() (interface{}, error) { return b.GetUserTransactions(url.Values{"limit": {"10"}}) },
+			Params: map[string]string{"limit": "10"},
+			Want:   []string{"{Date:2016-03-29 08:06:50 TransID:11432455 Type:2 USD:-44.42 BTC:0.1 BTCUSD:444.2 Fee:0.12 OrderID:1.16427213e+08}"}},
+		{Name: "GetOpenOrders", Call: func() (interface{}, error) { return b.GetOpenOrders() },
+			Want: []string{"[{ID:116427213 Date:2016-03-29 08:06:50 Type:0 Price:440 Amount:0.1}]"}},
+		{Name: "GetOrderStatus", Call: func() (interface{}, error) { return b.GetOrderStatus(116427213) },
+			Params: map[string]string{"id": "116427213"},
+			Want:   []string{"Status:Finished Transactions:[{TradeID:10796405 USD:44.42 Price:444.2 Fee:0.12 BTC:0.1}]"}},
+		{Name: "GetOrderStatus unknown", Call: func() (interface{}, error) { return b.GetOrderStatus(1) },
+			Err: ERROR_ORDER_NOT_FOUND},
+		{Name: "CancelOrder", Call: func() (interface{}, error) { return b.CancelOrder(116427213) },
+			Params: map[string]string{"id": "116427213"},
+			Want:   []string{"true"}},
+		{Name: "CancelOrder unknown", Call: func() (interface{}, error) { return b.CancelOrder(1) },
+			Err: ERROR_ORDER_NOT_FOUND},
+		{Name: "CancelAllOrders", Call: func() (interface{}, error) { return b.CancelAllOrders() },
+			Want: []string{"true"}},
+		{Name: "PlaceOrder", Call: func() (interface{}, error) { return b.PlaceOrder(440, 0.1, true) },
+			Params: map[string]string{"price": "440", "amount": "0.1"},
+			Want:   []string{"{ID:116427214 Date:2016-03-29 08:07:01 Type:0 Price:440 Amount:0.1}"}},
+		{Name: "PlaceOrder insufficient", Call: func() (interface{}, error) { return b.PlaceOrder(450, 1, false) },
+			Err: ERROR_INSUFFICIENT_FUNDS},
+		{Name: "GetWithdrawalRequests", Call: func() (interface{}, error) { return b.GetWithdrawalRequests() },
+			Want: []string{"{OrderID:1083437 Date:2016-03-28 11:00:02 Type:1 Amount:0.5 Status:2 Data:map[address:1A2wyHKJ4KWEoahDHVxwQy3kdd6g1qiSYV"}},
+		{Name: "BitcoinWithdrawal", Call: func() (interface{}, error) { return b.BitcoinWithdrawal(0.5, "1A2wyHKJ4KWEoahDHVxwQy3kdd6g1qiSYV") },
+			Params: map[string]string{"amount": "0.5", "address": "1A2wyHKJ4KWEoahDHVxwQy3kdd6g1qiSYV"},
+			Want:   []string{"1083438"}},
+		{Name: "GetBitcoinDepositAddress", Call: func() (interface{}, error) { return b.GetBitcoinDepositAddress() },
+			Want: []string{"3QXYWgRGX2BPYBpUDBssGbeWEa5zq6snBZ"}},
+		{Name: "GetUnconfirmedBitcoinDeposits", Call: func() (interface{}, error) { return b.GetUnconfirmedBitcoinDeposits() },
+			Want: []string{"[{Amount:0.1 Address:3QXYWgRGX2BPYBpUDBssGbeWEa5zq6snBZ Confirmations:1}]"}},
+		{Name: "RippleWithdrawal", Call: func() (interface{}, error) {
+			return b.RippleWithdrawal(10, "rHb9CJAWyB4rj91VRWn96DkukG4bwdtyTh", "USD")
+		},
+			Params: map[string]string{"amount": "10", "address": "rHb9CJAWyB4rj91VRWn96DkukG4bwdtyTh", "currency": "USD"},
+			Want:   []string{"true"}},
+		{Name: "GetRippleDepositAddress", Call: func() (interface{}, error) { return b.GetRippleDepositAddress() },
+			Want: []string{"rHb9CJAWyB4rj91VRWn96DkukG4bwdtyTh"}},
+	})
+}
+
+func TestBitstampSigning(t *testing.T) {
+	b, f := bitstampFixtures(t)
+	defer f.Close()
+
+	if _, err := b.GetBalance(); err != nil {
+		t.Fatal(err)
+	}
+
+	req := f.Last()
+	form := req.Form()
+	if req.Method != "POST" || req.Header.Get("Content-Type") != "application/x-www-form-urlencoded" || form.Get("key") != "bitstamp-key" {
+		t.Error(fmt.Sprintf("Test failed. Unexpected request %s %s %s", req.Method, req.Header.Get("Content-Type"), req.Body))
+	}
+	hmac := GetHMAC(HASH_SHA256, []byte(form.Get("nonce")+"123456"+"bitstamp-key"), []byte("bitstamp-secret"))
+	if sign := strings.ToUpper(HexEncodeToString(hmac)); form.Get("signature") != sign {
+		t.Error(fmt.Sprintf("Test failed. Expected signature %s. Actual %s", sign, form.Get("signature")))
+	}
+}
@@ -0,0 +1,335 @@
+package main
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	BITSTAMP_MOCK_PAIR          = "btcusd"
+	BITSTAMP_MOCK_DEPTH         = 100
+	BITSTAMP_MOCK_TRANSACTIONS  = 100
+	BITSTAMP_MOCK_DATETIME      = "2006-01-02 15:04:05"
+	BITSTAMP_MOCK_PUSHER_SOCKET = "1.1"
+)
+
+// bitstampMock serves Bitstamp's v1 REST API, which only trades BTCUSD, and
+// its Pusher websocket for a MockVenue.
+type bitstampMock struct {
+	venue *MockVenue
+}
+
+func bitstampMockError(w http.ResponseWriter, err interface{}) {
+	mockWriteJSON(w, http.StatusOK, map[string]interface{}{"error": err})
+}
+
+func bitstampMockType(buy bool) int {
+	if buy {
+		return 0
+	}
+	return 1
+}
+
+func (b *bitstampMock) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/")
+	if r.Method == "POST" {
+		b.authenticated(w, r, path)
+		return
+	}
+
+	switch path {
+	case BITSTAMP_API_TICKER, BITSTAMP_API_TICKER_HOURLY:
+		period := 24 * time.Hour
+		if path == BITSTAMP_API_TICKER_HOURLY {
+			period = time.Hour
+		}
+		ticker, _ := b.venue.Ticker(BITSTAMP_MOCK_PAIR, period)
+		vwap := ticker.Last
+		if ticker.Volume > 0 {
+			vwap = ticker.Total / ticker.Volume
+		}
+		mockWriteJSON(w, http.StatusOK, map[string]string{
+			"last":      mockFormatFloat(ticker.Last),
+			"high":      mockFormatFloat(ticker.High),
+			"low":       mockFormatFloat(ticker.Low),
+			"vwap":      mockFormatFloat(vwap),
+			"volume":    mockFormatFloat(ticker.Volume),
+			"bid":       mockFormatFloat(ticker.Bid),
+			"ask":       mockFormatFloat(ticker.Ask),
+			"open":      mockFormatFloat(ticker.Open),
+			"timestamp": strconv.FormatInt(time.Now().Unix(), 10),
+		})
+	case BITSTAMP_API_ORDERBOOK:
+		mockWriteJSON(w, http.StatusOK, b.orderbook(true))
+	case BITSTAMP_API_TRANSACTIONS:
+		period := time.Hour
+		switch r.URL.Query().Get("time") {
+		case "minute":
+			period = time.Minute
+		case "day":
+			period = 24 * time.Hour
+		}
+		trades, _ := b.venue.Trades(BITSTAMP_MOCK_PAIR, time.Now().Add(-period), time.Time{}, math.MaxInt32)
+		result := []map[string]interface{}{}
+		for _, x := range trades {
+			result = append(result, map[string]interface{}{
+				"date":   strconv.FormatInt(x.Time.Unix(), 10),
+				"tid":    x.TradeID,
+				"price":  mockFormatFloat(x.Price),
+				"type":   bitstampMockType(x.Buy),
+				"amount": mockFormatFloat(x.Amount),
+			})
+		}
+		mockWriteJSON(w, http.StatusOK, result)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// orderbook returns the book as Bitstamp's REST API and Pusher order_book
+// channel do, the latter without a timestamp.
+func (b *bitstampMock) orderbook(timestamp bool) map[string]interface{} {
+	bids, asks, _, _ := b.venue.Book(BITSTAMP_MOCK_PAIR, BITSTAMP_MOCK_DEPTH)
+	levels := func(x []PaperBookLevel) [][]string {
+		result := [][]string{}
+		for _, y := range x {
+			result = append(result, []string{mockFormatFloat(y.Price), mockFormatFloat(y.Amount)})
+		}
+		return result
+	}
+	result := map[string]interface{}{"bids": levels(bids), "asks": levels(asks)}
+	if timestamp {
+		result["timestamp"] = strconv.FormatInt(time.Now().Unix(), 10)
+	}
+	return result
+}
+
+func (b *bitstampMock) authenticated(w http.ResponseWriter, r *http.Request, path string) {
+	if r.PostFormValue("key") != MOCK_EXCHANGE_API_KEY {
+		bitstampMockError(w, "API key not found")
+		return
+	}
+	nonce := r.PostFormValue("nonce")
+	signature := GetHMAC(HASH_SHA256, []byte(nonce+MOCK_EXCHANGE_CLIENT_ID+MOCK_EXCHANGE_API_KEY), []byte(MOCK_EXCHANGE_API_SECRET))
+	if r.PostFormValue("signature") != StringToUpper(HexEncodeToString(signature)) {
+		bitstampMockError(w, "Invalid signature")
+		return
+	}
+	n, _ := strconv.ParseInt(nonce, 10, 64)
+	if _, ok := b.venue.CheckNonce(n); !ok {
+		bitstampMockError(w, "Invalid nonce")
+		return
+	}
+	orderID, _ := strconv.ParseInt(r.PostFormValue("id"), 10, 64)
+
+	switch path {
+	case BITSTAMP_API_BALANCE:
+		balances := b.venue.Balances()[PAPER_ACCOUNT_EXCHANGE]
+		held := b.venue.Held()
+		mockWriteJSON(w, http.StatusOK, map[string]string{
+			"usd_balance":   mockFormatFloat(balances["USD"] + held["USD"]),
+			"btc_balance":   mockFormatFloat(balances["BTC"] + held["BTC"]),
+			"usd_reserved":  mockFormatFloat(held["USD"]),
+			"btc_reserved":  mockFormatFloat(held["BTC"]),
+			"usd_available": mockFormatFloat(balances["USD"]),
+			"btc_available": mockFormatFloat(balances["BTC"]),
+			"fee":           mockFormatFloat(b.venue.account.TakerFee),
+		})
+	case BITSTAMP_API_BUY, BITSTAMP_API_SELL:
+		buy := path == BITSTAMP_API_BUY
+		price, _ := strconv.ParseFloat(r.PostFormValue("price"), 64)
+		amount, _ := strconv.ParseFloat(r.PostFormValue("amount"), 64)
+		order, err := b.venue.PlaceOrder(BITSTAMP_MOCK_PAIR, price, amount, buy, false, false, false)
+		switch err {
+		case nil:
+			mockWriteJSON(w, http.StatusOK, b.order(order))
+		case ErrPaperInsufficientFunds:
+			required, currency := mockFormatFloat(amount*price), "USD"
+			if !buy {
+				required, currency = mockFormatFloat(amount), "BTC"
+			}
+			bitstampMockError(w, map[string][]string{"__all__": {"You have only " + mockFormatFloat(b.venue.Balances()[PAPER_ACCOUNT_EXCHANGE][currency]) + " " + currency + " available. Check your account balance for details. Required " + required + "."}})
+		default:
+			bitstampMockError(w, map[string][]string{"__all__": {"Invalid price or amount."}})
+		}
+	case BITSTAMP_API_CANCEL_ORDER:
+		if err := b.venue.CancelOrder(orderID); err != nil {
+			bitstampMockError(w, "Order not found")
+			return
+		}
+		mockWriteJSON(w, http.StatusOK, true)
+	case BITSTAMP_API_CANCEL_ALL_ORDERS:
+		for _, x := range b.venue.OpenOrders(BITSTAMP_MOCK_PAIR) {
+			b.venue.CancelOrder(x.OrderID)
+		}
+		mockWriteJSON(w, http.StatusOK, true)
+	case BITSTAMP_API_OPEN_ORDERS:
+		result := []map[string]interface{}{}
+		for _, x := range b.venue.OpenOrders(BITSTAMP_MOCK_PAIR) {
+			order := b.order(x)
+			order["amount"] = mockFormatFloat(x.Amount - x.Filled)
+			result = append(result, order)
+		}
+		mockWriteJSON(w, http.StatusOK, result)
+	case BITSTAMP_API_ORDER_STATUS:
+		order, err := b.venue.Order(orderID)
+		if err != nil {
+			bitstampMockError(w, "Order not found")
+			return
+		}
+		status := "Finished"
+		if order.Open {
+			status = "Open"
+		}
+		transactions := []map[string]interface{}{}
+		for _, x := range order.Fills {
+			transactions = append(transactions, map[string]interface{}{
+				"tid":   x.TradeID,
+				"usd":   mockFormatFloat(x.Total),
+				"price": mockFormatFloat(x.Price),
+				"fee":   mockFormatFloat(x.Fee),
+				"btc":   mockFormatFloat(x.Amount),
+			})
+		}
+		mockWriteJSON(w, http.StatusOK, map[string]interface{}{"status": status, "transactions": transactions})
+	case BITSTAMP_API_USER_TRANSACTIONS:
+		b.userTransactions(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (b *bitstampMock) order(order PaperOrder) map[string]interface{} {
+	return map[string]interface{}{
+		"id":       order.OrderID,
+		"datetime": order.Time.UTC().Format(BITSTAMP_MOCK_DATETIME),
+		"type":     bitstampMockType(order.Buy),
+		"price":    mockFormatFloat(order.Price),
+		"amount":   mockFormatFloat(order.Amount),
+	}
+}
+
+// userTransactions returns the account's trades, newest first unless sort is
+// asc, paged by offset and limit.
+func (b *bitstampMock) userTransactions(w http.ResponseWriter, r *http.Request) {
+	transactions := []map[string]interface{}{}
+	for _, order := range b.venue.Orders(BITSTAMP_MOCK_PAIR) {
+		for _, x := range order.Fills {
+			usd, btc := x.Total, -x.Amount
+			if x.Buy {
+				usd, btc = -usd, -btc
+			}
+			transactions = append(transactions, map[string]interface{}{
+				"datetime": x.Time.UTC().Format(BITSTAMP_MOCK_DATETIME),
+				"id":       x.TradeID,
+				"type":     2,
+				"usd":      mockFormatFloat(usd),
+				"btc":      mockFormatFloat(btc),
+				"btc_usd":  mockFormatFloat(x.Price),
+				"fee":      mockFormatFloat(x.Fee),
+				"order_id": x.OrderID,
+			})
+		}
+	}
+	if r.PostFormValue("sort") != "asc" {
+		for i, j := 0, len(transactions)-1; i < j; i, j = i+1, j-1 {
+			transactions[i], transactions[j] = transactions[j], transactions[i]
+		}
+	}
+
+	offset, _ := strconv.Atoi(r.PostFormValue("offset"))
+	limit, err := strconv.Atoi(r.PostFormValue("limit"))
+	if err != nil {
+		limit = BITSTAMP_MOCK_TRANSACTIONS
+	}
+	if offset > len(transactions) {
+		offset = len(transactions)
+	}
+	if offset+limit < len(transactions) {
+		transactions = transactions[:offset+limit]
+	}
+	mockWriteJSON(w, http.StatusOK, transactions[offset:])
+}
+
+type bitstampMockPusherEvent struct {
+	Event   string `json:"event"`
+	Channel string `json:"channel,omitempty"`
+	Data    string `json:"data"`
+}
+
+// pusher speaks enough of the Pusher protocol for Bitstamp's live_trades and
+// order_book channels.
+func (b *bitstampMock) pusher(w http.ResponseWriter, r *http.Request) {
+	conn, err := mockUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	events := b.venue.Subscribe()
+	defer b.venue.Unsubscribe(events)
+
+	requests := make(chan map[string]interface{})
+	go func() {
+		defer close(requests)
+		for {
+			request := make(map[string]interface{})
+			if err := conn.ReadJSON(&request); err != nil {
+				return
+			}
+			requests <- request
+		}
+	}()
+
+	established := `{"socket_id":"` + BITSTAMP_MOCK_PUSHER_SOCKET + `","activity_timeout":120}`
+	if conn.WriteJSON(bitstampMockPusherEvent{Event: "pusher:connection_established", Data: established}) != nil {
+		return
+	}
+
+	channels := make(map[string]bool)
+	for {
+		messages := []bitstampMockPusherEvent{}
+		select {
+		case request, ok := <-requests:
+			if !ok {
+				return
+			}
+			switch request["event"] {
+			case "pusher:ping":
+				messages = append(messages, bitstampMockPusherEvent{Event: "pusher:pong", Data: "{}"})
+			case "pusher:subscribe":
+				data, _ := request["data"].(map[string]interface{})
+				channel, _ := data["channel"].(string)
+				channels[channel] = true
+				messages = append(messages, bitstampMockPusherEvent{Event: "pusher_internal:subscription_succeeded", Channel: channel, Data: "{}"})
+			case "pusher:unsubscribe":
+				data, _ := request["data"].(map[string]interface{})
+				channel, _ := data["channel"].(string)
+				delete(channels, channel)
+			}
+		case event := <-events:
+			if event.Pair != BITSTAMP_MOCK_PAIR {
+				continue
+			}
+			if channels["live_trades"] {
+				for _, x := range event.Trades {
+					data, _ := JSONEncode(map[string]interface{}{"price": x.Price, "amount": x.Amount, "id": x.TradeID})
+					messages = append(messages, bitstampMockPusherEvent{Event: "trade", Channel: "live_trades", Data: string(data)})
+				}
+			}
+			if channels["order_book"] {
+				data, _ := JSONEncode(b.orderbook(false))
+				messages = append(messages, bitstampMockPusherEvent{Event: "data", Channel: "order_book", Data: string(data)})
+			}
+		}
+
+		for _, x := range messages {
+			if conn.WriteJSON(x) != nil {
+				return
+			}
+		}
+	}
+}
@@ -1,8 +1,13 @@
 package main
 
 import (
+	"errors"
 	"github.com/toorop/go-pusher"
 	"log"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
 )
 
 type BitstampPusherOrderbook struct {
@@ -10,62 +15,107 @@ type BitstampPusherOrderbook struct {
 	Bids [][]string `json:"bids"`
 }
 type BitstampPusherTrade struct {
-	Price  float64 `json:"price"`
-	Amount float64 `json:"amount"`
-	ID     int64   `json:"id"`
+	Price     float64 `json:"price"`
+	Amount    float64 `json:"amount"`
+	ID        int64   `json:"id"`
+	Timestamp int64   `json:"timestamp,string"`
 }
 
+var (
+	ErrBitstampPusherClosed = errors.New("pusher connection closed")
+)
+
 const (
-	BITSTAMP_PUSHER_KEY = "de504dc5763aeef9ff52"
+	BITSTAMP_PUSHER_KEY         = "de504dc5763aeef9ff52"
+	BITSTAMP_PUSHER_URL         = "ws://ws.pusherapp.com:80"
+	BITSTAMP_PUSHER_LIVE_TRADES = "live_trades"
 )
 
+// bitstampPusherTrade decodes a live trades event and the pair it's for.
+// The BTCUSD channel has no suffix, the others are e.g. live_trades_btceur.
+func bitstampPusherTrade(event *pusher.Event) (string, BitstampPusherTrade, error) {
+	trade := BitstampPusherTrade{}
+	err := JSONDecode([]byte(event.Data), &trade)
+	if err != nil {
+		return "", trade, err
+	}
+
+	pair := "BTCUSD"
+	if suffix := strings.TrimPrefix(event.Channel, BITSTAMP_PUSHER_LIVE_TRADES+"_"); suffix != event.Channel {
+		pair = StringToUpper(suffix)
+	}
+	return pair, trade, nil
+}
+
+// PusherClient runs the Pusher connection under a supervisor so dropped or
+// silent connections are retried with backoff.
 func (b *Bitstamp) PusherClient() {
-	for b.Enabled && b.Websocket {
-		pusherClient, err := pusher.NewClient(BITSTAMP_PUSHER_KEY)
-		if err != nil {
-			log.Printf("%s Unable to connect to Websocket. Error: %s\n", b.GetName(), err)
-			continue
-		}
+	u, err := url.Parse(b.WebsocketURL)
+	if err != nil {
+		log.Printf("%s Invalid Websocket URL %s. Error: %s\n", b.GetName(), b.WebsocketURL, err)
+		return
+	}
 
-		err = pusherClient.Subscribe("live_trades")
-		if err != nil {
-			log.Printf("%s Websocket Trade subscription error: %s\n", b.GetName(), err)
-		}
+	var pusherClient *pusher.Client
+	var dataChannel, tradeChannel chan *pusher.Event
+	var closed chan struct{}
+	var closeOnce *sync.Once
 
-		err = pusherClient.Subscribe("order_book")
+	s := GetWebsocketSupervisor(b.GetName())
+	s.Verbose = b.Verbose
+	s.Running = func() bool { return b.Enabled && b.Websocket }
+	s.Dial = func() error {
+		pusherClient, err = pusher.NewCustomClient(BITSTAMP_PUSHER_KEY, u.Host, u.Scheme)
 		if err != nil {
-			log.Printf("%s Websocket Trade subscription error: %s\n", b.GetName(), err)
+			return err
 		}
 
-		dataChannelTrade, err := pusherClient.Bind("data")
-		if err != nil {
-			log.Printf("%s Websocket Bind error: ", b.GetName(), err)
-			continue
+		dataChannel, err = pusherClient.Bind("data")
+		if err == nil {
+			tradeChannel, err = pusherClient.Bind("trade")
 		}
-		tradeChannelTrade, err := pusherClient.Bind("trade")
 		if err != nil {
-			log.Printf("%s Websocket Bind error: ", b.GetName(), err)
-			continue
+			pusherClient.Close()
+			return err
 		}
-
-		log.Printf("%s Pusher client connected.\n", b.GetName())
-
-		for b.Websocket {
+		closed = make(chan struct{})
+		closeOnce = &sync.Once{}
+		return nil
+	}
+	s.Send = func(sub WebsocketSubscription) error {
+		return pusherClient.Subscribe(sub.Channel)
+	}
+	s.Close = func() error {
+		closeOnce.Do(func() { close(closed) })
+		return pusherClient.Close()
+	}
+	s.Serve = func() error {
+		for b.Enabled && b.Websocket {
 			select {
-			case data := <-dataChannelTrade:
+			case <-closed:
+				return ErrBitstampPusherClosed
+			case data := <-dataChannel:
+				s.Received()
 				result := BitstampPusherOrderbook{}
 				err := JSONDecode([]byte(data.Data), &result)
 				if err != nil {
 					log.Println(err)
 				}
-			case trade := <-tradeChannelTrade:
-				result := BitstampPusherTrade{}
-				err := JSONDecode([]byte(trade.Data), &result)
+			case trade := <-tradeChannel:
+				s.Received()
+				pair, result, err := bitstampPusherTrade(trade)
 				if err != nil {
 					log.Println(err)
+					continue
 				}
-				log.Printf("%s Pusher trade: Price: %f Amount: %f\n", b.GetName(), result.Price, result.Amount)
+				log.Printf("%s Pusher %s trade: Price: %f Amount: %f\n", b.GetName(), pair, result.Price, result.Amount)
+				CandleAddTrade(b.GetName(), pair, result.Price, result.Amount, time.Unix(result.Timestamp, 0))
 			}
 		}
+		return nil
 	}
+
+	s.Subscribe(WebsocketSubscription{Channel: BITSTAMP_PUSHER_LIVE_TRADES})
+	s.Subscribe(WebsocketSubscription{Channel: "order_book"})
+	s.Run()
 }
@@ -0,0 +1,22 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/toorop/go-pusher"
+)
+
+func TestBitstampPusherTrade(t *testing.T) {
+	t.Parallel()
+	data := `{"amount": 0.5, "price": 730.25, "id": 12345, "timestamp": "1480000000", "type": 0}`
+	for channel, expected := range map[string]string{"live_trades": "BTCUSD", "live_trades_btceur": "BTCEUR"} {
+		pair, trade, err := bitstampPusherTrade(&pusher.Event{Event: "trade", Channel: channel, Data: data})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if pair != expected || trade.Price != 730.25 || trade.Amount != 0.5 || trade.Timestamp != 1480000000 {
+			t.Error(fmt.Sprintf("Test failed. Expected a %s trade at the exchange's time. Actual %s %+v", expected, pair, trade))
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"log"
@@ -43,8 +44,10 @@ type BTCC struct {
 	Enabled                 bool
 	Verbose                 bool
 	Websocket               bool
+	WebsocketURL            string
 	RESTPollingDelay        time.Duration
 	AuthenticatedAPISupport bool
+	APIUrl                  string
 	APISecret, APIKey       string
 	Fee                     float64
 	BaseCurrencies          []string
@@ -65,6 +68,29 @@ type BTCCTicker struct {
 	Open       float64 `json:",string"`
 }
 
+type BTCCTrade struct {
+	Date   int64 `json:",string"`
+	Price  float64
+	Amount float64
+	TID    int64 `json:",string"`
+	Type   string
+}
+
+type BTCCOrderbook struct {
+	Asks [][]float64 `json:"asks"`
+	Bids [][]float64 `json:"bids"`
+	Date int64       `json:"date"`
+}
+
+// BTCCResponse is a JSON-RPC response, which has either a result or an error.
+type BTCCResponse struct {
+	Result json.RawMessage `json:"result"`
+	Error  *struct {
+		Code    int64  `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
 type BTCCProfile struct {
 	Username             string
 	TradePasswordEnabled bool    `json:"trade_password_enabled,bool"`
@@ -74,44 +100,52 @@ type BTCCProfile struct {
 	TradeFeeBTCLTC       float64 `json:"trade_fee_btcltc"`
 	DailyBTCLimit        float64 `json:"daily_btc_limit"`
 	DailyLTCLimit        float64 `json:"daily_ltc_limit"`
-	BTCDespoitAddress    string  `json:"btc_despoit_address"`
+	BTCDespoitAddress    string  `json:"btc_deposit_address"`
 	BTCWithdrawalAddress string  `json:"btc_withdrawal_address"`
 	LTCDepositAddress    string  `json:"ltc_deposit_address"`
-	LTCWithdrawalAddress string  `json:"ltc_withdrawal_request"`
+	LTCWithdrawalAddress string  `json:"ltc_withdrawal_address"`
 	APIKeyPermission     int64   `json:"api_key_permission"`
 }
 
 type BTCCCurrencyGeneric struct {
 	Currency      string
 	Symbol        string
-	Amount        string
-	AmountInt     int64   `json:"amount_integer"`
-	AmountDecimal float64 `json:"amount_decimal"`
+	Amount        float64 `json:",string"`
+	AmountInt     int64   `json:"amount_integer,string"`
+	AmountDecimal int     `json:"amount_decimal"`
+}
+
+// BTCCAccountInfo balances are keyed by lower case currency, e.g. "btc".
+type BTCCAccountInfo struct {
+	Profile BTCCProfile                    `json:"profile"`
+	Balance map[string]BTCCCurrencyGeneric `json:"balance"`
+	Frozen  map[string]BTCCCurrencyGeneric `json:"frozen"`
+	Loan    map[string]BTCCCurrencyGeneric `json:"loan"`
 }
 
 type BTCCOrder struct {
 	ID         int64
 	Type       string
-	Price      float64
+	Price      float64 `json:",string"`
 	Currency   string
-	Amount     float64
-	AmountOrig float64 `json:"amount_original"`
+	Amount     float64 `json:",string"`
+	AmountOrig float64 `json:"amount_original,string"`
 	Date       int64
 	Status     string
-	Detail     BTCCOrderDetail
+	Details    []BTCCOrderDetail
 }
 
 type BTCCOrderDetail struct {
-	Dateline int64
-	Price    float64
-	Amount   float64
+	Dateline int64   `json:",string"`
+	Price    float64 `json:",string"`
+	Amount   float64 `json:",string"`
 }
 
 type BTCCWithdrawal struct {
 	ID          int64
 	Address     string
 	Currency    string
-	Amount      float64
+	Amount      float64 `json:",string"`
 	Date        int64
 	Transaction string
 	Status      string
@@ -121,7 +155,7 @@ type BTCCDeposit struct {
 	ID       int64
 	Address  string
 	Currency string
-	Amount   float64
+	Amount   float64 `json:",string"`
 	Date     int64
 	Status   string
 }
@@ -132,41 +166,44 @@ type BTCCBidAsk struct {
 }
 
 type BTCCDepth struct {
-	Bid []BTCCBidAsk
-	Ask []BTCCBidAsk
+	Bid  []BTCCBidAsk
+	Ask  []BTCCBidAsk
+	Date int64
 }
 
 type BTCCTransaction struct {
 	ID        int64
 	Type      string
-	BTCAmount float64 `json:"btc_amount"`
-	LTCAmount float64 `json:"ltc_amount"`
-	CNYAmount float64 `json:"cny_amount"`
+	BTCAmount float64 `json:"btc_amount,string"`
+	LTCAmount float64 `json:"ltc_amount,string"`
+	CNYAmount float64 `json:"cny_amount,string"`
 	Date      int64
 }
 
 type BTCCIcebergOrder struct {
 	ID              int64
 	Type            string
-	Price           float64
+	Price           float64 `json:",string"`
 	Market          string
-	Amount          float64
-	AmountOrig      float64 `json:"amount_original"`
-	DisclosedAmount float64 `json:"disclosed_amount"`
-	Variance        float64
+	Amount          float64 `json:",string"`
+	AmountOrig      float64 `json:"amount_original,string"`
+	DisclosedAmount float64 `json:"disclosed_amount,string"`
+	Variance        float64 `json:",string"`
 	Date            int64
 	Status          string
 }
 
+// BTCCStopOrder prices are null where they don't apply, e.g. StopPrice for
+// trailing stops and Price for market orders.
 type BTCCStopOrder struct {
 	ID          int64
 	Type        string
-	StopPrice   float64 `json:"stop_price"`
-	TrailingAmt float64 `json:"trailing_amount"`
-	TrailingPct float64 `json:"trailing_percentage"`
-	Price       float64
+	StopPrice   float64 `json:"stop_price,string"`
+	TrailingAmt float64 `json:"trailing_amount,string"`
+	TrailingPct float64 `json:"trailing_percentage,string"`
+	Price       float64 `json:",string"`
 	Market      string
-	Amount      float64
+	Amount      float64 `json:",string"`
 	Date        int64
 	Status      string
 	OrderID     int64 `json:"order_id"`
@@ -179,6 +216,8 @@ func (b *BTCC) SetDefaults() {
 	b.Verbose = false
 	b.Websocket = false
 	b.RESTPollingDelay = 10
+	b.APIUrl = BTCC_API_URL
+	b.WebsocketURL = BTCC_SOCKETIO_ADDRESS
 }
 
 //Setup is run on startup to setup exchange with config values
@@ -195,6 +234,8 @@ func (b *BTCC) Setup(exch Exchanges) {
 		b.BaseCurrencies = SplitStrings(exch.BaseCurrencies, ",")
 		b.AvailablePairs = SplitStrings(exch.AvailablePairs, ",")
 		b.EnabledPairs = SplitStrings(exch.EnabledPairs, ",")
+		urls := GetExchangeURLs(exch, ExchangeURLs{b.APIUrl, b.WebsocketURL})
+		b.APIUrl, b.WebsocketURL = urls.API, urls.Websocket
 	}
 }
 
@@ -263,8 +304,8 @@ func (b *BTCC) GetTicker(symbol string) BTCCTicker {
 	}
 
 	resp := Response{}
-	req := fmt.Sprintf("%sdata/ticker?market=%s", BTCC_API_URL, symbol)
-	err := SendHTTPGetRequest(req, true, &resp)
+	req := fmt.Sprintf("%sdata/ticker?market=%s", b.APIUrl, symbol)
+	err := GetHTTPClient(b.Name).SendHTTPGetRequest(req, true, &resp)
 	if err != nil {
 		log.Println(err)
 		return BTCCTicker{}
@@ -272,66 +313,71 @@ func (b *BTCC) GetTicker(symbol string) BTCCTicker {
 	return resp.Ticker
 }
 
-func (b *BTCC) GetTradesLast24h(symbol string) bool {
-	req := fmt.Sprintf("%sdata/trades?market=%s", BTCC_API_URL, symbol)
-	err := SendHTTPGetRequest(req, true, nil)
+func (b *BTCC) GetTradesLast24h(symbol string) ([]BTCCTrade, error) {
+	trades := []BTCCTrade{}
+	req := fmt.Sprintf("%sdata/trades?market=%s", b.APIUrl, symbol)
+	err := GetHTTPClient(b.Name).SendHTTPGetRequest(req, true, &trades)
 	if err != nil {
-		log.Println(err)
-		return false
+		return nil, ClassifyExchangeError(b.Name, err)
 	}
-	return true
+	return trades, nil
 }
 
-func (b *BTCC) GetTradeHistory(symbol string, limit, sinceTid int64, time time.Time) bool {
-	req := fmt.Sprintf("%sdata/historydata?market=%s", BTCC_API_URL, symbol)
+func (b *BTCC) GetTradeHistory(symbol string, limit, sinceTid int64, time time.Time) ([]BTCCTrade, error) {
+	req := fmt.Sprintf("%sdata/historydata", b.APIUrl)
 	v := url.Values{}
+	v.Set("market", symbol)
 
 	if limit > 0 {
 		v.Set("limit", strconv.FormatInt(limit, 10))
 	}
 	if sinceTid > 0 {
 		v.Set("since", strconv.FormatInt(sinceTid, 10))
-	}
-	if !time.IsZero() {
-		v.Set("sincetype", strconv.FormatInt(time.Unix(), 10))
+	} else if !time.IsZero() {
+		v.Set("since", strconv.FormatInt(time.Unix(), 10))
+		v.Set("sincetype", "time")
 	}
 
+	trades := []BTCCTrade{}
 	req = EncodeURLValues(req, v)
-	err := SendHTTPGetRequest(req, true, nil)
+	err := GetHTTPClient(b.Name).SendHTTPGetRequest(req, true, &trades)
 	if err != nil {
-		log.Println(err)
-		return false
+		return nil, ClassifyExchangeError(b.Name, err)
 	}
-	return true
+	return trades, nil
 }
 
-func (b *BTCC) GetOrderBook(symbol string, limit int) bool {
-	req := fmt.Sprintf("%sdata/orderbook?market=%s&limit=%d", BTCC_API_URL, symbol, limit)
-	err := SendHTTPGetRequest(req, true, nil)
+func (b *BTCC) GetOrderBook(symbol string, limit int) (BTCCOrderbook, error) {
+	orderbook := BTCCOrderbook{}
+	req := fmt.Sprintf("%sdata/orderbook?market=%s&limit=%d", b.APIUrl, symbol, limit)
+	err := GetHTTPClient(b.Name).SendHTTPGetRequest(req, true, &orderbook)
 	if err != nil {
-		log.Println(err)
-		return false
+		return orderbook, ClassifyExchangeError(b.Name, err)
 	}
-	return true
+	return orderbook, nil
 }
 
-func (b *BTCC) GetAccountInfo(infoType string) {
+func (b *BTCC) GetAccountInfo(infoType string) (BTCCAccountInfo, error) {
 	params := make([]interface{}, 0)
 
 	if len(infoType) > 0 {
 		params = append(params, infoType)
 	}
 
-	err := b.SendAuthenticatedHTTPRequest(BTCC_ACCOUNT_INFO, params)
-
-	if err != nil {
-		log.Println(err)
-	}
+	info := BTCCAccountInfo{}
+	err := b.SendAuthenticatedHTTPRequest(BTCC_ACCOUNT_INFO, params, &info)
+	return info, err
 }
 
-func (b *BTCC) PlaceOrder(buyOrder bool, price, amount float64, market string) {
+// PlaceOrder places a limit order, or a market order if price is 0,
+// returning its ID.
+func (b *BTCC) PlaceOrder(buyOrder bool, price, amount float64, market string) (int64, error) {
 	params := make([]interface{}, 0)
-	params = append(params, strconv.FormatFloat(price, 'f', -1, 64))
+	if price == 0 {
+		params = append(params, nil) // sent as null for a market order
+	} else {
+		params = append(params, strconv.FormatFloat(price, 'f', -1, 64))
+	}
 	params = append(params, strconv.FormatFloat(amount, 'f', -1, 64))
 
 	if len(market) > 0 {
@@ -343,14 +389,12 @@ func (b *BTCC) PlaceOrder(buyOrder bool, price, amount float64, market string) {
 		req = BTCC_ORDER_SELL
 	}
 
-	err := b.SendAuthenticatedHTTPRequest(req, params)
-
-	if err != nil {
-		log.Println(err)
-	}
+	var orderID int64
+	err := b.SendAuthenticatedHTTPRequest(req, params, &orderID)
+	return orderID, err
 }
 
-func (b *BTCC) CancelOrder(orderID int64, market string) {
+func (b *BTCC) CancelOrder(orderID int64, market string) error {
 	params := make([]interface{}, 0)
 	params = append(params, orderID)
 
@@ -358,14 +402,10 @@ func (b *BTCC) CancelOrder(orderID int64, market string) {
 		params = append(params, market)
 	}
 
-	err := b.SendAuthenticatedHTTPRequest(BTCC_ORDER_CANCEL, params)
-
-	if err != nil {
-		log.Println(err)
-	}
+	return b.sendCancelRequest(BTCC_ORDER_CANCEL, orderID, params)
 }
 
-func (b *BTCC) GetDeposits(currency string, pending bool) {
+func (b *BTCC) GetDeposits(currency string, pending bool) ([]BTCCDeposit, error) {
 	params := make([]interface{}, 0)
 	params = append(params, currency)
 
@@ -373,14 +413,14 @@ func (b *BTCC) GetDeposits(currency string, pending bool) {
 		params = append(params, pending)
 	}
 
-	err := b.SendAuthenticatedHTTPRequest(BTCC_DEPOSITS, params)
-
-	if err != nil {
-		log.Println(err)
-	}
+	resp := struct {
+		Deposits []BTCCDeposit `json:"deposit"`
+	}{}
+	err := b.SendAuthenticatedHTTPRequest(BTCC_DEPOSITS, params, &resp)
+	return resp.Deposits, err
 }
 
-func (b *BTCC) GetMarketDepth(market string, limit int64) {
+func (b *BTCC) GetMarketDepth(market string, limit int64) (BTCCDepth, error) {
 	params := make([]interface{}, 0)
 
 	if limit > 0 {
@@ -391,14 +431,14 @@ func (b *BTCC) GetMarketDepth(market string, limit int64) {
 		params = append(params, market)
 	}
 
-	err := b.SendAuthenticatedHTTPRequest(BTCC_MARKETDEPTH, params)
-
-	if err != nil {
-		log.Println(err)
-	}
+	resp := struct {
+		MarketDepth BTCCDepth `json:"market_depth"`
+	}{}
+	err := b.SendAuthenticatedHTTPRequest(BTCC_MARKETDEPTH, params, &resp)
+	return resp.MarketDepth, err
 }
 
-func (b *BTCC) GetOrder(orderID int64, market string, detailed bool) {
+func (b *BTCC) GetOrder(orderID int64, market string, detailed bool) (BTCCOrder, error) {
 	params := make([]interface{}, 0)
 	params = append(params, orderID)
 
@@ -410,14 +450,16 @@ func (b *BTCC) GetOrder(orderID int64, market string, detailed bool) {
 		params = append(params, detailed)
 	}
 
-	err := b.SendAuthenticatedHTTPRequest(BTCC_ORDER, params)
-
-	if err != nil {
-		log.Println(err)
-	}
+	resp := struct {
+		Order BTCCOrder `json:"order"`
+	}{}
+	err := b.SendAuthenticatedHTTPRequest(BTCC_ORDER, params, &resp)
+	return resp.Order, err
 }
 
-func (b *BTCC) GetOrders(openonly bool, market string, limit, offset, since int64, detailed bool) {
+// GetOrders returns orders for the market, or for every market if it is
+// "ALL".
+func (b *BTCC) GetOrders(openonly bool, market string, limit, offset, since int64, detailed bool) ([]BTCCOrder, error) {
 	params := make([]interface{}, 0)
 
 	if openonly {
@@ -444,14 +486,28 @@ func (b *BTCC) GetOrders(openonly bool, market string, limit, offset, since int6
 		params = append(params, detailed)
 	}
 
-	err := b.SendAuthenticatedHTTPRequest(BTCC_ORDERS, params)
-
+	// orders for all markets are keyed order_btccny, order_ltccny and so on
+	resp := make(map[string]json.RawMessage)
+	err := b.SendAuthenticatedHTTPRequest(BTCC_ORDERS, params, &resp)
 	if err != nil {
-		log.Println(err)
+		return nil, err
 	}
+
+	orders := []BTCCOrder{}
+	for key, data := range resp {
+		if !strings.HasPrefix(key, "order") {
+			continue
+		}
+		marketOrders := []BTCCOrder{}
+		if err := json.Unmarshal(data, &marketOrders); err != nil {
+			return nil, errors.New("Unable to JSON Unmarshal response.")
+		}
+		orders = append(orders, marketOrders...)
+	}
+	return orders, nil
 }
 
-func (b *BTCC) GetTransactions(transType string, limit, offset, since int64, sinceType string) {
+func (b *BTCC) GetTransactions(transType string, limit, offset, since int64, sinceType string) ([]BTCCTransaction, error) {
 	params := make([]interface{}, 0)
 
 	if len(transType) > 0 {
@@ -474,14 +530,14 @@ func (b *BTCC) GetTransactions(transType string, limit, offset, since int64, sin
 		params = append(params, sinceType)
 	}
 
-	err := b.SendAuthenticatedHTTPRequest(BTCC_TRANSACTIONS, params)
-
-	if err != nil {
-		log.Println(err)
-	}
+	resp := struct {
+		Transactions []BTCCTransaction `json:"transaction"`
+	}{}
+	err := b.SendAuthenticatedHTTPRequest(BTCC_TRANSACTIONS, params, &resp)
+	return resp.Transactions, err
 }
 
-func (b *BTCC) GetWithdrawal(withdrawalID int64, currency string) {
+func (b *BTCC) GetWithdrawal(withdrawalID int64, currency string) (BTCCWithdrawal, error) {
 	params := make([]interface{}, 0)
 	params = append(params, withdrawalID)
 
@@ -489,14 +545,14 @@ func (b *BTCC) GetWithdrawal(withdrawalID int64, currency string) {
 		params = append(params, currency)
 	}
 
-	err := b.SendAuthenticatedHTTPRequest(BTCC_WITHDRAWAL, params)
-
-	if err != nil {
-		log.Println(err)
-	}
+	resp := struct {
+		Withdrawal BTCCWithdrawal `json:"withdrawal"`
+	}{}
+	err := b.SendAuthenticatedHTTPRequest(BTCC_WITHDRAWAL, params, &resp)
+	return resp.Withdrawal, err
 }
 
-func (b *BTCC) GetWithdrawals(currency string, pending bool) {
+func (b *BTCC) GetWithdrawals(currency string, pending bool) ([]BTCCWithdrawal, error) {
 	params := make([]interface{}, 0)
 	params = append(params, currency)
 
@@ -504,26 +560,28 @@ func (b *BTCC) GetWithdrawals(currency string, pending bool) {
 		params = append(params, pending)
 	}
 
-	err := b.SendAuthenticatedHTTPRequest(BTCC_WITHDRAWALS, params)
-
-	if err != nil {
-		log.Println(err)
-	}
+	resp := struct {
+		Withdrawals []BTCCWithdrawal `json:"withdrawal"`
+	}{}
+	err := b.SendAuthenticatedHTTPRequest(BTCC_WITHDRAWALS, params, &resp)
+	return resp.Withdrawals, err
 }
 
-func (b *BTCC) RequestWithdrawal(currency string, amount float64) {
+// RequestWithdrawal returns the ID of the withdrawal.
+func (b *BTCC) RequestWithdrawal(currency string, amount float64) (int64, error) {
 	params := make([]interface{}, 0)
 	params = append(params, currency)
-	params = append(params, amount)
-
-	err := b.SendAuthenticatedHTTPRequest(BTCC_WITHDRAWAL_REQUEST, params)
+	params = append(params, strconv.FormatFloat(amount, 'f', -1, 64))
 
-	if err != nil {
-		log.Println(err)
-	}
+	resp := struct {
+		ID int64 `json:"id,string"`
+	}{}
+	err := b.SendAuthenticatedHTTPRequest(BTCC_WITHDRAWAL_REQUEST, params, &resp)
+	return resp.ID, err
 }
 
-func (b *BTCC) IcebergOrder(buyOrder bool, price, amount, discAmount, variance float64, market string) {
+// IcebergOrder places an iceberg order, returning its ID.
+func (b *BTCC) IcebergOrder(buyOrder bool, price, amount, discAmount, variance float64, market string) (int64, error) {
 	params := make([]interface{}, 0)
 	params = append(params, strconv.FormatFloat(price, 'f', -1, 64))
 	params = append(params, strconv.FormatFloat(amount, 'f', -1, 64))
@@ -539,14 +597,12 @@ func (b *BTCC) IcebergOrder(buyOrder bool, price, amount, discAmount, variance f
 		req = BTCC_ICEBERG_SELL
 	}
 
-	err := b.SendAuthenticatedHTTPRequest(req, params)
-
-	if err != nil {
-		log.Println(err)
-	}
+	var orderID int64
+	err := b.SendAuthenticatedHTTPRequest(req, params, &orderID)
+	return orderID, err
 }
 
-func (b *BTCC) GetIcebergOrder(orderID int64, market string) {
+func (b *BTCC) GetIcebergOrder(orderID int64, market string) (BTCCIcebergOrder, error) {
 	params := make([]interface{}, 0)
 	params = append(params, orderID)
 
@@ -554,14 +610,14 @@ func (b *BTCC) GetIcebergOrder(orderID int64, market string) {
 		params = append(params, market)
 	}
 
-	err := b.SendAuthenticatedHTTPRequest(BTCC_ICEBERG_ORDER, params)
-
-	if err != nil {
-		log.Println(err)
-	}
+	resp := struct {
+		Order BTCCIcebergOrder `json:"iceberg_order"`
+	}{}
+	err := b.SendAuthenticatedHTTPRequest(BTCC_ICEBERG_ORDER, params, &resp)
+	return resp.Order, err
 }
 
-func (b *BTCC) GetIcebergOrders(limit, offset int64, market string) {
+func (b *BTCC) GetIcebergOrders(limit, offset int64, market string) ([]BTCCIcebergOrder, error) {
 	params := make([]interface{}, 0)
 
 	if limit > 0 {
@@ -576,14 +632,14 @@ func (b *BTCC) GetIcebergOrders(limit, offset int64, market string) {
 		params = append(params, market)
 	}
 
-	err := b.SendAuthenticatedHTTPRequest(BTCC_ICEBERG_ORDERS, params)
-
-	if err != nil {
-		log.Println(err)
-	}
+	resp := struct {
+		Orders []BTCCIcebergOrder `json:"iceberg_orders"`
+	}{}
+	err := b.SendAuthenticatedHTTPRequest(BTCC_ICEBERG_ORDERS, params, &resp)
+	return resp.Orders, err
 }
 
-func (b *BTCC) CancelIcebergOrder(orderID int64, market string) {
+func (b *BTCC) CancelIcebergOrder(orderID int64, market string) error {
 	params := make([]interface{}, 0)
 	params = append(params, orderID)
 
@@ -591,18 +647,16 @@ func (b *BTCC) CancelIcebergOrder(orderID int64, market string) {
 		params = append(params, market)
 	}
 
-	err := b.SendAuthenticatedHTTPRequest(BTCC_ICEBERG_CANCEL, params)
-
-	if err != nil {
-		log.Println(err)
-	}
+	return b.sendCancelRequest(BTCC_ICEBERG_CANCEL, orderID, params)
 }
 
-func (b *BTCC) PlaceStopOrder(buyOder bool, stopPrice, price, amount, trailingAmt, trailingPct float64, market string) {
+// PlaceStopOrder places a stop order, triggered at stopPrice or trailing the
+// market by trailingAmt or trailingPct, returning its ID.
+func (b *BTCC) PlaceStopOrder(buyOder bool, stopPrice, price, amount, trailingAmt, trailingPct float64, market string) (int64, error) {
 	params := make([]interface{}, 0)
 
 	if stopPrice > 0 {
-		params = append(params, stopPrice)
+		params = append(params, strconv.FormatFloat(stopPrice, 'f', -1, 64))
 	}
 
 	params = append(params, strconv.FormatFloat(price, 'f', -1, 64))
@@ -625,14 +679,12 @@ func (b *BTCC) PlaceStopOrder(buyOder bool, stopPrice, price, amount, trailingAm
 		req = BTCC_STOPORDER_SELL
 	}
 
-	err := b.SendAuthenticatedHTTPRequest(req, params)
-
-	if err != nil {
-		log.Println(err)
-	}
+	var orderID int64
+	err := b.SendAuthenticatedHTTPRequest(req, params, &orderID)
+	return orderID, err
 }
 
-func (b *BTCC) GetStopOrder(orderID int64, market string) {
+func (b *BTCC) GetStopOrder(orderID int64, market string) (BTCCStopOrder, error) {
 	params := make([]interface{}, 0)
 	params = append(params, orderID)
 
@@ -640,14 +692,14 @@ func (b *BTCC) GetStopOrder(orderID int64, market string) {
 		params = append(params, market)
 	}
 
-	err := b.SendAuthenticatedHTTPRequest(BTCC_STOPORDER, params)
-
-	if err != nil {
-		log.Println(err)
-	}
+	resp := struct {
+		Order BTCCStopOrder `json:"stop_order"`
+	}{}
+	err := b.SendAuthenticatedHTTPRequest(BTCC_STOPORDER, params, &resp)
+	return resp.Order, err
 }
 
-func (b *BTCC) GetStopOrders(status, orderType string, stopPrice float64, limit, offset int64, market string) {
+func (b *BTCC) GetStopOrders(status, orderType string, stopPrice float64, limit, offset int64, market string) ([]BTCCStopOrder, error) {
 	params := make([]interface{}, 0)
 
 	if len(status) > 0 {
@@ -659,7 +711,7 @@ func (b *BTCC) GetStopOrders(status, orderType string, stopPrice float64, limit,
 	}
 
 	if stopPrice > 0 {
-		params = append(params, stopPrice)
+		params = append(params, strconv.FormatFloat(stopPrice, 'f', -1, 64))
 	}
 
 	if limit > 0 {
@@ -667,21 +719,21 @@ func (b *BTCC) GetStopOrders(status, orderType string, stopPrice float64, limit,
 	}
 
 	if offset > 0 {
-		params = append(params, limit)
+		params = append(params, offset)
 	}
 
 	if len(market) > 0 {
 		params = append(params, market)
 	}
 
-	err := b.SendAuthenticatedHTTPRequest(BTCC_STOPORDERS, params)
-
-	if err != nil {
-		log.Println(err)
-	}
+	resp := struct {
+		Orders []BTCCStopOrder `json:"stop_orders"`
+	}{}
+	err := b.SendAuthenticatedHTTPRequest(BTCC_STOPORDERS, params, &resp)
+	return resp.Orders, err
 }
 
-func (b *BTCC) CancelStopOrder(orderID int64, market string) {
+func (b *BTCC) CancelStopOrder(orderID int64, market string) error {
 	params := make([]interface{}, 0)
 	params = append(params, orderID)
 
@@ -689,15 +741,27 @@ func (b *BTCC) CancelStopOrder(orderID int64, market string) {
 		params = append(params, market)
 	}
 
-	err := b.SendAuthenticatedHTTPRequest(BTCC_STOPORDER_CANCEL, params)
+	return b.sendCancelRequest(BTCC_STOPORDER_CANCEL, orderID, params)
+}
 
+// sendCancelRequest sends a cancellation, which the exchange answers with
+// false if the order couldn't be cancelled.
+func (b *BTCC) sendCancelRequest(method string, orderID int64, params []interface{}) error {
+	var cancelled bool
+	err := b.SendAuthenticatedHTTPRequest(method, params, &cancelled)
 	if err != nil {
-		log.Println(err)
+		return err
+	}
+	if !cancelled {
+		return NewExchangeError(b.Name, "", fmt.Sprintf("unable to cancel order %d", orderID))
 	}
+	return nil
 }
 
-func (b *BTCC) SendAuthenticatedHTTPRequest(method string, params []interface{}) (err error) {
-	nonce := strconv.FormatInt(time.Now().UnixNano(), 10)[0:16]
+// SendAuthenticatedHTTPRequest calls a JSON-RPC method, decoding its result
+// into result. JSON-RPC errors are returned as ExchangeErrors.
+func (b *BTCC) SendAuthenticatedHTTPRequest(method string, params []interface{}, result interface{}) (err error) {
+	nonce := strconv.FormatInt(Nonces.Next(NonceKey(b.Name, b.APIKey), time.Microsecond), 10)
 	encoded := fmt.Sprintf("tonce=%s&accesskey=%s&requestmethod=post&id=%d&method=%s&params=", nonce, b.APIKey, 1, method)
 
 	if len(params) == 0 {
@@ -719,6 +783,10 @@ func (b *BTCC) SendAuthenticatedHTTPRequest(method string, params []interface{})
 				{
 					items = append(items, fmt.Sprintf("%f", x))
 				}
+			case "<nil>":
+				{
+					items = append(items, "")
+				}
 			case "bool":
 				{
 					if x == true {
@@ -744,7 +812,7 @@ func (b *BTCC) SendAuthenticatedHTTPRequest(method string, params []interface{})
 	postData["method"] = method
 	postData["params"] = params
 	postData["id"] = 1
-	apiURL := BTCC_API_URL + BTCC_API_AUTHENTICATED_METHOD
+	apiURL := b.APIUrl + BTCC_API_AUTHENTICATED_METHOD
 	data, err := JSONEncode(postData)
 
 	if err != nil {
@@ -760,15 +828,34 @@ func (b *BTCC) SendAuthenticatedHTTPRequest(method string, params []interface{})
 	headers["Authorization"] = "Basic " + Base64Encode([]byte(b.APIKey+":"+HexEncodeToString(hmac)))
 	headers["Json-Rpc-Tonce"] = nonce
 
-	resp, err := SendHTTPRequest("POST", apiURL, headers, strings.NewReader(string(data)))
+	resp, err := GetHTTPClient(b.Name).SendAuthenticatedHTTPRequest("POST", apiURL, headers, strings.NewReader(string(data)))
 
 	if err != nil {
-		return err
+		return ClassifyExchangeError(b.Name, err)
 	}
 
 	if b.Verbose {
 		log.Printf("Recv'd :%s\n", resp)
 	}
 
+	response := BTCCResponse{}
+	err = JSONDecode([]byte(resp), &response)
+
+	if err != nil {
+		return errors.New("Unable to JSON Unmarshal response.")
+	}
+
+	if response.Error != nil {
+		return NewExchangeError(b.Name, strconv.FormatInt(response.Error.Code, 10), response.Error.Message)
+	}
+
+	if result == nil {
+		return nil
+	}
+
+	if err := json.Unmarshal(response.Result, result); err != nil {
+		return errors.New("Unable to JSON Unmarshal response.")
+	}
+
 	return nil
 }
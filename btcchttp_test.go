@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func btccFixtures(t *testing.T) (*BTCC, *fixtureServer) {
+	b := &BTCC{}
+	b.SetDefaults()
+	b.SetAPIKeys("btcc-key", "btcc-secret")
+	return b, newFixtureServer(t, b.Name, "btcc.json")
+}
+
+func TestBTCCPublicFixtures(t *testing.T) {
+	b, f := btccFixtures(t)
+	defer f.Close()
+
+	runFixtureCases(t, f, []fixtureCase{
+		{Name: "GetTicker", Call: func() (interface{}, error) { return b.GetTicker("btccny"), nil },
+			Want: []string{"{High:2894.97 Low:2850.08 Buy:2876.92 Sell:2883.8 Last:2875.63 Vol:4133.638 Date:1396412995 Vwap:2879.12 Prev_close:2856.54 Open:2854.17}"}},
+		{Name: "GetTradesLast24h", Call: func() (interface{}, error) { return b.GetTradesLast24h("btccny") },
+			Params: map[string]string{"market": "btccny"},
+			Want:   []string{"[{Date:1396413176 Price:2875.63 Amount:0.5 TID:2881 Type:buy}]"}},
+		{Name: "GetTradeHistory", Call: func() (interface{}, error) { return b.GetTradeHistory("btccny", 10, 2880, time.Time{}) },
+			Params: map[string]string{"market": "btccny", "limit": "10", "since": "2880", "sincetype": ""},
+			Want:   []string{"TID:2881"}},
+		{Name: "GetTradeHistory since time", Call: func() (interface{}, error) { return b.GetTradeHistory("btccny", 0, 0, time.Unix(1396413000, 0)) },
+			Params: map[string]string{"market": "btccny", "since": "1396413000", "sincetype": "time"},
+			Want:   []string{"TID:2881"}},
+		{Name: "GetOrderBook", Call: func() (interface{}, error) { return b.GetOrderBook("btccny", 1) },
+			Params: map[string]string{"market": "btccny", "limit": "1"},
+			Want:   []string{"{Asks:[[2883.8 0.5]] Bids:[[2876.92 1.2]] Date:1396413176}"}},
+	})
+}
+
+func TestBTCCAuthenticatedFixtures(t *testing.T) {
+	b, f := btccFixtures(t)
+	defer f.Close()
+
+	runFixtureCases(t, f, []fixtureCase{
+		{Name: "GetAccountInfo", Call: func() (interface{}, error) { return b.GetAccountInfo("all") },
+			Params: map[string]string{"method": "getAccountInfo", "params": "[all]", "id": "1"},
+			Want:   []string{"BTCDespoitAddress:123myZyM9jBYGw5EB3wWmfgJ4Mvqnu7gEu", "LTCWithdrawalAddress:L23GzXJnAT7ZQ5XdwHV8ZJSgyHbnLoUrs", "btc:{Currency:BTC Symbol:฿ Amount:100 AmountInt:10000000000 AmountDecimal:8}"}},
+		{Name: "PlaceOrder", Call: func() (interface{}, error) { return b.PlaceOrder(true, 2876.92, 0.5, "BTCCNY") },
+			Params: map[string]string{"method": "buyOrder2", "params": "[2876.92 0.5 BTCCNY]"},
+			Want:   []string{"12345"}},
+		{Name: "PlaceOrder sell", Call: func() (interface{}, error) { return b.PlaceOrder(false, 2883.8, 0.5, "") },
+			Params: map[string]string{"method": "sellOrder2", "params": "[2883.8 0.5]"},
+			Want:   []string{"12346"}},
+		{Name: "PlaceOrder market", Call: func() (interface{}, error) { return b.PlaceOrder(false, 0, 0.5, "") },
+			Params: map[string]string{"method": "sellOrder2", "params": "[<nil> 0.5]"},
+			Want:   []string{"12346"}},
+		{Name: "CancelOrder", Call: func() (interface{}, error) { return nil, b.CancelOrder(12345, "BTCCNY") },
+			Params: map[string]string{"method": "cancelOrder", "params": "[12345 BTCCNY]"}},
+		{Name: "GetOrder", Call: func() (interface{}, error) { return b.GetOrder(12345, "BTCCNY", true) },
+			Params: map[string]string{"method": "getOrder", "params": "[12345 BTCCNY true]"},
+			Want:   []string{"{ID:12345 Type:bid Price:2876.92 Currency:CNY Amount:0.3 AmountOrig:0.5 Date:1396413176 Status:open Details:[{Dateline:1396413180 Price:2876.92 Amount:0.2}]}"}},
+		{Name: "GetOrders", Call: func() (interface{}, error) {
+			orders, err := b.GetOrders(true, "ALL", 0, 0, 0, false)
+			return len(orders), err
+		},
+			Params: map[string]string{"method": "getOrders", "params": "[true ALL]"},
+			Want:   []string{"2"}},
+		{Name: "GetMarketDepth", Call: func() (interface{}, error) { return b.GetMarketDepth("BTCCNY", 1) },
+			Want: []string{"{Bid:[{Price:2876.92 Amount:1.2}] Ask:[{Price:2883.8 Amount:0.5}] Date:1396413176}"}},
+		{Name: "GetTransactions", Call: func() (interface{}, error) { return b.GetTransactions("buybtc", 10, 0, 0, "") },
+			Params: map[string]string{"params": "[buybtc 10]"},
+			Want:   []string{"{ID:8 Type:buybtc BTCAmount:0.2 LTCAmount:0 CNYAmount:-575.384 Date:1396413180}"}},
+		{Name: "IcebergOrder insufficient", Call: func() (interface{}, error) { return b.IcebergOrder(true, 2876.92, 100, 10, 0.1, "BTCCNY") },
+			Err: ERROR_INSUFFICIENT_FUNDS},
+		{Name: "GetStopOrders", Call: func() (interface{}, error) { return b.GetStopOrders("open", "", 0, 10, 20, "") },
+			Params: map[string]string{"params": "[open 10 20]"},
+			Want:   []string{"{ID:7 Type:ask StopPrice:0 TrailingAmt:50 TrailingPct:0 Price:0 Market:BTCCNY Amount:0.5"}},
+		{Name: "CancelStopOrder unknown", Call: func() (interface{}, error) { return nil, b.CancelStopOrder(8, "") },
+			Err: ERROR_ORDER_NOT_FOUND},
+	})
+}
+
+func TestBTCCSigning(t *testing.T) {
+	b, f := btccFixtures(t)
+	defer f.Close()
+
+	if err := b.SendAuthenticatedHTTPRequest(BTCC_ORDER_BUY, []interface{}{"2876.92", "0.5", "BTCCNY"}, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	req := f.Last()
+	tonce := req.Header.Get("Json-Rpc-Tonce")
+	message := "tonce=" + tonce + "&accesskey=btcc-key&requestmethod=post&id=1&method=buyOrder2&params=2876.92,0.5,BTCCNY"
+	auth := "Basic " + Base64Encode([]byte("btcc-key:"+HexEncodeToString(GetHMAC(HASH_SHA1, []byte(message), []byte("btcc-secret")))))
+	if tonce == "" || req.Header.Get("Authorization") != auth {
+		t.Error(fmt.Sprintf("Test failed. Expected authorization %s. Actual %s", auth, req.Header.Get("Authorization")))
+	}
+	if req.Header.Get("Content-Type") != "application/json-rpc" {
+		t.Error(fmt.Sprintf("Test failed. Expected a JSON-RPC request. Actual %s", req.Header.Get("Content-Type")))
+	}
+
+	// a null price is signed as an empty parameter
+	if err := b.SendAuthenticatedHTTPRequest(BTCC_ORDER_BUY, []interface{}{nil, "0.5"}, nil); err != nil {
+		t.Fatal(err)
+	}
+	req = f.Last()
+	tonce = req.Header.Get("Json-Rpc-Tonce")
+	message = "tonce=" + tonce + "&accesskey=btcc-key&requestmethod=post&id=1&method=buyOrder2&params=,0.5"
+	auth = "Basic " + Base64Encode([]byte("btcc-key:"+HexEncodeToString(GetHMAC(HASH_SHA1, []byte(message), []byte("btcc-secret")))))
+	if req.Header.Get("Authorization") != auth {
+		t.Error(fmt.Sprintf("Test failed. Expected authorization %s. Actual %s", auth, req.Header.Get("Authorization")))
+	}
+	if params := fmt.Sprint(req.JSON()["params"]); params != "[<nil> 0.5]" {
+		t.Error(fmt.Sprintf("Test failed. Expected a null price. Actual %s", params))
+	}
+}
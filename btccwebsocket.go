@@ -51,6 +51,7 @@ func (b *BTCC) OnConnect(output chan socketio.Message) {
 	if b.Verbose {
 		log.Printf("%s Connected to Websocket.", b.GetName())
 	}
+	GetWebsocketSupervisor(b.GetName()).Received()
 
 	currencies := []string{}
 	for _, x := range b.EnabledPairs {
@@ -72,12 +73,10 @@ func (b *BTCC) OnConnect(output chan socketio.Message) {
 
 func (b *BTCC) OnDisconnect(output chan socketio.Message) {
 	log.Printf("%s Disconnected from websocket server.. Reconnecting.\n", b.GetName())
-	b.WebsocketClient()
 }
 
 func (b *BTCC) OnError() {
 	log.Printf("%s Error with Websocket connection.. Reconnecting.\n", b.GetName())
-	b.WebsocketClient()
 }
 
 func (b *BTCC) OnMessage(message []byte, output chan socketio.Message) {
@@ -138,12 +137,14 @@ func (b *BTCC) WebsocketClient() {
 		OnDisconnect: b.OnDisconnect,
 	}
 
-	for b.Enabled && b.Websocket {
-		err := socketio.ConnectToSocket(BTCC_SOCKETIO_ADDRESS, BTCCSocket)
-		if err != nil {
-			log.Printf("%s Unable to connect to Websocket. Err: %s\n", err)
-			continue
-		}
-		log.Printf("%s Disconnected from Websocket.")
-	}
+	// socketio handles its own heartbeats and returns once disconnected, so
+	// the supervisor only provides the backoff between attempts.
+	s := GetWebsocketSupervisor(b.GetName())
+	s.Verbose = b.Verbose
+	s.StaleTimeout = 0
+	s.Running = func() bool { return b.Enabled && b.Websocket }
+	s.Dial = func() error { return nil }
+	s.Serve = func() error { return socketio.ConnectToSocket(b.WebsocketURL, BTCCSocket) }
+	s.Close = func() error { return nil }
+	s.Run()
 }
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"log"
@@ -11,8 +12,9 @@ import (
 )
 
 const (
-	BTCE_API_PUBLIC_URL      = "https://btc-e.com/api"
-	BTCE_API_PRIVATE_URL     = "https://btc-e.com/tapi"
+	BTCE_API_URL             = "https://btc-e.com"
+	BTCE_API_PUBLIC          = "api"
+	BTCE_API_PRIVATE         = "tapi"
 	BTCE_API_PUBLIC_VERSION  = "3"
 	BTCE_API_PRIVATE_VERSION = "1"
 	BTCE_INFO                = "info"
@@ -38,6 +40,7 @@ type BTCE struct {
 	Websocket               bool
 	RESTPollingDelay        time.Duration
 	AuthenticatedAPISupport bool
+	APIUrl                  string
 	APIKey, APISecret       string
 	Fee                     float64
 	BaseCurrencies          []string
@@ -65,7 +68,7 @@ type BTCEOrderbook struct {
 
 type BTCETrades struct {
 	Type      string  `json:"type"`
-	Price     float64 `json:"bid"`
+	Price     float64 `json:"price"`
 	Amount    float64 `json:"amount"`
 	TID       int64   `json:"tid"`
 	Timestamp int64   `json:"timestamp"`
@@ -84,6 +87,7 @@ func (b *BTCE) SetDefaults() {
 	b.Verbose = false
 	b.Websocket = false
 	b.RESTPollingDelay = 10
+	b.APIUrl = BTCE_API_URL
 	b.Ticker = make(map[string]BTCeTicker)
 }
 
@@ -112,6 +116,7 @@ func (b *BTCE) Setup(exch Exchanges) {
 		b.BaseCurrencies = SplitStrings(exch.BaseCurrencies, ",")
 		b.AvailablePairs = SplitStrings(exch.AvailablePairs, ",")
 		b.EnabledPairs = SplitStrings(exch.EnabledPairs, ",")
+		b.APIUrl = GetExchangeURLs(exch, ExchangeURLs{API: b.APIUrl}).API
 
 	}
 }
@@ -138,86 +143,134 @@ func (b *BTCE) Run() {
 
 	pairs := []string{}
 	for _, x := range b.EnabledPairs {
-		x = StringToLower(x[0:3] + "_" + x[3:6])
-		pairs = append(pairs, x)
+		pairs = append(pairs, BTCEPairName(x))
 	}
-	pairsString := JoinStrings(pairs, "-")
 
 	for b.Enabled {
-		go func() {
-			ticker, err := b.GetTicker(pairsString)
-			if err != nil {
-				log.Println(err)
-				return
-			}
+		ticker, err := b.GetTicker(JoinStrings(pairs, "-"))
+		if err != nil {
+			log.Println(err)
+		} else {
 			for x, y := range ticker {
-				x = StringToUpper(x[0:3] + x[4:])
-				log.Printf("BTC-e %s: Last %f High %f Low %f Volume %f\n", x, y.Last, y.High, y.Low, y.Vol_cur)
 				b.Ticker[x] = y
-				AddExchangeInfo(b.GetName(), StringToUpper(x[0:3]), StringToUpper(x[4:]), y.Last, y.Vol_cur)
+				currency := StringToUpper(x)
+				log.Printf("BTC-e %s: Last %f High %f Low %f Volume %f\n", currency, y.Last, y.High, y.Low, y.Vol_cur)
+				AddExchangeInfo(b.GetName(), currency[0:3], currency[4:], y.Last, y.Vol_cur)
 			}
-		}()
+		}
 		time.Sleep(time.Second * b.RESTPollingDelay)
 	}
 }
 
-func (b *BTCE) GetInfo() {
-	req := fmt.Sprintf("%s/%s/%s/", BTCE_API_PUBLIC_URL, BTCE_API_PUBLIC_VERSION, BTCE_INFO)
-	err := SendHTTPGetRequest(req, true, nil)
+// BTCEPairName returns BTC-e's name for a pair, e.g. btc_usd for BTCUSD.
+// Several pairs can be requested at once by joining their names with "-".
+func BTCEPairName(pair string) string {
+	pair = StringToLower(pair)
+	if len(pair) == 6 {
+		return pair[0:3] + "_" + pair[3:]
+	}
+	return pair
+}
+
+type BTCEPairInfo struct {
+	DecimalPlaces int     `json:"decimal_places"`
+	MinPrice      float64 `json:"min_price"`
+	MaxPrice      float64 `json:"max_price"`
+	MinAmount     float64 `json:"min_amount"`
+	Hidden        int     `json:"hidden"`
+	Fee           float64 `json:"fee"`
+}
+
+type BTCEInfo struct {
+	ServerTime int64                   `json:"server_time"`
+	Pairs      map[string]BTCEPairInfo `json:"pairs"`
+}
+
+func (b *BTCE) GetInfo() (BTCEInfo, error) {
+	result := BTCEInfo{}
+	err := b.SendPublicHTTPRequest(BTCE_INFO, "", url.Values{}, &result)
 
 	if err != nil {
-		log.Println(err)
+		return result, err
 	}
+	return result, nil
 }
 
+// GetTicker returns the tickers for one or more "-" separated pairs, keyed by
+// pair.
 func (b *BTCE) GetTicker(symbol string) (map[string]BTCeTicker, error) {
-	type Response struct {
-		Data map[string]BTCeTicker
+	result := make(map[string]BTCeTicker)
+	err := b.SendPublicHTTPRequest(BTCE_TICKER, symbol, url.Values{}, &result)
+
+	if err != nil {
+		return nil, err
 	}
+	return result, nil
+}
 
-	response := Response{}
-	req := fmt.Sprintf("%s/%s/%s/%s", BTCE_API_PUBLIC_URL, BTCE_API_PUBLIC_VERSION, BTCE_TICKER, symbol)
-	err := SendHTTPGetRequest(req, true, &response.Data)
+// GetDepth returns the orderbooks for one or more "-" separated pairs, keyed
+// by pair. A limit of 0 uses BTC-e's default of 150 orders per side.
+func (b *BTCE) GetDepth(symbol string, limit int64) (map[string]BTCEOrderbook, error) {
+	values := url.Values{}
+	if limit != 0 {
+		values.Set("limit", strconv.FormatInt(limit, 10))
+	}
+
+	result := make(map[string]BTCEOrderbook)
+	err := b.SendPublicHTTPRequest(BTCE_DEPTH, symbol, values, &result)
 
 	if err != nil {
 		return nil, err
 	}
-	return response.Data, nil
+	return result, nil
 }
 
-func (b *BTCE) GetDepth(symbol string) {
-	type Response struct {
-		Data map[string]BTCEOrderbook
+// GetTrades returns the most recent trades for one or more "-" separated
+// pairs, keyed by pair. A limit of 0 uses BTC-e's default of 150.
+func (b *BTCE) GetTrades(symbol string, limit int64) (map[string][]BTCETrades, error) {
+	values := url.Values{}
+	if limit != 0 {
+		values.Set("limit", strconv.FormatInt(limit, 10))
 	}
 
-	response := Response{}
-	req := fmt.Sprintf("%s/%s/%s/%s", BTCE_API_PUBLIC_URL, BTCE_API_PUBLIC_VERSION, BTCE_DEPTH, symbol)
-	err := SendHTTPGetRequest(req, true, &response.Data)
+	result := make(map[string][]BTCETrades)
+	err := b.SendPublicHTTPRequest(BTCE_TRADES, symbol, values, &result)
 
 	if err != nil {
-		log.Println(err)
-		return
+		return nil, err
 	}
-
-	depth := response.Data[symbol]
-	log.Println(depth)
+	return result, nil
 }
 
-func (b *BTCE) GetTrades(symbol string) {
-	type Response struct {
-		Data map[string][]BTCETrades
+// SendPublicHTTPRequest calls a public API method, which reports errors such
+// as unknown pairs in the body with a success of 0.
+func (b *BTCE) SendPublicHTTPRequest(method, symbol string, values url.Values, result interface{}) error {
+	path := fmt.Sprintf("%s/%s/%s/%s", b.APIUrl, BTCE_API_PUBLIC, BTCE_API_PUBLIC_VERSION, method)
+	if symbol != "" {
+		path += "/" + symbol
 	}
 
-	response := Response{}
-	req := fmt.Sprintf("%s/%s/%s/%s", BTCE_API_PUBLIC_URL, BTCE_API_PUBLIC_VERSION, BTCE_TRADES, symbol)
-	err := SendHTTPGetRequest(req, true, &response.Data)
+	resp := json.RawMessage{}
+	err := GetHTTPClient(b.Name).SendHTTPGetRequest(EncodeURLValues(path, values), true, &resp)
 
 	if err != nil {
-		log.Println(err)
+		return err
 	}
 
-	trades := response.Data[symbol]
-	log.Println(trades)
+	failure := struct {
+		Success *int   `json:"success"`
+		Error   string `json:"error"`
+	}{}
+	if json.Unmarshal(resp, &failure) == nil && failure.Success != nil && *failure.Success != 1 {
+		return NewExchangeError(b.Name, "", failure.Error)
+	}
+
+	err = JSONDecode(resp, result)
+
+	if err != nil {
+		return errors.New("Unable to JSON Unmarshal response.")
+	}
+	return nil
 }
 
 type BTCEFunds struct {
@@ -261,10 +314,10 @@ func (b *BTCE) GetAccountInfo() (BTCEAccountInfo, error) {
 
 type BTCEActiveOrders struct {
 	Pair             string  `json:"pair"`
-	Type             string  `json:"sell"`
+	Type             string  `json:"type"`
 	Amount           float64 `json:"amount"`
 	Rate             float64 `json:"rate"`
-	TimestampCreated float64 `json:"time_created"`
+	TimestampCreated float64 `json:"timestamp_created"`
 	Status           int     `json:"status"`
 }
 
@@ -284,11 +337,11 @@ func (b *BTCE) GetActiveOrders(pair string) (map[string]BTCEActiveOrders, error)
 
 type BTCEOrderInfo struct {
 	Pair             string  `json:"pair"`
-	Type             string  `json:"sell"`
+	Type             string  `json:"type"`
 	StartAmount      float64 `json:"start_amount"`
 	Amount           float64 `json:"amount"`
 	Rate             float64 `json:"rate"`
-	TimestampCreated float64 `json:"time_created"`
+	TimestampCreated float64 `json:"timestamp_created"`
 	Status           int     `json:"status"`
 }
 
@@ -307,7 +360,7 @@ func (b *BTCE) GetOrderInfo(OrderID int64) (map[string]BTCEOrderInfo, error) {
 }
 
 type BTCECancelOrder struct {
-	OrderID float64   `json:"order_id"`
+	OrderID int64     `json:"order_id"`
 	Funds   BTCEFunds `json:"funds"`
 }
 
@@ -328,12 +381,12 @@ func (b *BTCE) CancelOrder(OrderID int64) (bool, error) {
 type BTCETrade struct {
 	Received float64   `json:"received"`
 	Remains  float64   `json:"remains"`
-	OrderID  float64   `json:"order_id"`
+	OrderID  int64     `json:"order_id"`
 	Funds    BTCEFunds `json:"funds"`
 }
 
-//to-do: convert orderid to int64
-func (b *BTCE) Trade(pair, orderType string, amount, price float64) (float64, error) {
+// Trade places a limit order, returning its ID or 0 if it filled immediately.
+func (b *BTCE) Trade(pair, orderType string, amount, price float64) (int64, error) {
 	req := url.Values{}
 	req.Add("pair", pair)
 	req.Add("type", orderType)
@@ -384,7 +437,7 @@ type BTCETradeHistory struct {
 	Type      string  `json:"type"`
 	Amount    float64 `json:"amount"`
 	Rate      float64 `json:"rate"`
-	OrderID   float64 `json:"order_id"`
+	OrderID   int64   `json:"order_id"`
 	MyOrder   int     `json:"is_your_order"`
 	Timestamp float64 `json:"timestamp"`
 }
@@ -477,15 +530,17 @@ func (b *BTCE) RedeemCoupon(coupon string) (BTCERedeemCoupon, error) {
 }
 
 func (b *BTCE) SendAuthenticatedHTTPRequest(method string, values url.Values, result interface{}) (err error) {
-	nonce := strconv.FormatInt(time.Now().Unix(), 10)
+	// BTC-e wants strictly increasing integers under 2^32, so seconds it is
+	nonce := strconv.FormatInt(Nonces.Next(NonceKey(b.Name, b.APIKey), time.Second), 10)
 	values.Set("nonce", nonce)
 	values.Set("method", method)
 
 	encoded := values.Encode()
 	hmac := GetHMAC(HASH_SHA512, []byte(encoded), []byte(b.APISecret))
+	path := fmt.Sprintf("%s/%s", b.APIUrl, BTCE_API_PRIVATE)
 
 	if b.Verbose {
-		log.Printf("Sending POST request to %s calling method %s with params %s\n", BTCE_API_PRIVATE_URL, method, encoded)
+		log.Printf("Sending POST request to %s calling method %s with params %s\n", path, method, encoded)
 	}
 
 	headers := make(map[string]string)
@@ -493,10 +548,10 @@ func (b *BTCE) SendAuthenticatedHTTPRequest(method string, values url.Values, re
 	headers["Sign"] = HexEncodeToString(hmac)
 	headers["Content-Type"] = "application/x-www-form-urlencoded"
 
-	resp, err := SendHTTPRequest("POST", BTCE_API_PRIVATE_URL, headers, strings.NewReader(encoded))
+	resp, err := GetHTTPClient(b.Name).SendAuthenticatedHTTPRequest("POST", path, headers, strings.NewReader(encoded))
 
 	if err != nil {
-		return err
+		return ClassifyExchangeError(b.Name, err)
 	}
 
 	response := BTCEResponse{}
@@ -507,7 +562,7 @@ func (b *BTCE) SendAuthenticatedHTTPRequest(method string, values url.Values, re
 	}
 
 	if response.Success != 1 {
-		return errors.New(response.Error)
+		return NewExchangeError(b.Name, "", response.Error)
 	}
 
 	jsonEncoded, err := JSONEncode(response.Return)
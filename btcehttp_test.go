@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func btceFixtures(t *testing.T) (*BTCE, *fixtureServer) {
+	b := &BTCE{}
+	b.SetDefaults()
+	b.SetAPIKeys("btce-key", "btce-secret")
+	return b, newFixtureServer(t, b.Name, "btce.json")
+}
+
+func TestBTCEPublicFixtures(t *testing.T) {
+	b, f := btceFixtures(t)
+	defer f.Close()
+
+	runFixtureCases(t, f, []fixtureCase{
+		{Name: "GetTicker", Call: func() (interface{}, error) { return b.GetTicker("btc_usd-ltc_btc") },
+			Want: []string{"btc_usd:{High:109.88 Low:91.14 Avg:100.51 Vol:1.6328982249e+06 Vol_cur:16541.51969 Last:101.773 Buy:101.9 Sell:101.773 Updated:1370816308}", "ltc_btc:{High:0.02996"}},
+		{Name: "GetInfo", Call: func() (interface{}, error) { return b.GetInfo() },
+			Want: []string{"ServerTime:1370814956", "btc_usd:{DecimalPlaces:3 MinPrice:0.1 MaxPrice:400 MinAmount:0.01 Hidden:0 Fee:0.2}"}},
+		{Name: "GetDepth", Call: func() (interface{}, error) { return b.GetDepth("btc_usd-ltc_btc", 2) },
+			Params: map[string]string{"limit": "2"},
+			Want:   []string{"btc_usd:{Asks:[[103.426 0.01] [103.5 15]] Bids:[[103.2 2.48502251] [103.082 0.46540304]]}", "ltc_btc:{Asks:[[0.02999 10]]"}},
+		{Name: "GetTrades", Call: func() (interface{}, error) { return b.GetTrades("btc_usd", 0) },
+			Params: map[string]string{"limit": ""},
+			Want:   []string{"btc_usd:[{Type:ask Price:103.6 Amount:0.101 TID:4861261 Timestamp:1370818007} {Type:bid"}},
+	})
+
+	if _, err := b.GetDepth("xxx_usd", 0); err == nil || !strings.Contains(err.Error(), "Invalid pair name") {
+		t.Error(fmt.Sprintf("Test failed. Expected an invalid pair error. Actual %v", err))
+	}
+}
+
+func TestBTCEPairName(t *testing.T) {
+	for pair, name := range map[string]string{"BTCUSD": "btc_usd", "ltcbtc": "ltc_btc", "btc_usd": "btc_usd"} {
+		if result := BTCEPairName(pair); result != name {
+			t.Error(fmt.Sprintf("Test failed. Expected %s for %s. Actual %s", name, pair, result))
+		}
+	}
+}
+
+func TestBTCEAuthenticatedFixtures(t *testing.T) {
+	b, f := btceFixtures(t)
+	defer f.Close()
+
+	runFixtureCases(t, f, []fixtureCase{
+		{Name: "GetAccountInfo", Call: func() (interface{}, error) { return b.GetAccountInfo() },
+			Params: map[string]string{"method": "getInfo"},
+			Want:   []string{"Funds:{BTC:23.998", "USD:325", "OpenOrders:1 Rights:{Info:1 Trade:0 Withdraw:0} ServerTime:1.342123547e+09 TransactionCount:0"}},
+		{Name: "GetActiveOrders", Call: func() (interface{}, error) { return b.GetActiveOrders("btc_usd") },
+			Params: map[string]string{"method": "ActiveOrders", "pair": "btc_usd"},
+			Want:   []string{"343152:{Pair:btc_usd Type:sell Amount:12.345 Rate:485 TimestampCreated:1.34244842e+09 Status:0}"}},
+		{Name: "GetOrderInfo", Call: func() (interface{}, error) { return b.GetOrderInfo(343152) },
+			Params: map[string]string{"method": "OrderInfo", "order_id": "343152"},
+			Want:   []string{"343152:{Pair:btc_usd Type:sell StartAmount:13.345 Amount:12.345 Rate:485"}},
+		{Name: "CancelOrder", Call: func() (interface{}, error) { return b.CancelOrder(343154) },
+			Params: map[string]string{"method": "CancelOrder", "order_id": "343154"},
+			Want:   []string{"true"}},
+		{Name: "CancelOrder unknown", Call: func() (interface{}, error) { return b.CancelOrder(1) },
+			Err: ERROR_ORDER_NOT_FOUND},
+		{Name: "Trade", Call: func() (interface{}, error) { return b.Trade("btc_usd", "sell", 0.1, 450) },
+			Params: map[string]string{"method": "Trade", "pair": "btc_usd", "type": "sell", "amount": "0.1", "rate": "450"},
+			Want:   []string{"0"}},
+		{Name: "Trade insufficient", Call: func() (interface{}, error) { return b.Trade("btc_usd", "buy", 1000, 450) },
+			Err: ERROR_INSUFFICIENT_FUNDS},
+		{Name: "GetTransactionHistory", Call: func() (interface{}, error) {
+			return b.GetTransactionHistory(0, 10, 1081672, "DESC", "1342400000", "1342500000")
+		},
+			Params: map[string]string{"method": "TransHistory", "count": "10", "from_id": "0", "end_id": "1081672", "order": "DESC", "since": "1342400000", "end": "1342500000"},
+			Want:   []string{"1081672:{Type:1 Amount:1 Currency:BTC Description:BTC Payment Status:2 Timestamp:1.34244842e+09}"}},
+		{Name: "GetTradeHistory", Call: func() (interface{}, error) {
+			return b.GetTradeHistory(0, 10, 166830, "ASC", "1342400000", "1342500000", "btc_usd")
+		},
+			Params: map[string]string{"method": "TradeHistory", "pair": "btc_usd", "order": "ASC"},
+			Want:   []string{"166830:{Pair:btc_usd Type:sell Amount:1 Rate:450 OrderID:343148 MyOrder:1 Timestamp:1.342445793e+09}"}},
+		{Name: "WithdrawCoins", Call: func() (interface{}, error) {
+			return b.WithdrawCoins("BTC", 0.009, "1KFHE7w8BhaENAswwryaoccDb6qcT6DbYY")
+		},
+			Params: map[string]string{"method": "WithdrawCoin", "coinName": "BTC", "amount": "0.009", "address": "1KFHE7w8BhaENAswwryaoccDb6qcT6DbYY"},
+			Want:   []string{"{TID:37832629 AmountSent:0.009 Funds:{BTC:0.991"}},
+		{Name: "CreateCoupon", Call: func() (interface{}, error) { return b.CreateCoupon("USD", 1) },
+			Params: map[string]string{"method": "CreateCoupon", "currency": "USD", "amount": "1"},
+			Want:   []string{"{Coupon:BTCE-USD-48ZK87Q3-AL7RZA3R-WYATC5CS-NGM5RDGS-YFZ8HGGS TransID:2186137"}},
+		{Name: "RedeemCoupon", Call: func() (interface{}, error) {
+			return b.RedeemCoupon("BTCE-USD-48ZK87Q3-AL7RZA3R-WYATC5CS-NGM5RDGS-YFZ8HGGS")
+		},
+			Params: map[string]string{"method": "RedeemCoupon", "coupon": "BTCE-USD-48ZK87Q3-AL7RZA3R-WYATC5CS-NGM5RDGS-YFZ8HGGS"},
+			Want:   []string{"{CouponAmount:1 CouponCurrency:USD TransID:2186137}"}},
+	})
+}
+
+func TestBTCESigning(t *testing.T) {
+	b, f := btceFixtures(t)
+	defer f.Close()
+
+	if _, err := b.GetAccountInfo(); err != nil {
+		t.Fatal(err)
+	}
+
+	req := f.Last()
+	if sign := HexEncodeToString(GetHMAC(HASH_SHA512, []byte(req.Body), []byte("btce-secret"))); req.Header.Get("Sign") != sign {
+		t.Error(fmt.Sprintf("Test failed. Expected signature %s. Actual %s", sign, req.Header.Get("Sign")))
+	}
+	if req.Header.Get("Key") != "btce-key" || req.Form().Get("nonce") == "" || req.Header.Get("Content-Type") != "application/x-www-form-urlencoded" {
+		t.Error(fmt.Sprintf("Test failed. Unexpected request %v %s", req.Header, req.Body))
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"math"
 	"net/url"
 	"strconv"
 	"time"
@@ -31,6 +32,7 @@ type BTCMarkets struct {
 	Fee                     float64
 	Ticker                  map[string]BTCMarketsTicker
 	AuthenticatedAPISupport bool
+	APIUrl                  string
 	APIKey, APISecret       string
 	BaseCurrencies          []string
 	AvailablePairs          []string
@@ -94,6 +96,7 @@ func (b *BTCMarkets) SetDefaults() {
 	b.Websocket = false
 	b.RESTPollingDelay = 10
 	b.Ticker = make(map[string]BTCMarketsTicker)
+	b.APIUrl = BTCMARKETS_API_URL
 }
 
 func (b *BTCMarkets) GetName() string {
@@ -121,6 +124,7 @@ func (b *BTCMarkets) Setup(exch Exchanges) {
 		b.BaseCurrencies = SplitStrings(exch.BaseCurrencies, ",")
 		b.AvailablePairs = SplitStrings(exch.AvailablePairs, ",")
 		b.EnabledPairs = SplitStrings(exch.EnabledPairs, ",")
+		b.APIUrl = GetExchangeURLs(exch, ExchangeURLs{API: b.APIUrl}).API
 
 	}
 }
@@ -181,7 +185,7 @@ func (b *BTCMarkets) Run() {
 func (b *BTCMarkets) GetTicker(symbol string) (BTCMarketsTicker, error) {
 	ticker := BTCMarketsTicker{}
 	path := fmt.Sprintf("/market/%s/AUD/tick", symbol)
-	err := SendHTTPGetRequest(BTCMARKETS_API_URL+path, true, &ticker)
+	err := GetHTTPClient(b.Name).SendHTTPGetRequest(b.APIUrl+path, true, &ticker)
 	if err != nil {
 		return BTCMarketsTicker{}, err
 	}
@@ -191,7 +195,7 @@ func (b *BTCMarkets) GetTicker(symbol string) (BTCMarketsTicker, error) {
 func (b *BTCMarkets) GetOrderbook(symbol string) (BTCMarketsOrderbook, error) {
 	orderbook := BTCMarketsOrderbook{}
 	path := fmt.Sprintf("/market/%s/AUD/orderbook", symbol)
-	err := SendHTTPGetRequest(BTCMARKETS_API_URL+path, true, &orderbook)
+	err := GetHTTPClient(b.Name).SendHTTPGetRequest(b.APIUrl+path, true, &orderbook)
 	if err != nil {
 		return BTCMarketsOrderbook{}, err
 	}
@@ -200,15 +204,15 @@ func (b *BTCMarkets) GetOrderbook(symbol string) (BTCMarketsOrderbook, error) {
 
 func (b *BTCMarkets) GetTrades(symbol string, values url.Values) ([]BTCMarketsTrade, error) {
 	trades := []BTCMarketsTrade{}
-	path := EncodeURLValues(fmt.Sprintf("%s/market/%s/AUD/trades", BTCMARKETS_API_URL, symbol), values)
-	err := SendHTTPGetRequest(path, true, &trades)
+	path := EncodeURLValues(fmt.Sprintf("%s/market/%s/AUD/trades", b.APIUrl, symbol), values)
+	err := GetHTTPClient(b.Name).SendHTTPGetRequest(path, true, &trades)
 	if err != nil {
 		return nil, err
 	}
 	return trades, nil
 }
 
-func (b *BTCMarkets) Order(currency, instrument string, price, amount int64, orderSide, orderType, clientReq string) (int, error) {
+func (b *BTCMarkets) Order(currency, instrument string, price, amount float64, orderSide, orderType, clientReq string) (int, error) {
 	type Order struct {
 		Currency        string `json:"currency"`
 		Instrument      string `json:"instrument"`
@@ -221,8 +225,8 @@ func (b *BTCMarkets) Order(currency, instrument string, price, amount int64, ord
 	order := Order{}
 	order.Currency = currency
 	order.Instrument = instrument
-	order.Price = price * SATOSHIS_PER_BTC
-	order.Volume = amount * SATOSHIS_PER_BTC
+	order.Price = int64(math.Floor(price*SATOSHIS_PER_BTC + 0.5))
+	order.Volume = int64(math.Floor(amount*SATOSHIS_PER_BTC + 0.5))
 	order.OrderSide = orderSide
 	order.OrderType = orderType
 	order.ClientRequestId = clientReq
@@ -421,7 +425,7 @@ func (b *BTCMarkets) SendAuthenticatedRequest(reqType, path string, data interfa
 	hmac := GetHMAC(HASH_SHA512, []byte(request), []byte(b.APISecret))
 
 	if b.Verbose {
-		log.Printf("Sending %s request to URL %s with params %s\n", reqType, BTCMARKETS_API_URL+path, request)
+		log.Printf("Sending %s request to URL %s with params %s\n", reqType, b.APIUrl+path, request)
 	}
 
 	headers := make(map[string]string)
@@ -432,10 +436,10 @@ func (b *BTCMarkets) SendAuthenticatedRequest(reqType, path string, data interfa
 	headers["timestamp"] = nonce
 	headers["signature"] = Base64Encode(hmac)
 
-	resp, err := SendHTTPRequest(reqType, BTCMARKETS_API_URL+path, headers, bytes.NewBuffer(payload))
+	resp, err := GetHTTPClient(b.Name).SendAuthenticatedHTTPRequest(reqType, b.APIUrl+path, headers, bytes.NewBuffer(payload))
 
 	if err != nil {
-		return err
+		return ClassifyExchangeError(b.Name, err)
 	}
 
 	if b.Verbose {
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"testing"
+)
+
+func btcmarketsFixtures(t *testing.T) (*BTCMarkets, *fixtureServer) {
+	b := &BTCMarkets{}
+	b.SetDefaults()
+	b.AuthenticatedAPISupport = true
+	b.SetAPIKeys("btcmarkets-key", Base64Encode([]byte("btcmarkets-secret")))
+	return b, newFixtureServer(t, b.Name, "btcmarkets.json")
+}
+
+func TestBTCMarketsPublicFixtures(t *testing.T) {
+	b, f := btcmarketsFixtures(t)
+	defer f.Close()
+
+	runFixtureCases(t, f, []fixtureCase{
+		{Name: "GetTicker", Call: func() (interface{}, error) { return b.GetTicker("BTC") },
+			Want: []string{"{BestBID:844 BestAsk:844.98 LastPrice:845 Currency:AUD Instrument:BTC Timestamp:1476242958}"}},
+		{Name: "GetOrderbook", Call: func() (interface{}, error) { return b.GetOrderbook("BTC") },
+			Want: []string{"{Currency:AUD Instrument:BTC Timestamp:1476243360 Asks:[[844.98 0.45077821] [845 2.7069457]] Bids:[[844 0.00489636] [843.77 0.8]]}"}},
+		{Name: "GetTrades", Call: func() (interface{}, error) { return b.GetTrades("BTC", url.Values{"since": {"4432702311"}}) },
+			Params: map[string]string{"since": "4432702311"},
+			Want:   []string{"[{TradeID:4432702312 Amount:0.01959674 Price:845 Date:1378878093}]"}},
+	})
+}
+
+func TestBTCMarketsAuthenticatedFixtures(t *testing.T) {
+	b, f := btcmarketsFixtures(t)
+	defer f.Close()
+
+	runFixtureCases(t, f, []fixtureCase{
+		{Name: "Order", Call: func() (interface{}, error) { return b.Order("AUD", "BTC", 130, 0.29, "Bid", "Limit", "abc-cdf-1000") },
+			Params: map[string]string{"currency": "AUD", "instrument": "BTC", "price": "13000000000", "volume": "29000000", "orderSide": "Bid", "ordertype": "Limit", "clientRequestId": "abc-cdf-1000"},
+			Want:   []string{"100"}},
+		{Name: "Order rejected", Call: func() (interface{}, error) {
+			id, err := b.Order("AUD", "BTC", 130, 1000, "Bid", "Limit", "abc-cdf-1000")
+			if err == nil {
+				return nil, fmt.Errorf("expected an error placing order %d", id)
+			}
+			return err.Error(), nil
+		},
+			Want: []string{"Insufficient funds."}},
+		{Name: "CancelOrder", Call: func() (interface{}, error) { return b.CancelOrder([]int64{6840125478, 6840125479}) },
+			Params: map[string]string{"orderIds": "[6840125478 6840125479]"},
+			Want:   []string{"true"}},
+		{Name: "CancelOrder unknown", Call: func() (interface{}, error) {
+			cancelled, err := b.CancelOrder([]int64{1})
+			if err == nil {
+				return nil, fmt.Errorf("expected an error cancelling an unknown order, got %v", cancelled)
+			}
+			return cancelled, nil
+		},
+			Want: []string{"false"}},
+		{Name: "GetOrders", Call: func() (interface{}, error) { return b.GetOrders("AUD", "BTC", 10, 1, false) },
+			Params: map[string]string{"currency": "AUD", "instrument": "BTC", "limit": "10", "since": "1"},
+			Want:   []string{"[{ID:1003245675 Currency:AUD Instrument:BTC OrderSide:Bid OrderType:Limit CreationTime:1.378862733366e+12 Status:Placed ErrorMessage: Price:130 Volume:0.1 OpenVolume:0.1 ClientRequestId: Trades:[]}]"}},
+		{Name: "GetOrders historic", Call: func() (interface{}, error) { return b.GetOrders("AUD", "BTC", 10, 1, true) },
+			Want: []string{"Status:Fully Matched", "Trades:[{ID:1003245677 CreationTime:1.378862733366e+12 Description: Price:130 Volume:0.1 Fee:0.013065}]"}},
+		{Name: "GetOrderDetail", Call: func() (interface{}, error) { return b.GetOrderDetail([]int64{1003245675}) },
+			Params: map[string]string{"orderIds": "[1003245675]"},
+			Want:   []string{"ID:1003245675", "Price:130 Volume:0.1 OpenVolume:0.1"}},
+		{Name: "GetAccountBalance", Call: func() (interface{}, error) { return b.GetAccountBalance() },
+			Want: []string{"[{Balance:1e+09 PendingFunds:0 Currency:AUD} {Balance:10 PendingFunds:1 Currency:BTC}]"}},
+	})
+}
+
+func TestBTCMarketsSigning(t *testing.T) {
+	b, f := btcmarketsFixtures(t)
+	defer f.Close()
+
+	for _, x := range []func() error{
+		func() error { _, err := b.GetAccountBalance(); return err },
+		func() error { _, err := b.GetOrderDetail([]int64{1003245675}); return err },
+	} {
+		if err := x(); err != nil {
+			t.Fatal(err)
+		}
+		req := f.Last()
+		message := req.Path + "\n" + req.Header.Get("timestamp") + "\n" + req.Body
+		if sign := Base64Encode(GetHMAC(HASH_SHA512, []byte(message), []byte("btcmarkets-secret"))); req.Header.Get("signature") != sign {
+			t.Error(fmt.Sprintf("Test failed. Expected signature %s for %s. Actual %s", sign, req.Path, req.Header.Get("signature")))
+		}
+		if req.Header.Get("apikey") != "btcmarkets-key" || len(req.Header.Get("timestamp")) != 13 {
+			t.Error(fmt.Sprintf("Test failed. Unexpected headers %v", req.Header))
+		}
+	}
+}
@@ -0,0 +1,366 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	ErrCandlePeriodInvalid      = errors.New("Candle period must be greater than zero.")
+	ErrCandleHistoryUnavailable = errors.New("No candle history source set.")
+)
+
+type Candle struct {
+	Exchange string
+	Pair     string
+	Start    time.Time
+	Period   time.Duration
+	Open     float64
+	High     float64
+	Low      float64
+	Close    float64
+	Volume   float64
+	Trades   int
+}
+
+func (c Candle) End() time.Time {
+	return c.Start.Add(c.Period)
+}
+
+type CandleTrade struct {
+	Price  float64
+	Amount float64
+	Time   time.Time
+}
+
+// CandleHistoryFunc returns closed candles between start and end, used to
+// backfill the builder on startup and whenever the live feed skips a bucket.
+type CandleHistoryFunc func(pair string, start, end time.Time, period time.Duration) ([]Candle, error)
+
+// CandleBuilder aggregates live trades into OHLCV candles of a fixed period.
+// Closed candles are delivered in order on Candles, one per period with no gaps.
+type CandleBuilder struct {
+	Exchange string
+	Pair     string
+	Period   time.Duration
+	Delay    time.Duration // how long past a boundary to wait for late trades, zero for push feeds
+	History  CandleHistoryFunc
+	Verbose  bool
+	Candles  chan Candle
+
+	mtx     sync.Mutex
+	sendMtx sync.Mutex
+	current *Candle
+	last    *Candle
+	stop    chan struct{}
+}
+
+var (
+	CandleBuilders    []*CandleBuilder
+	candleBuildersMtx sync.Mutex
+)
+
+func NewCandleBuilder(exchange, pair string, period time.Duration, history CandleHistoryFunc) (*CandleBuilder, error) {
+	if period <= 0 {
+		return nil, ErrCandlePeriodInvalid
+	}
+
+	c := &CandleBuilder{
+		Exchange: exchange,
+		Pair:     pair,
+		Period:   period,
+		History:  history,
+		Candles:  make(chan Candle, 100),
+		stop:     make(chan struct{}),
+	}
+
+	candleBuildersMtx.Lock()
+	CandleBuilders = append(CandleBuilders, c)
+	candleBuildersMtx.Unlock()
+	return c, nil
+}
+
+func RemoveCandleBuilder(c *CandleBuilder) bool {
+	candleBuildersMtx.Lock()
+	defer candleBuildersMtx.Unlock()
+
+	for i := range CandleBuilders {
+		if CandleBuilders[i] == c {
+			CandleBuilders = append(CandleBuilders[:i], CandleBuilders[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// CandleAddTrade routes a trade from an exchange feed to every builder
+// registered for that exchange and pair. Pairs are compared ignoring case.
+func CandleAddTrade(exchange, pair string, price, amount float64, t time.Time) {
+	candleBuildersMtx.Lock()
+	builders := []*CandleBuilder{}
+	for _, x := range CandleBuilders {
+		if x.Exchange == exchange && strings.EqualFold(x.Pair, pair) {
+			builders = append(builders, x)
+		}
+	}
+	candleBuildersMtx.Unlock()
+
+	for _, x := range builders {
+		x.AddTrade(CandleTrade{Price: price, Amount: amount, Time: t})
+	}
+}
+
+func (c *CandleBuilder) bucket(t time.Time) time.Time {
+	return t.Truncate(c.Period)
+}
+
+func (c *CandleBuilder) AddTrade(trade CandleTrade) {
+	c.mtx.Lock()
+	start := c.bucket(trade.Time)
+	history := c.history(start)
+
+	if (c.last != nil && !start.After(c.last.Start)) || (c.current != nil && start.Before(c.current.Start)) {
+		c.mtx.Unlock()
+		if c.Verbose {
+			log.Printf("%s %s candle builder dropping late trade at %v.\n", c.Exchange, c.Pair, trade.Time)
+		}
+		return
+	}
+
+	closed := []Candle{}
+	if c.current != nil && start.After(c.current.Start) {
+		closed = c.closeUntil(start, history)
+	} else if c.current == nil && c.last != nil {
+		closed = c.fill(c.last.End(), start, history)
+	}
+
+	if c.current == nil {
+		c.current = &Candle{
+			Exchange: c.Exchange,
+			Pair:     c.Pair,
+			Start:    start,
+			Period:   c.Period,
+			Open:     trade.Price,
+			High:     trade.Price,
+			Low:      trade.Price,
+		}
+	}
+
+	if trade.Price > c.current.High {
+		c.current.High = trade.Price
+	}
+	if trade.Price < c.current.Low {
+		c.current.Low = trade.Price
+	}
+	c.current.Close = trade.Price
+	c.current.Volume += trade.Amount
+	c.current.Trades++
+
+	c.emit(closed)
+}
+
+// Close emits every candle which ends at or before now, including empty
+// buckets since the last trade.
+func (c *CandleBuilder) Close(now time.Time) {
+	c.mtx.Lock()
+	boundary := c.bucket(now)
+	c.emit(c.closeUntil(boundary, c.history(boundary)))
+}
+
+// history fetches candles for the empty buckets which closing up to boundary
+// would fill, keyed by start time. mtx is released while the request is
+// made so trades aren't held up by it, so the builder may have moved on by
+// the time it returns. Must be called with mtx held, which it is again on
+// return.
+func (c *CandleBuilder) history(boundary time.Time) map[int64]Candle {
+	history := make(map[int64]Candle)
+	if c.History == nil {
+		return history
+	}
+
+	var from time.Time
+	switch {
+	case c.current != nil:
+		from = c.current.End()
+	case c.last != nil:
+		from = c.last.End()
+	default:
+		return history
+	}
+	if !from.Before(boundary) {
+		return history
+	}
+
+	c.mtx.Unlock()
+	candles, err := c.History(c.Pair, from, boundary, c.Period)
+	c.mtx.Lock()
+	if err != nil {
+		log.Printf("%s %s unable to backfill candles. Error: %s\n", c.Exchange, c.Pair, err)
+	}
+	for _, x := range candles {
+		history[x.Start.Unix()] = x
+	}
+	return history
+}
+
+// closeUntil closes the current candle if it ends by boundary and fills any
+// empty buckets up to boundary. Must be called with mtx held.
+func (c *CandleBuilder) closeUntil(boundary time.Time, history map[int64]Candle) []Candle {
+	closed := []Candle{}
+	if c.current != nil {
+		if c.current.End().After(boundary) {
+			return closed
+		}
+		closed = append(closed, *c.current)
+		c.last = &closed[0]
+		c.current = nil
+	}
+
+	if c.last == nil {
+		return closed
+	}
+	return append(closed, c.fill(c.last.End(), boundary, history)...)
+}
+
+// fill produces the candles for the empty buckets in [from, to). History is
+// preferred, falling back to flat candles at the last close when the source
+// was unavailable or had nothing for the bucket. Must be called with mtx
+// held.
+func (c *CandleBuilder) fill(from, to time.Time, history map[int64]Candle) []Candle {
+	filled := []Candle{}
+	if !from.Before(to) {
+		return filled
+	}
+
+	for t := from; t.Before(to); t = t.Add(c.Period) {
+		candle, ok := history[t.Unix()]
+		if !ok {
+			candle = Candle{Start: t, Open: c.last.Close, High: c.last.Close, Low: c.last.Close, Close: c.last.Close}
+		}
+		candle.Exchange = c.Exchange
+		candle.Pair = c.Pair
+		candle.Period = c.Period
+		filled = append(filled, candle)
+		c.last = &filled[len(filled)-1]
+	}
+	return filled
+}
+
+// emit releases mtx and delivers closed candles in order. sendMtx is taken
+// before mtx is released so concurrent callers can't reorder the stream.
+func (c *CandleBuilder) emit(closed []Candle) {
+	c.sendMtx.Lock()
+	c.mtx.Unlock()
+	defer c.sendMtx.Unlock()
+
+	for _, x := range closed {
+		if c.Verbose {
+			log.Printf("%s %s candle t=%v open=%f high=%f low=%f close=%f volume=%f trades=%d\n", c.Exchange, c.Pair, x.Start, x.Open, x.High, x.Low, x.Close, x.Volume, x.Trades)
+		}
+		c.Candles <- x
+	}
+}
+
+// Backfill fetches closed candles in [start, end) from History and seeds the
+// builder with them, so live candles continue on from the last one returned.
+func (c *CandleBuilder) Backfill(start, end time.Time) ([]Candle, error) {
+	if c.History == nil {
+		return nil, ErrCandleHistoryUnavailable
+	}
+
+	now := time.Now()
+	if end.After(now) {
+		end = now
+	}
+
+	candles, err := c.History(c.Pair, c.bucket(start), c.bucket(end), c.Period)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	closed := []Candle{}
+	for _, x := range candles {
+		x.Exchange = c.Exchange
+		x.Pair = c.Pair
+		x.Period = c.Period
+		if x.End().After(end) || (c.last != nil && !x.Start.After(c.last.Start)) {
+			continue
+		}
+		closed = append(closed, x)
+	}
+
+	if len(closed) > 0 {
+		last := closed[len(closed)-1]
+		c.last = &last
+		if c.current != nil && !c.current.Start.After(last.Start) {
+			c.current = nil
+		}
+	}
+	return closed, nil
+}
+
+// Run closes candles on every period boundary (plus Delay) until Stop.
+func (c *CandleBuilder) Run() {
+	for {
+		next := c.bucket(time.Now()).Add(c.Period)
+		timer := time.NewTimer(next.Add(c.Delay).Sub(time.Now()))
+
+		select {
+		case <-c.stop:
+			timer.Stop()
+			return
+		case <-timer.C:
+			c.Close(next)
+		}
+	}
+}
+
+func (c *CandleBuilder) Stop() {
+	close(c.stop)
+	RemoveCandleBuilder(c)
+}
+
+// Done is closed by Stop, so polling feeds started for the builder can stop
+// with it.
+func (c *CandleBuilder) Done() <-chan struct{} {
+	return c.stop
+}
+
+// CandlesFromTrades aggregates historical trades into candles, used by
+// exchanges which only offer trade history over REST.
+func CandlesFromTrades(trades []CandleTrade, period time.Duration) []Candle {
+	sort.Stable(candleTradesByTime(trades))
+
+	candles := []Candle{}
+	for _, x := range trades {
+		start := x.Time.Truncate(period)
+		if len(candles) == 0 || candles[len(candles)-1].Start != start {
+			candles = append(candles, Candle{Start: start, Period: period, Open: x.Price, High: x.Price, Low: x.Price})
+		}
+
+		candle := &candles[len(candles)-1]
+		if x.Price > candle.High {
+			candle.High = x.Price
+		}
+		if x.Price < candle.Low {
+			candle.Low = x.Price
+		}
+		candle.Close = x.Price
+		candle.Volume += x.Amount
+		candle.Trades++
+	}
+	return candles
+}
+
+type candleTradesByTime []CandleTrade
+
+func (t candleTradesByTime) Len() int           { return len(t) }
+func (t candleTradesByTime) Less(i, j int) bool { return t[i].Time.Before(t[j].Time) }
+func (t candleTradesByTime) Swap(i, j int)      { t[i], t[j] = t[j], t[i] }
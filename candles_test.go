@@ -0,0 +1,150 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestCandleBuilder(t *testing.T) {
+	t.Parallel()
+	c, err := NewCandleBuilder("Test", "BTCUSD", time.Minute, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer RemoveCandleBuilder(c)
+
+	base := time.Unix(1465776000, 0)
+	c.AddTrade(CandleTrade{Price: 10, Amount: 1, Time: base.Add(5 * time.Second)})
+	c.AddTrade(CandleTrade{Price: 12, Amount: 2, Time: base.Add(20 * time.Second)})
+	c.AddTrade(CandleTrade{Price: 9, Amount: 1, Time: base.Add(40 * time.Second)})
+	c.AddTrade(CandleTrade{Price: 11, Amount: 1, Time: base.Add(59 * time.Second)})
+
+	if len(c.Candles) != 0 {
+		t.Error(fmt.Sprintf("Test failed. Expected no closed candles. Actual %d", len(c.Candles)))
+	}
+
+	// skips a minute, which should be filled with a flat candle
+	c.AddTrade(CandleTrade{Price: 13, Amount: 1, Time: base.Add(150 * time.Second)})
+	c.AddTrade(CandleTrade{Price: 1, Amount: 1, Time: base.Add(30 * time.Second)}) // late
+
+	expected := []Candle{
+		{Start: base, Open: 10, High: 12, Low: 9, Close: 11, Volume: 5, Trades: 4},
+		{Start: base.Add(time.Minute), Open: 11, High: 11, Low: 11, Close: 11},
+	}
+	for _, x := range expected {
+		actual := <-c.Candles
+		if !actual.Start.Equal(x.Start) || actual.Open != x.Open || actual.High != x.High || actual.Low != x.Low || actual.Close != x.Close || actual.Volume != x.Volume || actual.Trades != x.Trades {
+			t.Error(fmt.Sprintf("Test failed. Expected %+v. Actual %+v", x, actual))
+		}
+	}
+
+	c.Close(base.Add(200 * time.Second))
+	actual := <-c.Candles
+	if !actual.Start.Equal(base.Add(2*time.Minute)) || actual.Open != 13 || actual.Close != 13 || actual.Trades != 1 {
+		t.Error(fmt.Sprintf("Test failed. Expected candle at %v. Actual %+v", base.Add(2*time.Minute), actual))
+	}
+
+	if len(c.Candles) != 0 {
+		t.Error(fmt.Sprintf("Test failed. Expected no closed candles. Actual %d", len(c.Candles)))
+	}
+}
+
+func TestCandleBuilderHistory(t *testing.T) {
+	t.Parallel()
+	base := time.Unix(1465776000, 0)
+	history := func(pair string, start, end time.Time, period time.Duration) ([]Candle, error) {
+		candles := []Candle{}
+		for x := start; x.Before(end); x = x.Add(period) {
+			candles = append(candles, Candle{Start: x, Open: 5, High: 6, Low: 4, Close: 5, Volume: 1, Trades: 1})
+		}
+		return candles, nil
+	}
+
+	c, err := NewCandleBuilder("Test", "BTCUSD", time.Minute, history)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer RemoveCandleBuilder(c)
+
+	backfill, err := c.Backfill(base, base.Add(3*time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(backfill) != 3 {
+		t.Error(fmt.Sprintf("Test failed. Expected 3 candles. Actual %d", len(backfill)))
+	}
+
+	c.AddTrade(CandleTrade{Price: 7, Amount: 1, Time: base.Add(5*time.Minute + time.Second)})
+	for i := 3; i < 5; i++ {
+		actual := <-c.Candles
+		if !actual.Start.Equal(base.Add(time.Duration(i)*time.Minute)) || actual.Close != 5 || actual.Trades != 1 {
+			t.Error(fmt.Sprintf("Test failed. Expected backfilled candle %d. Actual %+v", i, actual))
+		}
+	}
+}
+
+func TestCandleBuilderHistoryUnlocked(t *testing.T) {
+	t.Parallel()
+	base := time.Unix(1465776000, 0)
+	requested, release := make(chan struct{}), make(chan struct{})
+	history := func(pair string, start, end time.Time, period time.Duration) ([]Candle, error) {
+		close(requested)
+		<-release
+		candles := []Candle{}
+		for x := start; x.Before(end); x = x.Add(period) {
+			candles = append(candles, Candle{Start: x, Open: 5, High: 6, Low: 4, Close: 5, Volume: 1, Trades: 1})
+		}
+		return candles, nil
+	}
+
+	c, err := NewCandleBuilder("Test", "BTCUSD-UNLOCKED", time.Minute, history)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer RemoveCandleBuilder(c)
+
+	c.AddTrade(CandleTrade{Price: 7, Amount: 1, Time: base.Add(30 * time.Second)})
+	go c.Close(base.Add(3 * time.Minute))
+	<-requested
+
+	// trades keep coming in while the history request is outstanding
+	added := make(chan struct{})
+	go func() {
+		c.AddTrade(CandleTrade{Price: 8, Amount: 1, Time: base.Add(40 * time.Second)})
+		close(added)
+	}()
+	select {
+	case <-added:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Test failed. Expected trades not to wait on the history request")
+	}
+	close(release)
+
+	if actual := <-c.Candles; actual.Close != 8 || actual.Trades != 2 {
+		t.Error(fmt.Sprintf("Test failed. Expected both trades in the first candle. Actual %+v", actual))
+	}
+	for i := 1; i < 3; i++ {
+		if actual := <-c.Candles; !actual.Start.Equal(base.Add(time.Duration(i)*time.Minute)) || actual.Close != 5 || actual.Trades != 1 {
+			t.Error(fmt.Sprintf("Test failed. Expected backfilled candle %d. Actual %+v", i, actual))
+		}
+	}
+}
+
+func TestCandlesFromTrades(t *testing.T) {
+	t.Parallel()
+	base := time.Unix(1465776000, 0)
+	trades := []CandleTrade{
+		{Price: 3, Amount: 1, Time: base.Add(70 * time.Second)},
+		{Price: 1, Amount: 1, Time: base.Add(10 * time.Second)},
+		{Price: 2, Amount: 1, Time: base.Add(20 * time.Second)},
+	}
+
+	candles := CandlesFromTrades(trades, time.Minute)
+	if len(candles) != 2 {
+		t.Fatal(fmt.Sprintf("Test failed. Expected 2 candles. Actual %d", len(candles)))
+	}
+	if candles[0].Open != 1 || candles[0].Close != 2 || candles[0].Volume != 2 || candles[1].Open != 3 {
+		t.Error(fmt.Sprintf("Test failed. Unexpected candles %+v", candles))
+	}
+}
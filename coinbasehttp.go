@@ -7,26 +7,28 @@ import (
 	"log"
 	"net/url"
 	"strconv"
+	"sync"
 	"time"
 )
 
 const (
-	COINBASE_API_URL     = "https://api.exchange.coinbase.com/"
-	COINBASE_API_VERISON = "0"
-	COINBASE_PRODUCTS    = "products"
-	COINBASE_ORDERBOOK   = "book"
-	COINBASE_TICKER      = "ticker"
-	COINBASE_TRADES      = "trades"
-	COINBASE_HISTORY     = "candles"
-	COINBASE_STATS       = "stats"
-	COINBASE_CURRENCIES  = "currencies"
-	COINBASE_ACCOUNTS    = "accounts"
-	COINBASE_LEDGER      = "ledger"
-	COINBASE_HOLDS       = "holds"
-	COINBASE_ORDERS      = "orders"
-	COINBASE_FILLS       = "fills"
-	COINBASE_TRANSFERS   = "transfers"
-	COINBASE_REPORTS     = "reports"
+	COINBASE_API_URL         = "https://api.exchange.coinbase.com/"
+	COINBASE_SANDBOX_API_URL = "https://api-public.sandbox.gdax.com/"
+	COINBASE_API_VERISON     = "0"
+	COINBASE_PRODUCTS        = "products"
+	COINBASE_ORDERBOOK       = "book"
+	COINBASE_TICKER          = "ticker"
+	COINBASE_TRADES          = "trades"
+	COINBASE_HISTORY         = "candles"
+	COINBASE_STATS           = "stats"
+	COINBASE_CURRENCIES      = "currencies"
+	COINBASE_ACCOUNTS        = "accounts"
+	COINBASE_LEDGER          = "ledger"
+	COINBASE_HOLDS           = "holds"
+	COINBASE_ORDERS          = "orders"
+	COINBASE_FILLS           = "fills"
+	COINBASE_TRANSFERS       = "transfers"
+	COINBASE_REPORTS         = "reports"
 )
 
 type Coinbase struct {
@@ -34,13 +36,17 @@ type Coinbase struct {
 	Enabled                     bool
 	Verbose                     bool
 	Websocket                   bool
+	WebsocketURL                string
 	RESTPollingDelay            time.Duration
 	AuthenticatedAPISupport     bool
+	APIUrl                      string
 	Password, APIKey, APISecret string
 	TakerFee, MakerFee          float64
 	BaseCurrencies              []string
 	AvailablePairs              []string
 	EnabledPairs                []string
+	Orderbooks                  map[string]*CoinbaseOrderbook
+	orderbooksMtx               sync.Mutex
 }
 
 type CoinbaseTicker struct {
@@ -54,10 +60,10 @@ type CoinbaseProduct struct {
 	ID             string  `json:"id"`
 	BaseCurrency   string  `json:"base_currency"`
 	QuoteCurrency  string  `json:"quote_currency"`
-	BaseMinSize    float64 `json:"base_min_size"`
-	BaseMaxSize    int64   `json:"base_max_size"`
-	QuoteIncrement float64 `json:"quote_increment"`
-	DisplayName    string  `json:"string"`
+	BaseMinSize    float64 `json:"base_min_size,string"`
+	BaseMaxSize    float64 `json:"base_max_size,string"`
+	QuoteIncrement float64 `json:"quote_increment,string"`
+	DisplayName    string  `json:"display_name"`
 }
 
 type CoinbaseOrderL1L2 struct {
@@ -74,13 +80,13 @@ type CoinbaseOrderL3 struct {
 
 type CoinbaseOrderbookL1L2 struct {
 	Sequence int64                 `json:"sequence"`
-	Bids     [][]CoinbaseOrderL1L2 `json:"asks"`
+	Bids     [][]CoinbaseOrderL1L2 `json:"bids"`
 	Asks     [][]CoinbaseOrderL1L2 `json:"asks"`
 }
 
 type CoinbaseOrderbookL3 struct {
 	Sequence int64               `json:"sequence"`
-	Bids     [][]CoinbaseOrderL3 `json:"asks"`
+	Bids     [][]CoinbaseOrderL3 `json:"bids"`
 	Asks     [][]CoinbaseOrderL3 `json:"asks"`
 }
 
@@ -129,6 +135,8 @@ func (c *Coinbase) SetDefaults() {
 	c.Verbose = false
 	c.Websocket = false
 	c.RESTPollingDelay = 10
+	c.APIUrl = COINBASE_API_URL
+	c.WebsocketURL = COINBASE_WEBSOCKET_URL
 }
 
 func (c *Coinbase) GetName() string {
@@ -156,6 +164,8 @@ func (c *Coinbase) Setup(exch Exchanges) {
 		c.BaseCurrencies = SplitStrings(exch.BaseCurrencies, ",")
 		c.AvailablePairs = SplitStrings(exch.AvailablePairs, ",")
 		c.EnabledPairs = SplitStrings(exch.EnabledPairs, ",")
+		urls := GetExchangeURLs(exch, ExchangeURLs{c.APIUrl, c.WebsocketURL})
+		c.APIUrl, c.WebsocketURL = urls.API, urls.Websocket
 	}
 }
 
@@ -173,7 +183,7 @@ func (c *Coinbase) GetFee(maker bool) float64 {
 
 func (c *Coinbase) Run() {
 	if c.Verbose {
-		log.Printf("%s Websocket: %s. (url: %s).\n", c.GetName(), IsEnabled(c.Websocket), COINBASE_WEBSOCKET_URL)
+		log.Printf("%s Websocket: %s. (url: %s).\n", c.GetName(), IsEnabled(c.Websocket), c.WebsocketURL)
 		log.Printf("%s polling delay: %ds.\n", c.GetName(), c.RESTPollingDelay)
 		log.Printf("%s %d currencies enabled: %s.\n", c.GetName(), len(c.EnabledPairs), c.EnabledPairs)
 	}
@@ -249,7 +259,7 @@ func (c *Coinbase) SetAPIKeys(password, apiKey, apiSecret string) {
 
 func (c *Coinbase) GetProducts() ([]CoinbaseProduct, error) {
 	products := []CoinbaseProduct{}
-	err := SendHTTPGetRequest(COINBASE_API_URL+COINBASE_PRODUCTS, true, &products)
+	err := GetHTTPClient(c.Name).SendHTTPGetRequest(c.APIUrl+COINBASE_PRODUCTS, true, &products)
 
 	if err != nil {
 		return nil, err
@@ -263,12 +273,12 @@ func (c *Coinbase) GetOrderbook(symbol string, level int) (interface{}, error) {
 	path := ""
 	if level > 0 {
 		levelStr := strconv.Itoa(level)
-		path = fmt.Sprintf("%s/%s/%s?level=%s", COINBASE_API_URL+COINBASE_PRODUCTS, symbol, COINBASE_ORDERBOOK, levelStr)
+		path = fmt.Sprintf("%s/%s/%s?level=%s", c.APIUrl+COINBASE_PRODUCTS, symbol, COINBASE_ORDERBOOK, levelStr)
 	} else {
-		path = fmt.Sprintf("%s/%s/%s", COINBASE_API_URL+COINBASE_PRODUCTS, symbol, COINBASE_ORDERBOOK)
+		path = fmt.Sprintf("%s/%s/%s", c.APIUrl+COINBASE_PRODUCTS, symbol, COINBASE_ORDERBOOK)
 	}
 
-	err := SendHTTPGetRequest(path, true, &orderbook)
+	err := GetHTTPClient(c.Name).SendHTTPGetRequest(path, true, &orderbook)
 	if err != nil {
 		return nil, err
 	}
@@ -350,8 +360,8 @@ func (c *Coinbase) GetOrderbook(symbol string, level int) (interface{}, error) {
 
 func (c *Coinbase) GetTicker(symbol string) (CoinbaseTicker, error) {
 	ticker := CoinbaseTicker{}
-	path := fmt.Sprintf("%s/%s/%s", COINBASE_API_URL+COINBASE_PRODUCTS, symbol, COINBASE_TICKER)
-	err := SendHTTPGetRequest(path, true, &ticker)
+	path := fmt.Sprintf("%s/%s/%s", c.APIUrl+COINBASE_PRODUCTS, symbol, COINBASE_TICKER)
+	err := GetHTTPClient(c.Name).SendHTTPGetRequest(path, true, &ticker)
 
 	if err != nil {
 		return ticker, err
@@ -361,8 +371,8 @@ func (c *Coinbase) GetTicker(symbol string) (CoinbaseTicker, error) {
 
 func (c *Coinbase) GetTrades(symbol string) ([]CoinbaseTrade, error) {
 	trades := []CoinbaseTrade{}
-	path := fmt.Sprintf("%s/%s/%s", COINBASE_API_URL+COINBASE_PRODUCTS, symbol, COINBASE_TRADES)
-	err := SendHTTPGetRequest(path, true, &trades)
+	path := fmt.Sprintf("%s/%s/%s", c.APIUrl+COINBASE_PRODUCTS, symbol, COINBASE_TRADES)
+	err := GetHTTPClient(c.Name).SendHTTPGetRequest(path, true, &trades)
 
 	if err != nil {
 		return nil, err
@@ -370,8 +380,8 @@ func (c *Coinbase) GetTrades(symbol string) ([]CoinbaseTrade, error) {
 	return trades, nil
 }
 
+// GetHistoricRates returns candles of granularity seconds, newest first.
 func (c *Coinbase) GetHistoricRates(symbol string, start, end, granularity int64) ([]CoinbaseHistory, error) {
-	history := []CoinbaseHistory{}
 	values := url.Values{}
 
 	if start > 0 {
@@ -386,19 +396,29 @@ func (c *Coinbase) GetHistoricRates(symbol string, start, end, granularity int64
 		values.Set("granularity", strconv.FormatInt(granularity, 10))
 	}
 
-	path := EncodeURLValues(fmt.Sprintf("%s/%s/%s", COINBASE_API_URL+COINBASE_PRODUCTS, symbol, COINBASE_HISTORY), values)
-	err := SendHTTPGetRequest(path, true, &history)
+	// each candle is [time, low, high, open, close, volume]
+	candles := [][]float64{}
+	path := EncodeURLValues(fmt.Sprintf("%s/%s/%s", c.APIUrl+COINBASE_PRODUCTS, symbol, COINBASE_HISTORY), values)
+	err := GetHTTPClient(c.Name).SendHTTPGetRequest(path, true, &candles)
 
 	if err != nil {
 		return nil, err
 	}
+
+	history := []CoinbaseHistory{}
+	for _, x := range candles {
+		if len(x) < 6 {
+			continue
+		}
+		history = append(history, CoinbaseHistory{int64(x[0]), x[1], x[2], x[3], x[4], x[5]})
+	}
 	return history, nil
 }
 
 func (c *Coinbase) GetStats(symbol string) (CoinbaseStats, error) {
 	stats := CoinbaseStats{}
-	path := fmt.Sprintf("%s/%s/%s", COINBASE_API_URL+COINBASE_PRODUCTS, symbol, COINBASE_STATS)
-	err := SendHTTPGetRequest(path, true, &stats)
+	path := fmt.Sprintf("%s/%s/%s", c.APIUrl+COINBASE_PRODUCTS, symbol, COINBASE_STATS)
+	err := GetHTTPClient(c.Name).SendHTTPGetRequest(path, true, &stats)
 
 	if err != nil {
 		return stats, err
@@ -408,7 +428,7 @@ func (c *Coinbase) GetStats(symbol string) (CoinbaseStats, error) {
 
 func (c *Coinbase) GetCurrencies() ([]CoinbaseCurrency, error) {
 	currencies := []CoinbaseCurrency{}
-	err := SendHTTPGetRequest(COINBASE_API_URL+COINBASE_CURRENCIES, true, &currencies)
+	err := GetHTTPClient(c.Name).SendHTTPGetRequest(c.APIUrl+COINBASE_CURRENCIES, true, &currencies)
 
 	if err != nil {
 		return nil, err
@@ -426,7 +446,7 @@ type CoinbaseAccountResponse struct {
 
 func (c *Coinbase) GetAccounts() ([]CoinbaseAccountResponse, error) {
 	resp := []CoinbaseAccountResponse{}
-	err := c.SendAuthenticatedHTTPRequest("GET", COINBASE_API_URL+COINBASE_ACCOUNTS, nil, &resp)
+	err := c.SendAuthenticatedHTTPRequest("GET", COINBASE_ACCOUNTS, nil, &resp)
 	if err != nil {
 		return nil, err
 	}
@@ -437,7 +457,7 @@ func (c *Coinbase) GetAccounts() ([]CoinbaseAccountResponse, error) {
 func (c *Coinbase) GetAccount(account string) (CoinbaseAccountResponse, error) {
 	resp := CoinbaseAccountResponse{}
 	path := fmt.Sprintf("%s/%s", COINBASE_ACCOUNTS, account)
-	err := c.SendAuthenticatedHTTPRequest("GET", COINBASE_API_URL+path, nil, &resp)
+	err := c.SendAuthenticatedHTTPRequest("GET", path, nil, &resp)
 	if err != nil {
 		return resp, err
 	}
@@ -450,13 +470,13 @@ type CoinbaseAccountLedgerResponse struct {
 	Amount    float64     `json:"amount,string"`
 	Balance   float64     `json:"balance,string"`
 	Type      string      `json:"type"`
-	details   interface{} `json:"details"`
+	Details   interface{} `json:"details"`
 }
 
 func (c *Coinbase) GetAccountHistory(accountID string) ([]CoinbaseAccountLedgerResponse, error) {
 	resp := []CoinbaseAccountLedgerResponse{}
 	path := fmt.Sprintf("%s/%s/%s", COINBASE_ACCOUNTS, accountID, COINBASE_LEDGER)
-	err := c.SendAuthenticatedHTTPRequest("GET", COINBASE_API_URL+path, nil, &resp)
+	err := c.SendAuthenticatedHTTPRequest("GET", path, nil, &resp)
 	if err != nil {
 		return nil, err
 	}
@@ -476,7 +496,7 @@ type CoinbaseAccountHolds struct {
 func (c *Coinbase) GetHolds(accountID string) ([]CoinbaseAccountHolds, error) {
 	resp := []CoinbaseAccountHolds{}
 	path := fmt.Sprintf("%s/%s/%s", COINBASE_ACCOUNTS, accountID, COINBASE_HOLDS)
-	err := c.SendAuthenticatedHTTPRequest("GET", COINBASE_API_URL+path, nil, &resp)
+	err := c.SendAuthenticatedHTTPRequest("GET", path, nil, &resp)
 	if err != nil {
 		return nil, err
 	}
@@ -504,7 +524,7 @@ func (c *Coinbase) PlaceOrder(clientRef string, price, amount float64, side stri
 	}
 
 	resp := OrderResponse{}
-	err := c.SendAuthenticatedHTTPRequest("POST", COINBASE_API_URL+COINBASE_ORDERS, request, &resp)
+	err := c.SendAuthenticatedHTTPRequest("POST", COINBASE_ORDERS, request, &resp)
 	if err != nil {
 		return "", err
 	}
@@ -514,7 +534,7 @@ func (c *Coinbase) PlaceOrder(clientRef string, price, amount float64, side stri
 
 func (c *Coinbase) CancelOrder(orderID string) error {
 	path := fmt.Sprintf("%s/%s", COINBASE_ORDERS, orderID)
-	err := c.SendAuthenticatedHTTPRequest("DELETE", COINBASE_API_URL+path, nil, nil)
+	err := c.SendAuthenticatedHTTPRequest("DELETE", path, nil, nil)
 	if err != nil {
 		return err
 	}
@@ -535,7 +555,7 @@ type CoinbaseOrdersResponse struct {
 }
 
 func (c *Coinbase) GetOrders(params url.Values) ([]CoinbaseOrdersResponse, error) {
-	path := EncodeURLValues(COINBASE_API_URL+COINBASE_ORDERS, params)
+	path := EncodeURLValues(COINBASE_ORDERS, params)
 	resp := []CoinbaseOrdersResponse{}
 	err := c.SendAuthenticatedHTTPRequest("GET", path, nil, &resp)
 	if err != nil {
@@ -562,7 +582,7 @@ type CoinbaseOrderResponse struct {
 func (c *Coinbase) GetOrder(orderID string) (CoinbaseOrderResponse, error) {
 	path := fmt.Sprintf("%s/%s", COINBASE_ORDERS, orderID)
 	resp := CoinbaseOrderResponse{}
-	err := c.SendAuthenticatedHTTPRequest("GET", COINBASE_API_URL+path, nil, &resp)
+	err := c.SendAuthenticatedHTTPRequest("GET", path, nil, &resp)
 	if err != nil {
 		return resp, err
 	}
@@ -583,7 +603,7 @@ type CoinbaseFillResponse struct {
 }
 
 func (c *Coinbase) GetFills(params url.Values) ([]CoinbaseFillResponse, error) {
-	path := EncodeURLValues(COINBASE_API_URL+COINBASE_FILLS, params)
+	path := EncodeURLValues(COINBASE_FILLS, params)
 	resp := []CoinbaseFillResponse{}
 	err := c.SendAuthenticatedHTTPRequest("GET", path, nil, &resp)
 	if err != nil {
@@ -598,7 +618,7 @@ func (c *Coinbase) Transfer(transferType string, amount float64, accountID strin
 	request["amount"] = strconv.FormatFloat(amount, 'f', -1, 64)
 	request["coinbase_account_id"] = accountID
 
-	err := c.SendAuthenticatedHTTPRequest("POST", COINBASE_API_URL+COINBASE_TRANSFERS, request, nil)
+	err := c.SendAuthenticatedHTTPRequest("POST", COINBASE_TRANSFERS, request, nil)
 	if err != nil {
 		return err
 	}
@@ -616,7 +636,7 @@ type CoinbaseReportResponse struct {
 	Params      struct {
 		StartDate string `json:"start_date"`
 		EndDate   string `json:"end_date"`
-	} `json:params"`
+	} `json:"params"`
 }
 
 func (c *Coinbase) GetReport(reportType, startDate, endDate string) (CoinbaseReportResponse, error) {
@@ -626,7 +646,7 @@ func (c *Coinbase) GetReport(reportType, startDate, endDate string) (CoinbaseRep
 	request["end_date"] = endDate
 
 	resp := CoinbaseReportResponse{}
-	err := c.SendAuthenticatedHTTPRequest("POST", COINBASE_API_URL+COINBASE_REPORTS, request, &resp)
+	err := c.SendAuthenticatedHTTPRequest("POST", COINBASE_REPORTS, request, &resp)
 	if err != nil {
 		return resp, err
 	}
@@ -636,15 +656,17 @@ func (c *Coinbase) GetReport(reportType, startDate, endDate string) (CoinbaseRep
 func (c *Coinbase) GetReportStatus(reportID string) (CoinbaseReportResponse, error) {
 	path := fmt.Sprintf("%s/%s", COINBASE_REPORTS, reportID)
 	resp := CoinbaseReportResponse{}
-	err := c.SendAuthenticatedHTTPRequest("POST", COINBASE_API_URL+path, nil, &resp)
+	err := c.SendAuthenticatedHTTPRequest("GET", path, nil, &resp)
 	if err != nil {
 		return resp, err
 	}
 	return resp, nil
 }
 
+// SendAuthenticatedHTTPRequest sends a request to path, relative to the API
+// URL, with params as the JSON body. A nil result ignores the response.
 func (c *Coinbase) SendAuthenticatedHTTPRequest(method, path string, params map[string]interface{}, result interface{}) (err error) {
-	timestamp := strconv.FormatInt(time.Now().UnixNano(), 10)[0:13]
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
 	payload := []byte("")
 
 	if params != nil {
@@ -659,7 +681,7 @@ func (c *Coinbase) SendAuthenticatedHTTPRequest(method, path string, params map[
 		}
 	}
 
-	message := timestamp + method + path + string(payload)
+	message := timestamp + method + "/" + path + string(payload)
 	hmac := GetHMAC(HASH_SHA256, []byte(message), []byte(c.APISecret))
 	headers := make(map[string]string)
 	headers["CB-ACCESS-SIGN"] = Base64Encode([]byte(hmac))
@@ -668,12 +690,26 @@ func (c *Coinbase) SendAuthenticatedHTTPRequest(method, path string, params map[
 	headers["CB-ACCESS-PASSPHRASE"] = c.Password
 	headers["Content-Type"] = "application/json"
 
-	resp, err := SendHTTPRequest(method, COINBASE_API_URL+path, headers, bytes.NewBuffer(payload))
+	resp, err := GetHTTPClient(c.Name).SendAuthenticatedHTTPRequest(method, c.APIUrl+path, headers, bytes.NewBuffer(payload))
 
 	if c.Verbose {
 		log.Printf("Recieved raw: \n%s\n", resp)
 	}
 
+	if err != nil {
+		return ClassifyExchangeError(c.Name, err)
+	}
+
+	// errors are usually sent with a 4xx, but not always
+	err = CheckExchangeErrorBody(c.Name, resp)
+	if err != nil {
+		return err
+	}
+
+	if result == nil {
+		return nil
+	}
+
 	err = JSONDecode([]byte(resp), &result)
 
 	if err != nil {
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"testing"
+)
+
+func coinbaseFixtures(t *testing.T) (*Coinbase, *fixtureServer) {
+	c := &Coinbase{}
+	c.SetDefaults()
+	c.AuthenticatedAPISupport = true
+	c.SetAPIKeys("coinbase-passphrase", "coinbase-key", Base64Encode([]byte("coinbase-secret")))
+	return c, newFixtureServer(t, c.Name, "coinbase.json")
+}
+
+func TestCoinbasePublicFixtures(t *testing.T) {
+	c, f := coinbaseFixtures(t)
+	defer f.Close()
+
+	runFixtureCases(t, f, []fixtureCase{
+		{Name: "GetProducts", Call: func() (interface{}, error) { return c.GetProducts() },
+			Want: []string{"{ID:BTC-USD BaseCurrency:BTC QuoteCurrency:USD BaseMinSize:0.01 BaseMaxSize:10000 QuoteIncrement:0.01 DisplayName:BTC/USD}"}},
+		{Name: "GetOrderbook", Call: func() (interface{}, error) { return c.GetOrderbook("BTC-USD", 0) },
+			Params: map[string]string{"level": ""},
+			Want:   []string{"{Sequence:3 Bids:[[{Price:295.96 Amount:4.39088265 NumOrders:2}]] Asks:[[{Price:295.97 Amount:25.23542881 NumOrders:12}]]}"}},
+		{Name: "GetOrderbook level 2", Call: func() (interface{}, error) { return c.GetOrderbook("BTC-USD", 2) },
+			Params: map[string]string{"level": "2"},
+			Want:   []string{"Bids:[[{Price:295.96 Amount:4.39088265 NumOrders:2}] [{Price:295.95 Amount:1 NumOrders:1}]]"}},
+		{Name: "GetOrderbook level 3", Call: func() (interface{}, error) { return c.GetOrderbook("BTC-USD", 3) },
+			Params: map[string]string{"level": "3"},
+			Want:   []string{"Bids:[[{Price:295.96 Amount:0.05088265 OrderID:3b0f1225-7f84-490b-a29f-0faef9de823a}]]", "Asks:[[{Price:295.97 Amount:5.72036512 OrderID:da863862"}},
+		{Name: "GetTicker", Call: func() (interface{}, error) { return c.GetTicker("BTC-USD") },
+			Want: []string{"{TradeID:4729088 Price:333.99 Size:0.193 Time:2015-11-14T20:46:03.511254Z}"}},
+		{Name: "GetTrades", Call: func() (interface{}, error) { return c.GetTrades("BTC-USD") },
+			Want: []string{"{TradeID:74 Price:10 Size:0.01 Time:2014-11-07T22:19:28.578544Z Side:buy}"}},
+		{Name: "GetHistoricRates", Call: func() (interface{}, error) { return c.GetHistoricRates("BTC-USD", 1415398700, 1415398800, 60) },
+			Params: map[string]string{"start": "1415398700", "end": "1415398800", "granularity": "60"},
+			Want:   []string{"[{Time:1415398768 Low:0.32 High:4.2 Open:0.35 Close:4.2 Volume:12.3} {Time:1415398708"}},
+		{Name: "GetStats", Call: func() (interface{}, error) { return c.GetStats("BTC-USD") },
+			Want: []string{"{Open:34.19 High:95.7 Low:7.06 Volume:2.41}"}},
+		{Name: "GetCurrencies", Call: func() (interface{}, error) { return c.GetCurrencies() },
+			Want: []string{"{ID:BTC Name:Bitcoin MinSize:1e-08}"}},
+	})
+}
+
+func TestCoinbaseAuthenticatedFixtures(t *testing.T) {
+	c, f := coinbaseFixtures(t)
+	defer f.Close()
+
+	account := "e316cb9a-0808-4fd7-8914-97829c1925de"
+	runFixtureCases(t, f, []fixtureCase{
+		{Name: "GetAccounts", Call: func() (interface{}, error) { return c.GetAccounts() },
+			Want: []string{"{ID:e316cb9a-0808-4fd7-8914-97829c1925de Balance:80.230137306693 Hold:1.0035025 Available:79.226634806693 Currency:USD}"}},
+		{Name: "GetAccount", Call: func() (interface{}, error) { return c.GetAccount(account) },
+			Want: []string{"Balance:1.1", "Available:1 Currency:USD"}},
+		{Name: "GetAccountHistory", Call: func() (interface{}, error) { return c.GetAccountHistory(account) },
+			Want: []string{"{ID:100 CreatedAt:2014-11-07T08:19:27.028459Z Amount:0.001 Balance:239.669 Type:fee Details:map["}},
+		{Name: "GetHolds", Call: func() (interface{}, error) { return c.GetHolds(account) },
+			Want: []string{"AccountID:e0b3f39a-183d-453e-b754-0c13e5bab0b3", "Amount:4.23 Type:order Reference:0a205de4"}},
+		{Name: "PlaceOrder", Call: func() (interface{}, error) { return c.PlaceOrder("client-ref", 0.1, 0.01, "buy", "BTC-USD", "dc") },
+			Params: map[string]string{"client_oid": "client-ref", "price": "0.1", "size": "0.01", "side": "buy", "product_id": "BTC-USD", "stp": "dc"},
+			Want:   []string{"d0c5340b-6d6c-49d9-b567-48c4bfca13d2"}},
+		{Name: "PlaceOrder insufficient", Call: func() (interface{}, error) { return c.PlaceOrder("", 1000, 1, "sell", "BTC-USD", "") },
+			Params: map[string]string{"client_oid": "", "stp": ""},
+			Err:    ERROR_INSUFFICIENT_FUNDS},
+		{Name: "CancelOrder", Call: func() (interface{}, error) { return nil, c.CancelOrder("d0c5340b-6d6c-49d9-b567-48c4bfca13d2") }},
+		{Name: "CancelOrder unknown", Call: func() (interface{}, error) { return nil, c.CancelOrder("unknown") },
+			Err: ERROR_ORDER_NOT_FOUND},
+		{Name: "GetOrders", Call: func() (interface{}, error) { return c.GetOrders(url.Values{"status": {"open"}}) },
+			Params: map[string]string{"status": "open"},
+			Want:   []string{"{ID:d0c5340b-6d6c-49d9-b567-48c4bfca13d2 Size:0.01 Price:0.1 ProductID:BTC-USD Status:open FilledSize:0 FillFees:0 Settled:false Side:buy"}},
+		{Name: "GetOrder", Call: func() (interface{}, error) { return c.GetOrder("68e6a28f-ae28-4788-8d4f-5ab4e5e5ae08") },
+			Want: []string{"DoneReason:filled Status:done Settled:true FilledSize:0.01291771", "DoneAt:2016-12-08T20:09:05.527Z"}},
+		{Name: "GetFills", Call: func() (interface{}, error) { return c.GetFills(url.Values{"product_id": {"BTC-USD"}}) },
+			Params: map[string]string{"product_id": "BTC-USD"},
+			Want:   []string{"{TradeID:74 ProductID:BTC-USD Price:10 Size:0.01 OrderID:d50ec984-77a8-460a-b958-66f114b0de9b CreatedAt:2014-11-07T22:19:28.578544Z Liquidity:T Fee:0.00025 Settled:true Side:buy}"}},
+		{Name: "Transfer", Call: func() (interface{}, error) { return nil, c.Transfer("deposit", 10, "coinbase-account") },
+			Params: map[string]string{"type": "deposit", "amount": "10", "coinbase_account_id": "coinbase-account"}},
+		{Name: "GetReport", Call: func() (interface{}, error) {
+			return c.GetReport("fills", "2014-11-01T00:00:00.000Z", "2014-11-30T23:59:59.000Z")
+		},
+			Params: map[string]string{"type": "fills", "start_date": "2014-11-01T00:00:00.000Z", "end_date": "2014-11-30T23:59:59.000Z"},
+			Want:   []string{"Status:pending", "Params:{StartDate:2014-11-01T00:00:00.000Z EndDate:2014-11-30T23:59:59.000Z}"}},
+		{Name: "GetReportStatus", Call: func() (interface{}, error) { return c.GetReportStatus("0428b97b-bec1-429e-a94c-59232926778d") },
+			Want: []string{"Status:ready", "FileURL:https://example.com/0428b97b.csv"}},
+	})
+}
+
+func TestCoinbaseSigning(t *testing.T) {
+	c, f := coinbaseFixtures(t)
+	defer f.Close()
+
+	if _, err := c.GetOrders(url.Values{"status": {"open"}}); err != nil {
+		t.Fatal(err)
+	}
+	req := f.Last()
+	message := req.Header.Get("CB-ACCESS-TIMESTAMP") + "GET" + "/orders?status=open"
+	if sign := Base64Encode(GetHMAC(HASH_SHA256, []byte(message), []byte("coinbase-secret"))); req.Header.Get("CB-ACCESS-SIGN") != sign {
+		t.Error(fmt.Sprintf("Test failed. Expected signature %s. Actual %s", sign, req.Header.Get("CB-ACCESS-SIGN")))
+	}
+	if len(req.Header.Get("CB-ACCESS-TIMESTAMP")) != 10 || req.Header.Get("CB-ACCESS-KEY") != "coinbase-key" || req.Header.Get("CB-ACCESS-PASSPHRASE") != "coinbase-passphrase" {
+		t.Error(fmt.Sprintf("Test failed. Unexpected headers %v", req.Header))
+	}
+
+	if _, err := c.PlaceOrder("", 0.1, 0.01, "buy", "BTC-USD", ""); err != nil {
+		t.Fatal(err)
+	}
+	req = f.Last()
+	message = req.Header.Get("CB-ACCESS-TIMESTAMP") + "POST" + "/orders" + req.Body
+	if sign := Base64Encode(GetHMAC(HASH_SHA256, []byte(message), []byte("coinbase-secret"))); req.Header.Get("CB-ACCESS-SIGN") != sign {
+		t.Error(fmt.Sprintf("Test failed. Expected signature over the body %s. Actual %s", sign, req.Header.Get("CB-ACCESS-SIGN")))
+	}
+	if req.Header.Get("Content-Type") != "application/json" {
+		t.Error(fmt.Sprintf("Test failed. Expected a JSON body. Actual %s", req.Header.Get("Content-Type")))
+	}
+}
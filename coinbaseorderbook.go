@@ -0,0 +1,347 @@
+package main
+
+import (
+	"log"
+	"sort"
+	"sync"
+	"time"
+)
+
+// The full channel's received, open, done, match and change messages are
+// replayed on a level 3 REST snapshot to rebuild each product's book order by
+// order. Messages are buffered while the snapshot is fetched, and any gap in
+// the sequence numbers starts a fresh sync.
+
+// CoinbaseWebsocketBookUpdate has the fields of the full channel's messages
+// which affect the book.
+type CoinbaseWebsocketBookUpdate struct {
+	Type          string  `json:"type"`
+	ProductID     string  `json:"product_id"`
+	Sequence      int64   `json:"sequence"`
+	OrderID       string  `json:"order_id"`
+	MakerOrderID  string  `json:"maker_order_id"`
+	Side          string  `json:"side"`
+	Price         float64 `json:"price,string"`
+	Size          float64 `json:"size,string"`
+	RemainingSize float64 `json:"remaining_size,string"`
+	NewSize       float64 `json:"new_size,string"`
+}
+
+type CoinbaseBookOrder struct {
+	OrderID string
+	Price   float64
+	Size    float64
+	Buy     bool
+}
+
+// CoinbaseQueuePosition estimates how much resting size is ahead of an order
+// at its price. Hidden liquidity isn't visible, so it is a lower bound.
+type CoinbaseQueuePosition struct {
+	OrderID     string
+	Price       float64
+	Size        float64
+	Ahead       float64
+	AheadOrders int
+	LevelSize   float64
+	LevelOrders int
+}
+
+type coinbaseBookUpdatesBySequence []CoinbaseWebsocketBookUpdate
+
+func (c coinbaseBookUpdatesBySequence) Len() int           { return len(c) }
+func (c coinbaseBookUpdatesBySequence) Less(i, j int) bool { return c[i].Sequence < c[j].Sequence }
+func (c coinbaseBookUpdatesBySequence) Swap(i, j int)      { c[i], c[j] = c[j], c[i] }
+
+// CoinbaseOrderbook is a product's level 3 book. Each price level keeps its
+// orders in queue order. Sequence is the last message applied.
+type CoinbaseOrderbook struct {
+	Product  string
+	Sequence int64
+	Synced   bool
+	Updated  time.Time
+
+	mtx     sync.Mutex
+	syncing bool
+	buffer  []CoinbaseWebsocketBookUpdate
+	orders  map[string]*CoinbaseBookOrder
+	bids    map[float64][]*CoinbaseBookOrder
+	asks    map[float64][]*CoinbaseBookOrder
+}
+
+func (b *CoinbaseOrderbook) levels(buy bool) map[float64][]*CoinbaseBookOrder {
+	if buy {
+		return b.bids
+	}
+	return b.asks
+}
+
+func (b *CoinbaseOrderbook) add(orderID string, price, size float64, buy bool) {
+	if _, ok := b.orders[orderID]; ok {
+		return
+	}
+	order := &CoinbaseBookOrder{OrderID: orderID, Price: price, Size: size, Buy: buy}
+	b.orders[orderID] = order
+	levels := b.levels(buy)
+	levels[price] = append(levels[price], order)
+}
+
+func (b *CoinbaseOrderbook) remove(orderID string) {
+	order, ok := b.orders[orderID]
+	if !ok {
+		return
+	}
+	delete(b.orders, orderID)
+
+	levels := b.levels(order.Buy)
+	queue := levels[order.Price]
+	for i, x := range queue {
+		if x == order {
+			queue = append(queue[:i], queue[i+1:]...)
+			break
+		}
+	}
+	if len(queue) == 0 {
+		delete(levels, order.Price)
+	} else {
+		levels[order.Price] = queue
+	}
+}
+
+// apply changes the book for one message. Received orders aren't on the book
+// until they open, and done, match and change messages for orders which
+// never rested are ignored. Must be called with mtx held.
+func (b *CoinbaseOrderbook) apply(update CoinbaseWebsocketBookUpdate) {
+	switch update.Type {
+	case "open":
+		b.add(update.OrderID, update.Price, update.RemainingSize, update.Side == "buy")
+	case "done":
+		b.remove(update.OrderID)
+	case "match":
+		if order, ok := b.orders[update.MakerOrderID]; ok {
+			order.Size -= update.Size
+		}
+	case "change":
+		if order, ok := b.orders[update.OrderID]; ok {
+			order.Size = update.NewSize
+		}
+	}
+	b.Sequence = update.Sequence
+	b.Updated = time.Now()
+}
+
+// Update applies a message in sequence, buffering it while a snapshot is
+// being fetched. False is returned when the book needs a snapshot, with the
+// message buffered for replay on top of it.
+func (b *CoinbaseOrderbook) Update(update CoinbaseWebsocketBookUpdate) bool {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	if b.syncing {
+		b.buffer = append(b.buffer, update)
+		return true
+	}
+	if b.Synced && update.Sequence <= b.Sequence {
+		return true
+	}
+	if !b.Synced || update.Sequence != b.Sequence+1 {
+		if b.Synced {
+			log.Printf("Coinbase %s orderbook missed messages %d to %d, resyncing.\n", b.Product, b.Sequence+1, update.Sequence-1)
+		}
+		b.Synced = false
+		b.syncing = true
+		b.buffer = []CoinbaseWebsocketBookUpdate{update}
+		return false
+	}
+
+	b.apply(update)
+	return true
+}
+
+// Reset replaces the book with a snapshot and replays the buffered messages
+// newer than it. The book is left unsynced if they have a gap, so the next
+// message starts another sync.
+func (b *CoinbaseOrderbook) Reset(snapshot CoinbaseOrderbookL3) {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	b.orders = make(map[string]*CoinbaseBookOrder)
+	b.bids = make(map[float64][]*CoinbaseBookOrder)
+	b.asks = make(map[float64][]*CoinbaseBookOrder)
+	for _, level := range snapshot.Bids {
+		for _, x := range level {
+			b.add(x.OrderID, x.Price, x.Amount, true)
+		}
+	}
+	for _, level := range snapshot.Asks {
+		for _, x := range level {
+			b.add(x.OrderID, x.Price, x.Amount, false)
+		}
+	}
+	b.Sequence = snapshot.Sequence
+	b.Updated = time.Now()
+
+	buffered := b.buffer
+	b.buffer = nil
+	b.syncing = false
+	b.Synced = true
+
+	sort.Sort(coinbaseBookUpdatesBySequence(buffered))
+	for _, x := range buffered {
+		if x.Sequence <= b.Sequence {
+			continue
+		}
+		if x.Sequence != b.Sequence+1 {
+			log.Printf("Coinbase %s orderbook snapshot at %d is behind the websocket at %d, resyncing.\n", b.Product, b.Sequence, x.Sequence)
+			b.Synced = false
+			return
+		}
+		b.apply(x)
+	}
+}
+
+// Invalidate drops the book, e.g. when the snapshot couldn't be fetched or
+// the connection closed, so the next message starts a fresh sync.
+func (b *CoinbaseOrderbook) Invalidate() {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	b.Synced = false
+	b.syncing = false
+	b.buffer = nil
+}
+
+// Levels returns up to depth aggregated bids and asks, best first, or every
+// level when depth is 0.
+func (b *CoinbaseOrderbook) Levels(depth int) ([]PaperBookLevel, []PaperBookLevel) {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	levels := func(book map[float64][]*CoinbaseBookOrder, descending bool) []PaperBookLevel {
+		result := []PaperBookLevel{}
+		for price, queue := range book {
+			level := PaperBookLevel{Price: price}
+			for _, x := range queue {
+				level.Amount += x.Size
+			}
+			result = append(result, level)
+		}
+		if descending {
+			sort.Sort(sort.Reverse(paperLevelsByPrice(result)))
+		} else {
+			sort.Sort(paperLevelsByPrice(result))
+		}
+		if depth > 0 && len(result) > depth {
+			result = result[:depth]
+		}
+		return result
+	}
+	return levels(b.bids, true), levels(b.asks, false)
+}
+
+// QueuePosition returns where a resting order sits at its price level.
+func (b *CoinbaseOrderbook) QueuePosition(orderID string) (CoinbaseQueuePosition, bool) {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	order, ok := b.orders[orderID]
+	if !b.Synced || !ok {
+		return CoinbaseQueuePosition{}, false
+	}
+
+	position := CoinbaseQueuePosition{OrderID: orderID, Price: order.Price, Size: order.Size}
+	ahead := true
+	for _, x := range b.levels(order.Buy)[order.Price] {
+		if x == order {
+			ahead = false
+		} else if ahead {
+			position.Ahead += x.Size
+			position.AheadOrders++
+		}
+		position.LevelSize += x.Size
+		position.LevelOrders++
+	}
+	return position, true
+}
+
+func (c *Coinbase) websocketOrderbook(product string) *CoinbaseOrderbook {
+	c.orderbooksMtx.Lock()
+	defer c.orderbooksMtx.Unlock()
+
+	if c.Orderbooks == nil {
+		c.Orderbooks = make(map[string]*CoinbaseOrderbook)
+	}
+	book, ok := c.Orderbooks[product]
+	if !ok {
+		book = &CoinbaseOrderbook{Product: product}
+		c.Orderbooks[product] = book
+	}
+	return book
+}
+
+func (c *Coinbase) getWebsocketOrderbook(product string) (*CoinbaseOrderbook, bool) {
+	c.orderbooksMtx.Lock()
+	defer c.orderbooksMtx.Unlock()
+	book, ok := c.Orderbooks[product]
+	return book, ok
+}
+
+// GetWebsocketOrderbook returns the streamed book's aggregated levels, best
+// first, if it is in sync.
+func (c *Coinbase) GetWebsocketOrderbook(product string, depth int) ([]PaperBookLevel, []PaperBookLevel, bool) {
+	book, ok := c.getWebsocketOrderbook(product)
+	if !ok {
+		return nil, nil, false
+	}
+
+	book.mtx.Lock()
+	synced := book.Synced
+	book.mtx.Unlock()
+	if !synced {
+		return nil, nil, false
+	}
+	bids, asks := book.Levels(depth)
+	return bids, asks, true
+}
+
+// GetQueuePosition estimates a resting order's place in the queue from the
+// streamed book.
+func (c *Coinbase) GetQueuePosition(product, orderID string) (CoinbaseQueuePosition, bool) {
+	book, ok := c.getWebsocketOrderbook(product)
+	if !ok {
+		return CoinbaseQueuePosition{}, false
+	}
+	return book.QueuePosition(orderID)
+}
+
+// WebsocketHandleBook applies a full channel message to its product's book,
+// fetching a snapshot in the background when one is needed.
+func (c *Coinbase) WebsocketHandleBook(update CoinbaseWebsocketBookUpdate) {
+	book := c.websocketOrderbook(update.ProductID)
+	if !book.Update(update) {
+		go c.syncWebsocketOrderbook(book)
+	}
+}
+
+func (c *Coinbase) syncWebsocketOrderbook(book *CoinbaseOrderbook) {
+	result, err := c.GetOrderbook(book.Product, 3)
+	if err != nil {
+		log.Printf("%s unable to sync %s orderbook. Error: %s\n", c.GetName(), book.Product, err)
+		book.Invalidate()
+		return
+	}
+
+	snapshot := result.(CoinbaseOrderbookL3)
+	book.Reset(snapshot)
+	if c.Verbose {
+		log.Printf("%s %s orderbook snapshot loaded at sequence %d.\n", c.GetName(), book.Product, snapshot.Sequence)
+	}
+}
+
+// invalidateWebsocketOrderbooks is called when the connection drops, as the
+// messages missed until it's back can't be recovered.
+func (c *Coinbase) invalidateWebsocketOrderbooks() {
+	c.orderbooksMtx.Lock()
+	defer c.orderbooksMtx.Unlock()
+	for _, x := range c.Orderbooks {
+		x.Invalidate()
+	}
+}
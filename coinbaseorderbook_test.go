@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func coinbaseBookUpdate(t *testing.T, message string) CoinbaseWebsocketBookUpdate {
+	update := CoinbaseWebsocketBookUpdate{}
+	if err := JSONDecode([]byte(message), &update); err != nil {
+		t.Fatal(err)
+	}
+	return update
+}
+
+func coinbaseWaitSynced(t *testing.T, c *Coinbase, product string) ([]PaperBookLevel, []PaperBookLevel) {
+	for start := time.Now(); time.Since(start) < 5*time.Second; time.Sleep(time.Millisecond) {
+		if bids, asks, ok := c.GetWebsocketOrderbook(product, 0); ok {
+			return bids, asks
+		}
+	}
+	t.Fatal("Test failed. Expected the orderbook to sync")
+	return nil, nil
+}
+
+func TestCoinbaseOrderbookL3(t *testing.T) {
+	snapshots := make(chan string, 2)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("level") != "3" {
+			t.Error(fmt.Sprintf("Test failed. Expected a level 3 snapshot. Actual %s", r.URL))
+		}
+		fmt.Fprint(w, <-snapshots)
+	}))
+	defer server.Close()
+
+	c := &Coinbase{Name: "CoinbaseOrderbookL3", APIUrl: server.URL + "/"}
+	for _, x := range []string{
+		`{"type":"open","product_id":"BTC-USD","sequence":100,"order_id":"z","price":"400.00","remaining_size":"5","side":"buy"}`,
+		`{"type":"received","product_id":"BTC-USD","sequence":101,"order_id":"e","size":"3","price":"400.00","side":"buy"}`,
+		`{"type":"open","product_id":"BTC-USD","sequence":102,"order_id":"e","price":"400.00","remaining_size":"3","side":"buy"}`,
+	} {
+		c.WebsocketHandleBook(coinbaseBookUpdate(t, x))
+	}
+
+	// released once the messages above are buffered, only 101 onwards apply
+	snapshots <- `{"sequence":100,"bids":[["400.00","1","a"],["400.00","2","b"],["399.00","1","c"]],"asks":[["401.00","1","d"]]}`
+	bids, asks := coinbaseWaitSynced(t, c, "BTC-USD")
+	if fmt.Sprint(bids) != "[{400 6} {399 1}]" || fmt.Sprint(asks) != "[{401 1}]" {
+		t.Error(fmt.Sprintf("Test failed. Expected the buffered messages replayed on the snapshot. Actual %v %v", bids, asks))
+	}
+
+	c.WebsocketHandleBook(coinbaseBookUpdate(t, `{"type":"match","product_id":"BTC-USD","sequence":103,"maker_order_id":"a","taker_order_id":"f","size":"0.4","price":"400.00","side":"buy"}`))
+	c.WebsocketHandleBook(coinbaseBookUpdate(t, `{"type":"change","product_id":"BTC-USD","sequence":104,"order_id":"b","new_size":"1.5","old_size":"2","price":"400.00","side":"buy"}`))
+	position, ok := c.GetQueuePosition("BTC-USD", "e")
+	if !ok || math.Abs(position.Ahead-2.1) > 1e-9 || position.AheadOrders != 2 || position.Size != 3 || math.Abs(position.LevelSize-5.1) > 1e-9 || position.LevelOrders != 3 {
+		t.Error(fmt.Sprintf("Test failed. Expected 2.1 ahead of e. Actual %+v", position))
+	}
+
+	c.WebsocketHandleBook(coinbaseBookUpdate(t, `{"type":"done","product_id":"BTC-USD","sequence":105,"order_id":"a","reason":"filled","side":"buy"}`))
+	if position, _ = c.GetQueuePosition("BTC-USD", "e"); position.Ahead != 1.5 || position.AheadOrders != 1 {
+		t.Error(fmt.Sprintf("Test failed. Expected 1.5 ahead of e once a is filled. Actual %+v", position))
+	}
+
+	// 106 is missed, so the book is rebuilt from a new snapshot
+	c.WebsocketHandleBook(coinbaseBookUpdate(t, `{"type":"done","product_id":"BTC-USD","sequence":107,"order_id":"b","reason":"canceled","side":"buy"}`))
+	if _, _, ok = c.GetWebsocketOrderbook("BTC-USD", 0); ok {
+		t.Error("Test failed. Expected the gap to unsync the book")
+	}
+	snapshots <- `{"sequence":107,"bids":[["400.00","3","e"]],"asks":[["401.00","1","d"],["402.00","2","g"]]}`
+	bids, asks = coinbaseWaitSynced(t, c, "BTC-USD")
+	if fmt.Sprint(bids) != "[{400 3}]" || fmt.Sprint(asks) != "[{401 1} {402 2}]" {
+		t.Error(fmt.Sprintf("Test failed. Expected the book resynced. Actual %v %v", bids, asks))
+	}
+	if position, ok = c.GetQueuePosition("BTC-USD", "e"); !ok || position.Ahead != 0 || position.LevelOrders != 1 {
+		t.Error(fmt.Sprintf("Test failed. Expected e at the front of the queue. Actual %+v", position))
+	}
+}
@@ -4,25 +4,37 @@ import (
 	"github.com/gorilla/websocket"
 	"log"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 )
 
 const (
-	COINBASE_WEBSOCKET_URL = "wss://ws-feed.exchange.coinbase.com"
+	COINBASE_WEBSOCKET_URL         = "wss://ws-feed.exchange.coinbase.com"
+	COINBASE_SANDBOX_WEBSOCKET_URL = "wss://ws-feed-public.sandbox.gdax.com"
 )
 
+// CoinbaseWebsocketSubscribe is signed when authenticated, which adds the
+// user's IDs to messages about their own orders on the full channel.
 type CoinbaseWebsocketSubscribe struct {
-	Type      string `json:"type"`
-	ProductID string `json:"product_id"`
+	Type       string `json:"type"`
+	ProductID  string `json:"product_id"`
+	Signature  string `json:"signature,omitempty"`
+	Key        string `json:"key,omitempty"`
+	Passphrase string `json:"passphrase,omitempty"`
+	Timestamp  string `json:"timestamp,omitempty"`
 }
 
 type CoinbaseWebsocketReceived struct {
-	Type     string  `json:"type"`
-	Time     string  `json:"time"`
-	Sequence int     `json:"sequence"`
-	OrderID  string  `json:"order_id"`
-	Size     float64 `json:"size,string"`
-	Price    float64 `json:"price,string"`
-	Side     string  `json:"side"`
+	Type      string  `json:"type"`
+	Time      string  `json:"time"`
+	ProductID string  `json:"product_id"`
+	Sequence  int     `json:"sequence"`
+	OrderID   string  `json:"order_id"`
+	Size      float64 `json:"size,string"`
+	Price     float64 `json:"price,string"`
+	Side      string  `json:"side"`
+	UserID    string  `json:"user_id"`
 }
 
 type CoinbaseWebsocketOpen struct {
@@ -38,17 +50,22 @@ type CoinbaseWebsocketOpen struct {
 type CoinbaseWebsocketDone struct {
 	Type          string  `json:"type"`
 	Time          string  `json:"time"`
+	ProductID     string  `json:"product_id"`
 	Sequence      int     `json:"sequence"`
 	Price         float64 `json:"price,string"`
 	OrderID       string  `json:"order_id"`
 	Reason        string  `json:"reason"`
 	Side          string  `json:"side"`
 	RemainingSize float64 `json:"remaining_size,string"`
+	UserID        string  `json:"user_id"`
 }
 
+// CoinbaseWebsocketMatch's Side is the maker's. MakerUserID or TakerUserID
+// is set, when authenticated, on the side that was the user's order.
 type CoinbaseWebsocketMatch struct {
 	Type         string  `json:"type"`
 	TradeID      int     `json:"trade_id"`
+	ProductID    string  `json:"product_id"`
 	Sequence     int     `json:"sequence"`
 	MakerOrderID string  `json:"maker_order_id"`
 	TakerOrderID string  `json:"taker_order_id"`
@@ -56,6 +73,8 @@ type CoinbaseWebsocketMatch struct {
 	Size         float64 `json:"size,string"`
 	Price        float64 `json:"price,string"`
 	Side         string  `json:"side"`
+	MakerUserID  string  `json:"maker_user_id"`
+	TakerUserID  string  `json:"taker_user_id"`
 }
 
 type CoinbaseWebsocketChange struct {
@@ -70,7 +89,13 @@ type CoinbaseWebsocketChange struct {
 }
 
 func (c *Coinbase) WebsocketSubscribe(product string, conn *websocket.Conn) error {
-	subscribe := CoinbaseWebsocketSubscribe{"subscribe", product}
+	subscribe := CoinbaseWebsocketSubscribe{Type: "subscribe", ProductID: product}
+	if c.AuthenticatedAPISupport {
+		subscribe.Timestamp = strconv.FormatInt(time.Now().Unix(), 10)
+		subscribe.Signature = Base64Encode(GetHMAC(HASH_SHA256, []byte(subscribe.Timestamp+"GET/users/self"), []byte(c.APISecret)))
+		subscribe.Key = c.APIKey
+		subscribe.Passphrase = c.Password
+	}
 	json, err := JSONEncode(subscribe)
 	if err != nil {
 		return err
@@ -85,41 +110,34 @@ func (c *Coinbase) WebsocketSubscribe(product string, conn *websocket.Conn) erro
 }
 
 func (c *Coinbase) WebsocketClient() {
-	for c.Enabled && c.Websocket {
-		var Dialer websocket.Dialer
-		conn, _, err := Dialer.Dial(COINBASE_WEBSOCKET_URL, http.Header{})
-
-		if err != nil {
-			log.Printf("%s Unable to connect to Websocket. Error: %s\n", c.GetName(), err)
-			continue
-		}
-
-		log.Printf("%s Connected to Websocket.\n", c.GetName())
-
-		currencies := []string{}
-		for _, x := range c.EnabledPairs {
-			currency := x[0:3] + "-" + x[3:]
-			currencies = append(currencies, currency)
-		}
-
-		for _, x := range currencies {
-			err = c.WebsocketSubscribe(x, conn)
-			if err != nil {
-				log.Printf("%s Websocket subscription error: %s\n", c.GetName(), err)
-				continue
-			}
-		}
-
-		if c.Verbose {
-			log.Printf("%s Subscribed to product messages.", c.GetName())
-		}
+	var conn *websocket.Conn
+	s := GetWebsocketSupervisor(c.GetName())
+	for _, x := range c.EnabledPairs {
+		s.Subscribe(WebsocketSubscription{Channel: x[0:3] + "-" + x[3:]})
+	}
 
+	s.Verbose = c.Verbose
+	s.Running = func() bool { return c.Enabled && c.Websocket }
+	s.Dial = func() error {
+		var Dialer websocket.Dialer
+		var err error
+		conn, _, err = Dialer.Dial(c.WebsocketURL, http.Header{})
+		return err
+	}
+	s.Send = func(sub WebsocketSubscription) error {
+		return c.WebsocketSubscribe(sub.Channel, conn)
+	}
+	s.Close = func() error {
+		c.invalidateWebsocketOrderbooks()
+		return conn.Close()
+	}
+	s.Serve = func() error {
 		for c.Enabled && c.Websocket {
 			msgType, resp, err := conn.ReadMessage()
 			if err != nil {
-				log.Println(err)
-				break
+				return err
 			}
+			s.Received()
 
 			switch msgType {
 			case websocket.TextMessage:
@@ -134,6 +152,17 @@ func (c *Coinbase) WebsocketClient() {
 					continue
 				}
 
+				switch msgType.Type {
+				case "received", "open", "done", "match", "change":
+					update := CoinbaseWebsocketBookUpdate{}
+					err := JSONDecode(resp, &update)
+					if err != nil {
+						log.Println(err)
+						continue
+					}
+					c.WebsocketHandleBook(update)
+				}
+
 				switch msgType.Type {
 				case "error":
 					log.Println(string(resp))
@@ -145,6 +174,9 @@ func (c *Coinbase) WebsocketClient() {
 						log.Println(err)
 						continue
 					}
+					if received.UserID != "" {
+						c.WebsocketHandleReceived(received)
+					}
 				case "open":
 					open := CoinbaseWebsocketOpen{}
 					err := JSONDecode(resp, &open)
@@ -159,6 +191,9 @@ func (c *Coinbase) WebsocketClient() {
 						log.Println(err)
 						continue
 					}
+					if done.UserID != "" {
+						c.WebsocketHandleDone(done)
+					}
 				case "match":
 					match := CoinbaseWebsocketMatch{}
 					err := JSONDecode(resp, &match)
@@ -166,6 +201,7 @@ func (c *Coinbase) WebsocketClient() {
 						log.Println(err)
 						continue
 					}
+					c.WebsocketHandleMatch(match)
 				case "change":
 					change := CoinbaseWebsocketChange{}
 					err := JSONDecode(resp, &change)
@@ -176,7 +212,61 @@ func (c *Coinbase) WebsocketClient() {
 				}
 			}
 		}
-		conn.Close()
-		log.Printf("%s Websocket client disconnected.", c.GetName())
+		return nil
+	}
+	s.Run()
+}
+
+// CoinbaseProductPair converts a product such as BTC-USD to BTCUSD.
+func CoinbaseProductPair(product string) string {
+	return strings.Replace(product, "-", "", -1)
+}
+
+func (c *Coinbase) WebsocketHandleReceived(received CoinbaseWebsocketReceived) {
+	t, _ := time.Parse(time.RFC3339Nano, received.Time)
+	PublishOrderEvent(OrderEvent{
+		Exchange:        c.GetName(),
+		ExchangeOrderID: received.OrderID,
+		Pair:            CoinbaseProductPair(received.ProductID),
+		Buy:             received.Side == "buy",
+		Status:          ORDER_STATUS_ACKNOWLEDGED,
+		Amount:          received.Size,
+		Remaining:       received.Size,
+		Price:           received.Price,
+		Time:            t,
+	})
+}
+
+func (c *Coinbase) WebsocketHandleDone(done CoinbaseWebsocketDone) {
+	status := ORDER_STATUS_FILLED
+	if done.Reason == "canceled" {
+		status = ORDER_STATUS_CANCELLED
+	}
+	t, _ := time.Parse(time.RFC3339Nano, done.Time)
+	PublishOrderEvent(OrderEvent{
+		Exchange:        c.GetName(),
+		ExchangeOrderID: done.OrderID,
+		Pair:            CoinbaseProductPair(done.ProductID),
+		Buy:             done.Side == "buy",
+		Status:          status,
+		Remaining:       done.RemainingSize,
+		Price:           done.Price,
+		Time:            t,
+	})
+}
+
+// WebsocketHandleMatch publishes a fill for each side of the match that was
+// the user's. Both are the user's when they trade with themselves. Matches
+// don't carry the fee, which is only available from the fills endpoint.
+func (c *Coinbase) WebsocketHandleMatch(match CoinbaseWebsocketMatch) {
+	t, _ := time.Parse(time.RFC3339Nano, match.Time)
+	fill := OrderFill{TradeID: strconv.Itoa(match.TradeID), Price: match.Price, Amount: match.Size, Time: t}
+	pair := CoinbaseProductPair(match.ProductID)
+
+	if match.MakerUserID != "" {
+		PublishFillEvent(FillEvent{Exchange: c.GetName(), ExchangeOrderID: match.MakerOrderID, Pair: pair, Buy: match.Side == "buy", Fill: fill})
+	}
+	if match.TakerUserID != "" {
+		PublishFillEvent(FillEvent{Exchange: c.GetName(), ExchangeOrderID: match.TakerOrderID, Pair: pair, Buy: match.Side != "buy", Fill: fill})
 	}
 }
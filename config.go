@@ -6,7 +6,9 @@ import (
 	"fmt"
 	"io/ioutil"
 	"log"
+	"net/url"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -19,6 +21,13 @@ var (
 	ErrExchangeAvailablePairsEmpty                  = "Exchange %s: Available pairs is empty."
 	ErrExchangeEnabledPairsEmpty                    = "Exchange %s: Enabled pairs is empty."
 	ErrExchangeBaseCurrenciesEmpty                  = "Exchange %s: Base currencies is empty."
+	ErrExchangeAPIURLInvalid                        = "Exchange %s: API URL %s is invalid."
+	ErrExchangeWebsocketURLInvalid                  = "Exchange %s: Websocket URL %s is invalid."
+	ErrExchangeMarketDataURLInvalid                 = "Exchange %s: Market data URL %s is invalid."
+	ErrExchangeFuturesMarginModeInvalid             = "Exchange %s: Futures margin mode %s is invalid, must be cross or fixed."
+	ErrExchangeNoSandbox                            = "Exchange %s: No sandbox environment available."
+	ErrExchangeNoPaperTrading                       = "Exchange %s: Paper trading is not supported, orders would be real."
+	ErrExchangeAPIURLRequired                       = "Exchange %s: API URL is required for exchanges on the %s platform."
 	WarningExchangeAuthAPIDefaultOrEmptyValues      = "WARNING -- Exchange %s: Authenticated API support disabled due to default/empty APIKey/Secret/ClientID values."
 	ErrExchangeNotFound                             = "Exchange %s: Not found."
 	ErrNoEnabledExchanges                           = "No Exchanges enabled."
@@ -52,24 +61,42 @@ type SMSGlobal struct {
 type Config struct {
 	Name             string
 	Cryptocurrencies string
-	SMS              SMSGlobal `json:"SMSGlobal"`
-	Webserver        Webserver `json:"Webserver"`
+	SMS              SMSGlobal  `json:"SMSGlobal"`
+	Webserver        Webserver  `json:"Webserver"`
+	Risk             RiskLimits `json:"Risk"`
+	JournalFiat      string     // fiat currency the trade journal is valued in
 	Exchanges        []Exchanges
 }
 
 type Exchanges struct {
 	Name                    string
+	Platform                string // white label platform the exchange runs on, e.g. Alphapoint
 	Enabled                 bool
 	Verbose                 bool
 	Websocket               bool
 	RESTPollingDelay        time.Duration
+	HTTPTimeout             time.Duration // seconds, 0 uses the default
+	HTTPRetries             int           // retries for unsigned idempotent requests, 0 uses the default
+	RateLimitPublic         float64       // requests per second, 0 uses the default
+	RateLimitAuthenticated  float64       // requests per second, 0 uses the default
+	RateLimitFailFast       bool          // error instead of waiting when rate limited
 	AuthenticatedAPISupport bool
+	APIURL                  string // overrides the exchange's API endpoint
+	WebsocketURL            string // overrides the exchange's websocket endpoint
+	MarketDataURL           string // overrides the exchange's market data endpoint, for those with their own (Huobi)
+	FuturesMarginMode       string // cross (the default) or fixed, for OKCoin's futures
+	Sandbox                 bool   // use the exchange's sandbox endpoints, an error for those without one (e.g. Kraken)
 	APIKey                  string
 	APISecret               string
 	ClientID                string
 	AvailablePairs          string
 	EnabledPairs            string
 	BaseCurrencies          string
+	PaperTrading            bool
+	PaperTradingLatency     time.Duration                 // milliseconds
+	PaperTradingMakerFee    float64                       // percent, 0 uses the exchange's default
+	PaperTradingTakerFee    float64                       // percent, 0 uses the exchange's default
+	PaperTradingBalances    map[string]map[string]float64 // account -> currency -> amount
 }
 
 func GetEnabledExchanges() int {
@@ -141,12 +168,37 @@ func CheckExchangeConfigValues() error {
 			if exch.BaseCurrencies == "" {
 				return fmt.Errorf(ErrExchangeBaseCurrenciesEmpty, exch.Name)
 			}
+			if exch.Sandbox {
+				if _, ok := ExchangeSandboxURLs[exch.Name]; !ok {
+					return fmt.Errorf(ErrExchangeNoSandbox, exch.Name)
+				}
+			}
+			if exch.PaperTrading && !ExchangePaperTrading[exch.Name] {
+				return fmt.Errorf(ErrExchangeNoPaperTrading, exch.Name)
+			}
+			if IsAlphapointExchange(exch) && exch.APIURL == "" {
+				if _, ok := AlphapointPlatforms[exch.Name]; !ok {
+					return fmt.Errorf(ErrExchangeAPIURLRequired, exch.Name, exch.Platform)
+				}
+			}
+			if exch.APIURL != "" && !ValidExchangeURL(exch.APIURL, "http", "https") {
+				return fmt.Errorf(ErrExchangeAPIURLInvalid, exch.Name, exch.APIURL)
+			}
+			if exch.WebsocketURL != "" && !ValidExchangeURL(exch.WebsocketURL, "ws", "wss", "http", "https") {
+				return fmt.Errorf(ErrExchangeWebsocketURLInvalid, exch.Name, exch.WebsocketURL)
+			}
+			if exch.MarketDataURL != "" && !ValidExchangeURL(exch.MarketDataURL, "http", "https") {
+				return fmt.Errorf(ErrExchangeMarketDataURLInvalid, exch.Name, exch.MarketDataURL)
+			}
+			if exch.FuturesMarginMode != "" && exch.FuturesMarginMode != OKCOIN_FUTURES_MARGIN_CROSS && exch.FuturesMarginMode != OKCOIN_FUTURES_MARGIN_FIXED {
+				return fmt.Errorf(ErrExchangeFuturesMarginModeInvalid, exch.Name, exch.FuturesMarginMode)
+			}
 			if exch.AuthenticatedAPISupport { // non-fatal error
 				if exch.APIKey == "" || exch.APISecret == "" || exch.APIKey == "Key" || exch.APISecret == "Secret" {
 					bot.config.Exchanges[i].AuthenticatedAPISupport = false
 					log.Printf(WarningExchangeAuthAPIDefaultOrEmptyValues, exch.Name)
 					continue
-				} else if exch.Name == "ITBIT" || exch.Name == "Bitstamp" || exch.Name == "Coinbase" {
+				} else if exch.Name == "ITBIT" || exch.Name == "Bitstamp" || exch.Name == "Coinbase" || IsAlphapointExchange(exch) {
 					if exch.ClientID == "" || exch.ClientID == "ClientID" {
 						bot.config.Exchanges[i].AuthenticatedAPISupport = false
 						log.Printf(WarningExchangeAuthAPIDefaultOrEmptyValues, exch.Name)
@@ -163,6 +215,69 @@ func CheckExchangeConfigValues() error {
 	return nil
 }
 
+// ExchangePaperTrading are the exchanges with a simulated venue to route
+// orders to when configured with PaperTrading.
+var ExchangePaperTrading = map[string]bool{
+	"Poloniex": true,
+}
+
+// ExchangeURLs are the endpoints an exchange client talks to.
+type ExchangeURLs struct {
+	API       string
+	Websocket string
+}
+
+// ExchangeSandboxURLs are the test environments of exchanges which have one,
+// used when an exchange is configured with Sandbox.
+var ExchangeSandboxURLs = map[string]ExchangeURLs{
+	"Coinbase": {COINBASE_SANDBOX_API_URL, COINBASE_SANDBOX_WEBSOCKET_URL},
+	"Gemini":   {GEMINI_SANDBOX_API_URL, ""},
+}
+
+// GetExchangeURLs returns the endpoints an exchange should use given its
+// config: its defaults, replaced by the sandbox's when enabled, replaced by
+// any explicit override. Overrides keep the default's trailing slash or lack
+// of one, since clients append paths to them as is.
+func GetExchangeURLs(exch Exchanges, defaults ExchangeURLs) ExchangeURLs {
+	urls := defaults
+	if sandbox, ok := ExchangeSandboxURLs[exch.Name]; ok && exch.Sandbox {
+		urls.API = sandbox.API
+		if sandbox.Websocket != "" {
+			urls.Websocket = sandbox.Websocket
+		}
+	}
+	if exch.APIURL != "" {
+		urls.API = matchTrailingSlash(exch.APIURL, defaults.API)
+	}
+	if exch.WebsocketURL != "" {
+		urls.Websocket = matchTrailingSlash(exch.WebsocketURL, defaults.Websocket)
+	}
+	return urls
+}
+
+func matchTrailingSlash(s, like string) string {
+	s = strings.TrimRight(s, "/")
+	if strings.HasSuffix(like, "/") {
+		return s + "/"
+	}
+	return s
+}
+
+// ValidExchangeURL reports whether s is an absolute URL with one of the given
+// schemes.
+func ValidExchangeURL(s string, schemes ...string) bool {
+	u, err := url.Parse(s)
+	if err != nil || u.Host == "" {
+		return false
+	}
+	for _, x := range schemes {
+		if u.Scheme == x {
+			return true
+		}
+	}
+	return false
+}
+
 func CheckWebserverValues() error {
 	_, err := ioutil.ReadDir("web/")
 	if err != nil {
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestGetExchangeURLs(t *testing.T) {
+	defaults := ExchangeURLs{COINBASE_API_URL, COINBASE_WEBSOCKET_URL}
+
+	tests := []struct {
+		exch Exchanges
+		want ExchangeURLs
+	}{
+		{Exchanges{Name: "Coinbase"}, defaults},
+		{Exchanges{Name: "Coinbase", Sandbox: true}, ExchangeURLs{COINBASE_SANDBOX_API_URL, COINBASE_SANDBOX_WEBSOCKET_URL}},
+		{Exchanges{Name: "Coinbase", APIURL: "http://localhost:8080"}, ExchangeURLs{"http://localhost:8080/", COINBASE_WEBSOCKET_URL}},
+		{Exchanges{Name: "Coinbase", Sandbox: true, WebsocketURL: "ws://localhost:8081/"}, ExchangeURLs{COINBASE_SANDBOX_API_URL, "ws://localhost:8081"}},
+		{Exchanges{Name: "Gemini", Sandbox: true}, ExchangeURLs{GEMINI_SANDBOX_API_URL, COINBASE_WEBSOCKET_URL}}, // no sandbox websocket
+		{Exchanges{Name: "Poloniex", Sandbox: true}, defaults},
+	}
+
+	for _, x := range tests {
+		if urls := GetExchangeURLs(x.exch, defaults); urls != x.want {
+			t.Error(fmt.Sprintf("Test failed - %+v. Expected %+v. Actual %+v", x.exch, x.want, urls))
+		}
+	}
+}
+
+func TestCheckExchangeConfigURLs(t *testing.T) {
+	saved := bot.config
+	defer func() { bot.config = saved }()
+
+	exch := Exchanges{Name: "Coinbase", Enabled: true, AvailablePairs: "BTCUSD", EnabledPairs: "BTCUSD", BaseCurrencies: "USD"}
+	tests := []struct {
+		apiURL, websocketURL string
+		sandbox              bool
+		name                 string
+		err                  string
+	}{
+		{"http://localhost:8080", "ws://localhost:8081", false, "Coinbase", ""},
+		{"", "", true, "Coinbase", ""},
+		{"localhost:8080", "", false, "Coinbase", fmt.Sprintf(ErrExchangeAPIURLInvalid, "Coinbase", "localhost:8080")},
+		{"", "ftp://localhost", false, "Coinbase", fmt.Sprintf(ErrExchangeWebsocketURLInvalid, "Coinbase", "ftp://localhost")},
+		{"", "", true, "Poloniex", fmt.Sprintf(ErrExchangeNoSandbox, "Poloniex")},
+		{"", "", true, "Kraken", fmt.Sprintf(ErrExchangeNoSandbox, "Kraken")}, // its demo is futures only
+		{"", "", false, "Brighton Peak", ""},
+	}
+
+	for _, x := range tests {
+		exch.Name, exch.APIURL, exch.WebsocketURL, exch.Sandbox = x.name, x.apiURL, x.websocketURL, x.sandbox
+		bot.config = Config{Cryptocurrencies: "BTC", Exchanges: []Exchanges{exch}}
+		err := CheckExchangeConfigValues()
+		if (err == nil && x.err != "") || (err != nil && err.Error() != x.err) {
+			t.Error(fmt.Sprintf("Test failed - %+v. Expected error %q. Actual %v", x, x.err, err))
+		}
+	}
+
+	for _, x := range []string{"Poloniex", "Bitfinex"} {
+		exch.Name, exch.APIURL, exch.WebsocketURL, exch.Sandbox, exch.PaperTrading = x, "", "", false, true
+		bot.config = Config{Cryptocurrencies: "BTC", Exchanges: []Exchanges{exch}}
+		err := CheckExchangeConfigValues()
+		if x == "Poloniex" && err != nil {
+			t.Error(fmt.Sprintf("Test failed. Expected Poloniex paper trading to be allowed. Actual %v", err))
+		}
+		if x == "Bitfinex" && (err == nil || err.Error() != fmt.Sprintf(ErrExchangeNoPaperTrading, x)) {
+			t.Error(fmt.Sprintf("Test failed. Expected a paper trading not supported error. Actual %v", err))
+		}
+	}
+	exch.PaperTrading = false
+
+	exch.Name, exch.Platform, exch.APIURL, exch.Sandbox = "Acme", "Alphapoint", "", false
+	bot.config = Config{Cryptocurrencies: "BTC", Exchanges: []Exchanges{exch}}
+	if err := CheckExchangeConfigValues(); err == nil || err.Error() != fmt.Sprintf(ErrExchangeAPIURLRequired, "Acme", "Alphapoint") {
+		t.Error(fmt.Sprintf("Test failed. Expected an API URL required error. Actual %v", err))
+	}
+
+	exch.Name, exch.Platform, exch.MarketDataURL = "Huobi", "", "market.huobi.com"
+	bot.config = Config{Cryptocurrencies: "BTC", Exchanges: []Exchanges{exch}}
+	if err := CheckExchangeConfigValues(); err == nil || err.Error() != fmt.Sprintf(ErrExchangeMarketDataURLInvalid, "Huobi", "market.huobi.com") {
+		t.Error(fmt.Sprintf("Test failed. Expected a market data URL invalid error. Actual %v", err))
+	}
+
+	exch.Name, exch.MarketDataURL, exch.FuturesMarginMode = "OKCOIN International", "", "isolated"
+	bot.config = Config{Cryptocurrencies: "BTC", Exchanges: []Exchanges{exch}}
+	if err := CheckExchangeConfigValues(); err == nil || err.Error() != fmt.Sprintf(ErrExchangeFuturesMarginModeInvalid, "OKCOIN International", "isolated") {
+		t.Error(fmt.Sprintf("Test failed. Expected a futures margin mode invalid error. Actual %v", err))
+	}
+}
@@ -43,6 +43,7 @@ const (
 
 var (
 	CurrencyStore             map[string]Rate
+	CurrencyStoreUpdated      time.Time // when the rates in CurrencyStore were fetched
 	BaseCurrencies            string
 	ErrCurrencyDataNotFetched = errors.New("Yahoo currency data has not been fetched yet.")
 	ErrCurrencyNotFound       = errors.New("Unable to find specified currency.")
@@ -203,5 +204,6 @@ func QueryYahooCurrencyValues(currencies string) error {
 			return err
 		}
 	}
+	CurrencyStoreUpdated = time.Now()
 	return nil
 }
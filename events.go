@@ -95,7 +95,13 @@ func (e *Event) ExecuteAction() bool {
 			if action[1] == "ALL" {
 				SMSSendToAll(message)
 			} else {
-				SMSNotify(SMSGetNumberByName(action[1]), message)
+				number, err := SMSGetNumberByName(action[1])
+				if err == nil {
+					err = SMSNotify(number, message)
+				}
+				if err != nil {
+					log.Printf("Unable to send SMS to %s: %s\n", action[1], err)
+				}
 			}
 		}
 	} else {
@@ -129,8 +135,8 @@ func (e *Event) CheckCondition() bool {
 		} else {
 			lastPrice = result.Last
 		}
-	} else if bot.exchange.brightonpeak.GetName() == e.Exchange {
-		result, err := bot.exchange.brightonpeak.GetTicker("BTCUSD")
+	} else if alphapoint := GetAlphapointExchange(e.Exchange); alphapoint != nil {
+		result, err := alphapoint.GetTicker("BTCUSD")
 		if err != nil {
 			lastPrice = 0
 		} else {
@@ -157,7 +163,12 @@ func (e *Event) CheckCondition() bool {
 	} else if bot.exchange.huobi.GetName() == e.Exchange {
 		lastPrice = bot.exchange.huobi.GetTicker("btc").Last
 	} else if bot.exchange.itbit.GetName() == e.Exchange {
-		lastPrice = bot.exchange.itbit.GetTicker("XBTUSD").LastPrice
+		result, err := bot.exchange.itbit.GetTicker("XBTUSD")
+		if err != nil {
+			lastPrice = 0
+		} else {
+			lastPrice = result.LastPrice
+		}
 	} else if bot.exchange.btce.GetName() == e.Exchange {
 		lastPrice = bot.exchange.btce.Ticker["btc_usd"].Last
 	} else if bot.exchange.btcmarkets.GetName() == e.Exchange {
@@ -181,11 +192,11 @@ func (e *Event) CheckCondition() bool {
 	} else if bot.exchange.kraken.GetName() == e.Exchange {
 		lastPrice = bot.exchange.kraken.Ticker["XBTUSD"].Last
 	} else if bot.exchange.poloniex.GetName() == e.Exchange {
-		result, err := bot.exchange.poloniex.GetTicker()
+		result, err := bot.exchange.poloniex.GetLatestTicker("BTC_LTC")
 		if err != nil {
 			lastPrice = 0
 		} else {
-			lastPrice = result["BTC_LTC"].Last
+			lastPrice = result.Last
 		}
 	}
 
@@ -258,8 +269,10 @@ func IsValidEvent(Exchange, Item, Condition, CryptoCurrency, FiatCurrency, Actio
 			return ErrInvalidAction
 		}
 
-		if action[1] != "ALL" && SMSGetNumberByName(action[1]) == ErrSMSContactNotFound {
-			return ErrInvalidAction
+		if action[1] != "ALL" {
+			if _, err := SMSGetNumberByName(action[1]); err == ErrSMSContactNotFound {
+				return ErrInvalidAction
+			}
 		}
 	} else {
 		if Action != ACTION_CONSOLE_PRINT {
@@ -287,9 +300,11 @@ func CheckEvents() {
 }
 
 func IsValidExchange(Exchange string) bool {
+	if alphapoint := GetAlphapointExchange(Exchange); alphapoint != nil && alphapoint.IsEnabled() {
+		return true
+	}
 	if bot.exchange.bitfinex.GetName() == Exchange && bot.exchange.bitfinex.IsEnabled() ||
 		bot.exchange.bitstamp.GetName() == Exchange && bot.exchange.bitstamp.IsEnabled() ||
-		bot.exchange.brightonpeak.GetName() == Exchange && bot.exchange.brightonpeak.IsEnabled() ||
 		bot.exchange.btcc.GetName() == Exchange && bot.exchange.btcc.IsEnabled() ||
 		bot.exchange.btce.GetName() == Exchange && bot.exchange.btce.IsEnabled() ||
 		bot.exchange.btcmarkets.GetName() == Exchange && bot.exchange.btcmarkets.IsEnabled() ||
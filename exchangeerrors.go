@@ -0,0 +1,352 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Exchanges each report errors their own way: codes, messages, or HTTP
+// statuses. Each one's errors are mapped into a small set of kinds here so
+// that callers can react to them, e.g. backing off when rate limited or
+// resizing an order on insufficient funds, without knowing the exchange.
+
+type ExchangeErrorKind int
+
+const (
+	ERROR_UNKNOWN ExchangeErrorKind = iota
+	ERROR_INSUFFICIENT_FUNDS
+	ERROR_INVALID_NONCE
+	ERROR_RATE_LIMITED
+	ERROR_ORDER_NOT_FOUND
+	ERROR_AUTH_FAILED
+	ERROR_MARKET_CLOSED
+	ERROR_TRANSIENT
+)
+
+var exchangeErrorKindNames = map[ExchangeErrorKind]string{
+	ERROR_UNKNOWN:            "Unknown",
+	ERROR_INSUFFICIENT_FUNDS: "InsufficientFunds",
+	ERROR_INVALID_NONCE:      "InvalidNonce",
+	ERROR_RATE_LIMITED:       "RateLimited",
+	ERROR_ORDER_NOT_FOUND:    "OrderNotFound",
+	ERROR_AUTH_FAILED:        "AuthFailed",
+	ERROR_MARKET_CLOSED:      "MarketClosed",
+	ERROR_TRANSIENT:          "Transient",
+}
+
+func (k ExchangeErrorKind) String() string {
+	return exchangeErrorKindNames[k]
+}
+
+type ExchangeError struct {
+	Exchange string
+	Kind     ExchangeErrorKind
+	Code     string // the exchange's own code, if it has one
+	Message  string
+	Err      error // the underlying error, if any
+}
+
+func (e *ExchangeError) Error() string {
+	code := ""
+	if e.Code != "" {
+		code = " " + e.Code
+	}
+	return fmt.Sprintf("%s: %s%s: %s", e.Exchange, e.Kind, code, e.Message)
+}
+
+// ExchangeErrorCodes maps each exchange's error codes to a kind.
+var ExchangeErrorCodes = map[string]map[string]ExchangeErrorKind{
+	"OKCOIN International": okcoinErrorCodes,
+	"OKCOIN China":         okcoinErrorCodes,
+	"Gemini": {
+		"InsufficientFunds":   ERROR_INSUFFICIENT_FUNDS,
+		"InvalidNonce":        ERROR_INVALID_NONCE,
+		"RateLimit":           ERROR_RATE_LIMITED,
+		"OrderNotFound":       ERROR_ORDER_NOT_FOUND,
+		"InvalidSignature":    ERROR_AUTH_FAILED,
+		"MissingApikeyHeader": ERROR_AUTH_FAILED,
+		"InvalidApiKey":       ERROR_AUTH_FAILED,
+		"MarketNotOpen":       ERROR_MARKET_CLOSED,
+		"Maintenance":         ERROR_TRANSIENT,
+		"System":              ERROR_TRANSIENT,
+	},
+	"Huobi": {
+		"1":  ERROR_TRANSIENT,
+		"2":  ERROR_INSUFFICIENT_FUNDS,
+		"3":  ERROR_INSUFFICIENT_FUNDS,
+		"26": ERROR_ORDER_NOT_FOUND,
+	},
+	"BTCC": {
+		"-32000": ERROR_TRANSIENT,
+		"-32003": ERROR_INSUFFICIENT_FUNDS,
+		"-32004": ERROR_INSUFFICIENT_FUNDS,
+		"-32005": ERROR_ORDER_NOT_FOUND,
+	},
+}
+
+var okcoinErrorCodes = map[string]ExchangeErrorKind{
+	"10001": ERROR_RATE_LIMITED,
+	"10002": ERROR_TRANSIENT,
+	"10003": ERROR_TRANSIENT,
+	"10004": ERROR_AUTH_FAILED,
+	"10005": ERROR_AUTH_FAILED,
+	"10006": ERROR_AUTH_FAILED,
+	"10007": ERROR_AUTH_FAILED,
+	"10009": ERROR_ORDER_NOT_FOUND,
+	"10010": ERROR_INSUFFICIENT_FUNDS,
+	"10016": ERROR_INSUFFICIENT_FUNDS,
+	"10017": ERROR_AUTH_FAILED,
+	"10023": ERROR_TRANSIENT,
+	"10024": ERROR_INSUFFICIENT_FUNDS,
+	"10035": ERROR_INSUFFICIENT_FUNDS,
+	"20008": ERROR_INSUFFICIENT_FUNDS,
+	"20009": ERROR_MARKET_CLOSED,
+	"20014": ERROR_TRANSIENT,
+	"20015": ERROR_ORDER_NOT_FOUND,
+	"20017": ERROR_AUTH_FAILED,
+	"20019": ERROR_AUTH_FAILED,
+	"20020": ERROR_AUTH_FAILED,
+	"20024": ERROR_AUTH_FAILED,
+	"20026": ERROR_AUTH_FAILED,
+}
+
+type exchangeErrorMessage struct {
+	substring string // lower case
+	kind      ExchangeErrorKind
+}
+
+// ExchangeErrorMessages maps substrings of each exchange's error messages to
+// a kind, for exchanges that only send messages.
+var ExchangeErrorMessages = map[string][]exchangeErrorMessage{
+	"Poloniex": {
+		{"not enough", ERROR_INSUFFICIENT_FUNDS},
+		{"nonce must be greater", ERROR_INVALID_NONCE},
+		{"api calls per second", ERROR_RATE_LIMITED},
+		{"invalid order number", ERROR_ORDER_NOT_FOUND},
+		{"invalid api key", ERROR_AUTH_FAILED},
+		{"frozen", ERROR_MARKET_CLOSED},
+		{"market is disabled", ERROR_MARKET_CLOSED},
+		{"internal error", ERROR_TRANSIENT},
+	},
+	"Bitfinex": {
+		{"not enough", ERROR_INSUFFICIENT_FUNDS},
+		{"nonce is too small", ERROR_INVALID_NONCE},
+		{"ratelimit", ERROR_RATE_LIMITED},
+		{"order could not be cancelled", ERROR_ORDER_NOT_FOUND},
+		{"no such order found", ERROR_ORDER_NOT_FOUND},
+		{"could not find a key", ERROR_AUTH_FAILED},
+		{"invalid x-bfx-signature", ERROR_AUTH_FAILED},
+	},
+	"BTCE": {
+		{"it is not enough", ERROR_INSUFFICIENT_FUNDS},
+		{"invalid nonce", ERROR_INVALID_NONCE},
+		{"bad status", ERROR_ORDER_NOT_FOUND},
+		{"order not found", ERROR_ORDER_NOT_FOUND},
+		{"invalid api key", ERROR_AUTH_FAILED},
+		{"invalid sign", ERROR_AUTH_FAILED},
+		{"api key dont have", ERROR_AUTH_FAILED},
+	},
+	"Kraken": {
+		{"insufficient funds", ERROR_INSUFFICIENT_FUNDS},
+		{"invalid nonce", ERROR_INVALID_NONCE},
+		{"rate limit exceeded", ERROR_RATE_LIMITED},
+		{"temporary lockout", ERROR_RATE_LIMITED},
+		{"unknown order", ERROR_ORDER_NOT_FOUND},
+		{"invalid key", ERROR_AUTH_FAILED},
+		{"invalid signature", ERROR_AUTH_FAILED},
+		{"permission denied", ERROR_AUTH_FAILED},
+		{"cancel_only", ERROR_MARKET_CLOSED},
+		{"eservice:unavailable", ERROR_TRANSIENT},
+		{"eservice:busy", ERROR_TRANSIENT},
+	},
+	"Coinbase": {
+		{"insufficient funds", ERROR_INSUFFICIENT_FUNDS},
+		{"request timestamp expired", ERROR_INVALID_NONCE},
+		{"rate limit", ERROR_RATE_LIMITED},
+		{"order not found", ERROR_ORDER_NOT_FOUND},
+		{"notfound", ERROR_ORDER_NOT_FOUND},
+		{"invalid signature", ERROR_AUTH_FAILED},
+		{"invalid api key", ERROR_AUTH_FAILED},
+		{"invalid passphrase", ERROR_AUTH_FAILED},
+		{"trading is disabled", ERROR_MARKET_CLOSED},
+	},
+	"Bitstamp": {
+		{"you have only", ERROR_INSUFFICIENT_FUNDS},
+		{"invalid nonce", ERROR_INVALID_NONCE},
+		{"order not found", ERROR_ORDER_NOT_FOUND},
+		{"invalid signature", ERROR_AUTH_FAILED},
+		{"api key not found", ERROR_AUTH_FAILED},
+	},
+	"ITBIT": {
+		{"sufficient funds", ERROR_INSUFFICIENT_FUNDS},
+		{"nonce", ERROR_INVALID_NONCE},
+		{"order not found", ERROR_ORDER_NOT_FOUND},
+		{"invalid signature", ERROR_AUTH_FAILED},
+		{"unauthorized", ERROR_AUTH_FAILED},
+	},
+	"LakeBTC": {
+		{"insufficient", ERROR_INSUFFICIENT_FUNDS},
+		{"nonce", ERROR_INVALID_NONCE},
+		{"order not found", ERROR_ORDER_NOT_FOUND},
+		{"invalid signature", ERROR_AUTH_FAILED},
+		{"unauthorized", ERROR_AUTH_FAILED},
+	},
+}
+
+// NewExchangeError maps an error reported by the exchange to its kind by
+// code, then by message.
+func NewExchangeError(exchange, code, message string) *ExchangeError {
+	e := &ExchangeError{Exchange: exchange, Code: code, Message: message}
+	if kind, ok := ExchangeErrorCodes[exchange][code]; ok && code != "" {
+		e.Kind = kind
+		return e
+	}
+
+	lower := strings.ToLower(message)
+	for _, x := range ExchangeErrorMessages[exchange] {
+		if strings.Contains(lower, x.substring) {
+			e.Kind = x.kind
+			return e
+		}
+	}
+	return e
+}
+
+// ClassifyExchangeError wraps an error from the HTTP layer as an
+// ExchangeError, picking the exchange's message out of error responses where
+// it can and falling back on the status code.
+func ClassifyExchangeError(exchange string, err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := err.(*ExchangeError); ok {
+		return err
+	}
+
+	if err == ErrRateLimited {
+		return &ExchangeError{Exchange: exchange, Kind: ERROR_RATE_LIMITED, Message: err.Error(), Err: err}
+	}
+
+	httpErr, ok := err.(*HTTPError)
+	if !ok {
+		if x, ok := err.(net.Error); ok && (x.Timeout() || x.Temporary()) {
+			return &ExchangeError{Exchange: exchange, Kind: ERROR_TRANSIENT, Message: err.Error(), Err: err}
+		}
+		return err
+	}
+
+	// Bitstamp's reason can be an object of messages by field
+	body := struct {
+		Message     string      `json:"message"`
+		Error       interface{} `json:"error"`
+		Reason      interface{} `json:"reason"`
+		Description string      `json:"description"`
+	}{}
+	code, message := "", httpErr.Body
+	if json.Unmarshal([]byte(httpErr.Body), &body) == nil {
+		code, _ = body.Reason.(string)
+		switch {
+		case body.Message != "":
+			message = body.Message
+		case body.Error != nil:
+			message = fmt.Sprint(body.Error)
+		case body.Reason != nil:
+			message = fmt.Sprint(body.Reason)
+		case body.Description != "":
+			message = body.Description
+		}
+	}
+
+	e := NewExchangeError(exchange, code, message)
+	e.Err = err
+	if e.Kind == ERROR_UNKNOWN {
+		switch {
+		case httpErr.StatusCode == 429:
+			e.Kind = ERROR_RATE_LIMITED
+		case httpErr.StatusCode == 401 || httpErr.StatusCode == 403:
+			e.Kind = ERROR_AUTH_FAILED
+		case httpErr.StatusCode == 404:
+			e.Kind = ERROR_ORDER_NOT_FOUND
+		case httpErr.Temporary():
+			e.Kind = ERROR_TRANSIENT
+		}
+	}
+	if e.Code == "" && httpErr.StatusCode != http.StatusOK {
+		e.Code = strconv.Itoa(httpErr.StatusCode)
+	}
+	return e
+}
+
+// CheckExchangeErrorBody returns the error in a response sent with a 200,
+// i.e. {"error": ...}, or {"status": "error", "reason": ...} and Gemini's
+// {"result": "error", ...}, classified as ClassifyExchangeError does. Any
+// other body is nil.
+func CheckExchangeErrorBody(exchange, body string) error {
+	envelope := struct {
+		Error  interface{} `json:"error"`
+		Status interface{} `json:"status"`
+		Result interface{} `json:"result"`
+	}{}
+	if json.Unmarshal([]byte(body), &envelope) != nil {
+		return nil
+	}
+	if envelope.Error == nil && envelope.Status != "error" && envelope.Result != "error" {
+		return nil
+	}
+	return ClassifyExchangeError(exchange, &HTTPError{StatusCode: http.StatusOK, Body: body})
+}
+
+// ExchangeErrorKindOf returns the kind of err, ERROR_UNKNOWN for anything
+// that isn't an ExchangeError. Errors from paper trading map as the
+// exchange's would.
+func ExchangeErrorKindOf(err error) ExchangeErrorKind {
+	switch err {
+	case ErrPaperInsufficientFunds:
+		return ERROR_INSUFFICIENT_FUNDS
+	case ErrPaperOrderNotFound:
+		return ERROR_ORDER_NOT_FOUND
+	case ErrRateLimited:
+		return ERROR_RATE_LIMITED
+	}
+	if x, ok := err.(*ExchangeError); ok {
+		return x.Kind
+	}
+	return ERROR_UNKNOWN
+}
+
+func IsInsufficientFunds(err error) bool {
+	return ExchangeErrorKindOf(err) == ERROR_INSUFFICIENT_FUNDS
+}
+
+func IsInvalidNonce(err error) bool {
+	return ExchangeErrorKindOf(err) == ERROR_INVALID_NONCE
+}
+
+func IsRateLimited(err error) bool {
+	return ExchangeErrorKindOf(err) == ERROR_RATE_LIMITED
+}
+
+func IsOrderNotFound(err error) bool {
+	return ExchangeErrorKindOf(err) == ERROR_ORDER_NOT_FOUND
+}
+
+func IsAuthFailed(err error) bool {
+	return ExchangeErrorKindOf(err) == ERROR_AUTH_FAILED
+}
+
+func IsMarketClosed(err error) bool {
+	return ExchangeErrorKindOf(err) == ERROR_MARKET_CLOSED
+}
+
+// IsTransient reports whether err is worth retrying as is.
+func IsTransient(err error) bool {
+	switch ExchangeErrorKindOf(err) {
+	case ERROR_TRANSIENT, ERROR_RATE_LIMITED, ERROR_INVALID_NONCE:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestNewExchangeError(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		exchange, code, message string
+		kind                    ExchangeErrorKind
+	}{
+		{"OKCOIN International", "10010", "Insufficient funds", ERROR_INSUFFICIENT_FUNDS},
+		{"Poloniex", "", "Not enough BTC.", ERROR_INSUFFICIENT_FUNDS},
+		{"Poloniex", "", "Nonce must be greater than 1465.", ERROR_INVALID_NONCE},
+		{"Kraken", "EAPI:Rate limit exceeded", "EAPI:Rate limit exceeded", ERROR_RATE_LIMITED},
+		{"BTCE", "", "invalid api key", ERROR_AUTH_FAILED},
+		{"Gemini", "MarketNotOpen", "The market is closed", ERROR_MARKET_CLOSED},
+		{"Poloniex", "", "Something new", ERROR_UNKNOWN},
+	}
+	for _, x := range tests {
+		if err := NewExchangeError(x.exchange, x.code, x.message); err.Kind != x.kind {
+			t.Error(fmt.Sprintf("Test failed. Expected %s. Actual %s for %v", x.kind, err.Kind, err))
+		}
+	}
+}
+
+func TestClassifyExchangeError(t *testing.T) {
+	t.Parallel()
+	err := ClassifyExchangeError("Coinbase", &HTTPError{StatusCode: 400, Body: `{"message":"Insufficient funds"}`})
+	if !IsInsufficientFunds(err) || IsTransient(err) {
+		t.Error(fmt.Sprintf("Test failed. Expected insufficient funds. Actual %v", err))
+	}
+
+	err = ClassifyExchangeError("Bitfinex", &HTTPError{StatusCode: 429, Body: "slow down"})
+	if !IsRateLimited(err) || err.(*ExchangeError).Code != "429" {
+		t.Error(fmt.Sprintf("Test failed. Expected rate limited by status. Actual %v", err))
+	}
+
+	err = ClassifyExchangeError("Gemini", &HTTPError{StatusCode: 400, Body: `{"result":"error","reason":"InvalidNonce","message":"Nonce 1 has already been used"}`})
+	if !IsInvalidNonce(err) {
+		t.Error(fmt.Sprintf("Test failed. Expected invalid nonce. Actual %v", err))
+	}
+
+	if err = ClassifyExchangeError("Poloniex", ErrRateLimited); !IsRateLimited(err) {
+		t.Error(fmt.Sprintf("Test failed. Expected rate limited. Actual %v", err))
+	}
+	if !IsInsufficientFunds(ErrPaperInsufficientFunds) {
+		t.Error("Test failed. Expected paper trading errors to map")
+	}
+}
+
+func TestCheckExchangeErrorBody(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		exchange, body string
+		kind           ExchangeErrorKind
+		failed         bool
+	}{
+		{"Bitstamp", `{"error":"Invalid nonce"}`, ERROR_INVALID_NONCE, true},
+		{"Bitstamp", `{"status":"error","reason":{"__all__":["You have only 0.5 BTC available."]}}`, ERROR_INSUFFICIENT_FUNDS, true},
+		{"Gemini", `{"result":"error","reason":"InsufficientFunds","message":"Failed to place buy order"}`, ERROR_INSUFFICIENT_FUNDS, true},
+		{"Coinbase", `{"status":"error","reason":"Order not found"}`, ERROR_ORDER_NOT_FOUND, true},
+		{"Coinbase", `{"id":"d50ec984","status":"open"}`, ERROR_UNKNOWN, false},
+		{"Bitstamp", `[{"id":1,"status":"error"}]`, ERROR_UNKNOWN, false},
+	}
+	for _, x := range tests {
+		err := CheckExchangeErrorBody(x.exchange, x.body)
+		if (err != nil) != x.failed || ExchangeErrorKindOf(err) != x.kind {
+			t.Error(fmt.Sprintf("Test failed - %s %s. Expected %s. Actual %v", x.exchange, x.body, x.kind, err))
+		}
+	}
+}
@@ -0,0 +1,451 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+// The execution engine works an order on any exchange implementing
+// ExecutionVenue. All algorithms share the same loop: price a limit order off
+// the touch, place it, poll its fills, and cancel and re-price it once it has
+// sat without filling for a while. They differ in how they price, how much
+// they show at once and how long they're given:
+//
+//   CHASE     sits just inside the far touch and follows it until filled.
+//   POST_ONLY rests on the near touch as a maker until PostOnlyTimeout, then
+//             crosses the spread as a taker for whatever is left.
+//   ICEBERG   chases like CHASE but never shows more than DisplayAmount.
+//   TWAP      splits the order into Slices spread over Duration, chasing each
+//             slice for its share of the time and carrying leftovers forward.
+//
+// Fills are tracked by trade ID so polling never counts a trade twice, and no
+// order is placed at a price more than MaxSlippage percent worse than the
+// price when execution started.
+
+const (
+	EXECUTION_CHASE     = "CHASE"
+	EXECUTION_POST_ONLY = "POST_ONLY"
+	EXECUTION_ICEBERG   = "ICEBERG"
+	EXECUTION_TWAP      = "TWAP"
+
+	EXECUTION_DEFAULT_POLL_INTERVAL = 5 * time.Second
+	EXECUTION_DEFAULT_REPRICE_AFTER = time.Minute
+	EXECUTION_DEFAULT_OUT_FRONT     = .00001 // .001%
+	EXECUTION_RETRIES               = 3
+	EXECUTION_DUST                  = .00000001
+)
+
+var (
+	ErrExecutionAlgorithmUnknown = errors.New("Unknown execution algorithm.")
+	ErrExecutionInvalidAmount    = errors.New("Execution amount must be greater than zero.")
+	ErrExecutionInvalidParams    = errors.New("Execution algorithm parameters are invalid.")
+	ErrExecutionMaxSlippage      = errors.New("Price moved beyond maximum slippage, stopped executing.")
+	ErrExecutionNoPrice          = errors.New("No bid or ask to price order from.")
+	ErrExecutionCancelFailed     = errors.New("Order couldn't be cancelled or confirmed done, stopped executing.")
+	// venues return this when a post only order would have taken liquidity
+	ErrExecutionWouldTake = errors.New("Post only order would have taken liquidity.")
+)
+
+type ExecutionVenue interface {
+	GetName() string
+	BestPrices(pair string) (bid, ask float64, err error)
+	PlaceLimitOrder(pair string, price, amount float64, buy, postOnly bool) (orderID string, fills []OrderFill, err error)
+	CancelOrder(orderID string) error
+	OrderFills(orderID string) ([]OrderFill, error)
+}
+
+type ExecutionParams struct {
+	Algorithm    string
+	Pair         string
+	Buy          bool
+	Margin       bool // recorded against the orders in the OMS
+	Amount       float64
+	MaxSlippage  float64       // percent from the arrival price, 0 disables
+	PollInterval time.Duration // how often to check for fills
+	RepriceAfter time.Duration // re-price an order that hasn't filled for this long
+	OutFront     float64       // how far inside the touch to price, as a fraction
+
+	PostOnlyTimeout time.Duration // POST_ONLY
+	DisplayAmount   float64       // ICEBERG
+	Duration        time.Duration // TWAP
+	Slices          int           // TWAP
+}
+
+type ExecutionSummary struct {
+	Exchange     string
+	Algorithm    string
+	Pair         string
+	Buy          bool
+	Requested    float64
+	Filled       float64
+	AveragePrice float64
+	ArrivalPrice float64
+	Slippage     float64 // percent, positive is worse than arrival
+	Orders       int
+	Trades       int
+	Start        time.Time
+	End          time.Time
+}
+
+func (s ExecutionSummary) String() string {
+	side := "SELL"
+	if s.Buy {
+		side = "BUY"
+	}
+	return fmt.Sprintf("%s %s %s %s filled=%f/%f (%.2f%%) avgPrice=%f arrivalPrice=%f slippage=%f%% orders=%d trades=%d duration=%v",
+		s.Exchange, s.Algorithm, side, s.Pair, s.Filled, s.Requested, 100*(s.Filled/s.Requested), s.AveragePrice, s.ArrivalPrice, s.Slippage, s.Orders, s.Trades, s.End.Sub(s.Start))
+}
+
+type executionPricing int
+
+const (
+	executionPassive executionPricing = iota // near touch, maker
+	executionInside                          // just inside the far touch
+	executionTaker                           // on the far touch, crossing
+)
+
+type Executor struct {
+	Venue   ExecutionVenue
+	Verbose bool
+}
+
+func NewExecutor(venue ExecutionVenue) *Executor {
+	return &Executor{Venue: venue}
+}
+
+type execution struct {
+	params   ExecutionParams
+	summary  ExecutionSummary
+	trades   map[string]struct{}
+	notional float64
+}
+
+// addFills records fills not seen before against the order, in the summary
+// and the OMS, and returns the amount they added.
+func (x *execution) addFills(orderID int, fills []OrderFill) float64 {
+	var added float64
+	for _, f := range fills {
+		if _, ok := x.trades[f.TradeID]; ok {
+			continue
+		}
+		x.trades[f.TradeID] = struct{}{}
+		x.summary.Trades++
+		x.summary.Filled += f.Amount
+		x.notional += f.Price * f.Amount
+		added += f.Amount
+		// the order's private stream may have recorded it already
+		if err := OrderFilled(orderID, f); err != nil && err != ErrOrderFillDuplicate {
+			log.Printf("WARN couldn't record fill for order %d: %v", orderID, err)
+		}
+	}
+	return added
+}
+
+func (x *execution) checkSlippage(price float64) error {
+	if x.params.MaxSlippage <= 0 || x.summary.ArrivalPrice <= 0 {
+		return nil
+	}
+	limit := x.summary.ArrivalPrice * x.params.MaxSlippage / 100
+	if (x.params.Buy && price > x.summary.ArrivalPrice+limit) || (!x.params.Buy && price < x.summary.ArrivalPrice-limit) {
+		return ErrExecutionMaxSlippage
+	}
+	return nil
+}
+
+// Execute works the order with the requested algorithm and returns a summary
+// of what was filled, along with any error that stopped it early.
+func (e *Executor) Execute(params ExecutionParams) (ExecutionSummary, error) {
+	if params.PollInterval <= 0 {
+		params.PollInterval = EXECUTION_DEFAULT_POLL_INTERVAL
+	}
+	if params.RepriceAfter <= 0 {
+		params.RepriceAfter = EXECUTION_DEFAULT_REPRICE_AFTER
+	}
+	if params.OutFront <= 0 {
+		params.OutFront = EXECUTION_DEFAULT_OUT_FRONT
+	}
+
+	x := &execution{
+		params: params,
+		trades: make(map[string]struct{}),
+		summary: ExecutionSummary{
+			Exchange:  e.Venue.GetName(),
+			Algorithm: params.Algorithm,
+			Pair:      params.Pair,
+			Buy:       params.Buy,
+			Requested: params.Amount,
+			Start:     time.Now(),
+		},
+	}
+
+	err := e.execute(x)
+
+	x.summary.End = time.Now()
+	if x.summary.Filled > 0 {
+		x.summary.AveragePrice = x.notional / x.summary.Filled
+		if x.summary.ArrivalPrice > 0 {
+			x.summary.Slippage = 100 * (x.summary.AveragePrice - x.summary.ArrivalPrice) / x.summary.ArrivalPrice
+			if !params.Buy {
+				x.summary.Slippage = -x.summary.Slippage
+			}
+		}
+	}
+	log.Printf("execution summary: %s err=%v", x.summary, err)
+	return x.summary, err
+}
+
+func (e *Executor) execute(x *execution) error {
+	params := x.params
+	if params.Amount <= 0 {
+		return ErrExecutionInvalidAmount
+	}
+
+	bid, ask, err := e.bestPrices(params.Pair)
+	if err != nil {
+		return err
+	}
+	x.summary.ArrivalPrice = bid
+	if params.Buy {
+		x.summary.ArrivalPrice = ask
+	}
+
+	switch params.Algorithm {
+	case EXECUTION_CHASE:
+		return e.work(x, params.Amount, 0, executionInside, time.Time{})
+	case EXECUTION_ICEBERG:
+		if params.DisplayAmount <= 0 {
+			return ErrExecutionInvalidParams
+		}
+		return e.work(x, params.Amount, params.DisplayAmount, executionInside, time.Time{})
+	case EXECUTION_POST_ONLY:
+		if params.PostOnlyTimeout <= 0 {
+			return ErrExecutionInvalidParams
+		}
+		err := e.work(x, params.Amount, 0, executionPassive, time.Now().Add(params.PostOnlyTimeout))
+		if err != nil {
+			return err
+		}
+		if left := params.Amount - x.summary.Filled; left > EXECUTION_DUST {
+			if e.Verbose {
+				log.Printf("%s post only timed out, taking %f", params.Pair, left)
+			}
+			return e.work(x, left, 0, executionTaker, time.Time{})
+		}
+		return nil
+	case EXECUTION_TWAP:
+		if params.Slices < 1 || params.Duration <= 0 {
+			return ErrExecutionInvalidParams
+		}
+		interval := params.Duration / time.Duration(params.Slices)
+		for i := 0; i < params.Slices; i++ {
+			sliceEnd := x.summary.Start.Add(time.Duration(i+1) * interval)
+			target := params.Amount * float64(i+1) / float64(params.Slices)
+			if i == params.Slices-1 {
+				sliceEnd = time.Time{} // last slice has to finish the order
+			}
+			if left := target - x.summary.Filled; left > EXECUTION_DUST {
+				if err := e.work(x, left, 0, executionInside, sliceEnd); err != nil {
+					return err
+				}
+			}
+			if wait := sliceEnd.Sub(time.Now()); i < params.Slices-1 && wait > 0 {
+				time.Sleep(wait)
+			}
+		}
+		return nil
+	}
+	return ErrExecutionAlgorithmUnknown
+}
+
+func (e *Executor) bestPrices(pair string) (bid, ask float64, err error) {
+	for i := 0; i < EXECUTION_RETRIES; i++ {
+		bid, ask, err = e.Venue.BestPrices(pair)
+		if err == nil && (bid <= 0 || ask <= 0) {
+			err = ErrExecutionNoPrice
+		}
+		if err == nil {
+			return bid, ask, nil
+		}
+		log.Printf("WARN couldn't get prices. pair=%s attempt=%d err=%v", pair, i+1, err)
+		time.Sleep(time.Second)
+	}
+	return 0, 0, err
+}
+
+func (e *Executor) price(x *execution, pricing executionPricing) (float64, error) {
+	bid, ask, err := e.bestPrices(x.params.Pair)
+	if err != nil {
+		return 0, err
+	}
+
+	out := x.params.OutFront
+	switch pricing {
+	case executionPassive:
+		// step in front of the near touch, as long as it doesn't cross
+		if x.params.Buy {
+			if price := bid + out*bid; price < ask {
+				return price, nil
+			}
+			return bid, nil
+		}
+		if price := ask - out*ask; price > bid {
+			return price, nil
+		}
+		return ask, nil
+	case executionTaker:
+		if x.params.Buy {
+			return ask, nil
+		}
+		return bid, nil
+	}
+
+	// try to take the maker fee, but not very hard so we don't wait...
+	if x.params.Buy {
+		return ask - out*ask, nil
+	}
+	return bid + out*bid, nil
+}
+
+// work fills amount more of the order, showing at most clip at once, until it
+// is filled or until passes. Whatever is open when it returns is cancelled.
+func (e *Executor) work(x *execution, amount, clip float64, pricing executionPricing, until time.Time) error {
+	target := x.summary.Filled + amount
+	postOnly := pricing == executionPassive
+	failures := 0
+
+	for target-x.summary.Filled > EXECUTION_DUST {
+		if !until.IsZero() && time.Now().After(until) {
+			return nil
+		}
+
+		price, err := e.price(x, pricing)
+		if err != nil {
+			return err
+		}
+		if err := x.checkSlippage(price); err != nil {
+			return err
+		}
+
+		size := target - x.summary.Filled
+		if clip > 0 && size > clip {
+			size = clip
+		}
+
+		id := NewOrder(x.summary.Exchange, x.params.Pair, x.params.Buy, x.params.Margin, LIMIT_ORDER, size, price)
+		orderID, fills, err := e.Venue.PlaceLimitOrder(x.params.Pair, price, size, x.params.Buy, postOnly)
+		if err != nil {
+			OrderRejected(id, err.Error())
+		}
+		if err == ErrExecutionWouldTake {
+			// the touch moved under us, try again at the new price
+			time.Sleep(x.params.PollInterval)
+			continue
+		}
+		if err != nil {
+			failures++
+			log.Printf("WARN couldn't place order. pair=%s price=%f amount=%f buy=%v attempt=%d err=%v", x.params.Pair, price, size, x.params.Buy, failures, err)
+			// no point trying again without enough funds, valid keys or an open market
+			kind := ExchangeErrorKindOf(err)
+			if failures >= EXECUTION_RETRIES || (kind != ERROR_UNKNOWN && !IsTransient(err)) {
+				return err
+			}
+			time.Sleep(x.params.PollInterval)
+			continue
+		}
+		failures = 0
+		x.summary.Orders++
+		OrderAcknowledged(id, orderID)
+
+		filled := x.addFills(id, fills)
+		if e.Verbose {
+			log.Printf("placed order %s %s %f@%f filled=%f", orderID, x.params.Pair, size, price, filled)
+		}
+		if size-filled > EXECUTION_DUST {
+			if err := e.wait(x, id, orderID, size-filled, until); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// wait watches the order until it fills, hasn't filled anything for
+// RepriceAfter or until passes, cancelling it in the last two cases. Fills
+// from the exchange's private stream are picked up as they arrive, polling
+// every PollInterval catches anything the stream missed. An error is returned
+// when a failed cancel leaves the order possibly still working.
+func (e *Executor) wait(x *execution, id int, orderID string, left float64, until time.Time) error {
+	listener := ListenOrderEvents(e.Venue.GetName(), orderID)
+	defer listener.Close()
+	poll := time.NewTicker(x.params.PollInterval)
+	defer poll.Stop()
+
+	lastFill := time.Now()
+	for {
+		fills := []OrderFill{}
+		select {
+		case event := <-listener.Events:
+			if fill, ok := event.(FillEvent); ok {
+				fills = append(fills, fill.Fill)
+			}
+		case <-poll.C:
+			// errors usually just mean nothing has traded yet
+			var err error
+			fills, err = e.Venue.OrderFills(orderID)
+			if err != nil && e.Verbose {
+				log.Printf("couldn't get fills for order %s: %v", orderID, err)
+			}
+		}
+		if filled := x.addFills(id, fills); filled > 0 {
+			left -= filled
+			lastFill = time.Now() // sit at this price a while longer since we filled something
+		}
+		if left <= EXECUTION_DUST {
+			return nil
+		}
+
+		if time.Since(lastFill) > x.params.RepriceAfter || (!until.IsZero() && time.Now().After(until)) {
+			break
+		}
+	}
+
+	// NOTE: the order could partially fill while we're cancelling, so pick up
+	// any last fills before placing another
+	cancelErr := e.Venue.CancelOrder(orderID)
+	fills, fillsErr := e.Venue.OrderFills(orderID)
+	left -= x.addFills(id, fills)
+	if left <= EXECUTION_DUST {
+		return nil
+	}
+	if cancelErr != nil {
+		// re-pricing while it might still be working could fill twice
+		if fillsErr != nil || !e.orderClosed(orderID) {
+			log.Printf("couldn't cancel order %s and it may still be open, stopping: %v", orderID, cancelErr)
+			return ErrExecutionCancelFailed
+		}
+		log.Printf("couldn't cancel order %s but it's no longer open: %v", orderID, cancelErr)
+	}
+	OrderCancelled(id, "re-pricing")
+	return nil
+}
+
+// orderClosed checks the venue's open orders, if it lists them, to confirm an
+// order is no longer working.
+func (e *Executor) orderClosed(orderID string) bool {
+	reconciler, ok := e.Venue.(OrderReconciler)
+	if !ok {
+		return false
+	}
+	open, err := reconciler.OpenOrders()
+	if err != nil {
+		return false
+	}
+	for _, x := range open {
+		if x.ExchangeOrderID == orderID {
+			return false
+		}
+	}
+	return true
+}
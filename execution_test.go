@@ -0,0 +1,249 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// executionTestVenue fills orders priced at or through the far touch
+// straight away, up to depth, and re-reports every fill on each poll.
+// Orders priced inside the spread fill passiveFill of their size on the
+// first poll.
+type executionTestVenue struct {
+	mtx         sync.Mutex
+	bid, ask    float64
+	depth       float64
+	passiveFill float64
+	orders      []executionTestOrder
+	cancelled   int
+	tradeID     int
+}
+
+type executionTestOrder struct {
+	price, amount float64
+	postOnly      bool
+	fills         []OrderFill
+	polled        bool
+}
+
+func (v *executionTestVenue) GetName() string {
+	return "Test"
+}
+
+func (v *executionTestVenue) BestPrices(pair string) (float64, float64, error) {
+	v.mtx.Lock()
+	defer v.mtx.Unlock()
+	return v.bid, v.ask, nil
+}
+
+func (v *executionTestVenue) fill(order *executionTestOrder, price, amount float64) {
+	v.tradeID++
+	order.fills = append(order.fills, OrderFill{TradeID: strconv.Itoa(v.tradeID), Price: price, Amount: amount})
+}
+
+func (v *executionTestVenue) PlaceLimitOrder(pair string, price, amount float64, buy, postOnly bool) (string, []OrderFill, error) {
+	v.mtx.Lock()
+	defer v.mtx.Unlock()
+
+	order := executionTestOrder{price: price, amount: amount, postOnly: postOnly}
+	if buy && price >= v.ask {
+		if postOnly {
+			return "", nil, ErrExecutionWouldTake
+		}
+		v.fill(&order, v.ask, math.Min(amount, v.depth))
+	}
+	v.orders = append(v.orders, order)
+	return strconv.Itoa(len(v.orders) - 1), order.fills, nil
+}
+
+func (v *executionTestVenue) CancelOrder(orderID string) error {
+	v.mtx.Lock()
+	defer v.mtx.Unlock()
+	v.cancelled++
+	return nil
+}
+
+func (v *executionTestVenue) OrderFills(orderID string) ([]OrderFill, error) {
+	v.mtx.Lock()
+	defer v.mtx.Unlock()
+
+	i, _ := strconv.Atoi(orderID)
+	order := &v.orders[i]
+	if !order.polled && order.price < v.ask && v.passiveFill > 0 {
+		v.fill(order, order.price, order.amount*v.passiveFill)
+	}
+	order.polled = true
+	return order.fills, nil
+}
+
+func executionTestParams(algorithm string, amount float64) ExecutionParams {
+	return ExecutionParams{
+		Algorithm:    algorithm,
+		Pair:         "BTC_ETH",
+		Buy:          true,
+		Amount:       amount,
+		PollInterval: time.Millisecond,
+		RepriceAfter: 5 * time.Millisecond,
+	}
+}
+
+func TestExecutionChase(t *testing.T) {
+	t.Parallel()
+	v := &executionTestVenue{bid: 0.019, ask: 0.02, depth: 100, passiveFill: 0.5}
+	summary, err := NewExecutor(v).Execute(executionTestParams(EXECUTION_CHASE, 10))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// half fills on the first poll, then the order sits and gets re-priced,
+	// fills repeated on every poll must only count once
+	if math.Abs(summary.Filled-10) > EXECUTION_DUST || summary.Orders < 2 || v.cancelled < 1 {
+		t.Error(fmt.Sprintf("Test failed. Unexpected summary %+v", summary))
+	}
+	if summary.Trades != v.tradeID {
+		t.Error(fmt.Sprintf("Test failed. Expected %d trades. Actual %d", v.tradeID, summary.Trades))
+	}
+	if summary.AveragePrice >= v.ask || summary.Slippage >= 0 {
+		t.Error(fmt.Sprintf("Test failed. Expected fills inside the ask. Actual %+v", summary))
+	}
+}
+
+func TestExecutionPostOnlyFallback(t *testing.T) {
+	t.Parallel()
+	v := &executionTestVenue{bid: 0.019, ask: 0.02, depth: 100}
+	params := executionTestParams(EXECUTION_POST_ONLY, 10)
+	params.PostOnlyTimeout = 20 * time.Millisecond
+
+	summary, err := NewExecutor(v).Execute(params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary.Filled != 10 || summary.AveragePrice != v.ask {
+		t.Error(fmt.Sprintf("Test failed. Expected taker fill at the ask. Actual %+v", summary))
+	}
+	if !v.orders[0].postOnly || v.orders[0].price >= v.ask || v.orders[len(v.orders)-1].postOnly {
+		t.Error(fmt.Sprintf("Test failed. Expected post only orders then a taker. Actual %+v", v.orders))
+	}
+}
+
+func TestExecutionIceberg(t *testing.T) {
+	t.Parallel()
+	v := &executionTestVenue{bid: 0.019, ask: 0.02, depth: 100, passiveFill: 1}
+	params := executionTestParams(EXECUTION_ICEBERG, 10)
+	params.DisplayAmount = 3
+
+	summary, err := NewExecutor(v).Execute(params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(summary.Filled-10) > EXECUTION_DUST || summary.Orders != 4 {
+		t.Error(fmt.Sprintf("Test failed. Expected 4 orders. Actual %+v", summary))
+	}
+	for _, x := range v.orders {
+		if x.amount > 3 {
+			t.Error(fmt.Sprintf("Test failed. Order larger than display amount %+v", x))
+		}
+	}
+}
+
+func TestExecutionMaxSlippage(t *testing.T) {
+	t.Parallel()
+	v := &executionTestVenue{bid: 0.019, ask: 0.02}
+	params := executionTestParams(EXECUTION_CHASE, 10)
+	params.MaxSlippage = 1
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		v.mtx.Lock()
+		v.bid, v.ask = 0.021, 0.022
+		v.mtx.Unlock()
+	}()
+
+	summary, err := NewExecutor(v).Execute(params)
+	if err != ErrExecutionMaxSlippage {
+		t.Error(fmt.Sprintf("Test failed. Expected %v. Actual %v", ErrExecutionMaxSlippage, err))
+	}
+	if summary.Filled != 0 || summary.ArrivalPrice != 0.02 {
+		t.Error(fmt.Sprintf("Test failed. Unexpected summary %+v", summary))
+	}
+}
+
+func TestExecutionTWAP(t *testing.T) {
+	t.Parallel()
+	v := &executionTestVenue{bid: 0.019, ask: 0.02, passiveFill: 1}
+	params := executionTestParams(EXECUTION_TWAP, 9)
+	params.Slices = 3
+	params.Duration = 30 * time.Millisecond
+
+	summary, err := NewExecutor(v).Execute(params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(summary.Filled-9) > EXECUTION_DUST || summary.Orders != 3 || summary.End.Sub(summary.Start) < 20*time.Millisecond {
+		t.Error(fmt.Sprintf("Test failed. Expected 3 slices over the duration. Actual %+v", summary))
+	}
+}
+
+type executionTestBrokeVenue struct {
+	executionTestVenue
+	placed int
+}
+
+func (v *executionTestBrokeVenue) PlaceLimitOrder(pair string, price, amount float64, buy, postOnly bool) (string, []OrderFill, error) {
+	v.placed++
+	return "", nil, &ExchangeError{Exchange: "Test", Kind: ERROR_INSUFFICIENT_FUNDS, Message: "Not enough BTC."}
+}
+
+func TestExecutionInsufficientFunds(t *testing.T) {
+	t.Parallel()
+	v := &executionTestBrokeVenue{executionTestVenue: executionTestVenue{bid: 0.019, ask: 0.02}}
+
+	_, err := NewExecutor(v).Execute(executionTestParams(EXECUTION_CHASE, 10))
+	if !IsInsufficientFunds(err) || v.placed != 1 {
+		t.Error(fmt.Sprintf("Test failed. Expected to give up after 1 order. Actual %d orders err=%v", v.placed, err))
+	}
+}
+
+// executionTestStuckVenue can't cancel, and lists its orders as open until
+// closed is set.
+type executionTestStuckVenue struct {
+	executionTestVenue
+	closed bool
+}
+
+func (v *executionTestStuckVenue) CancelOrder(orderID string) error {
+	v.executionTestVenue.CancelOrder(orderID)
+	return &ExchangeError{Exchange: "Test", Kind: ERROR_UNKNOWN, Message: "Timed out."}
+}
+
+func (v *executionTestStuckVenue) OpenOrders() ([]Order, error) {
+	v.mtx.Lock()
+	defer v.mtx.Unlock()
+	if v.closed {
+		return nil, nil
+	}
+	open := []Order{}
+	for i := range v.orders {
+		open = append(open, Order{ExchangeOrderID: strconv.Itoa(i)})
+	}
+	return open, nil
+}
+
+func TestExecutionCancelFailed(t *testing.T) {
+	t.Parallel()
+	v := &executionTestStuckVenue{executionTestVenue: executionTestVenue{bid: 0.019, ask: 0.02, passiveFill: 0.5}}
+	summary, err := NewExecutor(v).Execute(executionTestParams(EXECUTION_CHASE, 10))
+	if err != ErrExecutionCancelFailed || summary.Orders != 1 || summary.Filled != 5 {
+		t.Error(fmt.Sprintf("Test failed. Expected to stop rather than re-price a working order. Actual %+v err=%v", summary, err))
+	}
+
+	v = &executionTestStuckVenue{executionTestVenue: executionTestVenue{bid: 0.019, ask: 0.02, passiveFill: 0.5}, closed: true}
+	summary, err = NewExecutor(v).Execute(executionTestParams(EXECUTION_CHASE, 10))
+	if err != nil || summary.Orders < 2 || math.Abs(summary.Filled-10) > EXECUTION_DUST {
+		t.Error(fmt.Sprintf("Test failed. Expected to re-price once the order is confirmed closed. Actual %+v err=%v", summary, err))
+	}
+}
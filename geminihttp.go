@@ -11,8 +11,9 @@ import (
 )
 
 const (
-	GEMINI_API_URL     = "https://api.gemini.com"
-	GEMINI_API_VERSION = "1"
+	GEMINI_API_URL         = "https://api.gemini.com"
+	GEMINI_SANDBOX_API_URL = "https://api.sandbox.gemini.com"
+	GEMINI_API_VERSION     = "1"
 
 	GEMINI_SYMBOLS              = "symbols"
 	GEMINI_ORDERBOOK            = "book"
@@ -35,6 +36,7 @@ type Gemini struct {
 	Websocket               bool
 	RESTPollingDelay        time.Duration
 	AuthenticatedAPISupport bool
+	APIUrl                  string
 	APIKey, APISecret       string
 	BaseCurrencies          []string
 	AvailablePairs          []string
@@ -43,7 +45,7 @@ type Gemini struct {
 
 type GeminiOrderbookEntry struct {
 	Price    float64 `json:"price,string"`
-	Quantity float64 `json:"quantity,string"`
+	Quantity float64 `json:"amount,string"`
 }
 
 type GeminiOrderbook struct {
@@ -54,13 +56,13 @@ type GeminiOrderbook struct {
 type GeminiTrade struct {
 	Timestamp int64   `json:"timestamp"`
 	TID       int64   `json:"tid"`
-	Price     float64 `json:"price"`
-	Amount    float64 `json:"amount"`
-	Side      string  `json:"taker"`
+	Price     float64 `json:"price,string"`
+	Amount    float64 `json:"amount,string"`
+	Side      string  `json:"type"`
 }
 
 type GeminiOrder struct {
-	OrderID           int64   `json:"order_id"`
+	OrderID           int64   `json:"order_id,string"`
 	ClientOrderID     string  `json:"client_order_id"`
 	Symbol            string  `json:"symbol"`
 	Exchange          string  `json:"exchange"`
@@ -68,7 +70,7 @@ type GeminiOrder struct {
 	AvgExecutionPrice float64 `json:"avg_execution_price,string"`
 	Side              string  `json:"side"`
 	Type              string  `json:"type"`
-	Timestamp         int64   `json:"timestamp"`
+	Timestamp         int64   `json:"timestamp,string"`
 	TimestampMS       int64   `json:"timestampms"`
 	IsLive            bool    `json:"is_live"`
 	IsCancelled       bool    `json:"is_cancelled"`
@@ -79,26 +81,30 @@ type GeminiOrder struct {
 }
 
 type GeminiOrderResult struct {
-	Result bool `json:"result"`
+	Result  string `json:"result"`
+	Details struct {
+		CancelledOrders []int64 `json:"cancelledOrders"`
+		CancelRejects   []int64 `json:"cancelRejects"`
+	} `json:"details"`
 }
 
 type GeminiTradeHistory struct {
-	Price         float64 `json:"price"`
-	Amount        float64 `json:"amount"`
+	Price         float64 `json:"price,string"`
+	Amount        float64 `json:"amount,string"`
 	Timestamp     int64   `json:"timestamp"`
 	TimestampMS   int64   `json:"timestampms"`
 	Type          string  `json:"type"`
 	FeeCurrency   string  `json:"fee_currency"`
-	FeeAmount     float64 `json:"fee_amount"`
+	FeeAmount     float64 `json:"fee_amount,string"`
 	TID           int64   `json:"tid"`
-	OrderID       int64   `json:"order_id"`
+	OrderID       int64   `json:"order_id,string"`
 	ClientOrderID string  `json:"client_order_id"`
 }
 
 type GeminiBalance struct {
 	Currency  string  `json:"currency"`
-	Amount    float64 `json:"amount"`
-	Available float64 `json:"available"`
+	Amount    float64 `json:"amount,string"`
+	Available float64 `json:"available,string"`
 }
 
 func (g *Gemini) SetDefaults() {
@@ -107,6 +113,7 @@ func (g *Gemini) SetDefaults() {
 	g.Verbose = false
 	g.Websocket = false
 	g.RESTPollingDelay = 10
+	g.APIUrl = GEMINI_API_URL
 }
 
 func (g *Gemini) GetName() string {
@@ -134,6 +141,7 @@ func (g *Gemini) Setup(exch Exchanges) {
 		g.BaseCurrencies = SplitStrings(exch.BaseCurrencies, ",")
 		g.AvailablePairs = SplitStrings(exch.AvailablePairs, ",")
 		g.EnabledPairs = SplitStrings(exch.EnabledPairs, ",")
+		g.APIUrl = GetExchangeURLs(exch, ExchangeURLs{API: g.APIUrl}).API
 	}
 }
 
@@ -188,8 +196,8 @@ func (g *Gemini) Run() {
 
 func (g *Gemini) GetSymbols() ([]string, error) {
 	symbols := []string{}
-	path := fmt.Sprintf("%s/v%s/%s", GEMINI_API_URL, GEMINI_API_VERSION, GEMINI_SYMBOLS)
-	err := SendHTTPGetRequest(path, true, &symbols)
+	path := fmt.Sprintf("%s/v%s/%s", g.APIUrl, GEMINI_API_VERSION, GEMINI_SYMBOLS)
+	err := GetHTTPClient(g.Name).SendHTTPGetRequest(path, true, &symbols)
 	if err != nil {
 		return nil, err
 	}
@@ -197,9 +205,9 @@ func (g *Gemini) GetSymbols() ([]string, error) {
 }
 
 func (g *Gemini) GetOrderbook(currency string, params url.Values) (GeminiOrderbook, error) {
-	path := EncodeURLValues(fmt.Sprintf("%s/v%s/%s/%s", GEMINI_API_URL, GEMINI_API_VERSION, GEMINI_ORDERBOOK, currency), params)
+	path := EncodeURLValues(fmt.Sprintf("%s/v%s/%s/%s", g.APIUrl, GEMINI_API_VERSION, GEMINI_ORDERBOOK, currency), params)
 	orderbook := GeminiOrderbook{}
-	err := SendHTTPGetRequest(path, true, &orderbook)
+	err := GetHTTPClient(g.Name).SendHTTPGetRequest(path, true, &orderbook)
 	if err != nil {
 		return GeminiOrderbook{}, err
 	}
@@ -208,9 +216,9 @@ func (g *Gemini) GetOrderbook(currency string, params url.Values) (GeminiOrderbo
 }
 
 func (g *Gemini) GetTrades(currency string, params url.Values) ([]GeminiTrade, error) {
-	path := EncodeURLValues(fmt.Sprintf("%s/v%s/%s/%s", GEMINI_API_URL, GEMINI_API_VERSION, GEMINI_TRADES, currency), params)
+	path := EncodeURLValues(fmt.Sprintf("%s/v%s/%s/%s", g.APIUrl, GEMINI_API_VERSION, GEMINI_TRADES, currency), params)
 	trades := []GeminiTrade{}
-	err := SendHTTPGetRequest(path, true, &trades)
+	err := GetHTTPClient(g.Name).SendHTTPGetRequest(path, true, &trades)
 	if err != nil {
 		return []GeminiTrade{}, err
 	}
@@ -246,15 +254,15 @@ func (g *Gemini) CancelOrder(OrderID int64) (GeminiOrder, error) {
 	return response, nil
 }
 
-func (g *Gemini) CancelOrders(sessions bool) ([]GeminiOrderResult, error) {
-	response := []GeminiOrderResult{}
+func (g *Gemini) CancelOrders(sessions bool) (GeminiOrderResult, error) {
+	response := GeminiOrderResult{}
 	path := GEMINI_ORDER_CANCEL_ALL
 	if sessions {
 		path = GEMINI_ORDER_CANCEL_SESSION
 	}
 	err := g.SendAuthenticatedHTTPRequest("POST", path, nil, &response)
 	if err != nil {
-		return nil, err
+		return GeminiOrderResult{}, err
 	}
 	return response, nil
 }
@@ -304,7 +312,7 @@ func (g *Gemini) GetBalances() ([]GeminiBalance, error) {
 
 func (g *Gemini) PostHeartbeat() (bool, error) {
 	type Response struct {
-		Result bool `json:"result"`
+		Result string `json:"result"`
 	}
 
 	response := Response{}
@@ -313,13 +321,14 @@ func (g *Gemini) PostHeartbeat() (bool, error) {
 		return false, err
 	}
 
-	return response.Result, nil
+	return response.Result == "ok", nil
 }
 
 func (g *Gemini) SendAuthenticatedHTTPRequest(method, path string, params map[string]interface{}, result interface{}) (err error) {
+	endpoint := fmt.Sprintf("/v%s/%s", GEMINI_API_VERSION, path)
 	request := make(map[string]interface{})
-	request["request"] = fmt.Sprintf("/v%s/%s", GEMINI_API_VERSION, path)
-	request["nonce"] = time.Now().UnixNano()
+	request["request"] = endpoint
+	request["nonce"] = Nonces.Next(NonceKey(g.Name, g.APIKey), time.Nanosecond)
 
 	if params != nil {
 		for key, value := range params {
@@ -344,12 +353,22 @@ func (g *Gemini) SendAuthenticatedHTTPRequest(method, path string, params map[st
 	headers["X-GEMINI-PAYLOAD"] = PayloadBase64
 	headers["X-GEMINI-SIGNATURE"] = HexEncodeToString(hmac)
 
-	resp, err := SendHTTPRequest(method, BITFINEX_API_URL+path, headers, strings.NewReader(""))
+	resp, err := GetHTTPClient(g.Name).SendAuthenticatedHTTPRequest(method, g.APIUrl+endpoint, headers, strings.NewReader(""))
 
 	if g.Verbose {
 		log.Printf("Recieved raw: \n%s\n", resp)
 	}
 
+	if err != nil {
+		return ClassifyExchangeError(g.Name, err)
+	}
+
+	// errors are usually sent with a 4xx, but not always
+	err = CheckExchangeErrorBody(g.Name, resp)
+	if err != nil {
+		return err
+	}
+
 	err = JSONDecode([]byte(resp), &result)
 
 	if err != nil {
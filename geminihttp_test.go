@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+func geminiFixtures(t *testing.T) (*Gemini, *fixtureServer) {
+	g := &Gemini{}
+	g.SetDefaults()
+	g.SetAPIKeys("gemini-key", "gemini-secret")
+	return g, newFixturePayloadServer(t, g.Name, "gemini.json", func(h http.Header) string {
+		payload, _ := Base64Decode(h.Get("X-GEMINI-PAYLOAD"))
+		return string(payload)
+	})
+}
+
+func TestGeminiPublicFixtures(t *testing.T) {
+	g, f := geminiFixtures(t)
+	defer f.Close()
+
+	runFixtureCases(t, f, []fixtureCase{
+		{Name: "GetSymbols", Call: func() (interface{}, error) { return g.GetSymbols() },
+			Want: []string{"[btcusd ethbtc ethusd]"}},
+		{Name: "GetOrderbook", Call: func() (interface{}, error) {
+			return g.GetOrderbook("btcusd", url.Values{"limit_bids": {"1"}, "limit_asks": {"1"}})
+		},
+			Params: map[string]string{"limit_bids": "1", "limit_asks": "1"},
+			Want:   []string{"{Bids:[{Price:3607.85 Quantity:6.643373}] Asks:[{Price:3607.86 Quantity:14.68205084}]}"}},
+		{Name: "GetTrades", Call: func() (interface{}, error) { return g.GetTrades("btcusd", url.Values{"limit_trades": {"1"}}) },
+			Params: map[string]string{"limit_trades": "1"},
+			Want:   []string{"[{Timestamp:1547146811 TID:5335307668 Price:3610.85 Amount:0.27413495 Side:buy}]"}},
+	})
+}
+
+func TestGeminiAuthenticatedFixtures(t *testing.T) {
+	g, f := geminiFixtures(t)
+	defer f.Close()
+
+	runFixtureCases(t, f, []fixtureCase{
+		{Name: "NewOrder", Call: func() (interface{}, error) { return g.NewOrder("btcusd", 1, 34.23, "buy", "exchange limit") },
+			Params: map[string]string{"request": "/v1/order/new", "symbol": "btcusd", "amount": "1", "price": "34.23", "side": "buy", "type": "exchange limit"},
+			Want:   []string{"22333"}},
+		{Name: "NewOrder insufficient", Call: func() (interface{}, error) { return g.NewOrder("btcusd", 100, 3000, "sell", "exchange limit") },
+			Err: ERROR_INSUFFICIENT_FUNDS},
+		{Name: "CancelOrder", Call: func() (interface{}, error) { return g.CancelOrder(22333) },
+			Params: map[string]string{"request": "/v1/order/cancel", "order_id": "22333"},
+			Want:   []string{"OrderID:22333", "Timestamp:1128938491", "IsLive:false IsCancelled:true"}},
+		{Name: "CancelOrder unknown", Call: func() (interface{}, error) { return g.CancelOrder(1) },
+			Err: ERROR_ORDER_NOT_FOUND},
+		{Name: "CancelOrders", Call: func() (interface{}, error) { return g.CancelOrders(false) },
+			Params: map[string]string{"request": "/v1/order/cancel/all"},
+			Want:   []string{"{Result:ok Details:{CancelledOrders:[330429345 330429346] CancelRejects:[]}}"}},
+		{Name: "CancelOrders session", Call: func() (interface{}, error) { return g.CancelOrders(true) },
+			Params: map[string]string{"request": "/v1/order/cancel/session"},
+			Want:   []string{"CancelledOrders:[330429106]"}},
+		{Name: "GetOrderStatus", Call: func() (interface{}, error) { return g.GetOrderStatus(44375901) },
+			Params: map[string]string{"order_id": "44375901"},
+			Want:   []string{"{OrderID:44375901 ClientOrderID: Symbol:btcusd Exchange:gemini Price:400 AvgExecutionPrice:400 Side:buy Type:exchange limit Timestamp:1494870642 TimestampMS:1494870642156 IsLive:false IsCancelled:false WasForced:false ExecutedAmount:3 RemainingAmount:0 OriginalAmount:3}"}},
+		{Name: "GetOrders", Call: func() (interface{}, error) { return g.GetOrders() },
+			Want: []string{"OrderID:107421210", "Symbol:ethusd", "RemainingAmount:1"}},
+		{Name: "GetTradeHistory", Call: func() (interface{}, error) { return g.GetTradeHistory("btcusd", 1547232900) },
+			Params: map[string]string{"symbol": "btcusd", "timestamp": "1547232900"},
+			Want:   []string{"[{Price:3648.09 Amount:0.0027343246 Timestamp:1547232911 TimestampMS:1547232911021 Type:Buy FeeCurrency:USD FeeAmount:0.024937655575035 TID:107317526 OrderID:107317524 ClientOrderID:}]"}},
+		{Name: "GetBalances", Call: func() (interface{}, error) { return g.GetBalances() },
+			Want: []string{"[{Currency:BTC Amount:1154.62034001 Available:1129.10517279} {Currency:USD Amount:18722.79 Available:14481.62}]"}},
+		{Name: "PostHeartbeat", Call: func() (interface{}, error) { return g.PostHeartbeat() },
+			Params: map[string]string{"request": "/v1/heartbeat"},
+			Want:   []string{"true"}},
+	})
+}
+
+func TestGeminiSigning(t *testing.T) {
+	g, f := geminiFixtures(t)
+	defer f.Close()
+
+	if _, err := g.GetBalances(); err != nil {
+		t.Fatal(err)
+	}
+
+	req := f.Last()
+	payload := req.Header.Get("X-GEMINI-PAYLOAD")
+	if sign := HexEncodeToString(GetHMAC(HASH_SHA512_384, []byte(payload), []byte("gemini-secret"))); req.Header.Get("X-GEMINI-SIGNATURE") != sign {
+		t.Error(fmt.Sprintf("Test failed. Expected signature %s. Actual %s", sign, req.Header.Get("X-GEMINI-SIGNATURE")))
+	}
+	if req.Header.Get("X-GEMINI-APIKEY") != "gemini-key" || req.Body != "" {
+		t.Error(fmt.Sprintf("Test failed. Unexpected request %v %s", req.Header, req.Body))
+	}
+	if params := req.Params(); params.Get("request") != "/v1/balances" || params.Get("nonce") == "" {
+		t.Error(fmt.Sprintf("Test failed. Unexpected payload %s", req.Payload))
+	}
+}
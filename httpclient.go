@@ -0,0 +1,275 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Every REST request goes through an HTTPClient. Clients share one transport
+// so connections are kept alive and pooled per host, and each exchange gets
+// its own timeout and retry settings. Only idempotent requests are retried:
+// a POST may have placed an order before the connection dropped, and signed
+// requests can't be replayed with the same nonce anyway. Exchange clients
+// are rate limited, see ratelimit.go.
+
+const (
+	HTTP_DEFAULT_TIMEOUT      = 15 * time.Second
+	HTTP_DEFAULT_RETRIES      = 3
+	HTTP_DEFAULT_BACKOFF      = 500 * time.Millisecond
+	HTTP_MAX_BACKOFF          = 10 * time.Second
+	HTTP_MAX_IDLE_CONNS       = 10 // per host
+	HTTP_ERROR_BODY_LOG_LIMIT = 512
+)
+
+var (
+	ErrHTTPInvalidMethod = errors.New("Invalid HTTP method specified.")
+)
+
+// HTTPError is returned for any response outside 2xx, with the body so that
+// callers can still parse the exchange's error message out of it.
+type HTTPError struct {
+	Method     string
+	URL        string
+	StatusCode int
+	Body       string
+}
+
+func (e *HTTPError) Error() string {
+	body := e.Body
+	if len(body) > HTTP_ERROR_BODY_LOG_LIMIT {
+		body = body[:HTTP_ERROR_BODY_LOG_LIMIT] + "..."
+	}
+	return fmt.Sprintf("%s %s: HTTP status %d: %s", e.Method, e.URL, e.StatusCode, body)
+}
+
+// Temporary reports whether the request is worth trying again.
+func (e *HTTPError) Temporary() bool {
+	return e.StatusCode >= 500
+}
+
+var httpTransport = &http.Transport{
+	Proxy: http.ProxyFromEnvironment,
+	Dial: (&net.Dialer{
+		Timeout:   10 * time.Second,
+		KeepAlive: 30 * time.Second,
+	}).Dial,
+	TLSHandshakeTimeout: 10 * time.Second,
+	MaxIdleConnsPerHost: HTTP_MAX_IDLE_CONNS,
+}
+
+type HTTPClient struct {
+	Name    string
+	Retries int
+	Backoff time.Duration // doubled after each retry
+	Verbose bool
+	Limiter *RateLimiter // nil doesn't limit
+
+	client *http.Client
+}
+
+func NewHTTPClient(name string, timeout time.Duration) *HTTPClient {
+	if timeout <= 0 {
+		timeout = HTTP_DEFAULT_TIMEOUT
+	}
+	return &HTTPClient{
+		Name:    name,
+		Retries: HTTP_DEFAULT_RETRIES,
+		Backoff: HTTP_DEFAULT_BACKOFF,
+		client:  &http.Client{Transport: httpTransport, Timeout: timeout},
+	}
+}
+
+// DefaultHTTPClient is used for anything that isn't an exchange.
+var DefaultHTTPClient = NewHTTPClient("default", HTTP_DEFAULT_TIMEOUT)
+
+var (
+	httpClients    = make(map[string]*HTTPClient)
+	httpClientsMtx sync.Mutex
+)
+
+// GetHTTPClient returns the named exchange's client, creating one with the
+// defaults if it hasn't been configured.
+func GetHTTPClient(name string) *HTTPClient {
+	httpClientsMtx.Lock()
+	defer httpClientsMtx.Unlock()
+
+	client, ok := httpClients[name]
+	if !ok {
+		client = NewHTTPClient(name, HTTP_DEFAULT_TIMEOUT)
+		client.Limiter = NewRateLimiter(GetRateLimitConfig(Exchanges{Name: name}))
+		httpClients[name] = client
+	}
+	return client
+}
+
+// ConfigureHTTPClient sets up the exchange's client from its config.
+func ConfigureHTTPClient(exch Exchanges) {
+	client := NewHTTPClient(exch.Name, exch.HTTPTimeout*time.Second)
+	if exch.HTTPRetries > 0 {
+		client.Retries = exch.HTTPRetries
+	}
+	client.Verbose = exch.Verbose
+	client.Limiter = NewRateLimiter(GetRateLimitConfig(exch))
+	client.Limiter.FailFast = exch.RateLimitFailFast
+
+	httpClientsMtx.Lock()
+	defer httpClientsMtx.Unlock()
+	httpClients[exch.Name] = client
+}
+
+func (c *HTTPClient) Timeout() time.Duration {
+	return c.client.Timeout
+}
+
+func httpMethodIdempotent(method string) bool {
+	switch method {
+	case "GET", "HEAD", "PUT", "DELETE", "OPTIONS":
+		return true
+	}
+	return false
+}
+
+func httpValidMethod(method string) bool {
+	switch method {
+	case "GET", "HEAD", "POST", "PUT", "PATCH", "DELETE", "OPTIONS":
+		return true
+	}
+	return false
+}
+
+// httpRetryable reports whether err is a timeout or server side failure.
+func httpRetryable(err error) bool {
+	if x, ok := err.(*HTTPError); ok {
+		return x.Temporary()
+	}
+	if x, ok := err.(net.Error); ok {
+		return x.Timeout() || x.Temporary()
+	}
+	return false
+}
+
+// Do sends the request and returns the response body. Responses outside 2xx
+// return the body along with an *HTTPError.
+func (c *HTTPClient) Do(method, path string, headers map[string]string, body io.Reader) (string, error) {
+	return c.send(false, method, path, headers, body)
+}
+
+func (c *HTTPClient) send(authenticated bool, method, path string, headers map[string]string, body io.Reader) (string, error) {
+	method = strings.ToUpper(method)
+	if !httpValidMethod(method) {
+		return "", ErrHTTPInvalidMethod
+	}
+
+	// hold on to the body so it can be sent again
+	var payload []byte
+	if body != nil {
+		var err error
+		payload, err = ioutil.ReadAll(body)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	// signed requests carry a nonce, so sending one again would be rejected
+	attempts := 1
+	if !authenticated && httpMethodIdempotent(method) && c.Retries > 0 {
+		attempts += c.Retries
+	}
+
+	backoff := c.Backoff
+	for attempt := 1; ; attempt++ {
+		err := c.Limiter.Wait(authenticated, path)
+		if err != nil {
+			return "", err
+		}
+
+		resp, err := c.do(method, path, headers, payload)
+		if err == nil || attempt >= attempts || !httpRetryable(err) {
+			return resp, err
+		}
+
+		if c.Verbose {
+			log.Printf("%s: retrying %s %s in %v (attempt %d/%d): %v", c.Name, method, path, backoff, attempt, attempts, err)
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+		if backoff > HTTP_MAX_BACKOFF {
+			backoff = HTTP_MAX_BACKOFF
+		}
+	}
+}
+
+func (c *HTTPClient) do(method, path string, headers map[string]string, payload []byte) (string, error) {
+	var body io.Reader
+	if payload != nil {
+		body = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequest(method, path, body)
+	if err != nil {
+		return "", err
+	}
+
+	for k, v := range headers {
+		req.Header.Add(k, v)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	contents, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return string(contents), &HTTPError{Method: method, URL: path, StatusCode: resp.StatusCode, Body: string(contents)}
+	}
+	return string(contents), nil
+}
+
+func (c *HTTPClient) SendHTTPRequest(method, path string, headers map[string]string, body io.Reader) (string, error) {
+	return c.Do(method, path, headers, body)
+}
+
+// SendAuthenticatedHTTPRequest is SendHTTPRequest limited by the exchange's
+// authenticated bucket.
+func (c *HTTPClient) SendAuthenticatedHTTPRequest(method, path string, headers map[string]string, body io.Reader) (string, error) {
+	return c.send(true, method, path, headers, body)
+}
+
+// SendHTTPGetRequest decodes the JSON response into result, or when jsonDecode
+// is false stores the raw body in result, which must then be a *[]byte or
+// *string.
+func (c *HTTPClient) SendHTTPGetRequest(url string, jsonDecode bool, result interface{}) error {
+	contents, err := c.Do("GET", url, nil, nil)
+	if err != nil {
+		return err
+	}
+
+	if jsonDecode {
+		return JSONDecode([]byte(contents), result)
+	}
+
+	switch x := result.(type) {
+	case *[]byte:
+		*x = []byte(contents)
+	case *string:
+		*x = contents
+	default:
+		return fmt.Errorf("unable to store raw response in %T", result)
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// httpTestServer fails the first failures requests with a 503.
+func httpTestServer(failures int32, calls *int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(calls, 1) <= failures {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte(`{"error":"busy"}`))
+			return
+		}
+		w.Write([]byte(fmt.Sprintf(`{"method":"%s"}`, r.Method)))
+	}))
+}
+
+func httpTestClient() *HTTPClient {
+	client := NewHTTPClient("Test", time.Second)
+	client.Backoff = time.Millisecond
+	return client
+}
+
+func TestHTTPClientRetries(t *testing.T) {
+	t.Parallel()
+	var calls int32
+	server := httpTestServer(2, &calls)
+	defer server.Close()
+
+	result := struct{ Method string }{}
+	err := httpTestClient().SendHTTPGetRequest(server.URL, true, &result)
+	if err != nil || result.Method != "GET" || atomic.LoadInt32(&calls) != 3 {
+		t.Error(fmt.Sprintf("Test failed. Expected success after 2 retries. Actual calls=%d err=%v", atomic.LoadInt32(&calls), err))
+	}
+}
+
+func TestHTTPClientNoRetryPost(t *testing.T) {
+	t.Parallel()
+	var calls int32
+	server := httpTestServer(1, &calls)
+	defer server.Close()
+
+	body, err := httpTestClient().SendHTTPRequest("POST", server.URL, nil, strings.NewReader("order"))
+	httpErr, ok := err.(*HTTPError)
+	if !ok || httpErr.StatusCode != http.StatusServiceUnavailable || httpErr.Body != body || atomic.LoadInt32(&calls) != 1 {
+		t.Error(fmt.Sprintf("Test failed. Expected one failed POST. Actual calls=%d err=%v", atomic.LoadInt32(&calls), err))
+	}
+}
+
+func TestHTTPClientNoRetryAuthenticated(t *testing.T) {
+	t.Parallel()
+	var calls int32
+	server := httpTestServer(1, &calls)
+	defer server.Close()
+
+	_, err := httpTestClient().SendAuthenticatedHTTPRequest("GET", server.URL, nil, nil)
+	httpErr, ok := err.(*HTTPError)
+	if !ok || httpErr.StatusCode != http.StatusServiceUnavailable || atomic.LoadInt32(&calls) != 1 {
+		t.Error(fmt.Sprintf("Test failed. Expected one failed signed GET. Actual calls=%d err=%v", atomic.LoadInt32(&calls), err))
+	}
+}
+
+func TestHTTPClientMethodsAndRaw(t *testing.T) {
+	t.Parallel()
+	var calls int32
+	server := httpTestServer(0, &calls)
+	defer server.Close()
+
+	client := httpTestClient()
+	for _, x := range []string{"PUT", "PATCH", "delete"} {
+		body, err := client.SendHTTPRequest(x, server.URL, nil, nil)
+		if err != nil || !strings.Contains(body, strings.ToUpper(x)) {
+			t.Error(fmt.Sprintf("Test failed. Expected %s to be sent. Actual %s %v", x, body, err))
+		}
+	}
+	if _, err := client.SendHTTPRequest("FETCH", server.URL, nil, nil); err != ErrHTTPInvalidMethod {
+		t.Error(fmt.Sprintf("Test failed. Expected %v. Actual %v", ErrHTTPInvalidMethod, err))
+	}
+
+	var raw string
+	if err := client.SendHTTPGetRequest(server.URL, false, &raw); err != nil || raw != `{"method":"GET"}` {
+		t.Error(fmt.Sprintf("Test failed. Expected raw body. Actual %s %v", raw, err))
+	}
+}
+
+func TestHTTPClientTimeout(t *testing.T) {
+	t.Parallel()
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			time.Sleep(200 * time.Millisecond)
+		}
+		w.Write([]byte("{}"))
+	}))
+	defer server.Close()
+
+	client := NewHTTPClient("Test", 50*time.Millisecond)
+	client.Backoff = time.Millisecond
+	if _, err := client.SendHTTPRequest("GET", server.URL, nil, nil); err != nil || atomic.LoadInt32(&calls) != 2 {
+		t.Error(fmt.Sprintf("Test failed. Expected a retry after the timeout. Actual calls=%d err=%v", atomic.LoadInt32(&calls), err))
+	}
+}
@@ -1,6 +1,8 @@
 package main
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/url"
@@ -10,8 +12,9 @@ import (
 )
 
 const (
-	HUOBI_API_URL     = "https://api.huobi.com/apiv2.php"
-	HUOBI_API_VERSION = "2"
+	HUOBI_API_URL         = "https://api.huobi.com/apiv2.php"
+	HUOBI_MARKET_DATA_URL = "http://market.huobi.com/staticmarket/"
+	HUOBI_API_VERSION     = "2"
 )
 
 type HUOBI struct {
@@ -19,8 +22,11 @@ type HUOBI struct {
 	Enabled                 bool
 	Verbose                 bool
 	Websocket               bool
+	WebsocketURL            string
 	RESTPollingDelay        time.Duration
 	AuthenticatedAPISupport bool
+	APIUrl                  string
+	MarketDataURL           string
 	AccessKey, SecretKey    string
 	Fee                     float64
 	BaseCurrencies          []string
@@ -42,6 +48,58 @@ type HuobiTickerResponse struct {
 	Ticker HuobiTicker
 }
 
+type HuobiOrderbook struct {
+	Asks   [][]float64 `json:"asks"`
+	Bids   [][]float64 `json:"bids"`
+	Symbol string      `json:"symbol"`
+}
+
+type HuobiAccountInfo struct {
+	Total        float64 `json:"total,string"`
+	NetAsset     float64 `json:"net_asset,string"`
+	AvailableCNY float64 `json:"available_cny_display,string"`
+	AvailableBTC float64 `json:"available_btc_display,string"`
+	AvailableLTC float64 `json:"available_ltc_display,string"`
+	FrozenCNY    float64 `json:"frozen_cny_display,string"`
+	FrozenBTC    float64 `json:"frozen_btc_display,string"`
+	FrozenLTC    float64 `json:"frozen_ltc_display,string"`
+	LoanCNY      float64 `json:"loan_cny_display,string"`
+	LoanBTC      float64 `json:"loan_btc_display,string"`
+	LoanLTC      float64 `json:"loan_ltc_display,string"`
+}
+
+// HuobiOrder types are 1 buy, 2 sell, 3 market buy and 4 market sell.
+type HuobiOrder struct {
+	ID                int64   `json:"id"`
+	Type              int     `json:"type"`
+	OrderPrice        float64 `json:"order_price,string"`
+	OrderAmount       float64 `json:"order_amount,string"`
+	ProcessedAmount   float64 `json:"processed_amount,string"`
+	OrderTime         int64   `json:"order_time"`
+	LastProcessedTime int64   `json:"last_processed_time"`
+}
+
+// HuobiOrderInfo statuses are 0 open, 1 partially filled, 2 filled,
+// 3 cancelled, 4 deleted, 5 partially filled then cancelled, 6 failed and
+// 7 queued.
+type HuobiOrderInfo struct {
+	ID              int64   `json:"id"`
+	Type            int     `json:"type"`
+	OrderPrice      float64 `json:"order_price,string"`
+	OrderAmount     float64 `json:"order_amount,string"`
+	ProcessedPrice  float64 `json:"processed_price,string"`
+	ProcessedAmount float64 `json:"processed_amount,string"`
+	Vot             float64 `json:"vot,string"`
+	Fee             float64 `json:"fee,string"`
+	Total           float64 `json:"total,string"`
+	Status          int     `json:"status"`
+}
+
+type HuobiOrderResponse struct {
+	Result string `json:"result"`
+	ID     int64  `json:"id"`
+}
+
 func (h *HUOBI) SetDefaults() {
 	h.Name = "Huobi"
 	h.Enabled = false
@@ -49,6 +107,9 @@ func (h *HUOBI) SetDefaults() {
 	h.Verbose = false
 	h.Websocket = false
 	h.RESTPollingDelay = 10
+	h.APIUrl = HUOBI_API_URL
+	h.MarketDataURL = HUOBI_MARKET_DATA_URL
+	h.WebsocketURL = HUOBI_SOCKETIO_ADDRESS
 }
 
 func (h *HUOBI) GetName() string {
@@ -76,6 +137,11 @@ func (h *HUOBI) Setup(exch Exchanges) {
 		h.BaseCurrencies = SplitStrings(exch.BaseCurrencies, ",")
 		h.AvailablePairs = SplitStrings(exch.AvailablePairs, ",")
 		h.EnabledPairs = SplitStrings(exch.EnabledPairs, ",")
+		urls := GetExchangeURLs(exch, ExchangeURLs{h.APIUrl, h.WebsocketURL})
+		h.APIUrl, h.WebsocketURL = urls.API, urls.Websocket
+		if exch.MarketDataURL != "" {
+			h.MarketDataURL = matchTrailingSlash(exch.MarketDataURL, HUOBI_MARKET_DATA_URL)
+		}
 	}
 }
 
@@ -94,7 +160,7 @@ func (h *HUOBI) GetFee() float64 {
 
 func (h *HUOBI) Run() {
 	if h.Verbose {
-		log.Printf("%s Websocket: %s (url: %s).\n", h.GetName(), IsEnabled(h.Websocket), HUOBI_SOCKETIO_ADDRESS)
+		log.Printf("%s Websocket: %s (url: %s).\n", h.GetName(), IsEnabled(h.Websocket), h.WebsocketURL)
 		log.Printf("%s polling delay: %ds.\n", h.GetName(), h.RESTPollingDelay)
 		log.Printf("%s %d currencies enabled: %s.\n", h.GetName(), len(h.EnabledPairs), h.EnabledPairs)
 	}
@@ -122,8 +188,8 @@ func (h *HUOBI) Run() {
 
 func (h *HUOBI) GetTicker(symbol string) HuobiTicker {
 	resp := HuobiTickerResponse{}
-	path := fmt.Sprintf("http://market.huobi.com/staticmarket/ticker_%s_json.js", symbol)
-	err := SendHTTPGetRequest(path, true, &resp)
+	path := fmt.Sprintf("%sticker_%s_json.js", h.MarketDataURL, symbol)
+	err := GetHTTPClient(h.Name).SendHTTPGetRequest(path, true, &resp)
 
 	if err != nil {
 		log.Println(err)
@@ -132,46 +198,41 @@ func (h *HUOBI) GetTicker(symbol string) HuobiTicker {
 	return resp.Ticker
 }
 
-func (h *HUOBI) GetOrderBook(symbol string) bool {
-	path := fmt.Sprintf("http://market.huobi.com/staticmarket/depth_%s_json.js", symbol)
-	err := SendHTTPGetRequest(path, true, nil)
+func (h *HUOBI) GetOrderBook(symbol string) (HuobiOrderbook, error) {
+	orderbook := HuobiOrderbook{}
+	path := fmt.Sprintf("%sdepth_%s_json.js", h.MarketDataURL, symbol)
+	err := GetHTTPClient(h.Name).SendHTTPGetRequest(path, true, &orderbook)
 	if err != nil {
-		log.Println(err)
-		return false
+		return orderbook, ClassifyExchangeError(h.Name, err)
 	}
-	return true
+	return orderbook, nil
 }
 
-func (h *HUOBI) GetAccountInfo() {
-	err := h.SendAuthenticatedRequest("get_account_info", url.Values{})
-
-	if err != nil {
-		log.Println(err)
-	}
+func (h *HUOBI) GetAccountInfo() (HuobiAccountInfo, error) {
+	info := HuobiAccountInfo{}
+	err := h.SendAuthenticatedRequest("get_account_info", url.Values{}, &info)
+	return info, err
 }
 
-func (h *HUOBI) GetOrders(coinType int) {
+func (h *HUOBI) GetOrders(coinType int) ([]HuobiOrder, error) {
 	values := url.Values{}
 	values.Set("coin_type", strconv.Itoa(coinType))
-	err := h.SendAuthenticatedRequest("get_orders", values)
-
-	if err != nil {
-		log.Println(err)
-	}
+	orders := []HuobiOrder{}
+	err := h.SendAuthenticatedRequest("get_orders", values, &orders)
+	return orders, err
 }
 
-func (h *HUOBI) GetOrderInfo(orderID, coinType int) {
+func (h *HUOBI) GetOrderInfo(orderID, coinType int) (HuobiOrderInfo, error) {
 	values := url.Values{}
 	values.Set("id", strconv.Itoa(orderID))
 	values.Set("coin_type", strconv.Itoa(coinType))
-	err := h.SendAuthenticatedRequest("order_info", values)
-
-	if err != nil {
-		log.Println(err)
-	}
+	info := HuobiOrderInfo{}
+	err := h.SendAuthenticatedRequest("order_info", values, &info)
+	return info, err
 }
 
-func (h *HUOBI) Trade(orderType string, coinType int, price, amount float64) {
+// Trade places a limit order, returning its ID.
+func (h *HUOBI) Trade(orderType string, coinType int, price, amount float64) (int64, error) {
 	values := url.Values{}
 	if orderType != "buy" {
 		orderType = "sell"
@@ -179,14 +240,14 @@ func (h *HUOBI) Trade(orderType string, coinType int, price, amount float64) {
 	values.Set("coin_type", strconv.Itoa(coinType))
 	values.Set("amount", strconv.FormatFloat(amount, 'f', -1, 64))
 	values.Set("price", strconv.FormatFloat(price, 'f', -1, 64))
-	err := h.SendAuthenticatedRequest(orderType, values)
-
-	if err != nil {
-		log.Println(err)
-	}
+	resp := HuobiOrderResponse{}
+	err := h.SendAuthenticatedRequest(orderType, values, &resp)
+	return resp.ID, err
 }
 
-func (h *HUOBI) MarketTrade(orderType string, coinType int, price, amount float64) {
+// MarketTrade places a market order, returning its ID. Market buys are sized
+// by price, the amount of CNY to spend, and market sells by amount.
+func (h *HUOBI) MarketTrade(orderType string, coinType int, price, amount float64) (int64, error) {
 	values := url.Values{}
 	if orderType != "buy_market" {
 		orderType = "sell_market"
@@ -194,82 +255,107 @@ func (h *HUOBI) MarketTrade(orderType string, coinType int, price, amount float6
 	values.Set("coin_type", strconv.Itoa(coinType))
 	values.Set("amount", strconv.FormatFloat(amount, 'f', -1, 64))
 	values.Set("price", strconv.FormatFloat(price, 'f', -1, 64))
-	err := h.SendAuthenticatedRequest(orderType, values)
-
-	if err != nil {
-		log.Println(err)
-	}
+	resp := HuobiOrderResponse{}
+	err := h.SendAuthenticatedRequest(orderType, values, &resp)
+	return resp.ID, err
 }
 
-func (h *HUOBI) CancelOrder(orderID, coinType int) {
+func (h *HUOBI) CancelOrder(orderID, coinType int) error {
 	values := url.Values{}
 	values.Set("coin_type", strconv.Itoa(coinType))
 	values.Set("id", strconv.Itoa(orderID))
-	err := h.SendAuthenticatedRequest("cancel_order", values)
-
+	resp := HuobiOrderResponse{}
+	err := h.SendAuthenticatedRequest("cancel_order", values, &resp)
 	if err != nil {
-		log.Println(err)
+		return err
+	}
+	if resp.Result != "success" {
+		return NewExchangeError(h.Name, "", fmt.Sprintf("unable to cancel order %d: %s", orderID, resp.Result))
 	}
+	return nil
 }
 
-func (h *HUOBI) ModifyOrder(orderType string, coinType, orderID int, price, amount float64) {
+// ModifyOrder changes the price and amount of an order, returning the ID of
+// the order replacing it.
+func (h *HUOBI) ModifyOrder(orderType string, coinType, orderID int, price, amount float64) (int64, error) {
 	values := url.Values{}
 	values.Set("coin_type", strconv.Itoa(coinType))
 	values.Set("id", strconv.Itoa(orderID))
 	values.Set("amount", strconv.FormatFloat(amount, 'f', -1, 64))
 	values.Set("price", strconv.FormatFloat(price, 'f', -1, 64))
-	err := h.SendAuthenticatedRequest("modify_order", values)
-
-	if err != nil {
-		log.Println(err)
-	}
+	resp := HuobiOrderResponse{}
+	err := h.SendAuthenticatedRequest("modify_order", values, &resp)
+	return resp.ID, err
 }
 
-func (h *HUOBI) GetNewDealOrders(coinType int) {
+func (h *HUOBI) GetNewDealOrders(coinType int) ([]HuobiOrder, error) {
 	values := url.Values{}
 	values.Set("coin_type", strconv.Itoa(coinType))
-	err := h.SendAuthenticatedRequest("get_new_deal_orders", values)
-
-	if err != nil {
-		log.Println(err)
-	}
+	orders := []HuobiOrder{}
+	err := h.SendAuthenticatedRequest("get_new_deal_orders", values, &orders)
+	return orders, err
 }
 
-func (h *HUOBI) GetOrderIDByTradeID(coinType, orderID int) {
+func (h *HUOBI) GetOrderIDByTradeID(coinType, orderID int) (int64, error) {
 	values := url.Values{}
 	values.Set("coin_type", strconv.Itoa(coinType))
 	values.Set("trade_id", strconv.Itoa(orderID))
-	err := h.SendAuthenticatedRequest("get_order_id_by_trade_id", values)
-
-	if err != nil {
-		log.Println(err)
-	}
+	resp := struct {
+		OrderID int64 `json:"order_id"`
+	}{}
+	err := h.SendAuthenticatedRequest("get_order_id_by_trade_id", values, &resp)
+	return resp.OrderID, err
 }
 
-func (h *HUOBI) SendAuthenticatedRequest(method string, v url.Values) error {
+func (h *HUOBI) SendAuthenticatedRequest(method string, v url.Values, result interface{}) error {
 	v.Set("access_key", h.AccessKey)
 	v.Set("created", strconv.FormatInt(time.Now().Unix(), 10))
 	v.Set("method", method)
-	hash := GetMD5([]byte(v.Encode() + "&secret_key=" + h.SecretKey))
+	v.Set("secret_key", h.SecretKey)
+	hash := GetMD5([]byte(v.Encode()))
+	v.Del("secret_key")
 	v.Set("sign", strings.ToLower(HexEncodeToString(hash)))
 	encoded := v.Encode()
 
 	if h.Verbose {
-		log.Printf("Sending POST request to %s with params %s\n", HUOBI_API_URL, encoded)
+		log.Printf("Sending POST request to %s with params %s\n", h.APIUrl, encoded)
 	}
 
 	headers := make(map[string]string)
 	headers["Content-Type"] = "application/x-www-form-urlencoded"
 
-	resp, err := SendHTTPRequest("POST", HUOBI_API_URL, headers, strings.NewReader(encoded))
+	resp, err := GetHTTPClient(h.Name).SendAuthenticatedHTTPRequest("POST", h.APIUrl, headers, strings.NewReader(encoded))
 
 	if err != nil {
-		return err
+		return ClassifyExchangeError(h.Name, err)
 	}
 
 	if h.Verbose {
 		log.Printf("Recieved raw: %s\n", resp)
 	}
 
+	failure := struct {
+		Code    int64  `json:"code"`
+		Msg     string `json:"msg"`
+		Message string `json:"message"`
+	}{}
+	if json.Unmarshal([]byte(resp), &failure) == nil && failure.Code != 0 {
+		message := failure.Msg
+		if message == "" {
+			message = failure.Message
+		}
+		return NewExchangeError(h.Name, strconv.FormatInt(failure.Code, 10), message)
+	}
+
+	if result == nil {
+		return nil
+	}
+
+	err = JSONDecode([]byte(resp), result)
+
+	if err != nil {
+		return errors.New("Unable to JSON Unmarshal response.")
+	}
+
 	return nil
 }
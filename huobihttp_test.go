@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func huobiFixtures(t *testing.T) (*HUOBI, *fixtureServer) {
+	h := &HUOBI{}
+	h.SetDefaults()
+	h.SetAPIKeys("huobi-key", "huobi-secret")
+	return h, newFixtureServer(t, h.Name, "huobi.json")
+}
+
+func TestHuobiPublicFixtures(t *testing.T) {
+	h, f := huobiFixtures(t)
+	defer f.Close()
+
+	runFixtureCases(t, f, []fixtureCase{
+		{Name: "GetTicker", Call: func() (interface{}, error) { return h.GetTicker("btc"), nil },
+			Want: []string{"{High:2808 Low:2726.01 Last:2785.14 Vol:1.1789609434e+06 Buy:2785.14 Sell:2785.7}"}},
+		{Name: "GetOrderBook", Call: func() (interface{}, error) { return h.GetOrderBook("btc") },
+			Want: []string{"{Asks:[[2785.7 0.5]] Bids:[[2785.14 1.2]] Symbol:btccny}"}},
+	})
+}
+
+func TestHuobiAuthenticatedFixtures(t *testing.T) {
+	h, f := huobiFixtures(t)
+	defer f.Close()
+
+	runFixtureCases(t, f, []fixtureCase{
+		{Name: "GetAccountInfo", Call: func() (interface{}, error) { return h.GetAccountInfo() },
+			Params: map[string]string{"method": "get_account_info", "access_key": "huobi-key", "secret_key": ""},
+			Want:   []string{"{Total:2 NetAsset:2 AvailableCNY:1 AvailableBTC:0.001 AvailableLTC:0 FrozenCNY:0"}},
+		{Name: "GetOrders", Call: func() (interface{}, error) { return h.GetOrders(1) },
+			Params: map[string]string{"method": "get_orders", "coin_type": "1"},
+			Want:   []string{"{ID:2202 Type:1 OrderPrice:2785.14 OrderAmount:0.5 ProcessedAmount:0.1 OrderTime:1452667901"}},
+		{Name: "GetOrderInfo", Call: func() (interface{}, error) { return h.GetOrderInfo(2202, 1) },
+			Params: map[string]string{"method": "order_info", "id": "2202"},
+			Want:   []string{"ProcessedPrice:2785.14 ProcessedAmount:0.1 Vot:278.51 Fee:0 Total:278.51 Status:1"}},
+		{Name: "Trade", Call: func() (interface{}, error) { return h.Trade("buy", 1, 2785.14, 0.5) },
+			Params: map[string]string{"method": "buy", "coin_type": "1", "price": "2785.14", "amount": "0.5"},
+			Want:   []string{"2202"}},
+		{Name: "Trade insufficient", Call: func() (interface{}, error) { return h.Trade("buy", 1, 2785.14, 1000) },
+			Err: ERROR_INSUFFICIENT_FUNDS},
+		{Name: "MarketTrade", Call: func() (interface{}, error) { return h.MarketTrade("sell", 1, 0, 0.5) },
+			Params: map[string]string{"method": "sell_market", "amount": "0.5"},
+			Want:   []string{"2203"}},
+		{Name: "CancelOrder", Call: func() (interface{}, error) { return nil, h.CancelOrder(2202, 1) },
+			Params: map[string]string{"method": "cancel_order", "id": "2202", "coin_type": "1"}},
+		{Name: "CancelOrder unknown", Call: func() (interface{}, error) { return nil, h.CancelOrder(404, 1) },
+			Err: ERROR_ORDER_NOT_FOUND},
+		{Name: "ModifyOrder", Call: func() (interface{}, error) { return h.ModifyOrder("buy", 1, 2202, 2780, 0.5) },
+			Params: map[string]string{"method": "modify_order", "id": "2202", "price": "2780"},
+			Want:   []string{"2204"}},
+		{Name: "GetNewDealOrders", Call: func() (interface{}, error) { return h.GetNewDealOrders(1) },
+			Params: map[string]string{"method": "get_new_deal_orders"},
+			Want:   []string{"{ID:2201 Type:2 OrderPrice:2790", "LastProcessedTime:1452667700}"}},
+		{Name: "GetOrderIDByTradeID", Call: func() (interface{}, error) { return h.GetOrderIDByTradeID(1, 3303) },
+			Params: map[string]string{"method": "get_order_id_by_trade_id", "trade_id": "3303"},
+			Want:   []string{"2202"}},
+	})
+}
+
+func TestHuobiSigning(t *testing.T) {
+	h, f := huobiFixtures(t)
+	defer f.Close()
+
+	// trade_id sorts after secret_key, which has to be signed in order
+	if _, err := h.GetOrderIDByTradeID(1, 3303); err != nil {
+		t.Fatal(err)
+	}
+
+	req := f.Last()
+	values := req.Form()
+	sign := values.Get("sign")
+	values.Del("sign")
+	values.Set("secret_key", "huobi-secret")
+	if expected := strings.ToLower(HexEncodeToString(GetMD5([]byte(values.Encode())))); sign != expected {
+		t.Error(fmt.Sprintf("Test failed. Expected signature %s. Actual %s", expected, sign))
+	}
+	if _, ok := req.Form()["secret_key"]; ok {
+		t.Error("Test failed. The secret key was sent with the request.")
+	}
+}
+
+func TestHuobiMarketDataURL(t *testing.T) {
+	requested := ""
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = r.URL.Path
+		fmt.Fprint(w, `{"ticker":{"last":2785.14}}`)
+	}))
+	defer server.Close()
+
+	h := &HUOBI{}
+	h.SetDefaults()
+	h.Setup(Exchanges{Name: "Huobi", Enabled: true, MarketDataURL: server.URL + "/huobi"})
+	if ticker := h.GetTicker("btc"); ticker.Last != 2785.14 || requested != "/huobi/ticker_btc_json.js" {
+		t.Error(fmt.Sprintf("Test failed. Expected the ticker from /huobi/ticker_btc_json.js. Actual %+v from %s", ticker, requested))
+	}
+}
@@ -162,6 +162,7 @@ func (h *HUOBI) OnConnect(output chan socketio.Message) {
 	if h.Verbose {
 		log.Printf("%s Connected to Websocket.", h.GetName())
 	}
+	GetWebsocketSupervisor(h.GetName()).Received()
 
 	for _, x := range h.EnabledPairs {
 		currency := StringToLower(x)
@@ -176,12 +177,10 @@ func (h *HUOBI) OnConnect(output chan socketio.Message) {
 
 func (h *HUOBI) OnDisconnect(output chan socketio.Message) {
 	log.Printf("%s Disconnected from websocket server.. Reconnecting.\n", h.GetName())
-	h.WebsocketClient()
 }
 
 func (h *HUOBI) OnError() {
 	log.Printf("%s Error with Websocket connection.. Reconnecting.\n", h.GetName())
-	h.WebsocketClient()
 }
 
 func (h *HUOBI) OnMessage(message []byte, output chan socketio.Message) {
@@ -208,12 +207,14 @@ func (h *HUOBI) WebsocketClient() {
 		OnDisconnect: h.OnDisconnect,
 	}
 
-	for h.Enabled && h.Websocket {
-		err := socketio.ConnectToSocket(HUOBI_SOCKETIO_ADDRESS, HuobiSocket)
-		if err != nil {
-			log.Printf("%s Unable to connect to Websocket. Err: %s\n", err)
-			continue
-		}
-		log.Printf("%s Disconnected from Websocket.")
-	}
+	// socketio handles its own heartbeats and returns once disconnected, so
+	// the supervisor only provides the backoff between attempts.
+	s := GetWebsocketSupervisor(h.GetName())
+	s.Verbose = h.Verbose
+	s.StaleTimeout = 0
+	s.Running = func() bool { return h.Enabled && h.Websocket }
+	s.Dial = func() error { return nil }
+	s.Serve = func() error { return socketio.ConnectToSocket(h.WebsocketURL, HuobiSocket) }
+	s.Close = func() error { return nil }
+	s.Run()
 }
@@ -0,0 +1,559 @@
+package main
+
+import (
+	"errors"
+	"math"
+)
+
+// Indicators are fed one value (or candle) at a time through Update, which
+// returns the latest value and whether the indicator has seen enough data to
+// be trusted. The *Values helpers run the same indicators over a whole series
+// and return results aligned to the input, using NaN for the warm-up period.
+
+var (
+	ErrIndicatorPeriodInvalid = errors.New("Indicator period must be greater than zero.")
+)
+
+func checkIndicatorPeriods(periods ...int) error {
+	for _, x := range periods {
+		if x < 1 {
+			return ErrIndicatorPeriodInvalid
+		}
+	}
+	return nil
+}
+
+type SMA struct {
+	period int
+	values []float64
+	next   int
+	count  int
+	sum    float64
+	value  float64
+}
+
+func NewSMA(period int) (*SMA, error) {
+	if err := checkIndicatorPeriods(period); err != nil {
+		return nil, err
+	}
+	return &SMA{period: period, values: make([]float64, period)}, nil
+}
+
+func (s *SMA) Update(v float64) (float64, bool) {
+	s.sum += v - s.values[s.next]
+	s.values[s.next] = v
+	s.next = (s.next + 1) % s.period
+	if s.count < s.period {
+		s.count++
+	}
+	s.value = s.sum / float64(s.count)
+	return s.value, s.Ready()
+}
+
+func (s *SMA) Value() float64 {
+	return s.value
+}
+
+func (s *SMA) Ready() bool {
+	return s.count == s.period
+}
+
+// EMA = Price(t) * k + EMA(y) * (1 – k)
+// k = 2/(N+1), seeded with the SMA of the first N values
+type EMA struct {
+	period int
+	k      float64
+	seed   *SMA
+	value  float64
+}
+
+func NewEMA(period int) (*EMA, error) {
+	seed, err := NewSMA(period)
+	if err != nil {
+		return nil, err
+	}
+	return &EMA{period: period, k: 2 / (float64(period) + 1), seed: seed}, nil
+}
+
+func (e *EMA) Update(v float64) (float64, bool) {
+	if !e.seed.Ready() {
+		e.value, _ = e.seed.Update(v)
+		return e.value, e.Ready()
+	}
+	e.value = v*e.k + e.value*(1-e.k)
+	return e.value, true
+}
+
+func (e *EMA) Value() float64 {
+	return e.value
+}
+
+func (e *EMA) Ready() bool {
+	return e.seed.Ready()
+}
+
+// WMA weights the most recent value by N, the one before by N-1 and so on.
+type WMA struct {
+	period int
+	values []float64
+	value  float64
+}
+
+func NewWMA(period int) (*WMA, error) {
+	if err := checkIndicatorPeriods(period); err != nil {
+		return nil, err
+	}
+	return &WMA{period: period}, nil
+}
+
+func (w *WMA) Update(v float64) (float64, bool) {
+	w.values = append(w.values, v)
+	if len(w.values) > w.period {
+		w.values = w.values[1:]
+	}
+
+	var sum, weights float64
+	for i, x := range w.values {
+		weight := float64(i + 1)
+		sum += x * weight
+		weights += weight
+	}
+	w.value = sum / weights
+	return w.value, w.Ready()
+}
+
+func (w *WMA) Value() float64 {
+	return w.value
+}
+
+func (w *WMA) Ready() bool {
+	return len(w.values) == w.period
+}
+
+type MACDValue struct {
+	Line      float64 // fast EMA - slow EMA
+	Signal    float64 // EMA of Line
+	Histogram float64 // Line - Signal
+}
+
+type MACD struct {
+	fast, slow *EMA
+	signal     *EMA
+	value      MACDValue
+}
+
+func NewMACD(fast, slow, signal int) (*MACD, error) {
+	if err := checkIndicatorPeriods(fast, slow, signal); err != nil {
+		return nil, err
+	}
+	// the periods are valid, so the EMAs are too
+	m := &MACD{}
+	m.fast, _ = NewEMA(fast)
+	m.slow, _ = NewEMA(slow)
+	m.signal, _ = NewEMA(signal)
+	return m, nil
+}
+
+func (m *MACD) Update(v float64) (MACDValue, bool) {
+	f, fastReady := m.fast.Update(v)
+	s, slowReady := m.slow.Update(v)
+	if !fastReady || !slowReady {
+		return m.value, false
+	}
+
+	m.value.Line = f - s
+	m.value.Signal, _ = m.signal.Update(m.value.Line)
+	m.value.Histogram = m.value.Line - m.value.Signal
+	return m.value, m.Ready()
+}
+
+func (m *MACD) Value() MACDValue {
+	return m.value
+}
+
+func (m *MACD) Ready() bool {
+	return m.signal.Ready()
+}
+
+// RSI uses Wilder's smoothing, so it needs period+1 values before the first
+// reading.
+type RSI struct {
+	period  int
+	last    float64
+	count   int
+	avgGain float64
+	avgLoss float64
+	value   float64
+}
+
+func NewRSI(period int) (*RSI, error) {
+	if err := checkIndicatorPeriods(period); err != nil {
+		return nil, err
+	}
+	return &RSI{period: period}, nil
+}
+
+func (r *RSI) Update(v float64) (float64, bool) {
+	r.count++
+	if r.count == 1 {
+		r.last = v
+		return r.value, false
+	}
+
+	change := v - r.last
+	r.last = v
+	gain, loss := math.Max(change, 0), math.Max(-change, 0)
+
+	n := float64(r.period)
+	if r.count <= r.period+1 {
+		r.avgGain += gain / n
+		r.avgLoss += loss / n
+		if r.count <= r.period {
+			return r.value, false
+		}
+	} else {
+		r.avgGain = (r.avgGain*(n-1) + gain) / n
+		r.avgLoss = (r.avgLoss*(n-1) + loss) / n
+	}
+
+	if r.avgLoss == 0 {
+		r.value = 100
+	} else {
+		r.value = 100 - 100/(1+r.avgGain/r.avgLoss)
+	}
+	return r.value, true
+}
+
+func (r *RSI) Value() float64 {
+	return r.value
+}
+
+func (r *RSI) Ready() bool {
+	return r.count > r.period
+}
+
+type BollingerValue struct {
+	Upper  float64
+	Middle float64
+	Lower  float64
+}
+
+// Bollinger bands are the SMA plus/minus K population standard deviations.
+type Bollinger struct {
+	k     float64
+	sma   *SMA
+	value BollingerValue
+}
+
+func NewBollinger(period int, k float64) (*Bollinger, error) {
+	sma, err := NewSMA(period)
+	if err != nil {
+		return nil, err
+	}
+	return &Bollinger{k: k, sma: sma}, nil
+}
+
+func (b *Bollinger) Update(v float64) (BollingerValue, bool) {
+	mean, ready := b.sma.Update(v)
+
+	var variance float64
+	for _, x := range b.sma.values[:b.sma.count] {
+		variance += (x - mean) * (x - mean)
+	}
+	deviation := math.Sqrt(variance / float64(b.sma.count))
+
+	b.value = BollingerValue{Upper: mean + b.k*deviation, Middle: mean, Lower: mean - b.k*deviation}
+	return b.value, ready
+}
+
+func (b *Bollinger) Value() BollingerValue {
+	return b.value
+}
+
+func (b *Bollinger) Ready() bool {
+	return b.sma.Ready()
+}
+
+// ATR is Wilder's average true range, seeded with the mean of the first
+// period true ranges.
+type ATR struct {
+	period    int
+	count     int
+	lastClose float64
+	value     float64
+}
+
+func NewATR(period int) (*ATR, error) {
+	if err := checkIndicatorPeriods(period); err != nil {
+		return nil, err
+	}
+	return &ATR{period: period}, nil
+}
+
+func (a *ATR) Update(c Candle) (float64, bool) {
+	tr := c.High - c.Low
+	if a.count > 0 {
+		tr = math.Max(tr, math.Max(math.Abs(c.High-a.lastClose), math.Abs(c.Low-a.lastClose)))
+	}
+	a.lastClose = c.Close
+	a.count++
+
+	n := float64(a.period)
+	if a.count <= a.period {
+		a.value += (tr - a.value) / float64(a.count)
+	} else {
+		a.value = (a.value*(n-1) + tr) / n
+	}
+	return a.value, a.Ready()
+}
+
+func (a *ATR) Value() float64 {
+	return a.value
+}
+
+func (a *ATR) Ready() bool {
+	return a.count >= a.period
+}
+
+type StochasticValue struct {
+	K float64
+	D float64
+}
+
+// Stochastic is the fast stochastic oscillator, %K over period candles and
+// %D the SMA of %K over smoothing values.
+type Stochastic struct {
+	period  int
+	candles []Candle
+	d       *SMA
+	value   StochasticValue
+}
+
+func NewStochastic(period, smoothing int) (*Stochastic, error) {
+	if err := checkIndicatorPeriods(period); err != nil {
+		return nil, err
+	}
+	d, err := NewSMA(smoothing)
+	if err != nil {
+		return nil, err
+	}
+	return &Stochastic{period: period, d: d}, nil
+}
+
+func (s *Stochastic) Update(c Candle) (StochasticValue, bool) {
+	s.candles = append(s.candles, c)
+	if len(s.candles) > s.period {
+		s.candles = s.candles[1:]
+	}
+	if len(s.candles) < s.period {
+		return s.value, false
+	}
+
+	high, low := s.candles[0].High, s.candles[0].Low
+	for _, x := range s.candles[1:] {
+		high = math.Max(high, x.High)
+		low = math.Min(low, x.Low)
+	}
+
+	s.value.K = 50 // flat range, no direction
+	if high != low {
+		s.value.K = 100 * (c.Close - low) / (high - low)
+	}
+	s.value.D, _ = s.d.Update(s.value.K)
+	return s.value, s.Ready()
+}
+
+func (s *Stochastic) Value() StochasticValue {
+	return s.value
+}
+
+func (s *Stochastic) Ready() bool {
+	return s.d.Ready()
+}
+
+// OBV is the running on-balance volume, starting from zero at the first
+// candle.
+type OBV struct {
+	count     int
+	lastClose float64
+	value     float64
+}
+
+func NewOBV() *OBV {
+	return &OBV{}
+}
+
+func (o *OBV) Update(c Candle) (float64, bool) {
+	if o.count > 0 {
+		if c.Close > o.lastClose {
+			o.value += c.Volume
+		} else if c.Close < o.lastClose {
+			o.value -= c.Volume
+		}
+	}
+	o.lastClose = c.Close
+	o.count++
+	return o.value, true
+}
+
+func (o *OBV) Value() float64 {
+	return o.value
+}
+
+func (o *OBV) Ready() bool {
+	return o.count > 0
+}
+
+// VWAP is the cumulative volume weighted typical price since creation or the
+// last Reset, usually done at the start of each session.
+type VWAP struct {
+	priceVolume float64
+	volume      float64
+	value       float64
+}
+
+func NewVWAP() *VWAP {
+	return &VWAP{}
+}
+
+func (v *VWAP) Update(c Candle) (float64, bool) {
+	typical := (c.High + c.Low + c.Close) / 3
+	v.priceVolume += typical * c.Volume
+	v.volume += c.Volume
+	if v.volume > 0 {
+		v.value = v.priceVolume / v.volume
+	}
+	return v.value, v.Ready()
+}
+
+func (v *VWAP) Reset() {
+	*v = VWAP{}
+}
+
+func (v *VWAP) Value() float64 {
+	return v.value
+}
+
+func (v *VWAP) Ready() bool {
+	return v.volume > 0
+}
+
+func indicatorValues(values []float64, update func(float64) (float64, bool)) []float64 {
+	result := make([]float64, len(values))
+	for i, x := range values {
+		v, ready := update(x)
+		if !ready {
+			v = math.NaN()
+		}
+		result[i] = v
+	}
+	return result
+}
+
+func indicatorCandleValues(candles []Candle, update func(Candle) (float64, bool)) []float64 {
+	result := make([]float64, len(candles))
+	for i, x := range candles {
+		v, ready := update(x)
+		if !ready {
+			v = math.NaN()
+		}
+		result[i] = v
+	}
+	return result
+}
+
+func SMAValues(values []float64, period int) ([]float64, error) {
+	s, err := NewSMA(period)
+	if err != nil {
+		return nil, err
+	}
+	return indicatorValues(values, s.Update), nil
+}
+
+func EMAValues(values []float64, period int) ([]float64, error) {
+	e, err := NewEMA(period)
+	if err != nil {
+		return nil, err
+	}
+	return indicatorValues(values, e.Update), nil
+}
+
+func WMAValues(values []float64, period int) ([]float64, error) {
+	w, err := NewWMA(period)
+	if err != nil {
+		return nil, err
+	}
+	return indicatorValues(values, w.Update), nil
+}
+
+func RSIValues(values []float64, period int) ([]float64, error) {
+	r, err := NewRSI(period)
+	if err != nil {
+		return nil, err
+	}
+	return indicatorValues(values, r.Update), nil
+}
+
+func MACDValues(values []float64, fast, slow, signal int) ([]MACDValue, error) {
+	m, err := NewMACD(fast, slow, signal)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]MACDValue, len(values))
+	for i, x := range values {
+		v, ready := m.Update(x)
+		if !ready {
+			v = MACDValue{math.NaN(), math.NaN(), math.NaN()}
+		}
+		result[i] = v
+	}
+	return result, nil
+}
+
+func BollingerValues(values []float64, period int, k float64) ([]BollingerValue, error) {
+	b, err := NewBollinger(period, k)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]BollingerValue, len(values))
+	for i, x := range values {
+		v, ready := b.Update(x)
+		if !ready {
+			v = BollingerValue{math.NaN(), math.NaN(), math.NaN()}
+		}
+		result[i] = v
+	}
+	return result, nil
+}
+
+func ATRValues(candles []Candle, period int) ([]float64, error) {
+	a, err := NewATR(period)
+	if err != nil {
+		return nil, err
+	}
+	return indicatorCandleValues(candles, a.Update), nil
+}
+
+func StochasticValues(candles []Candle, period, smoothing int) ([]StochasticValue, error) {
+	s, err := NewStochastic(period, smoothing)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]StochasticValue, len(candles))
+	for i, x := range candles {
+		v, ready := s.Update(x)
+		if !ready {
+			v = StochasticValue{math.NaN(), math.NaN()}
+		}
+		result[i] = v
+	}
+	return result, nil
+}
+
+func OBVValues(candles []Candle) []float64 {
+	return indicatorCandleValues(candles, NewOBV().Update)
+}
+
+func VWAPValues(candles []Candle) []float64 {
+	return indicatorCandleValues(candles, NewVWAP().Update)
+}
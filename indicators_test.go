@@ -0,0 +1,223 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+// StockCharts.com ChartSchool reference data for the 10 day SMA/EMA and the
+// 14 day RSI. Their RSI spreadsheet rounds the average gain/loss to two
+// places, so it is only compared to within 0.1.
+var (
+	indicatorTestMovingAverageCloses = []float64{
+		22.27, 22.19, 22.08, 22.17, 22.18, 22.13, 22.23, 22.43, 22.24, 22.29,
+		22.15, 22.39, 22.38, 22.61, 23.36, 24.05, 23.75, 23.83, 23.95, 23.63,
+		23.82, 23.87, 23.65, 23.19, 23.10, 23.33, 22.68, 23.10, 22.40, 22.17,
+	}
+	indicatorTestSMA10 = []float64{
+		22.22, 22.21, 22.23, 22.26, 22.30, 22.42, 22.61, 22.77, 22.91, 23.08,
+		23.21, 23.38, 23.53, 23.65, 23.71, 23.68, 23.61, 23.51, 23.43, 23.28,
+		23.13,
+	}
+	indicatorTestEMA10 = []float64{
+		22.22, 22.21, 22.24, 22.27, 22.33, 22.52, 22.80, 22.97, 23.13, 23.28,
+		23.34, 23.43, 23.51, 23.53, 23.47, 23.40, 23.39, 23.26, 23.23, 23.08,
+		22.92,
+	}
+	indicatorTestRSICloses = []float64{
+		44.34, 44.09, 44.15, 43.61, 44.33, 44.83, 45.10, 45.42, 45.84, 46.08,
+		45.89, 46.03, 45.61, 46.28, 46.28, 46.00, 46.03, 46.41, 46.22, 45.64,
+		46.21, 46.25, 45.71, 46.45, 45.78, 45.35, 44.03, 44.18, 44.22, 44.57,
+		43.42, 42.66, 43.13,
+	}
+	indicatorTestRSI14 = []float64{
+		70.53, 66.32, 66.55, 69.41, 66.36, 57.97, 62.93, 63.26, 56.06, 62.38,
+		54.71, 50.42, 39.99, 41.46, 41.87, 45.46, 37.30, 33.08, 37.77,
+	}
+)
+
+func indicatorTestCompare(t *testing.T, name string, expected, actual []float64, warmup int, tolerance float64) {
+	if len(actual) != len(expected)+warmup {
+		t.Fatal(fmt.Sprintf("Test failed. %s expected %d values. Actual %d", name, len(expected)+warmup, len(actual)))
+	}
+
+	for i := 0; i < warmup; i++ {
+		if !math.IsNaN(actual[i]) {
+			t.Error(fmt.Sprintf("Test failed. %s expected NaN during warm-up at %d. Actual %f", name, i, actual[i]))
+		}
+	}
+
+	for i, x := range expected {
+		if math.Abs(actual[i+warmup]-x) > tolerance {
+			t.Error(fmt.Sprintf("Test failed. %s expected %f at %d. Actual %f", name, x, i+warmup, actual[i+warmup]))
+		}
+	}
+}
+
+func TestSMA(t *testing.T) {
+	t.Parallel()
+	values, err := SMAValues(indicatorTestMovingAverageCloses, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	indicatorTestCompare(t, "SMA", indicatorTestSMA10, values, 9, 0.005)
+}
+
+func TestEMA(t *testing.T) {
+	t.Parallel()
+	values, err := EMAValues(indicatorTestMovingAverageCloses, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	indicatorTestCompare(t, "EMA", indicatorTestEMA10, values, 9, 0.005)
+}
+
+func TestWMA(t *testing.T) {
+	t.Parallel()
+	// (1*1 + 2*2 + 3*3) / 6, (2*1 + 3*2 + 4*3) / 6, (3*1 + 4*2 + 5*3) / 6
+	expected := []float64{2.333, 3.333, 4.333}
+	values, err := WMAValues([]float64{1, 2, 3, 4, 5}, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	indicatorTestCompare(t, "WMA", expected, values, 2, 0.0005)
+}
+
+func TestRSI(t *testing.T) {
+	t.Parallel()
+	values, err := RSIValues(indicatorTestRSICloses, 14)
+	if err != nil {
+		t.Fatal(err)
+	}
+	indicatorTestCompare(t, "RSI", indicatorTestRSI14, values, 14, 0.1)
+}
+
+func TestMACD(t *testing.T) {
+	t.Parallel()
+	values, err := MACDValues(indicatorTestMovingAverageCloses, 5, 10, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fast, _ := EMAValues(indicatorTestMovingAverageCloses, 5)
+	slow, _ := EMAValues(indicatorTestMovingAverageCloses, 10)
+
+	// the line needs the slow EMA (10), the signal 3 more lines
+	for i, x := range values {
+		if i < 11 {
+			if !math.IsNaN(x.Line) {
+				t.Error(fmt.Sprintf("Test failed. Expected NaN during warm-up at %d. Actual %f", i, x.Line))
+			}
+			continue
+		}
+		if x.Line != fast[i]-slow[i] {
+			t.Error(fmt.Sprintf("Test failed. Expected line %f at %d. Actual %f", fast[i]-slow[i], i, x.Line))
+		}
+		if x.Histogram != x.Line-x.Signal {
+			t.Error(fmt.Sprintf("Test failed. Expected histogram %f at %d. Actual %f", x.Line-x.Signal, i, x.Histogram))
+		}
+	}
+
+	signal := (fast[9] - slow[9] + fast[10] - slow[10] + fast[11] - slow[11]) / 3
+	if values[11].Signal != signal {
+		t.Error(fmt.Sprintf("Test failed. Expected signal %f. Actual %f", signal, values[11].Signal))
+	}
+}
+
+func TestMACDWarmup(t *testing.T) {
+	t.Parallel()
+	// a MACD line of exactly -1 used to be mistaken for not trained
+	m, err := NewMACD(1, 2, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.Update(3)
+	value, ready := m.Update(1)
+	if !ready || value.Line != -1 {
+		t.Error(fmt.Sprintf("Test failed. Expected ready line -1. Actual %v %f", ready, value.Line))
+	}
+}
+
+func TestBollinger(t *testing.T) {
+	t.Parallel()
+	values, err := BollingerValues([]float64{1, 2, 3, 4, 5}, 5, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	actual := values[4]
+	if actual.Middle != 3 || math.Abs(actual.Upper-5.8284) > 0.0001 || math.Abs(actual.Lower-0.1716) > 0.0001 {
+		t.Error(fmt.Sprintf("Test failed. Expected 5.8284/3/0.1716. Actual %+v", actual))
+	}
+	if !math.IsNaN(values[3].Middle) {
+		t.Error(fmt.Sprintf("Test failed. Expected NaN during warm-up. Actual %+v", values[3]))
+	}
+}
+
+func TestATR(t *testing.T) {
+	t.Parallel()
+	candles := []Candle{
+		{High: 10, Low: 8, Close: 9},
+		{High: 12, Low: 9, Close: 11},  // TR 3
+		{High: 11, Low: 10, Close: 10}, // TR 1
+		{High: 16, Low: 12, Close: 15}, // TR 6 (high - previous close)
+	}
+	values, err := ATRValues(candles, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	indicatorTestCompare(t, "ATR", []float64{2, (2*2 + 6) / 3.0}, values, 2, 0.0000001)
+}
+
+func TestStochastic(t *testing.T) {
+	t.Parallel()
+	candles := []Candle{
+		{High: 10, Low: 5, Close: 6},
+		{High: 12, Low: 6, Close: 11},
+		{High: 11, Low: 7, Close: 8},
+		{High: 9, Low: 8, Close: 9},
+	}
+	values, err := StochasticValues(candles, 3, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// %K = 100 * (8-5)/(12-5), 100 * (9-6)/(12-6)
+	if !math.IsNaN(values[2].D) || values[3].K != 50 || math.Abs(values[3].D-(300.0/7+50)/2) > 0.0000001 {
+		t.Error(fmt.Sprintf("Test failed. Unexpected stochastic values %+v", values))
+	}
+}
+
+func TestOBVAndVWAP(t *testing.T) {
+	t.Parallel()
+	candles := []Candle{
+		{High: 11, Low: 9, Close: 10, Volume: 100},
+		{High: 12, Low: 10, Close: 11, Volume: 200},
+		{High: 11, Low: 8, Close: 8, Volume: 50},
+	}
+
+	obv := OBVValues(candles)
+	if obv[0] != 0 || obv[1] != 200 || obv[2] != 150 {
+		t.Error(fmt.Sprintf("Test failed. Expected 0/200/150. Actual %v", obv))
+	}
+
+	vwap := VWAPValues(candles)
+	expected := (10*100 + 11*200 + 9*50) / 350.0
+	if vwap[2] != expected {
+		t.Error(fmt.Sprintf("Test failed. Expected %f. Actual %f", expected, vwap[2]))
+	}
+}
+
+func TestIndicatorPeriodInvalid(t *testing.T) {
+	t.Parallel()
+	if _, err := NewSMA(0); err != ErrIndicatorPeriodInvalid {
+		t.Error(fmt.Sprintf("Test failed. Expected %v. Actual %v", ErrIndicatorPeriodInvalid, err))
+	}
+	if _, err := NewMACD(12, 26, -1); err != ErrIndicatorPeriodInvalid {
+		t.Error(fmt.Sprintf("Test failed. Expected %v. Actual %v", ErrIndicatorPeriodInvalid, err))
+	}
+	if _, err := NewStochastic(14, 0); err != ErrIndicatorPeriodInvalid {
+		t.Error(fmt.Sprintf("Test failed. Expected %v. Actual %v", ErrIndicatorPeriodInvalid, err))
+	}
+	if _, err := BollingerValues([]float64{1, 2, 3}, 0, 2); err != ErrIndicatorPeriodInvalid {
+		t.Error(fmt.Sprintf("Test failed. Expected %v. Actual %v", ErrIndicatorPeriodInvalid, err))
+	}
+}
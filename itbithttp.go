@@ -12,6 +12,16 @@ import (
 const (
 	ITBIT_API_URL     = "https://api.itbit.com/v1"
 	ITBIT_API_VERSION = "1"
+
+	ITBIT_ORDER_BUY   = "buy"
+	ITBIT_ORDER_SELL  = "sell"
+	ITBIT_ORDER_LIMIT = "limit"
+
+	ITBIT_ORDER_STATUS_SUBMITTED = "submitted"
+	ITBIT_ORDER_STATUS_OPEN      = "open"
+	ITBIT_ORDER_STATUS_FILLED    = "filled"
+	ITBIT_ORDER_STATUS_CANCELLED = "cancelled"
+	ITBIT_ORDER_STATUS_REJECTED  = "rejected"
 )
 
 type ItBit struct {
@@ -21,6 +31,7 @@ type ItBit struct {
 	Websocket                    bool
 	RESTPollingDelay             time.Duration
 	AuthenticatedAPISupport      bool
+	APIUrl                       string
 	ClientKey, APISecret, UserID string
 	MakerFee, TakerFee           float64
 	BaseCurrencies               []string
@@ -56,6 +67,7 @@ func (i *ItBit) SetDefaults() {
 	i.Verbose = false
 	i.Websocket = false
 	i.RESTPollingDelay = 10
+	i.APIUrl = ITBIT_API_URL
 }
 
 func (i *ItBit) GetName() string {
@@ -83,6 +95,7 @@ func (i *ItBit) Setup(exch Exchanges) {
 		i.BaseCurrencies = SplitStrings(exch.BaseCurrencies, ",")
 		i.AvailablePairs = SplitStrings(exch.AvailablePairs, ",")
 		i.EnabledPairs = SplitStrings(exch.EnabledPairs, ",")
+		i.APIUrl = GetExchangeURLs(exch, ExchangeURLs{API: i.APIUrl}).API
 	}
 }
 
@@ -110,11 +123,22 @@ func (i *ItBit) Run() {
 		log.Printf("%s %d currencies enabled: %s.\n", i.GetName(), len(i.EnabledPairs), i.EnabledPairs)
 	}
 
+	if i.AuthenticatedAPISupport {
+		err := i.reconcileOrders()
+		if err != nil {
+			log.Printf("%s unable to reconcile open orders: %v", i.GetName(), err)
+		}
+	}
+
 	for i.Enabled {
 		for _, x := range i.EnabledPairs {
 			currency := x
 			go func() {
-				ticker := i.GetTicker(currency)
+				ticker, err := i.GetTicker(currency)
+				if err != nil {
+					log.Println(err)
+					return
+				}
 				log.Printf("ItBit %s: Last %f High %f Low %f Volume %f\n", currency, ticker.LastPrice, ticker.High24h, ticker.Low24h, ticker.Volume24h)
 				AddExchangeInfo(i.GetName(), currency[0:3], currency[3:], ticker.LastPrice, ticker.Volume24h)
 			}()
@@ -123,15 +147,14 @@ func (i *ItBit) Run() {
 	}
 }
 
-func (i *ItBit) GetTicker(currency string) ItBitTicker {
-	path := ITBIT_API_URL + "/markets/" + currency + "/ticker"
+func (i *ItBit) GetTicker(currency string) (ItBitTicker, error) {
+	path := i.APIUrl + "/markets/" + currency + "/ticker"
 	var itbitTicker ItBitTicker
-	err := SendHTTPGetRequest(path, true, &itbitTicker)
+	err := GetHTTPClient(i.Name).SendHTTPGetRequest(path, true, &itbitTicker)
 	if err != nil {
-		log.Println(err)
-		return ItBitTicker{}
+		return ItBitTicker{}, ClassifyExchangeError(i.Name, err)
 	}
-	return itbitTicker
+	return itbitTicker, nil
 }
 
 type ItbitOrderbookEntry struct {
@@ -149,85 +172,173 @@ type ItBitOrderbookResponse struct {
 
 func (i *ItBit) GetOrderbook(currency string) (ItBitOrderbookResponse, error) {
 	response := ItBitOrderbookResponse{}
-	path := ITBIT_API_URL + "/markets/" + currency + "/order_book"
-	err := SendHTTPGetRequest(path, true, &response)
+	path := i.APIUrl + "/markets/" + currency + "/order_book"
+	err := GetHTTPClient(i.Name).SendHTTPGetRequest(path, true, &response)
 	if err != nil {
-		return ItBitOrderbookResponse{}, err
+		return ItBitOrderbookResponse{}, ClassifyExchangeError(i.Name, err)
 	}
 	return response, nil
 }
 
-func (i *ItBit) GetTradeHistory(currency, timestamp string) bool {
+type ItBitTrade struct {
+	Timestamp   string  `json:"timestamp"`
+	MatchNumber string  `json:"matchNumber"`
+	Price       float64 `json:"price,string"`
+	Amount      float64 `json:"amount,string"`
+}
+
+type ItBitTradeHistory struct {
+	Count        int          `json:"count"`
+	RecentTrades []ItBitTrade `json:"recentTrades"`
+}
+
+// GetTradeHistory returns recent trades after the trade with match number
+// timestamp.
+func (i *ItBit) GetTradeHistory(currency, timestamp string) (ItBitTradeHistory, error) {
+	response := ItBitTradeHistory{}
 	req := "/trades?since=" + timestamp
-	err := SendHTTPGetRequest(ITBIT_API_URL+"markets/"+currency+req, true, nil)
+	err := GetHTTPClient(i.Name).SendHTTPGetRequest(i.APIUrl+"/markets/"+currency+req, true, &response)
 	if err != nil {
-		log.Println(err)
-		return false
+		return response, ClassifyExchangeError(i.Name, err)
 	}
-	return true
+	return response, nil
+}
+
+type ItBitWalletBalance struct {
+	Currency         string  `json:"currency"`
+	AvailableBalance float64 `json:"availableBalance,string"`
+	TotalBalance     float64 `json:"totalBalance,string"`
+}
+
+type ItBitWallet struct {
+	ID       string               `json:"id"`
+	UserID   string               `json:"userId"`
+	Name     string               `json:"name"`
+	Balances []ItBitWalletBalance `json:"balances"`
 }
 
-func (i *ItBit) GetWallets(params url.Values) {
+// ItBitOrder amounts are in Currency, the base currency of Instrument.
+type ItBitOrder struct {
+	ID                         string                 `json:"id"`
+	WalletID                   string                 `json:"walletId"`
+	Side                       string                 `json:"side"`
+	Instrument                 string                 `json:"instrument"`
+	Type                       string                 `json:"type"`
+	Currency                   string                 `json:"currency"`
+	Amount                     float64                `json:"amount,string"`
+	DisplayAmount              float64                `json:"displayAmount,string"`
+	Price                      float64                `json:"price,string"`
+	AmountFilled               float64                `json:"amountFilled,string"`
+	VolumeWeightedAveragePrice float64                `json:"volumeWeightedAveragePrice,string"`
+	CreatedTime                string                 `json:"createdTime"`
+	Status                     string                 `json:"status"`
+	Metadata                   map[string]interface{} `json:"metadata"`
+	ClientOrderIdentifier      string                 `json:"clientOrderIdentifier"`
+}
+
+type ItBitWalletTrade struct {
+	OrderID            string  `json:"orderId"`
+	ExecutionID        string  `json:"executionId"`
+	Timestamp          string  `json:"timestamp"`
+	Instrument         string  `json:"instrument"`
+	Direction          string  `json:"direction"`
+	Currency1          string  `json:"currency1"`
+	Currency1Amount    float64 `json:"currency1Amount,string"`
+	Currency2          string  `json:"currency2"`
+	Currency2Amount    float64 `json:"currency2Amount,string"`
+	Rate               float64 `json:"rate,string"`
+	CommissionPaid     float64 `json:"commissionPaid,string"`
+	CommissionCurrency string  `json:"commissionCurrency"`
+	RebatesApplied     float64 `json:"rebatesApplied,string"`
+	RebateCurrency     string  `json:"rebateCurrency"`
+}
+
+type ItBitWalletTrades struct {
+	TotalNumberOfRecords int                `json:"totalNumberOfRecords,string"`
+	CurrentPageNumber    int                `json:"currentPageNumber,string"`
+	LatestExecutionID    string             `json:"latestExecutionId"`
+	RecordsPerPage       int                `json:"recordsPerPage,string"`
+	TradingHistory       []ItBitWalletTrade `json:"tradingHistory"`
+}
+
+type ItBitWithdrawal struct {
+	WithdrawalID       int64   `json:"withdrawalId"`
+	Time               string  `json:"time"`
+	DestinationAddress string  `json:"destinationAddress"`
+	Currency           string  `json:"currency"`
+	Amount             float64 `json:"amount,string"`
+	NetworkFee         float64 `json:"networkFee,string"`
+}
+
+type ItBitDepositAddress struct {
+	ID             int64                  `json:"id"`
+	WalletID       string                 `json:"walletID"`
+	DepositAddress string                 `json:"depositAddress"`
+	Metadata       map[string]interface{} `json:"metadata"`
+}
+
+type ItBitWalletTransfer struct {
+	SourceWalletID      string  `json:"sourceWalletId"`
+	DestinationWalletID string  `json:"destinationWalletId"`
+	Amount              float64 `json:"amount,string"`
+	CurrencyCode        string  `json:"currencyCode"`
+}
+
+// GetWallets returns the user's wallets, paged by the page and perPage
+// params.
+func (i *ItBit) GetWallets(params url.Values) ([]ItBitWallet, error) {
 	params.Set("userId", i.UserID)
 	path := "/wallets?" + params.Encode()
 
-	err := i.SendAuthenticatedHTTPRequest("GET", path, nil)
-
-	if err != nil {
-		log.Println(err)
-	}
+	wallets := []ItBitWallet{}
+	err := i.SendAuthenticatedHTTPRequest("GET", path, nil, &wallets)
+	return wallets, err
 }
 
-func (i *ItBit) CreateWallet(walletName string) {
+func (i *ItBit) CreateWallet(walletName string) (ItBitWallet, error) {
 	path := "/wallets"
 	params := make(map[string]interface{})
 	params["userId"] = i.UserID
 	params["name"] = walletName
 
-	err := i.SendAuthenticatedHTTPRequest("POST", path, params)
-
-	if err != nil {
-		log.Println(err)
-	}
+	wallet := ItBitWallet{}
+	err := i.SendAuthenticatedHTTPRequest("POST", path, params, &wallet)
+	return wallet, err
 }
 
-func (i *ItBit) GetWallet(walletID string) {
+func (i *ItBit) GetWallet(walletID string) (ItBitWallet, error) {
 	path := "/wallets/" + walletID
-	err := i.SendAuthenticatedHTTPRequest("GET", path, nil)
-
-	if err != nil {
-		log.Println(err)
-	}
+	wallet := ItBitWallet{}
+	err := i.SendAuthenticatedHTTPRequest("GET", path, nil, &wallet)
+	return wallet, err
 }
 
-func (i *ItBit) GetWalletBalance(walletID, currency string) {
-	path := "/wallets/ " + walletID + "/balances/" + currency
-	err := i.SendAuthenticatedHTTPRequest("GET", path, nil)
-
-	if err != nil {
-		log.Println(err)
-	}
+func (i *ItBit) GetWalletBalance(walletID, currency string) (ItBitWalletBalance, error) {
+	path := "/wallets/" + walletID + "/balances/" + currency
+	balance := ItBitWalletBalance{}
+	err := i.SendAuthenticatedHTTPRequest("GET", path, nil, &balance)
+	return balance, err
 }
 
-func (i *ItBit) GetWalletTrades(walletID string, params url.Values) {
+// GetWalletTrades returns the wallet's executions, filtered by the
+// rangeStart and rangeEnd params and paged by page and perPage.
+func (i *ItBit) GetWalletTrades(walletID string, params url.Values) (ItBitWalletTrades, error) {
 	path := EncodeURLValues("/wallets/"+walletID+"/trades", params)
-	err := i.SendAuthenticatedHTTPRequest("GET", path, nil)
-
-	if err != nil {
-		log.Println(err)
-	}
+	trades := ItBitWalletTrades{}
+	err := i.SendAuthenticatedHTTPRequest("GET", path, nil, &trades)
+	return trades, err
 }
 
-func (i *ItBit) GetWalletOrders(walletID string, params url.Values) {
+// GetWalletOrders returns the wallet's orders, filtered by the instrument and
+// status params and paged by page and perPage.
+func (i *ItBit) GetWalletOrders(walletID string, params url.Values) ([]ItBitOrder, error) {
 	path := EncodeURLValues("/wallets/"+walletID+"/orders", params)
-	err := i.SendAuthenticatedHTTPRequest("GET", path, nil)
-
-	if err != nil {
-		log.Println(err)
-	}
+	orders := []ItBitOrder{}
+	err := i.SendAuthenticatedHTTPRequest("GET", path, nil, &orders)
+	return orders, err
 }
 
-func (i *ItBit) PlaceWalletOrder(walletID, side, orderType, currency string, amount, price float64, instrument string, clientRef string) {
+func (i *ItBit) PlaceWalletOrder(walletID, side, orderType, currency string, amount, price float64, instrument string, clientRef string) (ItBitOrder, error) {
 	path := "/wallets/" + walletID + "/orders"
 	params := make(map[string]interface{})
 	params["side"] = side
@@ -241,58 +352,48 @@ func (i *ItBit) PlaceWalletOrder(walletID, side, orderType, currency string, amo
 		params["clientOrderIdentifier"] = clientRef
 	}
 
-	err := i.SendAuthenticatedHTTPRequest("POST", path, params)
-
-	if err != nil {
-		log.Println(err)
-	}
+	order := ItBitOrder{}
+	err := i.SendAuthenticatedHTTPRequest("POST", path, params, &order)
+	return order, err
 }
 
-func (i *ItBit) GetWalletOrder(walletID, orderID string) {
+func (i *ItBit) GetWalletOrder(walletID, orderID string) (ItBitOrder, error) {
 	path := "/wallets/" + walletID + "/orders/" + orderID
-	err := i.SendAuthenticatedHTTPRequest("GET", path, nil)
-
-	if err != nil {
-		log.Println(err)
-	}
+	order := ItBitOrder{}
+	err := i.SendAuthenticatedHTTPRequest("GET", path, nil, &order)
+	return order, err
 }
 
-func (i *ItBit) CancelWalletOrder(walletID, orderID string) {
+// CancelWalletOrder requests cancellation, which ItBit accepts before the
+// order is actually cancelled.
+func (i *ItBit) CancelWalletOrder(walletID, orderID string) error {
 	path := "/wallets/" + walletID + "/orders/" + orderID
-	err := i.SendAuthenticatedHTTPRequest("DELETE", path, nil)
-
-	if err != nil {
-		log.Println(err)
-	}
+	return i.SendAuthenticatedHTTPRequest("DELETE", path, nil, nil)
 }
 
-func (i *ItBit) PlaceWithdrawalRequest(walletID, currency, address string, amount float64) {
+func (i *ItBit) PlaceWithdrawalRequest(walletID, currency, address string, amount float64) (ItBitWithdrawal, error) {
 	path := "/wallets/" + walletID + "/cryptocurrency_withdrawals"
 	params := make(map[string]interface{})
 	params["currency"] = currency
-	params["amount"] = amount
+	params["amount"] = strconv.FormatFloat(amount, 'f', -1, 64)
 	params["address"] = address
 
-	err := i.SendAuthenticatedHTTPRequest("POST", path, params)
-
-	if err != nil {
-		log.Println(err)
-	}
+	withdrawal := ItBitWithdrawal{}
+	err := i.SendAuthenticatedHTTPRequest("POST", path, params, &withdrawal)
+	return withdrawal, err
 }
 
-func (i *ItBit) GetDepositAddress(walletID, currency string) {
+func (i *ItBit) GetDepositAddress(walletID, currency string) (ItBitDepositAddress, error) {
 	path := "/wallets/" + walletID + "/cryptocurrency_deposits"
 	params := make(map[string]interface{})
 	params["currency"] = currency
 
-	err := i.SendAuthenticatedHTTPRequest("POST", path, params)
-
-	if err != nil {
-		log.Println(err)
-	}
+	address := ItBitDepositAddress{}
+	err := i.SendAuthenticatedHTTPRequest("POST", path, params, &address)
+	return address, err
 }
 
-func (i *ItBit) WalletTransfer(walletID, sourceWallet, destWallet string, amount float64, currency string) {
+func (i *ItBit) WalletTransfer(walletID, sourceWallet, destWallet string, amount float64, currency string) (ItBitWalletTransfer, error) {
 	path := "/wallets/" + walletID + "/wallet_transfers"
 	params := make(map[string]interface{})
 	params["sourceWalletId"] = sourceWallet
@@ -300,24 +401,192 @@ func (i *ItBit) WalletTransfer(walletID, sourceWallet, destWallet string, amount
 	params["amount"] = strconv.FormatFloat(amount, 'f', -1, 64)
 	params["currencyCode"] = currency
 
-	err := i.SendAuthenticatedHTTPRequest("POST", path, params)
+	transfer := ItBitWalletTransfer{}
+	err := i.SendAuthenticatedHTTPRequest("POST", path, params, &transfer)
+	return transfer, err
+}
 
+// reconcileOrders reconciles the OMS against the user's first wallet, which
+// is the one orders are placed from.
+func (i *ItBit) reconcileOrders() error {
+	wallets, err := i.GetWallets(url.Values{})
 	if err != nil {
-		log.Println(err)
+		return err
 	}
+	if len(wallets) == 0 {
+		return errors.New("no wallets found")
+	}
+	return ReconcileOrders(&ItBitExecutionVenue{i: i, WalletID: wallets[0].ID})
 }
 
-func (i *ItBit) SendAuthenticatedHTTPRequest(method string, path string, params map[string]interface{}) (err error) {
-	timestamp := strconv.FormatInt(time.Now().UnixNano(), 10)[0:13]
-	nonce, err := strconv.Atoi(timestamp)
+// ItBitExecutionVenue places orders for the execution engine and reconciles
+// the OMS against a single wallet, as ItBit scopes orders and balances to
+// wallets. Pairs are ItBit instruments, e.g. XBTUSD.
+type ItBitExecutionVenue struct {
+	i        *ItBit
+	WalletID string
+}
+
+func (v *ItBitExecutionVenue) GetName() string {
+	return v.i.GetName()
+}
 
+func (v *ItBitExecutionVenue) BestPrices(pair string) (float64, float64, error) {
+	ticker, err := v.i.GetTicker(pair)
 	if err != nil {
-		return err
+		return 0, 0, err
+	}
+	return ticker.Bid, ticker.Ask, nil
+}
+
+func (v *ItBitExecutionVenue) PlaceLimitOrder(pair string, price, amount float64, buy, postOnly bool) (string, []OrderFill, error) {
+	if postOnly {
+		return "", nil, errors.New("post only orders are not supported")
 	}
 
-	nonce -= 1
+	side := ITBIT_ORDER_SELL
+	if buy {
+		side = ITBIT_ORDER_BUY
+	}
+	order, err := v.i.PlaceWalletOrder(v.WalletID, side, ITBIT_ORDER_LIMIT, pair[0:3], amount, price, pair, "")
+	if err != nil {
+		return "", nil, err
+	}
+	if order.ID == "" {
+		return "", nil, errors.New("no order ID returned")
+	}
+	if order.Status == ITBIT_ORDER_STATUS_REJECTED {
+		return "", nil, NewExchangeError(v.i.Name, "", "order rejected")
+	}
+	return order.ID, nil, nil
+}
+
+func (v *ItBitExecutionVenue) CancelOrder(orderID string) error {
+	return v.i.CancelWalletOrder(v.WalletID, orderID)
+}
+
+// OrderFills returns the order's executions from the wallet's trade history,
+// with fees net of rebates. Only trades since the order was created are
+// fetched, and paging stops once they add up to what the order has filled.
+func (v *ItBitExecutionVenue) OrderFills(orderID string) ([]OrderFill, error) {
+	order, err := v.i.GetWalletOrder(v.WalletID, orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	fills := []OrderFill{}
+	filled := 0.0
+	executions := make(map[string]int)
+	for page := 1; filled < order.AmountFilled; page++ {
+		params := url.Values{"page": {strconv.Itoa(page)}, "perPage": {"50"}, "rangeStart": {order.CreatedTime}}
+		trades, err := v.i.GetWalletTrades(v.WalletID, params)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, t := range trades.TradingHistory {
+			if t.OrderID != orderID {
+				continue
+			}
+			created, _ := time.Parse(time.RFC3339Nano, t.Timestamp)
+			tradeID := t.ExecutionID
+			if tradeID == "" {
+				// executions sharing a timestamp are told apart by their position
+				key := t.OrderID + "@" + t.Timestamp
+				tradeID = key
+				if n := executions[key]; n > 0 {
+					tradeID = key + "#" + strconv.Itoa(n)
+				}
+				executions[key]++
+			}
+			fills = append(fills, OrderFill{
+				TradeID: tradeID,
+				Price:   t.Rate,
+				Amount:  t.Currency1Amount,
+				Fee:     t.CommissionPaid - t.RebatesApplied,
+				Time:    created,
+			})
+			filled += t.Currency1Amount
+		}
+
+		if len(trades.TradingHistory) == 0 || page*trades.RecordsPerPage >= trades.TotalNumberOfRecords {
+			break
+		}
+	}
+	return fills, nil
+}
+
+// OpenOrders lets the OMS reconcile against the wallet's open orders.
+func (v *ItBitExecutionVenue) OpenOrders() ([]Order, error) {
+	open, err := v.i.GetWalletOrders(v.WalletID, url.Values{"status": {ITBIT_ORDER_STATUS_OPEN}})
+	if err != nil {
+		return nil, err
+	}
+
+	orders := []Order{}
+	for _, x := range open {
+		created, _ := time.Parse(time.RFC3339Nano, x.CreatedTime)
+		orderType := LIMIT_ORDER
+		if x.Type != ITBIT_ORDER_LIMIT {
+			orderType = MARKET_ORDER
+		}
+		orders = append(orders, Order{
+			ExchangeOrderID: x.ID,
+			Pair:            x.Instrument,
+			Buy:             x.Side == ITBIT_ORDER_BUY,
+			Type:            orderType,
+			Amount:          x.Amount,
+			Price:           x.Price,
+			Filled:          x.AmountFilled,
+			AveragePrice:    x.VolumeWeightedAveragePrice,
+			Status:          ItBitOrderStatus(x),
+			Created:         created,
+		})
+	}
+	return orders, nil
+}
+
+// Balances returns the wallet's available balance of each currency.
+func (v *ItBitExecutionVenue) Balances() (map[string]float64, error) {
+	wallet, err := v.i.GetWallet(v.WalletID)
+	if err != nil {
+		return nil, err
+	}
+
+	balances := make(map[string]float64)
+	for _, x := range wallet.Balances {
+		balances[x.Currency] = x.AvailableBalance
+	}
+	return balances, nil
+}
+
+// ItBitOrderStatus maps an ItBit order's status onto the OMS lifecycle.
+func ItBitOrderStatus(order ItBitOrder) string {
+	switch order.Status {
+	case ITBIT_ORDER_STATUS_SUBMITTED:
+		return ORDER_STATUS_NEW
+	case ITBIT_ORDER_STATUS_OPEN:
+		if order.AmountFilled > 0 {
+			return ORDER_STATUS_PARTIALLY_FILLED
+		}
+		return ORDER_STATUS_ACKNOWLEDGED
+	case ITBIT_ORDER_STATUS_FILLED:
+		return ORDER_STATUS_FILLED
+	case ITBIT_ORDER_STATUS_CANCELLED:
+		return ORDER_STATUS_CANCELLED
+	case ITBIT_ORDER_STATUS_REJECTED:
+		return ORDER_STATUS_REJECTED
+	}
+	return ""
+}
+
+// SendAuthenticatedHTTPRequest signs and sends a request, decoding the
+// response into result if it isn't nil.
+func (i *ItBit) SendAuthenticatedHTTPRequest(method string, path string, params map[string]interface{}, result interface{}) (err error) {
+	timestamp := strconv.FormatInt(time.Now().UnixNano(), 10)[0:13]
+	nonce := Nonces.Next(NonceKey(i.Name, i.ClientKey), time.Millisecond)
 	request := make(map[string]interface{})
-	url := ITBIT_API_URL + path
+	url := i.APIUrl + path
 
 	if params != nil {
 		for key, value := range params {
@@ -339,7 +608,7 @@ func (i *ItBit) SendAuthenticatedHTTPRequest(method string, path string, params
 		}
 	}
 
-	nonceStr := strconv.Itoa(nonce)
+	nonceStr := strconv.FormatInt(nonce, 10)
 	message, err := JSONEncode([]string{method, url, string(PayloadJson), nonceStr, timestamp})
 	if err != nil {
 		log.Println(err)
@@ -356,10 +625,25 @@ func (i *ItBit) SendAuthenticatedHTTPRequest(method string, path string, params
 	headers["X-Auth-Nonce"] = nonceStr
 	headers["Content-Type"] = "application/json"
 
-	resp, err := SendHTTPRequest(method, url, headers, bytes.NewBuffer([]byte(PayloadJson)))
+	resp, err := GetHTTPClient(i.Name).SendAuthenticatedHTTPRequest(method, url, headers, bytes.NewBuffer([]byte(PayloadJson)))
 
 	if i.Verbose {
 		log.Printf("Recieved raw: \n%s\n", resp)
 	}
+
+	if err != nil {
+		return ClassifyExchangeError(i.Name, err)
+	}
+
+	if result == nil || resp == "" {
+		return nil
+	}
+
+	err = JSONDecode([]byte(resp), result)
+
+	if err != nil {
+		return errors.New("Unable to JSON Unmarshal response.")
+	}
+
 	return nil
 }
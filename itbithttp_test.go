@@ -0,0 +1,142 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"testing"
+)
+
+func itbitFixtures(t *testing.T) (*ItBit, *fixtureServer) {
+	i := &ItBit{}
+	i.SetDefaults()
+	i.SetAPIKeys("itbit-key", "itbit-secret", "itbit-user")
+	return i, newFixtureServer(t, i.Name, "itbit.json")
+}
+
+func TestItBitPublicFixtures(t *testing.T) {
+	i, f := itbitFixtures(t)
+	defer f.Close()
+
+	runFixtureCases(t, f, []fixtureCase{
+		{Name: "GetTicker", Call: func() (interface{}, error) { return i.GetTicker("XBTUSD") },
+			Want: []string{"{Pair:XBTUSD Bid:622 BidAmt:0.0006 Ask:641.29 AskAmt:0.5 LastPrice:618 LastAmt:0.0004", "ServertimeUTC:2014-06-24T20:42:35.6160000Z}"}},
+		{Name: "GetOrderbook", Call: func() (interface{}, error) { return i.GetOrderbook("XBTUSD") },
+			Want: []string{"Ticker:XBTUSD Bids:[{Quantitiy:1.5 Price:610}] Asks:[{Quantitiy:0.5 Price:641.29}]"}},
+		{Name: "GetTradeHistory", Call: func() (interface{}, error) { return i.GetTradeHistory("XBTUSD", "5CR1JEUBBM8J") },
+			Params: map[string]string{"since": "5CR1JEUBBM8J"},
+			Want:   []string{"{Count:1 RecentTrades:[{Timestamp:2015-05-22T17:45:34.7570000Z MatchNumber:5CR1JEUBBM8J Price:351.45 Amount:0.0001}]}"}},
+	})
+}
+
+func TestItBitAuthenticatedFixtures(t *testing.T) {
+	i, f := itbitFixtures(t)
+	defer f.Close()
+
+	wallet := "fae1ce9a-848d-479b-b059-e93cb026cdf9"
+	order := "13d6af57-8b0b-41e5-af30-becf0bcc574d"
+	runFixtureCases(t, f, []fixtureCase{
+		{Name: "GetWallets", Call: func() (interface{}, error) { return i.GetWallets(url.Values{"page": {"1"}}) },
+			Params: map[string]string{"userId": "itbit-user", "page": "1"},
+			Want:   []string{"{ID:" + wallet + " UserID:itbit-user Name:primary Balances:[{Currency:USD AvailableBalance:50000 TotalBalance:50000}]}"}},
+		{Name: "CreateWallet", Call: func() (interface{}, error) { return i.CreateWallet("trading") },
+			Params: map[string]string{"userId": "itbit-user", "name": "trading"},
+			Want:   []string{"Name:trading"}},
+		{Name: "GetWalletBalance", Call: func() (interface{}, error) { return i.GetWalletBalance(wallet, "XBT") },
+			Want: []string{"{Currency:XBT AvailableBalance:1.5 TotalBalance:2}"}},
+		{Name: "PlaceWalletOrder", Call: func() (interface{}, error) {
+			return i.PlaceWalletOrder(wallet, "buy", "limit", "XBT", 2.5, 650, "XBTUSD", "optional")
+		},
+			Params: map[string]string{"side": "buy", "type": "limit", "currency": "XBT", "amount": "2.5", "price": "650", "instrument": "XBTUSD", "clientOrderIdentifier": "optional"},
+			Want:   []string{"{ID:" + order + " WalletID:" + wallet + " Side:buy Instrument:XBTUSD Type:limit Currency:XBT Amount:2.5", "Status:submitted"}},
+		{Name: "PlaceWalletOrder insufficient", Call: func() (interface{}, error) {
+			return i.PlaceWalletOrder(wallet, "buy", "limit", "XBT", 1000, 650, "XBTUSD", "")
+		},
+			Err: ERROR_INSUFFICIENT_FUNDS},
+		{Name: "GetWalletOrders", Call: func() (interface{}, error) {
+			return i.GetWalletOrders(wallet, url.Values{"instrument": {"XBTUSD"}, "status": {"open"}})
+		},
+			Params: map[string]string{"instrument": "XBTUSD", "status": "open"},
+			Want:   []string{"AmountFilled:1 VolumeWeightedAveragePrice:649.5", "Status:open"}},
+		{Name: "GetWalletOrder", Call: func() (interface{}, error) { return i.GetWalletOrder(wallet, order) },
+			Want: []string{"Status:filled"}},
+		{Name: "GetWalletTrades", Call: func() (interface{}, error) { return i.GetWalletTrades(wallet, nil) },
+			Want: []string{"TotalNumberOfRecords:3 CurrentPageNumber:1 LatestExecutionID:332 RecordsPerPage:2", "Rate:649.5 CommissionPaid:1.62375"}},
+		{Name: "CancelWalletOrder", Call: func() (interface{}, error) {
+			err := i.CancelWalletOrder(wallet, order)
+			return f.Last().Method + " " + f.Last().Path, err
+		},
+			Want: []string{"DELETE /v1/wallets/" + wallet + "/orders/" + order}},
+		{Name: "CancelWalletOrder unknown", Call: func() (interface{}, error) { return nil, i.CancelWalletOrder(wallet, "unknown") },
+			Err: ERROR_ORDER_NOT_FOUND},
+		{Name: "PlaceWithdrawalRequest", Call: func() (interface{}, error) {
+			return i.PlaceWithdrawalRequest(wallet, "XBT", "mfsANnvGuvNRqnHn5uJiRbY2MRRLFqB9cr", 0.5)
+		},
+			Params: map[string]string{"currency": "XBT", "amount": "0.5", "address": "mfsANnvGuvNRqnHn5uJiRbY2MRRLFqB9cr"},
+			Want:   []string{"{WithdrawalID:12345", "Amount:0.5 NetworkFee:0.0001}"}},
+		{Name: "GetDepositAddress", Call: func() (interface{}, error) { return i.GetDepositAddress(wallet, "XBT") },
+			Want: []string{"DepositAddress:msrFTKjRyjhC3NhLKEDdQeFxdsoDcjRHjn"}},
+		{Name: "WalletTransfer", Call: func() (interface{}, error) {
+			return i.WalletTransfer(wallet, wallet, "2a6d2d5b-0b1f-4a29-8b64-4a3c4a6fb7b4", 100, "USD")
+		},
+			Params: map[string]string{"sourceWalletId": wallet, "amount": "100", "currencyCode": "USD"},
+			Want:   []string{"Amount:100 CurrencyCode:USD"}},
+	})
+}
+
+func TestItBitExecutionVenue(t *testing.T) {
+	i, f := itbitFixtures(t)
+	defer f.Close()
+
+	v := &ItBitExecutionVenue{i: i, WalletID: "fae1ce9a-848d-479b-b059-e93cb026cdf9"}
+	order := "13d6af57-8b0b-41e5-af30-becf0bcc574d"
+	unnamed := "5b6b3e8f-0c8e-4f4a-9b1e-2f3d7a4c9e10"
+	runFixtureCases(t, f, []fixtureCase{
+		{Name: "BestPrices", Call: func() (interface{}, error) {
+			bid, ask, err := v.BestPrices("XBTUSD")
+			return []float64{bid, ask}, err
+		},
+			Want: []string{"[622 641.29]"}},
+		{Name: "PlaceLimitOrder", Call: func() (interface{}, error) {
+			id, _, err := v.PlaceLimitOrder("XBTUSD", 650, 2.5, true, false)
+			return id, err
+		},
+			Params: map[string]string{"side": "buy", "type": "limit", "currency": "XBT", "instrument": "XBTUSD"},
+			Want:   []string{order}},
+		{Name: "OpenOrders", Call: func() (interface{}, error) { return v.OpenOrders() },
+			Params: map[string]string{"status": "open"},
+			Want:   []string{"ExchangeOrderID:" + order, "Pair:XBTUSD Buy:true", "Amount:2.5 Price:650 Status:PARTIALLY_FILLED Filled:1 AveragePrice:649.5"}},
+		{Name: "OrderFills", Call: func() (interface{}, error) { return v.OrderFills(order) },
+			Params: map[string]string{"page": "2", "rangeStart": "2014-02-11T17:05:15Z"},
+			Want:   []string{"{TradeID:330 Price:649.5 Amount:1 Fee:1.62375", "{TradeID:332 Price:650 Amount:1.5 Fee:-0.4875"}},
+		{Name: "OrderFills without execution IDs", Call: func() (interface{}, error) { return v.OrderFills(unnamed) },
+			Params: map[string]string{"page": "1", "rangeStart": "2014-02-12T09:00:00Z"},
+			Want:   []string{"{TradeID:" + unnamed + "@2014-02-12T09:01:00.0000000Z Price:655 Amount:0.25", "{TradeID:" + unnamed + "@2014-02-12T09:01:00.0000000Z#1 Price:655 Amount:0.25"}},
+		{Name: "Balances", Call: func() (interface{}, error) { return v.Balances() },
+			Want: []string{"map[USD:48375 XBT:1.5]"}},
+	})
+
+	if _, _, err := v.PlaceLimitOrder("XBTUSD", 650, 2.5, true, true); err == nil {
+		t.Error("Test failed. Expected post only orders to be refused.")
+	}
+}
+
+func TestItBitSigning(t *testing.T) {
+	i, f := itbitFixtures(t)
+	defer f.Close()
+
+	if _, err := i.PlaceWalletOrder("fae1ce9a-848d-479b-b059-e93cb026cdf9", "buy", "limit", "XBT", 2.5, 650, "XBTUSD", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	req := f.Last()
+	u := ITBIT_API_URL + "/wallets/fae1ce9a-848d-479b-b059-e93cb026cdf9/orders"
+	nonce, timestamp := req.Header.Get("X-Auth-Nonce"), req.Header.Get("X-Auth-Timestamp")
+	message, _ := JSONEncode([]string{"POST", u, req.Body, nonce, timestamp})
+	hmac := GetHMAC(HASH_SHA512, []byte(u+string(GetSHA256([]byte(nonce+string(message))))), []byte("itbit-secret"))
+	if auth := "itbit-key:" + Base64Encode(hmac); req.Header.Get("Authorization") != auth {
+		t.Error(fmt.Sprintf("Test failed. Expected authorization %s. Actual %s", auth, req.Header.Get("Authorization")))
+	}
+	if nonce == "" || len(timestamp) != 13 || req.Header.Get("Content-Type") != "application/json" {
+		t.Error(fmt.Sprintf("Test failed. Unexpected headers %v", req.Header))
+	}
+}
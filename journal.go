@@ -0,0 +1,540 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The trade journal keeps every fill we make or pull from an exchange's
+// trade history, valued in fiat when it's recorded. It exports to CSV and
+// works out the tax lots each disposal closed, FIFO or LIFO.
+
+const (
+	JOURNAL_FILE          = "journal.json"
+	JOURNAL_DEFAULT_FIAT  = "USD"
+	JOURNAL_SOURCE_BOT    = "bot"
+	JOURNAL_SOURCE_IMPORT = "import"
+	JOURNAL_TAX_LOT_FIFO  = "FIFO"
+	JOURNAL_TAX_LOT_LIFO  = "LIFO"
+
+	// cached exchange rates are only used for trades this close to when they
+	// were fetched
+	JOURNAL_MAX_FIAT_RATE_AGE = 24 * time.Hour
+)
+
+var (
+	ErrJournalNoFiatRate    = errors.New("Unable to value trade in fiat.")
+	ErrJournalStaleFiatRate = errors.New("No exchange rate for the time of the trade, unable to value it in fiat.")
+	ErrJournalUnknownMethod = errors.New("Unknown tax lot method.")
+	ErrJournalInvalidEntry  = errors.New("Journal entry needs an exchange, currencies, price and amount.")
+)
+
+type JournalEntry struct {
+	Exchange     string
+	TradeID      string
+	OrderID      string
+	Pair         string // as the exchange names it
+	Base         string // the currency bought or sold
+	Quote        string // the currency it's priced in
+	Buy          bool
+	Price        float64
+	Amount       float64
+	Fee          float64
+	FeeCurrency  string
+	Time         time.Time
+	Source       string
+	FiatCurrency string
+	FiatPrice    float64 // one unit of Quote in FiatCurrency, 0 if unknown
+}
+
+func (e JournalEntry) Total() float64 {
+	return e.Price * e.Amount
+}
+
+// FiatTotal is the trade's value in FiatCurrency.
+func (e JournalEntry) FiatTotal() float64 {
+	return e.Total() * e.FiatPrice
+}
+
+// FiatFee is the fee's value in FiatCurrency.
+func (e JournalEntry) FiatFee() float64 {
+	switch e.FeeCurrency {
+	case e.Quote:
+		return e.Fee * e.FiatPrice
+	case e.Base:
+		return e.Fee * e.Price * e.FiatPrice
+	}
+	return 0
+}
+
+func (e JournalEntry) Side() string {
+	if e.Buy {
+		return "buy"
+	}
+	return "sell"
+}
+
+// JournalPriceFunc returns the price of one unit of currency at when, and the
+// fiat currency it's priced in.
+type JournalPriceFunc func(currency string, when time.Time) (float64, string, error)
+
+type Journal struct {
+	Fiat  string
+	Price JournalPriceFunc // used for crypto quoted trades, optional
+
+	mtx     sync.Mutex
+	entries []JournalEntry
+	trades  map[string]struct{}
+	path    string
+}
+
+// TradeJournal records every fill the OMS books.
+var TradeJournal = NewJournal(JOURNAL_DEFAULT_FIAT)
+
+func NewJournal(fiat string) *Journal {
+	return &Journal{
+		Fiat:   StringToUpper(fiat),
+		trades: make(map[string]struct{}),
+	}
+}
+
+func journalKey(exchange, tradeID string) string {
+	return exchange + " " + tradeID
+}
+
+// journalSplitPair returns the base and quote currencies of an exchange's
+// pair. Poloniex puts the quote currency first.
+func journalSplitPair(exchange, pair string) (string, string) {
+	pair = StringToUpper(pair)
+	for _, x := range []string{"_", "-", "/"} {
+		if StringContains(pair, x) {
+			currencies := SplitStrings(pair, x)
+			if exchange == "Poloniex" {
+				return currencies[1], currencies[0]
+			}
+			return currencies[0], currencies[1]
+		}
+	}
+	if len(pair) == 6 {
+		return pair[0:3], pair[3:]
+	}
+	return pair, ""
+}
+
+func journalIsFiat(currency string) bool {
+	return IsDefaultCurrency(currency) || (BaseCurrencies != "" && IsFiatCurrency(currency))
+}
+
+// fiatPrice values one unit of currency in the journal's fiat currency.
+// Crypto currencies are priced by Price, falling back to the journal's own
+// fiat quoted trades. must be called with mtx held.
+func (j *Journal) fiatPrice(currency string, when time.Time) (float64, error) {
+	currency = StringToUpper(currency)
+	if currency == j.Fiat {
+		return 1, nil
+	}
+	if journalIsFiat(currency) {
+		return journalConvertFiat(1, currency, j.Fiat, when)
+	}
+
+	price, fiat := 0., ""
+	if j.Price != nil {
+		var err error
+		price, fiat, err = j.Price(currency, when)
+		if err != nil {
+			price = 0
+		}
+	}
+	if price == 0 {
+		price, fiat = j.impliedPrice(currency, when)
+	}
+	if price == 0 {
+		return 0, ErrJournalNoFiatRate
+	}
+
+	if StringToUpper(fiat) == j.Fiat {
+		return price, nil
+	}
+	return journalConvertFiat(price, fiat, j.Fiat, when)
+}
+
+// journalConvertFiat converts at the cached exchange rates, which are only
+// current ones. Older trades are left unvalued rather than valued at today's
+// rate.
+func journalConvertFiat(amount float64, from, to string, when time.Time) (float64, error) {
+	age := CurrencyStoreUpdated.Sub(when)
+	if CurrencyStoreUpdated.IsZero() || age > JOURNAL_MAX_FIAT_RATE_AGE || age < -JOURNAL_MAX_FIAT_RATE_AGE {
+		return 0, ErrJournalStaleFiatRate
+	}
+	return ConvertCurrency(amount, from, to)
+}
+
+// impliedPrice is the price of the last fiat quoted trade in currency at or
+// before when. must be called with mtx held.
+func (j *Journal) impliedPrice(currency string, when time.Time) (float64, string) {
+	var last *JournalEntry
+	for i := range j.entries {
+		x := &j.entries[i]
+		if x.Base != currency || !journalIsFiat(x.Quote) || x.Time.After(when) {
+			continue
+		}
+		if last == nil || x.Time.After(last.Time) {
+			last = x
+		}
+	}
+	if last == nil {
+		return 0, ""
+	}
+	return last.Price, last.Quote
+}
+
+// add must be called with mtx held.
+func (j *Journal) add(entry JournalEntry) (bool, error) {
+	if entry.Exchange == "" || entry.Base == "" || entry.Quote == "" || entry.Price <= 0 || entry.Amount <= 0 {
+		return false, ErrJournalInvalidEntry
+	}
+
+	key := journalKey(entry.Exchange, entry.TradeID)
+	if entry.TradeID != "" {
+		if _, ok := j.trades[key]; ok {
+			return false, nil
+		}
+	}
+
+	entry.Base = StringToUpper(entry.Base)
+	entry.Quote = StringToUpper(entry.Quote)
+	entry.FeeCurrency = StringToUpper(entry.FeeCurrency)
+	if entry.FeeCurrency == "" {
+		entry.FeeCurrency = entry.Quote
+	}
+	entry.Fee = math.Abs(entry.Fee)
+	if entry.Time.IsZero() {
+		entry.Time = time.Now()
+	}
+
+	if entry.FiatPrice == 0 {
+		price, err := j.fiatPrice(entry.Quote, entry.Time)
+		if err != nil {
+			log.Printf("WARN couldn't value %s trade %s in %s: %v", entry.Exchange, entry.TradeID, j.Fiat, err)
+		}
+		entry.FiatCurrency = j.Fiat
+		entry.FiatPrice = price
+	}
+
+	if entry.TradeID != "" {
+		j.trades[key] = struct{}{}
+	}
+	j.entries = append(j.entries, entry)
+	return true, nil
+}
+
+// Add records an entry. Entries with a trade ID already in the journal are
+// ignored.
+func (j *Journal) Add(entry JournalEntry) error {
+	j.mtx.Lock()
+	defer j.mtx.Unlock()
+
+	added, err := j.add(entry)
+	if added {
+		j.save()
+	}
+	return err
+}
+
+// Import records entries pulled from an exchange's trade history and returns
+// how many weren't already in the journal.
+func (j *Journal) Import(entries []JournalEntry) (int, error) {
+	j.mtx.Lock()
+	defer j.mtx.Unlock()
+
+	// oldest first, so that implied prices are there when they're needed
+	entries = append([]JournalEntry{}, entries...)
+	sort.Sort(journalByTime(entries))
+
+	count := 0
+	for _, x := range entries {
+		x.Source = JOURNAL_SOURCE_IMPORT
+		added, err := j.add(x)
+		if err != nil {
+			return count, err
+		}
+		if added {
+			count++
+		}
+	}
+	if count > 0 {
+		j.save()
+	}
+	return count, nil
+}
+
+// Entries returns the journal ordered by trade time.
+func (j *Journal) Entries() []JournalEntry {
+	j.mtx.Lock()
+	defer j.mtx.Unlock()
+
+	entries := append([]JournalEntry{}, j.entries...)
+	sort.Sort(journalByTime(entries))
+	return entries
+}
+
+type journalByTime []JournalEntry
+
+func (e journalByTime) Len() int           { return len(e) }
+func (e journalByTime) Swap(i, k int)      { e[i], e[k] = e[k], e[i] }
+func (e journalByTime) Less(i, k int) bool { return e[i].Time.Before(e[k].Time) }
+
+func journalFloat(x float64) string {
+	return strconv.FormatFloat(x, 'f', -1, 64)
+}
+
+// ExportCSV writes the journal, oldest first, with each trade's fiat value.
+// Trades that couldn't be valued have empty fiat columns.
+func (j *Journal) ExportCSV(w io.Writer) error {
+	out := csv.NewWriter(w)
+	out.Write([]string{"time", "exchange", "trade_id", "order_id", "pair", "side", "base", "quote", "amount", "price", "total", "fee", "fee_currency", "fiat_currency", "fiat_price", "fiat_total", "fiat_fee", "source"})
+
+	for _, x := range j.Entries() {
+		fiatPrice, fiatTotal, fiatFee := "", "", ""
+		if x.FiatPrice != 0 {
+			fiatPrice = journalFloat(x.FiatPrice)
+			fiatTotal = journalFloat(x.FiatTotal())
+			fiatFee = journalFloat(x.FiatFee())
+		}
+		out.Write([]string{
+			x.Time.UTC().Format(time.RFC3339), x.Exchange, x.TradeID, x.OrderID, x.Pair, x.Side(), x.Base, x.Quote,
+			journalFloat(x.Amount), journalFloat(x.Price), journalFloat(x.Total()), journalFloat(x.Fee), x.FeeCurrency,
+			x.FiatCurrency, fiatPrice, fiatTotal, fiatFee, x.Source,
+		})
+	}
+
+	out.Flush()
+	return out.Error()
+}
+
+type TaxLot struct {
+	Currency string
+	Amount   float64
+	Cost     float64 // fiat, including fees
+	Acquired time.Time
+	Exchange string
+	TradeID  string
+}
+
+type TaxLotDisposal struct {
+	Currency  string
+	Amount    float64
+	Acquired  time.Time
+	Disposed  time.Time
+	Cost      float64
+	Proceeds  float64
+	Gain      float64
+	Exchange  string
+	TradeID   string
+	Unmatched bool // sold more than the journal shows was bought
+}
+
+type TaxLotReport struct {
+	Method    string
+	Fiat      string
+	Disposals []TaxLotDisposal
+	Open      []TaxLot
+}
+
+// TaxLots matches every disposal in the journal against the lots acquired
+// before it. A trade between two cryptocurrencies disposes of one and
+// acquires the other, both at the trade's fiat value. Fees are added to the
+// cost of what's acquired, or taken from the proceeds of what's disposed.
+func (j *Journal) TaxLots(method string) (TaxLotReport, error) {
+	if method != JOURNAL_TAX_LOT_FIFO && method != JOURNAL_TAX_LOT_LIFO {
+		return TaxLotReport{}, ErrJournalUnknownMethod
+	}
+
+	report := TaxLotReport{Method: method, Fiat: j.Fiat}
+	lots := make(map[string][]TaxLot)
+
+	acquire := func(x JournalEntry, currency string, amount, cost float64) {
+		lots[currency] = append(lots[currency], TaxLot{Currency: currency, Amount: amount, Cost: cost, Acquired: x.Time, Exchange: x.Exchange, TradeID: x.TradeID})
+	}
+
+	dispose := func(x JournalEntry, currency string, amount, proceeds float64) {
+		held := lots[currency]
+		left := amount
+		for left > EXECUTION_DUST && len(held) > 0 {
+			i := 0
+			if method == JOURNAL_TAX_LOT_LIFO {
+				i = len(held) - 1
+			}
+			lot := &held[i]
+
+			take := math.Min(left, lot.Amount)
+			cost := lot.Cost * take / lot.Amount
+			share := proceeds * take / amount
+			report.Disposals = append(report.Disposals, TaxLotDisposal{
+				Currency: currency, Amount: take, Acquired: lot.Acquired, Disposed: x.Time,
+				Cost: cost, Proceeds: share, Gain: share - cost, Exchange: x.Exchange, TradeID: x.TradeID,
+			})
+
+			lot.Cost -= cost
+			lot.Amount -= take
+			left -= take
+			if lot.Amount <= EXECUTION_DUST {
+				held = append(held[:i], held[i+1:]...)
+			}
+		}
+		lots[currency] = held
+
+		if left > EXECUTION_DUST {
+			share := proceeds * left / amount
+			report.Disposals = append(report.Disposals, TaxLotDisposal{
+				Currency: currency, Amount: left, Disposed: x.Time,
+				Proceeds: share, Gain: share, Exchange: x.Exchange, TradeID: x.TradeID, Unmatched: true,
+			})
+		}
+	}
+
+	for _, x := range j.Entries() {
+		if x.FiatPrice == 0 {
+			return report, fmt.Errorf("%s trade %s at %v: %v", x.Exchange, x.TradeID, x.Time, ErrJournalNoFiatRate)
+		}
+		if x.FiatCurrency != j.Fiat {
+			return report, fmt.Errorf("%s trade %s is valued in %s not %s", x.Exchange, x.TradeID, x.FiatCurrency, j.Fiat)
+		}
+
+		value, fee := x.FiatTotal(), x.FiatFee()
+		cryptoQuote := !journalIsFiat(x.Quote)
+		if x.Buy {
+			acquire(x, x.Base, x.Amount, value+fee)
+			if cryptoQuote {
+				dispose(x, x.Quote, x.Total(), value)
+			}
+		} else {
+			dispose(x, x.Base, x.Amount, value-fee)
+			if cryptoQuote {
+				acquire(x, x.Quote, x.Total(), value)
+			}
+		}
+	}
+
+	currencies := []string{}
+	for x := range lots {
+		currencies = append(currencies, x)
+	}
+	sort.Strings(currencies)
+	for _, x := range currencies {
+		report.Open = append(report.Open, lots[x]...)
+	}
+	return report, nil
+}
+
+// WriteCSV writes the report's disposals, one row per lot closed.
+func (r TaxLotReport) WriteCSV(w io.Writer) error {
+	out := csv.NewWriter(w)
+	out.Write([]string{"currency", "amount", "acquired", "disposed", "cost_" + strings.ToLower(r.Fiat), "proceeds_" + strings.ToLower(r.Fiat), "gain_" + strings.ToLower(r.Fiat), "exchange", "trade_id", "method", "unmatched"})
+
+	for _, x := range r.Disposals {
+		acquired := ""
+		if !x.Acquired.IsZero() {
+			acquired = x.Acquired.UTC().Format(time.RFC3339)
+		}
+		out.Write([]string{
+			x.Currency, journalFloat(x.Amount), acquired, x.Disposed.UTC().Format(time.RFC3339),
+			journalFloat(RoundFloat(x.Cost, 8)), journalFloat(RoundFloat(x.Proceeds, 8)), journalFloat(RoundFloat(x.Gain, 8)),
+			x.Exchange, x.TradeID, r.Method, strconv.FormatBool(x.Unmatched),
+		})
+	}
+
+	out.Flush()
+	return out.Error()
+}
+
+// save must be called with mtx held.
+func (j *Journal) save() {
+	if j.path == "" {
+		return
+	}
+	payload, err := json.MarshalIndent(j.entries, "", " ")
+	if err == nil {
+		tmp := j.path + ".tmp"
+		err = ioutil.WriteFile(tmp, payload, 0644)
+		if err == nil {
+			err = os.Rename(tmp, j.path)
+		}
+	}
+	if err != nil {
+		log.Printf("Unable to save journal to %s: %v", j.path, err)
+	}
+}
+
+// Load replaces the journal with the one saved at path, and saves to path
+// from then on. A missing file is not an error.
+func (j *Journal) Load(path string) error {
+	entries := []JournalEntry{}
+	file, err := ioutil.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err == nil {
+		err = json.Unmarshal(file, &entries)
+		if err != nil {
+			return fmt.Errorf("unable to parse %s: %v", path, err)
+		}
+	}
+
+	j.mtx.Lock()
+	defer j.mtx.Unlock()
+	j.path = path
+	j.entries = entries
+	j.trades = make(map[string]struct{})
+	for _, x := range entries {
+		if x.TradeID != "" {
+			j.trades[journalKey(x.Exchange, x.TradeID)] = struct{}{}
+		}
+	}
+	return nil
+}
+
+func (j *Journal) Len() int {
+	j.mtx.Lock()
+	defer j.mtx.Unlock()
+	return len(j.entries)
+}
+
+// journalOrderFill records a fill booked by the OMS. Paper fills aren't
+// real trades so they're left out.
+func journalOrderFill(order *Order, fill OrderFill) {
+	if IsPaperExchangeName(order.Exchange) {
+		return
+	}
+	base, quote := journalSplitPair(order.Exchange, order.Pair)
+	err := TradeJournal.Add(JournalEntry{
+		Exchange:    order.Exchange,
+		TradeID:     fill.TradeID,
+		OrderID:     order.ExchangeOrderID,
+		Pair:        order.Pair,
+		Base:        base,
+		Quote:       quote,
+		Buy:         order.Buy,
+		Price:       fill.Price,
+		Amount:      fill.Amount,
+		Fee:         fill.Fee,
+		FeeCurrency: quote,
+		Time:        fill.Time,
+		Source:      JOURNAL_SOURCE_BOT,
+	})
+	if err != nil {
+		log.Printf("WARN couldn't journal fill %s for order %d: %v", fill.TradeID, order.OrderID, err)
+	}
+}
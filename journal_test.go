@@ -0,0 +1,152 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func journalTestEntry(tradeID string, buy bool, price, amount, fee float64, when time.Time) JournalEntry {
+	return JournalEntry{Exchange: "Bitfinex", TradeID: tradeID, Pair: "btcusd", Base: "BTC", Quote: "USD", Buy: buy, Price: price, Amount: amount, Fee: fee, Time: when}
+}
+
+func TestJournalTaxLots(t *testing.T) {
+	t.Parallel()
+	j := NewJournal("USD")
+	start := time.Date(2016, 1, 1, 0, 0, 0, 0, time.UTC)
+	j.Add(journalTestEntry("1", true, 100, 1, 1, start))
+	j.Add(journalTestEntry("2", true, 200, 1, 0, start.Add(time.Hour)))
+	j.Add(journalTestEntry("3", false, 300, 1.5, 0, start.Add(2*time.Hour)))
+
+	expected := map[string][]float64{
+		JOURNAL_TAX_LOT_FIFO: {300 - 101, 150 - 100},
+		JOURNAL_TAX_LOT_LIFO: {300 - 200, 150 - 50.5},
+	}
+	for method, gains := range expected {
+		report, err := j.TaxLots(method)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(report.Disposals) != 2 || len(report.Open) != 1 || math.Abs(report.Open[0].Amount-0.5) > 1e-9 {
+			t.Fatal(fmt.Sprintf("Test failed. %s unexpected report %+v", method, report))
+		}
+		for i, x := range report.Disposals {
+			if math.Abs(x.Gain-gains[i]) > 1e-9 || x.Unmatched {
+				t.Error(fmt.Sprintf("Test failed. %s expected gain %f. Actual %+v", method, gains[i], x))
+			}
+		}
+	}
+
+	if _, err := j.TaxLots("HIFO"); err != ErrJournalUnknownMethod {
+		t.Error(fmt.Sprintf("Test failed. Expected %v. Actual %v", ErrJournalUnknownMethod, err))
+	}
+}
+
+func TestJournalCryptoQuotedImport(t *testing.T) {
+	t.Parallel()
+	j := NewJournal("USD")
+	j.Price = func(currency string, when time.Time) (float64, string, error) {
+		return 1000, "USD", nil
+	}
+
+	trades := []PoloniexAuthenticatedTradeHistory{
+		{TradeID: 1, Date: "2016-01-01 00:00:00", Rate: 0.01, Amount: 10, Total: 0.1, Fee: 0.0025, Type: "buy"},
+	}
+	count, err := j.Import(PoloniexJournalEntries("BTC_ETH", trades))
+	if err != nil || count != 1 {
+		t.Fatal(fmt.Sprintf("Test failed. Expected 1 trade imported. Actual %d %v", count, err))
+	}
+	if count, _ = j.Import(PoloniexJournalEntries("BTC_ETH", trades)); count != 0 {
+		t.Error(fmt.Sprintf("Test failed. Expected duplicates ignored. Actual %d imported", count))
+	}
+
+	entry := j.Entries()[0]
+	if entry.Base != "ETH" || entry.Quote != "BTC" || entry.FeeCurrency != "ETH" || entry.FiatTotal() != 100 || math.Abs(entry.FiatFee()-0.25) > 1e-9 {
+		t.Error(fmt.Sprintf("Test failed. Unexpected entry %+v", entry))
+	}
+
+	// buying ETH disposes of BTC the journal never saw bought
+	report, err := j.TaxLots(JOURNAL_TAX_LOT_FIFO)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Disposals) != 1 || report.Disposals[0].Currency != "BTC" || !report.Disposals[0].Unmatched || report.Disposals[0].Proceeds != 100 {
+		t.Error(fmt.Sprintf("Test failed. Expected unmatched BTC disposal. Actual %+v", report.Disposals))
+	}
+	if len(report.Open) != 1 || math.Abs(report.Open[0].Cost-100.25) > 1e-9 {
+		t.Error(fmt.Sprintf("Test failed. Expected ETH lot costing 100.25. Actual %+v", report.Open))
+	}
+}
+
+func TestJournalImpliedPriceAndCSV(t *testing.T) {
+	t.Parallel()
+	j := NewJournal("USD")
+	start := time.Date(2016, 1, 1, 0, 0, 0, 0, time.UTC)
+	j.Add(journalTestEntry("1", true, 400, 1, 0, start))
+	j.Add(JournalEntry{Exchange: "Poloniex", TradeID: "1", Pair: "BTC_ETH", Base: "ETH", Quote: "BTC", Buy: true, Price: 0.01, Amount: 10, Time: start.Add(time.Hour)})
+
+	// valued off the earlier BTC/USD trade
+	if entry := j.Entries()[1]; entry.FiatPrice != 400 {
+		t.Error(fmt.Sprintf("Test failed. Expected implied BTC price 400. Actual %f", entry.FiatPrice))
+	}
+
+	var buf bytes.Buffer
+	if err := j.ExportCSV(&buf); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 3 || rows[0][15] != "fiat_total" || rows[2][15] != "40" {
+		t.Error(fmt.Sprintf("Test failed. Unexpected CSV %v", rows))
+	}
+}
+
+func TestJournalFiatRateAge(t *testing.T) {
+	store, updated := CurrencyStore, CurrencyStoreUpdated
+	defer func() {
+		CurrencyStore, CurrencyStoreUpdated = store, updated
+	}()
+	CurrencyStore = map[string]Rate{"EURUSD": {Id: "EURUSD", Rate: 1.1}}
+	CurrencyStoreUpdated = time.Date(2016, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	j := NewJournal("USD")
+	recent := JournalEntry{Exchange: "Kraken", TradeID: "1", Pair: "XXBTZEUR", Base: "BTC", Quote: "EUR", Buy: true, Price: 500, Amount: 1, Time: CurrencyStoreUpdated.Add(-time.Hour)}
+	old := recent
+	old.TradeID, old.Time = "2", CurrencyStoreUpdated.AddDate(0, -1, 0)
+	j.Add(recent)
+	j.Add(old)
+
+	entries := j.Entries()
+	if entries[0].FiatPrice != 0 || math.Abs(entries[1].FiatPrice-1.1) > 1e-9 {
+		t.Error(fmt.Sprintf("Test failed. Expected only the recent trade valued at the cached rate. Actual %+v", entries))
+	}
+}
+
+func TestJournalCoinbasePrice(t *testing.T) {
+	t.Parallel()
+	when := time.Date(2016, 1, 1, 0, 10, 30, 0, time.UTC)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, "/BTC-USD/candles") || r.URL.Query().Get("end") != strconv.FormatInt(when.Unix(), 10) {
+			t.Error(fmt.Sprintf("Test failed. Unexpected request %s", r.URL))
+		}
+		fmt.Fprintf(w, "[[%d,429,431,430,430.5,2],[%d,428,430,429,429.5,1]]", when.Unix()-30, when.Unix()-300)
+	}))
+	defer server.Close()
+
+	c := &Coinbase{Name: "CoinbaseJournalPrice", APIUrl: server.URL + "/"}
+	j := NewJournal("USD")
+	j.Price = c.JournalPrice
+	j.Add(JournalEntry{Exchange: "Poloniex", TradeID: "1", Pair: "BTC_ETH", Base: "ETH", Quote: "BTC", Buy: true, Price: 0.01, Amount: 10, Time: when})
+	if entry := j.Entries()[0]; entry.FiatPrice != 430.5 || entry.FiatCurrency != "USD" {
+		t.Error(fmt.Sprintf("Test failed. Expected BTC valued at the last candle's close. Actual %+v", entry))
+	}
+}
@@ -0,0 +1,255 @@
+package main
+
+import (
+	"errors"
+	"math"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+const (
+	// minute candles are only there for minutes with trades, so look back a
+	// while for the last price
+	JOURNAL_PRICE_LOOKBACK = time.Hour
+)
+
+var (
+	ErrJournalImportUnsupported = errors.New("Trade history import is not supported for this exchange.")
+)
+
+// Converters from each exchange's trade history to journal entries, ready
+// for Journal.Import.
+
+func journalUnixTime(seconds float64) time.Time {
+	whole, frac := math.Modf(seconds)
+	return time.Unix(int64(whole), int64(frac*1e9))
+}
+
+func BitfinexJournalEntries(symbol string, trades []BitfinexTradeHistory) []JournalEntry {
+	base, quote := journalSplitPair("Bitfinex", symbol)
+	entries := []JournalEntry{}
+	for _, x := range trades {
+		timestamp, _ := strconv.ParseFloat(x.Timestamp, 64)
+		entries = append(entries, JournalEntry{
+			Exchange:    "Bitfinex",
+			TradeID:     strconv.FormatInt(x.TID, 10),
+			OrderID:     strconv.FormatInt(x.OrderID, 10),
+			Pair:        symbol,
+			Base:        base,
+			Quote:       quote,
+			Buy:         StringToLower(x.Type) == "buy",
+			Price:       x.Price,
+			Amount:      math.Abs(x.Amount),
+			Fee:         x.FeeAmount,
+			FeeCurrency: x.FeeCurrency,
+			Time:        journalUnixTime(timestamp),
+		})
+	}
+	return entries
+}
+
+// PoloniexJournalEntries takes the trades for one pair. Poloniex reports its
+// fee as a rate, charged on whatever the trade pays out.
+func PoloniexJournalEntries(pair string, trades []PoloniexAuthenticatedTradeHistory) []JournalEntry {
+	base, quote := journalSplitPair("Poloniex", pair)
+	entries := []JournalEntry{}
+	for _, x := range trades {
+		t, _ := time.Parse(POLONIEX_DATE_LAYOUT, x.Date)
+		buy := x.Type == POLONIEX_ORDER_BUY
+		fee, feeCurrency := x.Total*x.Fee, quote
+		if buy {
+			fee, feeCurrency = x.Amount*x.Fee, base
+		}
+		entries = append(entries, JournalEntry{
+			Exchange:    "Poloniex",
+			TradeID:     strconv.FormatInt(x.TradeID, 10),
+			OrderID:     strconv.FormatInt(x.OrderNumber, 10),
+			Pair:        pair,
+			Base:        base,
+			Quote:       quote,
+			Buy:         buy,
+			Price:       x.Rate,
+			Amount:      x.Amount,
+			Fee:         fee,
+			FeeCurrency: feeCurrency,
+			Time:        t,
+		})
+	}
+	return entries
+}
+
+func CoinbaseJournalEntries(fills []CoinbaseFillResponse) []JournalEntry {
+	entries := []JournalEntry{}
+	for _, x := range fills {
+		base, quote := journalSplitPair("Coinbase", x.ProductID)
+		t, _ := time.Parse(time.RFC3339Nano, x.CreatedAt)
+		entries = append(entries, JournalEntry{
+			Exchange:    "Coinbase",
+			TradeID:     x.ProductID + ":" + strconv.Itoa(x.TradeID),
+			OrderID:     x.OrderID,
+			Pair:        x.ProductID,
+			Base:        base,
+			Quote:       quote,
+			Buy:         x.Side == "buy",
+			Price:       x.Price,
+			Amount:      x.Size,
+			Fee:         x.Fee,
+			FeeCurrency: quote,
+			Time:        t,
+		})
+	}
+	return entries
+}
+
+func GeminiJournalEntries(symbol string, trades []GeminiTradeHistory) []JournalEntry {
+	base, quote := journalSplitPair("Gemini", symbol)
+	entries := []JournalEntry{}
+	for _, x := range trades {
+		t := time.Unix(x.Timestamp, 0)
+		if x.TimestampMS != 0 {
+			t = time.Unix(0, x.TimestampMS*int64(time.Millisecond))
+		}
+		entries = append(entries, JournalEntry{
+			Exchange:    "Gemini",
+			TradeID:     strconv.FormatInt(x.TID, 10),
+			OrderID:     strconv.FormatInt(x.OrderID, 10),
+			Pair:        symbol,
+			Base:        base,
+			Quote:       quote,
+			Buy:         StringToLower(x.Type) == "buy",
+			Price:       x.Price,
+			Amount:      x.Amount,
+			Fee:         x.FeeAmount,
+			FeeCurrency: x.FeeCurrency,
+			Time:        t,
+		})
+	}
+	return entries
+}
+
+// BTCEJournalEntries takes the trade history keyed by trade ID. BTC-e doesn't
+// report fees with its trades.
+func BTCEJournalEntries(trades map[string]BTCETradeHistory) []JournalEntry {
+	entries := []JournalEntry{}
+	for id, x := range trades {
+		base, quote := journalSplitPair("BTCE", x.Pair)
+		entries = append(entries, JournalEntry{
+			Exchange: "BTCE",
+			TradeID:  id,
+			OrderID:  strconv.FormatInt(x.OrderID, 10),
+			Pair:     x.Pair,
+			Base:     base,
+			Quote:    quote,
+			Buy:      x.Type == "buy",
+			Price:    x.Rate,
+			Amount:   x.Amount,
+			Time:     journalUnixTime(x.Timestamp),
+		})
+	}
+	return entries
+}
+
+// The ImportTradeHistory methods pull an exchange's trade history into the
+// trade journal and return how many trades were new.
+
+func (b *Bitfinex) ImportTradeHistory(symbol string, since time.Time) (int, error) {
+	trades, err := b.GetTradeHistory(symbol, since, time.Time{}, 0, 0)
+	if err != nil {
+		return 0, err
+	}
+	return TradeJournal.Import(BitfinexJournalEntries(symbol, trades))
+}
+
+// ImportTradeHistory imports every pair's trades when currency is empty.
+func (p *Poloniex) ImportTradeHistory(currency, start, end string) (int, error) {
+	result, err := p.GetAuthenticatedTradeHistory(currency, start, end)
+	if err != nil {
+		return 0, err
+	}
+
+	entries := []JournalEntry{}
+	switch x := result.(type) {
+	case PoloniexAuthenticatedTradeHistoryResponse:
+		entries = PoloniexJournalEntries(currency, x.Data)
+	case PoloniexAuthenticatedTradeHistoryAll:
+		for pair, trades := range x.Data {
+			entries = append(entries, PoloniexJournalEntries(pair, trades)...)
+		}
+	}
+	return TradeJournal.Import(entries)
+}
+
+func (c *Coinbase) ImportTradeHistory(params url.Values) (int, error) {
+	fills, err := c.GetFills(params)
+	if err != nil {
+		return 0, err
+	}
+	return TradeJournal.Import(CoinbaseJournalEntries(fills))
+}
+
+func (g *Gemini) ImportTradeHistory(symbol string, since time.Time) (int, error) {
+	trades, err := g.GetTradeHistory(symbol, since.Unix())
+	if err != nil {
+		return 0, err
+	}
+	return TradeJournal.Import(GeminiJournalEntries(symbol, trades))
+}
+
+func (b *BTCE) ImportTradeHistory(pair string, since time.Time) (int, error) {
+	trades, err := b.GetTradeHistory(0, 1000, 0, "ASC", strconv.FormatInt(since.Unix(), 10), "", pair)
+	if err != nil {
+		return 0, err
+	}
+	return TradeJournal.Import(BTCEJournalEntries(trades))
+}
+
+// ImportTradeHistory imports an enabled exchange's trades in pair since the
+// given time. Where the exchange allows it, an empty pair imports every pair.
+func ImportTradeHistory(exchange, pair string, since time.Time) (int, error) {
+	switch exchange {
+	case bot.exchange.bitfinex.GetName():
+		if bot.exchange.bitfinex.IsEnabled() {
+			return bot.exchange.bitfinex.ImportTradeHistory(pair, since)
+		}
+	case bot.exchange.poloniex.GetName():
+		if bot.exchange.poloniex.IsEnabled() {
+			return bot.exchange.poloniex.ImportTradeHistory(pair, strconv.FormatInt(since.Unix(), 10), "")
+		}
+	case bot.exchange.coinbase.GetName():
+		if bot.exchange.coinbase.IsEnabled() {
+			params := url.Values{}
+			if pair != "" {
+				params.Set("product_id", pair)
+			}
+			return bot.exchange.coinbase.ImportTradeHistory(params)
+		}
+	case bot.exchange.gemini.GetName():
+		if bot.exchange.gemini.IsEnabled() {
+			return bot.exchange.gemini.ImportTradeHistory(pair, since)
+		}
+	case bot.exchange.btce.GetName():
+		if bot.exchange.btce.IsEnabled() {
+			return bot.exchange.btce.ImportTradeHistory(pair, since)
+		}
+	}
+	return 0, ErrJournalImportUnsupported
+}
+
+// JournalPrice is a JournalPriceFunc for the currencies Coinbase trades
+// against USD, using the close of the last minute candle at or before when.
+func (c *Coinbase) JournalPrice(currency string, when time.Time) (float64, string, error) {
+	product := StringToUpper(currency) + "-USD"
+	history, err := c.GetHistoricRates(product, when.Add(-JOURNAL_PRICE_LOOKBACK).Unix(), when.Unix(), 60)
+	if err != nil {
+		return 0, "", err
+	}
+
+	// newest first
+	for _, x := range history {
+		if x.Time <= when.Unix() && x.Close > 0 {
+			return x.Close, "USD", nil
+		}
+	}
+	return 0, "", ErrJournalNoFiatRate
+}
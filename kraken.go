@@ -1,6 +1,7 @@
 package main
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"log"
@@ -34,8 +35,26 @@ const (
 	KRAKEN_TRADE_VOLUME   = "TradeVolume"
 	KRAKEN_ORDER_CANCEL   = "CancelOrder"
 	KRAKEN_ORDER_PLACE    = "AddOrder"
+
+	KRAKEN_ORDER_BUY               = "buy"
+	KRAKEN_ORDER_SELL              = "sell"
+	KRAKEN_ORDER_MARKET            = "market"
+	KRAKEN_ORDER_LIMIT             = "limit"
+	KRAKEN_ORDER_STOP_LOSS         = "stop-loss"
+	KRAKEN_ORDER_TAKE_PROFIT       = "take-profit"
+	KRAKEN_ORDER_STOP_LOSS_LIMIT   = "stop-loss-limit"
+	KRAKEN_ORDER_TAKE_PROFIT_LIMIT = "take-profit-limit"
+	KRAKEN_ORDER_TRAILING_STOP     = "trailing-stop"
+
+	KRAKEN_ORDER_FLAG_POST_ONLY       = "post"
+	KRAKEN_ORDER_FLAG_FEE_IN_BASE     = "fcib"
+	KRAKEN_ORDER_FLAG_FEE_IN_QUOTE    = "fciq"
+	KRAKEN_ORDER_FLAG_NO_MARKET_PRICE = "nompp" // no market price protection
+	KRAKEN_ORDER_FLAG_VOLUME_IN_QUOTE = "viqc"
 )
 
+var krakenFiatCurrencies = []string{"USD", "EUR", "GBP", "JPY", "CAD", "KRW"}
+
 type Kraken struct {
 	Name                    string
 	Enabled                 bool
@@ -43,6 +62,7 @@ type Kraken struct {
 	Websocket               bool
 	RESTPollingDelay        time.Duration
 	AuthenticatedAPISupport bool
+	APIUrl                  string
 	ClientKey, APISecret    string
 	FiatFee, CryptoFee      float64
 	BaseCurrencies          []string
@@ -60,6 +80,7 @@ func (k *Kraken) SetDefaults() {
 	k.Websocket = false
 	k.RESTPollingDelay = 10
 	k.Ticker = make(map[string]KrakenTicker)
+	k.APIUrl = KRAKEN_API_URL
 }
 
 func (k *Kraken) GetName() string {
@@ -87,6 +108,7 @@ func (k *Kraken) Setup(exch Exchanges) {
 		k.BaseCurrencies = SplitStrings(exch.BaseCurrencies, ",")
 		k.AvailablePairs = SplitStrings(exch.AvailablePairs, ",")
 		k.EnabledPairs = SplitStrings(exch.EnabledPairs, ",")
+		k.APIUrl = GetExchangeURLs(exch, ExchangeURLs{API: k.APIUrl}).API
 	}
 }
 
@@ -128,43 +150,180 @@ func (k *Kraken) Run() {
 	}
 }
 
-func (k *Kraken) GetServerTime() error {
-	var result interface{}
-	path := fmt.Sprintf("%s/%s/public/%s", KRAKEN_API_URL, KRAKEN_API_VERSION, KRAKEN_SERVER_TIME)
-	err := SendHTTPGetRequest(path, true, &result)
+// KrakenAssetName returns Kraken's name for a currency, e.g. XXBT for BTC and
+// ZUSD for USD. Kraken accepts either name in requests but only uses its own
+// in responses.
+func KrakenAssetName(currency string) string {
+	currency = StringToUpper(currency)
+	switch currency {
+	case "BTC":
+		currency = "XBT"
+	case "DOGE":
+		currency = "XDG"
+	}
+	if len(currency) != 3 {
+		return currency
+	}
+	for _, x := range krakenFiatCurrencies {
+		if x == currency {
+			return "Z" + currency
+		}
+	}
+	return "X" + currency
+}
 
-	if err != nil {
-		return err
+// KrakenPairName returns Kraken's name for a pair, e.g. XXBTZUSD for BTCUSD
+// or XBTUSD.
+func KrakenPairName(pair string) string {
+	pair = StringToUpper(strings.Replace(pair, "_", "", -1))
+	if len(pair) != 6 {
+		return pair
+	}
+	return KrakenAssetName(pair[:3]) + KrakenAssetName(pair[3:])
+}
+
+// KrakenAltPairName returns the short name of one of Kraken's pairs, e.g.
+// XBTUSD for XXBTZUSD.
+func KrakenAltPairName(pair string) string {
+	if len(pair) == 8 && strings.IndexByte("XZ", pair[0]) >= 0 && strings.IndexByte("XZ", pair[4]) >= 0 {
+		return pair[1:4] + pair[5:]
 	}
+	return pair
+}
 
-	log.Println(result)
+// krakenPairResult picks pair's entry out of a result keyed by pair, which
+// also holds "last" for the methods that page.
+func krakenPairResult(result map[string]json.RawMessage, pair string) json.RawMessage {
+	if x, ok := result[KrakenPairName(pair)]; ok {
+		return x
+	}
+	for name, x := range result {
+		if name != "last" {
+			return x
+		}
+	}
 	return nil
 }
 
-func (k *Kraken) GetAssets() error {
-	var result interface{}
-	path := fmt.Sprintf("%s/%s/public/%s", KRAKEN_API_URL, KRAKEN_API_VERSION, KRAKEN_ASSETS)
-	err := SendHTTPGetRequest(path, true, &result)
+// krakenFloat parses the numbers Kraken sends in arrays, as strings or not.
+func krakenFloat(x interface{}) float64 {
+	switch v := x.(type) {
+	case float64:
+		return v
+	case string:
+		f, _ := strconv.ParseFloat(v, 64)
+		return f
+	}
+	return 0
+}
 
-	if err != nil {
-		return err
+type KrakenResponse struct {
+	Error  []string        `json:"error"`
+	Result json.RawMessage `json:"result"`
+}
+
+// decodeResponse returns the errors in resp, if any, as an ExchangeError or
+// otherwise decodes its result. Warnings are only logged.
+func (k *Kraken) decodeResponse(resp KrakenResponse, result interface{}) error {
+	errs := []string{}
+	for _, x := range resp.Error {
+		if strings.HasPrefix(x, "W") {
+			if k.Verbose {
+				log.Printf("%s warning: %s\n", k.GetName(), x)
+			}
+			continue
+		}
+		errs = append(errs, x)
+	}
+	if len(errs) > 0 {
+		return NewExchangeError(k.Name, errs[0], strings.Join(errs, ", "))
 	}
 
-	log.Println(result)
+	if result == nil {
+		return nil
+	}
+	if err := json.Unmarshal(resp.Result, result); err != nil {
+		return errors.New("Unable to JSON Unmarshal response.")
+	}
 	return nil
 }
 
-func (k *Kraken) GetAssetPairs() error {
-	var result interface{}
-	path := fmt.Sprintf("%s/%s/public/%s", KRAKEN_API_URL, KRAKEN_API_VERSION, KRAKEN_ASSET_PAIRS)
-	err := SendHTTPGetRequest(path, true, &result)
+func (k *Kraken) SendPublicHTTPRequest(method string, values url.Values, result interface{}) error {
+	path := EncodeURLValues(fmt.Sprintf("%s/%s/public/%s", k.APIUrl, KRAKEN_API_VERSION, method), values)
+	resp := KrakenResponse{}
+	err := GetHTTPClient(k.Name).SendHTTPGetRequest(path, true, &resp)
 
 	if err != nil {
 		return err
 	}
 
-	log.Println(result)
-	return nil
+	return k.decodeResponse(resp, result)
+}
+
+type KrakenServerTime struct {
+	UnixTime int64  `json:"unixtime"`
+	RFC1123  string `json:"rfc1123"`
+}
+
+func (k *Kraken) GetServerTime() (KrakenServerTime, error) {
+	result := KrakenServerTime{}
+	err := k.SendPublicHTTPRequest(KRAKEN_SERVER_TIME, nil, &result)
+
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+type KrakenAsset struct {
+	AltName         string `json:"altname"`
+	AClass          string `json:"aclass"`
+	Decimals        int    `json:"decimals"`
+	DisplayDecimals int    `json:"display_decimals"`
+}
+
+// GetAssets returns Kraken's assets keyed by its names for them.
+func (k *Kraken) GetAssets() (map[string]KrakenAsset, error) {
+	result := make(map[string]KrakenAsset)
+	err := k.SendPublicHTTPRequest(KRAKEN_ASSETS, nil, &result)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+type KrakenAssetPair struct {
+	AltName           string      `json:"altname"`
+	AClassBase        string      `json:"aclass_base"`
+	Base              string      `json:"base"`
+	AClassQuote       string      `json:"aclass_quote"`
+	Quote             string      `json:"quote"`
+	Lot               string      `json:"lot"`
+	PairDecimals      int         `json:"pair_decimals"`
+	LotDecimals       int         `json:"lot_decimals"`
+	LotMultiplier     int         `json:"lot_multiplier"`
+	LeverageBuy       []int       `json:"leverage_buy"`
+	LeverageSell      []int       `json:"leverage_sell"`
+	Fees              [][]float64 `json:"fees"`       // [volume, percent] tiers
+	FeesMaker         [][]float64 `json:"fees_maker"` // as above
+	FeeVolumeCurrency string      `json:"fee_volume_currency"`
+	MarginCall        int         `json:"margin_call"`
+	MarginStop        int         `json:"margin_stop"`
+}
+
+// GetAssetPairs returns Kraken's pairs keyed by its names for them.
+func (k *Kraken) GetAssetPairs() (map[string]KrakenAssetPair, error) {
+	result := make(map[string]KrakenAssetPair)
+	err := k.SendPublicHTTPRequest(KRAKEN_ASSET_PAIRS, nil, &result)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
 }
 
 type KrakenTicker struct {
@@ -191,29 +350,20 @@ type KrakenTickerResponse struct {
 	Open   string   `json:"o"`
 }
 
+// GetTicker updates Ticker for the comma separated pairs in symbol, keyed by
+// their short names, e.g. XBTUSD.
 func (k *Kraken) GetTicker(symbol string) error {
 	values := url.Values{}
 	values.Set("pair", symbol)
 
-	type Response struct {
-		Error []interface{}                   `json:"error"`
-		Data  map[string]KrakenTickerResponse `json:"result"`
-	}
-
-	resp := Response{}
-	path := fmt.Sprintf("%s/%s/public/%s?%s", KRAKEN_API_URL, KRAKEN_API_VERSION, KRAKEN_TICKER, values.Encode())
-	err := SendHTTPGetRequest(path, true, &resp)
+	result := make(map[string]KrakenTickerResponse)
+	err := k.SendPublicHTTPRequest(KRAKEN_TICKER, values, &result)
 
 	if err != nil {
 		return err
 	}
 
-	if len(resp.Error) > 0 {
-		return errors.New(fmt.Sprintf("Kraken error: %s", resp.Error))
-	}
-
-	for x, y := range resp.Data {
-		x = x[1:4] + x[5:]
+	for x, y := range result {
 		ticker := KrakenTicker{}
 		ticker.Ask, _ = strconv.ParseFloat(y.Ask[0], 64)
 		ticker.Bid, _ = strconv.ParseFloat(y.Bid[0], 64)
@@ -224,85 +374,239 @@ func (k *Kraken) GetTicker(symbol string) error {
 		ticker.Low, _ = strconv.ParseFloat(y.Low[1], 64)
 		ticker.High, _ = strconv.ParseFloat(y.High[1], 64)
 		ticker.Open, _ = strconv.ParseFloat(y.Open, 64)
-		k.Ticker[x] = ticker
+		k.Ticker[KrakenAltPairName(x)] = ticker
 	}
 	return nil
 }
 
-func (k *Kraken) GetOHLC(symbol string) error {
+type KrakenOHLC struct {
+	Time   int64
+	Open   float64
+	High   float64
+	Low    float64
+	Close  float64
+	VWAP   float64
+	Volume float64
+	Count  int64
+}
+
+// GetOHLC returns candles of interval minutes since the given id, and the id
+// to poll for new ones with.
+func (k *Kraken) GetOHLC(symbol string, interval int, since int64) ([]KrakenOHLC, int64, error) {
 	values := url.Values{}
 	values.Set("pair", symbol)
 
-	var result interface{}
-	path := fmt.Sprintf("%s/%s/public/%s?%s", KRAKEN_API_URL, KRAKEN_API_VERSION, KRAKEN_OHLC, values.Encode())
-	err := SendHTTPGetRequest(path, true, &result)
+	if interval != 0 {
+		values.Set("interval", strconv.Itoa(interval))
+	}
+
+	if since != 0 {
+		values.Set("since", strconv.FormatInt(since, 10))
+	}
+
+	result := make(map[string]json.RawMessage)
+	err := k.SendPublicHTTPRequest(KRAKEN_OHLC, values, &result)
 
 	if err != nil {
-		return err
+		return nil, 0, err
 	}
 
-	log.Println(result)
-	return nil
+	var last int64
+	rows := [][]interface{}{}
+	if json.Unmarshal(result["last"], &last) != nil || json.Unmarshal(krakenPairResult(result, symbol), &rows) != nil {
+		return nil, 0, errors.New("Unable to JSON Unmarshal response.")
+	}
+
+	candles := []KrakenOHLC{}
+	for _, x := range rows {
+		if len(x) < 8 {
+			continue
+		}
+		candles = append(candles, KrakenOHLC{
+			Time:   int64(krakenFloat(x[0])),
+			Open:   krakenFloat(x[1]),
+			High:   krakenFloat(x[2]),
+			Low:    krakenFloat(x[3]),
+			Close:  krakenFloat(x[4]),
+			VWAP:   krakenFloat(x[5]),
+			Volume: krakenFloat(x[6]),
+			Count:  int64(krakenFloat(x[7])),
+		})
+	}
+	return candles, last, nil
 }
 
-func (k *Kraken) GetDepth(symbol string) error {
+type KrakenOrderbookBase struct {
+	Price     float64
+	Amount    float64
+	Timestamp int64
+}
+
+type KrakenOrderbook struct {
+	Bids []KrakenOrderbookBase
+	Asks []KrakenOrderbookBase
+}
+
+// GetDepth returns up to count levels of each side of the book, or all of
+// them when count is 0.
+func (k *Kraken) GetDepth(symbol string, count int) (KrakenOrderbook, error) {
 	values := url.Values{}
 	values.Set("pair", symbol)
 
-	var result interface{}
-	path := fmt.Sprintf("%s/%s/public/%s?%s", KRAKEN_API_URL, KRAKEN_API_VERSION, KRAKEN_DEPTH, values.Encode())
-	err := SendHTTPGetRequest(path, true, &result)
+	if count != 0 {
+		values.Set("count", strconv.Itoa(count))
+	}
+
+	result := make(map[string]json.RawMessage)
+	orderbook := KrakenOrderbook{}
+	err := k.SendPublicHTTPRequest(KRAKEN_DEPTH, values, &result)
 
 	if err != nil {
-		return err
+		return orderbook, err
 	}
 
-	log.Println(result)
-	return nil
+	book := struct {
+		Bids [][]interface{} `json:"bids"`
+		Asks [][]interface{} `json:"asks"`
+	}{}
+	if json.Unmarshal(krakenPairResult(result, symbol), &book) != nil {
+		return orderbook, errors.New("Unable to JSON Unmarshal response.")
+	}
+
+	levels := func(x [][]interface{}) []KrakenOrderbookBase {
+		result := []KrakenOrderbookBase{}
+		for _, y := range x {
+			if len(y) < 3 {
+				continue
+			}
+			result = append(result, KrakenOrderbookBase{krakenFloat(y[0]), krakenFloat(y[1]), int64(krakenFloat(y[2]))})
+		}
+		return result
+	}
+	orderbook.Bids = levels(book.Bids)
+	orderbook.Asks = levels(book.Asks)
+	return orderbook, nil
+}
+
+type KrakenTrade struct {
+	Price  float64
+	Volume float64
+	Time   float64
+	Buy    bool
+	Market bool
+	Misc   string
 }
 
-func (k *Kraken) GetTrades(symbol string) error {
+// GetTrades returns the trades since the given id, all recent trades if it's
+// empty, and the id to poll for new ones with.
+func (k *Kraken) GetTrades(symbol, since string) ([]KrakenTrade, string, error) {
 	values := url.Values{}
 	values.Set("pair", symbol)
 
-	var result interface{}
-	path := fmt.Sprintf("%s/%s/public/%s?%s", KRAKEN_API_URL, KRAKEN_API_VERSION, KRAKEN_TRADES, values.Encode())
-	err := SendHTTPGetRequest(path, true, &result)
+	if len(since) > 0 {
+		values.Set("since", since)
+	}
+
+	result := make(map[string]json.RawMessage)
+	err := k.SendPublicHTTPRequest(KRAKEN_TRADES, values, &result)
 
 	if err != nil {
-		return err
+		return nil, "", err
 	}
 
-	log.Println(result)
-	return nil
+	var last string
+	rows := [][]interface{}{}
+	if json.Unmarshal(result["last"], &last) != nil || json.Unmarshal(krakenPairResult(result, symbol), &rows) != nil {
+		return nil, "", errors.New("Unable to JSON Unmarshal response.")
+	}
+
+	trades := []KrakenTrade{}
+	for _, x := range rows {
+		if len(x) < 6 {
+			continue
+		}
+		misc, _ := x[5].(string)
+		trades = append(trades, KrakenTrade{
+			Price:  krakenFloat(x[0]),
+			Volume: krakenFloat(x[1]),
+			Time:   krakenFloat(x[2]),
+			Buy:    x[3] == "b",
+			Market: x[4] == "m",
+			Misc:   misc,
+		})
+	}
+	return trades, last, nil
 }
 
-func (k *Kraken) GetSpread(symbol string) {
+type KrakenSpread struct {
+	Time int64
+	Bid  float64
+	Ask  float64
+}
+
+// GetSpread returns the recent best bids and asks since the given id, and
+// the id to poll for new ones with.
+func (k *Kraken) GetSpread(symbol string, since int64) ([]KrakenSpread, int64, error) {
 	values := url.Values{}
 	values.Set("pair", symbol)
 
-	var result interface{}
-	path := fmt.Sprintf("%s/%s/public/%s?%s", KRAKEN_API_URL, KRAKEN_API_VERSION, KRAKEN_SPREAD, values.Encode())
-	err := SendHTTPGetRequest(path, true, &result)
+	if since != 0 {
+		values.Set("since", strconv.FormatInt(since, 10))
+	}
+
+	result := make(map[string]json.RawMessage)
+	err := k.SendPublicHTTPRequest(KRAKEN_SPREAD, values, &result)
 
 	if err != nil {
-		log.Println(err)
-		return
+		return nil, 0, err
+	}
+
+	var last int64
+	rows := [][]interface{}{}
+	if json.Unmarshal(result["last"], &last) != nil || json.Unmarshal(krakenPairResult(result, symbol), &rows) != nil {
+		return nil, 0, errors.New("Unable to JSON Unmarshal response.")
 	}
+
+	spreads := []KrakenSpread{}
+	for _, x := range rows {
+		if len(x) < 3 {
+			continue
+		}
+		spreads = append(spreads, KrakenSpread{int64(krakenFloat(x[0])), krakenFloat(x[1]), krakenFloat(x[2])})
+	}
+	return spreads, last, nil
 }
 
-func (k *Kraken) GetBalance() {
-	result, err := k.SendAuthenticatedHTTPRequest(KRAKEN_BALANCE, url.Values{})
+// GetBalance returns the account's balances keyed by Kraken's asset names,
+// e.g. XXBT.
+func (k *Kraken) GetBalance() (map[string]float64, error) {
+	result := make(map[string]string)
+	err := k.SendAuthenticatedHTTPRequest(KRAKEN_BALANCE, url.Values{}, &result)
 
 	if err != nil {
-		log.Println(err)
-		return
+		return nil, err
+	}
+
+	balances := make(map[string]float64)
+	for x, y := range result {
+		balances[x], _ = strconv.ParseFloat(y, 64)
 	}
+	return balances, nil
+}
 
-	log.Println(result)
+type KrakenTradeBalance struct {
+	EquivalentBalance float64 `json:"eb,string"`
+	TradeBalance      float64 `json:"tb,string"`
+	MarginAmount      float64 `json:"m,string"`
+	Net               float64 `json:"n,string"`
+	Cost              float64 `json:"c,string"`
+	Valuation         float64 `json:"v,string"`
+	Equity            float64 `json:"e,string"`
+	FreeMargin        float64 `json:"mf,string"`
+	MarginLevel       float64 `json:"ml,string"` // only with open positions
 }
 
-func (k *Kraken) GetTradeBalance(symbol, asset string) {
+func (k *Kraken) GetTradeBalance(symbol, asset string) (KrakenTradeBalance, error) {
 	values := url.Values{}
 
 	if len(symbol) > 0 {
@@ -313,17 +617,51 @@ func (k *Kraken) GetTradeBalance(symbol, asset string) {
 		values.Set("asset", asset)
 	}
 
-	result, err := k.SendAuthenticatedHTTPRequest(KRAKEN_TRADE_BALANCE, values)
+	result := KrakenTradeBalance{}
+	err := k.SendAuthenticatedHTTPRequest(KRAKEN_TRADE_BALANCE, values, &result)
 
 	if err != nil {
-		log.Println(err)
-		return
+		return result, err
 	}
 
-	log.Println(result)
+	return result, nil
 }
 
-func (k *Kraken) GetOpenOrders(showTrades bool, userref int64) {
+type KrakenOrderDescription struct {
+	Pair      string  `json:"pair"`
+	Type      string  `json:"type"`
+	OrderType string  `json:"ordertype"`
+	Price     float64 `json:"price,string"`
+	Price2    float64 `json:"price2,string"`
+	Leverage  string  `json:"leverage"`
+	Order     string  `json:"order"`
+	Close     string  `json:"close"`
+}
+
+type KrakenOrder struct {
+	RefID          string                 `json:"refid"`
+	UserRef        int64                  `json:"userref"`
+	Status         string                 `json:"status"`
+	Reason         string                 `json:"reason"`
+	OpenTime       float64                `json:"opentm"`
+	CloseTime      float64                `json:"closetm"`
+	StartTime      float64                `json:"starttm"`
+	ExpireTime     float64                `json:"expiretm"`
+	Description    KrakenOrderDescription `json:"descr"`
+	Volume         float64                `json:"vol,string"`
+	VolumeExecuted float64                `json:"vol_exec,string"`
+	Cost           float64                `json:"cost,string"`
+	Fee            float64                `json:"fee,string"`
+	Price          float64                `json:"price,string"` // average
+	StopPrice      float64                `json:"stopprice,string"`
+	LimitPrice     float64                `json:"limitprice,string"`
+	Misc           string                 `json:"misc"`
+	Flags          string                 `json:"oflags"`
+	Trades         []string               `json:"trades"`
+}
+
+// GetOpenOrders returns the open orders keyed by transaction ID.
+func (k *Kraken) GetOpenOrders(showTrades bool, userref int64) (map[string]KrakenOrder, error) {
 	values := url.Values{}
 
 	if showTrades {
@@ -334,17 +672,24 @@ func (k *Kraken) GetOpenOrders(showTrades bool, userref int64) {
 		values.Set("userref", strconv.FormatInt(userref, 10))
 	}
 
-	result, err := k.SendAuthenticatedHTTPRequest(KRAKEN_OPEN_ORDERS, values)
+	result := struct {
+		Open map[string]KrakenOrder `json:"open"`
+	}{}
+	err := k.SendAuthenticatedHTTPRequest(KRAKEN_OPEN_ORDERS, values, &result)
 
 	if err != nil {
-		log.Println(err)
-		return
+		return nil, err
 	}
 
-	log.Println(result)
+	return result.Open, nil
 }
 
-func (k *Kraken) GetClosedOrders(showTrades bool, userref, start, end, offset int64, closetime string) {
+type KrakenClosedOrders struct {
+	Closed map[string]KrakenOrder `json:"closed"`
+	Count  int64                  `json:"count"`
+}
+
+func (k *Kraken) GetClosedOrders(showTrades bool, userref, start, end, offset int64, closetime string) (KrakenClosedOrders, error) {
 	values := url.Values{}
 
 	if showTrades {
@@ -371,17 +716,19 @@ func (k *Kraken) GetClosedOrders(showTrades bool, userref, start, end, offset in
 		values.Set("closetime", closetime)
 	}
 
-	result, err := k.SendAuthenticatedHTTPRequest(KRAKEN_CLOSED_ORDERS, values)
+	result := KrakenClosedOrders{}
+	err := k.SendAuthenticatedHTTPRequest(KRAKEN_CLOSED_ORDERS, values, &result)
 
 	if err != nil {
-		log.Println(err)
-		return
+		return result, err
 	}
 
-	log.Println(result)
+	return result, nil
 }
 
-func (k *Kraken) QueryOrdersInfo(showTrades bool, userref, txid int64) {
+// QueryOrdersInfo returns the orders with the given transaction IDs, at
+// most 20.
+func (k *Kraken) QueryOrdersInfo(showTrades bool, userref int64, txids ...string) (map[string]KrakenOrder, error) {
 	values := url.Values{}
 
 	if showTrades {
@@ -392,25 +739,46 @@ func (k *Kraken) QueryOrdersInfo(showTrades bool, userref, txid int64) {
 		values.Set("userref", strconv.FormatInt(userref, 10))
 	}
 
-	if txid != 0 {
-		values.Set("txid", strconv.FormatInt(userref, 10))
+	if len(txids) > 0 {
+		values.Set("txid", JoinStrings(txids, ","))
 	}
 
-	result, err := k.SendAuthenticatedHTTPRequest(KRAKEN_QUERY_ORDERS, values)
+	result := make(map[string]KrakenOrder)
+	err := k.SendAuthenticatedHTTPRequest(KRAKEN_QUERY_ORDERS, values, &result)
 
 	if err != nil {
-		log.Println(err)
-		return
+		return nil, err
 	}
 
-	log.Println(result)
+	return result, nil
+}
+
+type KrakenTradeInfo struct {
+	OrderTxID      string  `json:"ordertxid"`
+	PositionTxID   string  `json:"postxid"`
+	Pair           string  `json:"pair"`
+	Time           float64 `json:"time"`
+	Type           string  `json:"type"`
+	OrderType      string  `json:"ordertype"`
+	Price          float64 `json:"price,string"`
+	Cost           float64 `json:"cost,string"`
+	Fee            float64 `json:"fee,string"`
+	Volume         float64 `json:"vol,string"`
+	Margin         float64 `json:"margin,string"`
+	Misc           string  `json:"misc"`
+	PositionStatus string  `json:"posstatus"` // only for trades opening a position
+}
+
+type KrakenTradesHistory struct {
+	Trades map[string]KrakenTradeInfo `json:"trades"`
+	Count  int64                      `json:"count"`
 }
 
-func (k *Kraken) GetTradesHistory(tradeType string, showRelatedTrades bool, start, end, offset int64) {
+func (k *Kraken) GetTradesHistory(tradeType string, showRelatedTrades bool, start, end, offset int64) (KrakenTradesHistory, error) {
 	values := url.Values{}
 
 	if len(tradeType) > 0 {
-		values.Set("aclass", tradeType)
+		values.Set("type", tradeType)
 	}
 
 	if showRelatedTrades {
@@ -426,56 +794,96 @@ func (k *Kraken) GetTradesHistory(tradeType string, showRelatedTrades bool, star
 	}
 
 	if offset != 0 {
-		values.Set("offset", strconv.FormatInt(offset, 10))
+		values.Set("ofs", strconv.FormatInt(offset, 10))
 	}
 
-	result, err := k.SendAuthenticatedHTTPRequest(KRAKEN_TRADES_HISTORY, values)
+	result := KrakenTradesHistory{}
+	err := k.SendAuthenticatedHTTPRequest(KRAKEN_TRADES_HISTORY, values, &result)
 
 	if err != nil {
-		log.Println(err)
-		return
+		return result, err
 	}
 
-	log.Println(result)
+	return result, nil
 }
 
-func (k *Kraken) QueryTrades(txid int64, showRelatedTrades bool) {
+func (k *Kraken) QueryTrades(showRelatedTrades bool, txids ...string) (map[string]KrakenTradeInfo, error) {
 	values := url.Values{}
-	values.Set("txid", strconv.FormatInt(txid, 10))
+	values.Set("txid", JoinStrings(txids, ","))
 
 	if showRelatedTrades {
 		values.Set("trades", "true")
 	}
 
-	result, err := k.SendAuthenticatedHTTPRequest(KRAKEN_QUERY_TRADES, values)
+	result := make(map[string]KrakenTradeInfo)
+	err := k.SendAuthenticatedHTTPRequest(KRAKEN_QUERY_TRADES, values, &result)
 
 	if err != nil {
-		log.Println(err)
-		return
+		return nil, err
 	}
 
-	log.Println(result)
+	return result, nil
+}
+
+type KrakenPosition struct {
+	OrderTxID    string  `json:"ordertxid"`
+	Status       string  `json:"posstatus"`
+	Pair         string  `json:"pair"`
+	Time         float64 `json:"time"`
+	Type         string  `json:"type"`
+	OrderType    string  `json:"ordertype"`
+	Cost         float64 `json:"cost,string"`
+	Fee          float64 `json:"fee,string"`
+	Volume       float64 `json:"vol,string"`
+	VolumeClosed float64 `json:"vol_closed,string"`
+	Margin       float64 `json:"margin,string"`
+	Value        float64 `json:"value,string"` // only with showPL
+	Net          float64 `json:"net,string"`   // only with showPL
+	Terms        string  `json:"terms"`
+	Misc         string  `json:"misc"`
+	Flags        string  `json:"oflags"`
 }
 
-func (k *Kraken) OpenPositions(txid int64, showPL bool) {
+// OpenPositions returns the open margin positions keyed by the transaction
+// ID which opened them, all of them if no IDs are given.
+func (k *Kraken) OpenPositions(showPL bool, txids ...string) (map[string]KrakenPosition, error) {
 	values := url.Values{}
-	values.Set("txid", strconv.FormatInt(txid, 10))
+
+	if len(txids) > 0 {
+		values.Set("txid", JoinStrings(txids, ","))
+	}
 
 	if showPL {
 		values.Set("docalcs", "true")
 	}
 
-	result, err := k.SendAuthenticatedHTTPRequest(KRAKEN_OPEN_POSITIONS, values)
+	result := make(map[string]KrakenPosition)
+	err := k.SendAuthenticatedHTTPRequest(KRAKEN_OPEN_POSITIONS, values, &result)
 
 	if err != nil {
-		log.Println(err)
-		return
+		return nil, err
 	}
 
-	log.Println(result)
+	return result, nil
 }
 
-func (k *Kraken) GetLedgers(symbol, asset, ledgerType string, start, end, offset int64) {
+type KrakenLedger struct {
+	RefID   string  `json:"refid"`
+	Time    float64 `json:"time"`
+	Type    string  `json:"type"`
+	AClass  string  `json:"aclass"`
+	Asset   string  `json:"asset"`
+	Amount  float64 `json:"amount,string"`
+	Fee     float64 `json:"fee,string"`
+	Balance float64 `json:"balance,string"`
+}
+
+type KrakenLedgers struct {
+	Ledger map[string]KrakenLedger `json:"ledger"`
+	Count  int64                   `json:"count"`
+}
+
+func (k *Kraken) GetLedgers(symbol, asset, ledgerType string, start, end, offset int64) (KrakenLedgers, error) {
 	values := url.Values{}
 
 	if len(symbol) > 0 {
@@ -499,111 +907,197 @@ func (k *Kraken) GetLedgers(symbol, asset, ledgerType string, start, end, offset
 	}
 
 	if offset != 0 {
-		values.Set("offset", strconv.FormatInt(offset, 10))
+		values.Set("ofs", strconv.FormatInt(offset, 10))
 	}
 
-	result, err := k.SendAuthenticatedHTTPRequest(KRAKEN_LEDGERS, values)
+	result := KrakenLedgers{}
+	err := k.SendAuthenticatedHTTPRequest(KRAKEN_LEDGERS, values, &result)
 
 	if err != nil {
-		log.Println(err)
-		return
+		return result, err
 	}
 
-	log.Println(result)
+	return result, nil
 }
 
-func (k *Kraken) QueryLedgers(id string) {
+func (k *Kraken) QueryLedgers(ids ...string) (map[string]KrakenLedger, error) {
 	values := url.Values{}
-	values.Set("id", id)
+	values.Set("id", JoinStrings(ids, ","))
 
-	result, err := k.SendAuthenticatedHTTPRequest(KRAKEN_QUERY_LEDGERS, values)
+	result := make(map[string]KrakenLedger)
+	err := k.SendAuthenticatedHTTPRequest(KRAKEN_QUERY_LEDGERS, values, &result)
 
 	if err != nil {
-		log.Println(err)
-		return
+		return nil, err
 	}
 
-	log.Println(result)
+	return result, nil
+}
+
+type KrakenFee struct {
+	Fee        float64 `json:"fee,string"`
+	MinFee     float64 `json:"minfee,string"`
+	MaxFee     float64 `json:"maxfee,string"`
+	NextFee    float64 `json:"nextfee,string"`
+	NextVolume float64 `json:"nextvolume,string"`
+	TierVolume float64 `json:"tiervolume,string"`
 }
 
-func (k *Kraken) GetTradeVolume(symbol string) {
+type KrakenTradeVolume struct {
+	Currency  string               `json:"currency"`
+	Volume    float64              `json:"volume,string"`
+	Fees      map[string]KrakenFee `json:"fees"`
+	FeesMaker map[string]KrakenFee `json:"fees_maker"`
+}
+
+// GetTradeVolume returns the account's 30 day volume and its fees on the
+// given pairs.
+func (k *Kraken) GetTradeVolume(symbols ...string) (KrakenTradeVolume, error) {
 	values := url.Values{}
-	values.Set("pair", symbol)
 
-	result, err := k.SendAuthenticatedHTTPRequest(KRAKEN_TRADE_VOLUME, values)
+	if len(symbols) > 0 {
+		values.Set("pair", JoinStrings(symbols, ","))
+		values.Set("fee-info", "true")
+	}
+
+	result := KrakenTradeVolume{}
+	err := k.SendAuthenticatedHTTPRequest(KRAKEN_TRADE_VOLUME, values, &result)
 
 	if err != nil {
-		log.Println(err)
-		return
+		return result, err
 	}
 
-	log.Println(result)
+	return result, nil
 }
 
-func (k *Kraken) AddOrder(symbol, side, orderType string, price, price2, volume, leverage, position float64) {
+type KrakenOrderRequest struct {
+	Pair      string
+	Side      string // KRAKEN_ORDER_BUY or KRAKEN_ORDER_SELL
+	OrderType string // KRAKEN_ORDER_LIMIT etc.
+	Volume    float64
+	Price     float64
+	Price2    float64  // the limit price of stop-loss-limit and take-profit-limit orders
+	Leverage  float64  // on margin when above 1
+	Flags     []string // KRAKEN_ORDER_FLAG_*
+	UserRef   int64
+	Validate  bool // check the order without placing it
+
+	// a close order placed for the volume filled, e.g. a stop-loss
+	CloseOrderType string
+	ClosePrice     float64
+	ClosePrice2    float64
+}
+
+type KrakenAddOrderResponse struct {
+	Description    KrakenOrderDescription `json:"descr"`
+	TransactionIDs []string               `json:"txid"`
+}
+
+func (k *Kraken) AddOrder(order KrakenOrderRequest) (KrakenAddOrderResponse, error) {
 	values := url.Values{}
-	values.Set("pairs", symbol)
-	values.Set("type", side)
-	values.Set("ordertype", orderType)
-	values.Set("price", strconv.FormatFloat(price, 'f', -1, 64))
-	values.Set("price2", strconv.FormatFloat(price, 'f', -1, 64))
-	values.Set("volume", strconv.FormatFloat(volume, 'f', -1, 64))
-	values.Set("leverage", strconv.FormatFloat(leverage, 'f', -1, 64))
-	values.Set("position", strconv.FormatFloat(position, 'f', -1, 64))
+	values.Set("pair", order.Pair)
+	values.Set("type", order.Side)
+	values.Set("ordertype", order.OrderType)
+	values.Set("volume", strconv.FormatFloat(order.Volume, 'f', -1, 64))
+
+	if order.Price != 0 {
+		values.Set("price", strconv.FormatFloat(order.Price, 'f', -1, 64))
+	}
+
+	if order.Price2 != 0 {
+		values.Set("price2", strconv.FormatFloat(order.Price2, 'f', -1, 64))
+	}
 
-	result, err := k.SendAuthenticatedHTTPRequest(KRAKEN_ORDER_PLACE, values)
+	if order.Leverage > 1 {
+		values.Set("leverage", strconv.FormatFloat(order.Leverage, 'f', -1, 64))
+	}
+
+	if len(order.Flags) > 0 {
+		values.Set("oflags", JoinStrings(order.Flags, ","))
+	}
+
+	if order.UserRef != 0 {
+		values.Set("userref", strconv.FormatInt(order.UserRef, 10))
+	}
+
+	if order.Validate {
+		values.Set("validate", "true")
+	}
+
+	if len(order.CloseOrderType) > 0 {
+		values.Set("close[ordertype]", order.CloseOrderType)
+		values.Set("close[price]", strconv.FormatFloat(order.ClosePrice, 'f', -1, 64))
+		if order.ClosePrice2 != 0 {
+			values.Set("close[price2]", strconv.FormatFloat(order.ClosePrice2, 'f', -1, 64))
+		}
+	}
+
+	result := KrakenAddOrderResponse{}
+	err := k.SendAuthenticatedHTTPRequest(KRAKEN_ORDER_PLACE, values, &result)
 
 	if err != nil {
-		log.Println(err)
-		return
+		return result, err
 	}
 
-	log.Println(result)
+	return result, nil
+}
+
+type KrakenCancelOrderResponse struct {
+	Count   int64 `json:"count"`
+	Pending bool  `json:"pending"`
 }
 
-func (k *Kraken) CancelOrder(orderID int64) {
+// CancelOrder cancels the order with the given transaction ID, or every
+// order with the given user reference.
+func (k *Kraken) CancelOrder(txid string) (KrakenCancelOrderResponse, error) {
 	values := url.Values{}
-	values.Set("txid", strconv.FormatInt(orderID, 10))
+	values.Set("txid", txid)
 
-	result, err := k.SendAuthenticatedHTTPRequest(KRAKEN_ORDER_CANCEL, values)
+	result := KrakenCancelOrderResponse{}
+	err := k.SendAuthenticatedHTTPRequest(KRAKEN_ORDER_CANCEL, values, &result)
 
 	if err != nil {
-		log.Println(err)
-		return
+		return result, err
 	}
 
-	log.Println(result)
+	return result, nil
 }
 
-func (k *Kraken) SendAuthenticatedHTTPRequest(method string, values url.Values) (interface{}, error) {
+func (k *Kraken) SendAuthenticatedHTTPRequest(method string, values url.Values, result interface{}) error {
 	path := fmt.Sprintf("/%s/private/%s", KRAKEN_API_VERSION, method)
-	values.Set("nonce", strconv.FormatInt(time.Now().UnixNano(), 10))
+	values.Set("nonce", strconv.FormatInt(Nonces.Next(NonceKey(k.Name, k.ClientKey), time.Nanosecond), 10))
 	secret, err := Base64Decode(k.APISecret)
 
 	if err != nil {
-		return nil, err
+		return err
 	}
 
 	shasum := GetSHA256([]byte(values.Get("nonce") + values.Encode()))
 	signature := Base64Encode(GetHMAC(HASH_SHA512, append([]byte(path), shasum...), secret))
 
 	if k.Verbose {
-		log.Printf("Sending POST request to %s, path: %s.", KRAKEN_API_URL, path)
+		log.Printf("Sending POST request to %s, path: %s.", k.APIUrl, path)
 	}
 
 	headers := make(map[string]string)
 	headers["API-Key"] = k.ClientKey
 	headers["API-Sign"] = signature
+	headers["Content-Type"] = "application/x-www-form-urlencoded"
 
-	resp, err := SendHTTPRequest("POST", KRAKEN_API_URL+path, headers, strings.NewReader(values.Encode()))
+	resp, err := GetHTTPClient(k.Name).SendAuthenticatedHTTPRequest("POST", k.APIUrl+path, headers, strings.NewReader(values.Encode()))
 
 	if err != nil {
-		return nil, err
+		return ClassifyExchangeError(k.Name, err)
 	}
 
 	if k.Verbose {
 		log.Printf("Recieved raw: \n%s\n", resp)
 	}
 
-	return resp, nil
+	response := KrakenResponse{}
+	if err := json.Unmarshal([]byte(resp), &response); err != nil {
+		return errors.New("Unable to JSON Unmarshal response.")
+	}
+
+	return k.decodeResponse(response, result)
 }
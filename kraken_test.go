@@ -0,0 +1,154 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"testing"
+)
+
+func krakenFixtures(t *testing.T) (*Kraken, *fixtureServer) {
+	k := &Kraken{}
+	k.SetDefaults()
+	k.SetAPIKeys("kraken-key", Base64Encode([]byte("kraken-secret")))
+	return k, newFixtureServer(t, k.Name, "kraken.json")
+}
+
+func TestKrakenPairNames(t *testing.T) {
+	for _, x := range []struct{ pair, name, alt string }{
+		{"BTCUSD", "XXBTZUSD", "XBTUSD"},
+		{"XBTUSD", "XXBTZUSD", "XBTUSD"},
+		{"ETH_EUR", "XETHZEUR", "ETHEUR"},
+		{"ltcxbt", "XLTCXXBT", "LTCXBT"},
+		{"DASHEUR", "DASHEUR", "DASHEUR"},
+	} {
+		if name := KrakenPairName(x.pair); name != x.name {
+			t.Error(fmt.Sprintf("Test failed. Expected %s for %s. Actual %s", x.name, x.pair, name))
+		}
+		if alt := KrakenAltPairName(KrakenPairName(x.pair)); alt != x.alt {
+			t.Error(fmt.Sprintf("Test failed. Expected %s for %s. Actual %s", x.alt, x.pair, alt))
+		}
+	}
+}
+
+func TestKrakenPublicFixtures(t *testing.T) {
+	k, f := krakenFixtures(t)
+	defer f.Close()
+
+	runFixtureCases(t, f, []fixtureCase{
+		{Name: "GetServerTime", Call: func() (interface{}, error) { return k.GetServerTime() },
+			Want: []string{"UnixTime:1457623014"}},
+		{Name: "GetTicker", Call: func() (interface{}, error) {
+			err := k.GetTicker("XBTUSD")
+			return k.Ticker["XBTUSD"], err
+		},
+			Params: map[string]string{"pair": "XBTUSD"},
+			Want:   []string{"{Ask:455 Bid:454.999 Last:455 Volume:3051.2233541 VWAP:451.59413 Trades:3972 Low:447.442 High:457.091 Open:452.012}"}},
+		{Name: "GetOHLC", Call: func() (interface{}, error) {
+			candles, last, err := k.GetOHLC("XBTUSD", 1, 0)
+			return []interface{}{candles, last}, err
+		},
+			Params: map[string]string{"pair": "XBTUSD", "interval": "1"},
+			Want:   []string{"{Time:1457622600 Open:454.999 High:455 Low:454.5 Close:454.888 VWAP:454.81234 Volume:12.4311 Count:37}", "] 1457622600]"}},
+		{Name: "GetDepth", Call: func() (interface{}, error) { return k.GetDepth("XBTUSD", 2) },
+			Params: map[string]string{"pair": "XBTUSD", "count": "2"},
+			Want:   []string{"Bids:[{Price:454.999 Amount:2 Timestamp:1457622990}]", "{Price:455.5 Amount:4.25 Timestamp:1457622901}"}},
+		{Name: "GetTrades", Call: func() (interface{}, error) {
+			trades, last, err := k.GetTrades("XBTUSD", "")
+			return []interface{}{trades, last}, err
+		},
+			Want: []string{"{Price:454.999 Volume:0.01 Time:1.4576229891234e+09 Buy:false Market:false Misc:}", "Buy:true Market:true", "1457622993567812345"}},
+		{Name: "GetSpread", Call: func() (interface{}, error) {
+			spreads, _, err := k.GetSpread("XBTUSD", 0)
+			return spreads, err
+		},
+			Want: []string{"{Time:1457622990 Bid:454.999 Ask:455}"}},
+		{Name: "GetSpread unavailable", Call: func() (interface{}, error) {
+			spreads, _, err := k.GetSpread("XBTEUR", 0)
+			return spreads, err
+		},
+			Err: ERROR_TRANSIENT},
+	})
+}
+
+func TestKrakenAuthenticatedFixtures(t *testing.T) {
+	k, f := krakenFixtures(t)
+	defer f.Close()
+
+	runFixtureCases(t, f, []fixtureCase{
+		{Name: "GetBalance", Call: func() (interface{}, error) { return k.GetBalance() },
+			Want: []string{"XXBT:1011.19088779", "ZUSD:171288.6158"}},
+		{Name: "GetTradeBalance", Call: func() (interface{}, error) { return k.GetTradeBalance("", "ZUSD") },
+			Params: map[string]string{"asset": "ZUSD"},
+			Want:   []string{"EquivalentBalance:466432.3214", "FreeMargin:169931.8158 MarginLevel:12511.36"}},
+		{Name: "GetOpenOrders", Call: func() (interface{}, error) { return k.GetOpenOrders(true, 42) },
+			Params: map[string]string{"trades": "true", "userref": "42"},
+			Want:   []string{"OQCLML-BW3P3-BUCMWZ:{RefID: UserRef:42 Status:open", "Leverage:2:1", "Volume:1.25 VolumeExecuted:0.25", "Flags:fciq,post"}},
+		{Name: "GetClosedOrders", Call: func() (interface{}, error) { return k.GetClosedOrders(true, 0, 1457622000, 0, 0, "close") },
+			Params: map[string]string{"start": "1457622000", "closetime": "close"},
+			Want:   []string{"OB5VMB-B4U2U-DK2WRW:{", "CloseTime:1.4576221014e+09", "Trades:[TCCCTY-WE2O6-P3NB37]", "Count:1"}},
+		{Name: "QueryOrdersInfo", Call: func() (interface{}, error) {
+			return k.QueryOrdersInfo(false, 0, "OB5VMB-B4U2U-DK2WRW", "OQCLML-BW3P3-BUCMWZ")
+		},
+			Params: map[string]string{"txid": "OB5VMB-B4U2U-DK2WRW,OQCLML-BW3P3-BUCMWZ"},
+			Want:   []string{"Status:closed", "Price:454.5"}},
+		{Name: "GetTradesHistory", Call: func() (interface{}, error) { return k.GetTradesHistory("all", false, 0, 0, 50) },
+			Params: map[string]string{"type": "all", "ofs": "50"},
+			Want:   []string{"TCCCTY-WE2O6-P3NB37:{OrderTxID:OB5VMB-B4U2U-DK2WRW", "Pair:XXBTZUSD", "Volume:0.5"}},
+		{Name: "OpenPositions", Call: func() (interface{}, error) { return k.OpenPositions(true) },
+			Params: map[string]string{"docalcs": "true"},
+			Want:   []string{"TF5GVO-T7ZZ2-6NBKBI:{OrderTxID:OQCLML-BW3P3-BUCMWZ Status:open", "Margin:56.25 Value:113.7 Net:1.2"}},
+		{Name: "GetLedgers", Call: func() (interface{}, error) { return k.GetLedgers("", "ZUSD", "trade", 0, 0, 0) },
+			Params: map[string]string{"asset": "ZUSD", "type": "trade"},
+			Want:   []string{"LXXURB-ITI7S-CXVERS:{RefID:TCCCTY-WE2O6-P3NB37", "Amount:227.25 Fee:0.5909 Balance:171288.6158"}},
+		{Name: "GetTradeVolume", Call: func() (interface{}, error) { return k.GetTradeVolume("XBTUSD") },
+			Params: map[string]string{"pair": "XBTUSD", "fee-info": "true"},
+			Want:   []string{"Volume:10523.12", "XXBTZUSD:{Fee:0.26 MinFee:0.1 MaxFee:0.26 NextFee:0.24", "XXBTZUSD:{Fee:0.16 MinFee:0 MaxFee:0.16 NextFee:0 NextVolume:0"}},
+		{Name: "AddOrder", Call: func() (interface{}, error) {
+			return k.AddOrder(KrakenOrderRequest{
+				Pair:           "XBTUSD",
+				Side:           KRAKEN_ORDER_BUY,
+				OrderType:      KRAKEN_ORDER_LIMIT,
+				Volume:         1.25,
+				Price:          455,
+				Leverage:       2,
+				Flags:          []string{KRAKEN_ORDER_FLAG_POST_ONLY, KRAKEN_ORDER_FLAG_FEE_IN_QUOTE},
+				CloseOrderType: KRAKEN_ORDER_STOP_LOSS,
+				ClosePrice:     430,
+			})
+		},
+			Params: map[string]string{"pair": "XBTUSD", "type": "buy", "ordertype": "limit", "price": "455", "price2": "", "volume": "1.25", "leverage": "2", "oflags": "post,fciq", "close[ordertype]": "stop-loss", "close[price]": "430"},
+			Want:   []string{"TransactionIDs:[OAVY7T-MV5VK-KHDF5X]", "Close:close position @ stop loss 430.00000"}},
+		{Name: "AddOrder validate", Call: func() (interface{}, error) {
+			return k.AddOrder(KrakenOrderRequest{Pair: "XBTUSD", Side: KRAKEN_ORDER_SELL, OrderType: KRAKEN_ORDER_MARKET, Volume: 2, Validate: true})
+		},
+			Params: map[string]string{"validate": "true", "price": "", "leverage": ""},
+			Want:   []string{"Order:sell 2.00000000 XBTUSD @ market"}},
+		{Name: "AddOrder insufficient", Call: func() (interface{}, error) {
+			return k.AddOrder(KrakenOrderRequest{Pair: "XBTUSD", Side: KRAKEN_ORDER_BUY, OrderType: KRAKEN_ORDER_MARKET, Volume: 1000})
+		},
+			Err: ERROR_INSUFFICIENT_FUNDS},
+		{Name: "CancelOrder", Call: func() (interface{}, error) { return k.CancelOrder("OAVY7T-MV5VK-KHDF5X") },
+			Params: map[string]string{"txid": "OAVY7T-MV5VK-KHDF5X"},
+			Want:   []string{"{Count:1 Pending:false}"}},
+		{Name: "CancelOrder unknown", Call: func() (interface{}, error) { return k.CancelOrder("OUNKNO-WN000-000000") },
+			Err: ERROR_ORDER_NOT_FOUND},
+	})
+}
+
+func TestKrakenSigning(t *testing.T) {
+	k, f := krakenFixtures(t)
+	defer f.Close()
+
+	if err := k.SendAuthenticatedHTTPRequest(KRAKEN_BALANCE, url.Values{}, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	req := f.Last()
+	message := append([]byte("/0/private/Balance"), GetSHA256([]byte(req.Form().Get("nonce")+req.Body))...)
+	if sign := Base64Encode(GetHMAC(HASH_SHA512, message, []byte("kraken-secret"))); req.Header.Get("API-Sign") != sign {
+		t.Error(fmt.Sprintf("Test failed. Expected signature %s. Actual %s", sign, req.Header.Get("API-Sign")))
+	}
+	if req.Header.Get("API-Key") != "kraken-key" || req.Header.Get("Content-Type") != "application/x-www-form-urlencoded" {
+		t.Error(fmt.Sprintf("Test failed. Unexpected headers %v", req.Header))
+	}
+}
@@ -1,7 +1,9 @@
 package main
 
 import (
+	"encoding/json"
 	"errors"
+	"fmt"
 	"log"
 	"net/url"
 	"strconv"
@@ -29,8 +31,10 @@ type LakeBTC struct {
 	Enabled                 bool
 	Verbose                 bool
 	Websocket               bool
+	WebsocketURL            string
 	RESTPollingDelay        time.Duration
 	AuthenticatedAPISupport bool
+	APIUrl                  string
 	Email, APISecret        string
 	TakerFee, MakerFee      float64
 	BaseCurrencies          []string
@@ -48,8 +52,8 @@ type LakeBTCTicker struct {
 }
 
 type LakeBTCOrderbook struct {
-	Bids [][]float64 `json:"asks"`
-	Asks [][]float64 `json:"bids"`
+	Bids [][]float64 `json:"bids"`
+	Asks [][]float64 `json:"asks"`
 }
 
 type LakeBTCTickerResponse struct {
@@ -57,6 +61,41 @@ type LakeBTCTickerResponse struct {
 	CNY LakeBTCTicker
 }
 
+type LakeBTCTradeHistory struct {
+	Date   int64   `json:"date"`
+	Price  float64 `json:"price"`
+	Amount float64 `json:"amount"`
+	TID    int64   `json:"tid"`
+}
+
+type LakeBTCAccountInfo struct {
+	Balance map[string]float64 `json:"balance"`
+	Locked  map[string]float64 `json:"locked"`
+	Profile struct {
+		Email             string `json:"email"`
+		UID               string `json:"uid"`
+		BTCDepositAddress string `json:"btc_deposit_addres"`
+	} `json:"profile"`
+}
+
+type LakeBTCOrder struct {
+	ID       int64   `json:"id"`
+	Amount   float64 `json:"amount"`
+	Price    float64 `json:"price"`
+	Category string  `json:"category"`
+	Currency string  `json:"currency"`
+	State    string  `json:"state"`
+	At       int64   `json:"at"`
+}
+
+type LakeBTCAuthenticatedTrade struct {
+	Type   string  `json:"type"`
+	Symbol string  `json:"symbol"`
+	Amount float64 `json:"amount"`
+	Total  float64 `json:"total"`
+	At     int64   `json:"at"`
+}
+
 func (l *LakeBTC) SetDefaults() {
 	l.Name = "LakeBTC"
 	l.Enabled = false
@@ -65,6 +104,8 @@ func (l *LakeBTC) SetDefaults() {
 	l.Verbose = false
 	l.Websocket = false
 	l.RESTPollingDelay = 10
+	l.APIUrl = LAKEBTC_API_URL
+	l.WebsocketURL = LAKEBTC_WEBSOCKET_URL
 }
 
 func (l *LakeBTC) GetName() string {
@@ -92,6 +133,8 @@ func (l *LakeBTC) Setup(exch Exchanges) {
 		l.BaseCurrencies = SplitStrings(exch.BaseCurrencies, ",")
 		l.AvailablePairs = SplitStrings(exch.AvailablePairs, ",")
 		l.EnabledPairs = SplitStrings(exch.EnabledPairs, ",")
+		urls := GetExchangeURLs(exch, ExchangeURLs{l.APIUrl, l.WebsocketURL})
+		l.APIUrl, l.WebsocketURL = urls.API, urls.Websocket
 	}
 }
 
@@ -114,7 +157,7 @@ func (l *LakeBTC) GetFee(maker bool) float64 {
 
 func (l *LakeBTC) Run() {
 	if l.Verbose {
-		log.Printf("%s Websocket: %s. (url: %s).\n", l.GetName(), IsEnabled(l.Websocket), LAKEBTC_WEBSOCKET_URL)
+		log.Printf("%s Websocket: %s. (url: %s).\n", l.GetName(), IsEnabled(l.Websocket), l.WebsocketURL)
 		log.Printf("%s polling delay: %ds.\n", l.GetName(), l.RESTPollingDelay)
 		log.Printf("%s %d currencies enabled: %s.\n", l.GetName(), len(l.EnabledPairs), l.EnabledPairs)
 	}
@@ -140,7 +183,7 @@ func (l *LakeBTC) Run() {
 
 func (l *LakeBTC) GetTicker() LakeBTCTickerResponse {
 	response := LakeBTCTickerResponse{}
-	err := SendHTTPGetRequest(LAKEBTC_API_URL+LAKEBTC_TICKER, true, &response)
+	err := GetHTTPClient(l.Name).SendHTTPGetRequest(l.APIUrl+LAKEBTC_TICKER, true, &response)
 	if err != nil {
 		log.Println(err)
 		return response
@@ -148,82 +191,87 @@ func (l *LakeBTC) GetTicker() LakeBTCTickerResponse {
 	return response
 }
 
-func (l *LakeBTC) GetOrderBook(currency string) bool {
+func (l *LakeBTC) GetOrderBook(currency string) (LakeBTCOrderbook, error) {
 	req := LAKEBTC_ORDERBOOK
 	if currency == "CNY" {
 		req = LAKEBTC_ORDERBOOK_CNY
 	}
 
-	err := SendHTTPGetRequest(LAKEBTC_API_URL+req, true, nil)
+	orderbook := LakeBTCOrderbook{}
+	err := GetHTTPClient(l.Name).SendHTTPGetRequest(l.APIUrl+req, true, &orderbook)
 	if err != nil {
-		log.Println(err)
-		return false
+		return orderbook, ClassifyExchangeError(l.Name, err)
 	}
-	return true
+	return orderbook, nil
 }
 
-func (l *LakeBTC) GetTradeHistory() bool {
-	err := SendHTTPGetRequest(LAKEBTC_API_URL+LAKEBTC_TRADES, true, nil)
+func (l *LakeBTC) GetTradeHistory() ([]LakeBTCTradeHistory, error) {
+	trades := []LakeBTCTradeHistory{}
+	err := GetHTTPClient(l.Name).SendHTTPGetRequest(l.APIUrl+LAKEBTC_TRADES, true, &trades)
 	if err != nil {
-		log.Println(err)
-		return false
+		return nil, ClassifyExchangeError(l.Name, err)
 	}
-	return true
+	return trades, nil
 }
 
-func (l *LakeBTC) GetAccountInfo() {
-	err := l.SendAuthenticatedHTTPRequest(LAKEBTC_GET_ACCOUNT_INFO, "")
-
-	if err != nil {
-		log.Println(err)
-	}
+func (l *LakeBTC) GetAccountInfo() (LakeBTCAccountInfo, error) {
+	info := LakeBTCAccountInfo{}
+	err := l.SendAuthenticatedHTTPRequest(LAKEBTC_GET_ACCOUNT_INFO, "", &info)
+	return info, err
 }
 
-func (l *LakeBTC) Trade(orderType int, amount, price float64, currency string) {
+// Trade places a buy order if orderType is 0 and a sell order otherwise,
+// returning its ID.
+func (l *LakeBTC) Trade(orderType int, amount, price float64, currency string) (int64, error) {
 	params := strconv.FormatFloat(price, 'f', -1, 64) + "," + strconv.FormatFloat(amount, 'f', -1, 64) + "," + currency
-	err := errors.New("")
-
-	if orderType == 0 {
-		err = l.SendAuthenticatedHTTPRequest(LAKEBTC_BUY_ORDER, params)
-	} else {
-		err = l.SendAuthenticatedHTTPRequest(LAKEBTC_SELL_ORDER, params)
+	method := LAKEBTC_BUY_ORDER
+	if orderType != 0 {
+		method = LAKEBTC_SELL_ORDER
 	}
 
-	if err != nil {
-		log.Println(err)
-	}
+	resp := struct {
+		ID     int64  `json:"id"`
+		Result string `json:"result"`
+	}{}
+	err := l.SendAuthenticatedHTTPRequest(method, params, &resp)
+	return resp.ID, err
 }
 
-func (l *LakeBTC) GetOrders() {
-	err := l.SendAuthenticatedHTTPRequest(LAKEBTC_GET_ORDERS, "")
-	if err != nil {
-		log.Println(err)
-	}
+func (l *LakeBTC) GetOrders() ([]LakeBTCOrder, error) {
+	orders := []LakeBTCOrder{}
+	err := l.SendAuthenticatedHTTPRequest(LAKEBTC_GET_ORDERS, "", &orders)
+	return orders, err
 }
 
-func (l *LakeBTC) CancelOrder(orderID int64) {
+func (l *LakeBTC) CancelOrder(orderID int64) error {
 	params := strconv.FormatInt(orderID, 10)
-	err := l.SendAuthenticatedHTTPRequest(LAKEBTC_CANCEL_ORDER, params)
+	resp := struct {
+		Result bool `json:"result"`
+	}{}
+	err := l.SendAuthenticatedHTTPRequest(LAKEBTC_CANCEL_ORDER, params, &resp)
 	if err != nil {
-		log.Println(err)
+		return err
 	}
+	if !resp.Result {
+		return NewExchangeError(l.Name, "", fmt.Sprintf("unable to cancel order %d", orderID))
+	}
+	return nil
 }
 
-func (l *LakeBTC) GetTrades(timestamp time.Time) {
+func (l *LakeBTC) GetTrades(timestamp time.Time) ([]LakeBTCAuthenticatedTrade, error) {
 	params := ""
 
 	if !timestamp.IsZero() {
 		params = strconv.FormatInt(timestamp.Unix(), 10)
 	}
 
-	err := l.SendAuthenticatedHTTPRequest(LAKEBTC_GET_TRADES, params)
-	if err != nil {
-		log.Println(err)
-	}
+	trades := []LakeBTCAuthenticatedTrade{}
+	err := l.SendAuthenticatedHTTPRequest(LAKEBTC_GET_TRADES, params, &trades)
+	return trades, err
 }
 
-func (l *LakeBTC) SendAuthenticatedHTTPRequest(method, params string) (err error) {
-	nonce := strconv.FormatInt(time.Now().Unix(), 10)
+func (l *LakeBTC) SendAuthenticatedHTTPRequest(method, params string, result interface{}) (err error) {
+	nonce := strconv.FormatInt(Nonces.Next(NonceKey(l.Name, l.Email), time.Second), 10)
 	v := url.Values{}
 	v.Set("tnonce", nonce)
 	v.Set("accesskey", l.Email)
@@ -236,23 +284,50 @@ func (l *LakeBTC) SendAuthenticatedHTTPRequest(method, params string) (err error
 	hmac := GetHMAC(HASH_SHA256, []byte(encoded), []byte(l.APISecret))
 
 	if l.Verbose {
-		log.Printf("Sending POST request to %s calling method %s with params %s\n", LAKEBTC_API_URL, method, encoded)
+		log.Printf("Sending POST request to %s calling method %s with params %s\n", l.APIUrl, method, encoded)
 	}
 
 	headers := make(map[string]string)
 	headers["Json-Rpc-Tonce"] = nonce
-	headers["Authorization: Basic"] = Base64Encode([]byte(l.Email + ":" + HexEncodeToString(hmac)))
+	headers["Authorization"] = "Basic " + Base64Encode([]byte(l.Email+":"+HexEncodeToString(hmac)))
 	headers["Content-Type"] = "application/x-www-form-urlencoded"
 
-	resp, err := SendHTTPRequest("POST", LAKEBTC_API_URL, headers, strings.NewReader(encoded))
+	resp, err := GetHTTPClient(l.Name).SendAuthenticatedHTTPRequest("POST", l.APIUrl, headers, strings.NewReader(encoded))
 
 	if err != nil {
-		return err
+		return ClassifyExchangeError(l.Name, err)
 	}
 
 	if l.Verbose {
 		log.Printf("Recieved raw: %s\n", resp)
 	}
 
+	// errors come back as {"error": "message"}, or with a code and message
+	failure := struct {
+		Error interface{} `json:"error"`
+	}{}
+	if json.Unmarshal([]byte(resp), &failure) == nil && failure.Error != nil {
+		code, message := "", fmt.Sprint(failure.Error)
+		if x, ok := failure.Error.(map[string]interface{}); ok {
+			if c, ok := x["code"]; ok {
+				code = fmt.Sprint(c)
+			}
+			if m, ok := x["message"]; ok {
+				message = fmt.Sprint(m)
+			}
+		}
+		return NewExchangeError(l.Name, code, message)
+	}
+
+	if result == nil {
+		return nil
+	}
+
+	err = JSONDecode([]byte(resp), result)
+
+	if err != nil {
+		return errors.New("Unable to JSON Unmarshal response.")
+	}
+
 	return nil
 }
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func lakebtcFixtures(t *testing.T) (*LakeBTC, *fixtureServer) {
+	l := &LakeBTC{}
+	l.SetDefaults()
+	l.SetAPIKeys("lakebtc@example.com", "lakebtc-secret")
+	return l, newFixtureServer(t, l.Name, "lakebtc.json")
+}
+
+func TestLakeBTCPublicFixtures(t *testing.T) {
+	l, f := lakebtcFixtures(t)
+	defer f.Close()
+
+	runFixtureCases(t, f, []fixtureCase{
+		{Name: "GetTicker", Call: func() (interface{}, error) { return l.GetTicker(), nil },
+			Want: []string{"{USD:{Last:586.98 Bid:586.5 Ask:587.2 High:592.5 Low:580.1 Volume:2153.1} CNY:{Last:3616.31"}},
+		{Name: "GetOrderBook", Call: func() (interface{}, error) { return l.GetOrderBook("USD") },
+			Want: []string{"{Bids:[[586.5 0.8]] Asks:[[587.2 1.5]]}"}},
+		{Name: "GetOrderBook CNY", Call: func() (interface{}, error) {
+			orderbook, err := l.GetOrderBook("CNY")
+			return fmt.Sprintf("%v %s", orderbook, f.Last().Path), err
+		},
+			Want: []string{"{[[3612.21 0.8]] [[3618.8 1.5]]} /api_v1/bcorderbook_cny"}},
+		{Name: "GetTradeHistory", Call: func() (interface{}, error) { return l.GetTradeHistory() },
+			Want: []string{"[{Date:1405062015 Price:586.98 Amount:0.1 TID:1}]"}},
+	})
+}
+
+func TestLakeBTCAuthenticatedFixtures(t *testing.T) {
+	l, f := lakebtcFixtures(t)
+	defer f.Close()
+
+	runFixtureCases(t, f, []fixtureCase{
+		{Name: "GetAccountInfo", Call: func() (interface{}, error) { return l.GetAccountInfo() },
+			Params: map[string]string{"method": "getAccountInfo", "params": "", "accesskey": "lakebtc@example.com", "requestmethod": "POST"},
+			Want:   []string{"Balance:map[BTC:1.5 USD:1000]", "BTCDepositAddress:1LakeBTCDepositAddress"}},
+		{Name: "Trade", Call: func() (interface{}, error) { return l.Trade(0, 0.1, 586.5, "USD") },
+			Params: map[string]string{"method": "buyOrder", "params": "586.5,0.1,USD"},
+			Want:   []string{"129"}},
+		{Name: "Trade sell", Call: func() (interface{}, error) { return l.Trade(1, 0.1, 587.2, "USD") },
+			Params: map[string]string{"method": "sellOrder", "params": "587.2,0.1,USD"},
+			Want:   []string{"130"}},
+		{Name: "Trade insufficient", Call: func() (interface{}, error) { return l.Trade(0, 1000, 586.5, "USD") },
+			Err: ERROR_INSUFFICIENT_FUNDS},
+		{Name: "GetOrders", Call: func() (interface{}, error) { return l.GetOrders() },
+			Want: []string{"[{ID:129 Amount:0.1 Price:586.5 Category:buy Currency:USD State:active At:1405062100}]"}},
+		{Name: "CancelOrder", Call: func() (interface{}, error) { return nil, l.CancelOrder(129) },
+			Params: map[string]string{"method": "cancelOrder", "params": "129"}},
+		{Name: "CancelOrder unknown", Call: func() (interface{}, error) { return nil, l.CancelOrder(404) },
+			Err: ERROR_ORDER_NOT_FOUND},
+		{Name: "GetTrades", Call: func() (interface{}, error) { return l.GetTrades(time.Unix(1405062000, 0)) },
+			Params: map[string]string{"method": "getTrades", "params": "1405062000"},
+			Want:   []string{"[{Type:buy Symbol:BTCUSD Amount:0.1 Total:58.65 At:1405062200}]"}},
+	})
+}
+
+func TestLakeBTCSigning(t *testing.T) {
+	l, f := lakebtcFixtures(t)
+	defer f.Close()
+
+	if _, err := l.Trade(0, 0.1, 586.5, "USD"); err != nil {
+		t.Fatal(err)
+	}
+
+	req := f.Last()
+	hmac := GetHMAC(HASH_SHA256, []byte(req.Body), []byte("lakebtc-secret"))
+	if auth := "Basic " + Base64Encode([]byte("lakebtc@example.com:"+HexEncodeToString(hmac))); req.Header.Get("Authorization") != auth {
+		t.Error(fmt.Sprintf("Test failed. Expected authorization %s. Actual %s", auth, req.Header.Get("Authorization")))
+	}
+	if tonce := req.Header.Get("Json-Rpc-Tonce"); tonce == "" || req.Form().Get("tnonce") != tonce {
+		t.Error(fmt.Sprintf("Test failed. Expected the tonce header to match the body. Actual %s %s", tonce, req.Body))
+	}
+}
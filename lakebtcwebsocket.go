@@ -41,31 +41,39 @@ func WSRailsPong(id string, conn *websocket.Conn) {
 	}
 }
 
+// WebsocketClient subscribes once websocket-rails reports the client as
+// connected, so the supervisor's subscriptions are replayed from there
+// rather than straight after dialling.
 func (l *LakeBTC) WebsocketClient() {
-	for l.Enabled && l.Websocket {
-		var Dialer websocket.Dialer
-		conn, _, err := Dialer.Dial(LAKEBTC_WEBSOCKET_URL, http.Header{})
-
-		if err != nil {
-			log.Printf("%s Unable to connect to Websocket. Error: %s\n", l.GetName(), err)
-			continue
-		}
-
-		log.Printf("%s Connected to Websocket.\n", l.GetName())
+	var conn *websocket.Conn
+	s := GetWebsocketSupervisor(l.GetName())
+	s.Subscribe(WebsocketSubscription{Channel: "ticker"})
+	for _, x := range l.EnabledPairs {
+		s.Subscribe(WebsocketSubscription{Channel: fmt.Sprintf("orderbook_%s", x[3:])})
+	}
 
+	s.Verbose = l.Verbose
+	s.Running = func() bool { return l.Enabled && l.Websocket }
+	s.Dial = func() error {
+		var Dialer websocket.Dialer
+		var err error
+		conn, _, err = Dialer.Dial(l.WebsocketURL, http.Header{})
+		return err
+	}
+	s.Close = func() error { return conn.Close() }
+	s.Serve = func() error {
 		for l.Enabled && l.Websocket {
 			msgType, resp, err := conn.ReadMessage()
 			if err != nil {
-				log.Println(err)
-				break
+				return err
 			}
+			s.Received()
 
 			response := [][]interface{}{}
 			err = JSONDecode(resp, &response)
 
 			if err != nil {
-				log.Println(err)
-				break
+				return err
 			}
 
 			if msgType == websocket.TextMessage {
@@ -74,10 +82,8 @@ func (l *LakeBTC) WebsocketClient() {
 
 				switch event {
 				case "client_connected":
-					WSRailsSubscribe("ticker", conn)
-					for _, x := range l.EnabledPairs {
-						currency := x[3:]
-						WSRailsSubscribe(fmt.Sprintf("orderbook_%s", currency), conn)
+					for _, x := range s.Subscriptions() {
+						WSRailsSubscribe(x.Channel, conn)
 					}
 				case "websocket_rails.subscribe":
 				case "websocket_rails.ping":
@@ -114,7 +120,7 @@ func (l *LakeBTC) WebsocketClient() {
 				}
 			}
 		}
-		conn.Close()
-		log.Printf("%s Websocket client disconnected.\n", l.GetName())
+		return nil
 	}
+	s.Run()
 }
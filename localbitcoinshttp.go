@@ -30,6 +30,7 @@ type LocalBitcoins struct {
 	Websocket                   bool
 	RESTPollingDelay            time.Duration
 	AuthenticatedAPISupport     bool
+	APIUrl                      string
 	Password, APIKey, APISecret string
 	TakerFee, MakerFee          float64
 	BaseCurrencies              []string
@@ -44,6 +45,7 @@ func (l *LocalBitcoins) SetDefaults() {
 	l.Verbose = false
 	l.Websocket = false
 	l.RESTPollingDelay = 10
+	l.APIUrl = LOCALBITCOINS_API_URL
 }
 
 func (l *LocalBitcoins) GetName() string {
@@ -71,6 +73,7 @@ func (l *LocalBitcoins) Setup(exch Exchanges) {
 		l.BaseCurrencies = SplitStrings(exch.BaseCurrencies, ",")
 		l.AvailablePairs = SplitStrings(exch.AvailablePairs, ",")
 		l.EnabledPairs = SplitStrings(exch.EnabledPairs, ",")
+		l.APIUrl = GetExchangeURLs(exch, ExchangeURLs{API: l.APIUrl}).API
 	}
 }
 
@@ -127,7 +130,7 @@ type LocalBitcoinsTicker struct {
 
 func (l *LocalBitcoins) GetTicker() (map[string]LocalBitcoinsTicker, error) {
 	result := make(map[string]LocalBitcoinsTicker)
-	err := SendHTTPGetRequest(LOCALBITCOINS_API_URL+LOCALBITCOINS_API_TICKER, true, &result)
+	err := GetHTTPClient(l.Name).SendHTTPGetRequest(l.APIUrl+LOCALBITCOINS_API_TICKER, true, &result)
 
 	if err != nil {
 		return result, err
@@ -144,9 +147,9 @@ type LocalBitcoinsTrade struct {
 }
 
 func (l *LocalBitcoins) GetTrades(currency string, values url.Values) ([]LocalBitcoinsTrade, error) {
-	path := EncodeURLValues(fmt.Sprintf("%s/%s/trades.json", LOCALBITCOINS_API_URL+LOCALBITCOINS_API_BITCOINCHARTS, currency), values)
+	path := EncodeURLValues(fmt.Sprintf("%s%s/trades.json", l.APIUrl+LOCALBITCOINS_API_BITCOINCHARTS, currency), values)
 	result := []LocalBitcoinsTrade{}
-	err := SendHTTPGetRequest(path, true, &result)
+	err := GetHTTPClient(l.Name).SendHTTPGetRequest(path, true, &result)
 
 	if err != nil {
 		return result, err
@@ -171,9 +174,9 @@ func (l *LocalBitcoins) GetOrderbook(currency string) (LocalBitcoinsOrderbook, e
 		Asks [][]string `json:"asks"`
 	}
 
-	path := fmt.Sprintf("%s/%s/orderbook.json", LOCALBITCOINS_API_URL+LOCALBITCOINS_API_BITCOINCHARTS, currency)
+	path := fmt.Sprintf("%s%s/orderbook.json", l.APIUrl+LOCALBITCOINS_API_BITCOINCHARTS, currency)
 	resp := response{}
-	err := SendHTTPGetRequest(path, true, &resp)
+	err := GetHTTPClient(l.Name).SendHTTPGetRequest(path, true, &resp)
 
 	if err != nil {
 		return LocalBitcoinsOrderbook{}, err
@@ -243,8 +246,8 @@ func (l *LocalBitcoins) GetAccountInfo(username string, self bool) (LocalBitcoin
 			return resp.Data, err
 		}
 	} else {
-		path := fmt.Sprintf("%s/api/account_info/%s/", LOCALBITCOINS_API_URL, username)
-		err := SendHTTPGetRequest(path, true, &resp)
+		path := fmt.Sprintf("%s/api/account_info/%s/", l.APIUrl, username)
+		err := GetHTTPClient(l.Name).SendHTTPGetRequest(path, true, &resp)
 
 		if err != nil {
 			return resp.Data, err
@@ -278,7 +281,7 @@ func (l *LocalBitcoins) CheckPincode(pin int) (bool, error) {
 
 type LocalBitcoinsBalance struct {
 	Balance  float64 `json:"balance,string"`
-	Sendable float64 `json:"Sendable,string"`
+	Sendable float64 `json:"sendable,string"`
 }
 
 type LocalBitcoinsWalletTransaction struct {
@@ -381,7 +384,7 @@ func (l *LocalBitcoins) GetWalletAddress() (string, error) {
 		Data struct {
 			Message string `json:"message"`
 			Address string `json:"address"`
-		}
+		} `json:"data"`
 	}
 	resp := response{}
 	err := l.SendAuthenticatedHTTPRequest("POST", LOCALBITCOINS_API_WALLET_ADDRESS, nil, &resp)
@@ -397,7 +400,7 @@ func (l *LocalBitcoins) GetWalletAddress() (string, error) {
 }
 
 func (l *LocalBitcoins) SendAuthenticatedHTTPRequest(method, path string, values url.Values, result interface{}) (err error) {
-	nonce := strconv.FormatInt(time.Now().UnixNano(), 10)
+	nonce := strconv.FormatInt(Nonces.Next(NonceKey(l.Name, l.APIKey), time.Nanosecond), 10)
 	payload := ""
 	path = "/api/" + path
 
@@ -413,12 +416,16 @@ func (l *LocalBitcoins) SendAuthenticatedHTTPRequest(method, path string, values
 	headers["Apiauth-Signature"] = StringToUpper(HexEncodeToString(hmac))
 	headers["Content-Type"] = "application/x-www-form-urlencoded"
 
-	resp, err := SendHTTPRequest(method, LOCALBITCOINS_API_URL+path, headers, bytes.NewBuffer([]byte(payload)))
+	resp, err := GetHTTPClient(l.Name).SendAuthenticatedHTTPRequest(method, l.APIUrl+path, headers, bytes.NewBuffer([]byte(payload)))
 
 	if l.Verbose {
 		log.Printf("Recieved raw: \n%s\n", resp)
 	}
 
+	if err != nil {
+		return ClassifyExchangeError(l.Name, err)
+	}
+
 	err = JSONDecode([]byte(resp), &result)
 
 	if err != nil {
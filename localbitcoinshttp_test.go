@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func localbitcoinsFixtures(t *testing.T) (*LocalBitcoins, *fixtureServer) {
+	l := &LocalBitcoins{}
+	l.SetDefaults()
+	l.SetAPIKeys("localbitcoins-key", "localbitcoins-secret")
+	return l, newFixtureServer(t, l.Name, "localbitcoins.json")
+}
+
+func TestLocalBitcoinsPublicFixtures(t *testing.T) {
+	l, f := localbitcoinsFixtures(t)
+	defer f.Close()
+
+	runFixtureCases(t, f, []fixtureCase{
+		{Name: "GetTicker", Call: func() (interface{}, error) { return l.GetTicker() },
+			Want: []string{"USD:{Avg12h:435.12 Avg1h:436.5 Avg24h:433.87 Rates:{Last:437.1} VolumeBTC:512.31}"}},
+		{Name: "GetTrades", Call: func() (interface{}, error) { return l.GetTrades("USD", url.Values{"since": {"1000"}}) },
+			Params: map[string]string{"since": "1000"},
+			Want:   []string{"[{TID:1001 Date:1459238810 Amount:0.5 Price:437.1}]"}},
+		{Name: "GetOrderbook", Call: func() (interface{}, error) { return l.GetOrderbook("USD") },
+			Want: []string{"{Bids:[{Price:436 Amount:1.25} {Price:435.5 Amount:0.4}] Asks:[{Price:438 Amount:0.75}]}"}},
+		{Name: "GetAccountInfo", Call: func() (interface{}, error) { return l.GetAccountInfo("satoshi", false) },
+			Want: []string{"Username:satoshi CreatedAt:2013-06-02 12:20:15 +0000 UTC", "TradingPartners:12", "FeedbackScore:100 FeedbackCount:11"}},
+	})
+}
+
+func TestLocalBitcoinsAuthenticatedFixtures(t *testing.T) {
+	l, f := localbitcoinsFixtures(t)
+	defer f.Close()
+
+	runFixtureCases(t, f, []fixtureCase{
+		{Name: "GetAccountInfo self", Call: func() (interface{}, error) { return l.GetAccountInfo("", true) },
+			Want: []string{"Username:localbitcoins-user", "FeedbackScore:99"}},
+		{Name: "CheckPincode", Call: func() (interface{}, error) { return l.CheckPincode(1234) },
+			Params: map[string]string{"pincode": "1234"},
+			Want:   []string{"true"}},
+		{Name: "GetWalletInfo", Call: func() (interface{}, error) { return l.GetWalletInfo() },
+			Want: []string{"Total:{Balance:1.5 Sendable:1.49}", "SentTransactions30d:[{TXID:a1b2c3 Amount:0.25", "ReceivingAddressList:[{Address:1LocalBTCReceive Received:2}]"}},
+		{Name: "GetWalletBalance", Call: func() (interface{}, error) { return l.GetWalletBalance() },
+			Want: []string{"{Message:OK Total:{Balance:1.5 Sendable:1.49} ReceivingAddressCount:1"}},
+		{Name: "WalletSend", Call: func() (interface{}, error) { return l.WalletSend("1LocalBTCAddress", 0.25, 0) },
+			Params: map[string]string{"address": "1LocalBTCAddress", "amount": "0.25", "pincode": ""},
+			Want:   []string{"true"}},
+		{Name: "WalletSend with pin", Call: func() (interface{}, error) { return l.WalletSend("1LocalBTCAddress", 0.25, 1234) },
+			Params: map[string]string{"address": "1LocalBTCAddress", "pincode": "1234"},
+			Want:   []string{"true"}},
+		{Name: "WalletSend unauthorised", Call: func() (interface{}, error) { return l.WalletSend("1Unauthorised", 1, 0) },
+			Err: ERROR_AUTH_FAILED},
+		{Name: "GetWalletAddress", Call: func() (interface{}, error) { return l.GetWalletAddress() },
+			Want: []string{"1LocalBTCFreshAddress"}},
+	})
+
+	if _, err := l.CheckPincode(9999); err == nil {
+		t.Error("Test failed - CheckPincode. Expected an error for a wrong pin.")
+	}
+}
+
+func TestLocalBitcoinsSigning(t *testing.T) {
+	l, f := localbitcoinsFixtures(t)
+	defer f.Close()
+
+	if _, err := l.CheckPincode(1234); err != nil {
+		t.Fatal(err)
+	}
+
+	req := f.Last()
+	nonce := req.Header.Get("Apiauth-Nonce")
+	message := nonce + "localbitcoins-key" + "/api/pincode/" + req.Body
+	hmac := GetHMAC(HASH_SHA256, []byte(message), []byte("localbitcoins-secret"))
+	if sign := strings.ToUpper(HexEncodeToString(hmac)); req.Header.Get("Apiauth-Signature") != sign {
+		t.Error(fmt.Sprintf("Test failed. Expected signature %s. Actual %s", sign, req.Header.Get("Apiauth-Signature")))
+	}
+	if nonce == "" || req.Header.Get("Apiauth-Key") != "localbitcoins-key" || req.Body != "pincode=1234" {
+		t.Error(fmt.Sprintf("Test failed. Unexpected request %v %s", req.Header, req.Body))
+	}
+}
@@ -2,6 +2,8 @@ package main
 
 import (
 	"errors"
+	"flag"
+	"fmt"
 	"log"
 	"os"
 	"os/signal"
@@ -15,7 +17,6 @@ type Exchange struct {
 	btcc          BTCC
 	bitstamp      Bitstamp
 	bitfinex      Bitfinex
-	brightonpeak  BrightonPeak
 	btce          BTCE
 	btcmarkets    BTCMarkets
 	coinbase      Coinbase
@@ -28,6 +29,7 @@ type Exchange struct {
 	poloniex      Poloniex
 	huobi         HUOBI
 	kraken        Kraken
+	alphapoint    []*Alphapoint
 }
 
 type Bot struct {
@@ -40,10 +42,15 @@ type Bot struct {
 var bot Bot
 
 func SetupBotConfiguration(s IBotExchange, exch Exchanges) {
-	s.Setup(exch)
 	if s.GetName() == exch.Name {
+		s.Setup(exch)
+		ConfigureHTTPClient(exch)
 		if s.IsEnabled() {
-			log.Printf("%s: Exchange support: %s (Authenticated API support: %s - Verbose mode: %s).\n", exch.Name, IsEnabled(exch.Enabled), IsEnabled(exch.AuthenticatedAPISupport), IsEnabled(exch.Verbose))
+			paper := ""
+			if ExchangePaperTrading[exch.Name] {
+				paper = fmt.Sprintf(" - Paper trading: %s", IsEnabled(exch.PaperTrading))
+			}
+			log.Printf("%s: Exchange support: %s (Authenticated API support: %s - Verbose mode: %s%s).\n", exch.Name, IsEnabled(exch.Enabled), IsEnabled(exch.AuthenticatedAPISupport), IsEnabled(exch.Verbose), paper)
 			s.Start()
 		} else {
 			log.Printf("%s: Exchange support: %s\n", exch.Name, IsEnabled(exch.Enabled))
@@ -52,6 +59,12 @@ func SetupBotConfiguration(s IBotExchange, exch Exchanges) {
 }
 
 func main() {
+	mockExchange := flag.String("mockexchange", "", "serve mock Poloniex, Bitfinex and Bitstamp APIs on this address instead of running the bot")
+	flag.Parse()
+	if *mockExchange != "" {
+		log.Fatal(RunMockExchange(*mockExchange))
+	}
+
 	HandleInterrupt()
 	log.Println("Loading config file config.json..")
 
@@ -104,6 +117,36 @@ func main() {
 		log.Println("HTTP Webserver support disabled.")
 	}
 
+	OrdersFile = ORDERS_FILE
+	err = LoadOrders(OrdersFile)
+	if err != nil {
+		log.Printf("Fatal error loading orders from %s. Error: %s", OrdersFile, err)
+		return
+	}
+	log.Printf("Loaded %d orders.\n", len(Orders))
+
+	if bot.config.JournalFiat != "" {
+		TradeJournal.Fiat = StringToUpper(bot.config.JournalFiat)
+	}
+	// crypto quoted trades are valued at the time of the trade from Coinbase's
+	// public candles, separate from the configured exchange so a sandbox
+	// never prices them
+	journalPrices := &Coinbase{}
+	journalPrices.SetDefaults()
+	TradeJournal.Price = journalPrices.JournalPrice
+	err = TradeJournal.Load(JOURNAL_FILE)
+	if err != nil {
+		log.Printf("Fatal error loading trade journal from %s. Error: %s", JOURNAL_FILE, err)
+		return
+	}
+	log.Printf("Loaded %d trade journal entries valued in %s.\n", TradeJournal.Len(), TradeJournal.Fiat)
+
+	err = Nonces.Load(NONCES_FILE)
+	if err != nil {
+		log.Printf("Fatal error loading nonces from %s. Error: %s", NONCES_FILE, err)
+		return
+	}
+
 	log.Printf("Available Exchanges: %d. Enabled Exchanges: %d.\n", len(bot.config.Exchanges), GetEnabledExchanges())
 	log.Println("Bot Exchange support:")
 
@@ -115,7 +158,6 @@ func main() {
 		&bot.exchange.kraken,
 		&bot.exchange.btcc,
 		&bot.exchange.bitstamp,
-		&bot.exchange.brightonpeak,
 		&bot.exchange.bitfinex,
 		&bot.exchange.btce,
 		&bot.exchange.btcmarkets,
@@ -130,6 +172,14 @@ func main() {
 		&bot.exchange.huobi,
 	}
 
+	for _, exch := range bot.config.Exchanges {
+		if IsAlphapointExchange(exch) {
+			a := &Alphapoint{Name: exch.Name}
+			bot.exchange.alphapoint = append(bot.exchange.alphapoint, a)
+			bot.exchanges = append(bot.exchanges, a)
+		}
+	}
+
 	for i := 0; i < len(bot.exchanges); i++ {
 		if bot.exchanges[i] != nil {
 			bot.exchanges[i].SetDefaults()
@@ -194,6 +244,20 @@ func Shutdown() {
 		log.Println("Config file saved successfully.")
 	}
 
+	if OrdersFile != "" {
+		err = SaveOrders(OrdersFile)
+		if err != nil {
+			log.Println("Unable to save orders.")
+		} else {
+			log.Println("Orders saved successfully.")
+		}
+	}
+
+	err = Nonces.Save()
+	if err != nil {
+		log.Println("Unable to save nonces.")
+	}
+
 	log.Println("Exiting.")
 	os.Exit(1)
 }
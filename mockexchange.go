@@ -0,0 +1,692 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"math"
+	"math/rand"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// MockExchange is a local stand in for Poloniex, Bitfinex and Bitstamp, so
+// the bot can be run end to end without the network, in CI for instance. The
+// REST APIs are served under /poloniex, /bitfinex/v1/ and /bitstamp/api/,
+// Poloniex's WAMP websocket at /poloniex/wamp, Bitfinex's at /bitfinex/ws and
+// Bitstamp's Pusher websocket at /app/. Point an exchange at them with its
+// APIURL and WebsocketURL config.
+//
+// Each pair has a price-time priority orderbook. A market maker quotes
+// levels either side of a price which random walks on every Tick, printing a
+// trade of its own each time so that the tape keeps moving, and the last
+// MOCK_EXCHANGE_HISTORY of trades are made up on startup for charts to
+// backfill from. Orders placed through the APIs rest on and match against the
+// same book. There is a single account per exchange, which is a
+// PaperExchange's ledger for balances, holds, fees and margin positions, and
+// requests must be signed with MOCK_EXCHANGE_API_KEY and
+// MOCK_EXCHANGE_API_SECRET (and MOCK_EXCHANGE_CLIENT_ID for Bitstamp).
+
+const (
+	MOCK_EXCHANGE_API_KEY      = "mock-key"
+	MOCK_EXCHANGE_API_SECRET   = "mock-secret"
+	MOCK_EXCHANGE_CLIENT_ID    = "1"
+	MOCK_EXCHANGE_SEED         = 1
+	MOCK_EXCHANGE_TICK         = time.Second
+	MOCK_EXCHANGE_HEARTBEAT    = 5 * time.Second
+	MOCK_EXCHANGE_HISTORY      = 30 * 24 * time.Hour
+	MOCK_EXCHANGE_HISTORY_STEP = 5 * time.Minute
+	MOCK_EXCHANGE_LEVELS       = 20
+	MOCK_EXCHANGE_SPREAD       = 0.001 // between market maker levels, as a fraction of the price
+	MOCK_EXCHANGE_VOLATILITY   = 0.002 // of the price per tick
+	MOCK_EXCHANGE_DUST         = 1e-9
+	MOCK_EXCHANGE_EVENT_BUFFER = 256
+)
+
+var (
+	ErrMockExchangeUnknownPair = errors.New("Unknown currency pair.")
+)
+
+type MockMarket struct {
+	Pair  string
+	Price float64 // the market maker's mid
+	Size  float64 // of each market maker level
+
+	seq    int64         // bumped on every event published
+	bids   []*PaperOrder // best first, then oldest first
+	asks   []*PaperOrder
+	quotes []*PaperOrder // the market maker's
+	trades []PaperFill   // oldest first, Buy is the taker's side
+}
+
+// MockTicker is a market's last 24 hours. Volume is in the currency traded,
+// Total in the currency prices are quoted in.
+type MockTicker struct {
+	Last    float64
+	Bid     float64
+	BidSize float64
+	Ask     float64
+	AskSize float64
+	Open    float64
+	High    float64
+	Low     float64
+	Volume  float64
+	Total   float64
+}
+
+// MockEvent tells listeners that a pair's book changed and what traded. Seq
+// is the book's sequence number, and Bids and Asks its levels, as of the
+// event.
+type MockEvent struct {
+	Pair   string
+	Seq    int64
+	Bids   []PaperBookLevel
+	Asks   []PaperBookLevel
+	Trades []PaperFill
+}
+
+type MockVenue struct {
+	Name string
+
+	// mtx must be taken before account.mtx, and guards everything but the
+	// account
+	mtx       sync.Mutex
+	account   *PaperExchange
+	markets   map[string]*MockMarket
+	pairs     []string
+	rand      *rand.Rand
+	nonce     int64
+	listeners map[chan MockEvent]bool
+}
+
+func NewMockVenue(name string, split PaperPairFunc, balances map[string]map[string]float64, markets []MockMarket) *MockVenue {
+	v := &MockVenue{
+		Name:      name,
+		markets:   make(map[string]*MockMarket),
+		rand:      rand.New(rand.NewSource(MOCK_EXCHANGE_SEED)),
+		listeners: make(map[chan MockEvent]bool),
+	}
+	v.account = NewPaperExchange(name, balances, v.orderbook, split)
+
+	now := time.Now()
+	for i := range markets {
+		m := &markets[i]
+		v.markets[m.Pair] = m
+		v.pairs = append(v.pairs, m.Pair)
+		v.seedHistory(m, now)
+		v.quote(m)
+		v.publish(m.Pair, nil)
+	}
+	return v
+}
+
+func (v *MockVenue) lock() {
+	v.mtx.Lock()
+	v.account.mtx.Lock()
+}
+
+func (v *MockVenue) unlock() {
+	v.account.mtx.Unlock()
+	v.mtx.Unlock()
+}
+
+// Pairs returns the venue's pairs in the order they were configured.
+func (v *MockVenue) Pairs() []string {
+	return append([]string{}, v.pairs...)
+}
+
+// Split returns the currency pair's prices are quoted in and the currency
+// traded, as PaperPairFunc.
+func (v *MockVenue) Split(pair string) (string, string) {
+	return v.account.split(pair)
+}
+
+// CheckNonce accepts nonce if it's greater than the last one accepted, which
+// it returns.
+func (v *MockVenue) CheckNonce(nonce int64) (int64, bool) {
+	v.mtx.Lock()
+	defer v.mtx.Unlock()
+
+	last := v.nonce
+	if nonce <= last {
+		return last, false
+	}
+	v.nonce = nonce
+	return last, true
+}
+
+func mockRound(x float64) float64 {
+	return math.Floor(x*1e8+0.5) / 1e8
+}
+
+func (v *MockVenue) walk(price float64) float64 {
+	return price * math.Exp(v.rand.NormFloat64()*MOCK_EXCHANGE_VOLATILITY)
+}
+
+// seedHistory makes up a trade every MOCK_EXCHANGE_HISTORY_STEP for the last
+// MOCK_EXCHANGE_HISTORY, walking back from the current price.
+func (v *MockVenue) seedHistory(m *MockMarket, now time.Time) {
+	steps := int(MOCK_EXCHANGE_HISTORY / MOCK_EXCHANGE_HISTORY_STEP)
+	prices := make([]float64, steps)
+	price := m.Price
+	for i := steps - 1; i >= 0; i-- {
+		prices[i] = mockRound(price)
+		price = v.walk(price)
+	}
+	for i, price := range prices {
+		v.print(m, price, now.Add(-time.Duration(steps-i)*MOCK_EXCHANGE_HISTORY_STEP))
+	}
+}
+
+// print puts a trade between other, imaginary, traders on the tape.
+func (v *MockVenue) print(m *MockMarket, price float64, t time.Time) PaperFill {
+	v.account.nextTradeID++
+	trade := PaperFill{TradeID: v.account.nextTradeID, Pair: m.Pair, Buy: v.rand.Intn(2) == 0, Price: price, Amount: mockRound(m.Size * v.rand.Float64()), Time: t}
+	trade.Total = trade.Price * trade.Amount
+	m.trades = append(m.trades, trade)
+	return trade
+}
+
+// quote replaces the market maker's levels around m.Price. Orders resting
+// where the new levels cross are filled, as the price has moved through them.
+func (v *MockVenue) quote(m *MockMarket) []PaperFill {
+	for _, x := range m.quotes {
+		x.Open = false
+	}
+	m.quotes = nil
+	m.bids = mockOpenOrders(m.bids)
+	m.asks = mockOpenOrders(m.asks)
+
+	trades := []PaperFill{}
+	for i := 1; i <= MOCK_EXCHANGE_LEVELS; i++ {
+		for _, buy := range []bool{true, false} {
+			offset := MOCK_EXCHANGE_SPREAD * float64(i)
+			if buy {
+				offset = -offset
+			}
+			order := &PaperOrder{Pair: m.Pair, Buy: buy, Price: mockRound(m.Price * (1 + offset)), Amount: m.Size, Open: true, Time: time.Now()}
+			trades = append(trades, v.match(m, order)...)
+			if order.Open {
+				v.rest(m, order)
+				m.quotes = append(m.quotes, order)
+			}
+		}
+	}
+	return trades
+}
+
+func mockOpenOrders(orders []*PaperOrder) []*PaperOrder {
+	open := []*PaperOrder{}
+	for _, x := range orders {
+		if x.Open {
+			open = append(open, x)
+		}
+	}
+	return open
+}
+
+// ours tells the account's orders from the market maker's.
+func (v *MockVenue) ours(order *PaperOrder) bool {
+	return v.account.orders[order.OrderID] == order
+}
+
+// fill books amount of order at price, through the account if it's one of
+// ours, and closes the order once there's only dust left.
+func (v *MockVenue) fill(order *PaperOrder, tradeID int64, price, amount float64, maker bool) {
+	if v.ours(order) {
+		v.account.fill(order, price, amount, maker)
+		order.Fills[len(order.Fills)-1].TradeID = tradeID
+	} else {
+		order.Filled += amount
+	}
+	if order.Open && order.Amount-order.Filled <= MOCK_EXCHANGE_DUST {
+		v.close(order)
+	}
+}
+
+func (v *MockVenue) close(order *PaperOrder) {
+	if v.ours(order) {
+		v.account.cancel(order)
+		return
+	}
+	order.Open = false
+}
+
+// match fills order against the other side of the book for as long as it
+// crosses, at the resting orders' prices, and returns the trades.
+func (v *MockVenue) match(m *MockMarket, order *PaperOrder) []PaperFill {
+	book := &m.asks
+	if !order.Buy {
+		book = &m.bids
+	}
+
+	trades := []PaperFill{}
+	for order.Open && len(*book) > 0 {
+		resting := (*book)[0]
+		if (order.Buy && resting.Price > order.Price) || (!order.Buy && resting.Price < order.Price) {
+			break
+		}
+
+		v.account.nextTradeID++
+		amount := math.Min(order.Amount-order.Filled, resting.Amount-resting.Filled)
+		trade := PaperFill{TradeID: v.account.nextTradeID, OrderID: order.OrderID, Pair: m.Pair, Buy: order.Buy, Price: resting.Price, Amount: amount, Total: resting.Price * amount, Time: time.Now()}
+		v.fill(resting, trade.TradeID, trade.Price, amount, true)
+		v.fill(order, trade.TradeID, trade.Price, amount, false)
+		m.trades = append(m.trades, trade)
+		trades = append(trades, trade)
+
+		if !resting.Open {
+			*book = (*book)[1:]
+		}
+	}
+	return trades
+}
+
+// rest adds order to its side of the book, behind any orders at the same
+// price.
+func (v *MockVenue) rest(m *MockMarket, order *PaperOrder) {
+	book := &m.bids
+	i := sort.Search(len(m.bids), func(i int) bool { return m.bids[i].Price < order.Price })
+	if !order.Buy {
+		book = &m.asks
+		i = sort.Search(len(m.asks), func(i int) bool { return m.asks[i].Price > order.Price })
+	}
+	*book = append(*book, nil)
+	copy((*book)[i+1:], (*book)[i:])
+	(*book)[i] = order
+}
+
+// publish must be called with mtx held, so that events go out in order.
+func (v *MockVenue) publish(pair string, trades []PaperFill) {
+	m := v.markets[pair]
+	m.seq++
+	event := MockEvent{Pair: pair, Seq: m.seq, Bids: mockLevels(m.bids, 0), Asks: mockLevels(m.asks, 0), Trades: trades}
+	for c := range v.listeners {
+		select {
+		case c <- event:
+		default: // too far behind, as the exchange would drop them
+		}
+	}
+}
+
+// Subscribe returns a channel of every MockEvent from now on, until it is
+// passed to Unsubscribe. Events are dropped for listeners which fall behind.
+func (v *MockVenue) Subscribe() chan MockEvent {
+	v.mtx.Lock()
+	defer v.mtx.Unlock()
+
+	c := make(chan MockEvent, MOCK_EXCHANGE_EVENT_BUFFER)
+	v.listeners[c] = true
+	return c
+}
+
+func (v *MockVenue) Unsubscribe(c chan MockEvent) {
+	v.mtx.Lock()
+	defer v.mtx.Unlock()
+	delete(v.listeners, c)
+}
+
+// Tick moves every market's price on a step, prints a trade at it and
+// re-quotes around it.
+func (v *MockVenue) Tick() {
+	v.lock()
+	defer v.unlock()
+
+	for _, pair := range v.pairs {
+		m := v.markets[pair]
+		m.Price = v.walk(m.Price)
+		trades := []PaperFill{v.print(m, mockRound(m.Price), time.Now())}
+		trades = append(trades, v.quote(m)...)
+		v.publish(pair, trades)
+	}
+}
+
+// PlaceOrder places a limit order as PaperExchange.PlaceOrder, but against
+// the mock's own book.
+func (v *MockVenue) PlaceOrder(pair string, price, amount float64, buy, margin, postOnly, immediate bool) (PaperOrder, error) {
+	if price <= 0 || amount <= 0 {
+		return PaperOrder{}, ErrPaperInvalidOrder
+	}
+
+	v.lock()
+	defer v.unlock()
+
+	m, ok := v.markets[pair]
+	if !ok {
+		return PaperOrder{}, ErrMockExchangeUnknownPair
+	}
+
+	crosses := (buy && len(m.asks) > 0 && m.asks[0].Price <= price) || (!buy && len(m.bids) > 0 && m.bids[0].Price >= price)
+	if postOnly && crosses {
+		return PaperOrder{}, ErrPaperPostOnly
+	}
+
+	order := &PaperOrder{Pair: pair, Buy: buy, Margin: margin, PostOnly: postOnly, Price: price, Amount: amount, Open: true, Time: time.Now()}
+	return v.place(m, order, immediate)
+}
+
+// place must be called with the venue locked.
+func (v *MockVenue) place(m *MockMarket, order *PaperOrder, immediate bool) (PaperOrder, error) {
+	if err := v.account.holdFunds(order); err != nil {
+		return PaperOrder{}, err
+	}
+	v.account.nextOrderID++
+	order.OrderID = v.account.nextOrderID
+	v.account.orders[order.OrderID] = order
+
+	trades := v.match(m, order)
+	if order.Open && immediate {
+		v.close(order)
+	} else if order.Open {
+		v.rest(m, order)
+	}
+	v.publish(m.Pair, trades)
+	return order.snapshot(), nil
+}
+
+func (v *MockVenue) CancelOrder(orderID int64) error {
+	v.lock()
+	defer v.unlock()
+
+	order, ok := v.account.orders[orderID]
+	if !ok || !order.Open {
+		return ErrPaperOrderNotFound
+	}
+	v.close(order)
+
+	m := v.markets[order.Pair]
+	m.bids = mockOpenOrders(m.bids)
+	m.asks = mockOpenOrders(m.asks)
+	v.publish(m.Pair, nil)
+	return nil
+}
+
+func (v *MockVenue) Order(orderID int64) (PaperOrder, error) {
+	v.lock()
+	defer v.unlock()
+
+	order, ok := v.account.orders[orderID]
+	if !ok {
+		return PaperOrder{}, ErrPaperOrderNotFound
+	}
+	return order.snapshot(), nil
+}
+
+// Orders returns every order placed on pair, or on every pair if it's empty,
+// oldest first.
+func (v *MockVenue) Orders(pair string) []PaperOrder {
+	v.lock()
+	defer v.unlock()
+
+	orders := []PaperOrder{}
+	for _, x := range v.account.orders {
+		if pair == "" || x.Pair == pair {
+			orders = append(orders, x.snapshot())
+		}
+	}
+	sort.Sort(paperOrdersByID(orders))
+	return orders
+}
+
+func (v *MockVenue) OpenOrders(pair string) []PaperOrder {
+	return v.account.OpenOrders(pair)
+}
+
+func (v *MockVenue) Balances() map[string]map[string]float64 {
+	return v.account.Balances()
+}
+
+// Held returns what open orders have held of each currency in the exchange
+// account.
+func (v *MockVenue) Held() map[string]float64 {
+	v.lock()
+	defer v.unlock()
+
+	held := make(map[string]float64)
+	for _, x := range v.account.orders {
+		if !x.Open || x.Margin {
+			continue
+		}
+		base, quote := v.account.split(x.Pair)
+		if x.Buy {
+			held[base] += x.hold
+		} else {
+			held[quote] += x.hold
+		}
+	}
+	return held
+}
+
+func (v *MockVenue) SetLendingRate(pair string, rate float64) {
+	v.account.SetLendingRate(pair, rate)
+}
+
+// Position returns the margin position on pair, marked to the book.
+func (v *MockVenue) Position(pair string) (PaperPosition, error) {
+	v.mtx.Lock()
+	_, ok := v.markets[pair]
+	v.mtx.Unlock()
+	if !ok {
+		return PaperPosition{}, ErrMockExchangeUnknownPair
+	}
+	return v.account.Position(pair)
+}
+
+// ClosePosition closes the margin position on pair at market, as far as the
+// book goes.
+func (v *MockVenue) ClosePosition(pair string) (PaperOrder, error) {
+	v.lock()
+	defer v.unlock()
+
+	m, ok := v.markets[pair]
+	if !ok {
+		return PaperOrder{}, ErrMockExchangeUnknownPair
+	}
+	position, ok := v.account.positions[pair]
+	if !ok || position.Amount == 0 {
+		return PaperOrder{}, ErrPaperNoPosition
+	}
+
+	buy := position.Amount < 0
+	order := &PaperOrder{Pair: pair, Buy: buy, Margin: true, Amount: math.Abs(position.Amount), Open: true, Time: time.Now()}
+	if buy {
+		order.Price = math.MaxFloat64
+	}
+	result, err := v.place(m, order, true)
+	if err != nil {
+		return result, err
+	}
+	if len(result.Fills) == 0 {
+		return result, ErrPaperOrderbookEmpty
+	}
+	result.Price = result.Fills[len(result.Fills)-1].Price
+	return result, nil
+}
+
+// orderbook is the account's PaperOrderbookFunc, so that positions are
+// marked to the mock's book.
+func (v *MockVenue) orderbook(pair string) ([]PaperBookLevel, []PaperBookLevel, error) {
+	bids, asks, _, err := v.Book(pair, 0)
+	return bids, asks, err
+}
+
+// Book returns up to depth price levels, or all of them if depth is 0, of
+// each side of pair's book, best first, and its sequence number.
+func (v *MockVenue) Book(pair string, depth int) ([]PaperBookLevel, []PaperBookLevel, int64, error) {
+	v.mtx.Lock()
+	defer v.mtx.Unlock()
+
+	m, ok := v.markets[pair]
+	if !ok {
+		return nil, nil, 0, ErrMockExchangeUnknownPair
+	}
+	return mockLevels(m.bids, depth), mockLevels(m.asks, depth), m.seq, nil
+}
+
+func mockLevels(orders []*PaperOrder, depth int) []PaperBookLevel {
+	levels := []PaperBookLevel{}
+	for _, x := range orders {
+		if n := len(levels); n > 0 && levels[n-1].Price == x.Price {
+			levels[n-1].Amount += x.Amount - x.Filled
+			continue
+		}
+		if depth > 0 && len(levels) == depth {
+			break
+		}
+		levels = append(levels, PaperBookLevel{Price: x.Price, Amount: x.Amount - x.Filled})
+	}
+	return levels
+}
+
+// Trades returns up to limit of pair's trades between start and end, newest
+// first. A zero start or end leaves that end of the range open.
+func (v *MockVenue) Trades(pair string, start, end time.Time, limit int) ([]PaperFill, error) {
+	v.mtx.Lock()
+	defer v.mtx.Unlock()
+
+	m, ok := v.markets[pair]
+	if !ok {
+		return nil, ErrMockExchangeUnknownPair
+	}
+
+	i := len(m.trades)
+	if !end.IsZero() {
+		i = sort.Search(len(m.trades), func(i int) bool { return m.trades[i].Time.After(end) })
+	}
+	trades := []PaperFill{}
+	for i--; i >= 0 && len(trades) < limit; i-- {
+		if !start.IsZero() && m.trades[i].Time.Before(start) {
+			break
+		}
+		trades = append(trades, m.trades[i])
+	}
+	return trades, nil
+}
+
+// Ticker summarises pair over the period up to now.
+func (v *MockVenue) Ticker(pair string, period time.Duration) (MockTicker, error) {
+	v.mtx.Lock()
+	defer v.mtx.Unlock()
+
+	m, ok := v.markets[pair]
+	if !ok {
+		return MockTicker{}, ErrMockExchangeUnknownPair
+	}
+
+	ticker := MockTicker{Last: m.trades[len(m.trades)-1].Price}
+	bids, asks := mockLevels(m.bids, 1), mockLevels(m.asks, 1)
+	if len(bids) > 0 {
+		ticker.Bid, ticker.BidSize = bids[0].Price, bids[0].Amount
+	}
+	if len(asks) > 0 {
+		ticker.Ask, ticker.AskSize = asks[0].Price, asks[0].Amount
+	}
+
+	since := time.Now().Add(-period)
+	i := sort.Search(len(m.trades), func(i int) bool { return !m.trades[i].Time.Before(since) })
+	ticker.Open, ticker.High, ticker.Low = ticker.Last, ticker.Last, ticker.Last
+	if i < len(m.trades) {
+		ticker.Open = m.trades[i].Price
+	}
+	for _, x := range m.trades[i:] {
+		ticker.High = math.Max(ticker.High, x.Price)
+		ticker.Low = math.Min(ticker.Low, x.Price)
+		ticker.Volume += x.Amount
+		ticker.Total += x.Total
+	}
+	return ticker, nil
+}
+
+type MockExchange struct {
+	Poloniex *MockVenue
+	Bitfinex *MockVenue
+	Bitstamp *MockVenue
+
+	mux *http.ServeMux
+}
+
+func NewMockExchange() *MockExchange {
+	e := &MockExchange{
+		Poloniex: NewMockVenue("Poloniex", poloniexPaperPair,
+			map[string]map[string]float64{
+				PAPER_ACCOUNT_EXCHANGE: {"BTC": 10, "ETH": 500, "LTC": 1000, "USDT": 10000},
+				PAPER_ACCOUNT_MARGIN:   {"BTC": 5, "ETH": 100},
+			},
+			[]MockMarket{
+				{Pair: "BTC_ETH", Price: 0.012, Size: 50},
+				{Pair: "BTC_LTC", Price: 0.0045, Size: 100},
+				{Pair: "USDT_BTC", Price: 750, Size: 2},
+			}),
+		Bitfinex: NewMockVenue("Bitfinex", bitfinexMockPair,
+			map[string]map[string]float64{
+				PAPER_ACCOUNT_EXCHANGE: {"USD": 10000, "BTC": 10, "LTC": 1000, "ETH": 500},
+				PAPER_ACCOUNT_MARGIN:   {"USD": 5000},
+			},
+			[]MockMarket{
+				{Pair: "btcusd", Price: 750, Size: 2},
+				{Pair: "ltcusd", Price: 3.8, Size: 100},
+				{Pair: "ethusd", Price: 9, Size: 50},
+			}),
+		Bitstamp: NewMockVenue("Bitstamp", bitfinexMockPair,
+			map[string]map[string]float64{
+				PAPER_ACCOUNT_EXCHANGE: {"USD": 10000, "BTC": 10},
+			},
+			[]MockMarket{
+				{Pair: "btcusd", Price: 750, Size: 2},
+			}),
+	}
+	e.Poloniex.account.MakerFee, e.Poloniex.account.TakerFee = POLONIEX_PAPER_MAKER_FEE, POLONIEX_PAPER_TAKER_FEE
+	e.Bitfinex.account.MakerFee, e.Bitfinex.account.TakerFee = 0.1, 0.2
+	e.Bitstamp.account.MakerFee, e.Bitstamp.account.TakerFee = 0.25, 0.25
+
+	e.mux = http.NewServeMux()
+	e.mux.Handle("/poloniex/", http.StripPrefix("/poloniex", &poloniexMock{e.Poloniex}))
+	e.mux.Handle("/bitfinex/v1/", http.StripPrefix("/bitfinex/v1", &bitfinexMock{e.Bitfinex}))
+	e.mux.HandleFunc("/bitfinex/ws", (&bitfinexMock{e.Bitfinex}).websocket)
+	e.mux.Handle("/bitstamp/api/", http.StripPrefix("/bitstamp/api", &bitstampMock{e.Bitstamp}))
+	e.mux.HandleFunc("/app/", (&bitstampMock{e.Bitstamp}).pusher)
+	return e
+}
+
+func (e *MockExchange) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	e.mux.ServeHTTP(w, r)
+}
+
+// Tick moves every venue's prices on a step.
+func (e *MockExchange) Tick() {
+	for _, x := range []*MockVenue{e.Poloniex, e.Bitfinex, e.Bitstamp} {
+		x.Tick()
+	}
+}
+
+// RunMockExchange serves a MockExchange on addr, ticking every
+// MOCK_EXCHANGE_TICK.
+func RunMockExchange(addr string) error {
+	e := NewMockExchange()
+	go func() {
+		for range time.Tick(MOCK_EXCHANGE_TICK) {
+			e.Tick()
+		}
+	}()
+
+	log.Printf("Mock exchange listening on %s. API key: %s secret: %s client ID: %s\n", addr, MOCK_EXCHANGE_API_KEY, MOCK_EXCHANGE_API_SECRET, MOCK_EXCHANGE_CLIENT_ID)
+	return http.ListenAndServe(addr, e)
+}
+
+func mockWriteJSON(w http.ResponseWriter, status int, v interface{}) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(data)
+}
+
+func mockFormatFloat(x float64) string {
+	return strconv.FormatFloat(x, 'f', 8, 64)
+}
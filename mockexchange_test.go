@@ -0,0 +1,547 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// The mock exchange is tested through the real clients, so the tests can't
+// run in parallel with the fixture tests of the same exchanges.
+
+type mockExchangeServer struct {
+	*httptest.Server
+	exchange *MockExchange
+	limits   map[string]*RateLimiter
+}
+
+func newMockExchangeServer() *mockExchangeServer {
+	m := &mockExchangeServer{exchange: NewMockExchange(), limits: make(map[string]*RateLimiter)}
+	m.Server = httptest.NewServer(m.exchange)
+	for _, x := range []string{"Poloniex", "Bitfinex", "Bitstamp"} {
+		m.limits[x] = GetHTTPClient(x).Limiter
+		GetHTTPClient(x).Limiter = nil
+	}
+	return m
+}
+
+func (m *mockExchangeServer) Close() {
+	m.Server.Close()
+	for x, limiter := range m.limits {
+		GetHTTPClient(x).Limiter = limiter
+	}
+}
+
+func (m *mockExchangeServer) websocketURL(path string) string {
+	return "ws" + strings.TrimPrefix(m.URL, "http") + path
+}
+
+func (m *mockExchangeServer) poloniex() *Poloniex {
+	p := &Poloniex{}
+	p.SetDefaults()
+	p.APIUrl = m.URL + "/poloniex"
+	p.SetAPIKeys(MOCK_EXCHANGE_API_KEY, MOCK_EXCHANGE_API_SECRET)
+	return p
+}
+
+func TestMockPoloniexMarketData(t *testing.T) {
+	m := newMockExchangeServer()
+	defer m.Close()
+	p := m.poloniex()
+
+	ticker, err := p.GetTicker()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if x := ticker["BTC_ETH"]; x.Last <= 0 || x.HighestBid >= x.LowestAsk {
+		t.Error(fmt.Sprintf("Test failed. Expected a BTC_ETH ticker with bid below ask. Actual %+v", x))
+	}
+
+	books, err := p.GetOrderbook("BTC_ETH", 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if book := books["BTC_ETH"]; len(book.Bids) != 5 || len(book.Asks) != 5 || book.Seq == 0 {
+		t.Error(fmt.Sprintf("Test failed. Expected 5 levels each side and a sequence number. Actual %+v", book))
+	}
+
+	end := time.Now()
+	candles, err := p.GetCandleHistory("BTC_ETH", end.Add(-24*time.Hour), end, 2*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(candles) < 12 || len(candles) > 13 {
+		t.Error(fmt.Sprintf("Test failed. Expected 12 or 13 candles. Actual %d", len(candles)))
+	}
+	for _, x := range candles {
+		if x.Low > x.Open || x.Low > x.Close || x.High < x.Open || x.High < x.Close || x.Volume <= 0 {
+			t.Error(fmt.Sprintf("Test failed. Expected a consistent candle. Actual %+v", x))
+		}
+	}
+}
+
+func TestMockPoloniexTrading(t *testing.T) {
+	m := newMockExchangeServer()
+	defer m.Close()
+	p := m.poloniex()
+
+	p.SetAPIKeys(MOCK_EXCHANGE_API_KEY, "wrong")
+	if _, err := p.GetBalances(); err == nil || !strings.Contains(err.Error(), "Invalid API key") {
+		t.Error(fmt.Sprintf("Test failed. Expected an invalid API key error. Actual %v", err))
+	}
+	p.SetAPIKeys(MOCK_EXCHANGE_API_KEY, MOCK_EXCHANGE_API_SECRET)
+
+	ticker, err := p.GetTicker()
+	if err != nil {
+		t.Fatal(err)
+	}
+	bid, ask := ticker["BTC_ETH"].HighestBid, ticker["BTC_ETH"].LowestAsk
+
+	resting, err := p.PlaceOrder("BTC_ETH", mockRound(bid*0.9), 10, false, false, true, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resting.Trades) != 0 {
+		t.Error(fmt.Sprintf("Test failed. Expected an order below the bid to rest. Actual %+v", resting))
+	}
+	if _, err := p.CancelOrder(resting.OrderNumber); err != nil {
+		t.Error(fmt.Sprintf("Test failed. Unable to cancel order: %s", err))
+	}
+	if _, err := p.CancelOrder(resting.OrderNumber); err == nil {
+		t.Error("Test failed. Expected cancelling a cancelled order to fail.")
+	}
+	if _, _, err := (&PoloniexExecutionVenue{p: p}).PlaceLimitOrder("BTC_ETH", mockRound(ask*1.01), 10, true, true); err != ErrExecutionWouldTake {
+		t.Error(fmt.Sprintf("Test failed. Expected %v. Actual %v", ErrExecutionWouldTake, err))
+	}
+
+	taken, err := p.PlaceOrder("BTC_ETH", mockRound(ask*1.01), 10, true, false, false, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var filled float64
+	for _, x := range taken.Trades {
+		filled += x.Amount
+		if x.Rate < ask {
+			t.Error(fmt.Sprintf("Test failed. Expected fills at or above the ask %f. Actual %f", ask, x.Rate))
+		}
+	}
+	if filled <= 0 {
+		t.Error(fmt.Sprintf("Test failed. Expected a crossing order to fill. Actual %+v", taken))
+	}
+
+	trades, err := p.GetOrderTrades(taken.OrderNumber)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(trades) != len(taken.Trades) {
+		t.Error(fmt.Sprintf("Test failed. Expected %d order trades. Actual %d", len(taken.Trades), len(trades)))
+	}
+	for _, x := range trades {
+		if x.Fee != POLONIEX_PAPER_TAKER_FEE/100 {
+			t.Error(fmt.Sprintf("Test failed. Expected the taker fee rate on the trade. Actual %+v", x))
+		}
+	}
+	fills, err := (&PoloniexExecutionVenue{p: p}).OrderFills(strconv.FormatInt(taken.OrderNumber, 10))
+	if err != nil || len(fills) == 0 || math.Abs(fills[0].Fee-fills[0].Price*fills[0].Amount*POLONIEX_PAPER_TAKER_FEE/100) > MOCK_EXCHANGE_DUST {
+		t.Error(fmt.Sprintf("Test failed. Expected fills to carry the fee in BTC. Actual %+v %v", fills, err))
+	}
+
+	balances, err := p.GetBalances()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := 500 + filled*(1-POLONIEX_PAPER_TAKER_FEE/100); math.Abs(balances.Currency["ETH"]-expected) > MOCK_EXCHANGE_DUST {
+		t.Error(fmt.Sprintf("Test failed. Expected ETH balance %f. Actual %f", expected, balances.Currency["ETH"]))
+	}
+
+	if _, err := p.PlaceOrder("BTC_ETH", ask, 100000, false, false, false, true); !IsInsufficientFunds(err) {
+		t.Error(fmt.Sprintf("Test failed. Expected insufficient funds. Actual %v", err))
+	}
+}
+
+func TestMockPoloniexMargin(t *testing.T) {
+	m := newMockExchangeServer()
+	defer m.Close()
+	p := m.poloniex()
+
+	ticker, err := p.GetTicker()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ask := ticker["BTC_ETH"].LowestAsk
+
+	order, err := p.PlaceMarginOrder("BTC_ETH", mockRound(ask*1.01), 5, 0.0002, false, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(order.Trades) == 0 {
+		t.Fatal(fmt.Sprintf("Test failed. Expected the margin buy to fill. Actual %+v", order))
+	}
+
+	result, err := p.GetMarginPosition("BTC_ETH")
+	if err != nil {
+		t.Fatal(err)
+	}
+	position := result.(PoloniexMarginPosition)
+	if position.Amount != 5 || position.Type != "long" {
+		t.Error(fmt.Sprintf("Test failed. Expected a long position of 5. Actual %+v", position))
+	}
+
+	closed, err := p.CloseMarginPosition("BTC_ETH")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(closed.Trades["BTC_ETH"]) == 0 {
+		t.Error(fmt.Sprintf("Test failed. Expected closing trades. Actual %+v", closed))
+	}
+	if _, err := p.CloseMarginPosition("BTC_ETH"); err == nil {
+		t.Error("Test failed. Expected closing a closed position to fail.")
+	}
+}
+
+func TestMockPoloniexExecution(t *testing.T) {
+	m := newMockExchangeServer()
+	defer m.Close()
+
+	// CHASE rests inside the spread, so the market has to move to fill it
+	done := make(chan bool)
+	defer close(done)
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case <-time.After(5 * time.Millisecond):
+				m.exchange.Poloniex.Tick()
+			}
+		}
+	}()
+
+	executor := NewExecutor(&PoloniexExecutionVenue{p: m.poloniex()})
+	summary, err := executor.Execute(ExecutionParams{
+		Algorithm:    EXECUTION_CHASE,
+		Pair:         "BTC_LTC",
+		Buy:          true,
+		Amount:       20,
+		PollInterval: 10 * time.Millisecond,
+		RepriceAfter: 20 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary.Filled != 20 || summary.Trades == 0 {
+		t.Error(fmt.Sprintf("Test failed. Expected 20 filled. Actual %s", summary))
+	}
+}
+
+func TestMockPoloniexEvent(t *testing.T) {
+	m := newMockExchangeServer()
+	defer m.Close()
+
+	// the exchange holds locks, so swap in the mock's settings rather than a copy
+	name, apiURL := bot.exchange.poloniex.Name, bot.exchange.poloniex.APIUrl
+	defer func() { bot.exchange.poloniex.Name, bot.exchange.poloniex.APIUrl = name, apiURL }()
+	bot.exchange.poloniex.Name, bot.exchange.poloniex.APIUrl = "Poloniex", m.URL+"/poloniex"
+
+	triggered := &Event{Exchange: "Poloniex", Item: "PRICE", Condition: GREATER_THAN + ",0.001", CryptoCurrency: "LTC", FiatCurrency: "BTC", Action: "LOG"}
+	if !triggered.CheckCondition() {
+		t.Error("Test failed. Expected the BTC_LTC price to be above 0.001.")
+	}
+	pending := &Event{Exchange: "Poloniex", Item: "PRICE", Condition: LESS_THAN + ",0.001", CryptoCurrency: "LTC", FiatCurrency: "BTC", Action: "LOG"}
+	if pending.CheckCondition() {
+		t.Error("Test failed. Expected the BTC_LTC price not to be below 0.001.")
+	}
+}
+
+func TestMockBitfinex(t *testing.T) {
+	m := newMockExchangeServer()
+	defer m.Close()
+
+	b := &Bitfinex{}
+	b.SetDefaults()
+	b.APIUrl = m.URL + "/bitfinex/v1/"
+	b.SetAPIKeys(MOCK_EXCHANGE_API_KEY, MOCK_EXCHANGE_API_SECRET)
+
+	ticker, err := b.GetTicker("btcusd", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ticker.Last <= 0 || ticker.Bid >= ticker.Ask {
+		t.Error(fmt.Sprintf("Test failed. Expected a btcusd ticker with bid below ask. Actual %+v", ticker))
+	}
+
+	order, err := b.NewOrder("btcusd", 1, mockRound(ticker.Bid*0.9), true, "exchange limit", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !order.IsLive || order.OrderID == 0 {
+		t.Error(fmt.Sprintf("Test failed. Expected a live order. Actual %+v", order))
+	}
+	cancelled, err := b.CancelOrder(order.OrderID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cancelled.IsLive || !cancelled.IsCancelled {
+		t.Error(fmt.Sprintf("Test failed. Expected a cancelled order. Actual %+v", cancelled))
+	}
+
+	if _, err := b.NewOrder("btcusd", 1000, ticker.Ask, true, "exchange limit", false); !IsInsufficientFunds(err) {
+		t.Error(fmt.Sprintf("Test failed. Expected insufficient funds. Actual %v", err))
+	}
+
+	conn, _, err := websocket.DefaultDialer.Dial(m.websocketURL("/bitfinex/ws"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	read := func() interface{} {
+		var message interface{}
+		if err := conn.ReadJSON(&message); err != nil {
+			t.Fatal(err)
+		}
+		return message
+	}
+	if info, ok := read().(map[string]interface{}); !ok || info["event"] != "info" {
+		t.Error(fmt.Sprintf("Test failed. Expected info event. Actual %v", info))
+	}
+
+	conn.WriteJSON(map[string]string{"event": "subscribe", "channel": "trades", "pair": "BTCUSD"})
+	subscribed, ok := read().(map[string]interface{})
+	if !ok || subscribed["event"] != "subscribed" || subscribed["channel"] != "trades" {
+		t.Fatal(fmt.Sprintf("Test failed. Expected subscribed event. Actual %v", subscribed))
+	}
+	if snapshot, ok := read().([]interface{}); !ok || len(snapshot) != 2 || len(snapshot[1].([]interface{})) != BITFINEX_MOCK_TRADES {
+		t.Error(fmt.Sprintf("Test failed. Expected a trades snapshot. Actual %v", snapshot))
+	}
+
+	m.exchange.Bitfinex.Tick()
+	if update, ok := read().([]interface{}); !ok || len(update) != 5 || update[0] != subscribed["chanId"] {
+		t.Error(fmt.Sprintf("Test failed. Expected a trade update. Actual %v", update))
+	}
+}
+
+func TestMockBitstamp(t *testing.T) {
+	m := newMockExchangeServer()
+	defer m.Close()
+
+	b := &Bitstamp{}
+	b.SetDefaults()
+	b.APIUrl = m.URL + "/bitstamp/api/"
+	b.SetAPIKeys(MOCK_EXCHANGE_CLIENT_ID, MOCK_EXCHANGE_API_KEY, MOCK_EXCHANGE_API_SECRET)
+
+	ticker, err := b.GetTicker(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ticker.Last <= 0 || ticker.Bid >= ticker.Ask {
+		t.Error(fmt.Sprintf("Test failed. Expected a ticker with bid below ask. Actual %+v", ticker))
+	}
+
+	order, err := b.PlaceOrder(mockRound(ticker.Bid*0.9), 1, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	balance, err := b.GetBalance()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reserved := mockRound(order.Price * order.Amount); balance.USDReserved != reserved || balance.USDBalance != 10000 {
+		t.Error(fmt.Sprintf("Test failed. Expected %f USD reserved of 10000. Actual %+v", reserved, balance))
+	}
+	if ok, err := b.CancelOrder(order.ID); !ok || err != nil {
+		t.Error(fmt.Sprintf("Test failed. Unable to cancel order: %v", err))
+	}
+
+	if _, err := b.PlaceOrder(ticker.Ask, 1000, true); !IsInsufficientFunds(err) {
+		t.Error(fmt.Sprintf("Test failed. Expected insufficient funds. Actual %v", err))
+	}
+
+	conn, _, err := websocket.DefaultDialer.Dial(m.websocketURL("/app/"+BITSTAMP_PUSHER_KEY+"?protocol=7"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	read := func() bitstampMockPusherEvent {
+		message := bitstampMockPusherEvent{}
+		if err := conn.ReadJSON(&message); err != nil {
+			t.Fatal(err)
+		}
+		return message
+	}
+	if event := read(); event.Event != "pusher:connection_established" {
+		t.Error(fmt.Sprintf("Test failed. Expected connection established. Actual %+v", event))
+	}
+	conn.WriteJSON(map[string]interface{}{"event": "pusher:subscribe", "data": map[string]string{"channel": "live_trades"}})
+	if event := read(); event.Event != "pusher_internal:subscription_succeeded" || event.Channel != "live_trades" {
+		t.Error(fmt.Sprintf("Test failed. Expected subscription succeeded. Actual %+v", event))
+	}
+
+	m.exchange.Bitstamp.Tick()
+	event := read()
+	trade := BitstampPusherTrade{}
+	if err := JSONDecode([]byte(event.Data), &trade); err != nil || event.Event != "trade" || trade.Price <= 0 || trade.Amount <= 0 {
+		t.Error(fmt.Sprintf("Test failed. Expected a trade. Actual %+v", event))
+	}
+}
+
+// TestMockPoloniexEndToEnd runs the bot's Poloniex strategy against
+// RunMockExchange, as -mockexchange does. The mock exchange can't be stopped,
+// so it and the websocket client are left running, under a name of their
+// own.
+func TestMockPoloniexEndToEnd(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := listener.Addr().String()
+	listener.Close()
+	go RunMockExchange(addr)
+
+	p := &Poloniex{}
+	p.SetDefaults()
+	p.Name = "PoloniexEndToEnd"
+	p.Enabled = true
+	p.Websocket = true
+	p.APIUrl = "http://" + addr + "/poloniex"
+	p.WebsocketURL = "ws://" + addr + "/poloniex/wamp"
+	p.EnabledPairs = []string{"BTC_ETH"}
+	p.SetAPIKeys(MOCK_EXCHANGE_API_KEY, MOCK_EXCHANGE_API_SECRET)
+	GetHTTPClient(p.Name).Limiter = nil
+
+	for start := time.Now(); ; time.Sleep(10 * time.Millisecond) {
+		if _, err := p.GetTicker(); err == nil {
+			break
+		} else if time.Since(start) > 5*time.Second {
+			t.Fatal(err)
+		}
+	}
+
+	go p.WebsocketClient()
+	synced := func() (int64, bool) {
+		book := p.websocketOrderbook("BTC_ETH")
+		book.mtx.Lock()
+		defer book.mtx.Unlock()
+		return book.Seq, book.Synced
+	}
+	// wait for a couple of events on top of the snapshot
+	snapshot := int64(0)
+	for start := time.Now(); ; time.Sleep(10 * time.Millisecond) {
+		seq, ok := synced()
+		if ok && snapshot == 0 {
+			snapshot = seq
+		}
+		if _, ticker := p.GetLastTicker("BTC_ETH"); ok && ticker && seq >= snapshot+2 {
+			break
+		}
+		if time.Since(start) > 10*time.Second {
+			t.Fatal("Test failed. Expected the BTC_ETH book and ticker to stream from the mock exchange.")
+		}
+	}
+	bids, asks, ok := p.GetWebsocketOrderbook("BTC_ETH", 0)
+	if !ok || len(bids) != MOCK_EXCHANGE_LEVELS || len(asks) != MOCK_EXCHANGE_LEVELS || bids[0].Price >= asks[0].Price {
+		t.Error(fmt.Sprintf("Test failed. Expected %d levels either side of the spread. Actual bids %v asks %v", MOCK_EXCHANGE_LEVELS, bids, asks))
+	}
+	if ticker, _ := p.GetLastTicker("BTC_ETH"); ticker.Last <= 0 || ticker.HighestBid >= ticker.LowestAsk {
+		t.Error(fmt.Sprintf("Test failed. Expected a streamed ticker with bid below ask. Actual %+v", ticker))
+	}
+
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		p.realTrade("ETH", "BTC_ETH", done)
+		close(stopped)
+	}()
+
+	// the strategy's candles are built from the streamed trades
+	builder := func() *CandleBuilder {
+		candleBuildersMtx.Lock()
+		defer candleBuildersMtx.Unlock()
+		for _, x := range CandleBuilders {
+			if x.Exchange == p.Name {
+				return x
+			}
+		}
+		return nil
+	}
+	for start := time.Now(); ; time.Sleep(10 * time.Millisecond) {
+		if c := builder(); c != nil {
+			c.mtx.Lock()
+			building := c.current != nil
+			c.mtx.Unlock()
+			if building {
+				break
+			}
+		}
+		if time.Since(start) > 10*time.Second {
+			t.Fatal("Test failed. Expected realTrade to build candles from the websocket.")
+		}
+	}
+
+	username, password := bot.config.Webserver.AdminUsername, bot.config.Webserver.AdminPassword
+	defer func() { bot.config.Webserver.AdminUsername, bot.config.Webserver.AdminPassword = username, password }()
+	bot.config.Webserver.AdminUsername, bot.config.Webserver.AdminPassword = "admin", "password"
+
+	// other tests leave fills without fiat prices in the journal, which the
+	// tax lots can't value
+	journal := TradeJournal
+	defer func() { TradeJournal = journal }()
+	TradeJournal = NewJournal(JOURNAL_DEFAULT_FIAT)
+
+	get := func(handler http.HandlerFunc, path string) *httptest.ResponseRecorder {
+		r, err := http.NewRequest("GET", path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r.SetBasicAuth("admin", "password")
+		w := httptest.NewRecorder()
+		handler(w, r)
+		if w.Code != http.StatusOK {
+			t.Error(fmt.Sprintf("Test failed. Expected %s to succeed. Actual %d %s", path, w.Code, w.Body))
+		}
+		return w
+	}
+
+	stats := []WebsocketStats{}
+	if err := JSONDecode(get(websocketStatsJSON, "/websockets.json").Body.Bytes(), &stats); err != nil {
+		t.Fatal(err)
+	}
+	found := false
+	for _, x := range stats {
+		if x.Name != p.Name {
+			continue
+		}
+		found = true
+		if x.State != WEBSOCKET_STATE_CONNECTED || x.Subscriptions != 2 || x.Messages == 0 {
+			t.Error(fmt.Sprintf("Test failed. Expected a connected websocket with 2 subscriptions. Actual %+v", x))
+		}
+	}
+	if !found {
+		t.Error(fmt.Sprintf("Test failed. Expected %s in the websocket stats. Actual %+v", p.Name, stats))
+	}
+	if body := get(journalCSV, "/journal.csv").Body.String(); !strings.HasPrefix(body, "time,exchange,") {
+		t.Error(fmt.Sprintf("Test failed. Expected the journal's CSV header. Actual %s", body))
+	}
+	get(taxLotsCSV, "/taxlots.csv")
+
+	close(done)
+	select {
+	case <-stopped:
+	case <-time.After(30 * time.Second):
+		t.Fatal("Test failed. Expected realTrade to stop.")
+	}
+	if builder() != nil {
+		t.Error("Test failed. Expected realTrade to stop its candle builder.")
+	}
+}
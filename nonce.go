@@ -0,0 +1,113 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// Nonces hands out strictly increasing nonces per set of API credentials. A
+// nonce is the current time in the exchange's unit, or one more than the last
+// if the clock hasn't moved on since, so concurrent requests never share one.
+// Nonces that have run ahead of the clock are saved as they're issued, so a
+// restart carries on above them. Nonces that track the clock don't need to
+// be: by the time we're back up the clock is past them.
+
+const (
+	NONCES_FILE = "nonces.json"
+)
+
+type NonceManager struct {
+	mtx  sync.Mutex
+	last map[string]int64
+	path string
+}
+
+// Nonces is shared by every exchange.
+var Nonces = NewNonceManager()
+
+func NewNonceManager() *NonceManager {
+	return &NonceManager{last: make(map[string]int64)}
+}
+
+// NonceKey identifies a set of credentials without saving the API key itself.
+func NonceKey(exchange, apiKey string) string {
+	return exchange + " " + HexEncodeToString(GetSHA256([]byte(apiKey)))[0:16]
+}
+
+// Next returns the next nonce for key, counted in unit since the epoch, e.g.
+// time.Millisecond for a 13 digit nonce.
+func (n *NonceManager) Next(key string, unit time.Duration) int64 {
+	now := time.Now().UnixNano() / int64(unit)
+
+	n.mtx.Lock()
+	defer n.mtx.Unlock()
+
+	nonce := now
+	if last := n.last[key]; nonce <= last {
+		nonce = last + 1
+	}
+	n.last[key] = nonce
+
+	if nonce > now {
+		if err := n.save(); err != nil {
+			log.Printf("Unable to save nonces to %s: %v", n.path, err)
+		}
+	}
+	return nonce
+}
+
+// save must be called with mtx held.
+func (n *NonceManager) save() error {
+	if n.path == "" {
+		return nil
+	}
+
+	payload, err := json.MarshalIndent(n.last, "", " ")
+	if err != nil {
+		return err
+	}
+
+	tmp := n.path + ".tmp"
+	err = ioutil.WriteFile(tmp, payload, 0600)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp, n.path)
+}
+
+func (n *NonceManager) Save() error {
+	n.mtx.Lock()
+	defer n.mtx.Unlock()
+	return n.save()
+}
+
+// Load picks up the nonces saved at path, and saves to path from then on. A
+// missing file is not an error.
+func (n *NonceManager) Load(path string) error {
+	last := make(map[string]int64)
+	file, err := ioutil.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err == nil {
+		err = json.Unmarshal(file, &last)
+		if err != nil {
+			return fmt.Errorf("unable to parse %s: %v", path, err)
+		}
+	}
+
+	n.mtx.Lock()
+	defer n.mtx.Unlock()
+	n.path = path
+	for key, x := range last {
+		if x > n.last[key] {
+			n.last[key] = x
+		}
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestNonceConcurrent(t *testing.T) {
+	t.Parallel()
+	n := NewNonceManager()
+	key := NonceKey("Test", "key")
+
+	var mtx sync.Mutex
+	var wg sync.WaitGroup
+	seen := make(map[int64]bool)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for k := 0; k < 100; k++ {
+				nonce := n.Next(key, time.Second)
+				mtx.Lock()
+				seen[nonce] = true
+				mtx.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(seen) != 1000 {
+		t.Error(fmt.Sprintf("Test failed. Expected 1000 unique nonces. Actual %d", len(seen)))
+	}
+	if other := n.Next(NonceKey("Test", "other"), time.Second); other > time.Now().Unix() {
+		t.Error(fmt.Sprintf("Test failed. Expected other credentials to track the clock. Actual %d", other))
+	}
+}
+
+func TestNoncePersistence(t *testing.T) {
+	t.Parallel()
+	dir, err := ioutil.TempDir("", "nonces")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, NONCES_FILE)
+
+	n := NewNonceManager()
+	if err = n.Load(path); err != nil {
+		t.Fatal(err)
+	}
+	key := NonceKey("Test", "key")
+	var last int64
+	for i := 0; i < 5; i++ {
+		last = n.Next(key, time.Second)
+	}
+
+	// the nonces ran ahead of the clock, a restart has to carry on above them
+	restarted := NewNonceManager()
+	if err = restarted.Load(path); err != nil {
+		t.Fatal(err)
+	}
+	if next := restarted.Next(key, time.Second); next <= last {
+		t.Error(fmt.Sprintf("Test failed. Expected nonce above %d. Actual %d", last, next))
+	}
+}
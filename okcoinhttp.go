@@ -1,6 +1,7 @@
 package main
 
 import (
+	"encoding/json"
 	"errors"
 	"github.com/gorilla/websocket"
 	"log"
@@ -62,6 +63,25 @@ const (
 	OKCOIN_FUTURES_DEVOLVE         = "future_devolve.do"
 )
 
+const (
+	OKCOIN_CONTRACT_THIS_WEEK = "this_week"
+	OKCOIN_CONTRACT_NEXT_WEEK = "next_week"
+	OKCOIN_CONTRACT_QUARTER   = "quarter"
+
+	OKCOIN_FUTURES_OPEN_LONG   = "1"
+	OKCOIN_FUTURES_OPEN_SHORT  = "2"
+	OKCOIN_FUTURES_CLOSE_LONG  = "3"
+	OKCOIN_FUTURES_CLOSE_SHORT = "4"
+
+	OKCOIN_FUTURES_MARGIN_CROSS = "cross"
+	OKCOIN_FUTURES_MARGIN_FIXED = "fixed"
+)
+
+var (
+	ErrOKCoinContractType    = errors.New("Futures contract type must be this_week, next_week or quarter.")
+	ErrOKCoinFuturesLeverage = errors.New("Futures leverage must be 10 or 20.")
+)
+
 type OKCoin struct {
 	Name                         string
 	Enabled                      bool
@@ -78,6 +98,7 @@ type OKCoin struct {
 	AvailablePairs               []string
 	EnabledPairs                 []string
 	FuturesValues                []string
+	FuturesMarginMode            string
 	WebsocketConn                *websocket.Conn
 }
 
@@ -158,14 +179,14 @@ type OKCoinAccountRecords struct {
 type OKCoinFuturesOrder struct {
 	Amount       float64 `json:"amount"`
 	ContractName string  `json:"contract_name"`
-	DateCreated  float64 `json:"create_date"`
+	DateCreated  int64   `json:"create_date"`
 	TradeAmount  float64 `json:"deal_amount"`
 	Fee          float64 `json:"fee"`
-	LeverageRate float64 `json:"lever_rate"`
+	LeverageRate int64   `json:"lever_rate"`
 	OrderID      int64   `json:"order_id"`
 	Price        float64 `json:"price"`
 	AvgPrice     float64 `json:"avg_price"`
-	Status       float64 `json:"status"`
+	Status       int64   `json:"status"`
 	Symbol       string  `json:"symbol"`
 	Type         int64   `json:"type"`
 	UnitAmount   int64   `json:"unit_amount"`
@@ -198,7 +219,8 @@ func (o *OKCoin) SetDefaults() {
 	o.Verbose = false
 	o.Websocket = false
 	o.RESTPollingDelay = 10
-	o.FuturesValues = []string{"this_week", "next_week", "quarter"}
+	o.FuturesValues = []string{OKCOIN_CONTRACT_THIS_WEEK, OKCOIN_CONTRACT_NEXT_WEEK, OKCOIN_CONTRACT_QUARTER}
+	o.FuturesMarginMode = OKCOIN_FUTURES_MARGIN_CROSS
 }
 
 func (o *OKCoin) GetName() string {
@@ -226,6 +248,11 @@ func (o *OKCoin) Setup(exch Exchanges) {
 		o.BaseCurrencies = SplitStrings(exch.BaseCurrencies, ",")
 		o.AvailablePairs = SplitStrings(exch.AvailablePairs, ",")
 		o.EnabledPairs = SplitStrings(exch.EnabledPairs, ",")
+		urls := GetExchangeURLs(exch, ExchangeURLs{o.APIUrl, o.WebsocketURL})
+		o.APIUrl, o.WebsocketURL = urls.API, urls.Websocket
+		if exch.FuturesMarginMode != "" {
+			o.FuturesMarginMode = exch.FuturesMarginMode
+		}
 	}
 }
 
@@ -237,13 +264,19 @@ func (o *OKCoin) SetURL(url string) {
 	o.APIUrl = url
 }
 
+// IsInternational reports whether this is OKCoin's international exchange
+// rather than the Chinese one, whichever URLs it has been pointed at.
+func (o *OKCoin) IsInternational() bool {
+	return o.Name == "OKCOIN International"
+}
+
 func (o *OKCoin) SetAPIKeys(apiKey, apiSecret string) {
 	o.PartnerID = apiKey
 	o.SecretKey = apiSecret
 }
 
 func (o *OKCoin) GetFee(maker bool) float64 {
-	if o.APIUrl == OKCOIN_API_URL {
+	if o.IsInternational() {
 		if maker {
 			return o.MakerFee
 		} else {
@@ -268,7 +301,7 @@ func (o *OKCoin) Run() {
 	for o.Enabled {
 		for _, x := range o.EnabledPairs {
 			currency := StringToLower(x[0:3] + "_" + x[3:])
-			if o.APIUrl == OKCOIN_API_URL {
+			if o.IsInternational() {
 				for _, y := range o.FuturesValues {
 					futuresValue := y
 					go func() {
@@ -315,7 +348,7 @@ func (o *OKCoin) GetTicker(symbol string) (OKCoinTicker, error) {
 	vals := url.Values{}
 	vals.Set("symbol", symbol)
 	path := EncodeURLValues(o.APIUrl+OKCOIN_TICKER, vals)
-	err := SendHTTPGetRequest(path, true, &resp)
+	err := GetHTTPClient(o.Name).SendHTTPGetRequest(path, true, &resp)
 	if err != nil {
 		return OKCoinTicker{}, err
 	}
@@ -334,7 +367,7 @@ func (o *OKCoin) GetOrderBook(symbol string, size int64, merge bool) (OKCoinOrde
 	}
 
 	path := EncodeURLValues(o.APIUrl+OKCOIN_DEPTH, vals)
-	err := SendHTTPGetRequest(path, true, &resp)
+	err := GetHTTPClient(o.Name).SendHTTPGetRequest(path, true, &resp)
 	if err != nil {
 		return resp, err
 	}
@@ -343,7 +376,7 @@ func (o *OKCoin) GetOrderBook(symbol string, size int64, merge bool) (OKCoinOrde
 
 type OKCoinTrades struct {
 	Amount  float64 `json:"amount,string"`
-	Date    int64   `json:"date`
+	Date    int64   `json:"date"`
 	DateMS  int64   `json:"date_ms"`
 	Price   float64 `json:"price,string"`
 	TradeID int64   `json:"tid"`
@@ -359,15 +392,26 @@ func (o *OKCoin) GetTrades(symbol string, since int64) ([]OKCoinTrades, error) {
 	}
 
 	path := EncodeURLValues(o.APIUrl+OKCOIN_TRADES, vals)
-	err := SendHTTPGetRequest(path, true, &result)
+	err := GetHTTPClient(o.Name).SendHTTPGetRequest(path, true, &result)
 	if err != nil {
 		return nil, err
 	}
 	return result, nil
 }
 
-func (o *OKCoin) GetKline(symbol, klineType string, size, since int64) ([]interface{}, error) {
-	resp := []interface{}{}
+// OKCoinKline is a candle starting at Time (in milliseconds). ContractVolume
+// is only set for futures, where Volume is in contracts.
+type OKCoinKline struct {
+	Time           int64
+	Open           float64
+	High           float64
+	Low            float64
+	Close          float64
+	Volume         float64
+	ContractVolume float64
+}
+
+func (o *OKCoin) GetKline(symbol, klineType string, size, since int64) ([]OKCoinKline, error) {
 	vals := url.Values{}
 	vals.Set("symbol", symbol)
 	vals.Set("type", klineType)
@@ -380,13 +424,28 @@ func (o *OKCoin) GetKline(symbol, klineType string, size, since int64) ([]interf
 		vals.Set("since", strconv.FormatInt(since, 10))
 	}
 
-	path := EncodeURLValues(o.APIUrl+OKCOIN_KLINE, vals)
-	err := SendHTTPGetRequest(path, true, &resp)
+	return o.getKline(EncodeURLValues(o.APIUrl+OKCOIN_KLINE, vals), false)
+}
+
+func (o *OKCoin) getKline(path string, futures bool) ([]OKCoinKline, error) {
+	resp := [][]float64{}
+	err := GetHTTPClient(o.Name).SendHTTPGetRequest(path, true, &resp)
 	if err != nil {
 		return nil, err
 	}
 
-	return resp, nil
+	klines := []OKCoinKline{}
+	for _, x := range resp {
+		if len(x) < 6 || futures && len(x) < 7 {
+			return nil, errors.New("Unexpected kline length.")
+		}
+		kline := OKCoinKline{Time: int64(x[0]), Open: x[1], High: x[2], Low: x[3], Close: x[4], Volume: x[5]}
+		if futures {
+			kline.Volume, kline.ContractVolume = x[6], x[5]
+		}
+		klines = append(klines, kline)
+	}
+	return klines, nil
 }
 
 func (o *OKCoin) GetFuturesTicker(symbol, contractType string) (OKCoinFuturesTicker, error) {
@@ -395,7 +454,7 @@ func (o *OKCoin) GetFuturesTicker(symbol, contractType string) (OKCoinFuturesTic
 	vals.Set("symbol", symbol)
 	vals.Set("contract_type", contractType)
 	path := EncodeURLValues(o.APIUrl+OKCOIN_FUTURES_TICKER, vals)
-	err := SendHTTPGetRequest(path, true, &resp)
+	err := GetHTTPClient(o.Name).SendHTTPGetRequest(path, true, &resp)
 	if err != nil {
 		return OKCoinFuturesTicker{}, err
 	}
@@ -416,7 +475,7 @@ func (o *OKCoin) GetFuturesDepth(symbol, contractType string, size int64, merge
 	}
 
 	path := EncodeURLValues(o.APIUrl+OKCOIN_FUTURES_DEPTH, vals)
-	err := SendHTTPGetRequest(path, true, &result)
+	err := GetHTTPClient(o.Name).SendHTTPGetRequest(path, true, &result)
 	if err != nil {
 		return result, err
 	}
@@ -439,7 +498,7 @@ func (o *OKCoin) GetFuturesTrades(symbol, contractType string) ([]OKCoinFuturesT
 	vals.Set("contract_type", contractType)
 
 	path := EncodeURLValues(o.APIUrl+OKCOIN_FUTURES_TRADES, vals)
-	err := SendHTTPGetRequest(path, true, &result)
+	err := GetHTTPClient(o.Name).SendHTTPGetRequest(path, true, &result)
 	if err != nil {
 		return nil, err
 	}
@@ -456,7 +515,7 @@ func (o *OKCoin) GetFuturesIndex(symbol string) (float64, error) {
 	vals.Set("symbol", symbol)
 
 	path := EncodeURLValues(o.APIUrl+OKCOIN_FUTURES_INDEX, vals)
-	err := SendHTTPGetRequest(path, true, &result)
+	err := GetHTTPClient(o.Name).SendHTTPGetRequest(path, true, &result)
 	if err != nil {
 		return 0, err
 	}
@@ -469,7 +528,7 @@ func (o *OKCoin) GetFuturesExchangeRate() (float64, error) {
 	}
 
 	result := Response{}
-	err := SendHTTPGetRequest(o.APIUrl+OKCOIN_EXCHANGE_RATE, true, &result)
+	err := GetHTTPClient(o.Name).SendHTTPGetRequest(o.APIUrl+OKCOIN_EXCHANGE_RATE, true, &result)
 	if err != nil {
 		return result.Rate, err
 	}
@@ -485,15 +544,18 @@ func (o *OKCoin) GetFuturesEstimatedPrice(symbol string) (float64, error) {
 	vals := url.Values{}
 	vals.Set("symbol", symbol)
 	path := EncodeURLValues(o.APIUrl+OKCOIN_FUTURES_ESTIMATED_PRICE, vals)
-	err := SendHTTPGetRequest(path, true, &result)
+	err := GetHTTPClient(o.Name).SendHTTPGetRequest(path, true, &result)
 	if err != nil {
 		return result.Price, err
 	}
 	return result.Price, nil
 }
 
-func (o *OKCoin) GetFuturesKline(symbol, klineType, contractType string, size, since int64) ([]interface{}, error) {
-	resp := []interface{}{}
+func (o *OKCoin) GetFuturesKline(symbol, klineType, contractType string, size, since int64) ([]OKCoinKline, error) {
+	if !IsOKCoinContractType(contractType) {
+		return nil, ErrOKCoinContractType
+	}
+
 	vals := url.Values{}
 	vals.Set("symbol", symbol)
 	vals.Set("type", klineType)
@@ -506,13 +568,7 @@ func (o *OKCoin) GetFuturesKline(symbol, klineType, contractType string, size, s
 		vals.Set("since", strconv.FormatInt(since, 10))
 	}
 
-	path := EncodeURLValues(o.APIUrl+OKCOIN_FUTURES_KLINE, vals)
-	err := SendHTTPGetRequest(path, true, &resp)
-
-	if err != nil {
-		return nil, err
-	}
-	return resp, nil
+	return o.getKline(EncodeURLValues(o.APIUrl+OKCOIN_FUTURES_KLINE, vals), true)
 }
 
 func (o *OKCoin) GetFuturesHoldAmount(symbol, contractType string) ([]OKCoinFuturesHoldAmount, error) {
@@ -522,7 +578,7 @@ func (o *OKCoin) GetFuturesHoldAmount(symbol, contractType string) ([]OKCoinFutu
 	vals.Set("contract_type", contractType)
 
 	path := EncodeURLValues(o.APIUrl+OKCOIN_FUTURES_HOLD_AMOUNT, vals)
-	err := SendHTTPGetRequest(path, true, &resp)
+	err := GetHTTPClient(o.Name).SendHTTPGetRequest(path, true, &resp)
 
 	if err != nil {
 		return nil, err
@@ -543,7 +599,7 @@ func (o *OKCoin) GetFuturesExplosive(symbol, contractType string, status, curren
 	vals.Set("page_length", strconv.FormatInt(pageLength, 10))
 
 	path := EncodeURLValues(o.APIUrl+OKCOIN_FUTURES_EXPLOSIVE, vals)
-	err := SendHTTPGetRequest(path, true, &resp)
+	err := GetHTTPClient(o.Name).SendHTTPGetRequest(path, true, &resp)
 
 	if err != nil {
 		return nil, err
@@ -660,26 +716,16 @@ func (o *OKCoin) BatchTrade(orderData string, symbol, orderType string) (OKCoinB
 }
 
 type OKCoinCancelOrderResponse struct {
-	Success string
-	Error   string
+	Result  bool   `json:"result"`
+	OrderID int64  `json:"order_id"`
+	Success string `json:"success"`
+	Error   string `json:"error"`
 }
 
 func (o *OKCoin) CancelOrder(orderID []int64, symbol string) (OKCoinCancelOrderResponse, error) {
 	v := url.Values{}
-	orders := []string{}
-	orderStr := ""
 	result := OKCoinCancelOrderResponse{}
-
-	if len(orderID) > 1 {
-		for x := range orderID {
-			orders = append(orders, strconv.FormatInt(orderID[x], 10))
-		}
-		orderStr = JoinStrings(orders, ",")
-	} else {
-		orderStr = strconv.FormatInt(orderID[0], 10)
-	}
-
-	v.Set("order_id", orderStr)
+	v.Set("order_id", okcoinOrderIDs(orderID))
 	v.Set("symbol", symbol)
 
 	err := o.SendAuthenticatedHTTPRequest(OKCOIN_ORDER_CANCEL, v, &result)
@@ -925,7 +971,7 @@ func (o *OKCoin) GetBorrowInfo(symbol string) (OKCoinBorrowInfo, error) {
 	err := o.SendAuthenticatedHTTPRequest(OKCOIN_BORROWS_INFO, v, &result)
 
 	if err != nil {
-		return result, nil
+		return result, err
 	}
 
 	return result, nil
@@ -1039,47 +1085,175 @@ func (o *OKCoin) GetUnrepaymentsInfo(symbol string, currentPage, pageLength int)
 	return result.Unrepayments, nil
 }
 
-func (o *OKCoin) GetAccountRecords(symbol string, recType, currentPage, pageLength int) ([]OKCoinAccountRecords, error) {
-	type Response struct {
-		Records []OKCoinAccountRecords `json:"records"`
-		Symbol  string                 `json:"symbol"`
-	}
+func (o *OKCoin) GetAccountRecords(symbol string, recType, currentPage, pageLength int) (OKCoinAccountRecords, error) {
 	v := url.Values{}
 	v.Set("symbol", symbol)
 	v.Set("type", strconv.Itoa(recType))
 	v.Set("current_page", strconv.Itoa(currentPage))
 	v.Set("page_length", strconv.Itoa(pageLength))
-	result := Response{}
+	result := OKCoinAccountRecords{}
 
 	err := o.SendAuthenticatedHTTPRequest(OKCOIN_ACCOUNT_RECORDS, v, &result)
 
 	if err != nil {
-		return nil, err
+		return result, err
+	}
+
+	return result, nil
+}
+
+func IsOKCoinContractType(contractType string) bool {
+	switch contractType {
+	case OKCOIN_CONTRACT_THIS_WEEK, OKCOIN_CONTRACT_NEXT_WEEK, OKCOIN_CONTRACT_QUARTER:
+		return true
+	}
+	return false
+}
+
+// OKCoinFuturesDelivery returns when the contract type trading at now is
+// delivered. Contracts settle on Fridays at 08:00 UTC, quarterlies on the last
+// Friday of March, June, September and December; once a quarterly is within
+// two weeks of delivery it becomes next_week and the following quarter lists.
+func OKCoinFuturesDelivery(contractType string, now time.Time) (time.Time, error) {
+	now = now.UTC()
+	delivery := time.Date(now.Year(), now.Month(), now.Day(), 8, 0, 0, 0, time.UTC)
+	delivery = delivery.AddDate(0, 0, int(time.Friday-delivery.Weekday()+7)%7)
+	if !delivery.After(now) {
+		delivery = delivery.AddDate(0, 0, 7)
+	}
+
+	switch contractType {
+	case OKCOIN_CONTRACT_THIS_WEEK:
+		return delivery, nil
+	case OKCOIN_CONTRACT_NEXT_WEEK:
+		return delivery.AddDate(0, 0, 7), nil
+	case OKCOIN_CONTRACT_QUARTER:
+		nextWeek := delivery.AddDate(0, 0, 7)
+		month := time.Date(now.Year(), now.Month()+2-(now.Month()-1)%3, 1, 8, 0, 0, 0, time.UTC)
+		for {
+			quarter := month.AddDate(0, 1, -1)
+			quarter = quarter.AddDate(0, 0, -(int(quarter.Weekday()-time.Friday+7) % 7))
+			if quarter.After(nextWeek) {
+				return quarter, nil
+			}
+			month = month.AddDate(0, 3, 0)
+		}
 	}
+	return time.Time{}, ErrOKCoinContractType
+}
 
-	return result.Records, nil
+type OKCoinFuturesAccount struct {
+	AccountRights float64 `json:"account_rights"`
+	KeepDeposit   float64 `json:"keep_deposit"`
+	ProfitReal    float64 `json:"profit_real"`
+	ProfitUnreal  float64 `json:"profit_unreal"`
+	RiskRate      float64 `json:"risk_rate"`
 }
 
-func (o *OKCoin) GetFuturesUserInfo() {
-	err := o.SendAuthenticatedHTTPRequest(OKCOIN_FUTURES_USERINFO, url.Values{}, nil)
+// GetFuturesUserInfo returns the cross margin futures account for each
+// currency.
+func (o *OKCoin) GetFuturesUserInfo() (map[string]OKCoinFuturesAccount, error) {
+	type Response struct {
+		Info   map[string]OKCoinFuturesAccount `json:"info"`
+		Result bool                            `json:"result"`
+	}
+	result := Response{}
+
+	err := o.SendAuthenticatedHTTPRequest(OKCOIN_FUTURES_USERINFO, url.Values{}, &result)
 
 	if err != nil {
-		log.Println(err)
+		return nil, err
 	}
+
+	if !result.Result {
+		return nil, errors.New("Unable to get futures user info.")
+	}
+
+	return result.Info, nil
 }
 
-func (o *OKCoin) GetFuturesPosition(symbol, contractType string) {
-	v := url.Values{}
+type OKCoinFuturesPosition struct {
+	BuyAmount           float64 `json:"buy_amount"`
+	BuyAvailable        float64 `json:"buy_available"`
+	BuyBond             float64 `json:"buy_bond"`
+	BuyFlatPrice        float64 `json:"buy_flatprice,string"`
+	BuyPriceAvg         float64 `json:"buy_price_avg"`
+	BuyPriceCost        float64 `json:"buy_price_cost"`
+	BuyProfitLossRatio  float64 `json:"buy_profit_lossratio,string"`
+	BuyProfitReal       float64 `json:"buy_profit_real"`
+	ContractID          int64   `json:"contract_id"`
+	ContractType        string  `json:"contract_type"`
+	CreateDate          int64   `json:"create_date"`
+	LeverRate           int64   `json:"lever_rate"`
+	SellAmount          float64 `json:"sell_amount"`
+	SellAvailable       float64 `json:"sell_available"`
+	SellBond            float64 `json:"sell_bond"`
+	SellFlatPrice       float64 `json:"sell_flatprice,string"`
+	SellPriceAvg        float64 `json:"sell_price_avg"`
+	SellPriceCost       float64 `json:"sell_price_cost"`
+	SellProfitLossRatio float64 `json:"sell_profit_lossratio,string"`
+	SellProfitReal      float64 `json:"sell_profit_real"`
+	Symbol              string  `json:"symbol"`
+}
+
+// OKCoinFuturesPositions holds the positions for one contract. Bond, flat
+// price and profit/loss ratio are only set for fixed margin positions, and
+// ForceLiquPrice only for cross margin.
+type OKCoinFuturesPositions struct {
+	ForceLiquPrice float64                 `json:"force_liqu_price,string"`
+	Holding        []OKCoinFuturesPosition `json:"holding"`
+	Result         bool                    `json:"result"`
+}
+
+func (o *OKCoin) GetFuturesPosition(symbol, contractType string) (OKCoinFuturesPositions, error) {
+	return o.getFuturesPosition(OKCOIN_FUTURES_POSITION, symbol, contractType, url.Values{})
+}
+
+// GetFuturesPositions returns the positions for the configured margin mode.
+func (o *OKCoin) GetFuturesPositions(symbol, contractType string) (OKCoinFuturesPositions, error) {
+	if o.FuturesMarginMode == OKCOIN_FUTURES_MARGIN_FIXED {
+		return o.GetFuturesUserPosition4Fix(symbol, contractType)
+	}
+	return o.GetFuturesPosition(symbol, contractType)
+}
+
+func (o *OKCoin) getFuturesPosition(method, symbol, contractType string, v url.Values) (OKCoinFuturesPositions, error) {
+	result := OKCoinFuturesPositions{}
+
+	if !IsOKCoinContractType(contractType) {
+		return result, ErrOKCoinContractType
+	}
+
 	v.Set("symbol", symbol)
 	v.Set("contract_type", contractType)
-	err := o.SendAuthenticatedHTTPRequest(OKCOIN_FUTURES_POSITION, v, nil)
+
+	err := o.SendAuthenticatedHTTPRequest(method, v, &result)
 
 	if err != nil {
-		log.Println(err)
+		return result, err
+	}
+
+	if !result.Result {
+		return result, errors.New("Unable to get futures position.")
 	}
+
+	return result, nil
 }
 
-func (o *OKCoin) FuturesTrade(amount, price float64, matchPrice, leverage int64, symbol, contractType, orderType string) {
+func (o *OKCoin) FuturesTrade(amount, price float64, matchPrice, leverage int64, symbol, contractType, orderType string) (int64, error) {
+	type Response struct {
+		Result  bool  `json:"result"`
+		OrderID int64 `json:"order_id"`
+	}
+
+	if !IsOKCoinContractType(contractType) {
+		return 0, ErrOKCoinContractType
+	}
+
+	if leverage != 10 && leverage != 20 {
+		return 0, ErrOKCoinFuturesLeverage
+	}
+
 	v := url.Values{}
 	v.Set("symbol", symbol)
 	v.Set("contract_type", contractType)
@@ -1088,42 +1262,91 @@ func (o *OKCoin) FuturesTrade(amount, price float64, matchPrice, leverage int64,
 	v.Set("type", orderType)
 	v.Set("match_price", strconv.FormatInt(matchPrice, 10))
 	v.Set("lever_rate", strconv.FormatInt(leverage, 10))
+	result := Response{}
 
-	err := o.SendAuthenticatedHTTPRequest(OKCOIN_FUTURES_TRADE, v, nil)
+	err := o.SendAuthenticatedHTTPRequest(OKCOIN_FUTURES_TRADE, v, &result)
 
 	if err != nil {
-		log.Println(err)
+		return 0, err
 	}
+
+	if !result.Result {
+		return 0, errors.New("Unable to place futures order.")
+	}
+
+	return result.OrderID, nil
 }
 
-func (o *OKCoin) FuturesBatchTrade(orderData, symbol, contractType string, leverage int64, orderType string) {
-	v := url.Values{} //to-do batch trade support for orders_data)
+type OKCoinFuturesBatchOrder struct {
+	Price      float64 `json:"price"`
+	Amount     float64 `json:"amount"`
+	Type       int64   `json:"type"`
+	MatchPrice int64   `json:"match_price"`
+}
+
+func (o *OKCoin) FuturesBatchTrade(orders []OKCoinFuturesBatchOrder, symbol, contractType string, leverage int64) (OKCoinBatchTrade, error) {
+	result := OKCoinBatchTrade{}
+
+	if !IsOKCoinContractType(contractType) {
+		return result, ErrOKCoinContractType
+	}
+
+	if leverage != 10 && leverage != 20 {
+		return result, ErrOKCoinFuturesLeverage
+	}
+
+	data, err := JSONEncode(orders)
+	if err != nil {
+		return result, err
+	}
+
+	v := url.Values{}
 	v.Set("symbol", symbol)
 	v.Set("contract_type", contractType)
-	v.Set("orders_data", orderData)
+	v.Set("orders_data", string(data))
 	v.Set("lever_rate", strconv.FormatInt(leverage, 10))
 
-	err := o.SendAuthenticatedHTTPRequest(OKCOIN_FUTURES_TRADE_BATCH, v, nil)
+	err = o.SendAuthenticatedHTTPRequest(OKCOIN_FUTURES_TRADE_BATCH, v, &result)
 
 	if err != nil {
-		log.Println(err)
+		return result, err
 	}
+
+	if !result.Result {
+		return result, errors.New("Unable to place futures orders.")
+	}
+
+	return result, nil
 }
 
-func (o *OKCoin) CancelFuturesOrder(orderID int64, symbol, contractType string) {
+func (o *OKCoin) CancelFuturesOrder(orderID []int64, symbol, contractType string) (OKCoinCancelOrderResponse, error) {
+	result := OKCoinCancelOrderResponse{}
+
+	if !IsOKCoinContractType(contractType) {
+		return result, ErrOKCoinContractType
+	}
+
 	v := url.Values{}
 	v.Set("symbol", symbol)
 	v.Set("contract_type", contractType)
-	v.Set("order_id", strconv.FormatInt(orderID, 10))
+	v.Set("order_id", okcoinOrderIDs(orderID))
 
-	err := o.SendAuthenticatedHTTPRequest(OKCOIN_FUTURES_CANCEL, v, nil)
+	err := o.SendAuthenticatedHTTPRequest(OKCOIN_FUTURES_CANCEL, v, &result)
 
 	if err != nil {
-		log.Println(err)
+		return result, err
 	}
+
+	return result, nil
 }
 
-func (o *OKCoin) GetFuturesOrderInfo(orderID, status, currentPage, pageLength int64, symbol, contractType string) {
+// GetFuturesOrderInfo returns a single order, or with an orderID of -1 a page
+// of orders with the given status (1 unfilled, 2 filled).
+func (o *OKCoin) GetFuturesOrderInfo(orderID, status, currentPage, pageLength int64, symbol, contractType string) ([]OKCoinFuturesOrder, error) {
+	if !IsOKCoinContractType(contractType) {
+		return nil, ErrOKCoinContractType
+	}
+
 	v := url.Values{}
 	v.Set("symbol", symbol)
 	v.Set("contract_type", contractType)
@@ -1132,47 +1355,93 @@ func (o *OKCoin) GetFuturesOrderInfo(orderID, status, currentPage, pageLength in
 	v.Set("current_page", strconv.FormatInt(currentPage, 10))
 	v.Set("page_length", strconv.FormatInt(pageLength, 10))
 
-	err := o.SendAuthenticatedHTTPRequest(OKCOIN_FUTURES_ORDER_INFO, v, nil)
+	return o.getFuturesOrders(OKCOIN_FUTURES_ORDER_INFO, v)
+}
 
-	if err != nil {
-		log.Println(err)
+func (o *OKCoin) GetFutureOrdersInfo(orderID []int64, contractType, symbol string) ([]OKCoinFuturesOrder, error) {
+	if !IsOKCoinContractType(contractType) {
+		return nil, ErrOKCoinContractType
 	}
-}
 
-func (o *OKCoin) GetFutureOrdersInfo(orderID int64, contractType, symbol string) {
 	v := url.Values{}
-	v.Set("order_id", strconv.FormatInt(orderID, 10))
+	v.Set("order_id", okcoinOrderIDs(orderID))
 	v.Set("contract_type", contractType)
 	v.Set("symbol", symbol)
 
-	err := o.SendAuthenticatedHTTPRequest(OKCOIN_FUTURES_ORDERS_INFO, v, nil)
+	return o.getFuturesOrders(OKCOIN_FUTURES_ORDERS_INFO, v)
+}
+
+func (o *OKCoin) getFuturesOrders(method string, v url.Values) ([]OKCoinFuturesOrder, error) {
+	type Response struct {
+		Orders []OKCoinFuturesOrder `json:"orders"`
+		Result bool                 `json:"result"`
+	}
+	result := Response{}
+
+	err := o.SendAuthenticatedHTTPRequest(method, v, &result)
 
 	if err != nil {
-		log.Println(err)
+		return nil, err
 	}
+
+	if !result.Result {
+		return nil, errors.New("Unable to get futures order info.")
+	}
+
+	return result.Orders, nil
 }
 
-func (o *OKCoin) GetFuturesUserInfo4Fix() {
-	v := url.Values{}
+type OKCoinFuturesContract struct {
+	Available    float64 `json:"available"`
+	Balance      float64 `json:"balance"`
+	Bond         float64 `json:"bond"`
+	ContractID   int64   `json:"contract_id"`
+	ContractType string  `json:"contract_type"`
+	Freeze       float64 `json:"freeze"`
+	Profit       float64 `json:"profit"`
+	Unprofit     float64 `json:"unprofit"`
+}
 
-	err := o.SendAuthenticatedHTTPRequest(OKCOIN_FUTURES_USERINFO_4FIX, v, nil)
+type OKCoinFuturesFixedAccount struct {
+	Balance   float64                 `json:"balance"`
+	Contracts []OKCoinFuturesContract `json:"contracts"`
+	Rights    float64                 `json:"rights"`
+}
+
+// GetFuturesUserInfo4Fix returns the fixed margin futures account for each
+// currency.
+func (o *OKCoin) GetFuturesUserInfo4Fix() (map[string]OKCoinFuturesFixedAccount, error) {
+	type Response struct {
+		Info   map[string]OKCoinFuturesFixedAccount `json:"info"`
+		Result bool                                 `json:"result"`
+	}
+	result := Response{}
+
+	err := o.SendAuthenticatedHTTPRequest(OKCOIN_FUTURES_USERINFO_4FIX, url.Values{}, &result)
 
 	if err != nil {
-		log.Println(err)
+		return nil, err
 	}
+
+	if !result.Result {
+		return nil, errors.New("Unable to get futures user info.")
+	}
+
+	return result.Info, nil
 }
 
-func (o *OKCoin) GetFuturesUserPosition4Fix(symbol, contractType string) {
+func (o *OKCoin) GetFuturesUserPosition4Fix(symbol, contractType string) (OKCoinFuturesPositions, error) {
 	v := url.Values{}
-	v.Set("symbol", symbol)
-	v.Set("contract_type", contractType)
 	v.Set("type", strconv.FormatInt(1, 10))
+	return o.getFuturesPosition(OKCOIN_FUTURES_POSITION_4FIX, symbol, contractType, v)
+}
 
-	err := o.SendAuthenticatedHTTPRequest(OKCOIN_FUTURES_POSITION_4FIX, v, nil)
-
-	if err != nil {
-		log.Println(err)
+func okcoinOrderIDs(orderID []int64) string {
+	orders := []string{}
+	for x := range orderID {
+		orders = append(orders, strconv.FormatInt(orderID[x], 10))
 	}
+	return JoinStrings(orders, ",")
 }
 
 func (o *OKCoin) SendAuthenticatedHTTPRequest(method string, v url.Values, result interface{}) (err error) {
@@ -1190,16 +1459,24 @@ func (o *OKCoin) SendAuthenticatedHTTPRequest(method string, v url.Values, resul
 	headers := make(map[string]string)
 	headers["Content-Type"] = "application/x-www-form-urlencoded"
 
-	resp, err := SendHTTPRequest("POST", path, headers, strings.NewReader(encoded))
+	resp, err := GetHTTPClient(o.Name).SendAuthenticatedHTTPRequest("POST", path, headers, strings.NewReader(encoded))
 
 	if err != nil {
-		return err
+		return ClassifyExchangeError(o.Name, err)
 	}
 
 	if o.Verbose {
 		log.Printf("Recieved raw: \n%s\n", resp)
 	}
 
+	failure := struct {
+		ErrorCode int64 `json:"error_code"`
+	}{}
+	if json.Unmarshal([]byte(resp), &failure) == nil && failure.ErrorCode != 0 {
+		code := strconv.FormatInt(failure.ErrorCode, 10)
+		return NewExchangeError(o.Name, code, o.RESTErrors[code])
+	}
+
 	err = JSONDecode([]byte(resp), &result)
 
 	if err != nil {
@@ -0,0 +1,260 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func okcoinFixtures(t *testing.T) (*OKCoin, *fixtureServer) {
+	o := &OKCoin{APIUrl: OKCOIN_API_URL}
+	o.SetDefaults()
+	o.SetAPIKeys("okcoin-key", "okcoin-secret")
+	return o, newFixtureServer(t, o.Name, "okcoin.json")
+}
+
+func TestOKCoinPublicFixtures(t *testing.T) {
+	o, f := okcoinFixtures(t)
+	defer f.Close()
+
+	runFixtureCases(t, f, []fixtureCase{
+		{Name: "GetTicker", Call: func() (interface{}, error) { return o.GetTicker("btc_usd") },
+			Want: []string{"{Buy:33.15 High:34.15 Last:33.15 Low:32.05 Sell:33.16 Vol:1.053269639199642e+07}"}},
+		{Name: "GetOrderBook", Call: func() (interface{}, error) { return o.GetOrderBook("btc_usd", 3, true) },
+			Params: map[string]string{"symbol": "btc_usd", "size": "3", "merge": "1"},
+			Want:   []string{"Asks:[[792 5] [789.68 0.018] [788.99 0.042]] Bids:[[787.1 0.35]"}},
+		{Name: "GetTrades", Call: func() (interface{}, error) { return o.GetTrades("btc_usd", 230000) },
+			Params: map[string]string{"since": "230000"},
+			Want:   []string{"[{Amount:0.1 Date:1367130137 DateMS:1367130137000 Price:787.71 TradeID:230433 Type:sell}]"}},
+		{Name: "GetKline", Call: func() (interface{}, error) { return o.GetKline("btc_usd", "1day", 1, 0) },
+			Params: map[string]string{"type": "1day", "size": "1", "since": ""},
+			Want:   []string{"[{Time:1417478400000 Open:2339.11 High:2383.15 Low:2322 Close:2369.85 Volume:83850.06 ContractVolume:0}]"}},
+		{Name: "GetFuturesTicker", Call: func() (interface{}, error) { return o.GetFuturesTicker("btc_usd", "this_week") },
+			Params: map[string]string{"contract_type": "this_week"},
+			Want:   []string{"{Last:409.2 Buy:408.23 Sell:409.18 High:432 Low:406 Vol:55764 Contract_ID:20140926012 Unit_Amount:100}"}},
+		{Name: "GetFuturesDepth", Call: func() (interface{}, error) { return o.GetFuturesDepth("btc_usd", "this_week", 2, false) },
+			Params: map[string]string{"contract_type": "this_week", "size": "2", "merge": ""},
+			Want:   []string{"Asks:[[411.8 6] [410.23 3]] Bids:[[410.05 4] [409.8 5]]"}},
+		{Name: "GetFuturesTrades", Call: func() (interface{}, error) { return o.GetFuturesTrades("btc_usd", "this_week") },
+			Want: []string{"[{Amount:100 Date:1411625481 DateMS:1411625481000 Price:411.93 TradeID:6543228 Type:sell}]"}},
+		{Name: "GetFuturesIndex", Call: func() (interface{}, error) { return o.GetFuturesIndex("btc_usd") },
+			Want: []string{"471.0817"}},
+		{Name: "GetFuturesExchangeRate", Call: func() (interface{}, error) { return o.GetFuturesExchangeRate() },
+			Want: []string{"6.1216"}},
+		{Name: "GetFuturesEstimatedPrice", Call: func() (interface{}, error) { return o.GetFuturesEstimatedPrice("btc_usd") },
+			Want: []string{"5.4"}},
+		{Name: "GetFuturesKline", Call: func() (interface{}, error) {
+			return o.GetFuturesKline("btc_usd", "1min", "this_week", 0, 1440308700000)
+		},
+			Params: map[string]string{"type": "1min", "contract_type": "this_week", "since": "1440308700000"},
+			Want:   []string{"[{Time:1440308700000 Open:233.37 High:233.48 Low:233.37 Close:233.48 Volume:22.2810015 ContractVolume:52}]"}},
+		{Name: "GetFuturesHoldAmount", Call: func() (interface{}, error) { return o.GetFuturesHoldAmount("btc_usd", "this_week") },
+			Want: []string{"[{Amount:106856 ContractName:BTC0213}]"}},
+		{Name: "GetFuturesExplosive", Call: func() (interface{}, error) { return o.GetFuturesExplosive("btc_usd", "this_week", 1, 1, 50) },
+			Params: map[string]string{"status": "1", "current_page": "1", "page_length": "50"},
+			Want:   []string{"[{Amount:20 DateCreated:2015-08-28 15:41:11 Loss:0.0002 Type:4}]"}},
+	})
+}
+
+func TestOKCoinAuthenticatedFixtures(t *testing.T) {
+	o, f := okcoinFixtures(t)
+	defer f.Close()
+
+	runFixtureCases(t, f, []fixtureCase{
+		{Name: "GetUserInfo", Call: func() (interface{}, error) { return o.GetUserInfo() },
+			Want: []string{"Free:{BTC:0.5 LTC:0 USD:1000} Freezed:{BTC:0.1 LTC:0 USD:0}", "Result:true"}},
+		{Name: "Trade", Call: func() (interface{}, error) { return o.Trade(0.5, 500, "btc_usd", "buy") },
+			Params: map[string]string{"amount": "0.5", "price": "500", "symbol": "btc_usd", "type": "buy"},
+			Want:   []string{"123456"}},
+		{Name: "Trade insufficient", Call: func() (interface{}, error) { return o.Trade(1000, 500, "btc_usd", "buy") },
+			Err: ERROR_INSUFFICIENT_FUNDS},
+		{Name: "GetTradeHistory", Call: func() (interface{}, error) { return o.GetTradeHistory("btc_usd", 1) },
+			Params: map[string]string{"since": "1"},
+			Want:   []string{"[{Amount:0.5 Date:1418019500 DateMS:1418019500000 Price:500 TradeID:1 Type:buy}]"}},
+		{Name: "BatchTrade", Call: func() (interface{}, error) {
+			return o.BatchTrade("[{price:3,amount:5,type:'sell'},{price:3,amount:3,type:'buy'}]", "btc_usd", "")
+		},
+			Params: map[string]string{"orders_data": "[{price:3,amount:5,type:'sell'},{price:3,amount:3,type:'buy'}]"},
+			Want:   []string{"{OrderInfo:[{OrderID:41724206 ErrorCode:0} {OrderID:-1 ErrorCode:10011}] Result:true}"}},
+		{Name: "CancelOrder", Call: func() (interface{}, error) { return o.CancelOrder([]int64{123456}, "btc_usd") },
+			Params: map[string]string{"order_id": "123456"},
+			Want:   []string{"{Result:true OrderID:123456 Success: Error:}"}},
+		{Name: "CancelOrder batch", Call: func() (interface{}, error) { return o.CancelOrder([]int64{123456, 123457}, "btc_usd") },
+			Params: map[string]string{"order_id": "123456,123457"},
+			Want:   []string{"Success:123456 Error:123457"}},
+		{Name: "CancelOrder unknown", Call: func() (interface{}, error) { return o.CancelOrder([]int64{1}, "btc_usd") },
+			Err: ERROR_ORDER_NOT_FOUND},
+		{Name: "GetOrderInfo", Call: func() (interface{}, error) { return o.GetOrderInfo(10000591, "btc_usd") },
+			Params: map[string]string{"order_id": "10000591"},
+			Want:   []string{"[{Amount:0.1 AvgPrice:0 Created:1418008467000 DealAmount:0 OrderID:10000591 OrdersID:10000591 Price:500 Status:0 Symbol:btc_usd Type:sell}]"}},
+		{Name: "GetOrderInfoBatch", Call: func() (interface{}, error) { return o.GetOrderInfoBatch([]int64{10000591, 10000592}, "btc_usd") },
+			Params: map[string]string{"order_id": "10000591,10000592"},
+			Want:   []string{"OrderID:10000591"}},
+		{Name: "GetOrderHistory", Call: func() (interface{}, error) { return o.GetOrderHistory(1, 1, "1", "btc_usd") },
+			Params: map[string]string{"status": "1", "current_page": "1", "page_length": "1"},
+			Want:   []string{"{CurrentPage:1 Orders:[{", "PageLength:1 Result:true Total:3}"}},
+		{Name: "Withdrawal", Call: func() (interface{}, error) {
+			return o.Withdrawal("btc_usd", 0.0001, "password", "1BGyWRCYnHEWGZTmVFs46iWEGjkqJGDGsx", 1)
+		},
+			Params: map[string]string{"chargefee": "0.0001", "trade_pwd": "password", "withdraw_address": "1BGyWRCYnHEWGZTmVFs46iWEGjkqJGDGsx", "withdraw_amount": "1"},
+			Want:   []string{"301"}},
+		{Name: "CancelWithdrawal", Call: func() (interface{}, error) { return o.CancelWithdrawal("btc_usd", 301) },
+			Params: map[string]string{"withdrawal_id": "301"},
+			Want:   []string{"301"}},
+		{Name: "GetWithdrawalInfo", Call: func() (interface{}, error) { return o.GetWithdrawalInfo("btc_usd", 301) },
+			Want: []string{"[{Address:1BGyWRCYnHEWGZTmVFs46iWEGjkqJGDGsx Amount:1 Created:1408616394000 ChargeFee:0.0001 Status:2 WithdrawID:301}]"}},
+		{Name: "GetOrderFeeInfo", Call: func() (interface{}, error) { return o.GetOrderFeeInfo("btc_usd", 123456) },
+			Want: []string{"{Fee:0.0002 OrderID:123456 Type:btc}"}},
+		{Name: "GetLendDepth", Call: func() (interface{}, error) { return o.GetLendDepth("btc_usd") },
+			Want: []string{"[{Amount:78 Days:10 Num:2 Rate:3}]"}},
+		{Name: "GetBorrowInfo", Call: func() (interface{}, error) { return o.GetBorrowInfo("btc_usd") },
+			Want: []string{"BorrowCNY:1.5 CanBorrow:1.8", "DailyInterestCNY:0.0015"}},
+		{Name: "Borrow", Call: func() (interface{}, error) { return o.Borrow("cny", "fifteen", 1000, 0.0015) },
+			Params: map[string]string{"days": "fifteen", "amount": "1000", "rate": "0.0015"},
+			Want:   []string{"3"}},
+		{Name: "CancelBorrow", Call: func() (interface{}, error) { return o.CancelBorrow("cny", 3) },
+			Params: map[string]string{"borrow_id": "3"},
+			Want:   []string{"true"}},
+		{Name: "GetBorrowOrderInfo", Call: func() (interface{}, error) { return o.GetBorrowOrderInfo(3) },
+			Want: []string{"BorrowCNY:1000", "DailyInterestCNY:1.5"}},
+		{Name: "GetRepaymentInfo", Call: func() (interface{}, error) { return o.GetRepaymentInfo(3) },
+			Params: map[string]string{"borrow_id": "3"},
+			Want:   []string{"true"}},
+		{Name: "GetUnrepaymentsInfo", Call: func() (interface{}, error) { return o.GetUnrepaymentsInfo("cny", 1, 10) },
+			Params: map[string]string{"current_page": "1", "page_length": "10"},
+			Want:   []string{"[{Amount:1000 BorrowDate:1414550000000 BorrowID:3 Days:10 TradeAmount:1000 Rate:0.0015 Status:0 Symbol:cny}]"}},
+		{Name: "GetAccountRecords", Call: func() (interface{}, error) { return o.GetAccountRecords("btc_usd", 1, 1, 10) },
+			Params: map[string]string{"type": "1"},
+			Want:   []string{"{Records:[{Address:1BGyWRCYnHEWGZTmVFs46iWEGjkqJGDGsx Account:1 Amount:0.1", "Symbol:btc}"}},
+	})
+}
+
+func TestOKCoinFuturesFixtures(t *testing.T) {
+	o, f := okcoinFixtures(t)
+	defer f.Close()
+
+	runFixtureCases(t, f, []fixtureCase{
+		{Name: "GetFuturesUserInfo", Call: func() (interface{}, error) { return o.GetFuturesUserInfo() },
+			Want: []string{"btc:{AccountRights:1.5 KeepDeposit:0.25 ProfitReal:0.03 ProfitUnreal:-0.01 RiskRate:6.5}", "ltc:{"}},
+		{Name: "GetFuturesUserInfo4Fix", Call: func() (interface{}, error) { return o.GetFuturesUserInfo4Fix() },
+			Want: []string{"btc:{Balance:0.0049 Contracts:[{Available:0.0049 Balance:0.0012 Bond:0.0012 ContractID:20140926012 ContractType:this_week Freeze:0 Profit:0.0001 Unprofit:-0.0002}] Rights:0.0049}"}},
+		{Name: "GetFuturesPositions cross", Call: func() (interface{}, error) { return o.GetFuturesPositions("btc_usd", OKCOIN_CONTRACT_THIS_WEEK) },
+			Params: map[string]string{"symbol": "btc_usd", "contract_type": "this_week", "type": ""},
+			Want:   []string{"ForceLiquPrice:0.07", "BuyAmount:1 BuyAvailable:1 BuyBond:0 BuyFlatPrice:0 BuyPriceAvg:422.78", "LeverRate:10"}},
+		{Name: "GetFuturesPositions fixed", Call: func() (interface{}, error) {
+			o.FuturesMarginMode = OKCOIN_FUTURES_MARGIN_FIXED
+			defer func() { o.FuturesMarginMode = OKCOIN_FUTURES_MARGIN_CROSS }()
+			return o.GetFuturesPositions("btc_usd", OKCOIN_CONTRACT_QUARTER)
+		},
+			Params: map[string]string{"contract_type": "quarter", "type": "1"},
+			Want:   []string{"ForceLiquPrice:0", "BuyBond:1.21 BuyFlatPrice:338.97", "SellProfitLossRatio:-1.5", "ContractType:quarter"}},
+		{Name: "FuturesTrade", Call: func() (interface{}, error) {
+			return o.FuturesTrade(1, 422.5, 0, 10, "btc_usd", OKCOIN_CONTRACT_NEXT_WEEK, OKCOIN_FUTURES_OPEN_SHORT)
+		},
+			Params: map[string]string{"amount": "1", "price": "422.5", "contract_type": "next_week", "type": "2", "match_price": "0", "lever_rate": "10"},
+			Want:   []string{"986"}},
+		{Name: "FuturesTrade insufficient", Call: func() (interface{}, error) {
+			return o.FuturesTrade(1000, 422.5, 1, 20, "btc_usd", OKCOIN_CONTRACT_THIS_WEEK, OKCOIN_FUTURES_OPEN_LONG)
+		},
+			Err: ERROR_INSUFFICIENT_FUNDS},
+		{Name: "FuturesBatchTrade", Call: func() (interface{}, error) {
+			return o.FuturesBatchTrade([]OKCoinFuturesBatchOrder{
+				{Price: 5, Amount: 2, Type: 1, MatchPrice: 1},
+				{Price: 2, Amount: 3, Type: 3},
+			}, "btc_usd", OKCOIN_CONTRACT_QUARTER, 20)
+		},
+			Params: map[string]string{"orders_data": `[{"price":5,"amount":2,"type":1,"match_price":1},{"price":2,"amount":3,"type":3,"match_price":0}]`, "lever_rate": "20"},
+			Want:   []string{"{OrderInfo:[{OrderID:41724206 ErrorCode:0} {OrderID:-1 ErrorCode:20012}] Result:true}"}},
+		{Name: "CancelFuturesOrder", Call: func() (interface{}, error) {
+			return o.CancelFuturesOrder([]int64{986}, "btc_usd", OKCOIN_CONTRACT_THIS_WEEK)
+		},
+			Params: map[string]string{"order_id": "986", "contract_type": "this_week"},
+			Want:   []string{"{Result:true OrderID:986 Success: Error:}"}},
+		{Name: "CancelFuturesOrder batch", Call: func() (interface{}, error) {
+			return o.CancelFuturesOrder([]int64{986, 987}, "btc_usd", OKCOIN_CONTRACT_THIS_WEEK)
+		},
+			Want: []string{"Success:986 Error:987"}},
+		{Name: "CancelFuturesOrder unknown", Call: func() (interface{}, error) {
+			return o.CancelFuturesOrder([]int64{1}, "btc_usd", OKCOIN_CONTRACT_THIS_WEEK)
+		},
+			Err: ERROR_ORDER_NOT_FOUND},
+		{Name: "GetFuturesOrderInfo", Call: func() (interface{}, error) {
+			return o.GetFuturesOrderInfo(-1, 1, 1, 50, "btc_usd", OKCOIN_CONTRACT_THIS_WEEK)
+		},
+			Params: map[string]string{"order_id": "-1", "status": "1", "current_page": "1", "page_length": "50"},
+			Want:   []string{"[{Amount:111 ContractName:LTC0815 DateCreated:1408076414000 TradeAmount:1 Fee:0 LeverageRate:10 OrderID:106837 Price:1111 AvgPrice:1111 Status:1 Symbol:btc_usd Type:1 UnitAmount:100}]"}},
+		{Name: "GetFutureOrdersInfo", Call: func() (interface{}, error) {
+			return o.GetFutureOrdersInfo([]int64{106837, 106838}, OKCOIN_CONTRACT_THIS_WEEK, "btc_usd")
+		},
+			Params: map[string]string{"order_id": "106837,106838"},
+			Want:   []string{"Fee:-0.0001", "AvgPrice:1110.5 Status:2", "Type:3"}},
+	})
+}
+
+func TestOKCoinFuturesDelivery(t *testing.T) {
+	for _, x := range []struct {
+		now, contractType, expected string
+	}{
+		{"2016-03-02T12:00:00Z", OKCOIN_CONTRACT_THIS_WEEK, "2016-03-04T08:00:00Z"},
+		{"2016-03-04T07:59:59Z", OKCOIN_CONTRACT_THIS_WEEK, "2016-03-04T08:00:00Z"},
+		{"2016-03-04T08:00:00Z", OKCOIN_CONTRACT_THIS_WEEK, "2016-03-11T08:00:00Z"},
+		{"2016-03-02T12:00:00Z", OKCOIN_CONTRACT_NEXT_WEEK, "2016-03-11T08:00:00Z"},
+		{"2016-03-02T12:00:00Z", OKCOIN_CONTRACT_QUARTER, "2016-03-25T08:00:00Z"},
+		{"2016-03-12T12:00:00Z", OKCOIN_CONTRACT_QUARTER, "2016-06-24T08:00:00Z"},
+		{"2016-11-20T12:00:00Z", OKCOIN_CONTRACT_QUARTER, "2016-12-30T08:00:00Z"},
+		{"2016-12-20T12:00:00Z", OKCOIN_CONTRACT_QUARTER, "2017-03-31T08:00:00Z"},
+	} {
+		now, _ := time.Parse(time.RFC3339, x.now)
+		delivery, err := OKCoinFuturesDelivery(x.contractType, now)
+		if err != nil || delivery.Format(time.RFC3339) != x.expected {
+			t.Error(fmt.Sprintf("Test failed. Expected %s delivery %s at %s. Actual %s %v", x.contractType, x.expected, x.now, delivery.Format(time.RFC3339), err))
+		}
+	}
+
+	if _, err := OKCoinFuturesDelivery("month", time.Now()); err != ErrOKCoinContractType {
+		t.Error(fmt.Sprintf("Test failed. Expected %v. Actual %v", ErrOKCoinContractType, err))
+	}
+
+	o := OKCoin{}
+	if _, err := o.GetFuturesKline("btc_usd", "1min", "month", 0, 0); err != ErrOKCoinContractType {
+		t.Error(fmt.Sprintf("Test failed. Expected %v. Actual %v", ErrOKCoinContractType, err))
+	}
+	if _, err := o.FuturesTrade(1, 422.5, 0, 5, "btc_usd", OKCOIN_CONTRACT_THIS_WEEK, OKCOIN_FUTURES_OPEN_LONG); err != ErrOKCoinFuturesLeverage {
+		t.Error(fmt.Sprintf("Test failed. Expected %v. Actual %v", ErrOKCoinFuturesLeverage, err))
+	}
+}
+
+func TestOKCoinSetupFuturesMarginMode(t *testing.T) {
+	o := &OKCoin{APIUrl: OKCOIN_API_URL}
+	o.SetDefaults()
+	o.Setup(Exchanges{Name: o.Name, Enabled: true})
+	if o.FuturesMarginMode != OKCOIN_FUTURES_MARGIN_CROSS {
+		t.Error(fmt.Sprintf("Test failed. Expected %s margin by default. Actual %s", OKCOIN_FUTURES_MARGIN_CROSS, o.FuturesMarginMode))
+	}
+	o.Setup(Exchanges{Name: o.Name, Enabled: true, FuturesMarginMode: OKCOIN_FUTURES_MARGIN_FIXED})
+	if o.FuturesMarginMode != OKCOIN_FUTURES_MARGIN_FIXED {
+		t.Error(fmt.Sprintf("Test failed. Expected %s margin. Actual %s", OKCOIN_FUTURES_MARGIN_FIXED, o.FuturesMarginMode))
+	}
+}
+
+func TestOKCoinSigning(t *testing.T) {
+	o, f := okcoinFixtures(t)
+	defer f.Close()
+
+	if _, err := o.GetUserInfo(); err != nil {
+		t.Fatal(err)
+	}
+
+	req := f.Last()
+	values := req.Form()
+	sign := values.Get("sign")
+	values.Del("sign")
+	expected := strings.ToUpper(HexEncodeToString(GetMD5([]byte(values.Encode() + "&secret_key=okcoin-secret"))))
+	if sign != expected || values.Get("api_key") != "okcoin-key" {
+		t.Error(fmt.Sprintf("Test failed. Expected signature %s. Actual %s", expected, sign))
+	}
+	if _, ok := req.Form()["secret_key"]; ok {
+		t.Error("Test failed. The secret key was sent with the request.")
+	}
+}
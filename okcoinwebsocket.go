@@ -13,6 +13,7 @@ import (
 )
 
 const (
+	OKCOIN_WEBSOCKET_HEARTBEAT            = time.Second * 30
 	OKCOIN_WEBSOCKET_USD_REALTRADES       = "ok_usd_realtrades"
 	OKCOIN_WEBSOCKET_CNY_REALTRADES       = "ok_cny_realtrades"
 	OKCOIN_WEBSOCKET_SPOTUSD_TRADE        = "ok_spotusd_trade"
@@ -154,7 +155,7 @@ type OKCoinWebsocketRealtrades struct {
 	Status               int64   `json:"status"`
 	Symbol               string  `json:"symbol"`
 	TradeAmount          float64 `json:"tradeAmount,string"`
-	TradePrice           float64 `json:"buy,string"`
+	TradePrice           float64 `json:"tradePrice,string"`
 	TradeType            string  `json:"tradeType"`
 	TradeUnitPrice       float64 `json:"tradeUnitPrice,string"`
 	UnTrade              float64 `json:"unTrade,string"`
@@ -213,34 +214,58 @@ func (o *OKCoin) PingHandler(message string) error {
 	return nil
 }
 
-func (o *OKCoin) AddChannel(channel string) {
-	event := OKCoinWebsocketEvent{"addChannel", channel}
+// WebsocketSend serialises writes with the supervisor's heartbeat.
+func (o *OKCoin) WebsocketSend(event interface{}) error {
 	json, err := JSONEncode(event)
 	if err != nil {
-		log.Println(err)
-		return
+		return err
 	}
-	err = o.WebsocketConn.WriteMessage(websocket.TextMessage, json)
+	return GetWebsocketSupervisor(o.GetName()).Write(func() error {
+		return o.WebsocketConn.WriteMessage(websocket.TextMessage, json)
+	})
+}
 
+// AddChannel records the channel so it is resubscribed after a reconnect,
+// adding it now if connected.
+func (o *OKCoin) AddChannel(channel string) {
+	err := GetWebsocketSupervisor(o.GetName()).Subscribe(WebsocketSubscription{Channel: channel})
 	if err != nil {
 		log.Println(err)
-		return
 	}
+}
 
-	if o.Verbose {
-		log.Printf("%s Adding channel: %s\n", o.GetName(), channel)
+// AddChannelSubscriptionAuthenticated is AddChannel for user data channels,
+// which are signed each time they are sent.
+func (o *OKCoin) AddChannelSubscriptionAuthenticated(channel string) {
+	err := GetWebsocketSupervisor(o.GetName()).Subscribe(WebsocketSubscription{Channel: channel, Authenticated: true})
+	if err != nil {
+		log.Println(err)
 	}
 }
 
-func (o *OKCoin) RemoveChannel(channel string) {
-	event := OKCoinWebsocketEvent{"removeChannel", channel}
-	json, err := JSONEncode(event)
+func (o *OKCoin) WebsocketSendSubscribe(sub WebsocketSubscription) error {
+	if sub.Authenticated {
+		values := make(map[string]string)
+		for k, v := range sub.Params {
+			values[k] = v
+		}
+		return o.sendChannelAuthenticated(sub.Channel, values)
+	}
+
+	err := o.WebsocketSend(OKCoinWebsocketEvent{"addChannel", sub.Channel})
 	if err != nil {
-		log.Println(err)
-		return
+		return err
+	}
+
+	if o.Verbose {
+		log.Printf("%s Adding channel: %s\n", o.GetName(), sub.Channel)
 	}
-	err = o.WebsocketConn.WriteMessage(websocket.TextMessage, json)
+	return nil
+}
 
+func (o *OKCoin) RemoveChannel(channel string) {
+	GetWebsocketSupervisor(o.GetName()).Unsubscribe(WebsocketSubscription{Channel: channel})
+	err := o.WebsocketSend(OKCoinWebsocketEvent{"removeChannel", channel})
 	if err != nil {
 		log.Println(err)
 		return
@@ -259,7 +284,7 @@ func (o *OKCoin) WebsocketSpotTrade(symbol, orderType string, price, amount floa
 	values["amount"] = strconv.FormatFloat(amount, 'f', -1, 64)
 	channel := ""
 
-	if o.WebsocketURL == OKCOIN_WEBSOCKET_URL_CHINA {
+	if !o.IsInternational() {
 		channel = OKCOIN_WEBSOCKET_SPOTCNY_TRADE
 	} else {
 		channel = OKCOIN_WEBSOCKET_SPOTUSD_TRADE
@@ -286,7 +311,7 @@ func (o *OKCoin) WebsocketSpotCancel(symbol string, orderID int64) {
 	values["order_id"] = strconv.FormatInt(orderID, 10)
 	channel := ""
 
-	if o.WebsocketURL == OKCOIN_WEBSOCKET_URL_CHINA {
+	if !o.IsInternational() {
 		channel = OKCOIN_WEBSOCKET_SPOTCNY_CANCEL_ORDER
 	} else {
 		channel = OKCOIN_WEBSOCKET_SPOTUSD_CANCEL_ORDER
@@ -309,7 +334,7 @@ func (o *OKCoin) WebsocketSpotOrderInfo(symbol string, orderID int64) {
 	values["order_id"] = strconv.FormatInt(orderID, 10)
 	channel := ""
 
-	if o.WebsocketURL == OKCOIN_WEBSOCKET_URL_CHINA {
+	if !o.IsInternational() {
 		channel = OKCOIN_WEBSOCKET_SPOTCNY_ORDER_INFO
 	} else {
 		channel = OKCOIN_WEBSOCKET_SPOTUSD_ORDER_INFO
@@ -343,36 +368,32 @@ func (o *OKCoin) WebsocketSign(values map[string]string) string {
 	return strings.ToUpper(HexEncodeToString(GetMD5([]byte(urlVals.Encode() + "&secret_key=" + o.SecretKey))))
 }
 
+// AddChannelAuthenticated sends a one off signed request such as a trade or
+// order query. It isn't replayed on reconnect.
 func (o *OKCoin) AddChannelAuthenticated(channel string, values map[string]string) {
-	values["sign"] = o.WebsocketSign(values)
-	event := OKCoinWebsocketEventAuth{"addChannel", channel, values}
-	json, err := JSONEncode(event)
+	err := o.sendChannelAuthenticated(channel, values)
 	if err != nil {
 		log.Println(err)
-		return
 	}
-	err = o.WebsocketConn.WriteMessage(websocket.TextMessage, json)
+}
 
+func (o *OKCoin) sendChannelAuthenticated(channel string, values map[string]string) error {
+	values["sign"] = o.WebsocketSign(values)
+	err := o.WebsocketSend(OKCoinWebsocketEventAuth{"addChannel", channel, values})
 	if err != nil {
-		log.Println(err)
-		return
+		return err
 	}
 
 	if o.Verbose {
 		log.Printf("%s Adding authenticated channel: %s\n", o.GetName(), channel)
 	}
+	return nil
 }
 
 func (o *OKCoin) RemoveChannelAuthenticated(conn *websocket.Conn, channel string, values map[string]string) {
+	GetWebsocketSupervisor(o.GetName()).Unsubscribe(WebsocketSubscription{Channel: channel, Authenticated: true})
 	values["sign"] = o.WebsocketSign(values)
-	event := OKCoinWebsocketEventAuthRemove{"removeChannel", channel, values}
-	json, err := JSONEncode(event)
-	if err != nil {
-		log.Println(err)
-		return
-	}
-	err = o.WebsocketConn.WriteMessage(websocket.TextMessage, json)
-
+	err := o.WebsocketSend(OKCoinWebsocketEventAuthRemove{"removeChannel", channel, values})
 	if err != nil {
 		log.Println(err)
 		return
@@ -387,7 +408,7 @@ func (o *OKCoin) WebsocketClient() {
 	klineValues := []string{"1min", "3min", "5min", "15min", "30min", "1hour", "2hour", "4hour", "6hour", "12hour", "day", "3day", "week"}
 	currencyChan, userinfoChan := "", ""
 
-	if o.WebsocketURL == OKCOIN_WEBSOCKET_URL_CHINA {
+	if !o.IsInternational() {
 		currencyChan = OKCOIN_WEBSOCKET_CNY_REALTRADES
 		userinfoChan = OKCOIN_WEBSOCKET_SPOTCNY_USERINFO
 	} else {
@@ -395,291 +416,394 @@ func (o *OKCoin) WebsocketClient() {
 		userinfoChan = OKCOIN_WEBSOCKET_SPOTUSD_USERINFO
 	}
 
-	for o.Enabled && o.Websocket {
-		var Dialer websocket.Dialer
-		var err error
-		o.WebsocketConn, _, err = Dialer.Dial(o.WebsocketURL, http.Header{})
-
-		if err != nil {
-			log.Printf("%s Unable to connect to Websocket. Error: %s\n", o.GetName(), err)
-			continue
-		}
-
-		if o.Verbose {
-			log.Printf("%s Connected to Websocket.\n", o.GetName())
+	if o.AuthenticatedAPISupport {
+		if o.IsInternational() {
+			o.AddChannelSubscriptionAuthenticated(OKCOIN_WEBSOCKET_FUTURES_REALTRADES)
+			o.AddChannelSubscriptionAuthenticated(OKCOIN_WEBSOCKET_FUTURES_USERINFO)
 		}
+		o.AddChannelSubscriptionAuthenticated(currencyChan)
+		o.AddChannelSubscriptionAuthenticated(userinfoChan)
+	}
 
-		o.WebsocketConn.SetPingHandler(o.PingHandler)
+	for _, x := range o.EnabledPairs {
+		currency := StringToLower(x)
+		if o.IsInternational() {
+			o.AddChannel(fmt.Sprintf("ok_%s_future_index", currency))
+			for _, y := range o.FuturesValues {
+				o.AddChannel(fmt.Sprintf("ok_%s_future_ticker_%s", currency, y))
+				o.AddChannel(fmt.Sprintf("ok_%s_future_depth_%s_60", currency, y))
+				o.AddChannel(fmt.Sprintf("ok_%s_future_trade_v1_%s", currency, y))
+				for _, z := range klineValues {
+					o.AddChannel(fmt.Sprintf("ok_future_%s_kline_%s_%s", currency, y, z))
+				}
+			}
+		} else {
+			o.AddChannel(fmt.Sprintf("ok_%s_ticker", currency))
+			o.AddChannel(fmt.Sprintf("ok_%s_depth60", currency))
+			o.AddChannel(fmt.Sprintf("ok_%s_trades_v1", currency))
 
-		if o.AuthenticatedAPISupport {
-			if o.WebsocketURL == OKCOIN_WEBSOCKET_URL {
-				o.AddChannelAuthenticated(OKCOIN_WEBSOCKET_FUTURES_REALTRADES, map[string]string{})
-				o.AddChannelAuthenticated(OKCOIN_WEBSOCKET_FUTURES_USERINFO, map[string]string{})
+			for _, y := range klineValues {
+				o.AddChannel(fmt.Sprintf("ok_%s_kline_%s", currency, y))
 			}
-			o.AddChannelAuthenticated(currencyChan, map[string]string{})
-			o.AddChannelAuthenticated(userinfoChan, map[string]string{})
 		}
+	}
+
+	s := GetWebsocketSupervisor(o.GetName())
+	s.Verbose = o.Verbose
+	s.HeartbeatInterval = OKCOIN_WEBSOCKET_HEARTBEAT
+	s.Running = func() bool { return o.Enabled && o.Websocket }
+	s.Dial = o.WebsocketConnect
+	s.Serve = o.WebsocketServe
+	s.Send = o.WebsocketSendSubscribe
+	s.Ping = func() error { return o.WebsocketSend(OKCoinWebsocketEvent{Event: "ping"}) }
+	s.Close = func() error { return o.WebsocketConn.Close() }
+	s.Run()
+}
+
+// WebsocketConnect dials and queries open orders, which are one off requests
+// rather than subscriptions so are made on every connection.
+func (o *OKCoin) WebsocketConnect() error {
+	var Dialer websocket.Dialer
+	var err error
+	o.WebsocketConn, _, err = Dialer.Dial(o.WebsocketURL, http.Header{})
+	if err != nil {
+		return err
+	}
+
+	o.WebsocketConn.SetPingHandler(o.PingHandler)
 
+	if o.AuthenticatedAPISupport {
 		for _, x := range o.EnabledPairs {
 			currency := StringToLower(x)
 			currencyUL := currency[0:3] + "_" + currency[3:]
-			if o.AuthenticatedAPISupport {
-				o.WebsocketSpotOrderInfo(currencyUL, -1)
-			}
-			if o.WebsocketURL == OKCOIN_WEBSOCKET_URL {
-				o.AddChannel(fmt.Sprintf("ok_%s_future_index", currency))
+			o.WebsocketSpotOrderInfo(currencyUL, -1)
+			if o.IsInternational() {
 				for _, y := range o.FuturesValues {
-					if o.AuthenticatedAPISupport {
-						o.WebsocketFuturesOrderInfo(currencyUL, y, -1, 1, 1, 50)
-					}
-					o.AddChannel(fmt.Sprintf("ok_%s_future_ticker_%s", currency, y))
-					o.AddChannel(fmt.Sprintf("ok_%s_future_depth_%s_60", currency, y))
-					o.AddChannel(fmt.Sprintf("ok_%s_future_trade_v1_%s", currency, y))
-					for _, z := range klineValues {
-						o.AddChannel(fmt.Sprintf("ok_future_%s_kline_%s_%s", currency, y, z))
-					}
-				}
-			} else {
-				o.AddChannel(fmt.Sprintf("ok_%s_ticker", currency))
-				o.AddChannel(fmt.Sprintf("ok_%s_depth60", currency))
-				o.AddChannel(fmt.Sprintf("ok_%s_trades_v1", currency))
-
-				for _, y := range klineValues {
-					o.AddChannel(fmt.Sprintf("ok_%s_kline_%s", currency, y))
+					o.WebsocketFuturesOrderInfo(currencyUL, y, -1, 1, 1, 50)
 				}
 			}
 		}
+	}
+	return nil
+}
+
+func (o *OKCoin) WebsocketServe() error {
+	s := GetWebsocketSupervisor(o.GetName())
+
+	for o.Enabled && o.Websocket {
+		msgType, resp, err := o.WebsocketConn.ReadMessage()
+		if err != nil {
+			return err
+		}
+		s.Received()
+
+		switch msgType {
+		case websocket.TextMessage:
+			if StringContains(string(resp), `"pong"`) {
+				continue
+			}
+
+			response := []interface{}{}
+			err = JSONDecode(resp, &response)
 
-		for o.Enabled && o.Websocket {
-			msgType, resp, err := o.WebsocketConn.ReadMessage()
 			if err != nil {
 				log.Println(err)
-				break
+				continue
 			}
-			switch msgType {
-			case websocket.TextMessage:
-				response := []interface{}{}
-				err = JSONDecode(resp, &response)
 
-				if err != nil {
-					log.Println(err)
+			for _, y := range response {
+				z := y.(map[string]interface{})
+				channel := z["channel"]
+				data := z["data"]
+				success := z["success"]
+				errorcode := z["errorcode"]
+				channelStr, ok := channel.(string)
+
+				if !ok {
+					log.Println("Unable to convert channel to string")
 					continue
 				}
 
-				for _, y := range response {
-					z := y.(map[string]interface{})
-					channel := z["channel"]
-					data := z["data"]
-					success := z["success"]
-					errorcode := z["errorcode"]
-					channelStr, ok := channel.(string)
-
+				if success != "true" && success != nil {
+					errorCodeStr, ok := errorcode.(string)
 					if !ok {
-						log.Println("Unable to convert channel to string")
-						continue
+						log.Printf("%s Websocket: Unable to convert errorcode to string.\n", o.GetName)
+						log.Printf("%s Websocket: channel %s error code: %s.\n", o.GetName(), channelStr, errorcode)
+					} else {
+						log.Printf("%s Websocket: channel %s error: %s.\n", o.GetName(), channelStr, o.WebsocketErrors[errorCodeStr])
 					}
+					continue
+				}
 
-					if success != "true" && success != nil {
-						errorCodeStr, ok := errorcode.(string)
-						if !ok {
-							log.Printf("%s Websocket: Unable to convert errorcode to string.\n", o.GetName)
-							log.Printf("%s Websocket: channel %s error code: %s.\n", o.GetName(), channelStr, errorcode)
-						} else {
-							log.Printf("%s Websocket: channel %s error: %s.\n", o.GetName(), channelStr, o.WebsocketErrors[errorCodeStr])
-						}
+				if success == "true" {
+					if data == nil {
 						continue
 					}
+				}
+
+				dataJSON, err := JSONEncode(data)
 
-					if success == "true" {
-						if data == nil {
-							continue
+				if err != nil {
+					log.Println(err)
+					continue
+				}
+
+				switch true {
+				case StringContains(channelStr, "ticker") && !StringContains(channelStr, "future"):
+					tickerValues := []string{"buy", "high", "last", "low", "sell", "timestamp"}
+					tickerMap := data.(map[string]interface{})
+					ticker := OKCoinWebsocketTicker{}
+					ticker.Vol = tickerMap["vol"].(string)
+
+					for _, z := range tickerValues {
+						result := reflect.TypeOf(tickerMap[z]).String()
+						if result == "string" {
+							value, err := strconv.ParseFloat(tickerMap[z].(string), 64)
+							if err != nil {
+								log.Println(err)
+								continue
+							}
+
+							switch z {
+							case "buy":
+								ticker.Buy = value
+							case "high":
+								ticker.High = value
+							case "last":
+								ticker.Last = value
+							case "low":
+								ticker.Low = value
+							case "sell":
+								ticker.Sell = value
+							case "timestamp":
+								ticker.Timestamp = value
+							}
+
+						} else if result == "float64" {
+							switch z {
+							case "buy":
+								ticker.Buy = tickerMap[z].(float64)
+							case "high":
+								ticker.High = tickerMap[z].(float64)
+							case "last":
+								ticker.Last = tickerMap[z].(float64)
+							case "low":
+								ticker.Low = tickerMap[z].(float64)
+							case "sell":
+								ticker.Sell = tickerMap[z].(float64)
+							case "timestamp":
+								ticker.Timestamp = tickerMap[z].(float64)
+							}
 						}
 					}
-
-					dataJSON, err := JSONEncode(data)
+				case StringContains(channelStr, "ticker") && StringContains(channelStr, "future"):
+					ticker := OKCoinWebsocketFuturesTicker{}
+					err = JSONDecode(dataJSON, &ticker)
 
 					if err != nil {
 						log.Println(err)
 						continue
 					}
+				case StringContains(channelStr, "depth"):
+					orderbook := OKCoinWebsocketOrderbook{}
+					err = JSONDecode(dataJSON, &orderbook)
 
-					switch true {
-					case StringContains(channelStr, "ticker") && !StringContains(channelStr, "future"):
-						tickerValues := []string{"buy", "high", "last", "low", "sell", "timestamp"}
-						tickerMap := data.(map[string]interface{})
-						ticker := OKCoinWebsocketTicker{}
-						ticker.Vol = tickerMap["vol"].(string)
-
-						for _, z := range tickerValues {
-							result := reflect.TypeOf(tickerMap[z]).String()
-							if result == "string" {
-								value, err := strconv.ParseFloat(tickerMap[z].(string), 64)
-								if err != nil {
-									log.Println(err)
-									continue
-								}
-
-								switch z {
-								case "buy":
-									ticker.Buy = value
-								case "high":
-									ticker.High = value
-								case "last":
-									ticker.Last = value
-								case "low":
-									ticker.Low = value
-								case "sell":
-									ticker.Sell = value
-								case "timestamp":
-									ticker.Timestamp = value
-								}
-
-							} else if result == "float64" {
-								switch z {
-								case "buy":
-									ticker.Buy = tickerMap[z].(float64)
-								case "high":
-									ticker.High = tickerMap[z].(float64)
-								case "last":
-									ticker.Last = tickerMap[z].(float64)
-								case "low":
-									ticker.Low = tickerMap[z].(float64)
-								case "sell":
-									ticker.Sell = tickerMap[z].(float64)
-								case "timestamp":
-									ticker.Timestamp = tickerMap[z].(float64)
-								}
-							}
-						}
-					case StringContains(channelStr, "ticker") && StringContains(channelStr, "future"):
-						ticker := OKCoinWebsocketFuturesTicker{}
-						err = JSONDecode(dataJSON, &ticker)
-
-						if err != nil {
-							log.Println(err)
-							continue
-						}
-					case StringContains(channelStr, "depth"):
-						orderbook := OKCoinWebsocketOrderbook{}
-						err = JSONDecode(dataJSON, &orderbook)
+					if err != nil {
+						log.Println(err)
+						continue
+					}
+				case StringContains(channelStr, "trades_v1") || StringContains(channelStr, "trade_v1"):
+					type TradeResponse struct {
+						Data [][]string
+					}
 
-						if err != nil {
-							log.Println(err)
-							continue
-						}
-					case StringContains(channelStr, "trades_v1") || StringContains(channelStr, "trade_v1"):
-						type TradeResponse struct {
-							Data [][]string
-						}
+					trades := TradeResponse{}
+					err = JSONDecode(dataJSON, &trades.Data)
 
-						trades := TradeResponse{}
-						err = JSONDecode(dataJSON, &trades.Data)
+					if err != nil {
+						log.Println(err)
+						continue
+					}
+					// to-do: convert from string array to trade struct
+				case StringContains(channelStr, "kline"):
+					klines := []interface{}{}
+					err := JSONDecode(dataJSON, &klines)
 
-						if err != nil {
-							log.Println(err)
-							continue
-						}
-						// to-do: convert from string array to trade struct
-					case StringContains(channelStr, "kline"):
-						klines := []interface{}{}
-						err := JSONDecode(dataJSON, &klines)
-
-						if err != nil {
-							log.Println(err)
-							continue
-						}
-					case StringContains(channelStr, "spot") && StringContains(channelStr, "realtrades"):
-						if string(dataJSON) == "null" {
-							continue
-						}
-						realtrades := OKCoinWebsocketRealtrades{}
-						err := JSONDecode(dataJSON, &realtrades)
+					if err != nil {
+						log.Println(err)
+						continue
+					}
+				case channelStr == OKCOIN_WEBSOCKET_USD_REALTRADES || channelStr == OKCOIN_WEBSOCKET_CNY_REALTRADES:
+					if string(dataJSON) == "null" {
+						continue
+					}
+					realtrades := OKCoinWebsocketRealtrades{}
+					err := JSONDecode(dataJSON, &realtrades)
 
-						if err != nil {
-							log.Println(err)
-							continue
-						}
-					case StringContains(channelStr, "future") && StringContains(channelStr, "realtrades"):
-						if string(dataJSON) == "null" {
-							continue
-						}
-						realtrades := OKCoinWebsocketFuturesRealtrades{}
-						err := JSONDecode(dataJSON, &realtrades)
+					if err != nil {
+						log.Println(err)
+						continue
+					}
+					o.WebsocketHandleRealtrades(realtrades)
+				case StringContains(channelStr, "future") && StringContains(channelStr, "realtrades"):
+					if string(dataJSON) == "null" {
+						continue
+					}
+					realtrades := OKCoinWebsocketFuturesRealtrades{}
+					err := JSONDecode(dataJSON, &realtrades)
 
-						if err != nil {
-							log.Println(err)
-							continue
-						}
-					case StringContains(channelStr, "spot") && StringContains(channelStr, "trade") || StringContains(channelStr, "futures") && StringContains(channelStr, "trade"):
-						tradeOrder := OKCoinWebsocketTradeOrderResponse{}
-						err := JSONDecode(dataJSON, &tradeOrder)
+					if err != nil {
+						log.Println(err)
+						continue
+					}
+				case StringContains(channelStr, "spot") && StringContains(channelStr, "trade") || StringContains(channelStr, "futures") && StringContains(channelStr, "trade"):
+					tradeOrder := OKCoinWebsocketTradeOrderResponse{}
+					err := JSONDecode(dataJSON, &tradeOrder)
 
-						if err != nil {
-							log.Println(err)
-							continue
-						}
-					case StringContains(channelStr, "cancel_order"):
-						cancelOrder := OKCoinWebsocketTradeOrderResponse{}
-						err := JSONDecode(dataJSON, &cancelOrder)
+					if err != nil {
+						log.Println(err)
+						continue
+					}
+				case StringContains(channelStr, "cancel_order"):
+					cancelOrder := OKCoinWebsocketTradeOrderResponse{}
+					err := JSONDecode(dataJSON, &cancelOrder)
 
-						if err != nil {
-							log.Println(err)
-							continue
-						}
-					case StringContains(channelStr, "spot") && StringContains(channelStr, "userinfo"):
-						userinfo := OKCoinWebsocketUserinfo{}
-						err = JSONDecode(dataJSON, &userinfo)
+					if err != nil {
+						log.Println(err)
+						continue
+					}
+				case StringContains(channelStr, "spot") && StringContains(channelStr, "userinfo"):
+					userinfo := OKCoinWebsocketUserinfo{}
+					err = JSONDecode(dataJSON, &userinfo)
 
-						if err != nil {
-							log.Println(err)
-							continue
-						}
-					case StringContains(channelStr, "futureusd_userinfo"):
-						userinfo := OKCoinWebsocketFuturesUserInfo{}
-						err = JSONDecode(dataJSON, &userinfo)
+					if err != nil {
+						log.Println(err)
+						continue
+					}
+					o.WebsocketHandleUserinfo(userinfo)
+				case StringContains(channelStr, "futureusd_userinfo"):
+					userinfo := OKCoinWebsocketFuturesUserInfo{}
+					err = JSONDecode(dataJSON, &userinfo)
 
-						if err != nil {
-							log.Println(err)
-							continue
-						}
-					case StringContains(channelStr, "spot") && StringContains(channelStr, "order_info"):
-						type OrderInfoResponse struct {
-							Result bool                   `json:"result"`
-							Orders []OKCoinWebsocketOrder `json:"orders"`
-						}
-						var orders OrderInfoResponse
-						err := JSONDecode(dataJSON, &orders)
+					if err != nil {
+						log.Println(err)
+						continue
+					}
+				case StringContains(channelStr, "spot") && StringContains(channelStr, "order_info"):
+					type OrderInfoResponse struct {
+						Result bool                   `json:"result"`
+						Orders []OKCoinWebsocketOrder `json:"orders"`
+					}
+					var orders OrderInfoResponse
+					err := JSONDecode(dataJSON, &orders)
 
-						if err != nil {
-							log.Println(err)
-							continue
-						}
-					case StringContains(channelStr, "futureusd_order_info"):
-						type OrderInfoResponse struct {
-							Result bool                          `json:"result"`
-							Orders []OKCoinWebsocketFuturesOrder `json:"orders"`
-						}
-						var orders OrderInfoResponse
-						err := JSONDecode(dataJSON, &orders)
+					if err != nil {
+						log.Println(err)
+						continue
+					}
+				case StringContains(channelStr, "futureusd_order_info"):
+					type OrderInfoResponse struct {
+						Result bool                          `json:"result"`
+						Orders []OKCoinWebsocketFuturesOrder `json:"orders"`
+					}
+					var orders OrderInfoResponse
+					err := JSONDecode(dataJSON, &orders)
 
-						if err != nil {
-							log.Println(err)
-							continue
-						}
-					case StringContains(channelStr, "future_index"):
-						index := OKCoinWebsocketFutureIndex{}
-						err = JSONDecode(dataJSON, &index)
+					if err != nil {
+						log.Println(err)
+						continue
+					}
+				case StringContains(channelStr, "future_index"):
+					index := OKCoinWebsocketFutureIndex{}
+					err = JSONDecode(dataJSON, &index)
 
-						if err != nil {
-							log.Println(err)
-							continue
-						}
+					if err != nil {
+						log.Println(err)
+						continue
 					}
 				}
 			}
 		}
-		o.WebsocketConn.Close()
-		log.Printf("%s Websocket client disconnected.", o.GetName())
+	}
+	return nil
+}
+
+// OKCoinSymbolPair converts a symbol such as btc_usd to BTCUSD.
+func OKCoinSymbolPair(symbol string) string {
+	return StringToUpper(strings.Replace(symbol, "_", "", -1))
+}
+
+// OKCoinOrderStatus maps an order status to the OMS. Orders being cancelled
+// (4) have no equivalent until the cancel completes.
+func OKCoinOrderStatus(status int64) (string, bool) {
+	switch status {
+	case -1:
+		return ORDER_STATUS_CANCELLED, true
+	case 0:
+		return ORDER_STATUS_ACKNOWLEDGED, true
+	case 1:
+		return ORDER_STATUS_PARTIALLY_FILLED, true
+	case 2:
+		return ORDER_STATUS_FILLED, true
+	}
+	return "", false
+}
+
+// WebsocketHandleRealtrades turns a spot order push into order and fill
+// events. Pushes carry no trade ID, so fills are keyed by the order and how
+// much of it had completed, which is unique to each trade.
+func (o *OKCoin) WebsocketHandleRealtrades(trade OKCoinWebsocketRealtrades) {
+	orderID := strconv.FormatInt(int64(trade.OrderID), 10)
+	pair := OKCoinSymbolPair(trade.Symbol)
+	buy := StringContains(trade.TradeType, "buy")
+
+	if trade.SigTradeAmount > 0 {
+		PublishFillEvent(FillEvent{
+			Exchange:        o.GetName(),
+			ExchangeOrderID: orderID,
+			Pair:            pair,
+			Buy:             buy,
+			Fill: OrderFill{
+				TradeID: orderID + "-" + strconv.FormatFloat(trade.CompletedTradeAmount, 'f', -1, 64),
+				Price:   trade.SigTradePrice,
+				Amount:  trade.SigTradeAmount,
+				Time:    time.Now(),
+			},
+		})
+	}
+
+	status, ok := OKCoinOrderStatus(trade.Status)
+	if !ok {
+		return
+	}
+	PublishOrderEvent(OrderEvent{
+		Exchange:        o.GetName(),
+		ExchangeOrderID: orderID,
+		Pair:            pair,
+		Buy:             buy,
+		Status:          status,
+		Amount:          trade.TradeAmount,
+		Remaining:       trade.UnTrade,
+		Price:           trade.TradeUnitPrice,
+		AveragePrice:    trade.AveragePrice,
+		Time:            time.Now(),
+	})
+}
+
+func (o *OKCoin) WebsocketHandleUserinfo(userinfo OKCoinWebsocketUserinfo) {
+	free, frozen := userinfo.Info.Funds.Free, userinfo.Info.Funds.Frozen
+	balances := map[string][]float64{
+		"BTC": {free.BTC, frozen.BTC},
+		"LTC": {free.LTC, frozen.LTC},
+	}
+	if o.IsInternational() {
+		balances["USD"] = []float64{free.USD, frozen.USD}
+	} else {
+		balances["CNY"] = []float64{free.CNY, frozen.CNY}
+	}
+
+	for currency, x := range balances {
+		PublishBalanceEvent(BalanceEvent{Exchange: o.GetName(), Wallet: "spot", Currency: currency, Total: x[0] + x[1], Available: x[0], Time: time.Now()})
 	}
 }
 
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+// Private websocket streams are normalised into order, fill and balance
+// events. Fills and exchange side cancels are applied to the OMS as they
+// arrive, and listeners (such as the execution engine) are woken straight
+// away rather than waiting for their next poll.
+
+const (
+	ORDER_EVENT_BUFFER = 100
+)
+
+// OrderEvent is an order's state as reported by the exchange. Status is one
+// of the ORDER_STATUS_* values.
+type OrderEvent struct {
+	Exchange        string
+	ExchangeOrderID string
+	Pair            string
+	Buy             bool
+	Status          string
+	Amount          float64 // original amount
+	Remaining       float64
+	Price           float64
+	AveragePrice    float64
+	Time            time.Time
+}
+
+type FillEvent struct {
+	Exchange        string
+	ExchangeOrderID string
+	Pair            string
+	Buy             bool
+	Fill            OrderFill
+}
+
+// BalanceEvent is a currency's balance in one of the exchange's wallets.
+// Available is -1 when the exchange only reports the total.
+type BalanceEvent struct {
+	Exchange  string
+	Wallet    string
+	Currency  string
+	Total     float64
+	Available float64
+	Time      time.Time
+}
+
+// OrderEventListener receives OrderEvent, FillEvent and BalanceEvent values
+// for an exchange, or only the order and fill events of one order when
+// ExchangeOrderID is set. Events are dropped rather than blocking the stream
+// if the listener falls behind.
+type OrderEventListener struct {
+	Exchange        string
+	ExchangeOrderID string
+	Events          chan interface{}
+}
+
+var (
+	orderEventListeners    []*OrderEventListener
+	orderEventListenersMtx sync.Mutex
+
+	Balances    = make(map[string]map[string]BalanceEvent)
+	balancesMtx sync.Mutex
+)
+
+func ListenOrderEvents(exchange, exchangeOrderID string) *OrderEventListener {
+	l := &OrderEventListener{
+		Exchange:        exchange,
+		ExchangeOrderID: exchangeOrderID,
+		Events:          make(chan interface{}, ORDER_EVENT_BUFFER),
+	}
+	orderEventListenersMtx.Lock()
+	orderEventListeners = append(orderEventListeners, l)
+	orderEventListenersMtx.Unlock()
+	return l
+}
+
+func (l *OrderEventListener) Close() {
+	orderEventListenersMtx.Lock()
+	defer orderEventListenersMtx.Unlock()
+
+	for i, x := range orderEventListeners {
+		if x == l {
+			orderEventListeners = append(orderEventListeners[:i], orderEventListeners[i+1:]...)
+			return
+		}
+	}
+}
+
+func notifyOrderEventListeners(exchange, exchangeOrderID string, event interface{}) {
+	orderEventListenersMtx.Lock()
+	defer orderEventListenersMtx.Unlock()
+
+	for _, x := range orderEventListeners {
+		if x.Exchange != exchange || (x.ExchangeOrderID != "" && x.ExchangeOrderID != exchangeOrderID) {
+			continue
+		}
+		select {
+		case x.Events <- event:
+		default:
+			log.Printf("%s order event listener is full, dropped %T for order %s", exchange, event, exchangeOrderID)
+		}
+	}
+}
+
+// PublishOrderEvent cancels the matching local order if the exchange has
+// cancelled it. Other transitions are left to fills and the order's owner.
+func PublishOrderEvent(event OrderEvent) {
+	if event.Status == ORDER_STATUS_CANCELLED {
+		if order, ok := GetOrderByExchangeOrderID(event.Exchange, event.ExchangeOrderID); ok && order.IsOpen() {
+			err := OrderCancelled(order.OrderID, "cancelled on exchange")
+			if err != nil {
+				log.Printf("%s couldn't cancel order %d: %v", event.Exchange, order.OrderID, err)
+			}
+		}
+	}
+	notifyOrderEventListeners(event.Exchange, event.ExchangeOrderID, event)
+}
+
+// PublishFillEvent records the fill against the matching local order, if
+// there is one. Fills already recorded by polling are ignored.
+func PublishFillEvent(event FillEvent) {
+	if order, ok := GetOrderByExchangeOrderID(event.Exchange, event.ExchangeOrderID); ok {
+		err := OrderFilled(order.OrderID, event.Fill)
+		if err != nil && err != ErrOrderFillDuplicate {
+			log.Printf("%s couldn't record fill %s for order %d: %v", event.Exchange, event.Fill.TradeID, order.OrderID, err)
+		}
+	}
+	notifyOrderEventListeners(event.Exchange, event.ExchangeOrderID, event)
+}
+
+func PublishBalanceEvent(event BalanceEvent) {
+	balancesMtx.Lock()
+	if _, ok := Balances[event.Exchange]; !ok {
+		Balances[event.Exchange] = make(map[string]BalanceEvent)
+	}
+	Balances[event.Exchange][event.Wallet+":"+event.Currency] = event
+	balancesMtx.Unlock()
+	notifyOrderEventListeners(event.Exchange, "", event)
+}
+
+// GetBalance returns the last streamed balance of the currency in the
+// exchange's wallet.
+func GetBalance(exchange, wallet, currency string) (BalanceEvent, bool) {
+	balancesMtx.Lock()
+	defer balancesMtx.Unlock()
+	balance, ok := Balances[exchange][wallet+":"+currency]
+	return balance, ok
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"testing"
+	"time"
+)
+
+func orderEventTypes(l *OrderEventListener) string {
+	types := ""
+	for {
+		select {
+		case event := <-l.Events:
+			switch event.(type) {
+			case OrderEvent:
+				types += "O"
+			case FillEvent:
+				types += "F"
+			case BalanceEvent:
+				types += "B"
+			}
+		default:
+			return types
+		}
+	}
+}
+
+func TestBitfinexWebsocketAccountEvents(t *testing.T) {
+	b := Bitfinex{Name: "BitfinexEvents"}
+	id := NewOrder(b.Name, "BTCUSD", true, false, LIMIT_ORDER, 2, 400)
+	OrderAcknowledged(id, "101")
+	l := ListenOrderEvents(b.Name, "")
+	defer l.Close()
+
+	for _, x := range []string{
+		`[0,"on",[101,"BTCUSD",2,2,"EXCHANGE LIMIT","ACTIVE",400,0,"2016-04-08T11:38:59Z",0]]`,
+		`[0,"te",["1234-BTCUSD","BTCUSD",1460115540,101,0.5,400]]`,
+		`[0,"tu",["1234-BTCUSD",5001,"BTCUSD",1460115540,101,0.5,400,"EXCHANGE LIMIT",400,-0.0005,"USD"]]`,
+		`[0,"tu",["1234-BTCUSD",5001,"BTCUSD",1460115540,101,0.5,400,"EXCHANGE LIMIT",400,-0.0005,"USD"]]`,
+		`[0,"ws",[["exchange","usd",1200.5,0],["trading","btc",1.25,0]]]`,
+		`[0,"ou",["malformed"]]`,
+		`[0,"oc",[101,"BTCUSD",1.5,2,"EXCHANGE LIMIT","CANCELED was PARTIALLY FILLED @ 400.0(0.5)",400,400,"2016-04-08T11:38:59Z",0]]`,
+	} {
+		chanData := []interface{}{}
+		if err := JSONDecode([]byte(x), &chanData); err != nil {
+			t.Fatal(err)
+		}
+		b.WebsocketHandleAccount(chanData)
+	}
+
+	if types := orderEventTypes(l); types != "OFFBBO" {
+		t.Error(fmt.Sprintf("Test failed. Expected events OFFBBO. Actual %s", types))
+	}
+	order, _ := GetOrderByOrderID(id)
+	if order.Status != ORDER_STATUS_CANCELLED || order.Filled != 0.5 || order.Fees != 0.0005 || len(order.Fills) != 1 || order.Fills[0].TradeID != "5001" {
+		t.Error(fmt.Sprintf("Test failed. Expected cancelled order with one fill. Actual %+v", order))
+	}
+	if balance, ok := GetBalance(b.Name, "trading", "BTC"); !ok || balance.Total != 1.25 || balance.Available != -1 {
+		t.Error(fmt.Sprintf("Test failed. Expected trading BTC balance 1.25. Actual %+v", balance))
+	}
+}
+
+func TestOKCoinWebsocketEvents(t *testing.T) {
+	o := OKCoin{Name: "OKCOIN International"}
+	id := NewOrder(o.Name, "BTCUSD", false, false, LIMIT_ORDER, 1, 420)
+	OrderAcknowledged(id, "9001")
+	l := ListenOrderEvents(o.Name, "9001")
+	defer l.Close()
+
+	for _, x := range []string{
+		`{"averagePrice":"420","completedTradeAmount":"0.4","createdDate":1460115540000,"id":1,"orderId":9001,"sigTradeAmount":"0.4","sigTradePrice":"420","status":1,"symbol":"btc_usd","tradeAmount":"1","tradePrice":"168","tradeType":"sell","tradeUnitPrice":"420","unTrade":"0.6"}`,
+		`{"averagePrice":"420.6","completedTradeAmount":"1","createdDate":1460115540000,"id":1,"orderId":9001,"sigTradeAmount":"0.6","sigTradePrice":"421","status":2,"symbol":"btc_usd","tradeAmount":"1","tradePrice":"420.6","tradeType":"sell","tradeUnitPrice":"420","unTrade":"0"}`,
+	} {
+		trade := OKCoinWebsocketRealtrades{}
+		if err := JSONDecode([]byte(x), &trade); err != nil {
+			t.Fatal(err)
+		}
+		o.WebsocketHandleRealtrades(trade)
+	}
+
+	userinfo := OKCoinWebsocketUserinfo{}
+	JSONDecode([]byte(`{"info":{"funds":{"free":{"btc":"2.5","usd":"1000"},"freezed":{"btc":"0.5","usd":"0"}}},"result":true}`), &userinfo)
+	o.WebsocketHandleUserinfo(userinfo)
+
+	if types := orderEventTypes(l); types != "FOFO" {
+		t.Error(fmt.Sprintf("Test failed. Expected events FOFO. Actual %s", types))
+	}
+	order, _ := GetOrderByOrderID(id)
+	if order.Status != ORDER_STATUS_FILLED || len(order.Fills) != 2 || order.Fills[0].TradeID != "9001-0.4" || math.Abs(order.AveragePrice-420.6) > 1e-9 {
+		t.Error(fmt.Sprintf("Test failed. Expected filled order. Actual %+v", order))
+	}
+	if balance, _ := GetBalance(o.Name, "spot", "BTC"); balance.Total != 3 || balance.Available != 2.5 {
+		t.Error(fmt.Sprintf("Test failed. Expected BTC balance 3 with 2.5 available. Actual %+v", balance))
+	}
+}
+
+func TestCoinbaseWebsocketMatch(t *testing.T) {
+	c := Coinbase{Name: "CoinbaseEvents"}
+	id := NewOrder(c.Name, "BTCUSD", true, false, LIMIT_ORDER, 1, 410)
+	OrderAcknowledged(id, "ac928c66-ca53-498f-9c13-a110027a60e8")
+
+	match := CoinbaseWebsocketMatch{}
+	JSONDecode([]byte(`{"type":"match","trade_id":10,"sequence":50,"maker_order_id":"ac928c66-ca53-498f-9c13-a110027a60e8","taker_order_id":"132fb6ae-456b-4654-b4e0-d681ac05cea1","time":"2014-11-07T08:19:27.028459Z","product_id":"BTC-USD","size":"1","price":"400.23","side":"buy","maker_user_id":"5844eceecf7e803e259d0365","user_id":"5844eceecf7e803e259d0365"}`), &match)
+	c.WebsocketHandleMatch(match)
+
+	order, _ := GetOrderByOrderID(id)
+	if order.Status != ORDER_STATUS_FILLED || order.Fills[0].TradeID != "10" || order.AveragePrice != 400.23 {
+		t.Error(fmt.Sprintf("Test failed. Expected maker order filled. Actual %+v", order))
+	}
+	if _, ok := GetOrderByExchangeOrderID(c.Name, match.TakerOrderID); ok {
+		t.Error("Test failed. Expected the taker side to be ignored")
+	}
+}
+
+type executionStreamVenue struct {
+	*executionTestVenue
+}
+
+func (v executionStreamVenue) GetName() string {
+	return "StreamTest"
+}
+
+func TestExecutionStreamedFills(t *testing.T) {
+	t.Parallel()
+	v := executionStreamVenue{&executionTestVenue{bid: 0.019, ask: 0.02, depth: 100}}
+	params := executionTestParams(EXECUTION_CHASE, 1)
+	params.PollInterval = time.Hour
+	params.RepriceAfter = time.Hour
+
+	done := make(chan struct{})
+	go func() {
+		// the order's listener may not be up the first time
+		for {
+			select {
+			case <-done:
+				return
+			case <-time.After(5 * time.Millisecond):
+				PublishFillEvent(FillEvent{Exchange: "StreamTest", ExchangeOrderID: "0", Pair: "BTC_ETH", Buy: true, Fill: OrderFill{TradeID: "S1", Price: 0.0195, Amount: 1}})
+			}
+		}
+	}()
+
+	start := time.Now()
+	summary, err := NewExecutor(v).Execute(params)
+	close(done)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary.Filled != 1 || summary.Trades != 1 || time.Since(start) > time.Second {
+		t.Error(fmt.Sprintf("Test failed. Expected the streamed fill without polling. Actual %+v", summary))
+	}
+}
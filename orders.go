@@ -1,51 +1,154 @@
-
 package main
 
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
 const (
 	LIMIT_ORDER = iota
 	MARKET_ORDER
 )
 
-var Orders []*Order
+// Order lifecycle: NEW -> ACKNOWLEDGED -> PARTIALLY_FILLED -> FILLED, with
+// CANCELLED possible from any open state and REJECTED only from NEW.
+const (
+	ORDER_STATUS_NEW              = "NEW"
+	ORDER_STATUS_ACKNOWLEDGED     = "ACKNOWLEDGED"
+	ORDER_STATUS_PARTIALLY_FILLED = "PARTIALLY_FILLED"
+	ORDER_STATUS_FILLED           = "FILLED"
+	ORDER_STATUS_CANCELLED        = "CANCELLED"
+	ORDER_STATUS_REJECTED         = "REJECTED"
+
+	ORDERS_FILE = "orders.json"
+)
+
+var (
+	ErrOrderNotFound          = errors.New("Order not found.")
+	ErrOrderInvalidTransition = errors.New("Invalid order status transition.")
+	ErrOrderFillDuplicate     = errors.New("Fill already recorded for order.")
+)
+
+var orderTransitions = map[string][]string{
+	ORDER_STATUS_NEW:              {ORDER_STATUS_ACKNOWLEDGED, ORDER_STATUS_PARTIALLY_FILLED, ORDER_STATUS_FILLED, ORDER_STATUS_CANCELLED, ORDER_STATUS_REJECTED},
+	ORDER_STATUS_ACKNOWLEDGED:     {ORDER_STATUS_PARTIALLY_FILLED, ORDER_STATUS_FILLED, ORDER_STATUS_CANCELLED},
+	ORDER_STATUS_PARTIALLY_FILLED: {ORDER_STATUS_PARTIALLY_FILLED, ORDER_STATUS_FILLED, ORDER_STATUS_CANCELLED},
+}
+
+type OrderFill struct {
+	TradeID string
+	Price   float64
+	Amount  float64
+	Fee     float64
+	Time    time.Time
+}
 
 type Order struct {
-	OrderID int
-	Exchange string
-	Type int
-	Amount float64
-	Price float64
+	OrderID         int // client ID, never reused
+	ExchangeOrderID string
+	Exchange        string
+	Pair            string
+	Buy             bool
+	Margin          bool
+	Type            int
+	Amount          float64
+	Price           float64
+	Status          string
+	Filled          float64
+	AveragePrice    float64
+	Fees            float64
+	Fills           []OrderFill
+	Reason          string
+	Created         time.Time
+	Updated         time.Time
 }
 
-func NewOrder(Exchange string, amount, price float64) (int) {
-	order := &Order{}
-	if  (len(Orders) == 0) {
-		order.OrderID = 0
-	} else {
-		order.OrderID = len(Orders)
-	}
+func (o *Order) IsOpen() bool {
+	return o.Status == ORDER_STATUS_NEW || o.Status == ORDER_STATUS_ACKNOWLEDGED || o.Status == ORDER_STATUS_PARTIALLY_FILLED
+}
+
+// OrderReconciler is implemented per exchange so that local orders can be
+// checked against what the exchange actually has open.
+type OrderReconciler interface {
+	GetName() string
+	OpenOrders() ([]Order, error)
+	OrderFills(exchangeOrderID string) ([]OrderFill, error)
+}
 
-	order.Exchange = Exchange
-	order.Amount = amount
-	order.Price = price
+var (
+	Orders      []*Order
+	OrdersFile  string // state is saved here on every change when set
+	nextOrderID int
+	ordersMtx   sync.Mutex
+)
+
+// ordersState is what's saved to OrdersFile. NextOrderID is kept as well as
+// the orders so that IDs of deleted orders aren't handed out again.
+type ordersState struct {
+	NextOrderID int
+	Orders      []*Order
+}
+
+func NewOrder(exchange, pair string, buy, margin bool, orderType int, amount, price float64) int {
+	ordersMtx.Lock()
+	defer ordersMtx.Unlock()
+
+	nextOrderID++
+	order := &Order{
+		OrderID:  nextOrderID,
+		Exchange: exchange,
+		Pair:     pair,
+		Buy:      buy,
+		Margin:   margin,
+		Type:     orderType,
+		Amount:   amount,
+		Price:    price,
+		Status:   ORDER_STATUS_NEW,
+		Created:  time.Now(),
+	}
+	order.Updated = order.Created
 	Orders = append(Orders, order)
+	saveOrders()
 	return order.OrderID
 }
 
-func DeleteOrder(orderID int) (bool) {
+func DeleteOrder(orderID int) bool {
+	ordersMtx.Lock()
+	defer ordersMtx.Unlock()
+
 	for i := range Orders {
 		if Orders[i].OrderID == orderID {
 			Orders = append(Orders[:i], Orders[i+1:]...)
+			saveOrders()
 			return true
 		}
 	}
 	return false
 }
 
-func GetOrdersByExchange(exchange string) ([]*Order, bool) {
-	orders := []*Order{}
-	for i := range Orders {
-		if Orders[i].Exchange == exchange {
-			orders = append(orders, Orders[i])
+// copyOrder returns a snapshot of the order which is safe to read once
+// ordersMtx is released. Must be called with ordersMtx held.
+func copyOrder(order *Order) Order {
+	c := *order
+	c.Fills = append([]OrderFill{}, order.Fills...)
+	return c
+}
+
+// GetOrdersByExchange returns copies of the exchange's orders.
+func GetOrdersByExchange(exchange string) ([]Order, bool) {
+	ordersMtx.Lock()
+	defer ordersMtx.Unlock()
+
+	orders := []Order{}
+	for _, x := range Orders {
+		if x.Exchange == exchange {
+			orders = append(orders, copyOrder(x))
 		}
 	}
 	if len(orders) > 0 {
@@ -54,11 +157,308 @@ func GetOrdersByExchange(exchange string) ([]*Order, bool) {
 	return nil, false
 }
 
-func GetOrderByOrderID(orderID int) (*Order, bool) {
-	for i := range Orders {
-		if Orders[i].OrderID == orderID {
-			return Orders[i], true
+func GetOpenOrdersByExchange(exchange string) []Order {
+	ordersMtx.Lock()
+	defer ordersMtx.Unlock()
+
+	orders := []Order{}
+	for _, x := range Orders {
+		if x.Exchange == exchange && x.IsOpen() {
+			orders = append(orders, copyOrder(x))
 		}
 	}
-	return nil, false
-}
\ No newline at end of file
+	return orders
+}
+
+func GetOrderByOrderID(orderID int) (Order, bool) {
+	ordersMtx.Lock()
+	defer ordersMtx.Unlock()
+
+	order := getOrder(orderID)
+	if order == nil {
+		return Order{}, false
+	}
+	return copyOrder(order), true
+}
+
+func GetOrderByExchangeOrderID(exchange, exchangeOrderID string) (Order, bool) {
+	ordersMtx.Lock()
+	defer ordersMtx.Unlock()
+
+	order := getOrderByExchangeOrderID(exchange, exchangeOrderID)
+	if order == nil {
+		return Order{}, false
+	}
+	return copyOrder(order), true
+}
+
+// getOrder must be called with ordersMtx held.
+func getOrder(orderID int) *Order {
+	for _, x := range Orders {
+		if x.OrderID == orderID {
+			return x
+		}
+	}
+	return nil
+}
+
+// getOrderByExchangeOrderID must be called with ordersMtx held.
+func getOrderByExchangeOrderID(exchange, exchangeOrderID string) *Order {
+	for _, x := range Orders {
+		if x.Exchange == exchange && x.ExchangeOrderID == exchangeOrderID {
+			return x
+		}
+	}
+	return nil
+}
+
+// setOrderStatus must be called with ordersMtx held.
+func setOrderStatus(order *Order, status string) error {
+	for _, x := range orderTransitions[order.Status] {
+		if x == status {
+			order.Status = status
+			order.Updated = time.Now()
+			return nil
+		}
+	}
+	return ErrOrderInvalidTransition
+}
+
+// OrderAcknowledged records that the exchange accepted the order under
+// exchangeOrderID.
+func OrderAcknowledged(orderID int, exchangeOrderID string) error {
+	ordersMtx.Lock()
+	defer ordersMtx.Unlock()
+
+	order := getOrder(orderID)
+	if order == nil {
+		return ErrOrderNotFound
+	}
+	if order.Status != ORDER_STATUS_NEW {
+		return ErrOrderInvalidTransition
+	}
+	order.ExchangeOrderID = exchangeOrderID
+	err := setOrderStatus(order, ORDER_STATUS_ACKNOWLEDGED)
+	saveOrders()
+	return err
+}
+
+func OrderRejected(orderID int, reason string) error {
+	ordersMtx.Lock()
+	defer ordersMtx.Unlock()
+
+	order := getOrder(orderID)
+	if order == nil {
+		return ErrOrderNotFound
+	}
+	err := setOrderStatus(order, ORDER_STATUS_REJECTED)
+	if err != nil {
+		return err
+	}
+	order.Reason = reason
+	saveOrders()
+	return nil
+}
+
+func OrderCancelled(orderID int, reason string) error {
+	ordersMtx.Lock()
+	defer ordersMtx.Unlock()
+
+	order := getOrder(orderID)
+	if order == nil {
+		return ErrOrderNotFound
+	}
+	err := setOrderStatus(order, ORDER_STATUS_CANCELLED)
+	if err != nil {
+		return err
+	}
+	order.Reason = reason
+	saveOrders()
+	return nil
+}
+
+// OrderFilled records a fill against the order, ignoring trades it has
+// already seen, and moves it to PARTIALLY_FILLED or FILLED.
+func OrderFilled(orderID int, fill OrderFill) error {
+	ordersMtx.Lock()
+	defer ordersMtx.Unlock()
+
+	order := getOrder(orderID)
+	if order == nil {
+		return ErrOrderNotFound
+	}
+	err := addOrderFill(order, fill)
+	saveOrders()
+	return err
+}
+
+// addOrderFill must be called with ordersMtx held.
+func addOrderFill(order *Order, fill OrderFill) error {
+	for _, x := range order.Fills {
+		if x.TradeID == fill.TradeID {
+			return ErrOrderFillDuplicate
+		}
+	}
+
+	status := ORDER_STATUS_PARTIALLY_FILLED
+	if order.Filled+fill.Amount >= order.Amount-EXECUTION_DUST {
+		status = ORDER_STATUS_FILLED
+	}
+	// fills can still trickle in for an order cancelled while it traded
+	if order.Status != ORDER_STATUS_CANCELLED {
+		if err := setOrderStatus(order, status); err != nil {
+			return err
+		}
+	}
+
+	notional := order.AveragePrice*order.Filled + fill.Price*fill.Amount
+	order.Fills = append(order.Fills, fill)
+	order.Filled += fill.Amount
+	order.Fees += fill.Fee
+	order.AveragePrice = notional / order.Filled
+	order.Updated = time.Now()
+
+	err := Positions.AddFill(order.Exchange, order.Pair, order.Margin, order.Buy, fill)
+	if err != nil {
+		log.Printf("WARN couldn't book fill %s for order %d to position: %v", fill.TradeID, order.OrderID, err)
+	}
+	journalOrderFill(order, fill)
+	return nil
+}
+
+// ReconcileOrders checks local open orders for the exchange against its open
+// orders endpoint. Orders it no longer has open are settled from their fills,
+// and open orders we didn't know about are adopted.
+func ReconcileOrders(exchange OrderReconciler) error {
+	open, err := exchange.OpenOrders()
+	if err != nil {
+		return err
+	}
+
+	name := exchange.GetName()
+	exchangeOpen := make(map[string]Order)
+	for _, x := range open {
+		exchangeOpen[x.ExchangeOrderID] = x
+	}
+
+	for _, x := range GetOpenOrdersByExchange(name) {
+		if _, ok := exchangeOpen[x.ExchangeOrderID]; ok {
+			continue
+		}
+
+		var fills []OrderFill
+		if x.ExchangeOrderID != "" {
+			fills, err = exchange.OrderFills(x.ExchangeOrderID)
+			if err != nil {
+				// it may have filled, so leave it open for the next reconcile
+				log.Printf("%s couldn't get fills for order %d (%s), leaving it open: %v", name, x.OrderID, x.ExchangeOrderID, err)
+				continue
+			}
+		}
+
+		ordersMtx.Lock()
+		order := getOrder(x.OrderID)
+		if order == nil {
+			ordersMtx.Unlock()
+			continue
+		}
+		for _, f := range fills {
+			addOrderFill(order, f)
+		}
+		if order.IsOpen() {
+			setOrderStatus(order, ORDER_STATUS_CANCELLED)
+			order.Reason = "not open on exchange when reconciled"
+		}
+		log.Printf("%s reconciled order %d (%s): status=%s filled=%f/%f", name, order.OrderID, order.ExchangeOrderID, order.Status, order.Filled, order.Amount)
+		ordersMtx.Unlock()
+	}
+
+	ordersMtx.Lock()
+	defer ordersMtx.Unlock()
+	for _, x := range open {
+		if getOrderByExchangeOrderID(name, x.ExchangeOrderID) != nil {
+			continue
+		}
+
+		nextOrderID++
+		order := x
+		order.OrderID = nextOrderID
+		order.Exchange = name
+		order.Status = ORDER_STATUS_ACKNOWLEDGED
+		if order.Filled > 0 {
+			order.Status = ORDER_STATUS_PARTIALLY_FILLED
+		}
+		if order.Created.IsZero() {
+			order.Created = time.Now()
+		}
+		order.Updated = time.Now()
+		Orders = append(Orders, &order)
+		log.Printf("%s adopted open order %d (%s): %s buy=%v %f@%f", name, order.OrderID, order.ExchangeOrderID, order.Pair, order.Buy, order.Amount, order.Price)
+	}
+	saveOrders()
+	return nil
+}
+
+// saveOrders must be called with ordersMtx held.
+func saveOrders() {
+	if OrdersFile == "" {
+		return
+	}
+	if err := writeOrders(OrdersFile); err != nil {
+		log.Printf("Unable to save orders to %s: %v", OrdersFile, err)
+	}
+}
+
+func writeOrders(path string) error {
+	payload, err := json.MarshalIndent(ordersState{NextOrderID: nextOrderID, Orders: Orders}, "", " ")
+	if err != nil {
+		return err
+	}
+
+	// write then rename so a crash can't leave a truncated file
+	tmp := path + ".tmp"
+	err = ioutil.WriteFile(tmp, payload, 0644)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func SaveOrders(path string) error {
+	ordersMtx.Lock()
+	defer ordersMtx.Unlock()
+	return writeOrders(path)
+}
+
+// LoadOrders replaces the orders in memory with those saved at path. A
+// missing file is not an error, there just aren't any orders yet.
+func LoadOrders(path string) error {
+	file, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	state := ordersState{}
+	err = json.Unmarshal(file, &state)
+	if err != nil {
+		// files saved before the next ID was kept are just the orders
+		err = json.Unmarshal(file, &state.Orders)
+	}
+	if err != nil {
+		return fmt.Errorf("unable to parse %s: %v", path, err)
+	}
+
+	ordersMtx.Lock()
+	defer ordersMtx.Unlock()
+	Orders = state.Orders
+	nextOrderID = state.NextOrderID
+	for _, x := range Orders {
+		if x.OrderID > nextOrderID {
+			nextOrderID = x.OrderID
+		}
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type ordersTestReconciler struct {
+	open   []Order
+	fills  map[string][]OrderFill
+	errors map[string]error
+}
+
+func (r *ordersTestReconciler) GetName() string {
+	return "OrdersTest"
+}
+
+func (r *ordersTestReconciler) OpenOrders() ([]Order, error) {
+	return r.open, nil
+}
+
+func (r *ordersTestReconciler) OrderFills(exchangeOrderID string) ([]OrderFill, error) {
+	return r.fills[exchangeOrderID], r.errors[exchangeOrderID]
+}
+
+func TestOrderIDsNotReused(t *testing.T) {
+	first := NewOrder("OrdersTest", "BTC_ETH", true, false, LIMIT_ORDER, 1, 0.02)
+	second := NewOrder("OrdersTest", "BTC_ETH", true, false, LIMIT_ORDER, 1, 0.02)
+	DeleteOrder(second)
+	third := NewOrder("OrdersTest", "BTC_ETH", true, false, LIMIT_ORDER, 1, 0.02)
+	if third == first || third == second {
+		t.Error(fmt.Sprintf("Test failed. Order ID %d reused", third))
+	}
+}
+
+func TestOrderLifecycle(t *testing.T) {
+	id := NewOrder("OrdersTest", "BTC_ETH", false, false, LIMIT_ORDER, 2, 0.02)
+	if err := OrderAcknowledged(id, "123"); err != nil {
+		t.Fatal(err)
+	}
+	if err := OrderFilled(id, OrderFill{TradeID: "1", Price: 0.02, Amount: 1, Fee: 0.00005}); err != nil {
+		t.Fatal(err)
+	}
+	if err := OrderFilled(id, OrderFill{TradeID: "1", Price: 0.02, Amount: 1}); err != ErrOrderFillDuplicate {
+		t.Error(fmt.Sprintf("Test failed. Expected %v. Actual %v", ErrOrderFillDuplicate, err))
+	}
+
+	order, _ := GetOrderByExchangeOrderID("OrdersTest", "123")
+	if order.OrderID != id || order.Status != ORDER_STATUS_PARTIALLY_FILLED {
+		t.Error(fmt.Sprintf("Test failed. Expected partially filled order %d. Actual %+v", id, order))
+	}
+
+	OrderFilled(id, OrderFill{TradeID: "2", Price: 0.03, Amount: 1})
+	order, _ = GetOrderByOrderID(id)
+	if order.Status != ORDER_STATUS_FILLED || order.AveragePrice != 0.025 || order.Fees != 0.00005 {
+		t.Error(fmt.Sprintf("Test failed. Expected filled order. Actual %+v", order))
+	}
+	if err := OrderCancelled(id, ""); err != ErrOrderInvalidTransition {
+		t.Error(fmt.Sprintf("Test failed. Expected %v. Actual %v", ErrOrderInvalidTransition, err))
+	}
+}
+
+func TestReconcileOrders(t *testing.T) {
+	gone := NewOrder("OrdersTest", "BTC_ETH", true, false, LIMIT_ORDER, 1, 0.02)
+	OrderAcknowledged(gone, "gone")
+	open := NewOrder("OrdersTest", "BTC_ETH", true, false, LIMIT_ORDER, 1, 0.02)
+	OrderAcknowledged(open, "open")
+	unknown := NewOrder("OrdersTest", "BTC_ETH", true, false, LIMIT_ORDER, 1, 0.02)
+	OrderAcknowledged(unknown, "timeout")
+
+	r := &ordersTestReconciler{
+		open: []Order{
+			{ExchangeOrderID: "open", Pair: "BTC_ETH", Amount: 1, Price: 0.02},
+			{ExchangeOrderID: "unknown", Pair: "BTC_LTC", Amount: 5, Price: 0.005},
+		},
+		fills:  map[string][]OrderFill{"gone": {{TradeID: "9", Price: 0.02, Amount: 1}}},
+		errors: map[string]error{"timeout": errors.New("timeout")},
+	}
+	if err := ReconcileOrders(r); err != nil {
+		t.Fatal(err)
+	}
+
+	if order, _ := GetOrderByOrderID(gone); order.Status != ORDER_STATUS_FILLED {
+		t.Error(fmt.Sprintf("Test failed. Expected filled. Actual %+v", order))
+	}
+	if order, _ := GetOrderByOrderID(open); order.Status != ORDER_STATUS_ACKNOWLEDGED {
+		t.Error(fmt.Sprintf("Test failed. Expected acknowledged. Actual %+v", order))
+	}
+	if order, _ := GetOrderByOrderID(unknown); order.Status != ORDER_STATUS_ACKNOWLEDGED {
+		t.Error(fmt.Sprintf("Test failed. Expected an order whose fills couldn't be fetched left open. Actual %+v", order))
+	}
+	if order, ok := GetOrderByExchangeOrderID("OrdersTest", "unknown"); !ok || order.Status != ORDER_STATUS_ACKNOWLEDGED || order.Amount != 5 {
+		t.Error(fmt.Sprintf("Test failed. Expected adopted order. Actual %+v", order))
+	}
+}
+
+func TestOrdersPersistence(t *testing.T) {
+	dir, err := ioutil.TempDir("", "orders")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, ORDERS_FILE)
+
+	id := NewOrder("OrdersTest", "BTC_ETH", true, false, MARKET_ORDER, 3, 0)
+	deleted := NewOrder("OrdersTest", "BTC_ETH", true, false, MARKET_ORDER, 1, 0)
+	DeleteOrder(deleted)
+	if err := SaveOrders(path); err != nil {
+		t.Fatal(err)
+	}
+
+	saved := Orders
+	defer func() { Orders = saved }()
+	Orders = nil
+
+	if err := LoadOrders(path); err != nil {
+		t.Fatal(err)
+	}
+	order, ok := GetOrderByOrderID(id)
+	if !ok || order.Type != MARKET_ORDER || order.Amount != 3 || order.Status != ORDER_STATUS_NEW {
+		t.Error(fmt.Sprintf("Test failed. Expected order %d restored. Actual %+v", id, order))
+	}
+	if next := NewOrder("OrdersTest", "BTC_ETH", true, false, LIMIT_ORDER, 1, 1); next <= deleted {
+		t.Error(fmt.Sprintf("Test failed. Expected ID after the deleted %d. Actual %d", deleted, next))
+	}
+}
@@ -0,0 +1,610 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// PaperExchange is a simulated exchange that fills orders against a live
+// orderbook without placing anything for real. Aggressive orders take
+// liquidity from the book at the taker fee, the rest of the order rests and
+// fills at its own price (and the maker fee) once the book trades through it.
+// Resting orders are matched lazily, whenever the exchange is next called.
+//
+// Balances are kept per account ("exchange" and "margin", like Poloniex) and
+// currency. Funds are held when an order is placed and released on cancel.
+// Margin orders build a position per pair instead of moving currencies, and
+// closing the position settles its profit or loss into the margin account in
+// the base currency.
+
+const (
+	PAPER_ACCOUNT_EXCHANGE = "exchange"
+	PAPER_ACCOUNT_MARGIN   = "margin"
+	PAPER_DEFAULT_LEVERAGE = 2.5
+	PAPER_EXCHANGE_SUFFIX  = " (paper)"
+)
+
+var (
+	ErrPaperInsufficientFunds = errors.New("Not enough funds to place order.")
+	ErrPaperOrderNotFound     = errors.New("Order not found, or already filled or cancelled.")
+	ErrPaperNoPosition        = errors.New("No open margin position.")
+	ErrPaperPostOnly          = errors.New("Post only order would have taken liquidity.")
+	ErrPaperInvalidOrder      = errors.New("Order price and amount must be greater than zero.")
+	ErrPaperOrderbookEmpty    = errors.New("Orderbook is empty, unable to fill order.")
+)
+
+// PaperExchangeName is the name paper orders, fills and positions are kept
+// under in the OMS, so they never mix with the exchange's real ones.
+func PaperExchangeName(exchange string) string {
+	return exchange + PAPER_EXCHANGE_SUFFIX
+}
+
+func IsPaperExchangeName(name string) bool {
+	return strings.HasSuffix(name, PAPER_EXCHANGE_SUFFIX)
+}
+
+type PaperBookLevel struct {
+	Price  float64
+	Amount float64
+}
+
+// PaperOrderbookFunc returns the live bids (best first) and asks (best first)
+// for pair.
+type PaperOrderbookFunc func(pair string) (bids, asks []PaperBookLevel, err error)
+
+// PaperPairFunc splits pair into the currency prices are quoted in (BTC for
+// Poloniex's BTC_ETH) and the currency being bought or sold (ETH).
+type PaperPairFunc func(pair string) (base, quote string)
+
+type paperLevelsByPrice []PaperBookLevel
+
+func (p paperLevelsByPrice) Len() int           { return len(p) }
+func (p paperLevelsByPrice) Less(i, j int) bool { return p[i].Price < p[j].Price }
+func (p paperLevelsByPrice) Swap(i, j int)      { p[i], p[j] = p[j], p[i] }
+
+type PaperFill struct {
+	TradeID int64
+	OrderID int64
+	Pair    string
+	Buy     bool
+	Maker   bool
+	Price   float64
+	Amount  float64
+	Total   float64
+	Fee     float64 // in the currency received
+	FeeRate float64 // fraction of the trade charged
+	Time    time.Time
+}
+
+type PaperOrder struct {
+	OrderID  int64
+	Pair     string
+	Buy      bool
+	Margin   bool
+	PostOnly bool
+	Price    float64
+	Amount   float64
+	Filled   float64
+	Open     bool
+	Time     time.Time
+	Fills    []PaperFill
+	hold     float64
+}
+
+// snapshot copies the order so callers can't race with later fills.
+func (o *PaperOrder) snapshot() PaperOrder {
+	x := *o
+	x.Fills = append([]PaperFill{}, o.Fills...)
+	return x
+}
+
+type paperOrdersByID []PaperOrder
+
+func (p paperOrdersByID) Len() int           { return len(p) }
+func (p paperOrdersByID) Less(i, j int) bool { return p[i].OrderID < p[j].OrderID }
+func (p paperOrdersByID) Swap(i, j int)      { p[i], p[j] = p[j], p[i] }
+
+type paperOrderRefsByID []*PaperOrder
+
+func (p paperOrderRefsByID) Len() int           { return len(p) }
+func (p paperOrderRefsByID) Less(i, j int) bool { return p[i].OrderID < p[j].OrderID }
+func (p paperOrderRefsByID) Swap(i, j int)      { p[i], p[j] = p[j], p[i] }
+
+// paperLevel identifies a price level on one side of a pair's book.
+type paperLevel struct {
+	Pair  string
+	Bid   bool
+	Price float64
+}
+
+type PaperPosition struct {
+	Pair        string
+	Amount      float64 // negative when short
+	BasePrice   float64
+	LendingRate float64 // per day
+	LendingFees float64
+	ProfitLoss  float64 // unrealised, at the last price seen
+	opened      time.Time
+}
+
+type PaperExchange struct {
+	Name     string
+	Latency  time.Duration
+	MakerFee float64 // percent
+	TakerFee float64 // percent
+	Leverage float64
+	Verbose  bool
+
+	orderbook   PaperOrderbookFunc
+	split       PaperPairFunc
+	mtx         sync.Mutex
+	balances    map[string]map[string]float64
+	orders      map[int64]*PaperOrder
+	positions   map[string]*PaperPosition
+	consumed    map[paperLevel]float64
+	nextOrderID int64
+	nextTradeID int64
+}
+
+func NewPaperExchange(name string, balances map[string]map[string]float64, orderbook PaperOrderbookFunc, split PaperPairFunc) *PaperExchange {
+	e := &PaperExchange{
+		Name:        name,
+		Leverage:    PAPER_DEFAULT_LEVERAGE,
+		orderbook:   orderbook,
+		split:       split,
+		balances:    make(map[string]map[string]float64),
+		orders:      make(map[int64]*PaperOrder),
+		positions:   make(map[string]*PaperPosition),
+		consumed:    make(map[paperLevel]float64),
+		nextOrderID: time.Now().Unix(),
+		nextTradeID: time.Now().Unix(),
+	}
+	for _, account := range []string{PAPER_ACCOUNT_EXCHANGE, PAPER_ACCOUNT_MARGIN} {
+		e.balances[account] = make(map[string]float64)
+	}
+	for account, currencies := range balances {
+		if e.balances[account] == nil {
+			e.balances[account] = make(map[string]float64)
+		}
+		for currency, amount := range currencies {
+			e.balances[account][currency] = amount
+		}
+	}
+	return e
+}
+
+// book waits out the simulated latency and fetches the orderbook, then
+// matches any resting orders on pair against it. It must be called without
+// mtx held and returns with it held.
+func (e *PaperExchange) book(pair string) (bids, asks []PaperBookLevel, err error) {
+	if e.Latency > 0 {
+		time.Sleep(e.Latency)
+	}
+	bids, asks, err = e.orderbook(pair)
+	e.mtx.Lock()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sort.Sort(sort.Reverse(paperLevelsByPrice(bids)))
+	sort.Sort(paperLevelsByPrice(asks))
+	e.matchResting(pair, bids, asks)
+	e.markPosition(pair, bids, asks)
+	return bids, asks, nil
+}
+
+func (e *PaperExchange) Balances() map[string]map[string]float64 {
+	e.mtx.Lock()
+	defer e.mtx.Unlock()
+
+	balances := make(map[string]map[string]float64)
+	for account, currencies := range e.balances {
+		balances[account] = make(map[string]float64)
+		for currency, amount := range currencies {
+			balances[account][currency] = amount
+		}
+	}
+	return balances
+}
+
+// PlaceOrder places a limit order. Immediate orders cancel whatever doesn't
+// fill straight away, post only orders are rejected if they would fill.
+func (e *PaperExchange) PlaceOrder(pair string, price, amount float64, buy, margin, postOnly, immediate bool) (PaperOrder, error) {
+	if price <= 0 || amount <= 0 {
+		return PaperOrder{}, ErrPaperInvalidOrder
+	}
+
+	bids, asks, err := e.book(pair)
+	defer e.mtx.Unlock()
+	if err != nil {
+		return PaperOrder{}, err
+	}
+
+	crosses := (buy && len(asks) > 0 && asks[0].Price <= price) || (!buy && len(bids) > 0 && bids[0].Price >= price)
+	if postOnly && crosses {
+		return PaperOrder{}, ErrPaperPostOnly
+	}
+
+	order := &PaperOrder{Pair: pair, Buy: buy, Margin: margin, PostOnly: postOnly, Price: price, Amount: amount, Open: true, Time: time.Now()}
+	if err := e.holdFunds(order); err != nil {
+		return PaperOrder{}, err
+	}
+	e.nextOrderID++
+	order.OrderID = e.nextOrderID
+	e.orders[order.OrderID] = order
+
+	levels := asks
+	if !buy {
+		levels = bids
+	}
+	for _, level := range levels {
+		if order.Filled >= order.Amount || (buy && level.Price > price) || (!buy && level.Price < price) {
+			break
+		}
+		e.fill(order, level.Price, math.Min(level.Amount, order.Amount-order.Filled), false)
+	}
+
+	if order.Filled < order.Amount && immediate {
+		e.cancel(order)
+	}
+	if e.Verbose {
+		log.Printf("%s paper order %d placed. pair=%s buy=%v margin=%v price=%f amount=%f filled=%f\n", e.Name, order.OrderID, pair, buy, margin, price, amount, order.Filled)
+	}
+	return order.snapshot(), nil
+}
+
+func (e *PaperExchange) CancelOrder(orderID int64) error {
+	e.mtx.Lock()
+	order, ok := e.orders[orderID]
+	open := ok && order.Open
+	e.mtx.Unlock()
+	if !open {
+		return ErrPaperOrderNotFound
+	}
+
+	// give the order one last chance to fill, as it would on a real exchange
+	_, _, err := e.book(order.Pair)
+	defer e.mtx.Unlock()
+	if err != nil {
+		return err
+	}
+	if !order.Open {
+		return ErrPaperOrderNotFound
+	}
+	e.cancel(order)
+	return nil
+}
+
+func (e *PaperExchange) Order(orderID int64) (PaperOrder, error) {
+	e.mtx.Lock()
+	order, ok := e.orders[orderID]
+	e.mtx.Unlock()
+	if !ok {
+		return PaperOrder{}, ErrPaperOrderNotFound
+	}
+
+	_, _, err := e.book(order.Pair)
+	defer e.mtx.Unlock()
+	if err != nil {
+		return PaperOrder{}, err
+	}
+	return order.snapshot(), nil
+}
+
+func (e *PaperExchange) OpenOrders(pair string) []PaperOrder {
+	e.mtx.Lock()
+	defer e.mtx.Unlock()
+
+	orders := []PaperOrder{}
+	for _, x := range e.orders {
+		if x.Open && (pair == "" || x.Pair == pair) {
+			orders = append(orders, x.snapshot())
+		}
+	}
+	sort.Sort(paperOrdersByID(orders))
+	return orders
+}
+
+// Position returns the margin position on pair, marked to the live book.
+func (e *PaperExchange) Position(pair string) (PaperPosition, error) {
+	_, _, err := e.book(pair)
+	defer e.mtx.Unlock()
+	if err != nil {
+		return PaperPosition{}, err
+	}
+
+	position, ok := e.positions[pair]
+	if !ok {
+		return PaperPosition{Pair: pair}, nil
+	}
+	return *position, nil
+}
+
+// ClosePosition closes the margin position on pair at market.
+func (e *PaperExchange) ClosePosition(pair string) (PaperOrder, error) {
+	bids, asks, err := e.book(pair)
+	defer e.mtx.Unlock()
+	if err != nil {
+		return PaperOrder{}, err
+	}
+
+	position, ok := e.positions[pair]
+	if !ok || position.Amount == 0 {
+		return PaperOrder{}, ErrPaperNoPosition
+	}
+
+	buy := position.Amount < 0
+	levels := bids
+	if buy {
+		levels = asks
+	}
+	if len(levels) == 0 {
+		return PaperOrder{}, ErrPaperOrderbookEmpty
+	}
+
+	e.nextOrderID++
+	order := &PaperOrder{OrderID: e.nextOrderID, Pair: pair, Buy: buy, Margin: true, Amount: math.Abs(position.Amount), Time: time.Now()}
+	e.orders[order.OrderID] = order
+	for _, level := range levels {
+		if order.Filled >= order.Amount {
+			break
+		}
+		e.fill(order, level.Price, math.Min(level.Amount, order.Amount-order.Filled), false)
+	}
+
+	// the book wasn't deep enough, close the rest at the worst price seen
+	if order.Filled < order.Amount {
+		e.fill(order, levels[len(levels)-1].Price, order.Amount-order.Filled, false)
+	}
+	order.Price = order.Fills[len(order.Fills)-1].Price
+
+	if e.Verbose {
+		log.Printf("%s paper position closed. pair=%s amount=%f\n", e.Name, pair, order.Amount)
+	}
+	return order.snapshot(), nil
+}
+
+// holdFunds takes what the order could cost out of the available balance.
+// Margin orders only need enough collateral for the leveraged position.
+func (e *PaperExchange) holdFunds(order *PaperOrder) error {
+	base, quote := e.split(order.Pair)
+
+	if order.Margin {
+		margin := e.balances[PAPER_ACCOUNT_MARGIN]
+		collateral := margin[base] + margin[quote]*order.Price
+		var exposure float64
+		for _, x := range e.positions {
+			exposure += math.Abs(x.Amount) * x.BasePrice
+		}
+		for _, x := range e.orders {
+			if x.Open && x.Margin {
+				exposure += (x.Amount - x.Filled) * x.Price
+			}
+		}
+		// only the part of the order that isn't reducing the position adds to it
+		adding := order.Amount
+		if position, ok := e.positions[order.Pair]; ok && position.Amount != 0 && (position.Amount > 0) != order.Buy {
+			adding = math.Max(order.Amount-math.Abs(position.Amount), 0)
+		}
+		if adding == 0 {
+			return nil
+		}
+		if collateral <= 0 || exposure+adding*order.Price > collateral*e.Leverage {
+			return ErrPaperInsufficientFunds
+		}
+		return nil
+	}
+
+	exchange := e.balances[PAPER_ACCOUNT_EXCHANGE]
+	currency, hold := quote, order.Amount
+	if order.Buy {
+		currency, hold = base, order.Amount*order.Price
+	}
+	if exchange[currency] < hold {
+		return ErrPaperInsufficientFunds
+	}
+	exchange[currency] -= hold
+	order.hold = hold
+	return nil
+}
+
+func (e *PaperExchange) cancel(order *PaperOrder) {
+	order.Open = false
+	if order.Margin || order.hold <= 0 {
+		return
+	}
+
+	base, quote := e.split(order.Pair)
+	currency := quote
+	if order.Buy {
+		currency = base
+	}
+	e.balances[PAPER_ACCOUNT_EXCHANGE][currency] += order.hold
+	order.hold = 0
+}
+
+// matchResting fills resting orders on pair, oldest first, against the book.
+// The live book never sees paper fills, so what resting orders take from a
+// level is remembered and not traded again until the level leaves the book.
+func (e *PaperExchange) matchResting(pair string, bids, asks []PaperBookLevel) {
+	orders := []*PaperOrder{}
+	for _, order := range e.orders {
+		if order.Open && order.Pair == pair {
+			orders = append(orders, order)
+		}
+	}
+	sort.Sort(paperOrderRefsByID(orders))
+
+	bids = e.remainingLevels(pair, true, bids)
+	asks = e.remainingLevels(pair, false, asks)
+	for _, order := range orders {
+		levels, bid := asks, false
+		if !order.Buy {
+			levels, bid = bids, true
+		}
+		for i := range levels {
+			level := &levels[i]
+			if order.Filled >= order.Amount || (order.Buy && level.Price > order.Price) || (!order.Buy && level.Price < order.Price) {
+				break
+			}
+			amount := math.Min(level.Amount, order.Amount-order.Filled)
+			if amount <= 0 {
+				continue
+			}
+			e.fill(order, order.Price, amount, true)
+			level.Amount -= amount
+			e.consumed[paperLevel{pair, bid, level.Price}] += amount
+		}
+	}
+}
+
+// remainingLevels copies one side of pair's book less what resting orders
+// already took from each level, forgetting levels that have left the book.
+func (e *PaperExchange) remainingLevels(pair string, bid bool, levels []PaperBookLevel) []PaperBookLevel {
+	remaining := make([]PaperBookLevel, len(levels))
+	seen := make(map[float64]bool)
+	for i, level := range levels {
+		key := paperLevel{pair, bid, level.Price}
+		if consumed := math.Min(e.consumed[key], level.Amount); consumed > 0 {
+			e.consumed[key] = consumed
+			level.Amount -= consumed
+		}
+		remaining[i] = level
+		seen[level.Price] = true
+	}
+	for key := range e.consumed {
+		if key.Pair == pair && key.Bid == bid && !seen[key.Price] {
+			delete(e.consumed, key)
+		}
+	}
+	return remaining
+}
+
+// fill must be called with mtx held.
+func (e *PaperExchange) fill(order *PaperOrder, price, amount float64, maker bool) {
+	if amount <= 0 {
+		return
+	}
+
+	rate := e.TakerFee
+	if maker {
+		rate = e.MakerFee
+	}
+
+	e.nextTradeID++
+	fill := PaperFill{TradeID: e.nextTradeID, OrderID: order.OrderID, Pair: order.Pair, Buy: order.Buy, Maker: maker, Price: price, Amount: amount, Total: price * amount, FeeRate: rate / 100, Time: time.Now()}
+	base, quote := e.split(order.Pair)
+
+	if order.Margin {
+		fill.Fee = fill.Total * rate / 100
+		e.balances[PAPER_ACCOUNT_MARGIN][base] -= fill.Fee
+		e.updatePosition(order, fill)
+	} else {
+		exchange := e.balances[PAPER_ACCOUNT_EXCHANGE]
+		// fees come out of whatever is received
+		if order.Buy {
+			fill.Fee = amount * rate / 100
+			order.hold -= fill.Total
+			exchange[quote] += amount - fill.Fee
+		} else {
+			fill.Fee = fill.Total * rate / 100
+			order.hold -= amount
+			exchange[base] += fill.Total - fill.Fee
+		}
+	}
+
+	order.Filled += amount
+	order.Fills = append(order.Fills, fill)
+	if order.Filled >= order.Amount {
+		e.cancel(order) // releases any price improvement still held
+	}
+}
+
+// updatePosition must be called with mtx held.
+func (e *PaperExchange) updatePosition(order *PaperOrder, fill PaperFill) {
+	position, ok := e.positions[order.Pair]
+	if !ok {
+		position = &PaperPosition{Pair: order.Pair, opened: fill.Time}
+		e.positions[order.Pair] = position
+	}
+	e.accrueLending(position, fill.Time)
+
+	amount := fill.Amount
+	if !order.Buy {
+		amount = -amount
+	}
+
+	switch {
+	case position.Amount == 0 || (position.Amount > 0) == (amount > 0):
+		position.BasePrice = (position.BasePrice*math.Abs(position.Amount) + fill.Total) / (math.Abs(position.Amount) + fill.Amount)
+		position.Amount += amount
+	default:
+		closed := math.Min(math.Abs(position.Amount), fill.Amount)
+		profit := closed * (fill.Price - position.BasePrice)
+		if position.Amount < 0 {
+			profit = -profit
+		}
+		base, _ := e.split(order.Pair)
+		e.balances[PAPER_ACCOUNT_MARGIN][base] += profit - position.LendingFees*closed/math.Abs(position.Amount)
+		position.LendingFees -= position.LendingFees * closed / math.Abs(position.Amount)
+
+		position.Amount += amount
+		if math.Abs(position.Amount) < 1e-12 {
+			delete(e.positions, order.Pair)
+			return
+		}
+		if (position.Amount > 0) == (amount > 0) { // flipped
+			position.BasePrice = fill.Price
+			position.LendingFees = 0
+		}
+	}
+}
+
+// accrueLending charges lending fees on the position's borrowed value since
+// they were last accrued.
+func (e *PaperExchange) accrueLending(position *PaperPosition, t time.Time) {
+	if position.LendingRate > 0 && position.Amount != 0 {
+		days := t.Sub(position.opened).Hours() / 24
+		position.LendingFees += math.Abs(position.Amount) * position.BasePrice * position.LendingRate * days
+	}
+	position.opened = t
+}
+
+// SetLendingRate sets the daily rate charged on the margin position for pair.
+func (e *PaperExchange) SetLendingRate(pair string, rate float64) {
+	e.mtx.Lock()
+	defer e.mtx.Unlock()
+
+	position, ok := e.positions[pair]
+	if !ok {
+		position = &PaperPosition{Pair: pair, opened: time.Now()}
+		e.positions[pair] = position
+	}
+	e.accrueLending(position, time.Now())
+	position.LendingRate = rate
+}
+
+// markPosition must be called with mtx held.
+func (e *PaperExchange) markPosition(pair string, bids, asks []PaperBookLevel) {
+	position, ok := e.positions[pair]
+	if !ok || position.Amount == 0 {
+		return
+	}
+
+	// mark to where the position could be closed
+	levels := bids
+	if position.Amount < 0 {
+		levels = asks
+	}
+	if len(levels) == 0 {
+		return
+	}
+
+	e.accrueLending(position, time.Now())
+	position.ProfitLoss = position.Amount*(levels[0].Price-position.BasePrice) - position.LendingFees
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+func paperTestExchange(balances map[string]map[string]float64, bids, asks *[]PaperBookLevel) *PaperExchange {
+	book := func(pair string) ([]PaperBookLevel, []PaperBookLevel, error) {
+		return append([]PaperBookLevel{}, *bids...), append([]PaperBookLevel{}, *asks...), nil
+	}
+	e := NewPaperExchange("Test", balances, book, poloniexPaperPair)
+	e.MakerFee = 0.1
+	e.TakerFee = 0.2
+	return e
+}
+
+func TestPaperExchangeOrders(t *testing.T) {
+	t.Parallel()
+	bids := []PaperBookLevel{{Price: 0.019, Amount: 10}}
+	asks := []PaperBookLevel{{Price: 0.021, Amount: 5}, {Price: 0.02, Amount: 2}}
+	e := paperTestExchange(map[string]map[string]float64{"exchange": {"BTC": 1}}, &bids, &asks)
+
+	if _, err := e.PlaceOrder("BTC_ETH", 0.021, 1, true, false, true, false); err != ErrPaperPostOnly {
+		t.Error(fmt.Sprintf("Test failed. Expected %v. Actual %v", ErrPaperPostOnly, err))
+	}
+	if _, err := e.PlaceOrder("BTC_ETH", 0.021, 100, true, false, false, false); err != ErrPaperInsufficientFunds {
+		t.Error(fmt.Sprintf("Test failed. Expected %v. Actual %v", ErrPaperInsufficientFunds, err))
+	}
+
+	// takes both ask levels up to its limit and rests the rest
+	order, err := e.PlaceOrder("BTC_ETH", 0.021, 10, true, false, false, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(order.Fills) != 2 || order.Fills[0].Price != 0.02 || order.Filled != 7 || !order.Open {
+		t.Fatal(fmt.Sprintf("Test failed. Unexpected order %+v", order))
+	}
+
+	balances := e.Balances()["exchange"]
+	if math.Abs(balances["ETH"]-7*0.998) > 1e-9 || math.Abs(balances["BTC"]-(1-10*0.021)) > 1e-9 {
+		t.Error(fmt.Sprintf("Test failed. Unexpected balances %v", balances))
+	}
+
+	// the book trades through the resting order, which fills at its own price
+	asks = []PaperBookLevel{{Price: 0.0205, Amount: 10}}
+	order, err = e.Order(order.OrderID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if order.Open || order.Filled != 10 || order.Fills[2].Price != 0.021 || !order.Fills[2].Maker {
+		t.Error(fmt.Sprintf("Test failed. Expected order filled. Actual %+v", order))
+	}
+
+	// price improvement on the taker fills is released once filled
+	spent := 2*0.02 + 5*0.021 + 3*0.021
+	balances = e.Balances()["exchange"]
+	if math.Abs(balances["BTC"]-(1-spent)) > 1e-9 {
+		t.Error(fmt.Sprintf("Test failed. Expected BTC %f. Actual %f", 1-spent, balances["BTC"]))
+	}
+
+	if err := e.CancelOrder(order.OrderID); err != ErrPaperOrderNotFound {
+		t.Error(fmt.Sprintf("Test failed. Expected %v. Actual %v", ErrPaperOrderNotFound, err))
+	}
+}
+
+func TestPaperExchangeRestingLiquidity(t *testing.T) {
+	t.Parallel()
+	bids := []PaperBookLevel{{Price: 0.018, Amount: 10}}
+	asks := []PaperBookLevel{{Price: 0.021, Amount: 10}}
+	e := paperTestExchange(map[string]map[string]float64{"exchange": {"BTC": 1}}, &bids, &asks)
+
+	first, err := e.PlaceOrder("BTC_ETH", 0.02, 5, true, false, true, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := e.PlaceOrder("BTC_ETH", 0.02, 5, true, false, true, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// one unit offered at the orders' price is shared between them, oldest
+	// first, and isn't traded again while the snapshot stays the same
+	asks = []PaperBookLevel{{Price: 0.02, Amount: 1}}
+	for i := 0; i < 3; i++ {
+		if first, err = e.Order(first.OrderID); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if second, err = e.Order(second.OrderID); err != nil {
+		t.Fatal(err)
+	}
+	if first.Filled != 1 || second.Filled != 0 {
+		t.Error(fmt.Sprintf("Test failed. Expected fills 1 and 0. Actual %f and %f", first.Filled, second.Filled))
+	}
+
+	// more size at the level only fills what's new
+	asks = []PaperBookLevel{{Price: 0.02, Amount: 3}}
+	if first, err = e.Order(first.OrderID); err != nil {
+		t.Fatal(err)
+	}
+	if second, err = e.Order(second.OrderID); err != nil {
+		t.Fatal(err)
+	}
+	if first.Filled != 3 || second.Filled != 0 {
+		t.Error(fmt.Sprintf("Test failed. Expected fills 3 and 0. Actual %f and %f", first.Filled, second.Filled))
+	}
+
+	// a fresh level is traded in full, split across both orders
+	asks = []PaperBookLevel{{Price: 0.0195, Amount: 4}}
+	if first, err = e.Order(first.OrderID); err != nil {
+		t.Fatal(err)
+	}
+	if second, err = e.Order(second.OrderID); err != nil {
+		t.Fatal(err)
+	}
+	if first.Filled != 5 || first.Open || second.Filled != 2 {
+		t.Error(fmt.Sprintf("Test failed. Expected fills 5 and 2. Actual %f and %f", first.Filled, second.Filled))
+	}
+
+	balances := e.Balances()["exchange"]
+	if math.Abs(balances["ETH"]-7*0.999) > 1e-9 {
+		t.Error(fmt.Sprintf("Test failed. Expected ETH %f. Actual %f", 7*0.999, balances["ETH"]))
+	}
+}
+
+func TestPaperExchangeMargin(t *testing.T) {
+	t.Parallel()
+	bids := []PaperBookLevel{{Price: 0.019, Amount: 100}}
+	asks := []PaperBookLevel{{Price: 0.02, Amount: 100}}
+	e := paperTestExchange(map[string]map[string]float64{"margin": {"BTC": 1}}, &bids, &asks)
+
+	if _, err := e.PlaceOrder("BTC_ETH", 0.02, 200, true, true, false, false); err != ErrPaperInsufficientFunds {
+		t.Error(fmt.Sprintf("Test failed. Expected %v over leverage. Actual %v", ErrPaperInsufficientFunds, err))
+	}
+
+	if _, err := e.PlaceOrder("BTC_ETH", 0.02, 50, true, true, false, false); err != nil {
+		t.Fatal(err)
+	}
+	position, _ := e.Position("BTC_ETH")
+	if position.Amount != 50 || position.BasePrice != 0.02 {
+		t.Error(fmt.Sprintf("Test failed. Unexpected position %+v", position))
+	}
+
+	bids = []PaperBookLevel{{Price: 0.022, Amount: 100}}
+	asks = []PaperBookLevel{{Price: 0.023, Amount: 100}}
+	order, err := e.ClosePosition("BTC_ETH")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if order.Buy || order.Filled != 50 || order.Price != 0.022 {
+		t.Error(fmt.Sprintf("Test failed. Unexpected closing order %+v", order))
+	}
+
+	// 50 * (0.022 - 0.02) less the taker fee on the way in and out
+	expected := 1 + 50*0.002 - (50*0.02+50*0.022)*0.002
+	if actual := e.Balances()["margin"]["BTC"]; math.Abs(actual-expected) > 1e-9 {
+		t.Error(fmt.Sprintf("Test failed. Expected %f. Actual %f", expected, actual))
+	}
+	if _, err := e.ClosePosition("BTC_ETH"); err != ErrPaperNoPosition {
+		t.Error(fmt.Sprintf("Test failed. Expected %v. Actual %v", ErrPaperNoPosition, err))
+	}
+}
+
+func TestPaperOrdersNamespace(t *testing.T) {
+	p := &Poloniex{Name: "PaperNamespaceTest"}
+	real := NewOrder(p.Name, "BTC_ETH", true, false, LIMIT_ORDER, 1, 0.02)
+	OrderAcknowledged(real, "123")
+
+	p.SetupPaperTrading(Exchanges{PaperTrading: true})
+	venue := &PoloniexExecutionVenue{p: p}
+	if name := venue.GetName(); name != "PaperNamespaceTest (paper)" {
+		t.Error(fmt.Sprintf("Test failed. Expected paper orders under their own name. Actual %s", name))
+	}
+	if err := ReconcileOrders(venue); err != nil {
+		t.Fatal(err)
+	}
+	if order, _ := GetOrderByOrderID(real); order.Status != ORDER_STATUS_ACKNOWLEDGED {
+		t.Error(fmt.Sprintf("Test failed. Expected the real order left alone. Actual %+v", order))
+	}
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"bytes"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"log"
@@ -9,9 +10,11 @@ import (
 	"net/url"
 	"sort"
 	"strconv"
+	"sync"
 	"time"
 
 	"github.com/aybabtme/rgbterm"
+	"github.com/gorilla/websocket"
 )
 
 const (
@@ -45,6 +48,7 @@ const (
 	POLONIEX_OPEN_LOAN_OFFERS       = "returnOpenLoanOffers"
 	POLONIEX_ACTIVE_LOANS           = "returnActiveLoans"
 	POLONIEX_AUTO_RENEW             = "toggleAutoRenew"
+	POLONIEX_DATE_LAYOUT            = "2006-01-02 15:04:05"
 
 	fee = .0015
 )
@@ -54,13 +58,28 @@ type Poloniex struct {
 	Enabled                 bool
 	Verbose                 bool
 	Websocket               bool
+	WebsocketURL            string
 	RESTPollingDelay        time.Duration
 	AuthenticatedAPISupport bool
+	APIUrl                  string
 	AccessKey, SecretKey    string
 	Fee                     float64
 	BaseCurrencies          []string
 	AvailablePairs          []string
 	EnabledPairs            []string
+	PaperTrading            bool
+	paper                   *PaperExchange
+	WebsocketConn           *websocket.Conn
+	Ticker                  map[string]PoloniexTicker
+	Orderbooks              map[string]*PoloniexWebsocketOrderbook
+	tickerMtx               sync.Mutex
+	orderbooksMtx           sync.Mutex
+	wampMtx                 sync.Mutex
+	wampRequestID           int64
+	wampRequests            map[int64]string               // subscribe request ID to topic
+	wampTopics              map[int64]string               // subscription ID to topic
+	candleTrades            map[string]poloniexCandleTrade // last trade fed to the candles per pair
+	candleTradesMtx         sync.Mutex
 }
 
 type PoloniexTicker struct {
@@ -82,12 +101,23 @@ func (p *Poloniex) SetDefaults() {
 	p.Verbose = false
 	p.Websocket = false
 	p.RESTPollingDelay = 10
+	p.APIUrl = POLONIEX_API_URL
+	p.WebsocketURL = POLONIEX_WEBSOCKET_ADDRESS
 }
 
 func (p *Poloniex) GetName() string {
 	return p.Name
 }
 
+// GetAccountName is the name orders and positions are kept under, which
+// differs from GetName when paper trading.
+func (p *Poloniex) GetAccountName() string {
+	if p.PaperTrading {
+		return PaperExchangeName(p.GetName())
+	}
+	return p.GetName()
+}
+
 func (p *Poloniex) SetEnabled(enabled bool) {
 	p.Enabled = enabled
 }
@@ -109,6 +139,9 @@ func (p *Poloniex) Setup(exch Exchanges) {
 		p.BaseCurrencies = SplitStrings(exch.BaseCurrencies, ",")
 		p.AvailablePairs = SplitStrings(exch.AvailablePairs, ",")
 		p.EnabledPairs = SplitStrings(exch.EnabledPairs, ",")
+		urls := GetExchangeURLs(exch, ExchangeURLs{p.APIUrl, p.WebsocketURL})
+		p.APIUrl, p.WebsocketURL = urls.API, urls.Websocket
+		p.SetupPaperTrading(exch)
 	}
 }
 
@@ -127,7 +160,7 @@ func (p *Poloniex) GetFee() float64 {
 
 func (p *Poloniex) Run() {
 	if p.Verbose {
-		log.Printf("%s Websocket: %s (url: %s).\n", p.GetName(), IsEnabled(p.Websocket), POLONIEX_WEBSOCKET_ADDRESS)
+		log.Printf("%s Websocket: %s (url: %s).\n", p.GetName(), IsEnabled(p.Websocket), p.WebsocketURL)
 		log.Printf("%s polling delay: %ds.\n", p.GetName(), p.RESTPollingDelay)
 		log.Printf("%s %d currencies enabled: %s.\n", p.GetName(), len(p.EnabledPairs), p.EnabledPairs)
 	}
@@ -136,6 +169,15 @@ func (p *Poloniex) Run() {
 		go p.WebsocketClient()
 	}
 
+	// when paper trading, only paper orders are reconciled against the paper
+	// venue, real ones are left alone under the exchange's own name
+	if p.AuthenticatedAPISupport || p.PaperTrading {
+		err := ReconcileOrders(&PoloniexExecutionVenue{p: p})
+		if err != nil {
+			log.Printf("%s unable to reconcile open orders: %v", p.GetName(), err)
+		}
+	}
+
 	currency := "BTC_ETH"
 	_ = currency
 
@@ -149,11 +191,8 @@ func (p *Poloniex) Run() {
 	//}()
 
 	// TODO add slippage penalty to sims
-	// TODO add a stop limit for -20% for every order? just in case...
-	// TODO this fucked up, test 2016/06/13 00:00:18 WARN couldn't place order. bailing for now. maybe postOnly=true? currency=BTC_ETH rate=0.023201 amount=1.897850 lending_rate=0.005000 buy=false err=error unmarshaling json: json: cannot unmarshal string into Go value of type int64 text: {"success":1,"message":"Margin order placed.","orderNumber":"67936208935","resultingTrades":[{"amount":"1.89785002","date":"2016-06-13 04:00:18","rate":"0.02320394","total":"0.04403759","tradeID":"11160991","type":"sell"}]}
-	// TODO last candle / tick candles seem fucked up. need to wait until past candle close then get last candle close, not current candle
 
-	p.realTrade(toTrade, currency)
+	p.realTrade(toTrade, currency, nil)
 
 	// BUY THE FARM
 	//p.allIn(toTrade, currency, true)
@@ -213,14 +252,21 @@ func (p *Poloniex) balance(side string) float64 {
 }
 
 func (p *Poloniex) allIn(side, currency string, buy bool) {
+	p.invest(side, currency, buy, 1)
+}
+
+// invest trades fraction of the margin balance, as sized by the risk manager
+func (p *Poloniex) invest(side, currency string, buy bool, fraction float64) {
 	bal := p.balance(side)
-	log.Printf("account balance %s: %f", side, bal)
+	log.Printf("account balance %s: %f investing: %f%%", side, bal, 100*fraction)
 
 	// BUY
-	p.trade(currency, bal, buy)
+	p.trade(currency, bal*fraction, buy)
 }
 
-func (p *Poloniex) realTrade(side, currency string) {
+// realTrade trades currency on the MACD until done is closed, which leaves
+// any position open.
+func (p *Poloniex) realTrade(side, currency string, done <-chan struct{}) {
 	// TODO close open orders when this shuts down?
 
 	// TODO close open orders before trading? can fix later..
@@ -249,286 +295,284 @@ func (p *Poloniex) realTrade(side, currency string) {
 	var profit, fees float64
 	fast, slow, sig := 50, 85, 5 // TODO make these configuramable ?
 	const candle = 7200          // 2hr candle; candlestick period in seconds; valid values are 300, 900, 1800, 7200, 14400, and 86400
-	emaFast := ema(fast)
-	emaSlow := ema(slow)
-	signal := ema(sig)
+	macd, err := NewMACD(fast, slow, sig)
+	if err != nil {
+		log.Fatalf("couldn't build the MACD: %v", err)
+	}
 
-	// get enough back data to seed the fast / slow emas so we can start trading..
-	// get 1 more than the last tick and save a tick so that we might immediately
-	// close/open a position. the loop ends up always getting the tick from approx:
-	// now() - candle, in order to read the full candle close (for charts yo)
+	// the risk manager works in the same units as the model, equity starts at 1
+	risk := NewRiskManager(bot.config.Risk, true)
+	risk.Verbose = p.Verbose
+	risk.UpdateEquity(1, time.Now())
+	if pos != none {
+		risk.OpenPosition(currency, pos == long, lastBuy, 1)
+		Positions.Open(p.GetAccountName(), currency, true, open.Amount, open.BasePrice)
+	}
 
-	// TODO need to get candle ~1 minute after it closes, so data is complete. meh
+	candles, err := NewCandleBuilder(p.GetName(), currency, candle*time.Second, p.GetCandleHistory)
+	if err != nil {
+		log.Fatalf("couldn't build candles: %v", err)
+	}
+	// wait for the poller to catch up before closing, it fills in whenever
+	// the websocket is down
+	candles.Delay = p.RESTPollingDelay * time.Second
+	candles.Verbose = p.Verbose
+	defer candles.Stop()
+
+	// get enough back data to seed the fast / slow emas so we can start trading..
+	// stop 1 candle short of the last closed one and save it so that we might
+	// immediately close/open a position. backfill only returns closed candles,
+	// so there's no more waiting around for polo to finish the current one.
 
-	lastData := time.Now().Add(-2 * candle * time.Second)
+	lastData := time.Now().Add(-candle * time.Second)
 	timemachine := time.Duration(sig+int(math.Max(float64(fast), float64(slow)))) * candle * time.Second
-	start := strconv.Itoa(int(lastData.Add(-timemachine).Unix()))
-	end := strconv.Itoa(int(lastData.Unix()))
-	period := strconv.Itoa(candle)
-	chart, err := p.GetChartData(currency, start, end, period)
+	chart, err := candles.Backfill(lastData.Add(-timemachine), lastData)
 	if err != nil {
-		log.Fatalf("issue going back in time; check the flux capacitor. err:", err)
+		log.Fatalf("issue going back in time; check the flux capacitor. err: %v", err)
 	}
 
 	// initialize all the things
 	for _, pt := range chart {
-		log.Printf("backdata t=%v high=%f low=%f open=%f close=%f %%=%f volume=%f", time.Unix(int64(pt.Date), 0), pt.High, pt.Low, pt.Open, pt.Close, 100*((pt.Close-pt.Open)/pt.Open), pt.Volume)
-		tradeMACD(pt.Close, &lastBuy, &profit, &fees, &pos, emaFast, emaSlow, signal)
-		lastData = time.Unix(int64(pt.Date), 0)
-	}
-
-	// TODO this doesn't gracefully handle missing ticks but trying to use lastData..lastData+candle kept giving lastData pt so fuck it
-	lastCandle := func(lastData time.Time) PoloniexChartData {
-		// retry for 2 minutes, and then bail
-		var errCount int
-		for ; ; time.Sleep(4 * time.Second) {
-			tryS := strconv.Itoa(int(lastData.Add(candle * time.Second).Unix()))
-			tryE := strconv.Itoa(int(lastData.Add(2 * candle * time.Second).Unix()))
-			// just get one data point
-			c, err := p.GetChartData(currency, tryS, tryE, period)
-			if err != nil {
-				errCount++
-				if errCount > 30 { // TODO assert that sleep * this is < fast ema
-					log.Fatalf("couldn't get price, maybe polo is down? err:", err)
-				}
-				continue
-			}
+		log.Printf("backdata t=%v high=%f low=%f open=%f close=%f %%=%f volume=%f", pt.Start, pt.High, pt.Low, pt.Open, pt.Close, 100*((pt.Close-pt.Open)/pt.Open), pt.Volume)
+		risk.UpdateEquity(1+profit, pt.End())
+		tradeMACD(currency, pt.Close, &lastBuy, &profit, &fees, &pos, macd, risk)
+	}
 
-			if len(c) < 1 || c[0].Date == 0 { // invalid, try again.. it takes a few tries to get next candle
+	// immediately do the first tick so that we might open a position, then
+	// trades from the current candle onwards drive the rest
+	candles.Close(time.Now())
+	go p.PollCandleTrades(currency, time.Now().Truncate(candle*time.Second), candles.Done())
+	go candles.Run()
+
+	// candles only close every couple hours, so check the stops against the
+	// ticker in between, which is streamed when the websocket is up
+	stops := time.NewTicker(p.RESTPollingDelay * time.Second)
+	defer stops.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-stops.C:
+			if pos == none {
 				continue
 			}
-
-			pt := c[0]
-			for _, p := range c { // sometimes we get 2 data points, probably should check these are contiguous but blech
-				if time.Unix(int64(p.Date), 0).Unix() != lastData.Unix() {
-					pt = p
-					break
-				}
+			ticker, err := p.GetLatestTicker(currency)
+			if err != nil {
+				log.Printf("WARN couldn't get ticker to check stops: %v", err)
+				continue
 			}
-
-			if time.Unix(int64(pt.Date), 0).Unix() == lastData.Unix() {
+			price := ticker.Last
+			Positions.Mark(p.GetAccountName(), currency, price)
+			reason, stopped := risk.CheckPrice(currency, price)
+			if !stopped {
 				continue
 			}
 
-			return pt
-		}
-	}
-
-	// immediately do the first tick so that we might open a position
-	tick := time.After(0)
-	for {
-		<-tick
-
-		pt := lastCandle(lastData)
-
-		lastData = time.Unix(int64(pt.Date), 0)
+			log.Printf("%s hit at %f, closing %s position opened at %f", reason, price, pos, lastBuy)
+			closeMACD(currency, price, &lastBuy, &profit, &fees, &pos, risk, reason)
+			risk.UpdateEquity(1+profit, time.Now())
+			p.closeMarginPosition(currency)
+		case pt, ok := <-candles.Candles:
+			if !ok {
+				return
+			}
+			log.Printf("tick t=%v high=%f low=%f open=%f close=%f %%=%f volume=%f pos=%s@%f", pt.Start, pt.High, pt.Low, pt.Open, pt.Close, 100*((pt.Close-pt.Open)/pt.Open), pt.Volume, pos, lastBuy)
 
-		nextTime := lastData.Add(2 * candle * time.Second).Sub(time.Now())
-		tick = time.After(nextTime)
+			last := pos
+			beforeProfit := profit
+			Positions.Mark(p.GetAccountName(), currency, pt.Close)
+			risk.UpdateEquity(1+profit, pt.End())
+			tradeMACD(currency, pt.Close, &lastBuy, &profit, &fees, &pos, macd, risk)
 
-		log.Printf("tick t=%v high=%f low=%f open=%f close=%f %%=%f volume=%f pos=%s@%f", time.Unix(int64(pt.Date), 0), pt.High, pt.Low, pt.Open, pt.Close, 100*((pt.Close-pt.Open)/pt.Open), pt.Volume, pos, lastBuy)
+			// execute trade if our position changed (but not our first time determining direction)
+			if last != pos && !(lastBuy == 0 && last == none) {
+				log.Printf("profits: total=%f total%%=%f last=%f last%%=%f", profit, 100*(profit/1), profit-beforeProfit, 100*((profit-beforeProfit)/beforeProfit))
 
-		last := pos
-		beforeProfit := profit
-		tradeMACD(pt.Close, &lastBuy, &profit, &fees, &pos, emaFast, emaSlow, signal)
+				p.closeMarginPosition(currency)
+				if position, ok := Positions.Position(p.GetAccountName(), currency); ok {
+					log.Printf("position: %s", position)
+				}
 
-		// execute trade if our position changed (but not our first time determining direction)
-		if last != pos && !(lastBuy == 0 && last == none) {
-			log.Printf("profits: total=%f total%%=%f last=%f last%%=%f", profit, 100*(profit/1), profit-beforeProfit, 100*((profit-beforeProfit)/beforeProfit))
+				// close our previous order and then invest what the risk manager
+				// allows, so that we invest any earnings (and don't take margin
+				// if we lost dollas and go short)
 
-			_, err = p.CloseMarginPosition(currency)
-			if err != nil {
-				log.Printf("WARN couldn't close margin position, maybe there isn't one? err=%v", err)
+				switch pos {
+				case none:
+				case long, short:
+					fraction := 1.
+					if position, ok := risk.Position(currency); ok {
+						fraction = position.Fraction
+					}
+					p.invest(side, currency, pos == long, fraction)
+				}
 			}
+		}
+	}
+}
 
-			// close our previous order and then go all in, so that we invest any earnings
-			// (and don't take margin if we lost dollas and go short)
+// closeMarginPosition closes the margin position at market, outside of the
+// OMS, so its trades and lending fees are booked to the position tracker here.
+func (p *Poloniex) closeMarginPosition(currency string) {
+	openI, err := p.GetMarginPosition(currency)
+	if err == nil {
+		Positions.AddLendingFee(p.GetAccountName(), currency, openI.(PoloniexMarginPosition).LendingFees)
+	}
 
-			switch pos {
-			case none:
-			case long, short:
-				p.allIn(side, currency, pos == long)
-			}
+	result, err := p.CloseMarginPosition(currency)
+	if err != nil {
+		log.Printf("WARN couldn't close margin position, maybe there isn't one? err=%v", err)
+		return
+	}
+
+	for _, x := range result.Trades[currency] {
+		fill := poloniexOrderFill(x.TradeID, x.Rate, x.Amount, x.Fee, x.Date)
+		err = Positions.AddFill(p.GetAccountName(), currency, true, x.Type == POLONIEX_ORDER_BUY, fill)
+		if err != nil {
+			log.Printf("WARN couldn't book closing trade to position. trade=%d err=%v", x.TradeID, err)
 		}
 	}
 }
 
-// TODO add retries to this so we don't miss a move
 func (p *Poloniex) trade(currency string, amount float64, buy bool) {
-	str := "LONG"
-	if !buy {
-		str = "SHORT"
-	}
+	// shave fees so we don't have to borrow anything
+	amount *= .975
 
 	// try to be a maker and save on fees. we're already guessing direction so adding
 	// an order slightly in front of it, if it doesn't hit we probably don't want to be
 	// in that position anyway.
+	// TODO calculate max lending rate from the open loan offers
+	venue := &PoloniexExecutionVenue{p: p, Margin: true, LendingRate: .005}
+	executor := NewExecutor(venue)
+	executor.Verbose = p.Verbose
+
+	_, err := executor.Execute(ExecutionParams{
+		Algorithm:    EXECUTION_CHASE,
+		Pair:         currency,
+		Buy:          buy,
+		Margin:       true,
+		Amount:       amount,
+		MaxSlippage:  1,
+		PollInterval: 5 * time.Second,
+		RepriceAfter: time.Minute,
+	})
+	if err != nil {
+		log.Printf("WARN couldn't fill order. currency=%s amount=%f buy=%v err=%v", currency, amount, buy, err)
+	}
+}
 
-	// TODO test that flipping long/short closes other order
-
-	var maxLendingRate float64 = .005
-	// TODO calculate maxRate later
-	//if !buy {
-	//loansA, err := p.GetOpenLoanOffers()
-	//if err != nil {
-	//log.Printf("WARN couldn't get loan offers. currency=%s err=%v", currency, err)
-	//return
-	//}
-	//var filled float64
-	//for _, l := range loansA[currency] {
-	//maxLendingRate = l.Rate
-	//filled += amount
-	//if filled >= amount {
-	//break
-	//}
-	//}
-
-	//if filled < amount {
-	//log.Printf("WARN not enough loans open to fill short order, this could mean high fees")
-	//}
-
-	//// add 10% to make sure it fills
-	//const maxTolerableRate = .005 // .5% -- yikes
-	//if maxLendingRate+(maxLendingRate*.1) > maxTolerableRate {
-	//log.Printf("WARN not making trade because lending fees are above tolerable rates. rate=%f tolerable=%f", maxLendingRate, maxTolerableRate)
-	//return
-	//}
-	//}
-
-	bestPrice := func() (float64, error) {
-		tickA, err := p.GetTicker()
-		if err != nil {
-			log.Printf("WARN couldn't get ticker. currency=%s err=%v", currency, err)
-			return 0, err
-		}
+// PoloniexExecutionVenue places orders for the execution engine, on the
+// margin account if Margin is set.
+type PoloniexExecutionVenue struct {
+	p           *Poloniex
+	Margin      bool
+	LendingRate float64
+}
 
-		tick := tickA[currency]
+func (v *PoloniexExecutionVenue) GetName() string {
+	return v.p.GetAccountName()
+}
 
-		// try to take the maker fee, but not very hard so we don't wait...
-		var rate float64
-		const outFront = .00001 // .01%
-		if buy {
-			rate = tick.LowestAsk - (outFront * tick.LowestAsk)
-		} else {
-			rate = tick.HighestBid + (outFront * tick.HighestBid)
-		}
-		return rate, nil
+// BestPrices comes from the streamed book when it's in sync, falling back to
+// the ticker.
+func (v *PoloniexExecutionVenue) BestPrices(pair string) (float64, float64, error) {
+	if bids, asks, ok := v.p.GetWebsocketOrderbook(pair, 1); ok && len(bids) > 0 && len(asks) > 0 {
+		return bids[0].Price, asks[0].Price, nil
 	}
 
-	// TODO jesus fuck clean this up
-
-	// TODO we could split the order across N orders and try to move the market a little ;)
-	rate, err := bestPrice()
+	ticker, err := v.p.GetLatestTicker(pair)
 	if err != nil {
-		return
+		return 0, 0, err
 	}
+	return ticker.HighestBid, ticker.LowestAsk, nil
+}
 
-	startRate := rate
-	var filled, avg float64
-	var trades uint64
-
-	// shave fees so we don't have to borrow anything
-	amount *= .975
+func (v *PoloniexExecutionVenue) PlaceLimitOrder(pair string, price, amount float64, buy, postOnly bool) (string, []OrderFill, error) {
+	if v.p.IsHalted(pair) {
+		return "", nil, ErrPoloniexMarketHalted
+	}
 
-	log.Printf("opening trade %s %s %f@%f", currency, str, amount, rate)
-	postOnly := false // only take maker fee, TODO do this later? if it starts moving fast, would prefer to just get in so...
-	order, err := p.PlaceMarginOrder(currency, rate, amount, maxLendingRate, postOnly, buy)
+	var order PoloniexOrderResponse
+	var err error
+	if v.Margin {
+		order, err = v.p.PlaceMarginOrder(pair, price, amount, v.LendingRate, postOnly, buy)
+	} else {
+		order, err = v.p.PlaceOrder(pair, price, amount, false, false, postOnly, buy)
+	}
+	if err == ErrPaperPostOnly || (err != nil && StringContains(err.Error(), "post-only")) {
+		return "", nil, ErrExecutionWouldTake
+	}
 	if err != nil {
-		log.Printf("WARN couldn't place order. bailing for now. maybe postOnly=true? currency=%s rate=%f amount=%f lending_rate=%f buy=%v err=%v", currency, rate, amount, maxLendingRate, buy, err)
-		return
+		return "", nil, err
+	}
+	if order.OrderNumber == 0 {
+		return "", nil, errors.New("no order number returned")
 	}
 
-	tradeIDs := make(map[int64]struct{}) // don't count trades twice
-	orderNum := order.OrderNumber
-
-	// avg = (amount1 * rate1) + (amount2 * rate2) + ...
-	//       ------------------------------------
-	//              amount1 + amount2 + ...
-
+	fills := []OrderFill{}
 	for _, t := range order.Trades {
-		tradeIDs[t.TradeID] = struct{}{}
-		trades++
-		filled += t.Amount
-		avg += t.Total // amount * rate
-		log.Printf("trade executed for order %d: amount=%f rate=%f total=%f type=%s time=%s originalAmount=%f left=%f filled=%f %%filled=%f", orderNum, t.Amount, t.Rate, t.Total, t.Type, t.Date, amount, amount-filled, filled, 100*(filled/amount))
+		fills = append(fills, poloniexOrderFill(t.TradeID, t.Rate, t.Amount, t.Fee, t.Date))
 	}
+	return strconv.FormatInt(order.OrderNumber, 10), fills, nil
+}
 
-	if amount-filled <= 0.00000 { // fuckin floats
-		avg /= amount
-		log.Printf("filled order for amount %f. trades=%d firstRate=%f avgRate=%f", amount, trades, startRate, avg)
-		return
+func (v *PoloniexExecutionVenue) CancelOrder(orderID string) error {
+	id, err := strconv.ParseInt(orderID, 10, 64)
+	if err != nil {
+		return err
 	}
+	_, err = v.p.CancelOrder(id)
+	return err
+}
 
-	// check every 5s to see if our order filled, after 1m go change our order
-	start := time.Now()
-	for ; ; time.Sleep(5 * time.Second) {
-
-		// if it's been a minute, cancel our order first, then check trades, and then
-		// if we still need to place another order to fulfill the amount, do so.
-		if time.Since(start) > 1*time.Minute {
-			// NOTE: MoveOrder could partially fill while we're doing calculation, so
-			// we need to cancel and then put it another order.
-			log.Printf("canceling open order, will make new one if still not filled. order=%d", orderNum)
-			_, err := p.CancelOrder(orderNum)
-			if err != nil {
-				log.Printf("couldn't cancel order. maybe filled? err=%v order=%d originalAmount=%f left=%f filled=%f %%filled=%f bail=%v", err, orderNum, amount, amount-filled, filled, 100*(filled/amount), amount-filled <= 0.00000)
-			}
-		}
-
-		orderTrades, _ := p.GetOrderTrades(orderNum)
-		// ignore errors, we don't care if no trades were executed for this order (that's the point)
-
-		for _, t := range orderTrades {
-			if _, ok := tradeIDs[t.TradeID]; ok {
-				continue // don't count trades twice
-			}
-
-			tradeIDs[t.TradeID] = struct{}{}
-			trades++
-			filled += t.Amount
-			avg += t.Total // (amount * rate)
-			log.Printf("trade executed for order %d: amount=%f rate=%f total=%f type=%s time=%s originalAmount=%f left=%f filled=%f %%filled=%f", orderNum, t.Amount, t.Rate, t.Total, t.Type, t.Date, amount, amount-filled, filled, 100*(filled/amount))
-			start = time.Now() // update this so that we sit at this price for a minute longer since we filled something
-		}
-
-		if amount-filled <= 0.00000 { // fuckin floats
-			avg /= amount
-			log.Printf("filled order for amount %f. trades=%d firstRate=%f avgRate=%f", amount, trades, startRate, avg)
-			return
-		}
-
-		// at this point, we cancelled the order, and we haven't yet filled it, so place another
-		if time.Since(start) > 1*time.Minute {
-			rate, err = bestPrice()
-			if err != nil {
-				return
-			}
-
-			log.Printf("re-opening trade at different price %s %s %f@%f", currency, str, amount-filled, rate)
-			order, err = p.PlaceMarginOrder(currency, rate, amount-filled, maxLendingRate, postOnly, buy)
-			if err != nil || order.OrderNumber == 0 {
-				log.Printf("WARN couldn't place order. bailing. maybe postOnly=true? currency=%s rate=%f amount=%f lending_rate=%f buy=%v err=%v", currency, rate, amount, maxLendingRate, buy, err)
-				return
-			}
+func (v *PoloniexExecutionVenue) OrderFills(orderID string) ([]OrderFill, error) {
+	id, err := strconv.ParseInt(orderID, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	trades, err := v.p.GetOrderTrades(id)
+	if err != nil {
+		return nil, err
+	}
 
-			orderNum = order.OrderNumber
-			start = time.Now()
+	fills := []OrderFill{}
+	for _, t := range trades {
+		fills = append(fills, poloniexOrderFill(t.TradeID, t.Rate, t.Amount, t.Fee, t.Date))
+	}
+	return fills, nil
+}
 
-			for _, t := range order.Trades {
-				tradeIDs[t.TradeID] = struct{}{}
-				trades++
-				filled += t.Amount
-				avg += t.Total // amount * rate
-				log.Printf("trade executed for order %d: amount=%f rate=%f total=%f type=%s time=%s originalAmount=%f left=%f filled=%f %%filled=%f", orderNum, t.Amount, t.Rate, t.Total, t.Type, t.Date, amount, amount-filled, filled, 100*(filled/amount))
-			}
+// OpenOrders lets the OMS reconcile against Poloniex's open orders.
+func (v *PoloniexExecutionVenue) OpenOrders() ([]Order, error) {
+	result, err := v.p.GetOpenOrders("")
+	if err != nil {
+		return nil, err
+	}
 
-			if amount-filled <= 0.00000 { // fuckin floats
-				avg /= amount
-				log.Printf("filled order for amount %f. trades=%d firstRate=%f avgRate=%f", amount, trades, startRate, avg)
-				return
-			}
+	orders := []Order{}
+	for pair, open := range result.(PoloniexOpenOrdersResponseAll).Data {
+		for _, x := range open {
+			created, _ := time.Parse(POLONIEX_DATE_LAYOUT, x.Date)
+			orders = append(orders, Order{
+				ExchangeOrderID: strconv.FormatInt(x.OrderNumber, 10),
+				Pair:            pair,
+				Buy:             x.Type == POLONIEX_ORDER_BUY,
+				Type:            LIMIT_ORDER,
+				Amount:          x.Amount,
+				Price:           x.Rate,
+				Created:         created,
+			})
 		}
 	}
+	return orders, nil
+}
+
+// poloniexOrderFill values the fee in the quote currency whichever side it
+// was charged on, as positions and the journal expect.
+func poloniexOrderFill(tradeID int64, rate, amount, feeRate float64, date string) OrderFill {
+	t, _ := time.Parse(POLONIEX_DATE_LAYOUT, date)
+	return OrderFill{TradeID: strconv.FormatInt(tradeID, 10), Price: rate, Amount: amount, Fee: rate * amount * feeRate, Time: t}
 }
 
 func (p *Poloniex) tryOne(currency string, days, fast, slow, tick int) {
@@ -550,7 +594,10 @@ func (p *Poloniex) tryOne(currency string, days, fast, slow, tick int) {
 		log.Print("no chart data, this will prove futile")
 	}
 
-	tharp, profit, f := tryEma(fast, slow, sig, tick, c)
+	tharp, profit, f, err := tryEma(currency, fast, slow, sig, tick, c)
+	if err != nil {
+		log.Fatal("can't backtest the MACD:", err)
+	}
 
 	log.Printf("%s profit: f= %d s= %d t= %d profit%%= %f profit= %f fees= %f price= %f tharp= %f", currency, fast, slow, tick*5, 100*(profit/1), profit, f, first, tharp)
 
@@ -609,7 +656,10 @@ func (p *Poloniex) tryAll(currency string) {
 			for slow := 1; slow <= maxSlow; slow++ {
 				matrix[i] = make([]float64, maxSig)
 				for sig := 1; sig <= maxSig; sig++ { // up to 2 hours
-					tharp, profit, f := tryEma(fast, slow, sig, tick, c)
+					tharp, profit, f, err := tryEma(currency, fast, slow, sig, tick, c)
+					if err != nil {
+						log.Fatal("can't backtest the MACD:", err)
+					}
 					p := 100 * (profit / 1)
 					matrix[i][sig-1] = p
 					if profit > maxProfit {
@@ -818,11 +868,12 @@ func color(v, vmin, vmax float64) (rd, gn, bl uint8) {
 	return uint8(r * 255), uint8(g * 255), uint8(b * 255)
 }
 
-func tryEma(fast, slow, sig, tickC int, lines []PoloniexChartData) (tharp, profit, fees float64) {
-	emaFast := ema(fast)
-	emaSlow := ema(slow)
-	signal := ema(sig)
-	_ = signal
+func tryEma(currency string, fast, slow, sig, tickC int, lines []PoloniexChartData) (tharp, profit, fees float64, err error) {
+	macd, err := NewMACD(fast, slow, sig)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	risk := NewRiskManager(bot.config.Risk, true)
 	var lastDir dir
 	var tick int
 	var lastBuy float64
@@ -836,8 +887,9 @@ func tryEma(fast, slow, sig, tickC int, lines []PoloniexChartData) (tharp, profi
 		// profit calc from log of prices
 		if tick%tickC == 0 {
 			oldP := profit
-			tradeMACD(l.Close, &lastBuy, &profit, &fees, &lastDir, emaFast, emaSlow, signal)
-			// tradeEMA(l.Close, &lastBuy, &profit, &fees, &lastDir, emaFast, emaSlow)
+			risk.UpdateEquity(1+profit, time.Unix(int64(l.Date), 0))
+			tradeMACD(currency, l.Close, &lastBuy, &profit, &fees, &lastDir, macd, risk)
+			// tradeEMA(l.Close, &lastBuy, &profit, &fees, &lastDir, NewEMA(fast), NewEMA(slow))
 
 			if oldP > profit {
 				p := profit - oldP
@@ -862,37 +914,31 @@ func tryEma(fast, slow, sig, tickC int, lines []PoloniexChartData) (tharp, profi
 
 	log.Printf("expectancy: f= %d s= %d t= %d sig= %d profit%%= %f profit= %f fees= %f %%win= %f avgW= %f %%loss= %f avgL= %f trades= %d tharp= %f", fast, slow, tickC*5, sig, 100*(profit/1), profit, fees, winPercent, avgWin, lossPercent, avgLoss, len(winners)+len(losers), tharp)
 
-	return tharp, profit, fees
+	return tharp, profit, fees, nil
 }
 
-func tradeMACD(price float64, lastBuy, profit, fees *float64, last *dir, emaFast, emaSlow, signal func(float64) float64) {
-	f := emaFast(price)
-	s := emaSlow(price)
-
-	if f == notTrained || s == notTrained {
+func tradeMACD(pair string, price float64, lastBuy, profit, fees *float64, last *dir, indicator *MACD, risk *RiskManager) {
+	value, ready := indicator.Update(price)
+	if !ready {
 		return
 	}
 
 	// MACD Line: (12-day EMA - 26-day EMA)
 	// Signal Line: 9-day EMA of MACD Line
-	macd := f - s
-	v := signal(macd)
+	macd := value.Line
+	v := value.Signal
 
-	if v == notTrained {
-		return
+	// stop losses, take profits and trailing stops come from the risk manager.
+	// in sims they're only checked on candle closes, so they aren't precise
+	// since w/i the candle they could have been tipped off, but it will help
+	// mitigate larger losses in data regardless
+	reason, stopped := "", false
+	if *lastBuy > 0 && *last != none {
+		reason, stopped = risk.CheckPrice(pair, price)
+	}
+	if !stopped {
+		reason = RISK_EXIT_SIGNAL
 	}
-
-	// stop losses
-	//stopLoss := .05
-	// TODO add drift to these, also they aren't precise since w/i the candle
-	// they could have been tipped off, but it will help mitigate larger losses in data regardless
-	//stopShortPrice := (*lastBuy) + (stopLoss * (*lastBuy))
-	//stopLongPrice := (*lastBuy) - (stopLoss * (*lastBuy))
-	//stopShort := price >= stopShortPrice
-	//stopLong := price <= stopLongPrice
-
-	// TODO figure these out..
-	//stopShort, stopLong := false, false
 
 	// TODO make sure macd actually 'breaks out' before flipping
 	//diff := (math.Abs(macd-v) / ((macd + v) / 2))
@@ -903,36 +949,11 @@ func tradeMACD(price float64, lastBuy, profit, fees *float64, last *dir, emaFast
 	// go short if macd < 0 && macd < v
 	// close short if macd > 0 || macd > v
 
-	// TODO calculate exposure
-
 	// close order first
-	if *lastBuy > 0 && *last == long && ( /*macd < 0 ||*/ macd < v) {
-		// close long
-		mult := 1. + *profit // NOTE: add profit back for compound
-
-		var p float64
-		p = mult * ((price - *lastBuy) / *lastBuy)
-		// NOTE compound ends up weighting later profits higher, which sucks (but shiny)
-
-		// log.Printf("msg=LONGPROFITS buy=%f price=%f profit=%f gross_profit=%f net_profit=%f fee=%f", *lastBuy, price, p, *profit+p, *profit+p-f, f)
-		f := (fee * mult) + (.0002 * mult)
-		*fees += f
-		*profit += p - f
-		*last = none
-	} else if *lastBuy > 0 && *last == short && ( /*macd > 0 ||*/ macd > v) {
-		// close short
-		mult := 1. + *profit // NOTE: add profit back for compound
-
-		var p float64
-		p = mult * ((*lastBuy - price) / *lastBuy)
-		// change profit in terms of eth_btc to be in terms of eth
-
-		// log.Printf("msg=SHORTPROFITS buy=%f price=%f profit=%f gross_profit=%f net_profit=%f fee=%f", *lastBuy, price, p, *profit+p, *profit+p-f, f)
-		//const lending = .0002
-		f := (fee * mult) + (.0002 * mult)
-		*fees += f
-		*profit += p - f
-		*last = none
+	if *lastBuy > 0 && *last == long && (stopped || /*macd < 0 ||*/ macd < v) {
+		closeMACD(pair, price, lastBuy, profit, fees, last, risk, reason)
+	} else if *lastBuy > 0 && *last == short && (stopped || /*macd > 0 ||*/ macd > v) {
+		closeMACD(pair, price, lastBuy, profit, fees, last, risk, reason)
 	}
 
 	// open new ones, if necessary
@@ -940,23 +961,59 @@ func tradeMACD(price float64, lastBuy, profit, fees *float64, last *dir, emaFast
 		// only go short if on the first trade, we were looking for a short xover or we were in cash.
 		// i.e. don't make the first trade until the first xover...
 		if *last == none || (*lastBuy == 0 && *last == long) {
-			*last = short
-			*lastBuy = price
+			openMACD(pair, price, lastBuy, profit, last, risk, short)
 			//	log.Printf("msg=SHORT price=%f", price)
 		}
 	} else if /*macd > 0 &&*/ macd > v { // TODO confirm > 0 ?
 		if *last == none || (*lastBuy == 0 && *last == short) {
-			*last = long
-			*lastBuy = price
+			openMACD(pair, price, lastBuy, profit, last, risk, long)
 			//	log.Printf("msg=LONG price=%f", price)
 		}
 	}
 }
 
-func tradeEMA(price float64, lastBuy, profit, fees *float64, last *dir, emaFast, emaSlow func(float64) float64) {
-	f := emaFast(price)
-	s := emaSlow(price)
-	if f == notTrained || s == notTrained {
+func closeMACD(pair string, price float64, lastBuy, profit, fees *float64, last *dir, risk *RiskManager, reason string) {
+	mult := 1. + *profit // NOTE: add profit back for compound
+	if position, ok := risk.Position(pair); ok {
+		mult = position.Size // whatever the risk manager let us open
+	}
+	// NOTE compound ends up weighting later profits higher, which sucks (but shiny)
+
+	var p float64
+	if *last == long {
+		p = mult * ((price - *lastBuy) / *lastBuy)
+	} else {
+		// change profit in terms of eth_btc to be in terms of eth
+		p = mult * ((*lastBuy - price) / *lastBuy)
+	}
+
+	// log.Printf("msg=PROFITS buy=%f price=%f profit=%f gross_profit=%f net_profit=%f fee=%f", *lastBuy, price, p, *profit+p, *profit+p-f, f)
+	//const lending = .0002
+	f := (fee * mult) + (.0002 * mult)
+	*fees += f
+	*profit += p - f
+	*last = none
+	risk.ClosePosition(pair, price, reason)
+}
+
+func openMACD(pair string, price float64, lastBuy, profit *float64, last *dir, risk *RiskManager, d dir) {
+	size, err := risk.CheckOrder(pair, d == long, 1+*profit)
+	if err != nil {
+		if risk.Verbose {
+			log.Printf("not opening %s %s position: %v", pair, d, err)
+		}
+		return
+	}
+
+	risk.OpenPosition(pair, d == long, price, size)
+	*last = d
+	*lastBuy = price
+}
+
+func tradeEMA(price float64, lastBuy, profit, fees *float64, last *dir, emaFast, emaSlow *EMA) {
+	f, fastReady := emaFast.Update(price)
+	s, slowReady := emaSlow.Update(price)
+	if !fastReady || !slowReady {
 		return
 	}
 
@@ -1005,8 +1062,6 @@ const (
 	none dir = iota
 	short
 	long
-
-	notTrained = -1
 )
 
 func (d dir) String() string {
@@ -1022,30 +1077,14 @@ func (d dir) String() string {
 	}
 }
 
-// EMA = Price(t) * k + EMA(y) * (1 – k)
-// k = 2/(N+1)
-func ema(n int) func(float64) float64 {
-	var avg float64
-	k := 2 / (float64(n) + 1)
-	var t int
-	return func(f float64) float64 {
-		avg = f*k + avg*(1-k)
-		if t < n {
-			t++
-			return notTrained
-		}
-		return avg
-	}
-}
-
 func (p *Poloniex) GetTicker() (map[string]PoloniexTicker, error) {
 	type response struct {
 		Data map[string]PoloniexTicker
 	}
 
 	resp := response{}
-	path := fmt.Sprintf("%s/public?command=returnTicker", POLONIEX_API_URL)
-	err := SendHTTPGetRequest(path, true, &resp.Data)
+	path := fmt.Sprintf("%s/public?command=returnTicker", p.APIUrl)
+	err := GetHTTPClient(p.Name).SendHTTPGetRequest(path, true, &resp.Data)
 
 	if err != nil {
 		return resp.Data, err
@@ -1055,8 +1094,8 @@ func (p *Poloniex) GetTicker() (map[string]PoloniexTicker, error) {
 
 func (p *Poloniex) GetVolume() (interface{}, error) {
 	var resp interface{}
-	path := fmt.Sprintf("%s/public?command=return24hVolume", POLONIEX_API_URL)
-	err := SendHTTPGetRequest(path, true, &resp)
+	path := fmt.Sprintf("%s/public?command=return24hVolume", p.APIUrl)
+	err := GetHTTPClient(p.Name).SendHTTPGetRequest(path, true, &resp)
 
 	if err != nil {
 		return resp, err
@@ -1068,14 +1107,12 @@ type PoloniexOrderbook struct {
 	Asks     [][]interface{} `json:"asks"`
 	Bids     [][]interface{} `json:"bids"`
 	IsFrozen string          `json:"isFrozen"`
+	Seq      int64           `json:"seq"`
 }
 
-//TO-DO: add support for individual pair depth fetching
+// GetOrderbook returns the book for currencyPair, or every pair's when it is
+// "all".
 func (p *Poloniex) GetOrderbook(currencyPair string, depth int) (map[string]PoloniexOrderbook, error) {
-	type Response struct {
-		Data map[string]PoloniexOrderbook
-	}
-
 	vals := url.Values{}
 	vals.Set("currencyPair", currencyPair)
 
@@ -1083,14 +1120,24 @@ func (p *Poloniex) GetOrderbook(currencyPair string, depth int) (map[string]Polo
 		vals.Set("depth", strconv.Itoa(depth))
 	}
 
-	resp := Response{}
-	path := fmt.Sprintf("%s/public?command=returnOrderBook&%s", POLONIEX_API_URL, vals.Encode())
-	err := SendHTTPGetRequest(path, true, &resp.Data)
+	path := fmt.Sprintf("%s/public?command=returnOrderBook&%s", p.APIUrl, vals.Encode())
+	if currencyPair != "all" {
+		book := PoloniexOrderbook{}
+		err := GetHTTPClient(p.Name).SendHTTPGetRequest(path, true, &book)
+
+		if err != nil {
+			return nil, err
+		}
+		return map[string]PoloniexOrderbook{currencyPair: book}, nil
+	}
+
+	resp := make(map[string]PoloniexOrderbook)
+	err := GetHTTPClient(p.Name).SendHTTPGetRequest(path, true, &resp)
 
 	if err != nil {
-		return resp.Data, err
+		return resp, err
 	}
-	return resp.Data, nil
+	return resp, nil
 }
 
 type PoloniexTradeHistory struct {
@@ -1116,8 +1163,8 @@ func (p *Poloniex) GetTradeHistory(currencyPair, start, end string) ([]PoloniexT
 	}
 
 	resp := []PoloniexTradeHistory{}
-	path := fmt.Sprintf("%s/public?command=returnTradeHistory&%s", POLONIEX_API_URL, vals.Encode())
-	err := SendHTTPGetRequest(path, true, &resp)
+	path := fmt.Sprintf("%s/public?command=returnTradeHistory&%s", p.APIUrl, vals.Encode())
+	err := GetHTTPClient(p.Name).SendHTTPGetRequest(path, true, &resp)
 
 	if err != nil {
 		return nil, err
@@ -1153,8 +1200,8 @@ func (p *Poloniex) GetChartData(currencyPair, start, end, period string) ([]Polo
 	}
 
 	resp := []PoloniexChartData{}
-	path := fmt.Sprintf("%s/public?command=returnChartData&%s", POLONIEX_API_URL, vals.Encode())
-	err := SendHTTPGetRequest(path, true, &resp)
+	path := fmt.Sprintf("%s/public?command=returnChartData&%s", p.APIUrl, vals.Encode())
+	err := GetHTTPClient(p.Name).SendHTTPGetRequest(path, true, &resp)
 
 	if err != nil {
 		return nil, err
@@ -1162,6 +1209,93 @@ func (p *Poloniex) GetChartData(currencyPair, start, end, period string) ([]Polo
 	return resp, nil
 }
 
+// GetCandleHistory is a CandleHistoryFunc backed by returnChartData. period
+// must be one of 300, 900, 1800, 7200, 14400 or 86400 seconds.
+func (p *Poloniex) GetCandleHistory(currencyPair string, start, end time.Time, period time.Duration) ([]Candle, error) {
+	chart, err := p.GetChartData(currencyPair, strconv.FormatInt(start.Unix(), 10), strconv.FormatInt(end.Unix(), 10), strconv.Itoa(int(period/time.Second)))
+	if err != nil {
+		return nil, err
+	}
+
+	candles := []Candle{}
+	for _, x := range chart {
+		if x.Date == 0 { // returned on its own when there is no data in range
+			continue
+		}
+		candles = append(candles, Candle{Start: time.Unix(int64(x.Date), 0), Period: period, Open: x.Open, High: x.High, Low: x.Low, Close: x.Close, Volume: x.QuoteVolume})
+	}
+	return candles, nil
+}
+
+type poloniexCandleTrade struct {
+	TradeID int64
+	Time    time.Time
+}
+
+// addCandleTrade feeds a public trade to the candle builders unless it has
+// been seen already, as the websocket and the poller overlap around
+// reconnects.
+func (p *Poloniex) addCandleTrade(pair string, tradeID int64, price, amount float64, t time.Time) {
+	p.candleTradesMtx.Lock()
+	if p.candleTrades == nil {
+		p.candleTrades = make(map[string]poloniexCandleTrade)
+	}
+	if tradeID != 0 {
+		if tradeID <= p.candleTrades[pair].TradeID {
+			p.candleTradesMtx.Unlock()
+			return
+		}
+		p.candleTrades[pair] = poloniexCandleTrade{TradeID: tradeID, Time: t}
+	}
+	p.candleTradesMtx.Unlock()
+
+	CandleAddTrade(p.GetName(), pair, price, amount, t)
+}
+
+func (p *Poloniex) lastCandleTrade(pair string) poloniexCandleTrade {
+	p.candleTradesMtx.Lock()
+	defer p.candleTradesMtx.Unlock()
+	return p.candleTrades[pair]
+}
+
+// PollCandleTrades feeds public trades for currencyPair since the given time
+// into any registered candle builders, every RESTPollingDelay seconds until
+// done is closed. While the websocket is connected it streams the trades, so
+// polling only runs when it isn't, plus once more after it reconnects to
+// cover the gap.
+func (p *Poloniex) PollCandleTrades(currencyPair string, since time.Time, done <-chan struct{}) {
+	polled := false
+	for {
+		streaming := p.Websocket && GetWebsocketSupervisor(p.GetName()).Connected()
+		if !streaming || polled {
+			polled = !streaming
+			if last := p.lastCandleTrade(currencyPair); last.Time.After(since) {
+				since = last.Time
+			}
+
+			trades, err := p.GetTradeHistory(currencyPair, strconv.FormatInt(since.Unix(), 10), strconv.FormatInt(time.Now().Unix(), 10))
+			if err != nil {
+				log.Printf("%s unable to poll trades for %s. Error: %s\n", p.GetName(), currencyPair, err)
+			}
+
+			for i := len(trades) - 1; i >= 0; i-- { // newest first
+				date, err := time.Parse(POLONIEX_DATE_LAYOUT, trades[i].Date)
+				if err != nil {
+					log.Println(err)
+					continue
+				}
+				p.addCandleTrade(currencyPair, trades[i].TradeID, trades[i].Rate, trades[i].Amount, date)
+			}
+		}
+
+		select {
+		case <-done:
+			return
+		case <-time.After(time.Second * p.RESTPollingDelay):
+		}
+	}
+}
+
 type PoloniexCurrencies struct {
 	Name               string      `json:"name"`
 	MaxDailyWithdrawal string      `json:"maxDailyWithdrawal"`
@@ -1178,8 +1312,8 @@ func (p *Poloniex) GetCurrencies() (map[string]PoloniexCurrencies, error) {
 		Data map[string]PoloniexCurrencies
 	}
 	resp := Response{}
-	path := fmt.Sprintf("%s/public?command=returnCurrencies", POLONIEX_API_URL)
-	err := SendHTTPGetRequest(path, true, &resp.Data)
+	path := fmt.Sprintf("%s/public?command=returnCurrencies", p.APIUrl)
+	err := GetHTTPClient(p.Name).SendHTTPGetRequest(path, true, &resp.Data)
 
 	if err != nil {
 		return resp.Data, err
@@ -1201,8 +1335,8 @@ type PoloniexLoanOrders struct {
 
 func (p *Poloniex) GetLoanOrders(currency string) (PoloniexLoanOrders, error) {
 	resp := PoloniexLoanOrders{}
-	path := fmt.Sprintf("%s/public?command=returnLoanOrders&currency=%s", POLONIEX_API_URL, currency)
-	err := SendHTTPGetRequest(path, true, &resp)
+	path := fmt.Sprintf("%s/public?command=returnLoanOrders&currency=%s", p.APIUrl, currency)
+	err := GetHTTPClient(p.Name).SendHTTPGetRequest(path, true, &resp)
 
 	if err != nil {
 		return resp, err
@@ -1215,6 +1349,10 @@ type PoloniexBalance struct {
 }
 
 func (p *Poloniex) GetBalances() (PoloniexBalance, error) {
+	if p.PaperTrading {
+		return PoloniexBalance{Currency: p.paper.Balances()[PAPER_ACCOUNT_EXCHANGE]}, nil
+	}
+
 	var result interface{}
 	err := p.SendAuthenticatedHTTPRequest("POST", POLONIEX_BALANCES, url.Values{}, &result)
 
@@ -1314,24 +1452,24 @@ func (p *Poloniex) GenerateNewAddress(currency string) (string, error) {
 
 type PoloniexDepositsWithdrawals struct {
 	Deposits []struct {
-		Currency      string    `json:"currency"`
-		Address       string    `json:"address"`
-		Amount        float64   `json:"amount,string"`
-		Confirmations int       `json:"confirmations"`
-		TransactionID string    `json:"txid"`
-		Timestamp     time.Time `json:"timestamp"`
-		Status        string    `json:"string"`
+		Currency      string  `json:"currency"`
+		Address       string  `json:"address"`
+		Amount        float64 `json:"amount,string"`
+		Confirmations int     `json:"confirmations"`
+		TransactionID string  `json:"txid"`
+		Timestamp     int64   `json:"timestamp"`
+		Status        string  `json:"status"`
 	} `json:"deposits"`
 	Withdrawals []struct {
-		WithdrawalNumber int64     `json:"withdrawalNumber"`
-		Currency         string    `json:"currency"`
-		Address          string    `json:"address"`
-		Amount           float64   `json:"amount,string"`
-		Confirmations    int       `json:"confirmations"`
-		TransactionID    string    `json:"txid"`
-		Timestamp        time.Time `json:"timestamp"`
-		Status           string    `json:"string"`
-		IPAddress        string    `json:"ipAddress"`
+		WithdrawalNumber int64   `json:"withdrawalNumber"`
+		Currency         string  `json:"currency"`
+		Address          string  `json:"address"`
+		Amount           float64 `json:"amount,string"`
+		Confirmations    int     `json:"confirmations"`
+		TransactionID    string  `json:"txid"`
+		Timestamp        int64   `json:"timestamp"`
+		Status           string  `json:"status"`
+		IPAddress        string  `json:"ipAddress"`
 	} `json:"withdrawals"`
 }
 
@@ -1384,6 +1522,10 @@ func (p *Poloniex) GetOpenOrders(currency string) (interface{}, error) {
 	if currency != "" {
 		values.Set("currencyPair", currency)
 		result := PoloniexOpenOrdersResponse{}
+		if p.PaperTrading {
+			result.Data = poloniexPaperOrders(p.paper.OpenOrders(currency))
+			return result, nil
+		}
 		err := p.SendAuthenticatedHTTPRequest("POST", POLONIEX_ORDERS, values, &result.Data)
 
 		if err != nil {
@@ -1394,6 +1536,13 @@ func (p *Poloniex) GetOpenOrders(currency string) (interface{}, error) {
 	} else {
 		values.Set("currencyPair", "all")
 		result := PoloniexOpenOrdersResponseAll{}
+		if p.PaperTrading {
+			result.Data = make(map[string][]PoloniexOrder)
+			for _, x := range p.paper.OpenOrders("") {
+				result.Data[x.Pair] = append(result.Data[x.Pair], poloniexPaperOrders([]PaperOrder{x})...)
+			}
+			return result, nil
+		}
 		err := p.SendAuthenticatedHTTPRequest("POST", POLONIEX_ORDERS, values, &result.Data)
 
 		if err != nil {
@@ -1404,10 +1553,10 @@ func (p *Poloniex) GetOpenOrders(currency string) (interface{}, error) {
 	}
 }
 
-type PoloniexAuthentictedTradeHistory struct {
+type PoloniexAuthenticatedTradeHistory struct {
 	GlobalTradeID int64   `json:"globalTradeID"`
 	TradeID       int64   `json:"tradeID,string"`
-	Date          string  `json:"data,string"`
+	Date          string  `json:"date"`
 	Rate          float64 `json:"rate,string"`
 	Amount        float64 `json:"amount,string"`
 	Total         float64 `json:"total,string"`
@@ -1418,11 +1567,11 @@ type PoloniexAuthentictedTradeHistory struct {
 }
 
 type PoloniexAuthenticatedTradeHistoryAll struct {
-	Data map[string][]PoloniexOrder
+	Data map[string][]PoloniexAuthenticatedTradeHistory
 }
 
 type PoloniexAuthenticatedTradeHistoryResponse struct {
-	Data []PoloniexOrder
+	Data []PoloniexAuthenticatedTradeHistory
 }
 
 func (p *Poloniex) GetAuthenticatedTradeHistory(currency, start, end string) (interface{}, error) {
@@ -1460,6 +1609,17 @@ func (p *Poloniex) GetAuthenticatedTradeHistory(currency, start, end string) (in
 }
 
 func (p *Poloniex) GetOrderTrades(orderID int64) ([]PoloniexOrderTrades, error) {
+	if p.PaperTrading {
+		order, err := p.paper.Order(orderID)
+		if err != nil {
+			return nil, err
+		}
+		if len(order.Fills) == 0 {
+			return nil, ErrPaperOrderNotFound // like poloniex, no trades is an error
+		}
+		return poloniexPaperOrderTrades(order), nil
+	}
+
 	values := url.Values{}
 	values.Set("orderNumber", strconv.FormatInt(orderID, 10))
 
@@ -1469,12 +1629,13 @@ func (p *Poloniex) GetOrderTrades(orderID int64) ([]PoloniexOrderTrades, error)
 	return result, err
 }
 
-// TODO no fees? :(
+// Fee is the fee rate charged on the trade, e.g. 0.0025 for 0.25%.
 type PoloniexResultingTrades struct {
 	Amount  float64 `json:"amount,string"`
 	Date    string  `json:"date"`
 	Rate    float64 `json:"rate,string"`
 	Total   float64 `json:"total,string"`
+	Fee     float64 `json:"fee,string"`
 	TradeID int64   `json:"tradeID,string"`
 	Type    string  `json:"type"`
 }
@@ -1485,6 +1646,7 @@ type PoloniexOrderTrades struct {
 	Date    string  `json:"date"`
 	Rate    float64 `json:"rate,string"`
 	Total   float64 `json:"total,string"`
+	Fee     float64 `json:"fee,string"`
 	TradeID int64   `json:"tradeID"`
 	Type    string  `json:"type"`
 }
@@ -1494,7 +1656,13 @@ type PoloniexOrderResponse struct {
 	Trades      []PoloniexResultingTrades `json:"resultingTrades"`
 }
 
-func (p *Poloniex) PlaceOrder(currency string, rate, amount float64, immediate, fillOrKill, buy bool) (PoloniexOrderResponse, error) {
+func (p *Poloniex) PlaceOrder(currency string, rate, amount float64, immediate, fillOrKill, postOnly, buy bool) (PoloniexOrderResponse, error) {
+	if p.PaperTrading {
+		// NOTE: fill or kill is simulated as immediate or cancel
+		order, err := p.paper.PlaceOrder(currency, rate, amount, buy, false, postOnly, immediate || fillOrKill)
+		return poloniexPaperOrderResponse(order), err
+	}
+
 	result := PoloniexOrderResponse{}
 	values := url.Values{}
 
@@ -1517,6 +1685,10 @@ func (p *Poloniex) PlaceOrder(currency string, rate, amount float64, immediate,
 		values.Set("fillOrKill", "1")
 	}
 
+	if postOnly {
+		values.Set("postOnly", "1")
+	}
+
 	err := p.SendAuthenticatedHTTPRequest("POST", orderType, values, &result)
 
 	if err != nil {
@@ -1532,6 +1704,11 @@ type PoloniexGenericResponse struct {
 }
 
 func (p *Poloniex) CancelOrder(orderID int64) (bool, error) {
+	if p.PaperTrading {
+		err := p.paper.CancelOrder(orderID)
+		return err == nil, err
+	}
+
 	result := PoloniexGenericResponse{}
 	values := url.Values{}
 	values.Set("orderNumber", strconv.FormatInt(orderID, 10))
@@ -1648,6 +1825,10 @@ func (p *Poloniex) GetTradableBalances() (map[string]map[string]float64, error)
 }
 
 func (p *Poloniex) GetAvailableBalances() (map[string]map[string]float64, error) {
+	if p.PaperTrading {
+		return p.paper.Balances(), nil
+	}
+
 	type Response struct {
 		Data map[string]map[string]interface{}
 	}
@@ -1714,6 +1895,14 @@ func (p *Poloniex) GetMarginAccountSummary() (PoloniexMargin, error) {
 }
 
 func (p *Poloniex) PlaceMarginOrder(currency string, rate, amount, lendingRate float64, postOnly, buy bool) (PoloniexOrderResponse, error) {
+	if p.PaperTrading {
+		if lendingRate != 0 {
+			p.paper.SetLendingRate(currency, lendingRate)
+		}
+		order, err := p.paper.PlaceOrder(currency, rate, amount, buy, true, postOnly, false)
+		return poloniexPaperOrderResponse(order), err
+	}
+
 	result := PoloniexOrderResponse{}
 	values := url.Values{}
 
@@ -1746,13 +1935,13 @@ func (p *Poloniex) PlaceMarginOrder(currency string, rate, amount, lendingRate f
 }
 
 type PoloniexMarginPosition struct {
-	Amount            float64 `json:"amount,string"`
-	Total             float64 `json:"total,string"`
-	BasePrice         float64 `json:"basePrice,string"`
-	LiquidiationPrice float64 `json:"liquidiationPrice"`
-	ProfitLoss        float64 `json:"pl,string"`
-	LendingFees       float64 `json:"lendingFees,string"`
-	Type              string  `json:"type"`
+	Amount           float64 `json:"amount,string"`
+	Total            float64 `json:"total,string"`
+	BasePrice        float64 `json:"basePrice,string"`
+	LiquidationPrice float64 `json:"liquidationPrice"`
+	ProfitLoss       float64 `json:"pl,string"`
+	LendingFees      float64 `json:"lendingFees,string"`
+	Type             string  `json:"type"`
 }
 
 func (p *Poloniex) GetMarginPosition(currency string) (interface{}, error) {
@@ -1761,6 +1950,13 @@ func (p *Poloniex) GetMarginPosition(currency string) (interface{}, error) {
 	if currency != "" && currency != "all" {
 		values.Set("currencyPair", currency)
 		result := PoloniexMarginPosition{}
+		if p.PaperTrading {
+			position, err := p.paper.Position(currency)
+			if err != nil {
+				return result, err
+			}
+			return poloniexPaperMarginPosition(position), nil
+		}
 		err := p.SendAuthenticatedHTTPRequest("POST", POLONIEX_MARGIN_POSITION, values, &result)
 
 		if err != nil {
@@ -1776,6 +1972,17 @@ func (p *Poloniex) GetMarginPosition(currency string) (interface{}, error) {
 		}
 
 		result := Response{}
+		if p.PaperTrading {
+			result.Data = make(map[string]PoloniexMarginPosition)
+			for _, x := range p.EnabledPairs {
+				position, err := p.paper.Position(x)
+				if err != nil {
+					return result, err
+				}
+				result.Data[x] = poloniexPaperMarginPosition(position)
+			}
+			return result, nil
+		}
 		err := p.SendAuthenticatedHTTPRequest("POST", POLONIEX_MARGIN_POSITION, values, &result.Data)
 
 		if err != nil {
@@ -1786,22 +1993,38 @@ func (p *Poloniex) GetMarginPosition(currency string) (interface{}, error) {
 	}
 }
 
-func (p *Poloniex) CloseMarginPosition(currency string) (bool, error) {
+type PoloniexCloseMarginResponse struct {
+	Success int                                  `json:"success"`
+	Error   string                               `json:"error"`
+	Message string                               `json:"message"`
+	Trades  map[string][]PoloniexResultingTrades `json:"resultingTrades"`
+}
+
+func (p *Poloniex) CloseMarginPosition(currency string) (PoloniexCloseMarginResponse, error) {
+	if p.PaperTrading {
+		order, err := p.paper.ClosePosition(currency)
+		if err != nil {
+			return PoloniexCloseMarginResponse{}, err
+		}
+		trades := poloniexPaperOrderResponse(order).Trades
+		return PoloniexCloseMarginResponse{Success: 1, Trades: map[string][]PoloniexResultingTrades{currency: trades}}, nil
+	}
+
 	values := url.Values{}
 	values.Set("currencyPair", currency)
-	result := PoloniexGenericResponse{}
+	result := PoloniexCloseMarginResponse{}
 
 	err := p.SendAuthenticatedHTTPRequest("POST", POLONIEX_MARGIN_POSITION_CLOSE, values, &result)
 
 	if err != nil {
-		return false, err
+		return result, err
 	}
 
 	if result.Success == 0 {
-		return false, errors.New(result.Error)
+		return result, errors.New(result.Error)
 	}
 
-	return true, nil
+	return result, nil
 }
 
 func (p *Poloniex) CreateLoanOffer(currency string, amount, rate float64, duration int, autoRenew bool) (int64, error) {
@@ -1862,7 +2085,7 @@ type PoloniexLoanOffer struct {
 	Rate      float64 `json:"rate,string"`
 	Amount    float64 `json:"amount,string"`
 	Duration  int     `json:"duration"`
-	AutoRenew bool    `json:"autoRenew,int"`
+	AutoRenew int     `json:"autoRenew"`
 	Date      string  `json:"date"`
 }
 
@@ -1924,7 +2147,7 @@ func (p *Poloniex) SendAuthenticatedHTTPRequest(method, endpoint string, values
 	headers["Content-Type"] = "application/x-www-form-urlencoded"
 	headers["Key"] = p.AccessKey
 
-	nonce := time.Now().UnixNano()
+	nonce := Nonces.Next(NonceKey(p.Name, p.AccessKey), time.Nanosecond)
 	nonceStr := strconv.FormatInt(nonce, 10)
 
 	values.Set("nonce", nonceStr)
@@ -1933,11 +2156,17 @@ func (p *Poloniex) SendAuthenticatedHTTPRequest(method, endpoint string, values
 	hmac := GetHMAC(HASH_SHA512, []byte(values.Encode()), []byte(p.SecretKey))
 	headers["Sign"] = HexEncodeToString(hmac)
 
-	path := fmt.Sprintf("%s/%s", POLONIEX_API_URL, POLONIEX_API_TRADING_ENDPOINT)
-	resp, err := SendHTTPRequest(method, path, headers, bytes.NewBufferString(values.Encode()))
+	path := fmt.Sprintf("%s/%s", p.APIUrl, POLONIEX_API_TRADING_ENDPOINT)
+	resp, err := GetHTTPClient(p.Name).SendAuthenticatedHTTPRequest(method, path, headers, bytes.NewBufferString(values.Encode()))
 
 	if err != nil {
-		return err
+		return ClassifyExchangeError(p.Name, err)
+	}
+
+	// errors come back as {"error": "..."} with a 200
+	failure := PoloniexGenericResponse{}
+	if json.Unmarshal([]byte(resp), &failure) == nil && failure.Error != "" {
+		return NewExchangeError(p.Name, "", failure.Error)
 	}
 
 	err = JSONDecode([]byte(resp), &result)
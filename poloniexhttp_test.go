@@ -0,0 +1,202 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func poloniexFixtures(t *testing.T) (*Poloniex, *fixtureServer) {
+	p := &Poloniex{}
+	p.SetDefaults()
+	p.SetAPIKeys("poloniex-key", "poloniex-secret")
+	return p, newFixtureServer(t, p.Name, "poloniex.json")
+}
+
+func TestPoloniexPublicFixtures(t *testing.T) {
+	p, f := poloniexFixtures(t)
+	defer f.Close()
+
+	start, end := time.Unix(1405699200, 0), time.Unix(1405702800, 0)
+	runFixtureCases(t, f, []fixtureCase{
+		{Name: "GetTicker", Call: func() (interface{}, error) { return p.GetTicker() },
+			Params: map[string]string{"command": "returnTicker"},
+			Want:   []string{"BTC_ETH:{Last:0.02086 LowestAsk:0.02087999", "IsFrozen:0 High24Hr:0.0080201"}},
+		{Name: "GetVolume", Call: func() (interface{}, error) { return p.GetVolume() },
+			Want: []string{"totalBTC:11205.13462112"}},
+		{Name: "GetOrderbook", Call: func() (interface{}, error) { return p.GetOrderbook("BTC_LTC", 50) },
+			Params: map[string]string{"command": "returnOrderBook", "currencyPair": "BTC_LTC", "depth": "50"},
+			Want:   []string{"BTC_LTC:{Asks:[[0.00780802 12.81543519]", "Seq:18849532"}},
+		{Name: "GetOrderbook all", Call: func() (interface{}, error) { return p.GetOrderbook("all", 0) },
+			Params: map[string]string{"currencyPair": "all", "depth": ""},
+			Want:   []string{"BTC_ETH:{Asks:[[0.02087999 1.5]] Bids:[[0.02086000 0.0346]] IsFrozen:1"}},
+		{Name: "GetTradeHistory", Call: func() (interface{}, error) { return p.GetTradeHistory("BTC_ETH", "1459843720", "") },
+			Params: map[string]string{"command": "returnTradeHistory", "currencyPair": "BTC_ETH", "start": "1459843720", "end": ""},
+			Want:   []string{"{GlobalTradeID:25129732 TradeID:6325758 Date:2016-04-05 08:08:40 Type:sell Rate:0.02565498 Amount:0.1 Total:0.00256549}"}},
+		{Name: "GetChartData", Call: func() (interface{}, error) { return p.GetChartData("BTC_XMR", "1405699200", "9999999999", "14400") },
+			Params: map[string]string{"command": "returnChartData", "currencyPair": "BTC_XMR", "start": "1405699200", "end": "9999999999", "period": "14400"},
+			Want:   []string{"Date:1405699200 High:0.0045388", "WeightedAverage:0.00430015"}},
+		{Name: "GetCandleHistory", Call: func() (interface{}, error) { return p.GetCandleHistory("BTC_XMR", start, end, 4*time.Hour) },
+			Params: map[string]string{"start": "1405699200", "end": "1405702800", "period": "14400"},
+			Want:   []string{"Open:0.00404545", "Close:0.00427592 Volume:10259.29079097"}},
+		{Name: "GetCandleHistory empty", Call: func() (interface{}, error) {
+			candles, err := p.GetCandleHistory("BTC_XMR", time.Unix(1000, 0), end, 4*time.Hour)
+			return len(candles), err
+		}, Want: []string{"0"}},
+		{Name: "GetCurrencies", Call: func() (interface{}, error) { return p.GetCurrencies() },
+			Want: []string{"BTC:{Name:Bitcoin MaxDailyWithdrawal:25.00000000 TxFee:0.0001 MinConfirmations:1", "Delisted:1"}},
+		{Name: "GetLoanOrders", Call: func() (interface{}, error) { return p.GetLoanOrders("BTC") },
+			Params: map[string]string{"command": "returnLoanOrders", "currency": "BTC"},
+			Want:   []string{"Offers:[{Rate:0.002 Amount:64.66305732 RangeMin:2 RangeMax:8}]", "Demands:[{Rate:0.0017"}},
+	})
+}
+
+func TestPoloniexAuthenticatedFixtures(t *testing.T) {
+	p, f := poloniexFixtures(t)
+	defer f.Close()
+
+	runFixtureCases(t, f, []fixtureCase{
+		{Name: "GetBalances", Call: func() (interface{}, error) { return p.GetBalances() },
+			Params: map[string]string{"command": "returnBalances"},
+			Want:   []string{"BTC:0.59098578", "LTC:3.31117268"}},
+		{Name: "GetCompleteBalances", Call: func() (interface{}, error) { return p.GetCompleteBalances("") },
+			Want: []string{"LTC:{Available:5.015 OnOrders:1.0025 BTCValue:0.078}"}},
+		{Name: "GetDepositAddresses", Call: func() (interface{}, error) { return p.GetDepositAddresses() },
+			Want: []string{"BTC:19YqztHmspv2egyD6jQM3yn81x5t5krVdJ"}},
+		{Name: "GenerateNewAddress", Call: func() (interface{}, error) { return p.GenerateNewAddress("DASH") },
+			Params: map[string]string{"command": "generateNewAddress", "currency": "DASH"},
+			Want:   []string{"CKXbbs8FAVbtEa397gJHSutmrdrBrhUMxe"}},
+		{Name: "GetDepositsWithdrawals", Call: func() (interface{}, error) { return p.GetDepositsWithdrawals("1399000000", "1400000000") },
+			Params: map[string]string{"start": "1399000000", "end": "1400000000"},
+			Want:   []string{"Amount:0.01006132 Confirmations:10", "Timestamp:1399305798 Status:COMPLETE}", "WithdrawalNumber:134933", "IPAddress:40.11.56.23"}},
+		{Name: "GetOpenOrders", Call: func() (interface{}, error) { return p.GetOpenOrders("BTC_AC") },
+			Params: map[string]string{"command": "returnOpenOrders", "currencyPair": "BTC_AC"},
+			Want:   []string{"{OrderNumber:120467 Type:buy Rate:0.04 Amount:100 Total:4 Date:2016-04-05 08:08:41 Margin:1}"}},
+		{Name: "GetOpenOrders all", Call: func() (interface{}, error) { return p.GetOpenOrders("") },
+			Params: map[string]string{"currencyPair": "all"},
+			Want:   []string{"BTC_1CR:[]", "BTC_AC:[{OrderNumber:120466"}},
+		{Name: "GetAuthenticatedTradeHistory", Call: func() (interface{}, error) { return p.GetAuthenticatedTradeHistory("BTC_ETH", "1459843700", "") },
+			Params: map[string]string{"command": "returnTradeHistory", "currencyPair": "BTC_ETH", "start": "1459843700"},
+			Want:   []string{"TradeID:6325758", "Fee:0.002 OrderNumber:34225313575 Type:sell Category:exchange"}},
+		{Name: "GetAuthenticatedTradeHistory all", Call: func() (interface{}, error) { return p.GetAuthenticatedTradeHistory("", "", "") },
+			Params: map[string]string{"currencyPair": "all"},
+			Want:   []string{"BTC_MAID:[{GlobalTradeID:29251512 TradeID:1385888", "Category:settlement"}},
+		{Name: "GetOrderTrades", Call: func() (interface{}, error) { return p.GetOrderTrades(12345) },
+			Params: map[string]string{"command": "returnOrderTrades", "orderNumber": "12345"},
+			Want:   []string{"{Amount:455.3420639 Date:2016-03-14 01:04:36 Rate:0.000185 Total:0.08423828 Fee:0.002 TradeID:147142 Type:buy}"}},
+		{Name: "PlaceOrder buy", Call: func() (interface{}, error) {
+			return p.PlaceOrder("BTC_ETH", 0.0000173, 338.8732, true, false, false, true)
+		},
+			Params: map[string]string{"command": "buy", "currencyPair": "BTC_ETH", "rate": "0.0000173", "amount": "338.8732", "immediateOrCancel": "1", "fillOrKill": "", "postOnly": ""},
+			Want:   []string{"OrderNumber:31226040", "TradeID:16164 Type:buy"}},
+		{Name: "PlaceOrder sell", Call: func() (interface{}, error) { return p.PlaceOrder("BTC_ETH", 0.03, 1, false, true, false, false) },
+			Params: map[string]string{"command": "sell", "fillOrKill": "1", "immediateOrCancel": "", "postOnly": ""},
+			Want:   []string{"OrderNumber:31226041 Trades:[]"}},
+		{Name: "CancelOrder", Call: func() (interface{}, error) { return p.CancelOrder(31226040) },
+			Params: map[string]string{"command": "cancelOrder", "orderNumber": "31226040"},
+			Want:   []string{"true"}},
+		{Name: "CancelOrder unknown", Call: func() (interface{}, error) { return p.CancelOrder(1) },
+			Err: ERROR_ORDER_NOT_FOUND},
+		{Name: "MoveOrder", Call: func() (interface{}, error) { return p.MoveOrder(239574175, 0.00001, 0) },
+			Params: map[string]string{"command": "moveOrder", "orderNumber": "239574175", "rate": "0.00001", "amount": ""},
+			Want:   []string{"OrderNumber:239574176 Trades:map[BTC_BTS:[]]"}},
+		{Name: "Withdraw", Call: func() (interface{}, error) { return p.Withdraw("NXT", "NXT-ADDR", 2398) },
+			Params: map[string]string{"command": "withdraw", "currency": "NXT", "address": "NXT-ADDR", "amount": "2398"},
+			Want:   []string{"true"}},
+		{Name: "GetFeeInfo", Call: func() (interface{}, error) { return p.GetFeeInfo() },
+			Want: []string{"{MakerFee:0.0014 TakerFee:0.0024 ThirtyDayVolume:612.00248891 NextTier:1200}"}},
+		{Name: "GetTradableBalances", Call: func() (interface{}, error) { return p.GetTradableBalances() },
+			Want: []string{"BTC_DASH:map[BTC:8.50274777 DASH:654.05752077]"}},
+		{Name: "GetAvailableBalances", Call: func() (interface{}, error) { return p.GetAvailableBalances() },
+			Want: []string{"exchange:map[BTC:1.19042859 BTM:386.52379392]", "margin:map[BTC:3.90015637]"}},
+		{Name: "TransferBalance", Call: func() (interface{}, error) { return p.TransferBalance("BTC", "exchange", "margin", 2) },
+			Params: map[string]string{"command": "transferBalance", "currency": "BTC", "amount": "2", "fromAccount": "exchange", "toAccount": "margin"},
+			Want:   []string{"true"}},
+		{Name: "GetMarginAccountSummary", Call: func() (interface{}, error) { return p.GetMarginAccountSummary() },
+			Want: []string{"{TotalValue:0.00346561 ProfitLoss:-1.22e-05 LendingFees:0 NetValue:0.00345341 BorrowedValue:0.0012322 CurrentMargin:2.80263755}"}},
+		{Name: "PlaceMarginOrder buy", Call: func() (interface{}, error) { return p.PlaceMarginOrder("BTC_DASH", 0.01383692, 1, 0.0002, true, true) },
+			Params: map[string]string{"command": "marginBuy", "currencyPair": "BTC_DASH", "rate": "0.01383692", "amount": "1", "postOnly": "1", "lendingRate": "0.0002"},
+			Want:   []string{"OrderNumber:154407998", "TradeID:1213556"}},
+		{Name: "PlaceMarginOrder sell", Call: func() (interface{}, error) { return p.PlaceMarginOrder("BTC_DASH", 0.015, 1, 0, false, false) },
+			Params: map[string]string{"command": "marginSell", "postOnly": "", "lendingRate": ""},
+			Want:   []string{"OrderNumber:154407999"}},
+		{Name: "GetMarginPosition", Call: func() (interface{}, error) { return p.GetMarginPosition("BTC_DASH") },
+			Params: map[string]string{"command": "getMarginPosition", "currencyPair": "BTC_DASH"},
+			Want:   []string{"{Amount:40.94717831 Total:-0.09671314 BasePrice:0.0023619 LiquidationPrice:-1 ProfitLoss:-0.00058655 LendingFees:-3.8e-07 Type:long}"}},
+		{Name: "GetMarginPosition all", Call: func() (interface{}, error) { return p.GetMarginPosition("") },
+			Params: map[string]string{"currencyPair": "all"},
+			Want:   []string{"BTC_DASH:{Amount:0", "Type:none"}},
+		{Name: "CloseMarginPosition", Call: func() (interface{}, error) { return p.CloseMarginPosition("BTC_XMR") },
+			Params: map[string]string{"command": "closeMarginPosition", "currencyPair": "BTC_XMR"},
+			Want:   []string{"Message:Successfully closed margin position.", "BTC_XMR:[{Amount:7.09215901", "TradeID:1213346 Type:sell"}},
+		{Name: "CreateLoanOffer", Call: func() (interface{}, error) { return p.CreateLoanOffer("BTC", 1, 0.00015, 2, true) },
+			Params: map[string]string{"command": "createLoanOffer", "currency": "BTC", "amount": "1", "duration": "2", "autoRenew": "1", "lendingRate": "0.00015"},
+			Want:   []string{"10590"}},
+		{Name: "CancelLoanOffer", Call: func() (interface{}, error) { return p.CancelLoanOffer(10590) },
+			Params: map[string]string{"command": "cancelLoanOffer", "orderID": "10590"},
+			Want:   []string{"true"}},
+		{Name: "GetOpenLoanOffers", Call: func() (interface{}, error) { return p.GetOpenLoanOffers() },
+			Want: []string{"BTC:[{ID:10595 Rate:0.0002 Amount:3 Duration:2 AutoRenew:1 Date:2015-05-10 23:33:50}]"}},
+		{Name: "GetActiveLoans", Call: func() (interface{}, error) { return p.GetActiveLoans() },
+			Want: []string{"Provided:[{ID:75073 Rate:0.0002 Amount:0.7223488", "Used:[{ID:75238"}},
+		{Name: "ToggleAutoRenew", Call: func() (interface{}, error) { return p.ToggleAutoRenew(10595) },
+			Params: map[string]string{"command": "toggleAutoRenew", "orderNumber": "10595"},
+			Want:   []string{"true"}},
+	})
+}
+
+func TestPoloniexSigning(t *testing.T) {
+	p, f := poloniexFixtures(t)
+	defer f.Close()
+
+	if _, err := p.GetBalances(); err != nil {
+		t.Fatal(err)
+	}
+
+	req := f.Last()
+	if req.Method != "POST" || req.Path != "/tradingApi" || req.Header.Get("Content-Type") != "application/x-www-form-urlencoded" {
+		t.Error(fmt.Sprintf("Test failed. Unexpected request %s %s %s", req.Method, req.Path, req.Header.Get("Content-Type")))
+	}
+	if req.Header.Get("Key") != "poloniex-key" {
+		t.Error(fmt.Sprintf("Test failed. Expected Key header. Actual %q", req.Header.Get("Key")))
+	}
+	if sign := HexEncodeToString(GetHMAC(HASH_SHA512, []byte(req.Body), []byte("poloniex-secret"))); req.Header.Get("Sign") != sign {
+		t.Error(fmt.Sprintf("Test failed. Expected Sign %s. Actual %s", sign, req.Header.Get("Sign")))
+	}
+	if req.Form().Get("nonce") == "" {
+		t.Error("Test failed. Expected a nonce in the signed body.")
+	}
+}
+
+func TestPoloniexPollCandleTrades(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `[{"globalTradeID":2,"tradeID":2,"date":"2016-04-05 08:08:50","type":"buy","rate":"0.021","amount":"2","total":"0.042"},{"globalTradeID":1,"tradeID":1,"date":"2016-04-05 08:08:40","type":"sell","rate":"0.02","amount":"1","total":"0.02"}]`)
+	}))
+	defer server.Close()
+
+	p := &Poloniex{Name: "PoloniexPollCandleTrades", APIUrl: server.URL, RESTPollingDelay: 1}
+	c, err := NewCandleBuilder(p.Name, "BTC_ETH", time.Minute, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		p.PollCandleTrades("BTC_ETH", time.Date(2016, 4, 5, 8, 0, 0, 0, time.UTC), c.Done())
+		close(stopped)
+	}()
+	time.Sleep(100 * time.Millisecond)
+	c.Close(time.Date(2016, 4, 5, 8, 10, 0, 0, time.UTC))
+	c.Stop()
+
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Test failed. Expected the poller to stop with the candle builder")
+	}
+	if candle := <-c.Candles; candle.Open != 0.02 || candle.Close != 0.021 || candle.Volume != 3 {
+		t.Error(fmt.Sprintf("Test failed. Expected the polled trades in the candle. Actual %+v", candle))
+	}
+}
@@ -0,0 +1,515 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	POLONIEX_MOCK_TRADES       = 200
+	POLONIEX_MOCK_TRADES_LIMIT = 50000
+	POLONIEX_MOCK_DEPTH        = 50
+)
+
+// poloniexMock serves Poloniex's public and trading APIs and its WAMP
+// websocket for a MockVenue. Responses are built from the client's own types,
+// so they decode exactly as Poloniex's do.
+type poloniexMock struct {
+	venue *MockVenue
+}
+
+func (p *poloniexMock) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/public":
+		p.public(w, r.URL.Query())
+	case "/" + POLONIEX_API_TRADING_ENDPOINT:
+		p.trading(w, r)
+	case "/wamp":
+		p.websocket(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func poloniexMockError(w http.ResponseWriter, format string, args ...interface{}) {
+	mockWriteJSON(w, http.StatusOK, PoloniexGenericResponse{Error: fmt.Sprintf(format, args...)})
+}
+
+func poloniexMockTime(s string) time.Time {
+	secs, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || s == "" {
+		return time.Time{}
+	}
+	return time.Unix(secs, 0)
+}
+
+func (p *poloniexMock) public(w http.ResponseWriter, values url.Values) {
+	pair := values.Get("currencyPair")
+
+	switch values.Get("command") {
+	case "returnTicker":
+		result := make(map[string]PoloniexTicker)
+		for _, x := range p.venue.Pairs() {
+			ticker, _ := p.venue.Ticker(x, 24*time.Hour)
+			result[x] = PoloniexTicker{
+				Last:          ticker.Last,
+				LowestAsk:     ticker.Ask,
+				HighestBid:    ticker.Bid,
+				PercentChange: (ticker.Last - ticker.Open) / ticker.Open,
+				BaseVolume:    ticker.Total,
+				QuoteVolume:   ticker.Volume,
+				High24Hr:      ticker.High,
+				Low24Hr:       ticker.Low,
+			}
+		}
+		mockWriteJSON(w, http.StatusOK, result)
+	case "return24hVolume":
+		result := make(map[string]interface{})
+		totals := make(map[string]float64)
+		for _, x := range p.venue.Pairs() {
+			ticker, _ := p.venue.Ticker(x, 24*time.Hour)
+			base, quote := p.venue.Split(x)
+			result[x] = map[string]string{base: mockFormatFloat(ticker.Total), quote: mockFormatFloat(ticker.Volume)}
+			totals[base] += ticker.Total
+		}
+		for currency, total := range totals {
+			result["total"+currency] = mockFormatFloat(total)
+		}
+		mockWriteJSON(w, http.StatusOK, result)
+	case "returnOrderBook":
+		depth, err := strconv.Atoi(values.Get("depth"))
+		if err != nil {
+			depth = POLONIEX_MOCK_DEPTH
+		}
+		if pair != "all" {
+			book, err := p.orderbook(pair, depth)
+			if err != nil {
+				poloniexMockError(w, "Invalid currency pair.")
+				return
+			}
+			mockWriteJSON(w, http.StatusOK, book)
+			return
+		}
+		result := make(map[string]PoloniexOrderbook)
+		for _, x := range p.venue.Pairs() {
+			result[x], _ = p.orderbook(x, depth)
+		}
+		mockWriteJSON(w, http.StatusOK, result)
+	case "returnTradeHistory":
+		start, end := poloniexMockTime(values.Get("start")), poloniexMockTime(values.Get("end"))
+		limit := POLONIEX_MOCK_TRADES
+		if !start.IsZero() {
+			limit = POLONIEX_MOCK_TRADES_LIMIT
+		}
+		trades, err := p.venue.Trades(pair, start, end, limit)
+		if err != nil {
+			poloniexMockError(w, "Invalid currency pair.")
+			return
+		}
+		result := []PoloniexTradeHistory{}
+		for _, x := range trades {
+			result = append(result, PoloniexTradeHistory{
+				GlobalTradeID: x.TradeID,
+				TradeID:       x.TradeID,
+				Date:          x.Time.UTC().Format(POLONIEX_DATE_LAYOUT),
+				Type:          poloniexPaperType(x.Buy),
+				Rate:          x.Price,
+				Amount:        x.Amount,
+				Total:         x.Total,
+			})
+		}
+		mockWriteJSON(w, http.StatusOK, result)
+	case "returnChartData":
+		period, _ := strconv.Atoi(values.Get("period"))
+		switch period {
+		case 300, 900, 1800, 7200, 14400, 86400:
+		default:
+			poloniexMockError(w, "Please specify a valid period.")
+			return
+		}
+		start, end := poloniexMockTime(values.Get("start")), poloniexMockTime(values.Get("end"))
+		if start.IsZero() {
+			poloniexMockError(w, "Please specify a time window of no more than 1 month.")
+			return
+		}
+		trades, err := p.venue.Trades(pair, start, end, math.MaxInt32)
+		if err != nil {
+			poloniexMockError(w, "Invalid currency pair.")
+			return
+		}
+		mockWriteJSON(w, http.StatusOK, poloniexMockChart(trades, time.Duration(period)*time.Second))
+	case "returnCurrencies":
+		result := make(map[string]PoloniexCurrencies)
+		for _, x := range p.venue.Pairs() {
+			base, quote := p.venue.Split(x)
+			for _, currency := range []string{base, quote} {
+				result[currency] = PoloniexCurrencies{Name: currency, MaxDailyWithdrawal: "10000.00000000", MinConfirmations: 1}
+			}
+		}
+		mockWriteJSON(w, http.StatusOK, result)
+	default:
+		poloniexMockError(w, "Invalid command.")
+	}
+}
+
+func (p *poloniexMock) orderbook(pair string, depth int) (PoloniexOrderbook, error) {
+	bids, asks, seq, err := p.venue.Book(pair, depth)
+	if err != nil {
+		return PoloniexOrderbook{}, err
+	}
+
+	levels := func(x []PaperBookLevel) [][]interface{} {
+		result := [][]interface{}{}
+		for _, y := range x {
+			result = append(result, []interface{}{mockFormatFloat(y.Price), y.Amount})
+		}
+		return result
+	}
+	return PoloniexOrderbook{Asks: levels(asks), Bids: levels(bids), IsFrozen: "0", Seq: seq}, nil
+}
+
+// poloniexMockChart buckets trades, newest first, into candles aligned to
+// period, oldest first. Poloniex returns a single zero candle when there's
+// nothing in range.
+func poloniexMockChart(trades []PaperFill, period time.Duration) []PoloniexChartData {
+	chart := []PoloniexChartData{}
+	for i := len(trades) - 1; i >= 0; i-- {
+		x := trades[i]
+		date := int(x.Time.Truncate(period).Unix())
+		if len(chart) == 0 || chart[len(chart)-1].Date != date {
+			chart = append(chart, PoloniexChartData{Date: date, Open: x.Price, High: x.Price, Low: x.Price})
+		}
+		candle := &chart[len(chart)-1]
+		candle.High = math.Max(candle.High, x.Price)
+		candle.Low = math.Min(candle.Low, x.Price)
+		candle.Close = x.Price
+		candle.Volume += x.Total
+		candle.QuoteVolume += x.Amount
+		candle.WeightedAverage = candle.Volume / candle.QuoteVolume
+	}
+	if len(chart) == 0 {
+		chart = append(chart, PoloniexChartData{})
+	}
+	return chart
+}
+
+func (p *poloniexMock) trading(w http.ResponseWriter, r *http.Request) {
+	body, _ := ioutil.ReadAll(r.Body)
+	sign := HexEncodeToString(GetHMAC(HASH_SHA512, body, []byte(MOCK_EXCHANGE_API_SECRET)))
+	if r.Header.Get("Key") != MOCK_EXCHANGE_API_KEY || r.Header.Get("Sign") != sign {
+		poloniexMockError(w, "Invalid API key/secret pair.")
+		return
+	}
+
+	values, err := url.ParseQuery(string(body))
+	if err != nil {
+		poloniexMockError(w, "Invalid request.")
+		return
+	}
+	nonce, _ := strconv.ParseInt(values.Get("nonce"), 10, 64)
+	if last, ok := p.venue.CheckNonce(nonce); !ok {
+		poloniexMockError(w, "Nonce must be greater than %d. You provided %d.", last, nonce)
+		return
+	}
+
+	pair := values.Get("currencyPair")
+	orderID, _ := strconv.ParseInt(values.Get("orderNumber"), 10, 64)
+	rate, _ := strconv.ParseFloat(values.Get("rate"), 64)
+	amount, _ := strconv.ParseFloat(values.Get("amount"), 64)
+
+	switch command := values.Get("command"); command {
+	case POLONIEX_BALANCES:
+		result := make(map[string]string)
+		for currency, x := range p.venue.Balances()[PAPER_ACCOUNT_EXCHANGE] {
+			result[currency] = mockFormatFloat(x)
+		}
+		mockWriteJSON(w, http.StatusOK, result)
+	case POLONIEX_BALANCES_COMPLETE:
+		held := p.venue.Held()
+		result := make(map[string]map[string]string)
+		for currency, x := range p.venue.Balances()[PAPER_ACCOUNT_EXCHANGE] {
+			result[currency] = map[string]string{
+				"available": mockFormatFloat(x),
+				"onOrders":  mockFormatFloat(held[currency]),
+				"btcValue":  mockFormatFloat(p.btcValue(currency, x+held[currency])),
+			}
+		}
+		mockWriteJSON(w, http.StatusOK, result)
+	case POLONIEX_AVAILABLE_BALANCES:
+		result := make(map[string]map[string]string)
+		for account, currencies := range p.venue.Balances() {
+			for currency, x := range currencies {
+				if x == 0 {
+					continue
+				}
+				if result[account] == nil {
+					result[account] = make(map[string]string)
+				}
+				result[account][currency] = mockFormatFloat(x)
+			}
+		}
+		mockWriteJSON(w, http.StatusOK, result)
+	case POLONIEX_ORDERS:
+		if pair != "all" {
+			mockWriteJSON(w, http.StatusOK, poloniexPaperOrders(p.venue.OpenOrders(pair)))
+			return
+		}
+		result := make(map[string][]PoloniexOrder)
+		for _, x := range p.venue.Pairs() {
+			result[x] = poloniexPaperOrders(p.venue.OpenOrders(x))
+		}
+		mockWriteJSON(w, http.StatusOK, result)
+	case POLONIEX_ORDER_TRADES:
+		order, err := p.venue.Order(orderID)
+		if err != nil || len(order.Fills) == 0 {
+			poloniexMockError(w, "Order not found, or you are not the person who placed it.")
+			return
+		}
+		mockWriteJSON(w, http.StatusOK, poloniexPaperOrderTrades(order))
+	case POLONIEX_ORDER_BUY, POLONIEX_ORDER_SELL, POLONIEX_MARGIN_BUY, POLONIEX_MARGIN_SELL:
+		buy := command == POLONIEX_ORDER_BUY || command == POLONIEX_MARGIN_BUY
+		margin := command == POLONIEX_MARGIN_BUY || command == POLONIEX_MARGIN_SELL
+		immediate := values.Get("immediateOrCancel") == "1" || values.Get("fillOrKill") == "1"
+		if lendingRate, _ := strconv.ParseFloat(values.Get("lendingRate"), 64); margin && lendingRate > 0 {
+			p.venue.SetLendingRate(pair, lendingRate)
+		}
+
+		order, err := p.venue.PlaceOrder(pair, rate, amount, buy, margin, values.Get("postOnly") == "1", immediate)
+		if err != nil {
+			p.orderError(w, pair, buy, err)
+			return
+		}
+		mockWriteJSON(w, http.StatusOK, poloniexPaperOrderResponse(order))
+	case POLONIEX_ORDER_CANCEL:
+		if err := p.venue.CancelOrder(orderID); err != nil {
+			poloniexMockError(w, "Invalid order number, or you are not the person who placed the order.")
+			return
+		}
+		mockWriteJSON(w, http.StatusOK, PoloniexGenericResponse{Success: 1})
+	case POLONIEX_MARGIN_POSITION:
+		if pair != "all" {
+			position, err := p.venue.Position(pair)
+			if err != nil {
+				poloniexMockError(w, "Invalid currency pair.")
+				return
+			}
+			mockWriteJSON(w, http.StatusOK, poloniexPaperMarginPosition(position))
+			return
+		}
+		result := make(map[string]PoloniexMarginPosition)
+		for _, x := range p.venue.Pairs() {
+			position, _ := p.venue.Position(x)
+			result[x] = poloniexPaperMarginPosition(position)
+		}
+		mockWriteJSON(w, http.StatusOK, result)
+	case POLONIEX_MARGIN_POSITION_CLOSE:
+		order, err := p.venue.ClosePosition(pair)
+		if err == ErrPaperNoPosition {
+			poloniexMockError(w, "You do not have an open position in this market.")
+			return
+		}
+		if err != nil {
+			p.orderError(w, pair, false, err)
+			return
+		}
+		trades := poloniexPaperOrderResponse(order).Trades
+		mockWriteJSON(w, http.StatusOK, PoloniexCloseMarginResponse{Success: 1, Message: "Successfully closed margin position.", Trades: map[string][]PoloniexResultingTrades{pair: trades}})
+	case POLONIEX_FEE_INFO:
+		mockWriteJSON(w, http.StatusOK, PoloniexFee{MakerFee: p.venue.account.MakerFee / 100, TakerFee: p.venue.account.TakerFee / 100})
+	default:
+		poloniexMockError(w, "Invalid command.")
+	}
+}
+
+func (p *poloniexMock) orderError(w http.ResponseWriter, pair string, buy bool, err error) {
+	switch err {
+	case ErrPaperInsufficientFunds:
+		base, quote := p.venue.Split(pair)
+		if buy {
+			poloniexMockError(w, "Not enough %s.", base)
+		} else {
+			poloniexMockError(w, "Not enough %s.", quote)
+		}
+	case ErrPaperPostOnly:
+		poloniexMockError(w, "Unable to place post-only order at this price.")
+	case ErrPaperInvalidOrder:
+		poloniexMockError(w, "Invalid rate or amount parameter.")
+	case ErrMockExchangeUnknownPair:
+		poloniexMockError(w, "Invalid currency pair.")
+	default:
+		poloniexMockError(w, "%s", err)
+	}
+}
+
+// btcValue values amount of currency at the last BTC price, where there is
+// one.
+func (p *poloniexMock) btcValue(currency string, amount float64) float64 {
+	if currency == "BTC" {
+		return amount
+	}
+	for _, x := range p.venue.Pairs() {
+		if base, quote := p.venue.Split(x); base == "BTC" && quote == currency {
+			ticker, _ := p.venue.Ticker(x, 24*time.Hour)
+			return amount * ticker.Last
+		}
+	}
+	return 0
+}
+
+var poloniexMockUpgrader = websocket.Upgrader{
+	Subprotocols: []string{POLONIEX_WAMP_PROTOCOL},
+	CheckOrigin:  func(r *http.Request) bool { return true },
+}
+
+type poloniexMockTopic struct {
+	ID   int64
+	seq  int64               // of the book last sent
+	bids map[float64]float64 // the levels last sent
+	asks map[float64]float64
+}
+
+// websocket speaks just enough WAMP for a subscriber: any hello is welcomed,
+// and the ticker and pair topics publish each MockEvent. A pair's events
+// carry the book levels changed since the last one sent, and the trades,
+// with the book's sequence number.
+func (p *poloniexMock) websocket(w http.ResponseWriter, r *http.Request) {
+	conn, err := poloniexMockUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	events := p.venue.Subscribe()
+	defer p.venue.Unsubscribe(events)
+
+	requests := make(chan []interface{})
+	go func() {
+		defer close(requests)
+		for {
+			request := []interface{}{}
+			if err := conn.ReadJSON(&request); err != nil {
+				return
+			}
+			requests <- request
+		}
+	}()
+
+	topics := make(map[string]*poloniexMockTopic)
+	publication := int64(0)
+	for {
+		messages := []interface{}{}
+		select {
+		case request, ok := <-requests:
+			if !ok {
+				return
+			}
+			messages = p.websocketRequest(request, topics)
+		case event := <-events:
+			if topic, ok := topics[event.Pair]; ok && event.Seq > topic.seq {
+				publication++
+				args := p.websocketBookChanges(topic, event)
+				for _, x := range event.Trades {
+					args = append(args, poloniexMockTrade(x))
+				}
+				messages = append(messages, []interface{}{POLONIEX_WAMP_EVENT, topic.ID, publication, map[string]interface{}{}, args, map[string]interface{}{"seq": event.Seq}})
+			}
+			if topic, ok := topics[POLONIEX_WEBSOCKET_TICKER]; ok && len(event.Trades) > 0 {
+				publication++
+				messages = append(messages, []interface{}{POLONIEX_WAMP_EVENT, topic.ID, publication, map[string]interface{}{}, p.ticker(event.Pair)})
+			}
+		}
+
+		for _, x := range messages {
+			if conn.WriteJSON(x) != nil {
+				return
+			}
+		}
+	}
+}
+
+func (p *poloniexMock) websocketRequest(request []interface{}, topics map[string]*poloniexMockTopic) []interface{} {
+	if len(request) == 0 {
+		return nil
+	}
+
+	switch poloniexWAMPID(request[0]) {
+	case POLONIEX_WAMP_HELLO:
+		return []interface{}{[]interface{}{POLONIEX_WAMP_WELCOME, 1, map[string]interface{}{"roles": map[string]interface{}{"broker": map[string]interface{}{}}}}}
+	case POLONIEX_WAMP_SUBSCRIBE:
+		if len(request) < 4 {
+			return nil
+		}
+		topic, _ := request[3].(string)
+		if topic == POLONIEX_WEBSOCKET_TICKER {
+			topics[topic] = &poloniexMockTopic{ID: int64(len(topics) + 1)}
+			return []interface{}{[]interface{}{POLONIEX_WAMP_SUBSCRIBED, request[1], topics[topic].ID}}
+		}
+
+		bids, asks, seq, err := p.venue.Book(topic, 0)
+		if err != nil {
+			return []interface{}{[]interface{}{POLONIEX_WAMP_ERROR, POLONIEX_WAMP_SUBSCRIBE, request[1], map[string]interface{}{}, "wamp.error.invalid_uri"}}
+		}
+		topics[topic] = &poloniexMockTopic{ID: int64(len(topics) + 1), seq: seq, bids: poloniexMockLevels(bids), asks: poloniexMockLevels(asks)}
+		return []interface{}{[]interface{}{POLONIEX_WAMP_SUBSCRIBED, request[1], topics[topic].ID}}
+	case POLONIEX_WAMP_GOODBYE:
+		return []interface{}{[]interface{}{POLONIEX_WAMP_GOODBYE, map[string]interface{}{}, "wamp.close.goodbye_and_out"}}
+	}
+	return nil
+}
+
+func poloniexMockLevels(levels []PaperBookLevel) map[float64]float64 {
+	result := make(map[float64]float64)
+	for _, x := range levels {
+		result[x.Price] = x.Amount
+	}
+	return result
+}
+
+// websocketBookChanges returns the orderBookModify and orderBookRemove
+// updates from the levels last sent on topic to those of event.
+func (p *poloniexMock) websocketBookChanges(topic *poloniexMockTopic, event MockEvent) []interface{} {
+	changes := []interface{}{}
+	diff := func(side string, sent map[float64]float64, levels []PaperBookLevel) map[float64]float64 {
+		book := poloniexMockLevels(levels)
+		for _, x := range levels {
+			if amount, ok := sent[x.Price]; !ok || amount != x.Amount {
+				changes = append(changes, map[string]interface{}{"type": POLONIEX_WEBSOCKET_BOOK_MODIFY, "data": map[string]interface{}{"type": side, "rate": mockFormatFloat(x.Price), "amount": mockFormatFloat(x.Amount)}})
+			}
+		}
+		for price := range sent {
+			if _, ok := book[price]; !ok {
+				changes = append(changes, map[string]interface{}{"type": POLONIEX_WEBSOCKET_BOOK_REMOVE, "data": map[string]interface{}{"type": side, "rate": mockFormatFloat(price)}})
+			}
+		}
+		return book
+	}
+	topic.bids = diff("bid", topic.bids, event.Bids)
+	topic.asks = diff("ask", topic.asks, event.Asks)
+	topic.seq = event.Seq
+	return changes
+}
+
+func poloniexMockTrade(trade PaperFill) map[string]interface{} {
+	return map[string]interface{}{"type": POLONIEX_WEBSOCKET_TRADE, "data": map[string]interface{}{
+		"tradeID": strconv.FormatInt(trade.TradeID, 10),
+		"rate":    mockFormatFloat(trade.Price),
+		"amount":  mockFormatFloat(trade.Amount),
+		"total":   mockFormatFloat(trade.Total),
+		"date":    trade.Time.UTC().Format(POLONIEX_DATE_LAYOUT),
+		"type":    poloniexPaperType(trade.Buy),
+	}}
+}
+
+// ticker is the ticker topic's arguments for pair, as returnTicker's.
+func (p *poloniexMock) ticker(pair string) []interface{} {
+	ticker, _ := p.venue.Ticker(pair, 24*time.Hour)
+	change := (ticker.Last - ticker.Open) / ticker.Open
+	return []interface{}{pair, mockFormatFloat(ticker.Last), mockFormatFloat(ticker.Ask), mockFormatFloat(ticker.Bid), mockFormatFloat(change), mockFormatFloat(ticker.Total), mockFormatFloat(ticker.Volume), 0, mockFormatFloat(ticker.High), mockFormatFloat(ticker.Low)}
+}
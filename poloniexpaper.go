@@ -0,0 +1,177 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	POLONIEX_PAPER_MAKER_FEE = 0.15
+	POLONIEX_PAPER_TAKER_FEE = 0.25
+	POLONIEX_PAPER_DEPTH     = 50
+)
+
+// SetupPaperTrading routes Poloniex's order and balance calls to a simulated
+// exchange which fills against the live orderbook. Market data still comes
+// from Poloniex, so no API keys are needed.
+func (p *Poloniex) SetupPaperTrading(exch Exchanges) {
+	p.PaperTrading = exch.PaperTrading
+	if !p.PaperTrading {
+		p.paper = nil
+		return
+	}
+
+	p.paper = NewPaperExchange(p.GetName(), exch.PaperTradingBalances, p.paperOrderbook, poloniexPaperPair)
+	p.paper.Latency = exch.PaperTradingLatency * time.Millisecond
+	p.paper.MakerFee = exch.PaperTradingMakerFee
+	if p.paper.MakerFee == 0 {
+		p.paper.MakerFee = POLONIEX_PAPER_MAKER_FEE
+	}
+	p.paper.TakerFee = exch.PaperTradingTakerFee
+	if p.paper.TakerFee == 0 {
+		p.paper.TakerFee = POLONIEX_PAPER_TAKER_FEE
+	}
+	p.paper.Verbose = exch.Verbose
+
+	log.Printf("%s paper trading enabled. latency=%v maker_fee=%f%% taker_fee=%f%% balances=%v\n", p.GetName(), p.paper.Latency, p.paper.MakerFee, p.paper.TakerFee, exch.PaperTradingBalances)
+}
+
+func poloniexPaperPair(pair string) (string, string) {
+	currencies := strings.SplitN(pair, "_", 2)
+	if len(currencies) != 2 {
+		return pair, ""
+	}
+	return currencies[0], currencies[1]
+}
+
+func (p *Poloniex) paperOrderbook(pair string) ([]PaperBookLevel, []PaperBookLevel, error) {
+	if bids, asks, ok := p.GetWebsocketOrderbook(pair, POLONIEX_PAPER_DEPTH); ok {
+		return bids, asks, nil
+	}
+
+	vals := url.Values{}
+	vals.Set("currencyPair", pair)
+	vals.Set("depth", strconv.Itoa(POLONIEX_PAPER_DEPTH))
+
+	book := PoloniexOrderbook{}
+	path := fmt.Sprintf("%s/public?command=returnOrderBook&%s", p.APIUrl, vals.Encode())
+	err := GetHTTPClient(p.Name).SendHTTPGetRequest(path, true, &book)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	bids, err := poloniexPaperLevels(book.Bids)
+	if err != nil {
+		return nil, nil, err
+	}
+	asks, err := poloniexPaperLevels(book.Asks)
+	if err != nil {
+		return nil, nil, err
+	}
+	return bids, asks, nil
+}
+
+// orderbook entries are ["price", amount]
+func poloniexPaperLevels(entries [][]interface{}) ([]PaperBookLevel, error) {
+	levels := []PaperBookLevel{}
+	for _, x := range entries {
+		if len(x) != 2 {
+			return nil, fmt.Errorf("unexpected orderbook entry: %v", x)
+		}
+		priceStr, ok := x[0].(string)
+		if !ok {
+			return nil, fmt.Errorf("unexpected orderbook price: %v", x[0])
+		}
+		price, err := strconv.ParseFloat(priceStr, 64)
+		if err != nil {
+			return nil, err
+		}
+		amount, ok := x[1].(float64)
+		if !ok {
+			return nil, fmt.Errorf("unexpected orderbook amount: %v", x[1])
+		}
+		levels = append(levels, PaperBookLevel{Price: price, Amount: amount})
+	}
+	return levels, nil
+}
+
+func poloniexPaperType(buy bool) string {
+	if buy {
+		return POLONIEX_ORDER_BUY
+	}
+	return POLONIEX_ORDER_SELL
+}
+
+func poloniexPaperOrderResponse(order PaperOrder) PoloniexOrderResponse {
+	result := PoloniexOrderResponse{OrderNumber: order.OrderID}
+	for _, x := range order.Fills {
+		result.Trades = append(result.Trades, PoloniexResultingTrades{
+			Amount:  x.Amount,
+			Date:    x.Time.UTC().Format(POLONIEX_DATE_LAYOUT),
+			Rate:    x.Price,
+			Total:   x.Total,
+			Fee:     x.FeeRate,
+			TradeID: x.TradeID,
+			Type:    poloniexPaperType(x.Buy),
+		})
+	}
+	return result
+}
+
+func poloniexPaperOrderTrades(order PaperOrder) []PoloniexOrderTrades {
+	result := []PoloniexOrderTrades{}
+	for _, x := range order.Fills {
+		result = append(result, PoloniexOrderTrades{
+			Amount:  x.Amount,
+			Date:    x.Time.UTC().Format(POLONIEX_DATE_LAYOUT),
+			Rate:    x.Price,
+			Total:   x.Total,
+			Fee:     x.FeeRate,
+			TradeID: x.TradeID,
+			Type:    poloniexPaperType(x.Buy),
+		})
+	}
+	return result
+}
+
+func poloniexPaperOrders(orders []PaperOrder) []PoloniexOrder {
+	result := []PoloniexOrder{}
+	for _, x := range orders {
+		var margin float64
+		if x.Margin {
+			margin = 1
+		}
+		result = append(result, PoloniexOrder{
+			OrderNumber: x.OrderID,
+			Type:        poloniexPaperType(x.Buy),
+			Rate:        x.Price,
+			Amount:      x.Amount - x.Filled,
+			Total:       (x.Amount - x.Filled) * x.Price,
+			Date:        x.Time.UTC().Format(POLONIEX_DATE_LAYOUT),
+			Margin:      margin,
+		})
+	}
+	return result
+}
+
+func poloniexPaperMarginPosition(position PaperPosition) PoloniexMarginPosition {
+	result := PoloniexMarginPosition{
+		Amount:      position.Amount,
+		BasePrice:   position.BasePrice,
+		ProfitLoss:  position.ProfitLoss,
+		LendingFees: position.LendingFees,
+		Type:        "none",
+	}
+	if position.Amount > 0 {
+		result.Type = "long"
+		result.Total = position.Amount * position.BasePrice
+	} else if position.Amount < 0 {
+		result.Type = "short"
+		result.Total = -position.Amount * position.BasePrice
+	}
+	return result
+}
@@ -1,175 +1,584 @@
 package main
 
-import "strconv"
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
 
 const (
-	POLONIEX_WEBSOCKET_ADDRESS  = "wss://api.poloniex.com"
-	POLONIEX_WEBSOCKET_REALM    = "realm1"
-	POLONIEX_WEBSOCKET_TICKER   = "ticker"
-	POLONIEX_WEBSOCKET_TROLLBOX = "trollbox"
+	POLONIEX_WEBSOCKET_ADDRESS     = "wss://api.poloniex.com"
+	POLONIEX_WEBSOCKET_REALM       = "realm1"
+	POLONIEX_WEBSOCKET_TICKER      = "ticker"
+	POLONIEX_WEBSOCKET_BOOK_MODIFY = "orderBookModify"
+	POLONIEX_WEBSOCKET_BOOK_REMOVE = "orderBookRemove"
+	POLONIEX_WEBSOCKET_TRADE       = "newTrade"
+	POLONIEX_WEBSOCKET_BOOK_DEPTH  = 500
+
+	// Poloniex speaks WAMP v2, of which only the subscriber role is needed
+	POLONIEX_WAMP_PROTOCOL   = "wamp.2.json"
+	POLONIEX_WAMP_HELLO      = 1
+	POLONIEX_WAMP_WELCOME    = 2
+	POLONIEX_WAMP_ABORT      = 3
+	POLONIEX_WAMP_GOODBYE    = 6
+	POLONIEX_WAMP_ERROR      = 8
+	POLONIEX_WAMP_SUBSCRIBE  = 32
+	POLONIEX_WAMP_SUBSCRIBED = 33
+	POLONIEX_WAMP_EVENT      = 36
+)
+
+var (
+	ErrPoloniexMarketHalted = errors.New("Market is frozen, trading is halted.")
 )
 
-type PoloniexWebsocketTicker struct {
-	CurrencyPair  string
-	Last          float64
-	LowestAsk     float64
-	HighestBid    float64
-	PercentChange float64
-	BaseVolume    float64
-	QuoteVolume   float64
-	IsFrozen      bool
-	High          float64
-	Low           float64
-}
-
-func PoloniexOnTicker(args []interface{}, kwargs map[string]interface{}) {
-	ticker := PoloniexWebsocketTicker{}
-	ticker.CurrencyPair = args[0].(string)
-	ticker.Last, _ = strconv.ParseFloat(args[1].(string), 64)
-	ticker.LowestAsk, _ = strconv.ParseFloat(args[2].(string), 64)
-	ticker.HighestBid, _ = strconv.ParseFloat(args[3].(string), 64)
-	ticker.PercentChange, _ = strconv.ParseFloat(args[4].(string), 64)
-	ticker.BaseVolume, _ = strconv.ParseFloat(args[5].(string), 64)
-	ticker.QuoteVolume, _ = strconv.ParseFloat(args[6].(string), 64)
-
-	if args[7].(float64) != 0 {
-		ticker.IsFrozen = true
-	} else {
-		ticker.IsFrozen = false
-	}
-
-	ticker.High, _ = strconv.ParseFloat(args[8].(string), 64)
-	ticker.Low, _ = strconv.ParseFloat(args[9].(string), 64)
-}
-
-type PoloniexWebsocketTrollboxMessage struct {
-	MessageNumber float64
-	Username      string
-	Message       string
-	Reputation    float64
-}
-
-func PoloniexOnTrollbox(args []interface{}, kwargs map[string]interface{}) {
-	message := PoloniexWebsocketTrollboxMessage{}
-	message.MessageNumber, _ = args[1].(float64)
-	message.Username = args[2].(string)
-	message.Message = args[3].(string)
-	message.Reputation = args[4].(float64)
-}
-
-func PoloniexOnDepthOrTrade(args []interface{}, kwargs map[string]interface{}) {
-	for x := range args {
-		data := args[x].(map[string]interface{})
-		msgData := data["data"].(map[string]interface{})
-		msgType := data["type"].(string)
-
-		switch msgType {
-		case "orderBookModify":
-			{
-				type PoloniexWebsocketOrderbookModify struct {
-					Type   string
-					Rate   float64
-					Amount float64
-				}
-
-				orderModify := PoloniexWebsocketOrderbookModify{}
-				orderModify.Type = msgData["type"].(string)
-
-				rateStr := msgData["rate"].(string)
-				orderModify.Rate, _ = strconv.ParseFloat(rateStr, 64)
-
-				amountStr := msgData["amount"].(string)
-				orderModify.Amount, _ = strconv.ParseFloat(amountStr, 64)
+// PoloniexWebsocketMarketUpdate is one entry of a pair's event. Side is bid
+// or ask for book updates and buy or sell for trades.
+type PoloniexWebsocketMarketUpdate struct {
+	Type    string
+	Side    string
+	TradeID int64
+	Rate    float64
+	Amount  float64
+	Date    time.Time
+}
+
+// PoloniexWebsocketOrderbook is a pair's book as kept up to date from the
+// websocket. Seq is the sequence number of the last event applied.
+type PoloniexWebsocketOrderbook struct {
+	Pair    string
+	Seq     int64
+	Synced  bool
+	Updated time.Time
+
+	mtx     sync.Mutex
+	syncing bool
+	buffer  []poloniexWebsocketBookEvent
+	bids    map[float64]float64
+	asks    map[float64]float64
+}
+
+type poloniexWebsocketBookEvent struct {
+	Seq     int64
+	Updates []PoloniexWebsocketMarketUpdate
+}
+
+type poloniexBookEventsBySeq []poloniexWebsocketBookEvent
+
+func (p poloniexBookEventsBySeq) Len() int           { return len(p) }
+func (p poloniexBookEventsBySeq) Less(i, j int) bool { return p[i].Seq < p[j].Seq }
+func (p poloniexBookEventsBySeq) Swap(i, j int)      { p[i], p[j] = p[j], p[i] }
+
+// poloniexWebsocketFloat reads a number Poloniex sent either as a string or
+// as a JSON number.
+func poloniexWebsocketFloat(v interface{}) (float64, bool) {
+	switch x := v.(type) {
+	case float64:
+		return x, true
+	case string:
+		f, err := strconv.ParseFloat(x, 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// ticker arguments are [pair, last, lowestAsk, highestBid, percentChange,
+// baseVolume, quoteVolume, isFrozen, 24hrHigh, 24hrLow]
+func poloniexWebsocketTicker(args []interface{}) (string, PoloniexTicker, error) {
+	ticker := PoloniexTicker{}
+	if len(args) < 10 {
+		return "", ticker, fmt.Errorf("unexpected ticker: %v", args)
+	}
+	pair, ok := args[0].(string)
+	if !ok {
+		return "", ticker, fmt.Errorf("unexpected ticker pair: %v", args[0])
+	}
+
+	fields := []*float64{nil, &ticker.Last, &ticker.LowestAsk, &ticker.HighestBid, &ticker.PercentChange, &ticker.BaseVolume, &ticker.QuoteVolume, nil, &ticker.High24Hr, &ticker.Low24Hr}
+	for i, x := range fields {
+		if x == nil {
+			continue
+		}
+		if *x, ok = poloniexWebsocketFloat(args[i]); !ok {
+			return "", ticker, fmt.Errorf("unexpected %s ticker field %d: %v", pair, i, args[i])
+		}
+	}
+
+	frozen, ok := poloniexWebsocketFloat(args[7])
+	if !ok {
+		return "", ticker, fmt.Errorf("unexpected %s isFrozen: %v", pair, args[7])
+	}
+	ticker.IsFrozen = int(frozen)
+	return pair, ticker, nil
+}
+
+// PoloniexWebsocketMarketUpdates parses a pair's event. Unknown update types
+// are skipped.
+func PoloniexWebsocketMarketUpdates(args []interface{}) ([]PoloniexWebsocketMarketUpdate, error) {
+	updates := []PoloniexWebsocketMarketUpdate{}
+	for _, x := range args {
+		data, ok := x.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("unexpected update: %v", x)
+		}
+		msgData, ok := data["data"].(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("unexpected update data: %v", data)
+		}
+
+		update := PoloniexWebsocketMarketUpdate{}
+		update.Type, _ = data["type"].(string)
+		update.Side, _ = msgData["type"].(string)
+		if update.Type != POLONIEX_WEBSOCKET_BOOK_MODIFY && update.Type != POLONIEX_WEBSOCKET_BOOK_REMOVE && update.Type != POLONIEX_WEBSOCKET_TRADE {
+			continue
+		}
+
+		if update.Rate, ok = poloniexWebsocketFloat(msgData["rate"]); !ok {
+			return nil, fmt.Errorf("unexpected %s rate: %v", update.Type, msgData)
+		}
+		if update.Type != POLONIEX_WEBSOCKET_BOOK_REMOVE {
+			if update.Amount, ok = poloniexWebsocketFloat(msgData["amount"]); !ok {
+				return nil, fmt.Errorf("unexpected %s amount: %v", update.Type, msgData)
 			}
-		case "orderBookRemove":
-			{
-				type PoloniexWebsocketOrderbookRemove struct {
-					Type string
-					Rate float64
-				}
-
-				orderRemoval := PoloniexWebsocketOrderbookRemove{}
-				orderRemoval.Type = msgData["type"].(string)
-
-				rateStr := msgData["rate"].(string)
-				orderRemoval.Rate, _ = strconv.ParseFloat(rateStr, 64)
+		}
+
+		if update.Type == POLONIEX_WEBSOCKET_TRADE {
+			tradeID, _ := poloniexWebsocketFloat(msgData["tradeID"])
+			update.TradeID = int64(tradeID)
+
+			date, _ := msgData["date"].(string)
+			var err error
+			update.Date, err = time.Parse(POLONIEX_DATE_LAYOUT, date)
+			if err != nil {
+				update.Date = time.Now()
 			}
-		case "newTrade":
-			{
-				type PoloniexWebsocketNewTrade struct {
-					Type    string
-					TradeID int64
-					Rate    float64
-					Amount  float64
-					Date    string
-					Total   float64
-				}
+		}
+		updates = append(updates, update)
+	}
+	return updates, nil
+}
 
-				trade := PoloniexWebsocketNewTrade{}
-				trade.Type = msgData["type"].(string)
+// Reset replaces the book with a REST snapshot and replays the events
+// buffered while it was fetched. The book is left unsynced if they have a
+// gap, or if it was invalidated while the snapshot was fetched, so the next
+// event starts another sync.
+func (b *PoloniexWebsocketOrderbook) Reset(snapshot PoloniexOrderbook) error {
+	bids, err := poloniexPaperLevels(snapshot.Bids)
+	if err != nil {
+		return err
+	}
+	asks, err := poloniexPaperLevels(snapshot.Asks)
+	if err != nil {
+		return err
+	}
 
-				tradeIDstr := msgData["tradeID"].(string)
-				trade.TradeID, _ = strconv.ParseInt(tradeIDstr, 10, 64)
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	if !b.syncing {
+		return nil
+	}
+	b.bids = make(map[float64]float64)
+	b.asks = make(map[float64]float64)
+	for _, x := range bids {
+		b.bids[x.Price] = x.Amount
+	}
+	for _, x := range asks {
+		b.asks[x.Price] = x.Amount
+	}
+	b.Seq = snapshot.Seq
+	b.Updated = time.Now()
 
-				rateStr := msgData["rate"].(string)
-				trade.Rate, _ = strconv.ParseFloat(rateStr, 64)
+	buffered := b.buffer
+	b.buffer = nil
+	b.syncing = false
+	b.Synced = true
 
-				amountStr := msgData["amount"].(string)
-				trade.Amount, _ = strconv.ParseFloat(amountStr, 64)
+	sort.Sort(poloniexBookEventsBySeq(buffered))
+	for _, x := range buffered {
+		if x.Seq <= b.Seq {
+			continue
+		}
+		if x.Seq != b.Seq+1 {
+			log.Printf("Poloniex %s orderbook snapshot at %d is behind the websocket at %d, resyncing.\n", b.Pair, b.Seq, x.Seq)
+			b.Synced = false
+			return nil
+		}
+		b.apply(x.Seq, x.Updates)
+	}
+	return nil
+}
 
-				totalStr := msgData["total"].(string)
-				trade.Rate, _ = strconv.ParseFloat(totalStr, 64)
+// Invalidate drops the book, e.g. when the snapshot couldn't be fetched or
+// the connection closed, so the next event starts a fresh sync.
+func (b *PoloniexWebsocketOrderbook) Invalidate() {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	b.Synced = false
+	b.syncing = false
+	b.buffer = nil
+}
 
-				trade.Date = msgData["date"].(string)
-			}
+// Apply applies an event's book updates in sequence, buffering them while a
+// snapshot is being fetched. Events already in the book are ignored. False
+// is returned when the book needs a snapshot, with the event buffered for
+// replay on top of it.
+func (b *PoloniexWebsocketOrderbook) Apply(seq int64, updates []PoloniexWebsocketMarketUpdate) bool {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	event := poloniexWebsocketBookEvent{Seq: seq, Updates: updates}
+	if b.syncing {
+		b.buffer = append(b.buffer, event)
+		return true
+	}
+	if b.Synced && seq <= b.Seq {
+		return true
+	}
+	if !b.Synced || seq != b.Seq+1 {
+		if b.Synced {
+			log.Printf("Poloniex %s orderbook missed events %d to %d, resyncing.\n", b.Pair, b.Seq+1, seq-1)
+		}
+		b.Synced = false
+		b.syncing = true
+		b.buffer = []poloniexWebsocketBookEvent{event}
+		return false
+	}
+
+	b.apply(seq, updates)
+	return true
+}
+
+// apply must be called with mtx held.
+func (b *PoloniexWebsocketOrderbook) apply(seq int64, updates []PoloniexWebsocketMarketUpdate) {
+	for _, x := range updates {
+		levels := b.bids
+		if x.Side == "ask" {
+			levels = b.asks
+		}
+		switch x.Type {
+		case POLONIEX_WEBSOCKET_BOOK_MODIFY:
+			levels[x.Rate] = x.Amount
+		case POLONIEX_WEBSOCKET_BOOK_REMOVE:
+			delete(levels, x.Rate)
+		}
+	}
+	b.Seq = seq
+	b.Updated = time.Now()
+}
+
+// Levels returns up to depth bids and asks, best first, or every level when
+// depth is 0.
+func (b *PoloniexWebsocketOrderbook) Levels(depth int) ([]PaperBookLevel, []PaperBookLevel) {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	levels := func(book map[float64]float64, descending bool) []PaperBookLevel {
+		result := []PaperBookLevel{}
+		for price, amount := range book {
+			result = append(result, PaperBookLevel{Price: price, Amount: amount})
+		}
+		if descending {
+			sort.Sort(sort.Reverse(paperLevelsByPrice(result)))
+		} else {
+			sort.Sort(paperLevelsByPrice(result))
+		}
+		if depth > 0 && len(result) > depth {
+			result = result[:depth]
+		}
+		return result
+	}
+	return levels(b.bids, true), levels(b.asks, false)
+}
+
+// UpdateTicker caches a pair's streamed ticker, logging when the market is
+// frozen or unfrozen.
+func (p *Poloniex) UpdateTicker(pair string, ticker PoloniexTicker) {
+	p.tickerMtx.Lock()
+	if p.Ticker == nil {
+		p.Ticker = make(map[string]PoloniexTicker)
+	}
+	previous, ok := p.Ticker[pair]
+	p.Ticker[pair] = ticker
+	p.tickerMtx.Unlock()
+
+	if ticker.IsFrozen != 0 && (!ok || previous.IsFrozen == 0) {
+		log.Printf("%s %s is frozen, trading halted.\n", p.GetName(), pair)
+	} else if ticker.IsFrozen == 0 && ok && previous.IsFrozen != 0 {
+		log.Printf("%s %s is no longer frozen, trading resumed.\n", p.GetName(), pair)
+	}
+}
+
+// GetLastTicker returns the most recently stored ticker for a pair.
+func (p *Poloniex) GetLastTicker(pair string) (PoloniexTicker, bool) {
+	p.tickerMtx.Lock()
+	defer p.tickerMtx.Unlock()
+	ticker, ok := p.Ticker[pair]
+	return ticker, ok
+}
+
+// GetLatestTicker returns the streamed ticker while the websocket is
+// connected, and polls for it otherwise.
+func (p *Poloniex) GetLatestTicker(pair string) (PoloniexTicker, error) {
+	if p.Websocket && GetWebsocketSupervisor(p.GetName()).Connected() {
+		if ticker, ok := p.GetLastTicker(pair); ok {
+			return ticker, nil
 		}
 	}
+
+	tickers, err := p.GetTicker()
+	if err != nil {
+		return PoloniexTicker{}, err
+	}
+	ticker, ok := tickers[pair]
+	if !ok {
+		return ticker, fmt.Errorf("%s no ticker for %s", p.GetName(), pair)
+	}
+	p.UpdateTicker(pair, ticker)
+	return ticker, nil
+}
+
+// IsHalted reports whether the pair's market was frozen when last seen.
+func (p *Poloniex) IsHalted(pair string) bool {
+	ticker, ok := p.GetLastTicker(pair)
+	return ok && ticker.IsFrozen != 0
 }
 
+func (p *Poloniex) websocketOrderbook(pair string) *PoloniexWebsocketOrderbook {
+	p.orderbooksMtx.Lock()
+	defer p.orderbooksMtx.Unlock()
+
+	if p.Orderbooks == nil {
+		p.Orderbooks = make(map[string]*PoloniexWebsocketOrderbook)
+	}
+	book, ok := p.Orderbooks[pair]
+	if !ok {
+		book = &PoloniexWebsocketOrderbook{Pair: pair}
+		p.Orderbooks[pair] = book
+	}
+	return book
+}
+
+// GetWebsocketOrderbook returns the streamed book's levels, best first, if it
+// is in sync.
+func (p *Poloniex) GetWebsocketOrderbook(pair string, depth int) ([]PaperBookLevel, []PaperBookLevel, bool) {
+	p.orderbooksMtx.Lock()
+	book, ok := p.Orderbooks[pair]
+	p.orderbooksMtx.Unlock()
+	if !ok {
+		return nil, nil, false
+	}
+
+	book.mtx.Lock()
+	synced := book.Synced
+	book.mtx.Unlock()
+	if !synced {
+		return nil, nil, false
+	}
+	bids, asks := book.Levels(depth)
+	return bids, asks, true
+}
+
+func (p *Poloniex) syncWebsocketOrderbook(book *PoloniexWebsocketOrderbook) {
+	books, err := p.GetOrderbook(book.Pair, POLONIEX_WEBSOCKET_BOOK_DEPTH)
+	if err == nil {
+		err = book.Reset(books[book.Pair])
+	}
+	if err != nil {
+		log.Printf("%s unable to sync %s orderbook. Error: %s\n", p.GetName(), book.Pair, err)
+		book.Invalidate()
+		return
+	}
+	if p.Verbose {
+		log.Printf("%s %s orderbook snapshot loaded at seq %d.\n", p.GetName(), book.Pair, books[book.Pair].Seq)
+	}
+}
+
+func (p *Poloniex) WebsocketHandleTicker(args []interface{}) {
+	pair, ticker, err := poloniexWebsocketTicker(args)
+	if err != nil {
+		log.Printf("%s websocket %s\n", p.GetName(), err)
+		return
+	}
+	p.UpdateTicker(pair, ticker)
+}
+
+// WebsocketHandleMarket passes a pair's trades to the candle builders and
+// applies its book updates, fetching a REST snapshot in the background when
+// the book isn't synced yet or an event was missed.
+func (p *Poloniex) WebsocketHandleMarket(pair string, args []interface{}, kwargs map[string]interface{}) {
+	book := p.websocketOrderbook(pair)
+	updates, err := PoloniexWebsocketMarketUpdates(args)
+	if err != nil {
+		log.Printf("%s websocket %s %s, resyncing orderbook.\n", p.GetName(), pair, err)
+		book.Invalidate()
+		return
+	}
+
+	for _, x := range updates {
+		if x.Type == POLONIEX_WEBSOCKET_TRADE {
+			p.addCandleTrade(pair, x.TradeID, x.Rate, x.Amount, x.Date)
+		}
+	}
+
+	seq, _ := poloniexWebsocketFloat(kwargs["seq"])
+	if !book.Apply(int64(seq), updates) {
+		go p.syncWebsocketOrderbook(book)
+	}
+}
+
+func poloniexWAMPID(v interface{}) int64 {
+	id, _ := v.(float64)
+	return int64(id)
+}
+
+// WebsocketClient subscribes to the ticker and each enabled pair's book and
+// trades.
 func (p *Poloniex) WebsocketClient() {
-	//for p.Enabled && p.Websocket {
-	//c, err := turnpike.NewWebsocketClient(turnpike.JSON, POLONIEX_WEBSOCKET_ADDRESS, nil)
-	//if err != nil {
-	//log.Printf("%s Unable to connect to Websocket. Error: %s\n", p.GetName(), err)
-	//continue
-	//}
-
-	//if p.Verbose {
-	//log.Printf("%s Connected to Websocket.\n", p.GetName())
-	//}
-
-	//_, err = c.JoinRealm(POLONIEX_WEBSOCKET_REALM, nil)
-	//if err != nil {
-	//log.Printf("%s Unable to join realm. Error: %s\n", p.GetName(), err)
-	//continue
-	//}
-
-	//if p.Verbose {
-	//log.Printf("%s Joined Websocket realm.\n", p.GetName())
-	//}
-
-	//c.ReceiveDone = make(chan bool)
-
-	//if err := c.Subscribe(POLONIEX_WEBSOCKET_TICKER, PoloniexOnTicker); err != nil {
-	//log.Printf("%s Error subscribing to ticker channel: %s\n", p.GetName(), err)
-	//}
-
-	//if err := c.Subscribe(POLONIEX_WEBSOCKET_TROLLBOX, PoloniexOnTrollbox); err != nil {
-	//log.Printf("%s Error subscribing to trollbox channel: %s\n", p.GetName(), err)
-	//}
-
-	//for x := range p.EnabledPairs {
-	//currency := p.EnabledPairs[x]
-	//if err := c.Subscribe(currency, PoloniexOnDepthOrTrade); err != nil {
-	//log.Printf("%s Error subscribing to %s channel: %s\n", p.GetName(), currency, err)
-	//}
-	//}
-
-	//if p.Verbose {
-	//log.Printf("%s Subscribed to websocket channels.\n", p.GetName())
-	//}
-
-	//<-c.ReceiveDone
-	//log.Printf("%s Websocket client disconnected.\n", p.GetName())
-	//}
+	s := GetWebsocketSupervisor(p.GetName())
+	s.Subscribe(WebsocketSubscription{Channel: POLONIEX_WEBSOCKET_TICKER})
+	for _, x := range p.EnabledPairs {
+		s.Subscribe(WebsocketSubscription{Channel: x})
+	}
+
+	s.Verbose = p.Verbose
+	s.Running = func() bool { return p.Enabled && p.Websocket }
+	s.Dial = p.WebsocketConnect
+	s.Serve = p.WebsocketServe
+	s.Send = p.WebsocketSubscribe
+	s.Close = p.WebsocketClose
+	s.Run()
+}
+
+// WebsocketConnect dials and joins the realm. Subscription IDs are assigned
+// per session so they are reset.
+func (p *Poloniex) WebsocketConnect() error {
+	Dialer := websocket.Dialer{Subprotocols: []string{POLONIEX_WAMP_PROTOCOL}}
+	var err error
+	p.WebsocketConn, _, err = Dialer.Dial(p.WebsocketURL, http.Header{})
+	if err != nil {
+		return err
+	}
+
+	p.wampMtx.Lock()
+	p.wampRequestID = 0
+	p.wampRequests = make(map[int64]string)
+	p.wampTopics = make(map[int64]string)
+	p.wampMtx.Unlock()
+
+	hello := []interface{}{POLONIEX_WAMP_HELLO, POLONIEX_WEBSOCKET_REALM, map[string]interface{}{"roles": map[string]interface{}{"subscriber": map[string]interface{}{}}}}
+	err = p.WebsocketConn.WriteJSON(hello)
+	if err != nil {
+		p.WebsocketConn.Close()
+		return err
+	}
+
+	welcome := []interface{}{}
+	err = p.WebsocketConn.ReadJSON(&welcome)
+	if err == nil && (len(welcome) < 2 || poloniexWAMPID(welcome[0]) != POLONIEX_WAMP_WELCOME) {
+		err = fmt.Errorf("unable to join realm %s: %v", POLONIEX_WEBSOCKET_REALM, welcome)
+	}
+	if err != nil {
+		p.WebsocketConn.Close()
+		return err
+	}
+
+	if p.Verbose {
+		log.Printf("%s Joined Websocket realm with session %d.\n", p.GetName(), poloniexWAMPID(welcome[1]))
+	}
+	return nil
+}
+
+// WebsocketClose drops the connection along with the streamed books, which
+// can't be trusted again until resynced.
+func (p *Poloniex) WebsocketClose() error {
+	p.orderbooksMtx.Lock()
+	for _, x := range p.Orderbooks {
+		x.Invalidate()
+	}
+	p.orderbooksMtx.Unlock()
+	return p.WebsocketConn.Close()
+}
+
+func (p *Poloniex) WebsocketSubscribe(sub WebsocketSubscription) error {
+	p.wampMtx.Lock()
+	p.wampRequestID++
+	id := p.wampRequestID
+	p.wampRequests[id] = sub.Channel
+	p.wampMtx.Unlock()
+
+	request := []interface{}{POLONIEX_WAMP_SUBSCRIBE, id, map[string]interface{}{}, sub.Channel}
+	return GetWebsocketSupervisor(p.GetName()).Write(func() error {
+		return p.WebsocketConn.WriteJSON(request)
+	})
+}
+
+func (p *Poloniex) WebsocketServe() error {
+	s := GetWebsocketSupervisor(p.GetName())
+
+	for p.Enabled && p.Websocket {
+		_, resp, err := p.WebsocketConn.ReadMessage()
+		if err != nil {
+			return err
+		}
+		s.Received()
+
+		msg := []interface{}{}
+		err = JSONDecode(resp, &msg)
+		if err != nil || len(msg) == 0 {
+			log.Printf("%s unexpected websocket message: %s\n", p.GetName(), resp)
+			continue
+		}
+
+		switch poloniexWAMPID(msg[0]) {
+		case POLONIEX_WAMP_SUBSCRIBED:
+			if len(msg) < 3 {
+				continue
+			}
+			p.wampMtx.Lock()
+			topic := p.wampRequests[poloniexWAMPID(msg[1])]
+			delete(p.wampRequests, poloniexWAMPID(msg[1]))
+			p.wampTopics[poloniexWAMPID(msg[2])] = topic
+			p.wampMtx.Unlock()
+
+			if p.Verbose {
+				log.Printf("%s Subscribed to %s.\n", p.GetName(), topic)
+			}
+		case POLONIEX_WAMP_ERROR:
+			log.Printf("%s websocket error: %s\n", p.GetName(), resp)
+		case POLONIEX_WAMP_EVENT:
+			if len(msg) < 2 {
+				continue
+			}
+			p.wampMtx.Lock()
+			topic, ok := p.wampTopics[poloniexWAMPID(msg[1])]
+			p.wampMtx.Unlock()
+			if !ok {
+				continue
+			}
+
+			args := []interface{}{}
+			kwargs := map[string]interface{}{}
+			if len(msg) > 4 {
+				args, _ = msg[4].([]interface{})
+			}
+			if len(msg) > 5 {
+				kwargs, _ = msg[5].(map[string]interface{})
+			}
+
+			if topic == POLONIEX_WEBSOCKET_TICKER {
+				p.WebsocketHandleTicker(args)
+			} else {
+				p.WebsocketHandleMarket(topic, args, kwargs)
+			}
+		case POLONIEX_WAMP_GOODBYE, POLONIEX_WAMP_ABORT:
+			return fmt.Errorf("session closed by server: %s", resp)
+		}
+	}
+	return nil
 }
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func poloniexWebsocketEvent(t *testing.T, event string) []interface{} {
+	args := []interface{}{}
+	if err := JSONDecode([]byte(event), &args); err != nil {
+		t.Fatal(err)
+	}
+	return args
+}
+
+func poloniexWaitSynced(t *testing.T, p *Poloniex, pair string) ([]PaperBookLevel, []PaperBookLevel) {
+	for start := time.Now(); time.Since(start) < 5*time.Second; time.Sleep(time.Millisecond) {
+		if bids, asks, ok := p.GetWebsocketOrderbook(pair, 0); ok {
+			return bids, asks
+		}
+	}
+	t.Fatal("Test failed. Expected the orderbook to sync")
+	return nil, nil
+}
+
+func TestPoloniexWebsocketMarket(t *testing.T) {
+	snapshots := make(chan string, 2)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, <-snapshots)
+	}))
+	defer server.Close()
+
+	p := &Poloniex{Name: "PoloniexWebsocketMarket", APIUrl: server.URL}
+	c, err := NewCandleBuilder(p.Name, "BTC_ETH", time.Minute, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer RemoveCandleBuilder(c)
+
+	// the first event starts a sync, both are buffered until the snapshot
+	// arrives and then replayed on it
+	p.WebsocketHandleMarket("BTC_ETH", poloniexWebsocketEvent(t, `[{"data":{"type":"bid","rate":"0.01950000","amount":"2"},"type":"orderBookModify"}]`), map[string]interface{}{"seq": float64(11)})
+	p.WebsocketHandleMarket("BTC_ETH", poloniexWebsocketEvent(t, `[{"data":{"type":"ask","rate":"0.02000000"},"type":"orderBookRemove"},{"data":{"tradeID":"364476","rate":"0.02000000","amount":"3","date":"2016-04-05 08:08:40","total":"0.06","type":"buy"},"type":"newTrade"}]`), map[string]interface{}{"seq": float64(12)})
+	snapshots <- `{"asks":[["0.02000000",3],["0.02100000",1]],"bids":[["0.01900000",5]],"isFrozen":"0","seq":10}`
+	bids, asks := poloniexWaitSynced(t, p, "BTC_ETH")
+	if fmt.Sprint(bids) != "[{0.0195 2} {0.019 5}]" || fmt.Sprint(asks) != "[{0.021 1}]" {
+		t.Error(fmt.Sprintf("Test failed. Expected the updates applied to the snapshot. Actual %v %v", bids, asks))
+	}
+
+	// 14 skips 13 so the book is resynced
+	p.WebsocketHandleMarket("BTC_ETH", poloniexWebsocketEvent(t, `[{"data":{"type":"bid","rate":"0.01700000","amount":"1"},"type":"orderBookModify"}]`), map[string]interface{}{"seq": float64(14)})
+	if _, _, ok := p.GetWebsocketOrderbook("BTC_ETH", 0); ok {
+		t.Error("Test failed. Expected the gap to unsync the book")
+	}
+	snapshots <- `{"asks":[["0.02200000",4]],"bids":[["0.01800000",6]],"isFrozen":"0","seq":15}`
+	bids, asks = poloniexWaitSynced(t, p, "BTC_ETH")
+	if fmt.Sprint(bids) != "[{0.018 6}]" || fmt.Sprint(asks) != "[{0.022 4}]" {
+		t.Error(fmt.Sprintf("Test failed. Expected the book resynced after the gap. Actual %v %v", bids, asks))
+	}
+
+	p.WebsocketHandleMarket("BTC_ETH", poloniexWebsocketEvent(t, `["malformed"]`), map[string]interface{}{"seq": float64(16)})
+	if _, _, ok := p.GetWebsocketOrderbook("BTC_ETH", 0); ok {
+		t.Error("Test failed. Expected a malformed event to unsync the book")
+	}
+
+	c.Close(time.Date(2016, 4, 5, 8, 10, 0, 0, time.UTC))
+	candle := <-c.Candles
+	if candle.Close != 0.02 || candle.Volume != 3 || candle.Trades != 1 {
+		t.Error(fmt.Sprintf("Test failed. Expected the streamed trade in the candle. Actual %+v", candle))
+	}
+}
+
+func TestPoloniexWebsocketWAMP(t *testing.T) {
+	upgrader := websocket.Upgrader{Subprotocols: []string{POLONIEX_WAMP_PROTOCOL}}
+	subscribed := make(chan string, 2)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		hello := []interface{}{}
+		if conn.ReadJSON(&hello) != nil || poloniexWAMPID(hello[0]) != POLONIEX_WAMP_HELLO || hello[1] != POLONIEX_WEBSOCKET_REALM {
+			conn.WriteJSON([]interface{}{POLONIEX_WAMP_ABORT, map[string]interface{}{}, "wamp.error.no_such_realm"})
+			return
+		}
+		conn.WriteJSON([]interface{}{POLONIEX_WAMP_WELCOME, 1234, map[string]interface{}{}})
+
+		for i := int64(0); i < 2; i++ {
+			subscribe := []interface{}{}
+			if conn.ReadJSON(&subscribe) != nil {
+				return
+			}
+			subscribed <- subscribe[3].(string)
+			conn.WriteJSON([]interface{}{POLONIEX_WAMP_SUBSCRIBED, subscribe[1], 500 + i})
+		}
+		conn.WriteJSON([]interface{}{POLONIEX_WAMP_EVENT, 500, 1, map[string]interface{}{}, []interface{}{"BTC_ETH", "0.02", "0.0201", "0.0199", "0.01", "100", "5000", 1, "0.021", "0.019"}})
+		conn.WriteJSON([]interface{}{POLONIEX_WAMP_GOODBYE, map[string]interface{}{}, "wamp.close.system_shutdown"})
+	}))
+	defer server.Close()
+
+	p := &Poloniex{Name: "PoloniexWebsocketWAMP", Enabled: true, Websocket: true, WebsocketURL: "ws" + strings.TrimPrefix(server.URL, "http")}
+	if err := p.WebsocketConnect(); err != nil {
+		t.Fatal(err)
+	}
+	defer p.WebsocketConn.Close()
+	for _, x := range []string{POLONIEX_WEBSOCKET_TICKER, "BTC_ETH"} {
+		if err := p.WebsocketSubscribe(WebsocketSubscription{Channel: x}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := p.WebsocketServe(); err == nil || !strings.Contains(err.Error(), "system_shutdown") {
+		t.Error(fmt.Sprintf("Test failed. Expected the goodbye to end the session. Actual %v", err))
+	}
+	if topics := <-subscribed + "," + <-subscribed; topics != "ticker,BTC_ETH" {
+		t.Error(fmt.Sprintf("Test failed. Expected ticker and pair subscriptions. Actual %s", topics))
+	}
+
+	ticker, ok := p.GetLastTicker("BTC_ETH")
+	if !ok || ticker.Last != 0.02 || ticker.HighestBid != 0.0199 || ticker.QuoteVolume != 5000 || ticker.Low24Hr != 0.019 {
+		t.Error(fmt.Sprintf("Test failed. Expected the streamed ticker. Actual %+v", ticker))
+	}
+	if !p.IsHalted("BTC_ETH") {
+		t.Error("Test failed. Expected the frozen market to be halted")
+	}
+	venue := &PoloniexExecutionVenue{p: p}
+	if _, _, err := venue.PlaceLimitOrder("BTC_ETH", 0.02, 1, true, false); err != ErrPoloniexMarketHalted {
+		t.Error(fmt.Sprintf("Test failed. Expected %v. Actual %v", ErrPoloniexMarketHalted, err))
+	}
+}
+
+func TestPoloniexWebsocketCandleFallback(t *testing.T) {
+	var mtx sync.Mutex
+	polls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("command") != "returnTradeHistory" {
+			fmt.Fprint(w, `{"asks":[],"bids":[],"isFrozen":"0","seq":1}`)
+			return
+		}
+		mtx.Lock()
+		polls++
+		mtx.Unlock()
+		fmt.Fprint(w, `[{"tradeID":2,"date":"2016-04-05 08:08:50","type":"buy","rate":"0.021","amount":"2","total":"0.042"},{"tradeID":1,"date":"2016-04-05 08:08:40","type":"sell","rate":"0.02","amount":"1","total":"0.02"}]`)
+	}))
+	defer server.Close()
+
+	p := &Poloniex{Name: "PoloniexWebsocketCandleFallback", APIUrl: server.URL, Websocket: true, RESTPollingDelay: 1}
+	c, err := NewCandleBuilder(p.Name, "BTC_ETH", time.Minute, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Stop()
+
+	s := GetWebsocketSupervisor(p.Name)
+	s.setState(WEBSOCKET_STATE_CONNECTED)
+	go p.PollCandleTrades("BTC_ETH", time.Date(2016, 4, 5, 8, 0, 0, 0, time.UTC), c.Done())
+	time.Sleep(100 * time.Millisecond)
+	mtx.Lock()
+	if polls != 0 {
+		t.Error(fmt.Sprintf("Test failed. Expected no polling while the websocket is up. Actual %d polls", polls))
+	}
+	mtx.Unlock()
+
+	s.setState(WEBSOCKET_STATE_DISCONNECTED)
+	for start := time.Now(); p.lastCandleTrade("BTC_ETH").TradeID != 2; time.Sleep(10 * time.Millisecond) {
+		if time.Since(start) > 5*time.Second {
+			t.Fatal("Test failed. Expected the poller to take over while the websocket is down")
+		}
+	}
+
+	// the websocket resends trade 2 once it's back, which mustn't count twice
+	p.WebsocketHandleMarket("BTC_ETH", poloniexWebsocketEvent(t, `[{"data":{"tradeID":"2","rate":"0.021","amount":"2","date":"2016-04-05 08:08:50","total":"0.042","type":"buy"},"type":"newTrade"},{"data":{"tradeID":"3","rate":"0.022","amount":"4","date":"2016-04-05 08:09:10","total":"0.088","type":"buy"},"type":"newTrade"}]`), map[string]interface{}{"seq": float64(2)})
+	c.Close(time.Date(2016, 4, 5, 8, 10, 0, 0, time.UTC))
+	if candle := <-c.Candles; candle.Volume != 3 || candle.Trades != 2 {
+		t.Error(fmt.Sprintf("Test failed. Expected the polled trades once. Actual %+v", candle))
+	}
+	if candle := <-c.Candles; candle.Volume != 4 || candle.Trades != 1 {
+		t.Error(fmt.Sprintf("Test failed. Expected the streamed trade. Actual %+v", candle))
+	}
+}
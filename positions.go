@@ -0,0 +1,288 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// The position tracker books fills per exchange and pair. Amounts are in the
+// traded currency (ETH for BTC_ETH) and are negative when short; prices, P&L,
+// fees and lending fees are all in the currency the pair is priced in (BTC).
+// Realized P&L is worked out FIFO, closing the oldest lots first, or against
+// the average entry price.
+
+const (
+	POSITION_COST_FIFO    = "FIFO"
+	POSITION_COST_AVERAGE = "AVERAGE"
+)
+
+var (
+	ErrPositionFillDuplicate  = errors.New("Fill already booked to position.")
+	ErrPositionSpotShort      = errors.New("Spot positions can't be sold short.")
+	ErrPositionInvalidFill    = errors.New("Fill price and amount must be greater than zero.")
+	ErrPositionUnknownMethod  = errors.New("Unknown cost method.")
+	ErrPositionMarginMismatch = errors.New("Fill is for a different account type than the position.")
+)
+
+type PositionLot struct {
+	Amount float64 // negative when short
+	Price  float64
+	Time   time.Time
+}
+
+type Position struct {
+	Exchange     string
+	Pair         string
+	Margin       bool
+	Amount       float64 // negative when short
+	AveragePrice float64
+	Lots         []PositionLot
+	MarkPrice    float64
+	RealizedPL   float64
+	UnrealizedPL float64
+	Fees         float64
+	LendingFees  float64
+	Trades       int
+	Updated      time.Time
+}
+
+func (p Position) Long() bool {
+	return p.Amount > EXECUTION_DUST
+}
+
+func (p Position) Short() bool {
+	return p.Amount < -EXECUTION_DUST
+}
+
+// NetPL is realized and unrealized P&L less all fees paid.
+func (p Position) NetPL() float64 {
+	return p.RealizedPL + p.UnrealizedPL - p.Fees - p.LendingFees
+}
+
+func (p Position) String() string {
+	side := "flat"
+	if p.Long() {
+		side = "long"
+	} else if p.Short() {
+		side = "short"
+	}
+	return fmt.Sprintf("%s %s %s amount=%f avgPrice=%f mark=%f realized=%f unrealized=%f fees=%f lending_fees=%f net=%f trades=%d",
+		p.Exchange, p.Pair, side, p.Amount, p.AveragePrice, p.MarkPrice, p.RealizedPL, p.UnrealizedPL, p.Fees, p.LendingFees, p.NetPL(), p.Trades)
+}
+
+type PositionTracker struct {
+	Method string
+
+	mtx       sync.Mutex
+	positions map[string]*Position
+	trades    map[string]struct{}
+}
+
+// Positions is fed by the OMS with every fill it records.
+var Positions = NewPositionTracker(POSITION_COST_FIFO)
+
+func NewPositionTracker(method string) *PositionTracker {
+	return &PositionTracker{
+		Method:    method,
+		positions: make(map[string]*Position),
+		trades:    make(map[string]struct{}),
+	}
+}
+
+func positionKey(exchange, pair string) string {
+	return exchange + " " + pair
+}
+
+// get must be called with mtx held.
+func (t *PositionTracker) get(exchange, pair string, margin bool) *Position {
+	key := positionKey(exchange, pair)
+	position, ok := t.positions[key]
+	if !ok {
+		position = &Position{Exchange: exchange, Pair: pair, Margin: margin}
+		t.positions[key] = position
+	}
+	return position
+}
+
+// Open seeds a position that was opened before the tracker was, e.g. from
+// the exchange's margin position on startup.
+func (t *PositionTracker) Open(exchange, pair string, margin bool, amount, price float64) error {
+	buy := amount > 0
+	return t.AddFill(exchange, pair, margin, buy, OrderFill{Price: price, Amount: math.Abs(amount), Time: time.Now()})
+}
+
+// AddFill books a fill to the position. Fills with a trade ID already booked
+// are ignored.
+func (t *PositionTracker) AddFill(exchange, pair string, margin, buy bool, fill OrderFill) error {
+	if fill.Price <= 0 || fill.Amount <= 0 {
+		return ErrPositionInvalidFill
+	}
+	if t.Method != POSITION_COST_FIFO && t.Method != POSITION_COST_AVERAGE {
+		return ErrPositionUnknownMethod
+	}
+
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	tradeKey := positionKey(exchange, fill.TradeID)
+	if fill.TradeID != "" {
+		if _, ok := t.trades[tradeKey]; ok {
+			return ErrPositionFillDuplicate
+		}
+	}
+
+	position := t.get(exchange, pair, margin)
+	if position.Margin != margin && (position.Long() || position.Short()) {
+		return ErrPositionMarginMismatch
+	}
+	position.Margin = margin
+
+	amount := fill.Amount
+	if !buy {
+		amount = -amount
+	}
+	if !margin && position.Amount+amount < -EXECUTION_DUST {
+		return ErrPositionSpotShort
+	}
+
+	if fill.TradeID != "" {
+		t.trades[tradeKey] = struct{}{}
+	}
+	position.Trades++
+	position.Fees += fill.Fee
+	position.Updated = fill.Time
+	t.book(position, amount, fill.Price, fill.Time)
+	if position.MarkPrice == 0 {
+		position.MarkPrice = fill.Price
+	}
+	t.mark(position, position.MarkPrice)
+	return nil
+}
+
+// book must be called with mtx held.
+func (t *PositionTracker) book(position *Position, amount, price float64, when time.Time) {
+	// opening or adding to the position
+	if position.Amount == 0 || (position.Amount > 0) == (amount > 0) {
+		position.AveragePrice = (position.AveragePrice*math.Abs(position.Amount) + price*math.Abs(amount)) / math.Abs(position.Amount+amount)
+		position.Amount += amount
+		position.Lots = append(position.Lots, PositionLot{Amount: amount, Price: price, Time: when})
+		return
+	}
+
+	// reducing, and possibly flipping
+	closing := math.Min(math.Abs(amount), math.Abs(position.Amount))
+	direction := 1.
+	if position.Amount < 0 {
+		direction = -1
+	}
+
+	if t.Method == POSITION_COST_AVERAGE {
+		position.RealizedPL += direction * closing * (price - position.AveragePrice)
+		left := math.Abs(position.Amount) - closing
+		position.Lots = []PositionLot{{Amount: direction * left, Price: position.AveragePrice, Time: when}}
+	} else {
+		left := closing
+		for left > EXECUTION_DUST && len(position.Lots) > 0 {
+			lot := &position.Lots[0]
+			take := math.Min(left, math.Abs(lot.Amount))
+			position.RealizedPL += direction * take * (price - lot.Price)
+			lot.Amount -= direction * take
+			left -= take
+			if math.Abs(lot.Amount) <= EXECUTION_DUST {
+				position.Lots = position.Lots[1:]
+			}
+		}
+	}
+
+	position.Amount += amount
+	remaining := math.Abs(amount) - closing
+	if math.Abs(position.Amount) <= EXECUTION_DUST {
+		position.Amount = 0
+		position.AveragePrice = 0
+		position.Lots = nil
+		return
+	}
+	if remaining > EXECUTION_DUST {
+		// flipped, what's left opens the other way
+		position.Lots = []PositionLot{{Amount: position.Amount, Price: price, Time: when}}
+		position.AveragePrice = price
+		return
+	}
+
+	// the average entry of what's left
+	var cost, held float64
+	for _, x := range position.Lots {
+		cost += math.Abs(x.Amount) * x.Price
+		held += math.Abs(x.Amount)
+	}
+	if held > 0 {
+		position.AveragePrice = cost / held
+	}
+}
+
+// mark must be called with mtx held.
+func (t *PositionTracker) mark(position *Position, price float64) {
+	position.MarkPrice = price
+	position.UnrealizedPL = position.Amount * (price - position.AveragePrice)
+}
+
+// Mark revalues the position at price, usually the ticker's last.
+func (t *PositionTracker) Mark(exchange, pair string, price float64) {
+	if price <= 0 {
+		return
+	}
+
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	position, ok := t.positions[positionKey(exchange, pair)]
+	if !ok {
+		return
+	}
+	t.mark(position, price)
+}
+
+// AddLendingFee books lending fees paid on a margin position.
+func (t *PositionTracker) AddLendingFee(exchange, pair string, fee float64) {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	position := t.get(exchange, pair, true)
+	position.LendingFees += fee
+}
+
+func (t *PositionTracker) Position(exchange, pair string) (Position, bool) {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	position, ok := t.positions[positionKey(exchange, pair)]
+	if !ok {
+		return Position{Exchange: exchange, Pair: pair}, false
+	}
+	x := *position
+	x.Lots = append([]PositionLot{}, position.Lots...)
+	return x, true
+}
+
+func (t *PositionTracker) Positions() []Position {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	keys := []string{}
+	for x := range t.positions {
+		keys = append(keys, x)
+	}
+	sort.Strings(keys)
+
+	positions := []Position{}
+	for _, x := range keys {
+		position := *t.positions[x]
+		position.Lots = append([]PositionLot{}, position.Lots...)
+		positions = append(positions, position)
+	}
+	return positions
+}
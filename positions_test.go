@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"testing"
+	"time"
+)
+
+func positionsTestFill(tradeID string, price, amount, fee float64) OrderFill {
+	return OrderFill{TradeID: tradeID, Price: price, Amount: amount, Fee: fee, Time: time.Now()}
+}
+
+func TestPositionCostMethods(t *testing.T) {
+	t.Parallel()
+	expected := map[string]float64{
+		// 10@1.1 against the 1.0 lot, then 5@1.1 against the 1.2 lot
+		POSITION_COST_FIFO: 10*0.1 - 5*0.1,
+		// 15@1.1 against the 1.0667 average
+		POSITION_COST_AVERAGE: 15 * (1.1 - 16./15),
+	}
+
+	for method, realized := range expected {
+		tracker := NewPositionTracker(method)
+		tracker.AddFill("Test", "BTC_ETH", false, true, positionsTestFill("1", 1, 10, 0))
+		tracker.AddFill("Test", "BTC_ETH", false, true, positionsTestFill("2", 1.2, 5, 0))
+		tracker.AddFill("Test", "BTC_ETH", false, false, positionsTestFill("3", 1.1, 15, 0))
+
+		position, _ := tracker.Position("Test", "BTC_ETH")
+		if math.Abs(position.RealizedPL-realized) > 1e-9 || position.Amount != 0 || position.Trades != 3 {
+			t.Error(fmt.Sprintf("Test failed. %s expected realized %f. Actual %+v", method, realized, position))
+		}
+	}
+}
+
+func TestPositionShortAndFlip(t *testing.T) {
+	t.Parallel()
+	tracker := NewPositionTracker(POSITION_COST_FIFO)
+
+	if err := tracker.AddFill("Test", "BTC_ETH", false, false, positionsTestFill("1", 1, 10, 0)); err != ErrPositionSpotShort {
+		t.Error(fmt.Sprintf("Test failed. Expected %v. Actual %v", ErrPositionSpotShort, err))
+	}
+
+	tracker.AddFill("Test", "BTC_ETH", true, false, positionsTestFill("1", 1, 10, 0))
+	position, _ := tracker.Position("Test", "BTC_ETH")
+	if !position.Short() || position.AveragePrice != 1 {
+		t.Error(fmt.Sprintf("Test failed. Expected short at 1. Actual %+v", position))
+	}
+
+	// covers the short at a loss and goes long the rest
+	tracker.AddFill("Test", "BTC_ETH", true, true, positionsTestFill("2", 1.5, 15, 0))
+	position, _ = tracker.Position("Test", "BTC_ETH")
+	if !position.Long() || position.Amount != 5 || position.AveragePrice != 1.5 || position.RealizedPL != -5 {
+		t.Error(fmt.Sprintf("Test failed. Expected long 5 at 1.5. Actual %+v", position))
+	}
+
+	if err := tracker.AddFill("Test", "BTC_ETH", true, true, positionsTestFill("2", 1.5, 15, 0)); err != ErrPositionFillDuplicate {
+		t.Error(fmt.Sprintf("Test failed. Expected %v. Actual %v", ErrPositionFillDuplicate, err))
+	}
+}
+
+func TestPositionMarkAndFees(t *testing.T) {
+	t.Parallel()
+	tracker := NewPositionTracker(POSITION_COST_FIFO)
+	tracker.AddFill("Test", "BTC_ETH", true, true, positionsTestFill("1", 2, 10, 0.01))
+	tracker.AddLendingFee("Test", "BTC_ETH", 0.005)
+	tracker.Mark("Test", "BTC_ETH", 2.5)
+
+	position, _ := tracker.Position("Test", "BTC_ETH")
+	if position.UnrealizedPL != 5 || math.Abs(position.NetPL()-(5-0.015)) > 1e-9 {
+		t.Error(fmt.Sprintf("Test failed. Expected unrealized 5 less fees. Actual %+v", position))
+	}
+	if len(tracker.Positions()) != 1 {
+		t.Error(fmt.Sprintf("Test failed. Expected 1 position. Actual %d", len(tracker.Positions())))
+	}
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Every exchange's HTTP client throttles itself with two token buckets, one
+// for public and one for authenticated requests, or a single one shared by
+// both where the exchange counts them together. Requests cost one token
+// unless the endpoint has a weight, matched against the request URL. By
+// default requests block until there are tokens; fail fast limiters return
+// ErrRateLimited instead.
+
+var (
+	ErrRateLimited = errors.New("Request rate limit reached.")
+)
+
+type RateLimit struct {
+	Rate  float64 // tokens per second, 0 is unlimited
+	Burst float64 // most tokens the bucket holds
+}
+
+type RateLimitConfig struct {
+	Public        RateLimit
+	Authenticated RateLimit
+	Shared        bool           // public and authenticated requests draw from the Public bucket
+	Weights       map[string]int // URL substring -> tokens, for endpoints that cost more (or nothing)
+}
+
+// RateLimitDefaults are documented limits, or where the exchange doesn't
+// document one, a conservative guess at what keeps us from getting banned.
+var RateLimitDefaults = map[string]RateLimitConfig{
+	// 6 calls per second, public and trading API counted together
+	"Poloniex": {Public: RateLimit{6, 6}, Shared: true},
+	// 90 requests per minute
+	"Bitfinex": {Public: RateLimit{1.5, 10}, Authenticated: RateLimit{1.5, 10}},
+	// 600 requests per 10 minutes, counted together
+	"Bitstamp": {Public: RateLimit{1, 10}, Shared: true},
+	// 3 per second public, 5 per second private, bursts of twice that
+	"Coinbase": {Public: RateLimit{3, 6}, Authenticated: RateLimit{5, 10}},
+	// 120 per minute public, 600 per minute private
+	"Gemini": {Public: RateLimit{2, 5}, Authenticated: RateLimit{10, 10}},
+	// private calls add to a counter that tops out at 15 and decays by 1
+	// every 3 seconds. history and ledger queries count twice, orders don't
+	"Kraken": {
+		Public:        RateLimit{1, 1},
+		Authenticated: RateLimit{1. / 3, 15},
+		Weights: map[string]int{
+			"/Ledgers":       2,
+			"/QueryLedgers":  2,
+			"/TradesHistory": 2,
+			"/QueryTrades":   2,
+			"/AddOrder":      0,
+			"/CancelOrder":   0,
+		},
+	},
+	// 3000 requests per 5 minutes
+	"OKCOIN International": {Public: RateLimit{10, 10}, Authenticated: RateLimit{10, 10}},
+	"OKCOIN China":         {Public: RateLimit{10, 10}, Authenticated: RateLimit{10, 10}},
+	"BTCE":                 {Public: RateLimit{2, 5}, Authenticated: RateLimit{2, 5}},
+}
+
+// RateLimitDefault covers exchanges without an entry in RateLimitDefaults.
+var RateLimitDefault = RateLimitConfig{Public: RateLimit{1, 5}, Authenticated: RateLimit{1, 5}}
+
+type TokenBucket struct {
+	limit  RateLimit
+	mtx    sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+func NewTokenBucket(limit RateLimit) *TokenBucket {
+	return &TokenBucket{limit: limit, tokens: limit.Burst, last: time.Now()}
+}
+
+// refill must be called with mtx held.
+func (b *TokenBucket) refill(now time.Time) {
+	b.tokens = math.Min(b.limit.Burst, b.tokens+now.Sub(b.last).Seconds()*b.limit.Rate)
+	b.last = now
+}
+
+// Take takes weight tokens if they're there.
+func (b *TokenBucket) Take(weight float64) bool {
+	if b == nil || b.limit.Rate <= 0 || weight <= 0 {
+		return true
+	}
+
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	b.refill(time.Now())
+	if b.tokens < weight {
+		return false
+	}
+	b.tokens -= weight
+	return true
+}
+
+// Wait takes weight tokens, blocking until they've accrued. Waiters are
+// queued by going into debt, so they're served in the order they arrived.
+func (b *TokenBucket) Wait(weight float64) {
+	if b == nil || b.limit.Rate <= 0 || weight <= 0 {
+		return
+	}
+
+	b.mtx.Lock()
+	b.refill(time.Now())
+	b.tokens -= weight
+	wait := time.Duration(-b.tokens / b.limit.Rate * float64(time.Second))
+	b.mtx.Unlock()
+
+	if wait > 0 {
+		time.Sleep(wait)
+	}
+}
+
+type RateLimiter struct {
+	FailFast bool
+
+	public        *TokenBucket
+	authenticated *TokenBucket
+	weights       map[string]int
+}
+
+func NewRateLimiter(config RateLimitConfig) *RateLimiter {
+	r := &RateLimiter{
+		public:  NewTokenBucket(config.Public),
+		weights: config.Weights,
+	}
+	r.authenticated = r.public
+	if !config.Shared {
+		r.authenticated = NewTokenBucket(config.Authenticated)
+	}
+	return r
+}
+
+// GetRateLimitConfig returns the exchange's default limits, with the rates
+// set in its config in place of the defaults.
+func GetRateLimitConfig(exch Exchanges) RateLimitConfig {
+	config, ok := RateLimitDefaults[exch.Name]
+	if !ok {
+		config = RateLimitDefault
+	}
+	if exch.RateLimitPublic > 0 {
+		config.Public = RateLimit{exch.RateLimitPublic, math.Max(config.Public.Burst, 1)}
+	}
+	if exch.RateLimitAuthenticated > 0 {
+		config.Authenticated = RateLimit{exch.RateLimitAuthenticated, math.Max(config.Authenticated.Burst, 1)}
+		config.Shared = false
+	}
+	return config
+}
+
+func (r *RateLimiter) weight(url string) float64 {
+	for x, weight := range r.weights {
+		if strings.Contains(url, x) {
+			return float64(weight)
+		}
+	}
+	return 1
+}
+
+// Wait takes the tokens for a request to url, blocking or, when FailFast is
+// set, returning ErrRateLimited if there aren't enough.
+func (r *RateLimiter) Wait(authenticated bool, url string) error {
+	if r == nil {
+		return nil
+	}
+
+	bucket := r.public
+	if authenticated {
+		bucket = r.authenticated
+	}
+
+	weight := r.weight(url)
+	if r.FailFast {
+		if !bucket.Take(weight) {
+			return ErrRateLimited
+		}
+		return nil
+	}
+	bucket.Wait(weight)
+	return nil
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestRateLimiterBlocking(t *testing.T) {
+	t.Parallel()
+	r := NewRateLimiter(RateLimitConfig{Public: RateLimit{100, 2}, Authenticated: RateLimit{100, 2}})
+
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		r.Wait(false, "/ticker")
+	}
+	// the burst goes straight through, the next two wait 10ms each
+	if elapsed := time.Since(start); elapsed < 15*time.Millisecond {
+		t.Error(fmt.Sprintf("Test failed. Expected to wait ~20ms. Actual %v", elapsed))
+	}
+
+	// authenticated requests have their own bucket
+	start = time.Now()
+	r.Wait(true, "/balances")
+	if elapsed := time.Since(start); elapsed > 5*time.Millisecond {
+		t.Error(fmt.Sprintf("Test failed. Expected no wait. Actual %v", elapsed))
+	}
+}
+
+func TestRateLimiterFailFast(t *testing.T) {
+	t.Parallel()
+	r := NewRateLimiter(RateLimitConfig{Public: RateLimit{1, 3}, Shared: true, Weights: map[string]int{"/Ledgers": 2, "/AddOrder": 0}})
+	r.FailFast = true
+
+	if err := r.Wait(true, "/0/private/Ledgers"); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Wait(false, "/0/public/Ticker"); err != nil {
+		t.Fatal(err)
+	}
+	// the shared bucket is empty
+	if err := r.Wait(true, "/0/private/Balance"); err != ErrRateLimited {
+		t.Error(fmt.Sprintf("Test failed. Expected %v. Actual %v", ErrRateLimited, err))
+	}
+	if err := r.Wait(true, "/0/private/AddOrder"); err != nil {
+		t.Error(fmt.Sprintf("Test failed. Expected free endpoint to go through. Actual %v", err))
+	}
+}
+
+func TestGetRateLimitConfig(t *testing.T) {
+	t.Parallel()
+	config := GetRateLimitConfig(Exchanges{Name: "Bitstamp", RateLimitAuthenticated: 0.5})
+	if config.Shared || config.Authenticated.Rate != 0.5 || config.Public.Rate != 1 {
+		t.Error(fmt.Sprintf("Test failed. Unexpected config %+v", config))
+	}
+	if config = GetRateLimitConfig(Exchanges{Name: "Unknown"}); config.Public != RateLimitDefault.Public {
+		t.Error(fmt.Sprintf("Test failed. Expected the default limits. Actual %+v", config))
+	}
+}
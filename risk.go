@@ -0,0 +1,237 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"math"
+	"sync"
+	"time"
+)
+
+const (
+	RISK_EXIT_STOP_LOSS     = "STOP_LOSS"
+	RISK_EXIT_TAKE_PROFIT   = "TAKE_PROFIT"
+	RISK_EXIT_TRAILING_STOP = "TRAILING_STOP"
+	RISK_EXIT_SIGNAL        = "SIGNAL"
+)
+
+var (
+	ErrRiskTradingHalted    = errors.New("Trading halted for the day, maximum daily loss reached.")
+	ErrRiskStoppedOut       = errors.New("Stopped out, waiting for the signal to reverse before re-entering.")
+	ErrRiskMaxLeverage      = errors.New("Order would exceed maximum leverage.")
+	ErrRiskPositionOpen     = errors.New("Position already open for pair.")
+	ErrRiskNoEquity         = errors.New("No equity available to size order.")
+	ErrRiskInvalidOrderSize = errors.New("Order size must be greater than zero.")
+)
+
+// RiskLimits are expressed in percent, e.g. a StopLoss of 5 exits a position
+// once it is 5% against its entry. Zero disables a limit.
+type RiskLimits struct {
+	StopLoss        float64
+	TakeProfit      float64
+	TrailingStop    float64
+	MaxPositionSize float64 // percent of equity per position
+	MaxDailyLoss    float64 // percent of equity at the start of the (UTC) day
+	MaxLeverage     float64 // total open position size over equity, margin accounts only
+}
+
+type RiskPosition struct {
+	Pair     string
+	Long     bool
+	Entry    float64
+	Size     float64 // notional at entry, in the same units as equity
+	Fraction float64 // Size as a fraction of equity when opened
+	Best     float64 // most favourable price seen, for the trailing stop
+}
+
+// RiskManager sits between a strategy and execution. Strategies ask it how
+// much they may open, tell it what they opened and closed, and feed it prices
+// so it can trigger stops. The same manager is used live and in backtests.
+type RiskManager struct {
+	Limits  RiskLimits
+	Margin  bool
+	Verbose bool
+
+	mtx            sync.Mutex
+	positions      map[string]*RiskPosition
+	stoppedOut     map[string]bool // pair -> direction (long) of the last position closed by a limit
+	equity         float64
+	dayStart       time.Time
+	dayStartEquity float64
+	halted         bool
+}
+
+func NewRiskManager(limits RiskLimits, margin bool) *RiskManager {
+	return &RiskManager{
+		Limits:     limits,
+		Margin:     margin,
+		positions:  make(map[string]*RiskPosition),
+		stoppedOut: make(map[string]bool),
+	}
+}
+
+// UpdateEquity marks the account to equity at time t, rolling the daily loss
+// window at UTC midnight and halting trading once the daily loss is hit.
+func (r *RiskManager) UpdateEquity(equity float64, t time.Time) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	day := t.UTC().Truncate(24 * time.Hour)
+	if r.dayStart.IsZero() || day.After(r.dayStart) {
+		if r.halted && r.Verbose {
+			log.Printf("Risk manager resuming trading on %s.\n", day.Format("2006-01-02"))
+		}
+		r.dayStart = day
+		r.dayStartEquity = equity
+		r.halted = false
+	}
+	r.equity = equity
+	r.checkDailyLoss()
+}
+
+// checkDailyLoss must be called with mtx held.
+func (r *RiskManager) checkDailyLoss() {
+	if r.Limits.MaxDailyLoss <= 0 || r.dayStartEquity <= 0 || r.halted {
+		return
+	}
+
+	loss := 100 * (r.dayStartEquity - r.equity) / r.dayStartEquity
+	if loss >= r.Limits.MaxDailyLoss {
+		r.halted = true
+		log.Printf("Risk manager halting trading. Daily loss %f%% exceeds maximum %f%%.\n", loss, r.Limits.MaxDailyLoss)
+	}
+}
+
+func (r *RiskManager) Halted() bool {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	return r.halted
+}
+
+// CheckOrder returns how much of the requested size may be opened on pair,
+// after applying the position size and leverage limits.
+func (r *RiskManager) CheckOrder(pair string, long bool, size float64) (float64, error) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	if size <= 0 {
+		return 0, ErrRiskInvalidOrderSize
+	}
+	if r.halted {
+		return 0, ErrRiskTradingHalted
+	}
+	if _, ok := r.positions[pair]; ok {
+		return 0, ErrRiskPositionOpen
+	}
+	if direction, ok := r.stoppedOut[pair]; ok {
+		if direction == long {
+			return 0, ErrRiskStoppedOut
+		}
+		delete(r.stoppedOut, pair)
+	}
+	if r.equity <= 0 {
+		return 0, ErrRiskNoEquity
+	}
+
+	if r.Limits.MaxPositionSize > 0 {
+		size = math.Min(size, r.equity*r.Limits.MaxPositionSize/100)
+	}
+
+	if r.Margin && r.Limits.MaxLeverage > 0 {
+		var exposure float64
+		for _, x := range r.positions {
+			exposure += x.Size
+		}
+		available := r.equity*r.Limits.MaxLeverage - exposure
+		if available <= 0 {
+			return 0, ErrRiskMaxLeverage
+		}
+		size = math.Min(size, available)
+	}
+	return size, nil
+}
+
+func (r *RiskManager) OpenPosition(pair string, long bool, price, size float64) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	position := &RiskPosition{Pair: pair, Long: long, Entry: price, Size: size, Best: price}
+	if r.equity > 0 {
+		position.Fraction = size / r.equity
+	}
+	r.positions[pair] = position
+}
+
+func (r *RiskManager) Position(pair string) (RiskPosition, bool) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	position, ok := r.positions[pair]
+	if !ok {
+		return RiskPosition{}, false
+	}
+	return *position, true
+}
+
+// CheckPrice updates the open position on pair with the latest price and
+// returns the exit reason if a stop loss, take profit or trailing stop hit.
+func (r *RiskManager) CheckPrice(pair string, price float64) (string, bool) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	position, ok := r.positions[pair]
+	if !ok || position.Entry <= 0 {
+		return "", false
+	}
+
+	change := 100 * (price - position.Entry) / position.Entry
+	if (position.Long && price > position.Best) || (!position.Long && price < position.Best) {
+		position.Best = price
+	}
+	fromBest := 100 * (position.Best - price) / position.Best
+	if !position.Long {
+		change, fromBest = -change, -fromBest
+	}
+
+	switch {
+	case r.Limits.StopLoss > 0 && change <= -r.Limits.StopLoss:
+		return RISK_EXIT_STOP_LOSS, true
+	case r.Limits.TakeProfit > 0 && change >= r.Limits.TakeProfit:
+		return RISK_EXIT_TAKE_PROFIT, true
+	case r.Limits.TrailingStop > 0 && fromBest >= r.Limits.TrailingStop:
+		return RISK_EXIT_TRAILING_STOP, true
+	}
+	return "", false
+}
+
+// ClosePosition removes the position on pair, returning its gross profit at
+// price in equity units. Closing on any limit blocks re-entry in the same
+// direction until an order in the other direction is checked, so a strategy
+// whose signal still points the same way can't immediately undo the exit.
+func (r *RiskManager) ClosePosition(pair string, price float64, reason string) float64 {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	position, ok := r.positions[pair]
+	if !ok {
+		return 0
+	}
+	delete(r.positions, pair)
+
+	profit := position.Size * (price - position.Entry) / position.Entry
+	if !position.Long {
+		profit = -profit
+	}
+
+	if reason != RISK_EXIT_SIGNAL {
+		r.stoppedOut[pair] = position.Long
+	}
+
+	if r.Verbose || reason != RISK_EXIT_SIGNAL {
+		log.Printf("Risk manager closed %s position. Reason: %s Entry: %f Exit: %f Profit: %f\n", pair, reason, position.Entry, price, profit)
+	}
+
+	r.equity += profit
+	r.checkDailyLoss()
+	return profit
+}
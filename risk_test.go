@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestRiskManagerStops(t *testing.T) {
+	t.Parallel()
+	r := NewRiskManager(RiskLimits{StopLoss: 5, TakeProfit: 20, TrailingStop: 10}, false)
+	r.UpdateEquity(1, time.Unix(1465776000, 0))
+
+	size, err := r.CheckOrder("BTC_ETH", true, 1)
+	if err != nil || size != 1 {
+		t.Fatal(fmt.Sprintf("Test failed. Expected size 1. Actual %f %v", size, err))
+	}
+	r.OpenPosition("BTC_ETH", true, 100, size)
+
+	if _, hit := r.CheckPrice("BTC_ETH", 96); hit {
+		t.Error("Test failed. Expected no stop at -4%")
+	}
+	if reason, hit := r.CheckPrice("BTC_ETH", 95); !hit || reason != RISK_EXIT_STOP_LOSS {
+		t.Error(fmt.Sprintf("Test failed. Expected stop loss at -5%%. Actual %s %v", reason, hit))
+	}
+
+	// trailing stop follows the best price up
+	r.CheckPrice("BTC_ETH", 115)
+	if reason, hit := r.CheckPrice("BTC_ETH", 103); !hit || reason != RISK_EXIT_TRAILING_STOP {
+		t.Error(fmt.Sprintf("Test failed. Expected trailing stop. Actual %s %v", reason, hit))
+	}
+
+	if profit := r.ClosePosition("BTC_ETH", 103, RISK_EXIT_TRAILING_STOP); profit < 0.0299 || profit > 0.0301 {
+		t.Error(fmt.Sprintf("Test failed. Expected profit 0.03. Actual %f", profit))
+	}
+
+	// no re-entry in the same direction until the signal reverses
+	if _, err := r.CheckOrder("BTC_ETH", true, 1); err != ErrRiskStoppedOut {
+		t.Error(fmt.Sprintf("Test failed. Expected %v. Actual %v", ErrRiskStoppedOut, err))
+	}
+	if _, err := r.CheckOrder("BTC_ETH", false, 1); err != nil {
+		t.Error(fmt.Sprintf("Test failed. Expected short to be allowed. Actual %v", err))
+	}
+}
+
+func TestRiskManagerSizing(t *testing.T) {
+	t.Parallel()
+	r := NewRiskManager(RiskLimits{MaxPositionSize: 50, MaxLeverage: 1}, true)
+	r.UpdateEquity(2, time.Unix(1465776000, 0))
+
+	size, _ := r.CheckOrder("BTC_ETH", true, 2)
+	if size != 1 {
+		t.Error(fmt.Sprintf("Test failed. Expected size clamped to 1. Actual %f", size))
+	}
+	r.OpenPosition("BTC_ETH", true, 10, size)
+	if position, _ := r.Position("BTC_ETH"); position.Fraction != 0.5 {
+		t.Error(fmt.Sprintf("Test failed. Expected fraction 0.5. Actual %f", position.Fraction))
+	}
+
+	if _, err := r.CheckOrder("BTC_ETH", false, 1); err != ErrRiskPositionOpen {
+		t.Error(fmt.Sprintf("Test failed. Expected %v. Actual %v", ErrRiskPositionOpen, err))
+	}
+
+	r.OpenPosition("BTC_LTC", true, 10, 1)
+	if _, err := r.CheckOrder("BTC_XMR", true, 1); err != ErrRiskMaxLeverage {
+		t.Error(fmt.Sprintf("Test failed. Expected %v. Actual %v", ErrRiskMaxLeverage, err))
+	}
+}
+
+func TestRiskManagerDailyLoss(t *testing.T) {
+	t.Parallel()
+	r := NewRiskManager(RiskLimits{MaxDailyLoss: 10}, false)
+	day := time.Unix(1465776000, 0)
+
+	r.UpdateEquity(1, day)
+	r.UpdateEquity(0.95, day.Add(time.Hour))
+	if r.Halted() {
+		t.Error("Test failed. Expected trading at -5%")
+	}
+
+	r.UpdateEquity(0.89, day.Add(2*time.Hour))
+	if _, err := r.CheckOrder("BTC_ETH", true, 1); err != ErrRiskTradingHalted {
+		t.Error(fmt.Sprintf("Test failed. Expected %v. Actual %v", ErrRiskTradingHalted, err))
+	}
+
+	r.UpdateEquity(0.89, day.Add(24*time.Hour))
+	if r.Halted() {
+		t.Error("Test failed. Expected trading to resume the next day")
+	}
+}
@@ -8,9 +8,12 @@ import (
 )
 
 const (
-	SMSGLOBAL_API_URL     = "http://www.smsglobal.com/http-api.php"
-	ErrSMSContactNotFound = "SMS Contact not found."
-	ErrSMSNotSent         = "SMS message not sent."
+	SMSGLOBAL_API_URL = "http://www.smsglobal.com/http-api.php"
+)
+
+var (
+	ErrSMSContactNotFound = errors.New("SMS Contact not found.")
+	ErrSMSNotSent         = errors.New("SMS message not sent.")
 )
 
 func GetEnabledSMSContacts() int {
@@ -34,13 +37,13 @@ func SMSSendToAll(message string) {
 	}
 }
 
-func SMSGetNumberByName(name string) string {
+func SMSGetNumberByName(name string) (string, error) {
 	for _, contact := range bot.config.SMS.Contacts {
 		if contact.Name == name {
-			return contact.Number
+			return contact.Number, nil
 		}
 	}
-	return ErrSMSContactNotFound
+	return "", ErrSMSContactNotFound
 }
 
 func SMSNotify(to, message string) error {
@@ -62,7 +65,7 @@ func SMSNotify(to, message string) error {
 	}
 
 	if !StringContains(resp, "OK: 0; Sent queued message") {
-		return errors.New(ErrSMSNotSent)
+		return ErrSMSNotSent
 	}
 	return nil
 }
@@ -5,6 +5,7 @@ import (
 	"log"
 	"net/http"
 	"strconv"
+	"time"
 )
 
 func GetWebserverHost() string {
@@ -23,6 +24,10 @@ func GetWebserverPort() int {
 
 func StartWebserver() error {
 	http.HandleFunc("/", index)
+	http.HandleFunc("/journal.csv", journalCSV)
+	http.HandleFunc("/taxlots.csv", taxLotsCSV)
+	http.HandleFunc("/journal/import", journalImport)
+	http.HandleFunc("/websockets.json", websocketStatsJSON)
 	var err error
 	go func() {
 		err = http.ListenAndServe(bot.config.Webserver.ListenAddress, nil)
@@ -52,3 +57,98 @@ func index(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 }
+
+func checkWebserverAuth(w http.ResponseWriter, r *http.Request) bool {
+	username, password, ok := r.BasicAuth()
+	if !ok || username != bot.config.Webserver.AdminUsername || password != bot.config.Webserver.AdminPassword {
+		w.Header().Set("WWW-Authenticate", `Basic realm="cryptotrader"`)
+		w.WriteHeader(http.StatusUnauthorized)
+		return false
+	}
+	return true
+}
+
+func journalCSV(w http.ResponseWriter, r *http.Request) {
+	if !checkWebserverAuth(w, r) {
+		return
+	}
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", "attachment; filename=journal.csv")
+	err := TradeJournal.ExportCSV(w)
+	if err != nil {
+		log.Println(err)
+	}
+}
+
+// taxLotsCSV reports FIFO lots unless asked for ?method=LIFO.
+func taxLotsCSV(w http.ResponseWriter, r *http.Request) {
+	if !checkWebserverAuth(w, r) {
+		return
+	}
+	method := StringToUpper(r.URL.Query().Get("method"))
+	if method == "" {
+		method = JOURNAL_TAX_LOT_FIFO
+	}
+	report, err := TradeJournal.TaxLots(method)
+	if err != nil {
+		ServerHTTPError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", "attachment; filename=taxlots.csv")
+	err = report.WriteCSV(w)
+	if err != nil {
+		log.Println(err)
+	}
+}
+
+// journalImport pulls an exchange's trade history into the journal, e.g.
+// POST /journal/import?exchange=Poloniex&pair=BTC_ETH&since=1451606400
+func journalImport(w http.ResponseWriter, r *http.Request) {
+	if !checkWebserverAuth(w, r) {
+		return
+	}
+	if r.Method != "POST" {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	since := time.Time{}
+	if query.Get("since") != "" {
+		seconds, err := strconv.ParseInt(query.Get("since"), 10, 64)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		since = time.Unix(seconds, 0)
+	}
+
+	count, err := ImportTradeHistory(query.Get("exchange"), query.Get("pair"), since)
+	if err != nil {
+		ServerHTTPError(w, err)
+		return
+	}
+	log.Printf("Imported %d %s trades into the trade journal.\n", count, query.Get("exchange"))
+
+	data, err := JSONEncode(map[string]int{"imported": count})
+	if err != nil {
+		ServerHTTPError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Write(data)
+}
+
+func websocketStatsJSON(w http.ResponseWriter, r *http.Request) {
+	if !checkWebserverAuth(w, r) {
+		return
+	}
+	data, err := JSONEncode(GetWebsocketStats())
+	if err != nil {
+		ServerHTTPError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Write(data)
+}
@@ -0,0 +1,354 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	WEBSOCKET_STATE_DISCONNECTED = "disconnected"
+	WEBSOCKET_STATE_CONNECTING   = "connecting"
+	WEBSOCKET_STATE_CONNECTED    = "connected"
+
+	WEBSOCKET_DEFAULT_MIN_BACKOFF   = time.Second
+	WEBSOCKET_DEFAULT_MAX_BACKOFF   = time.Minute
+	WEBSOCKET_DEFAULT_STALE_TIMEOUT = time.Minute
+)
+
+var (
+	ErrWebsocketStale = errors.New("no websocket data received within the stale timeout")
+)
+
+// WebsocketSubscription is a channel the supervisor replays on every
+// reconnect. Params are kept unsigned; exchanges sign authenticated
+// subscriptions when sending them.
+type WebsocketSubscription struct {
+	Channel       string
+	Params        map[string]string
+	Authenticated bool
+}
+
+func (w WebsocketSubscription) Key() string {
+	keys := []string{}
+	for k, v := range w.Params {
+		keys = append(keys, k+"="+v)
+	}
+	sort.Strings(keys)
+	key := w.Channel + "?" + strings.Join(keys, "&")
+	if w.Authenticated {
+		key += "#auth"
+	}
+	return key
+}
+
+type WebsocketStats struct {
+	Name           string
+	State          string
+	Connects       int64
+	Reconnects     int64
+	Failures       int64
+	StaleTimeouts  int64
+	Messages       int64
+	Subscriptions  int
+	Backoff        time.Duration
+	ConnectedSince time.Time
+	LastMessage    time.Time
+	LastError      string
+}
+
+// WebsocketSupervisor owns an exchange's reconnect loop. Dial connects and
+// handshakes, Serve reads until the connection drops (calling Received for
+// every message), Send writes one subscription and Close tears the
+// connection down, unblocking Serve. Ping is optional and is called every
+// HeartbeatInterval while connected.
+type WebsocketSupervisor struct {
+	Name              string
+	MinBackoff        time.Duration
+	MaxBackoff        time.Duration
+	StaleTimeout      time.Duration
+	HeartbeatInterval time.Duration
+	Verbose           bool
+
+	Running func() bool
+	Dial    func() error
+	Serve   func() error
+	Send    func(sub WebsocketSubscription) error
+	Ping    func() error
+	Close   func() error
+
+	mtx           sync.Mutex
+	writeMtx      sync.Mutex
+	stats         WebsocketStats
+	attempts      uint
+	subscriptions []WebsocketSubscription
+}
+
+var (
+	websocketSupervisors    = make(map[string]*WebsocketSupervisor)
+	websocketSupervisorsMtx sync.Mutex
+)
+
+// GetWebsocketSupervisor returns the named exchange's supervisor, creating
+// one with the defaults if needed.
+func GetWebsocketSupervisor(name string) *WebsocketSupervisor {
+	websocketSupervisorsMtx.Lock()
+	defer websocketSupervisorsMtx.Unlock()
+
+	s, ok := websocketSupervisors[name]
+	if !ok {
+		s = NewWebsocketSupervisor(name)
+		websocketSupervisors[name] = s
+	}
+	return s
+}
+
+// GetWebsocketStats returns a snapshot of every supervisor, sorted by name.
+func GetWebsocketStats() []WebsocketStats {
+	websocketSupervisorsMtx.Lock()
+	names := []string{}
+	for x := range websocketSupervisors {
+		names = append(names, x)
+	}
+	sort.Strings(names)
+	stats := []WebsocketStats{}
+	for _, x := range names {
+		stats = append(stats, websocketSupervisors[x].Stats())
+	}
+	websocketSupervisorsMtx.Unlock()
+	return stats
+}
+
+func NewWebsocketSupervisor(name string) *WebsocketSupervisor {
+	return &WebsocketSupervisor{
+		Name:         name,
+		MinBackoff:   WEBSOCKET_DEFAULT_MIN_BACKOFF,
+		MaxBackoff:   WEBSOCKET_DEFAULT_MAX_BACKOFF,
+		StaleTimeout: WEBSOCKET_DEFAULT_STALE_TIMEOUT,
+		stats:        WebsocketStats{Name: name, State: WEBSOCKET_STATE_DISCONNECTED},
+	}
+}
+
+// Subscribe records the subscription and sends it straight away if
+// connected. Duplicate subscriptions are only recorded once.
+func (s *WebsocketSupervisor) Subscribe(sub WebsocketSubscription) error {
+	s.mtx.Lock()
+	found := false
+	for _, x := range s.subscriptions {
+		if x.Key() == sub.Key() {
+			found = true
+			break
+		}
+	}
+	if !found {
+		s.subscriptions = append(s.subscriptions, sub)
+		s.stats.Subscriptions = len(s.subscriptions)
+	}
+	connected := s.stats.State == WEBSOCKET_STATE_CONNECTED
+	send := s.Send
+	s.mtx.Unlock()
+
+	if !connected || send == nil {
+		return nil
+	}
+	return send(sub)
+}
+
+func (s *WebsocketSupervisor) Unsubscribe(sub WebsocketSubscription) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	for i, x := range s.subscriptions {
+		if x.Key() == sub.Key() {
+			s.subscriptions = append(s.subscriptions[:i], s.subscriptions[i+1:]...)
+			break
+		}
+	}
+	s.stats.Subscriptions = len(s.subscriptions)
+}
+
+// Subscriptions returns the recorded subscriptions in the order they were made.
+func (s *WebsocketSupervisor) Subscriptions() []WebsocketSubscription {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return append([]WebsocketSubscription{}, s.subscriptions...)
+}
+
+// Write serialises writes to the connection, as the heartbeat runs on its
+// own goroutine.
+func (s *WebsocketSupervisor) Write(fn func() error) error {
+	s.writeMtx.Lock()
+	defer s.writeMtx.Unlock()
+	return fn()
+}
+
+// Received marks the connection as live. The first message on a new
+// connection also resets the backoff.
+func (s *WebsocketSupervisor) Received() {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.stats.Messages++
+	s.stats.LastMessage = time.Now()
+	s.attempts = 0
+	s.stats.Backoff = 0
+}
+
+func (s *WebsocketSupervisor) Stats() WebsocketStats {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return s.stats
+}
+
+// Connected reports whether streamed data can currently be relied on.
+func (s *WebsocketSupervisor) Connected() bool {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return s.stats.State == WEBSOCKET_STATE_CONNECTED
+}
+
+func (s *WebsocketSupervisor) setState(state string) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.stats.State = state
+	switch state {
+	case WEBSOCKET_STATE_CONNECTED:
+		if s.stats.Connects > 0 {
+			s.stats.Reconnects++
+		}
+		s.stats.Connects++
+		s.stats.ConnectedSince = time.Now()
+		s.stats.LastMessage = s.stats.ConnectedSince
+	case WEBSOCKET_STATE_DISCONNECTED:
+		s.stats.ConnectedSince = time.Time{}
+	}
+}
+
+func (s *WebsocketSupervisor) failed(err error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.stats.Failures++
+	s.stats.LastError = err.Error()
+}
+
+// nextBackoff doubles from MinBackoff on each consecutive failure, capped at
+// MaxBackoff.
+func (s *WebsocketSupervisor) nextBackoff() time.Duration {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	backoff := s.MinBackoff
+	for i := uint(0); i < s.attempts && backoff < s.MaxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > s.MaxBackoff {
+		backoff = s.MaxBackoff
+	}
+	s.attempts++
+	s.stats.Backoff = backoff
+	return backoff
+}
+
+// Run connects, replays subscriptions and serves until Running returns false,
+// backing off between attempts.
+func (s *WebsocketSupervisor) Run() {
+	for s.Running() {
+		s.setState(WEBSOCKET_STATE_CONNECTING)
+		err := s.Dial()
+		if err != nil {
+			s.failed(err)
+			s.setState(WEBSOCKET_STATE_DISCONNECTED)
+			backoff := s.nextBackoff()
+			log.Printf("%s Unable to connect to Websocket. Error: %s. Retrying in %s.\n", s.Name, err, backoff)
+			time.Sleep(backoff)
+			continue
+		}
+
+		s.setState(WEBSOCKET_STATE_CONNECTED)
+		if s.Verbose {
+			log.Printf("%s Connected to Websocket.\n", s.Name)
+		}
+
+		for _, x := range s.Subscriptions() {
+			if s.Send == nil {
+				break
+			}
+			err = s.Send(x)
+			if err != nil {
+				log.Printf("%s Websocket unable to subscribe to %s. Error: %s\n", s.Name, x.Channel, err)
+			}
+		}
+
+		stop := make(chan struct{})
+		stale := make(chan struct{}, 1)
+		go s.watchdog(stop, stale)
+
+		err = s.Serve()
+		close(stop)
+		s.Close()
+
+		select {
+		case <-stale:
+			err = ErrWebsocketStale
+		default:
+		}
+		if err != nil {
+			s.failed(err)
+		}
+		s.setState(WEBSOCKET_STATE_DISCONNECTED)
+
+		if !s.Running() {
+			break
+		}
+		backoff := s.nextBackoff()
+		log.Printf("%s Websocket client disconnected. Error: %v. Reconnecting in %s.\n", s.Name, err, backoff)
+		time.Sleep(backoff)
+	}
+}
+
+// watchdog sends heartbeats and closes the connection once no message has
+// arrived within StaleTimeout, which makes Serve return.
+func (s *WebsocketSupervisor) watchdog(stop, stale chan struct{}) {
+	interval := s.StaleTimeout / 4
+	if s.HeartbeatInterval > 0 && (interval <= 0 || s.HeartbeatInterval < interval) {
+		interval = s.HeartbeatInterval
+	}
+	if interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	lastPing := time.Now()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case now := <-ticker.C:
+			if s.Ping != nil && s.HeartbeatInterval > 0 && now.Sub(lastPing) >= s.HeartbeatInterval {
+				lastPing = now
+				err := s.Ping()
+				if err != nil {
+					log.Printf("%s Websocket heartbeat error: %s\n", s.Name, err)
+				}
+			}
+
+			s.mtx.Lock()
+			last := s.stats.LastMessage
+			s.mtx.Unlock()
+
+			if s.StaleTimeout > 0 && now.Sub(last) > s.StaleTimeout {
+				s.mtx.Lock()
+				s.stats.StaleTimeouts++
+				s.mtx.Unlock()
+				log.Printf("%s Websocket stale, no data for %s. Reconnecting.\n", s.Name, now.Sub(last))
+				stale <- struct{}{}
+				s.Close()
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWebsocketSupervisorBackoff(t *testing.T) {
+	t.Parallel()
+	s := NewWebsocketSupervisor("test")
+	s.MinBackoff, s.MaxBackoff = time.Second, 5*time.Second
+
+	for _, x := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second} {
+		if backoff := s.nextBackoff(); backoff != x {
+			t.Error(fmt.Sprintf("Test failed. Expected backoff %s. Actual %s", x, backoff))
+		}
+	}
+
+	// a message on a new connection means it's healthy again
+	s.Received()
+	if backoff := s.nextBackoff(); backoff != time.Second {
+		t.Error(fmt.Sprintf("Test failed. Expected backoff to reset. Actual %s", backoff))
+	}
+}
+
+func TestWebsocketSupervisorSubscriptions(t *testing.T) {
+	t.Parallel()
+	s := NewWebsocketSupervisor("test")
+	s.MinBackoff = time.Millisecond
+
+	var mtx sync.Mutex
+	dials := 0
+	sent := []string{}
+	s.Running = func() bool {
+		mtx.Lock()
+		defer mtx.Unlock()
+		return dials < 2
+	}
+	s.Dial = func() error {
+		mtx.Lock()
+		defer mtx.Unlock()
+		dials++
+		return nil
+	}
+	s.Send = func(sub WebsocketSubscription) error {
+		mtx.Lock()
+		defer mtx.Unlock()
+		sent = append(sent, fmt.Sprintf("%d:%s", dials, sub.Channel))
+		return nil
+	}
+	s.Serve = func() error {
+		s.Received()
+		return errors.New("connection reset")
+	}
+	s.Close = func() error { return nil }
+
+	s.Subscribe(WebsocketSubscription{Channel: "book", Params: map[string]string{"pair": "BTCUSD", "prec": "P0"}})
+	s.Subscribe(WebsocketSubscription{Channel: "trades", Params: map[string]string{"pair": "BTCUSD"}})
+	s.Subscribe(WebsocketSubscription{Channel: "book", Params: map[string]string{"prec": "P0", "pair": "BTCUSD"}})
+	s.Subscribe(WebsocketSubscription{Channel: "ticker"})
+	s.Unsubscribe(WebsocketSubscription{Channel: "ticker"})
+	s.Run()
+
+	if result := strings.Join(sent, ","); result != "1:book,1:trades,2:book,2:trades" {
+		t.Error(fmt.Sprintf("Test failed. Expected subscriptions replayed on reconnect. Actual %s", result))
+	}
+
+	stats := s.Stats()
+	if stats.State != WEBSOCKET_STATE_DISCONNECTED || stats.Connects != 2 || stats.Reconnects != 1 || stats.Failures != 2 || stats.Messages != 2 || stats.Subscriptions != 2 {
+		t.Error(fmt.Sprintf("Test failed. Unexpected stats %+v", stats))
+	}
+	if stats.LastError != "connection reset" {
+		t.Error(fmt.Sprintf("Test failed. Expected last error connection reset. Actual %s", stats.LastError))
+	}
+}
+
+func TestWebsocketSupervisorStale(t *testing.T) {
+	t.Parallel()
+	s := NewWebsocketSupervisor("test")
+	s.MinBackoff = time.Millisecond
+	s.StaleTimeout = 20 * time.Millisecond
+	s.HeartbeatInterval = 5 * time.Millisecond
+
+	var mtx sync.Mutex
+	dials, pings := 0, 0
+	closed := make(chan struct{}, 1)
+	s.Running = func() bool {
+		mtx.Lock()
+		defer mtx.Unlock()
+		return dials < 1
+	}
+	s.Dial = func() error {
+		mtx.Lock()
+		defer mtx.Unlock()
+		dials++
+		return nil
+	}
+	s.Ping = func() error {
+		mtx.Lock()
+		defer mtx.Unlock()
+		pings++
+		return nil
+	}
+	s.Close = func() error {
+		select {
+		case closed <- struct{}{}:
+		default:
+		}
+		return nil
+	}
+	// the connection stays open but nothing arrives
+	s.Serve = func() error {
+		<-closed
+		return errors.New("use of closed network connection")
+	}
+
+	done := make(chan struct{})
+	go func() {
+		s.Run()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Test failed. Expected the stale connection to be closed")
+	}
+
+	stats := s.Stats()
+	if stats.StaleTimeouts != 1 || stats.LastError != ErrWebsocketStale.Error() {
+		t.Error(fmt.Sprintf("Test failed. Unexpected stats %+v", stats))
+	}
+	mtx.Lock()
+	defer mtx.Unlock()
+	if pings == 0 {
+		t.Error("Test failed. Expected heartbeats while connected")
+	}
+}